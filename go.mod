@@ -6,6 +6,7 @@ require (
 	github.com/go-text/typesetting v0.1.1
 	github.com/pdfcpu/pdfcpu v0.11.1
 	golang.org/x/image v0.32.0
+	golang.org/x/text v0.30.0
 )
 
 require (
@@ -16,6 +17,5 @@ require (
 	github.com/mattn/go-runewidth v0.0.19 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	golang.org/x/crypto v0.43.0 // indirect
-	golang.org/x/text v0.30.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
@@ -3,14 +3,19 @@ package gopdf
 // Annotation 表示 PDF 注释
 // 注释是页面上的交互元素，如文本注释、高亮、链接等
 type Annotation struct {
-	Subtype    string                 // 注释子类型（Text, Highlight, Link 等）
-	Rect       []float64              // 注释矩形 [x1 y1 x2 y2]
-	Contents   string                 // 注释内容文本
-	Color      []float64              // 注释颜色（RGB 或 CMYK）
-	Appearance map[string]interface{} // 外观流字典（AP entry）
-	Flags      int                    // 注释标志
-	QuadPoints []float64              // 四边形点（用于高亮等）
-	Name       string                 // 注释名称（用于某些类型）
+	Subtype          string                 // 注释子类型（Text, Highlight, Link 等）
+	Rect             []float64              // 注释矩形 [x1 y1 x2 y2]
+	Contents         string                 // 注释内容文本
+	Color            []float64              // 注释颜色（RGB 或 CMYK）
+	Appearance       map[string]interface{} // 外观流字典（AP entry）
+	AppearanceState  string                 // 外观状态（AS entry）
+	NormalAppearance *XObject               // 按外观状态选出的正常外观流（AP /N）
+	Flags            int                    // 注释标志
+	QuadPoints       []float64              // 四边形点（用于高亮等）
+	Name             string                 // 注释名称（用于某些类型）
+	URI              string                 // 链接注释的 URI 动作目标
+	DestPage         int                    // 链接注释跳转的目标页码（从 1 开始，0 表示没有或无法解析）
+	DestName         string                 // 链接注释的命名目标
 }
 
 // NewAnnotation 创建新的注释
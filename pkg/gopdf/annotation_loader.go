@@ -134,6 +134,22 @@ func parseAnnotation(ctx *model.Context, annotDict types.Dict) (*Annotation, err
 		}
 	}
 
+	// 获取外观状态（AS），有多个外观状态时据此选择 /AP /N 中的子外观
+	if as, found := annotDict.Find("AS"); found {
+		if name, ok := as.(types.Name); ok {
+			annot.AppearanceState = name.Value()
+		}
+	}
+
+	if n, ok := annot.Appearance["N"].(types.Object); ok {
+		xobj, err := loadAppearanceStream(ctx, n, annot.AppearanceState)
+		if err != nil {
+			debugPrintf("Warning: failed to load annotation appearance: %v\n", err)
+		} else {
+			annot.NormalAppearance = xobj
+		}
+	}
+
 	// 获取四边形点（用于高亮等）
 	if quadPoints, found := annotDict.Find("QuadPoints"); found {
 		if arr, ok := quadPoints.(types.Array); ok {
@@ -155,5 +171,165 @@ func parseAnnotation(ctx *model.Context, annotDict types.Dict) (*Annotation, err
 		}
 	}
 
+	// 链接注释的目标：/Dest 或 /A 动作（URI、GoTo）
+	if annot.Subtype == "Link" {
+		parseLinkTarget(ctx, annotDict, annot)
+	}
+
 	return annot, nil
 }
+
+// maxNameTreeDepth 名称树的最大嵌套深度，防止循环引用
+const maxNameTreeDepth = 32
+
+// parseLinkTarget 解析链接注释的 URI 或跳转目标
+func parseLinkTarget(ctx *model.Context, annotDict types.Dict, annot *Annotation) {
+	dest, hasDest := annotDict.Find("Dest")
+
+	if actionObj, found := annotDict.Find("A"); found {
+		action, err := ctx.DereferenceDict(actionObj)
+		if err != nil || action == nil {
+			debugPrintf("Warning: invalid link action: %v\n", err)
+		} else {
+			var actionType string
+			if s, found := action.Find("S"); found {
+				if name, ok := s.(types.Name); ok {
+					actionType = name.Value()
+				}
+			}
+			switch actionType {
+			case "URI":
+				if uriObj, found := action.Find("URI"); found {
+					if uriObj, err = ctx.Dereference(uriObj); err == nil {
+						if uri, err := types.StringOrHexLiteral(uriObj); err == nil {
+							annot.URI = *uri
+						}
+					}
+				}
+			case "GoTo":
+				dest, hasDest = action.Find("D")
+			}
+		}
+	}
+
+	if hasDest {
+		resolveLinkDest(ctx, dest, annot)
+	}
+}
+
+// resolveLinkDest 解析目标（显式目标数组、命名目标的名称或字符串）的页码
+func resolveLinkDest(ctx *model.Context, dest types.Object, annot *Annotation) {
+	obj, err := ctx.Dereference(dest)
+	if err != nil || obj == nil {
+		return
+	}
+
+	var destArray types.Array
+	switch d := obj.(type) {
+	case types.Array:
+		destArray = d
+	case types.Name:
+		annot.DestName = d.Value()
+		destArray = lookupNamedDest(ctx, annot.DestName)
+	case types.StringLiteral, types.HexLiteral:
+		if name, err := types.StringOrHexLiteral(d); err == nil {
+			annot.DestName = *name
+			destArray = lookupNamedDest(ctx, annot.DestName)
+		}
+	}
+	if len(destArray) == 0 {
+		return
+	}
+
+	// 目标数组的第一个元素是页面对象的引用；远程目标（GoToR）使用从 0 开始的页码
+	switch page := destArray[0].(type) {
+	case types.IndirectRef:
+		if n, err := ctx.PageNumber(page.ObjectNumber.Value()); err == nil && n > 0 {
+			annot.DestPage = n
+		}
+	case types.Integer:
+		if page >= 0 {
+			annot.DestPage = int(page) + 1
+		}
+	}
+}
+
+// lookupNamedDest 查找命名目标：先查文档名称字典的 /Dests 名称树，再查目录的 /Dests 字典（PDF 1.1）
+func lookupNamedDest(ctx *model.Context, name string) types.Array {
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		return nil
+	}
+
+	var value types.Object
+	if namesObj, found := catalog.Find("Names"); found {
+		if names, err := ctx.DereferenceDict(namesObj); err == nil && names != nil {
+			if tree, found := names.Find("Dests"); found {
+				value = findInNameTree(ctx, tree, name, 0)
+			}
+		}
+	}
+	if value == nil {
+		if destsObj, found := catalog.Find("Dests"); found {
+			if dests, err := ctx.DereferenceDict(destsObj); err == nil && dests != nil {
+				value, _ = dests.Find(name)
+			}
+		}
+	}
+	if value == nil {
+		return nil
+	}
+
+	// 值是目标数组，或 /D 为目标数组的字典
+	obj, err := ctx.Dereference(value)
+	if err != nil {
+		return nil
+	}
+	if dict, ok := obj.(types.Dict); ok {
+		d, found := dict.Find("D")
+		if !found {
+			return nil
+		}
+		if obj, err = ctx.Dereference(d); err != nil {
+			return nil
+		}
+	}
+	arr, _ := obj.(types.Array)
+	return arr
+}
+
+// findInNameTree 在名称树中查找键，找不到时返回 nil
+func findInNameTree(ctx *model.Context, nodeObj types.Object, key string, depth int) types.Object {
+	if depth > maxNameTreeDepth {
+		return nil
+	}
+	node, err := ctx.DereferenceDict(nodeObj)
+	if err != nil || node == nil {
+		return nil
+	}
+
+	if namesObj, found := node.Find("Names"); found {
+		if names, err := ctx.DereferenceArray(namesObj); err == nil {
+			for i := 0; i+1 < len(names); i += 2 {
+				k, err := ctx.Dereference(names[i])
+				if err != nil {
+					continue
+				}
+				if s, err := types.StringOrHexLiteral(k); err == nil && *s == key {
+					return names[i+1]
+				}
+			}
+		}
+	}
+
+	if kidsObj, found := node.Find("Kids"); found {
+		if kids, err := ctx.DereferenceArray(kidsObj); err == nil {
+			for _, kid := range kids {
+				if v := findInNameTree(ctx, kid, key, depth+1); v != nil {
+					return v
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -2,11 +2,13 @@ package gopdf
 
 import (
 	"fmt"
+	"strings"
 )
 
 // AnnotationRenderer 注释渲染器
 type AnnotationRenderer struct {
 	gopdfCtx Context
+	budget   *renderBudget // 外观流计入的页面渲染预算，nil 表示不限制
 }
 
 // NewAnnotationRenderer 创建新的注释渲染器
@@ -26,17 +28,22 @@ func (r *AnnotationRenderer) RenderAnnotation(annot *Annotation) error {
 
 	debugPrintf("[Annotation] Rendering annotation: %s\n", annot.Subtype)
 
-	// 根据子类型分发
-	switch annot.Subtype {
-	case "/Text":
+	// 有正常外观流时按 BBox→Rect 映射执行外观流，与子类型无关
+	if annot.NormalAppearance != nil {
+		return r.RenderAnnotationAppearance(annot, "N")
+	}
+
+	// 没有外观流时按子类型绘制合成外观
+	switch strings.TrimPrefix(annot.Subtype, "/") {
+	case "Text":
 		return r.RenderTextAnnotation(annot)
-	case "/Highlight":
+	case "Highlight":
 		return r.RenderHighlightAnnotation(annot)
-	case "/Link":
+	case "Link":
 		// 链接注释通常不需要视觉渲染
 		debugPrintf("[Annotation] Link annotation (no visual rendering)\n")
 		return nil
-	case "/Popup":
+	case "Popup":
 		// 弹出注释通常不需要独立渲染
 		debugPrintf("[Annotation] Popup annotation (no visual rendering)\n")
 		return nil
@@ -54,13 +61,6 @@ func (r *AnnotationRenderer) RenderTextAnnotation(annot *Annotation) error {
 	r.gopdfCtx.Save()
 	defer r.gopdfCtx.Restore()
 
-	// 如果有外观流，优先使用外观流
-	if len(annot.Appearance) > 0 {
-		debugPrintf("[Annotation] Text annotation has appearance stream\n")
-		// TODO: 渲染外观流
-		// 当前简化实现：绘制简单的图标
-	}
-
 	// 获取颜色
 	red, green, blue := annot.GetColor()
 	if red == 0 && green == 0 && blue == 0 {
@@ -97,12 +97,6 @@ func (r *AnnotationRenderer) RenderHighlightAnnotation(annot *Annotation) error
 	r.gopdfCtx.Save()
 	defer r.gopdfCtx.Restore()
 
-	// 如果有外观流，优先使用外观流
-	if len(annot.Appearance) > 0 {
-		debugPrintf("[Annotation] Highlight annotation has appearance stream\n")
-		// TODO: 渲染外观流
-	}
-
 	// 获取颜色
 	red, green, blue := annot.GetColor()
 	if red == 0 && green == 0 && blue == 0 {
@@ -134,17 +128,16 @@ func (r *AnnotationRenderer) RenderHighlightAnnotation(annot *Annotation) error
 }
 
 // RenderAnnotationAppearance 渲染注释的外观流
+// 外观流是一个表单 XObject，按 BBox（经 Matrix 变换）到注释 /Rect 的映射通过操作符管线执行；
+// 目前只加载正常外观（N）
 func (r *AnnotationRenderer) RenderAnnotationAppearance(annot *Annotation, appearanceKey string) error {
-	// 获取外观流
-	apObj, found := annot.Appearance[appearanceKey]
-	if !found {
+	if _, found := annot.Appearance[appearanceKey]; !found {
 		return fmt.Errorf("appearance %s not found", appearanceKey)
 	}
+	if appearanceKey != "N" || annot.NormalAppearance == nil {
+		return fmt.Errorf("appearance %s not loaded", appearanceKey)
+	}
 
-	// TODO: 解析并渲染外观流
-	// 外观流是一个 XObject Form，需要像渲染 Form XObject 一样处理
-	debugPrintf("[Annotation] Appearance stream rendering not yet implemented\n")
-
-	_ = apObj // 避免未使用变量警告
-	return nil
+	debugPrintf("[Annotation] Rendering %s appearance stream at %v\n", annot.Subtype, annot.Rect)
+	return renderAppearanceXObject(r.gopdfCtx, annot.NormalAppearance, annot.Rect, r.budget)
 }
@@ -0,0 +1,126 @@
+package gopdf
+
+import (
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ===== 外观流（/AP）=====
+
+// loadAppearanceStream 加载外观字典中的一个外观流（如 /AP /N）
+// apObj 可以直接是外观流，也可以是以状态名为键的子外观字典（复选框、单选按钮），
+// 此时按 state（即 /AS）选择对应的子外观；找不到对应状态时返回 nil
+func loadAppearanceStream(ctx *model.Context, apObj types.Object, state string) (*XObject, error) {
+	if indRef, ok := apObj.(types.IndirectRef); ok {
+		derefObj, err := ctx.Dereference(indRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dereference appearance: %w", err)
+		}
+		apObj = derefObj
+	}
+
+	// 子外观字典：按外观状态选择
+	if stateDict, ok := apObj.(types.Dict); ok {
+		if state == "" {
+			return nil, nil
+		}
+		sub, found := stateDict.Find(state)
+		if !found {
+			debugPrintf("[Appearance] No sub-appearance for state %s\n", state)
+			return nil, nil
+		}
+		return loadAppearanceStream(ctx, sub, "")
+	}
+
+	streamDict, ok := apObj.(types.StreamDict)
+	if !ok {
+		return nil, fmt.Errorf("appearance is not a stream")
+	}
+
+	// 外观流本身就是表单 XObject，/Subtype 可以省略
+	dict := types.Dict{}
+	for key, value := range streamDict.Dict {
+		dict[key] = value
+	}
+	if _, found := dict.Find("Subtype"); !found {
+		dict["Subtype"] = types.Name("Form")
+	}
+	streamDict.Dict = dict
+
+	resources := NewResources()
+	if err := loadXObject(ctx, "AP", streamDict, resources); err != nil {
+		return nil, err
+	}
+	xobj := resources.GetXObject("AP")
+	if xobj == nil {
+		return nil, fmt.Errorf("failed to load appearance stream")
+	}
+
+	// 外观流使用自己的资源字典（字体等）
+	if resourcesObj, found := streamDict.Find("Resources"); found {
+		xobj.Resources = NewResources()
+		if err := loadResources(ctx, resourcesObj, xobj.Resources); err != nil {
+			debugPrintf("[Appearance] Warning: failed to load appearance resources: %v\n", err)
+		}
+	}
+
+	return xobj, nil
+}
+
+// renderAppearanceXObject 将外观流绘制到注释矩形中
+// 按 PDF 32000-1 12.5.5：BBox 经 Matrix 变换后的包围盒映射到 rect [x1 y1 x2 y2]
+// budget 为页面的渲染预算，外观流的操作符和嵌套表单计入其中；nil 时使用不限制操作符数的默认预算
+func renderAppearanceXObject(gopdfCtx Context, xobj *XObject, rect []float64, budget *renderBudget) error {
+	if xobj == nil || len(rect) < 4 {
+		return nil
+	}
+
+	rx1, ry1 := min(rect[0], rect[2]), min(rect[1], rect[3])
+	rx2, ry2 := max(rect[0], rect[2]), max(rect[1], rect[3])
+
+	bx1, by1, bx2, by2 := rx1, ry1, rx2, ry2
+	if len(xobj.BBox) == 4 {
+		bx1, by1, bx2, by2 = xobj.BBox[0], xobj.BBox[1], xobj.BBox[2], xobj.BBox[3]
+	}
+	if xobj.Matrix != nil {
+		bx1, by1, bx2, by2 = transformRectBounds(xobj.Matrix, bx1, by1, bx2, by2)
+	}
+
+	bw, bh := bx2-bx1, by2-by1
+	if bw == 0 || bh == 0 {
+		return nil
+	}
+
+	gopdfCtx.Save()
+	defer gopdfCtx.Restore()
+
+	gopdfCtx.Translate(rx1, ry1)
+	gopdfCtx.Scale((rx2-rx1)/bw, (ry2-ry1)/bh)
+	gopdfCtx.Translate(-bx1, -by1)
+
+	renderCtx := NewRenderContext(gopdfCtx, rx2-rx1, ry2-ry1)
+	if budget != nil {
+		renderCtx.budget = budget
+	}
+	return renderFormXObject(renderCtx, xobj)
+}
+
+// transformRectBounds 计算矩形经矩阵变换后的轴对齐包围盒
+func transformRectBounds(m *Matrix, x1, y1, x2, y2 float64) (float64, float64, float64, float64) {
+	xs := make([]float64, 0, 4)
+	ys := make([]float64, 0, 4)
+	for _, p := range [][2]float64{{x1, y1}, {x2, y1}, {x1, y2}, {x2, y2}} {
+		x, y := MatrixTransformPoint(m, p[0], p[1])
+		xs = append(xs, x)
+		ys = append(ys, y)
+	}
+
+	minX, minY, maxX, maxY := xs[0], ys[0], xs[0], ys[0]
+	for i := 1; i < 4; i++ {
+		minX, maxX = min(minX, xs[i]), max(maxX, xs[i])
+		minY, maxY = min(minY, ys[i]), max(maxY, ys[i])
+	}
+	return minX, minY, maxX, maxY
+}
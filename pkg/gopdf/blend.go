@@ -9,16 +9,12 @@ import (
 // manipulation of the destination surface, which is not exposed by Pango.
 // This function only handles the source color's alpha based on the operator.
 func pdfBlendColor(src color.Color, op Operator) color.Color {
-	r, g, b, a := src.RGBA()
-
 	// Convert to non-premultiplied alpha for easier logic
-	alpha := float64(a) / 0xFFFF
+	// （src.RGBA() 返回预乘值，直接作为 NRGBA 分量会把颜色再预乘一次）
+	n := color.NRGBAModel.Convert(src).(color.NRGBA)
+	alpha := float64(n.A) / 0xFF
 
-	// Convert RGBA values to 8-bit
-	r8 := uint8(r >> 8)
-	g8 := uint8(g >> 8)
-	b8 := uint8(b >> 8)
-	a8 := uint8(a >> 8)
+	r8, g8, b8, a8 := n.R, n.G, n.B, n.A
 
 	switch op {
 	case OperatorClear:
@@ -7,6 +7,8 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"sync"
+	"unicode/utf16"
 
 	popplerdata "github.com/novvoo/go-pdf/poppler-data"
 )
@@ -14,6 +16,7 @@ import (
 // CIDToUnicodeMap CID 到 Unicode 的映射
 type CIDToUnicodeMap struct {
 	Mappings map[uint16]rune
+	Strings  map[uint16]string // 映射到多个字符的 CID（如连字 ﬁ → "fi"）
 	Ranges   []cidRange
 }
 
@@ -27,6 +30,7 @@ type cidRange struct {
 func NewCIDToUnicodeMap() *CIDToUnicodeMap {
 	return &CIDToUnicodeMap{
 		Mappings: make(map[uint16]rune),
+		Strings:  make(map[uint16]string),
 		Ranges:   make([]cidRange, 0),
 	}
 }
@@ -84,32 +88,20 @@ func ParseToUnicodeCMap(cmapData []byte) (*CIDToUnicodeMap, error) {
 }
 
 // parseBfChar 解析 bfchar 映射
-// 格式: <CID> <Unicode>
+// 格式: <CID> <Unicode>，目标串可以包含多个 UTF-16BE 码元（如连字 fi 映射为两个字符）
 func parseBfChar(reader *bufio.Reader, cidMap *CIDToUnicodeMap) error {
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			return err
-		}
-
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, "endbfchar") {
-			break
-		}
-
-		// 解析行: <0001> <4E00>
-		parts := strings.Fields(line)
-		if len(parts) < 2 {
-			continue
-		}
+	tokens, err := readCMapBlock(reader, "endbfchar")
+	if err != nil {
+		return err
+	}
 
-		cid := parseHexString(parts[0])
-		uni := parseHexString(parts[1])
+	for i := 0; i+1 < len(tokens); i += 2 {
+		cid := parseHexString(tokens[i])
+		uni := parseHexString(tokens[i+1])
 
 		if len(cid) >= 2 && len(uni) >= 2 {
 			cidVal := uint16(cid[0])<<8 | uint16(cid[1])
-			uniVal := rune(uni[0])<<8 | rune(uni[1])
-			cidMap.Mappings[cidVal] = uniVal
+			cidMap.setMapping(cidVal, decodeUTF16BE(uni))
 		}
 	}
 
@@ -117,45 +109,162 @@ func parseBfChar(reader *bufio.Reader, cidMap *CIDToUnicodeMap) error {
 }
 
 // parseBfRange 解析 bfrange 映射
-// 格式: <startCID> <endCID> <startUnicode>
+// 支持两种格式:
+//
+//	<startCID> <endCID> <startUnicode>      目标串最后一个码元随 CID 递增
+//	<startCID> <endCID> [<u1> <u2> ...]     每个 CID 依次对应数组中的一个目标串
 func parseBfRange(reader *bufio.Reader, cidMap *CIDToUnicodeMap) error {
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			return err
-		}
+	tokens, err := readCMapBlock(reader, "endbfrange")
+	if err != nil {
+		return err
+	}
 
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, "endbfrange") {
-			break
+	for i := 0; i+2 < len(tokens); {
+		startCID := parseHexString(tokens[i])
+		endCID := parseHexString(tokens[i+1])
+		i += 2
+
+		// 数组形式: 收集到 ] 为止
+		var dests []string
+		isArray := tokens[i] == "["
+		if isArray {
+			i++
+			for i < len(tokens) && tokens[i] != "]" {
+				dests = append(dests, tokens[i])
+				i++
+			}
+			i++ // 跳过 ]
+		} else {
+			dests = []string{tokens[i]}
+			i++
 		}
 
-		// 解析行: <0001> <0010> <4E00>
-		parts := strings.Fields(line)
-		if len(parts) < 3 {
+		if len(startCID) < 2 || len(endCID) < 2 {
+			continue
+		}
+		startCIDVal := uint16(startCID[0])<<8 | uint16(startCID[1])
+		endCIDVal := uint16(endCID[0])<<8 | uint16(endCID[1])
+		if endCIDVal < startCIDVal {
 			continue
 		}
 
-		startCID := parseHexString(parts[0])
-		endCID := parseHexString(parts[1])
-		startUni := parseHexString(parts[2])
-
-		if len(startCID) >= 2 && len(endCID) >= 2 && len(startUni) >= 2 {
-			startCIDVal := uint16(startCID[0])<<8 | uint16(startCID[1])
-			endCIDVal := uint16(endCID[0])<<8 | uint16(endCID[1])
-			startUniVal := rune(startUni[0])<<8 | rune(startUni[1])
+		if isArray {
+			for j, dest := range dests {
+				cid := int(startCIDVal) + j
+				if cid > int(endCIDVal) {
+					break
+				}
+				if uni := parseHexString(dest); len(uni) >= 2 {
+					cidMap.setMapping(uint16(cid), decodeUTF16BE(uni))
+				}
+			}
+			continue
+		}
 
+		startUni := parseHexString(dests[0])
+		if len(startUni) < 2 {
+			continue
+		}
+		runes := []rune(decodeUTF16BE(startUni))
+		if len(runes) == 1 {
 			cidMap.Ranges = append(cidMap.Ranges, cidRange{
 				StartCID: startCIDVal,
 				EndCID:   endCIDVal,
-				StartUni: startUniVal,
+				StartUni: runes[0],
 			})
+			continue
+		}
+
+		// 多字符起始串：逐个展开，最后一个字符随 CID 递增
+		last := len(startUni) - 1
+		for cid := int(startCIDVal); cid <= int(endCIDVal); cid++ {
+			dest := append([]byte(nil), startUni...)
+			dest[last] += byte(cid - int(startCIDVal))
+			cidMap.setMapping(uint16(cid), decodeUTF16BE(dest))
 		}
 	}
 
 	return nil
 }
 
+// readCMapBlock 读取 begin…end 块内的全部记号，直到遇到 endKeyword
+// 记号为 <hex>、[、] 或其他单词；数组可以跨行
+func readCMapBlock(reader *bufio.Reader, endKeyword string) ([]string, error) {
+	var tokens []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return nil, err
+		}
+
+		for _, tok := range tokenizeCMapLine(line) {
+			if tok == endKeyword {
+				return tokens, nil
+			}
+			tokens = append(tokens, tok)
+		}
+
+		if err == io.EOF {
+			return nil, err
+		}
+	}
+}
+
+// tokenizeCMapLine 把一行 CMap 文本拆分为记号
+func tokenizeCMapLine(line string) []string {
+	var tokens []string
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == '<':
+			end := strings.IndexByte(line[i:], '>')
+			if end < 0 {
+				tokens = append(tokens, line[i:])
+				return tokens
+			}
+			tokens = append(tokens, line[i:i+end+1])
+			i += end + 1
+		case c == '[' || c == ']':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '%':
+			return tokens
+		case isPDFWhitespace(c):
+			i++
+		default:
+			start := i
+			for i < len(line) && !isPDFWhitespace(line[i]) && !strings.ContainsRune("<>[]%", rune(line[i])) {
+				i++
+			}
+			tokens = append(tokens, line[start:i])
+		}
+	}
+	return tokens
+}
+
+// decodeUTF16BE 把 ToUnicode 目标串（UTF-16BE，可含代理对）解码为字符串
+func decodeUTF16BE(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// setMapping 记录单个 CID 的映射；单个字符存入 Mappings，多字符（连字等）存入 Strings
+func (m *CIDToUnicodeMap) setMapping(cid uint16, s string) {
+	runes := []rune(s)
+	switch len(runes) {
+	case 0:
+		return
+	case 1:
+		m.Mappings[cid] = runes[0]
+		delete(m.Strings, cid)
+	default:
+		m.Strings[cid] = s
+		delete(m.Mappings, cid)
+	}
+}
+
 // parseHexString 解析十六进制字符串 <ABCD> -> []byte{0xAB, 0xCD}
 func parseHexString(s string) []byte {
 	s = strings.Trim(s, "<>")
@@ -223,6 +332,18 @@ func (m *CIDToUnicodeMap) MapCIDToUnicode(cid uint16) (rune, bool) {
 	return 0, false
 }
 
+// MapCIDToString 将 CID 映射到 Unicode 文本
+// 与 MapCIDToUnicode 不同，这里会返回多字符映射（连字等）
+func (m *CIDToUnicodeMap) MapCIDToString(cid uint16) (string, bool) {
+	if s, ok := m.Strings[cid]; ok {
+		return s, true
+	}
+	if uni, ok := m.MapCIDToUnicode(cid); ok {
+		return string(uni), true
+	}
+	return "", false
+}
+
 // isValidUnicodeRuneForCID 验证Unicode码点是否有效
 func isValidUnicodeRuneForCID(r rune) bool {
 	// 检查是否是有效的UTF-8 rune
@@ -252,8 +373,8 @@ func (m *CIDToUnicodeMap) MapCIDsToUnicode(cids []uint16) string {
 	var result strings.Builder
 
 	for _, cid := range cids {
-		if uni, ok := m.MapCIDToUnicode(cid); ok {
-			result.WriteRune(uni)
+		if text, ok := m.MapCIDToString(cid); ok {
+			result.WriteString(text)
 		} else {
 			// 无法映射，使用占位符
 			result.WriteRune('□')
@@ -263,9 +384,47 @@ func (m *CIDToUnicodeMap) MapCIDsToUnicode(cids []uint16) string {
 	return result.String()
 }
 
+// cidRegistryEntry 一个字符集合的加载结果，once 保证并发请求只解析一次
+type cidRegistryEntry struct {
+	once   sync.Once
+	cidMap *CIDToUnicodeMap
+	err    error
+}
+
+// cidRegistryCache 按字符集合缓存的 poppler-data 映射，在所有读取器之间共享
+var (
+	cidRegistryMu    sync.Mutex
+	cidRegistryCache = make(map[string]*cidRegistryEntry)
+)
+
 // LoadCIDToUnicodeFromRegistry 从 poppler-data 加载 CID 到 Unicode 映射
 // registry: Adobe-GB1, Adobe-CNS1, Adobe-Japan1, Adobe-Korea1
+// 结果按 registry 缓存在包级别，批量处理多个文件时只解析一次；
+// 返回的映射由所有调用方共享，调用方不能修改它
 func LoadCIDToUnicodeFromRegistry(registry string) (*CIDToUnicodeMap, error) {
+	cidRegistryMu.Lock()
+	entry, ok := cidRegistryCache[registry]
+	if !ok {
+		entry = &cidRegistryEntry{}
+		cidRegistryCache[registry] = entry
+	}
+	cidRegistryMu.Unlock()
+
+	entry.once.Do(func() {
+		entry.cidMap, entry.err = loadCIDToUnicodeFromRegistry(registry)
+	})
+	return entry.cidMap, entry.err
+}
+
+// ClearCIDRegistryCache 清空包级别的 CID 映射缓存（如需要释放内存时）
+func ClearCIDRegistryCache() {
+	cidRegistryMu.Lock()
+	defer cidRegistryMu.Unlock()
+	clear(cidRegistryCache)
+}
+
+// loadCIDToUnicodeFromRegistry 读取并解析 poppler-data 中的映射文件，不使用缓存
+func loadCIDToUnicodeFromRegistry(registry string) (*CIDToUnicodeMap, error) {
 	fs := popplerdata.GetFS()
 
 	// 构建文件路径
@@ -286,29 +445,15 @@ func LoadCIDToUnicodeFromRegistry(registry string) (*CIDToUnicodeMap, error) {
 	// 解析映射
 	cidMap := NewCIDToUnicodeMap()
 
-	// poppler-data 的 cidToUnicode 文件格式是简单的文本格式
-	// 每行: CID Unicode
+	// poppler-data 的 cidToUnicode 文件每行一个十六进制 Unicode 码点，行号（从 0 开始）即 CID；
+	// 0000 表示该 CID 没有映射
 	scanner := bufio.NewScanner(bytes.NewReader(content))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
+	for cid := 0; scanner.Scan() && cid <= 0xFFFF; cid++ {
+		uni, err := strconv.ParseUint(strings.TrimSpace(scanner.Text()), 16, 32)
+		if err != nil || uni == 0 || !isValidUnicodeRuneForCID(rune(uni)) {
 			continue
 		}
-
-		parts := strings.Fields(line)
-		if len(parts) >= 2 {
-			cid, err := strconv.ParseUint(parts[0], 10, 16)
-			if err != nil {
-				continue
-			}
-
-			uni, err := strconv.ParseInt(parts[1], 0, 32)
-			if err != nil {
-				continue
-			}
-
-			cidMap.Mappings[uint16(cid)] = rune(uni)
-		}
+		cidMap.Mappings[uint16(cid)] = rune(uni)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -99,22 +99,17 @@ func (cs *CalRGBColorSpace) ConvertToRGB(components []float64) (r, g, b float64,
 	b_val := math.Pow(clamp01(components[1]), cs.getGamma(1))
 	c := math.Pow(clamp01(components[2]), cs.getGamma(2))
 
-	// 应用矩阵变换（如果有）
-	if len(cs.Matrix) == 9 {
-		x := cs.Matrix[0]*a + cs.Matrix[1]*b_val + cs.Matrix[2]*c
-		y := cs.Matrix[3]*a + cs.Matrix[4]*b_val + cs.Matrix[5]*c
-		z := cs.Matrix[6]*a + cs.Matrix[7]*b_val + cs.Matrix[8]*c
-
-		// XYZ 到 RGB 转换（简化版）
-		r = 3.2406*x - 1.5372*y - 0.4986*z
-		g = -0.9689*x + 1.8758*y + 0.0415*z
-		b = 0.0557*x - 0.2040*y + 1.0570*z
-
-		return clamp01(r), clamp01(g), clamp01(b), nil
+	// Matrix 按列给出 [XA YA ZA XB YB ZB XC YC ZC]，默认是单位矩阵
+	m := cs.Matrix
+	if len(m) != 9 {
+		m = []float64{1, 0, 0, 0, 1, 0, 0, 0, 1}
 	}
+	x := m[0]*a + m[3]*b_val + m[6]*c
+	y := m[1]*a + m[4]*b_val + m[7]*c
+	z := m[2]*a + m[5]*b_val + m[8]*c
 
-	// 没有矩阵，直接使用 gamma 校正后的值
-	return clamp01(a), clamp01(b_val), clamp01(c), nil
+	r, g, b = xyzToSRGB(x, y, z, cs.WhitePoint)
+	return r, g, b, nil
 }
 
 func (cs *CalRGBColorSpace) ConvertToRGBA(components []float64, alpha float64) (r, g, b, a float64, err error) {
@@ -144,9 +139,13 @@ func (cs *CalGrayColorSpace) ConvertToRGB(components []float64) (r, g, b float64
 		return 0, 0, 0, fmt.Errorf("calGray requires 1 component, got %d", len(components))
 	}
 
-	// 应用 gamma 校正
-	gray := math.Pow(clamp01(components[0]), cs.Gamma)
-	return gray, gray, gray, nil
+	// A^G 是相对白点的亮度 Y，白点色度下的灰色映射为 sRGB 中性灰
+	gamma := cs.Gamma
+	if gamma <= 0 {
+		gamma = 1.0
+	}
+	gray := srgbGamma(math.Pow(clamp01(components[0]), gamma))
+	return clamp01(gray), clamp01(gray), clamp01(gray), nil
 }
 
 func (cs *CalGrayColorSpace) ConvertToRGBA(components []float64, alpha float64) (r, g, b, a float64, err error) {
@@ -315,6 +314,66 @@ func (cs *IndexedColorSpace) ConvertToRGBA(components []float64, alpha float64)
 	return r, g, b, clamp01(alpha), err
 }
 
+// DeviceNColorSpace Separation 或 DeviceN 颜色空间
+// tint 值经 tint 变换函数转换为备用颜色空间的分量；Separation 只有一个着色剂
+type DeviceNColorSpace struct {
+	Separation    bool         // 是否为 Separation 颜色空间
+	Names         []string     // 着色剂名称
+	Alternate     ColorSpace   // 备用颜色空间
+	TintTransform *PDFFunction // tint 变换函数
+}
+
+func (cs *DeviceNColorSpace) GetName() string {
+	if cs.Separation {
+		return "Separation"
+	}
+	return "DeviceN"
+}
+
+func (cs *DeviceNColorSpace) GetNumComponents() int { return len(cs.Names) }
+
+func (cs *DeviceNColorSpace) ConvertToRGB(components []float64) (r, g, b float64, err error) {
+	if len(components) < len(cs.Names) {
+		return 0, 0, 0, fmt.Errorf("%s requires %d components", cs.GetName(), len(cs.Names))
+	}
+	if cs.Separation && cs.Names[0] == "All" {
+		// All（套准色）：所有印版都使用该色调，显示为接近黑色的灰度，不使用 tint 变换
+		t := clamp01(components[0])
+		r, g, b = cmykToRGB(t, t, t, t)
+		return r, g, b, nil
+	}
+	if cs.Alternate == nil || cs.TintTransform == nil {
+		return 0, 0, 0, fmt.Errorf("%s has no alternate color space or tint transform", cs.GetName())
+	}
+	return cs.Alternate.ConvertToRGB(cs.TintTransform.Eval(components[:len(cs.Names)]))
+}
+
+// marksNothing 判断颜色空间是否不产生任何标记：着色剂全部为 None 的 Separation/DeviceN
+func (cs *DeviceNColorSpace) marksNothing() bool {
+	if len(cs.Names) == 0 {
+		return false
+	}
+	for _, name := range cs.Names {
+		if name != "None" {
+			return false
+		}
+	}
+	return true
+}
+
+// colorSpaceMarksNothing 判断使用该颜色空间的绘制是否不产生标记（Separation/DeviceN 的 None 着色剂）
+func colorSpaceMarksNothing(cs ColorSpace) bool {
+	if devN, ok := cs.(*DeviceNColorSpace); ok {
+		return devN.marksNothing()
+	}
+	return false
+}
+
+func (cs *DeviceNColorSpace) ConvertToRGBA(components []float64, alpha float64) (r, g, b, a float64, err error) {
+	r, g, b, err = cs.ConvertToRGB(components)
+	return r, g, b, clamp01(alpha), err
+}
+
 // 辅助函数
 
 func clamp01(v float64) float64 {
@@ -345,6 +404,35 @@ func labInvF(t float64) float64 {
 	return 3 * delta * delta * (t - 4.0/29.0)
 }
 
+// xyzToSRGB 把相对给定白点的 XYZ 转换为 sRGB（0-1）
+// 白点与 D65 不同时先用 Bradford 变换做色适应，whitePoint 为空时按 D65 处理
+func xyzToSRGB(x, y, z float64, whitePoint []float64) (r, g, b float64) {
+	const xn, yn, zn = 0.95047, 1.0, 1.08883
+	if len(whitePoint) >= 3 && whitePoint[1] > 0 &&
+		(math.Abs(whitePoint[0]-xn) > 1e-4 || math.Abs(whitePoint[1]-yn) > 1e-4 || math.Abs(whitePoint[2]-zn) > 1e-4) {
+		// Bradford 锥体响应
+		cone := func(x, y, z float64) (float64, float64, float64) {
+			return 0.8951*x + 0.2664*y - 0.1614*z,
+				-0.7502*x + 1.7135*y + 0.0367*z,
+				0.0389*x - 0.0685*y + 1.0296*z
+		}
+		sr, sg, sb := cone(whitePoint[0], whitePoint[1], whitePoint[2])
+		dr, dg, db := cone(xn, yn, zn)
+		cr, cg, cb := cone(x, y, z)
+		if sr != 0 && sg != 0 && sb != 0 {
+			cr, cg, cb = cr*dr/sr, cg*dg/sg, cb*db/sb
+			x = 0.9869929*cr - 0.1470543*cg + 0.1599627*cb
+			y = 0.4323053*cr + 0.5183603*cg + 0.0492912*cb
+			z = -0.0085287*cr + 0.0400428*cg + 0.9684867*cb
+		}
+	}
+
+	r = 3.2406*x - 1.5372*y - 0.4986*z
+	g = -0.9689*x + 1.8758*y + 0.0415*z
+	b = 0.0557*x - 0.2040*y + 1.0570*z
+	return clamp01(srgbGamma(clamp01(r))), clamp01(srgbGamma(clamp01(g))), clamp01(srgbGamma(clamp01(b)))
+}
+
 func srgbGamma(v float64) float64 {
 	if v <= 0.0031308 {
 		return 12.92 * v
@@ -0,0 +1,340 @@
+package gopdf
+
+import (
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// loadColorSpace 加载 ColorSpace 资源
+func loadColorSpace(ctx *model.Context, csName string, csObj types.Object, resources *Resources) error {
+	cs, err := parseColorSpaceObject(ctx, csObj, 0)
+	if err != nil {
+		return err
+	}
+
+	// 存储到资源
+	resources.SetColorSpace(csName, cs)
+	debugPrintf("✓ Loaded ColorSpace %s: %s (%d components)\n", csName, cs.GetName(), cs.GetNumComponents())
+	return nil
+}
+
+// parseColorSpaceObject 将 PDF 颜色空间对象（名称或数组）解析为 ColorSpace
+func parseColorSpaceObject(ctx *model.Context, csObj types.Object, depth int) (ColorSpace, error) {
+	// 防止循环引用
+	if depth > 10 {
+		return nil, fmt.Errorf("color space nesting too deep")
+	}
+
+	// 解引用
+	if indRef, ok := csObj.(types.IndirectRef); ok {
+		derefObj, err := ctx.Dereference(indRef)
+		if err != nil {
+			return nil, err
+		}
+		csObj = derefObj
+	}
+
+	switch v := csObj.(type) {
+	case types.Name:
+		return deviceColorSpaceByName(v.Value())
+	case types.Array:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("empty color space array")
+		}
+		family, ok := v[0].(types.Name)
+		if !ok {
+			return nil, fmt.Errorf("invalid color space family: %T", v[0])
+		}
+
+		switch family.Value() {
+		case "ICCBased":
+			return parseICCBasedColorSpace(ctx, v, depth)
+		case "Indexed", "I":
+			return parseIndexedColorSpace(ctx, v, depth)
+		case "Separation", "DeviceN":
+			return parseDeviceNColorSpace(ctx, v, depth)
+		case "CalRGB":
+			cs := &CalRGBColorSpace{}
+			if dict := colorSpaceParamsDict(ctx, v); dict != nil {
+				cs.WhitePoint = getNumberArray(ctx, dict, "WhitePoint")
+				cs.BlackPoint = getNumberArray(ctx, dict, "BlackPoint")
+				cs.Gamma = getNumberArray(ctx, dict, "Gamma")
+				cs.Matrix = getNumberArray(ctx, dict, "Matrix")
+			}
+			return cs, nil
+		case "CalGray":
+			cs := &CalGrayColorSpace{Gamma: 1.0}
+			if dict := colorSpaceParamsDict(ctx, v); dict != nil {
+				cs.WhitePoint = getNumberArray(ctx, dict, "WhitePoint")
+				cs.BlackPoint = getNumberArray(ctx, dict, "BlackPoint")
+				if gammaObj, found := dict.Find("Gamma"); found {
+					if gamma, ok := getNumber(gammaObj); ok {
+						cs.Gamma = gamma
+					}
+				}
+			}
+			return cs, nil
+		case "Lab":
+			cs := &LabColorSpace{}
+			if dict := colorSpaceParamsDict(ctx, v); dict != nil {
+				cs.WhitePoint = getNumberArray(ctx, dict, "WhitePoint")
+				cs.BlackPoint = getNumberArray(ctx, dict, "BlackPoint")
+				cs.Range = getNumberArray(ctx, dict, "Range")
+			}
+			return cs, nil
+		default:
+			// 单元素数组，例如 [/DeviceRGB]
+			if len(v) == 1 {
+				return deviceColorSpaceByName(family.Value())
+			}
+			return nil, fmt.Errorf("unsupported color space family: %s", family.Value())
+		}
+	default:
+		return nil, fmt.Errorf("invalid color space object: %T", csObj)
+	}
+}
+
+// deviceColorSpaceByName 根据名称（含内联图像缩写）返回设备颜色空间
+func deviceColorSpaceByName(name string) (ColorSpace, error) {
+	switch name {
+	case "DeviceRGB", "RGB":
+		return &DeviceRGBColorSpace{}, nil
+	case "DeviceGray", "G":
+		return &DeviceGrayColorSpace{}, nil
+	case "DeviceCMYK", "CMYK":
+		return &DeviceCMYKColorSpace{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported color space name: %s", name)
+	}
+}
+
+// parseICCBasedColorSpace 解析 [/ICCBased stream]
+func parseICCBasedColorSpace(ctx *model.Context, arr types.Array, depth int) (ColorSpace, error) {
+	if len(arr) < 2 {
+		return nil, fmt.Errorf("ICCBased color space missing stream")
+	}
+
+	streamObj := arr[1]
+	if indRef, ok := streamObj.(types.IndirectRef); ok {
+		derefObj, err := ctx.Dereference(indRef)
+		if err != nil {
+			return nil, err
+		}
+		streamObj = derefObj
+	}
+
+	streamDict, ok := streamObj.(types.StreamDict)
+	if !ok {
+		return nil, fmt.Errorf("ICCBased stream is not a stream: %T", streamObj)
+	}
+
+	cs := &ICCBasedColorSpace{}
+	if nObj, found := streamDict.Find("N"); found {
+		if n, ok := getInteger(nObj); ok {
+			cs.NumComponents = int(n)
+		}
+	}
+
+	if altObj, found := streamDict.Find("Alternate"); found {
+		if alt, err := parseColorSpaceObject(ctx, altObj, depth+1); err == nil {
+			cs.Alternate = alt
+		}
+	}
+
+	cs.Range = getNumberArray(ctx, streamDict.Dict, "Range")
+
+	// N 缺失时根据备用颜色空间推断
+	if cs.NumComponents == 0 {
+		if cs.Alternate != nil {
+			cs.NumComponents = cs.Alternate.GetNumComponents()
+		} else {
+			return nil, fmt.Errorf("ICCBased color space missing /N")
+		}
+	}
+
+	if decoded, _, err := ctx.DereferenceStreamDict(streamDict); err == nil && decoded != nil {
+		cs.Metadata = decoded.Content
+	}
+
+	return cs, nil
+}
+
+// parseIndexedColorSpace 解析 [/Indexed base hival lookup]
+func parseIndexedColorSpace(ctx *model.Context, arr types.Array, depth int) (ColorSpace, error) {
+	if len(arr) < 4 {
+		return nil, fmt.Errorf("indexed color space requires 4 elements, got %d", len(arr))
+	}
+
+	base, err := parseColorSpaceObject(ctx, arr[1], depth+1)
+	if err != nil {
+		return nil, fmt.Errorf("invalid indexed base color space: %w", err)
+	}
+
+	hival, ok := getInteger(arr[2])
+	if !ok {
+		return nil, fmt.Errorf("invalid indexed hival: %T", arr[2])
+	}
+
+	lookupObj := arr[3]
+	if indRef, ok := lookupObj.(types.IndirectRef); ok {
+		derefObj, err := ctx.Dereference(indRef)
+		if err != nil {
+			return nil, err
+		}
+		lookupObj = derefObj
+	}
+
+	var lookup []byte
+	switch l := lookupObj.(type) {
+	case types.StringLiteral:
+		if b, err := types.Unescape(l.Value()); err == nil {
+			lookup = b
+		} else {
+			lookup = []byte(l.Value())
+		}
+	case types.HexLiteral:
+		if b, err := l.Bytes(); err == nil {
+			lookup = b
+		}
+	case types.StreamDict:
+		if decoded, _, err := ctx.DereferenceStreamDict(l); err == nil && decoded != nil {
+			lookup = decoded.Content
+		}
+	}
+
+	return &IndexedColorSpace{
+		Base:   base,
+		HiVal:  int(hival),
+		Lookup: lookup,
+	}, nil
+}
+
+// parseDeviceNColorSpace 解析 [/Separation name alternate tintTransform]
+// 或 [/DeviceN names alternate tintTransform attributes]
+func parseDeviceNColorSpace(ctx *model.Context, arr types.Array, depth int) (ColorSpace, error) {
+	if len(arr) < 4 {
+		return nil, fmt.Errorf("%s color space requires 4 elements, got %d", arr[0], len(arr))
+	}
+
+	cs := &DeviceNColorSpace{Separation: arr[0].(types.Name).Value() == "Separation"}
+	if cs.Separation {
+		name, ok := arr[1].(types.Name)
+		if !ok {
+			return nil, fmt.Errorf("invalid Separation colorant name: %T", arr[1])
+		}
+		cs.Names = []string{name.Value()}
+	} else {
+		namesObj := arr[1]
+		if indRef, ok := namesObj.(types.IndirectRef); ok {
+			derefObj, err := ctx.Dereference(indRef)
+			if err != nil {
+				return nil, err
+			}
+			namesObj = derefObj
+		}
+		names, ok := namesObj.(types.Array)
+		if !ok || len(names) == 0 {
+			return nil, fmt.Errorf("invalid DeviceN colorant names: %T", namesObj)
+		}
+		for _, n := range names {
+			if name, ok := n.(types.Name); ok {
+				cs.Names = append(cs.Names, name.Value())
+			}
+		}
+	}
+
+	alt, err := parseColorSpaceObject(ctx, arr[2], depth+1)
+	if err != nil {
+		return nil, fmt.Errorf("%s alternate color space: %w", cs.GetName(), err)
+	}
+	cs.Alternate = alt
+
+	fn, err := loadPDFFunction(ctx, arr[3])
+	if err != nil {
+		return nil, fmt.Errorf("%s tint transform: %w", cs.GetName(), err)
+	}
+	cs.TintTransform = fn
+	return cs, nil
+}
+
+// colorSpaceParamsDict 获取 [/CalRGB dict] 等形式中的参数字典
+func colorSpaceParamsDict(ctx *model.Context, arr types.Array) types.Dict {
+	if len(arr) < 2 {
+		return nil
+	}
+	obj := arr[1]
+	if indRef, ok := obj.(types.IndirectRef); ok {
+		derefObj, err := ctx.Dereference(indRef)
+		if err != nil {
+			return nil
+		}
+		obj = derefObj
+	}
+	dict, _ := obj.(types.Dict)
+	return dict
+}
+
+// getNumberArray 读取字典中的数值数组
+func getNumberArray(ctx *model.Context, dict types.Dict, key string) []float64 {
+	obj, found := dict.Find(key)
+	if !found {
+		return nil
+	}
+	if indRef, ok := obj.(types.IndirectRef); ok {
+		derefObj, err := ctx.Dereference(indRef)
+		if err != nil {
+			return nil
+		}
+		obj = derefObj
+	}
+	arr, ok := obj.(types.Array)
+	if !ok {
+		return nil
+	}
+	result := make([]float64, 0, len(arr))
+	for _, item := range arr {
+		if num, ok := getNumber(item); ok {
+			result = append(result, num)
+		}
+	}
+	return result
+}
+
+// paletteToRGB 将 Indexed 颜色空间的查找表转换为 RGB 三元组调色板
+// decodeIndexedColorSpace 约定调色板为每条目 3 字节 RGB
+func paletteToRGB(cs *IndexedColorSpace) []byte {
+	if cs == nil || cs.Base == nil {
+		return nil
+	}
+
+	numComponents := cs.Base.GetNumComponents()
+	if numComponents <= 0 {
+		return nil
+	}
+
+	// 基础颜色空间本身就是 RGB，直接复用查找表
+	if _, ok := cs.Base.(*DeviceRGBColorSpace); ok {
+		return cs.Lookup
+	}
+
+	entries := len(cs.Lookup) / numComponents
+	if cs.HiVal+1 < entries {
+		entries = cs.HiVal + 1
+	}
+
+	palette := make([]byte, 0, entries*3)
+	components := make([]float64, numComponents)
+	for i := 0; i < entries; i++ {
+		for c := 0; c < numComponents; c++ {
+			components[c] = float64(cs.Lookup[i*numComponents+c]) / 255.0
+		}
+		r, g, b, err := cs.Base.ConvertToRGB(components)
+		if err != nil {
+			r, g, b = 0, 0, 0
+		}
+		palette = append(palette, uint8(clamp01(r)*255+0.5), uint8(clamp01(g)*255+0.5), uint8(clamp01(b)*255+0.5))
+	}
+	return palette
+}
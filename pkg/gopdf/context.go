@@ -1,7 +1,6 @@
 package gopdf
 
 import (
-	"fmt"
 	"image"
 	"image/color"
 	"math"
@@ -49,6 +48,10 @@ type context struct {
 
 	// Drawing context for backend
 	gc *rasterContext
+
+	// Composite in linear light (see SetGammaCorrectBlending); applies to
+	// every raster context the context draws through, including groups
+	gammaCorrect bool
 }
 
 // graphicsState represents the graphics state that can be saved/restored
@@ -95,6 +98,9 @@ type clipRegion struct {
 	tolerance float64
 	antialias Antialias
 
+	// 光栅化后的裁剪遮罩（设备空间，已与外层裁剪求交）
+	mask *image.Alpha
+
 	// Previous clip in stack
 	prev *clipRegion
 }
@@ -120,6 +126,16 @@ type point struct {
 
 // NewContext creates a new drawing context for the given surface
 func NewContext(target Surface) Context {
+	if target == nil {
+		return &context{
+			refCount: 1,
+			status:   StatusNullPointer,
+			userData: make(map[*UserDataKey]interface{}),
+			gstate:   newGraphicsState(),
+			path:     &path{data: make([]pathOp, 0)},
+		}
+	}
+
 	ctx := &context{
 		refCount: 1,
 		target:   target.Reference(),
@@ -155,6 +171,9 @@ func NewContext(target Surface) Context {
 		dummyImage := image.NewRGBA(image.Rect(0, 0, int(s.width), int(s.height)))
 		ctx.gc = newRasterContext(dummyImage)
 		// Store a reference in the surface for Finish()
+	case *recordingSurface:
+		// 记录表面只保存操作，不需要真实的光栅结果：使用最小的占位图像
+		ctx.gc = newRasterContext(image.NewRGBA(image.Rect(0, 0, 1, 1)))
 	}
 
 	// Initialize default state
@@ -195,6 +214,17 @@ func (c *context) Destroy() {
 }
 
 func (c *context) destroyConcrete() {
+	// Unwind groups that were pushed but never popped, so the original target
+	// is the one released below
+	for gs := c.gstate; gs != nil; gs = gs.next {
+		if gs.groupSurface != nil {
+			gs.groupSurface.Surface.Destroy()
+			c.target = gs.groupSurface.originalTarget
+			c.gc = gs.groupSurface.originalGC
+			gs.groupSurface = nil
+		}
+	}
+
 	if c.target != nil {
 		c.target.Destroy()
 	}
@@ -259,21 +289,22 @@ func (c *context) Save() error {
 
 	// Create a copy of current state
 	newState := &graphicsState{
-		source:       c.gstate.source.Reference(),
-		operator:     c.gstate.operator,
-		tolerance:    c.gstate.tolerance,
-		antialias:    c.gstate.antialias,
-		fillRule:     c.gstate.fillRule,
-		lineWidth:    c.gstate.lineWidth,
-		lineCap:      c.gstate.lineCap,
-		lineJoin:     c.gstate.lineJoin,
-		miterLimit:   c.gstate.miterLimit,
-		matrix:       c.gstate.matrix,
-		fontMatrix:   c.gstate.fontMatrix,
-		fontOptions:  c.gstate.fontOptions, // TODO: Copy font options
-		clip:         c.gstate.clip,        // Clip is part of the graphics state
-		next:         c.gstate,
-		groupSurface: c.gstate.groupSurface, // Copy group surface reference
+		source:      c.gstate.source.Reference(),
+		operator:    c.gstate.operator,
+		tolerance:   c.gstate.tolerance,
+		antialias:   c.gstate.antialias,
+		fillRule:    c.gstate.fillRule,
+		lineWidth:   c.gstate.lineWidth,
+		lineCap:     c.gstate.lineCap,
+		lineJoin:    c.gstate.lineJoin,
+		miterLimit:  c.gstate.miterLimit,
+		matrix:      c.gstate.matrix,
+		fontMatrix:  c.gstate.fontMatrix,
+		fontOptions: c.gstate.fontOptions, // TODO: Copy font options
+		clip:        c.gstate.clip,        // Clip is part of the graphics state
+		next:        c.gstate,
+		// groupSurface 只属于 PushGroup 保存的那一层状态，嵌套的 Save/Restore
+		// 不能继承它，否则组内的 Restore 会提前切回原目标
 	}
 
 	// Copy dash array
@@ -424,6 +455,20 @@ func (c *context) GetAntialias() Antialias {
 	return c.gstate.antialias
 }
 
+func (c *context) SetGammaCorrectBlending(enabled bool) {
+	if c.status != StatusSuccess {
+		return
+	}
+	c.gammaCorrect = enabled
+	if c.gc != nil {
+		c.gc.linearBlend = enabled
+	}
+}
+
+func (c *context) GetGammaCorrectBlending() bool {
+	return c.gammaCorrect
+}
+
 // Fill properties
 func (c *context) SetFillRule(fillRule FillRule) {
 	if c.status != StatusSuccess {
@@ -738,8 +783,15 @@ func (c *context) applyStateToPango() {
 	c.gc.SetLineWidth(c.gstate.lineWidth)
 	c.gc.SetLineCap(c.gstate.lineCap)
 	c.gc.SetLineJoin(c.gstate.lineJoin)
+	c.gc.SetMiterLimit(c.gstate.miterLimit)
 	c.gc.SetLineDash(c.gstate.dash, c.gstate.dashOffset)
 
+	// Clip mask
+	c.gc.clip = nil
+	if c.gstate.clip != nil {
+		c.gc.clip = c.gstate.clip.mask
+	}
+
 	// Transformation matrix
 	m := c.gstate.matrix
 	c.gc.SetMatrixTransform([6]float64{
@@ -748,6 +800,13 @@ func (c *context) applyStateToPango() {
 		m.X0, m.Y0,
 	})
 
+	// Blend mode
+	c.gc.SetOperator(c.gstate.operator)
+
+	// Antialiasing and fill rule
+	c.gc.aliased = c.gstate.antialias == AntialiasNone
+	c.gc.fillRule = c.gstate.fillRule
+
 	// Source pattern
 	// Check for gradient patterns first (using concrete types)
 	if pattern, ok := c.gstate.source.(*linearGradient); ok {
@@ -825,33 +884,42 @@ func (c *context) PushGroupWithContent(content Content) {
 		return
 	}
 
-	// 1. Save current state
-	c.Save()
-
-	// 2. Create a new temporary ImageSurface as the new target
-	// We use the current target's dimensions for the temporary surface.
+	// 1. The group surface mirrors the current target's dimensions, so only
+	// image targets can host a group
 	imgSurface, ok := c.target.(ImageSurface)
 	if !ok {
 		c.status = StatusSurfaceTypeMismatch
 		return
 	}
 
-	newSurface := NewImageSurface(FormatARGB32, imgSurface.GetWidth(), imgSurface.GetHeight())
+	// 2. Save current state; the pushed state owns the group
+	if err := c.Save(); err != nil {
+		return
+	}
 
-	// 3. Create a new context for the new surface
-	newCtx := NewContext(newSurface)
+	// 3. Create a new temporary ImageSurface as the new target
+	// The group surface starts fully transparent (isolated backdrop)
+	newSurface := NewImageSurface(FormatARGB32, imgSurface.GetWidth(), imgSurface.GetHeight())
+	groupImage, ok := newSurface.(ImageSurface).GetGoImage().(*image.RGBA)
+	if !ok {
+		newSurface.Destroy()
+		c.Restore()
+		c.status = StatusNoMemory
+		return
+	}
 
-	// 4. Replace current context's target and gc with the new one
-	c.target = newSurface
-	if ctxImpl, ok := newCtx.(*context); ok {
-		c.gc = ctxImpl.gc
+	// 4. Store the old target and gc in the pushed state so that the matching
+	// Restore (called by PopGroup) switches back to them
+	c.gstate.groupSurface = &GroupSurface{
+		Surface:        newSurface,
+		originalTarget: c.target,
+		originalGC:     c.gc,
 	}
 
-	// 5. Store the old target and gc in the saved state (for PopGroup)
-	// We'll use the gstate.next to store the old target/gc temporarily.
-	// This is a simplification and not a true gopdf group implementation.
-	// A proper implementation would require a dedicated group stack.
-	// For now, we'll just rely on the Save/Restore mechanism.
+	// 5. Redirect drawing to the group surface
+	c.target = newSurface
+	c.gc = newRasterContext(groupImage)
+	c.gc.linearBlend = c.gammaCorrect
 }
 
 func (c *context) PopGroup() Pattern {
@@ -859,17 +927,24 @@ func (c *context) PopGroup() Pattern {
 		return newPatternInError(c.status)
 	}
 
-	// 1. Get the current target (which is the group surface)
-	groupSurface := c.target
+	// PopGroup must pair with PushGroup: the current state has to be the one
+	// pushed for the group (an unbalanced Save inside the group is an error)
+	if c.gstate.groupSurface == nil {
+		c.status = StatusInvalidPopGroup
+		return newPatternInError(c.status)
+	}
 
-	// 2. Restore the previous state (which restores the old target and gc)
-	c.Restore()
+	// 1. Create a SurfacePattern from the current target (the group surface);
+	// the pattern holds its own reference
+	pattern := NewPatternForSurface(c.target)
 
-	// 3. Create a SurfacePattern from the group surface
-	pattern := NewPatternForSurface(groupSurface)
+	// 2. Restore the previous state (which restores the old target and gc
+	// and releases the group's reference to the surface)
+	c.Restore()
 
-	// 4. Destroy the group surface (since the pattern holds a reference)
-	groupSurface.Destroy()
+	// 3. The group surface is in device space: map user space to it with the CTM
+	matrix := c.gstate.matrix
+	pattern.SetMatrix(&matrix)
 
 	return pattern
 }
@@ -890,33 +965,58 @@ func (c *context) Paint() error {
 	}
 
 	c.applyStateToPango()
+	c.recordVectorOp(vectorOpPaint)
 
-	// Gopdf's paint is equivalent to filling the current clip region with the source pattern.
-	// If there's a clip region, use it; otherwise fill the entire surface.
+	c.fillSurface()
+	return nil
+}
 
-	if c.gstate.clip != nil && c.gstate.clip.path != nil {
-		// Use the clip path
-		savedPath := c.path
-		c.path = c.gstate.clip.path
-		c.applyPathToPango()
-		c.gc.Fill()
-		c.path = savedPath
-	} else {
-		// Fill the entire surface
-		if imgSurface, ok := c.target.(ImageSurface); ok {
-			width := float64(imgSurface.GetWidth())
-			height := float64(imgSurface.GetHeight())
-
-			c.gc.BeginPath()
-			c.gc.MoveTo(0, 0)
-			c.gc.LineTo(width, 0)
-			c.gc.LineTo(width, height)
-			c.gc.LineTo(0, height)
-			c.gc.Close()
-			c.gc.Fill()
+// fillSurface 用当前源填充整个目标表面，由光栅裁剪遮罩限制绘制范围
+// Gopdf's paint is equivalent to filling the current clip region with the source pattern.
+// The path is in user space, so map the device-space corners back through the inverse CTM
+func (c *context) fillSurface() {
+	imgSurface, ok := c.target.(ImageSurface)
+	if !ok {
+		return
+	}
+	width := float64(imgSurface.GetWidth())
+	height := float64(imgSurface.GetHeight())
+
+	inv, ok := c.gstate.matrix.Invert()
+	if !ok {
+		inv.InitIdentity()
+	}
+
+	c.gc.BeginPath()
+	for i, corner := range [][2]float64{{0, 0}, {width, 0}, {width, height}, {0, height}} {
+		x, y := inv.Transform(corner[0], corner[1])
+		if i == 0 {
+			c.gc.MoveTo(x, y)
+		} else {
+			c.gc.LineTo(x, y)
 		}
 	}
-	return nil
+	c.gc.Close()
+	c.gc.Fill()
+}
+
+// paintThroughMask 以 mask 的 alpha 逐像素乘到源上进行 Paint，mask 与当前裁剪求交
+func (c *context) paintThroughMask(mask *image.Alpha) {
+	c.applyStateToPango()
+	if clip := c.gc.clip; clip != nil {
+		// 裁剪遮罩只覆盖裁剪路径的包围盒，按坐标取值
+		b := mask.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				i := mask.PixOffset(x, y)
+				mask.Pix[i] = uint8((int(mask.Pix[i])*int(clip.AlphaAt(x, y).A) + 127) / 255)
+			}
+		}
+	}
+	c.gc.clip = mask
+	c.fillSurface()
+	// 下一次绘制会通过 applyStateToPango 恢复 gstate 中的裁剪
+	c.applyStateToPango()
 }
 
 func (c *context) PaintWithAlpha(alpha float64) error {
@@ -924,29 +1024,67 @@ func (c *context) PaintWithAlpha(alpha float64) error {
 		return newError(c.status, "")
 	}
 
-	// 1. Save current state
-	if err := c.Save(); err != nil {
-		return err
+	if alpha >= 1 {
+		return c.Paint()
+	}
+	if alpha <= 0 {
+		return nil
 	}
 
-	// 2. Modify the source pattern's alpha (if possible)
-	// This is a simplification. Gopdf creates a new pattern with the alpha applied.
-	// Note: Pango doesn't have SetGlobalAlpha method, so we skip this for now
+	c.recordVectorOpWithAlpha(vectorOpPaint, alpha)
 
-	// 3. Perform the paint operation
-	if err := c.Paint(); err != nil {
-		return err
+	// 用统一 alpha 的遮罩衰减源
+	mask := image.NewAlpha(c.gc.img.Bounds())
+	a := uint8(math.Round(alpha * 255))
+	for i := range mask.Pix {
+		mask.Pix[i] = a
 	}
-
-	// 4. Restore the state (which restores the original alpha)
-	return c.Restore()
+	c.paintThroughMask(mask)
+	return nil
 }
 
+// Mask 以 pattern 的 alpha 为遮罩绘制当前源
+// 矢量表面只记录纯色遮罩（等同于 PaintWithAlpha）；其它遮罩无法表示为单个绘制操作，
+// 矢量输出中省略这次绘制，只作用于光栅结果
 func (c *context) Mask(pattern Pattern) {
-	if c.status != StatusSuccess {
+	if c.status != StatusSuccess || c.gc == nil || pattern == nil {
 		return
 	}
-	// TODO: Implement mask operation
+	if solid, ok := pattern.(*solidPattern); ok {
+		_, _, _, a := solid.GetRGBA()
+		c.recordVectorOpWithAlpha(vectorOpPaint, a)
+	} else if _, ok := c.target.(vectorSurface); ok {
+		debugPrintln("[Mask] Non-solid masks are not recorded on vector surfaces, paint skipped in vector output")
+	}
+	c.paintThroughMask(c.patternAlphaMask(pattern))
+}
+
+// patternAlphaMask 在当前 CTM 下把 pattern 绘制到临时表面，取其 alpha 通道作为设备空间遮罩
+func (c *context) patternAlphaMask(pattern Pattern) *image.Alpha {
+	scratch := image.NewRGBA(c.gc.img.Bounds())
+
+	savedGC := c.gc
+	savedSource := c.gstate.source
+	savedClip := c.gstate.clip
+	savedOperator := c.gstate.operator
+
+	c.gc = newRasterContext(scratch)
+	c.gstate.source = pattern
+	c.gstate.clip = nil
+	c.gstate.operator = OperatorOver
+	c.applyStateToPango()
+	c.fillSurface()
+
+	c.gc = savedGC
+	c.gstate.source = savedSource
+	c.gstate.clip = savedClip
+	c.gstate.operator = savedOperator
+
+	mask := image.NewAlpha(scratch.Bounds())
+	for i := range mask.Pix {
+		mask.Pix[i] = scratch.Pix[i*4+3]
+	}
+	return mask
 }
 
 func (c *context) MaskSurface(surface Surface, surfaceX, surfaceY float64) {
@@ -975,6 +1113,7 @@ func (c *context) Stroke() error {
 	c.applyStateToPango()
 	c.applyPathToPango()
 	c.gc.Stroke()
+	c.recordVectorOp(vectorOpStroke)
 	c.NewPath() // Clear path after stroke
 	return nil
 }
@@ -987,6 +1126,7 @@ func (c *context) StrokePreserve() error {
 	c.applyStateToPango()
 	c.applyPathToPango()
 	c.gc.Stroke()
+	c.recordVectorOp(vectorOpStroke)
 	return nil
 }
 
@@ -998,6 +1138,7 @@ func (c *context) Fill() error {
 	c.applyStateToPango()
 	c.applyPathToPango()
 	c.gc.Fill()
+	c.recordVectorOp(vectorOpFill)
 	c.NewPath() // Clear path after fill
 	return nil
 }
@@ -1010,6 +1151,7 @@ func (c *context) FillPreserve() error {
 	c.applyStateToPango()
 	c.applyPathToPango()
 	c.gc.Fill()
+	c.recordVectorOp(vectorOpFill)
 	return nil
 }
 
@@ -1202,7 +1344,7 @@ func (c *context) Clip() {
 		return
 	}
 
-	fmt.Printf("[Clip] Before copy, c.path.data length: %d\n", len(c.path.data))
+	debugPrintf("[Clip] Before copy, c.path.data length: %d\n", len(c.path.data))
 
 	// Deep copy the current path for the clip region
 	// We need to copy both the path data and the points within each operation
@@ -1221,7 +1363,7 @@ func (c *context) Clip() {
 		copy(clipPath.data[i].points, op.points)
 	}
 
-	fmt.Printf("[Clip] After deep copy, clipPath.data length: %d\n", len(clipPath.data))
+	debugPrintf("[Clip] After deep copy, clipPath.data length: %d\n", len(clipPath.data))
 
 	// Set the copied path as the new clip path
 	c.gstate.clip = &clipRegion{
@@ -1229,13 +1371,10 @@ func (c *context) Clip() {
 		fillRule:  c.gstate.fillRule,
 		tolerance: c.gstate.tolerance,
 		antialias: c.gstate.antialias,
+		mask:      c.rasterizeClip(),
 		prev:      c.gstate.clip, // Push current clip onto stack
 	}
 
-	// Apply the new clip path to Pango
-	c.applyPathToPango()
-	// Note: Pango doesn't have SetClipPath method, so we skip this for now
-
 	// Clear the current path
 	c.NewPath()
 }
@@ -1251,12 +1390,17 @@ func (c *context) ClipPreserve() {
 		fillRule:  c.gstate.fillRule,
 		tolerance: c.gstate.tolerance,
 		antialias: c.gstate.antialias,
+		mask:      c.rasterizeClip(),
 		prev:      c.gstate.clip, // Push current clip onto stack
 	}
+}
 
-	// Apply the new clip path to Pango
+// rasterizeClip 在当前变换下光栅化当前路径，得到与现有裁剪求交后的设备空间遮罩
+// 遮罩在设置裁剪时固定下来，之后 CTM 的变化不影响裁剪区域
+func (c *context) rasterizeClip() *image.Alpha {
+	c.applyStateToPango()
 	c.applyPathToPango()
-	// Note: Pango doesn't have SetClipPath method, so we skip this for now
+	return c.gc.ClipMask()
 }
 
 func (c *context) ClipExtents() (x1, y1, x2, y2 float64) {
@@ -1320,10 +1464,40 @@ func (c *context) CopyPathFlat() *Path {
 		return &Path{Status: c.status}
 	}
 
-	// Flattening converts curves to line segments
-	// For now, we'll just return a copy of the path
-	// A proper implementation would flatten all curves
-	return c.CopyPath()
+	// The tolerance is in device units; the path is kept in user space
+	tolerance := c.gstate.tolerance
+	if scale := math.Sqrt(math.Abs(c.gstate.matrix.XX*c.gstate.matrix.YY - c.gstate.matrix.XY*c.gstate.matrix.YX)); scale > 0 {
+		tolerance /= scale
+	}
+
+	newPath := &Path{Status: StatusSuccess}
+	var current, subpathStart Point
+	for _, op := range c.path.data {
+		switch op.op {
+		case PathCurveTo:
+			p1, p2, p3 := op.points[0], op.points[1], op.points[2]
+			flattenCubic(current.X, current.Y, p1.x, p1.y, p2.x, p2.y, p3.x, p3.y, tolerance, 0, func(x, y float64) {
+				newPath.Data = append(newPath.Data, PathData{Type: PathLineTo, Points: []Point{{X: x, Y: y}}})
+			})
+			current = Point{X: p3.x, Y: p3.y}
+			continue
+		case PathMoveTo:
+			subpathStart = Point{X: op.points[0].x, Y: op.points[0].y}
+			current = subpathStart
+		case PathLineTo:
+			current = Point{X: op.points[0].x, Y: op.points[0].y}
+		case PathClosePath:
+			current = subpathStart
+		}
+
+		data := PathData{Type: op.op, Points: make([]Point, len(op.points))}
+		for j, p := range op.points {
+			data.Points[j] = Point{X: p.x, Y: p.y}
+		}
+		newPath.Data = append(newPath.Data, data)
+	}
+
+	return newPath
 }
 
 func (c *context) AppendPath(path *Path) {
@@ -1379,60 +1553,28 @@ func (c *context) GlyphPath(glyphs []Glyph) {
 // Helper functions for matrix operations
 
 // MatrixMultiply multiplies two matrices: result = a * b
+// (result may alias a or b; the math is shared with Matrix.Multiply)
 func MatrixMultiply(result, a, b *Matrix) {
-	xx := a.XX*b.XX + a.YX*b.XY
-	yx := a.XX*b.YX + a.YX*b.YY
-	xy := a.XY*b.XX + a.YY*b.XY
-	yy := a.XY*b.YX + a.YY*b.YY
-	x0 := a.X0*b.XX + a.Y0*b.XY + b.X0
-	y0 := a.X0*b.YX + a.Y0*b.YY + b.Y0
-
-	result.XX = xx
-	result.YX = yx
-	result.XY = xy
-	result.YY = yy
-	result.X0 = x0
-	result.Y0 = y0
+	*result = *a.Multiply(b)
 }
 
 // MatrixTransformPoint transforms a point using the matrix
 func MatrixTransformPoint(matrix *Matrix, x, y float64) (float64, float64) {
-	newX := matrix.XX*x + matrix.XY*y + matrix.X0
-	newY := matrix.YX*x + matrix.YY*y + matrix.Y0
-	return newX, newY
+	return matrix.Transform(x, y)
 }
 
 // MatrixTransformDistance transforms a distance vector
 func MatrixTransformDistance(matrix *Matrix, dx, dy float64) (float64, float64) {
-	newDx := matrix.XX*dx + matrix.XY*dy
-	newDy := matrix.YX*dx + matrix.YY*dy
-	return newDx, newDy
+	return matrix.TransformDistance(dx, dy)
 }
 
-// MatrixInvert inverts a matrix
+// MatrixInvert inverts the matrix in place
 func MatrixInvert(matrix *Matrix) Status {
-	det := matrix.XX*matrix.YY - matrix.YX*matrix.XY
-
-	if math.Abs(det) < 1e-10 {
+	inv, ok := matrix.Invert()
+	if !ok {
 		return StatusInvalidMatrix
 	}
-
-	invDet := 1.0 / det
-
-	xx := matrix.YY * invDet
-	yx := -matrix.YX * invDet
-	xy := -matrix.XY * invDet
-	yy := matrix.XX * invDet
-	x0 := (matrix.XY*matrix.Y0 - matrix.YY*matrix.X0) * invDet
-	y0 := (matrix.YX*matrix.X0 - matrix.XX*matrix.Y0) * invDet
-
-	matrix.XX = xx
-	matrix.YX = yx
-	matrix.XY = xy
-	matrix.YY = yy
-	matrix.X0 = x0
-	matrix.Y0 = y0
-
+	*matrix = inv
 	return StatusSuccess
 }
 
@@ -1573,3 +1715,8 @@ func (c *context) PangoPdfUpdateLayout(layout interface{}) {
 func (c *context) PangoPdfShowText(layout interface{}) {
 	PangoPdfShowText(c, layout.(*PangoPdfLayout))
 }
+
+// PangoPdfLayoutPath adds the layout's glyph outlines to the current path
+func (c *context) PangoPdfLayoutPath(layout interface{}) {
+	PangoPdfLayoutPath(c, layout.(*PangoPdfLayout))
+}
@@ -642,7 +642,22 @@ func (s *scaledFont) TextExtents(utf8 string) *TextExtents {
 		return s.toyTextExtentsFallback(utf8)
 	}
 
-	// 1. Shape the text
+	// Font size from the font matrix; shape at that size so hinted advances
+	// and kerning match the rendered text
+	sx := math.Hypot(s.fontMatrix.XX, s.fontMatrix.YX)
+	sy := math.Hypot(s.fontMatrix.XY, s.fontMatrix.YY)
+	if sx == 0 || sy == 0 {
+		sx, sy = 12.0, 12.0
+	}
+
+	// 1. Shape the text at the actual font size
+	// The shaper scales the font to whole pixels (size.Ceil()), so fractional
+	// and sub-1 sizes are shaped one pixel size up and scaled back to sx
+	size := fixed.Int26_6(math.Round(sx * 64))
+	if size < 1 {
+		size = 1
+	}
+	shapeScale := sx / float64(size.Ceil())
 	runes := []rune(utf8)
 	input := shaping.Input{
 		Text:      runes,
@@ -650,36 +665,34 @@ func (s *scaledFont) TextExtents(utf8 string) *TextExtents {
 		RunEnd:    len(runes),
 		Direction: di.DirectionLTR,
 		Face:      realFace,
-		Size:      fixed.I(12), // Default size, will be scaled by font matrix
+		Size:      size,
 	}
 	output := (&shaping.HarfbuzzShaper{}).Shape(input)
 
 	// 2. Calculate extents from shaped output
-	// Scale factor from font matrix
-	sx := math.Hypot(s.fontMatrix.XX, s.fontMatrix.YX)
-	sy := math.Hypot(s.fontMatrix.XY, s.fontMatrix.YY)
+	// Outlines are in font units; the vertical scale follows the font matrix
+	unitsPerEm := float64(realFace.Upem())
+	yScale := sy / sx
 
 	// Calculate total advance and bounds
-	var totalAdvance fixed.Int26_6
+	var totalAdvance float64
+	var curX float64 // Current X position for glyph placement
 	var minX, minY, maxX, maxY float64
 	firstGlyph := true
 
 	for _, g := range output.Glyphs {
-		totalAdvance += g.XAdvance
-
 		// Get glyph outline for bounds calculation
 		glyphData := realFace.GlyphData(api.GID(g.GlyphID))
 		if outline, ok := glyphData.(api.GlyphOutline); ok {
-			// Convert outline points to user space and apply font matrix scaling
 			for _, seg := range outline.Segments {
 				for _, arg := range seg.Args {
-					// Convert from fixed point and apply font matrix scaling
-					x := float64(arg.X) / 64.0 * sx
-					y := float64(arg.Y) / 64.0 * sy
+					// Convert from font units to user space
+					x := float64(arg.X) / unitsPerEm * sx
+					y := float64(arg.Y) / unitsPerEm * sy
 
-					// Add glyph position (also needs scaling)
-					x += float64(g.XOffset) / 64.0 * sx
-					y -= float64(g.YOffset) / 64.0 * sy // Subtract because glyph offsets are in font coordinate system
+					// Add glyph position (current X + offset)
+					x += curX + float64(g.XOffset)/64.0*shapeScale
+					y += float64(g.YOffset) / 64.0 * shapeScale * yScale
 
 					// For the first glyph, initialize bounds
 					if firstGlyph {
@@ -703,13 +716,18 @@ func (s *scaledFont) TextExtents(utf8 string) *TextExtents {
 				}
 			}
 		}
+
+		// Advance to next glyph position
+		advance := float64(g.XAdvance) / 64.0 * shapeScale
+		curX += advance
+		totalAdvance += advance
 	}
 
-	// Convert to user space units and apply font matrix scaling
-	ext.XAdvance = float64(totalAdvance) / 64.0 * sx
+	// Advances are in user space at the font size
+	ext.XAdvance = totalAdvance
 	ext.YAdvance = 0
 
-	// Set proper width and height based on actual bounds (already scaled above)
+	// Set proper width and height based on actual bounds
 	ext.Width = maxX - minX
 	ext.Height = maxY - minY
 	ext.XBearing = minX
@@ -772,99 +790,76 @@ func (s *scaledFont) GlyphPath(glyphID uint64) (*Path, error) {
 		return nil, newError(StatusFontTypeMismatch, "glyph has no outline")
 	}
 
-	// Convert the outline to Path
+	// Scale factor from font matrix; outline coordinates are in font units
+	unitsPerEm := float64(realFace.Upem())
+	sx := math.Hypot(s.fontMatrix.XX, s.fontMatrix.YX) / unitsPerEm
+	sy := math.Hypot(s.fontMatrix.XY, s.fontMatrix.YY) / unitsPerEm
+
+	pdfPath := glyphOutlinePath(outline, sx, sy)
+
+	// Apply hinting to the points of each segment, keeping the segment types
+	for i := range pdfPath.Data {
+		pdfPath.Data[i].Points = s.applyHinting(pdfPath.Data[i].Points)
+	}
+
+	return pdfPath, nil
+}
+
+// glyphOutlinePath converts a glyph outline in font units to a path, scaling
+// by (sx, sy) and flipping Y: glyphs are designed with Y growing upward, but
+// our coordinate system has Y growing downward.
+// The segment structure is kept: each contour becomes a subpath ending in
+// PathClosePath, and curves stay curves.
+// Quadratic segments are converted to the equivalent cubic: for current point
+// P0, control point Q and end point P2, the cubic control points are
+// C1 = P0 + 2/3(Q-P0) and C2 = P2 + 2/3(Q-P2).
+func glyphOutlinePath(outline api.GlyphOutline, sx, sy float64) *Path {
 	pdfPath := &Path{
 		Status: StatusSuccess,
-		Data:   make([]PathData, 0),
+		Data:   make([]PathData, 0, len(outline.Segments)),
 	}
 
-	// Scale factor from font matrix
-	sx := math.Hypot(s.fontMatrix.XX, s.fontMatrix.YX)
-	sy := math.Hypot(s.fontMatrix.XY, s.fontMatrix.YY)
-
-	// Check if we need to flip the Y axis based on the font matrix
-	// Font glyphs are designed for Y growing upward, but our coordinate system has Y growing downward.
-	// Since we now use positive Y scale in font matrix, we always need to flip.
-	flipY := true
+	point := func(p api.SegmentPoint) Point {
+		return Point{X: float64(p.X) * sx, Y: -float64(p.Y) * sy}
+	}
 
-	// Iterate over the path segments
-	var pathPoints []Point
+	var current Point
+	open := false // whether a contour has been started and not yet closed
 	for _, seg := range outline.Segments {
+		var pd PathData
 		switch seg.Op {
 		case api.SegmentOpMoveTo:
-			// Convert from fixed point and apply font matrix scaling
-			x := float64(seg.Args[0].X) / 64.0 * sx
-			y := float64(seg.Args[0].Y) / 64.0 * sy
-			// Apply Y flip if needed
-			if flipY {
-				y = -y
+			// Each contour starts with a MoveTo; close the previous one
+			if open {
+				pdfPath.Data = append(pdfPath.Data, PathData{Type: PathClosePath})
 			}
-			point := Point{X: x, Y: y}
-			pathPoints = append(pathPoints, point)
+			open = true
+			pd.Type = PathMoveTo
+			pd.Points = []Point{point(seg.Args[0])}
 		case api.SegmentOpLineTo:
-			// Convert from fixed point and apply font matrix scaling
-			x := float64(seg.Args[0].X) / 64.0 * sx
-			y := float64(seg.Args[0].Y) / 64.0 * sy
-			// Apply Y flip if needed
-			if flipY {
-				y = -y
-			}
-			point := Point{X: x, Y: y}
-			pathPoints = append(pathPoints, point)
+			pd.Type = PathLineTo
+			pd.Points = []Point{point(seg.Args[0])}
 		case api.SegmentOpQuadTo:
-			// Convert quadratic to cubic Bezier
-			// Convert from fixed point and apply font matrix scaling
-			x1 := float64(seg.Args[0].X) / 64.0 * sx
-			y1 := float64(seg.Args[0].Y) / 64.0 * sy
-			x2 := float64(seg.Args[1].X) / 64.0 * sx
-			y2 := float64(seg.Args[1].Y) / 64.0 * sy
-			// Apply Y flip if needed
-			if flipY {
-				y1 = -y1
-				y2 = -y2
+			q, p2 := point(seg.Args[0]), point(seg.Args[1])
+			pd.Type = PathCurveTo
+			pd.Points = []Point{
+				{X: current.X + 2.0/3.0*(q.X-current.X), Y: current.Y + 2.0/3.0*(q.Y-current.Y)},
+				{X: p2.X + 2.0/3.0*(q.X-p2.X), Y: p2.Y + 2.0/3.0*(q.Y-p2.Y)},
+				p2,
 			}
-			p1 := Point{X: x1, Y: y1}
-			p2 := Point{X: x2, Y: y2}
-			pathPoints = append(pathPoints, p1, p1, p2)
 		case api.SegmentOpCubeTo:
-			// Convert from fixed point and apply font matrix scaling
-			x1 := float64(seg.Args[0].X) / 64.0 * sx
-			y1 := float64(seg.Args[0].Y) / 64.0 * sy
-			x2 := float64(seg.Args[1].X) / 64.0 * sx
-			y2 := float64(seg.Args[1].Y) / 64.0 * sy
-			x3 := float64(seg.Args[2].X) / 64.0 * sx
-			y3 := float64(seg.Args[2].Y) / 64.0 * sy
-			// Apply Y flip if needed
-			if flipY {
-				y1 = -y1
-				y2 = -y2
-				y3 = -y3
-			}
-			p1 := Point{X: x1, Y: y1}
-			p2 := Point{X: x2, Y: y2}
-			p3 := Point{X: x3, Y: y3}
-			pathPoints = append(pathPoints, p1, p2, p3)
-		}
-	}
-
-	// Apply hinting to the path points
-	hintedPoints := s.applyHinting(pathPoints)
-
-	// Convert hinted points back to path data
-	// This is a simplified approach - in reality, we'd need to preserve
-	// the segment structure while applying hinting
-	for i, point := range hintedPoints {
-		var pd PathData
-		if i == 0 {
-			pd.Type = PathMoveTo
-		} else {
-			pd.Type = PathLineTo
+			pd.Type = PathCurveTo
+			pd.Points = []Point{point(seg.Args[0]), point(seg.Args[1]), point(seg.Args[2])}
+		default:
+			continue
 		}
-		pd.Points = []Point{point}
+		current = pd.Points[len(pd.Points)-1]
 		pdfPath.Data = append(pdfPath.Data, pd)
 	}
-
-	return pdfPath, nil
+	if open {
+		pdfPath.Data = append(pdfPath.Data, PathData{Type: PathClosePath})
+	}
+	return pdfPath
 }
 
 // GetTextBearingMetrics returns the bearing metrics for a text string
@@ -885,13 +880,20 @@ func (s *scaledFont) GetTextAlignmentOffset(alignment TextAlignment) (float64, S
 	return GetAlignmentOffset(alignment, fontExtents), StatusSuccess
 }
 
-// GetKerning returns the kerning adjustment between two runes
+// GetKerning returns the kerning adjustment between two runes, in user space.
+// It reads the legacy kern/kerx tables and falls back to GPOS pair kerning
+// by shaping the pair. Advances from TextToGlyphs and TextExtents already
+// include this kerning, so it must not be added to them again.
 func (s *scaledFont) GetKerning(r1, r2 rune) (float64, Status) {
 	realFace, status := s.getRealFace()
 	if status != StatusSuccess {
 		return 0, status
 	}
+	return faceKerning(realFace, r1, r2, math.Hypot(s.fontMatrix.XX, s.fontMatrix.YX))
+}
 
+// faceKerning returns the kerning between two runes at the given font size
+func faceKerning(realFace font.Face, r1, r2 rune, fontSize float64) (float64, Status) {
 	// Get the glyph indices for the runes
 	gid1, ok1 := realFace.NominalGlyph(r1)
 	gid2, ok2 := realFace.NominalGlyph(r2)
@@ -900,12 +902,12 @@ func (s *scaledFont) GetKerning(r1, r2 rune) (float64, Status) {
 	}
 
 	// Check if we have kerning data
-	var kernValue int16
+	var kernValue float64
 	if len(realFace.Kern) > 0 {
 		// Try Kern tables first
 		for _, kernSubtable := range realFace.Kern {
 			if kd, ok := kernSubtable.Data.(apifont.Kern0); ok {
-				kernValue = kd.KernPair(gid1, gid2)
+				kernValue = float64(kd.KernPair(gid1, gid2))
 				break
 			}
 		}
@@ -913,20 +915,42 @@ func (s *scaledFont) GetKerning(r1, r2 rune) (float64, Status) {
 		// Try Kerx tables if no Kern tables
 		for _, kerxSubtable := range realFace.Kerx {
 			if kd, ok := kerxSubtable.Data.(apifont.Kern0); ok {
-				kernValue = kd.KernPair(gid1, gid2)
+				kernValue = float64(kd.KernPair(gid1, gid2))
 				break
 			}
 		}
 	}
 
-	// Scale factor from font matrix
-	sx := math.Hypot(s.fontMatrix.XX, s.fontMatrix.YX)
-	unitsPerEm := float64(realFace.Upem())
+	// Modern fonts only kern through GPOS: shape the pair and compare the
+	// first glyph's advance with its nominal advance
+	if kernValue == 0 {
+		kernValue = shapedPairKerning(realFace, r1, r2, gid1)
+	}
 
-	// Convert kerning value to user space units
-	kerning := float64(kernValue) * sx / unitsPerEm
+	// Convert kerning value from font units to user space units
+	unitsPerEm := float64(realFace.Upem())
+	return kernValue * fontSize / unitsPerEm, StatusSuccess
+}
 
-	return kerning, StatusSuccess
+// shapedPairKerning returns the kerning (in font units) the shaper applies
+// between r1 and r2, or 0 when the pair does not shape to two glyphs
+// (for example a ligature)
+func shapedPairKerning(realFace font.Face, r1, r2 rune, gid1 api.GID) float64 {
+	// Shaping at upem size keeps the advances in font units (26.6)
+	runes := []rune{r1, r2}
+	output := (&shaping.HarfbuzzShaper{}).Shape(shaping.Input{
+		Text:      runes,
+		RunStart:  0,
+		RunEnd:    len(runes),
+		Direction: di.DirectionLTR,
+		Face:      realFace,
+		Size:      fixed.I(int(realFace.Upem())),
+	})
+	if len(output.Glyphs) != 2 || output.Glyphs[0].GlyphID != gid1 {
+		return 0
+	}
+	shaped := float64(output.Glyphs[0].XAdvance) / 64.0
+	return shaped - float64(realFace.HorizontalAdvance(gid1))
 }
 
 // applyHinting applies font hinting based on the font options
@@ -1042,7 +1066,8 @@ func (s *scaledFont) GetGlyphMetrics(r rune) (*GlyphMetrics, Status) {
 }
 
 // GetGlyphs returns the glyphs for a given text string.
-// This is a simplified version of gopdf_scaled_font_get_glyphs, primarily for font subsetting.
+// This is a simplified version of gopdf_scaled_font_get_glyphs, primarily for font subsetting
+// (see SubsetFont).
 func (s *scaledFont) GetGlyphs(utf8 string) (glyphs []Glyph, status Status) {
 	realFace, status := s.getRealFace()
 	if status != StatusSuccess {
@@ -1137,10 +1162,18 @@ func (s *scaledFont) TextToGlyphsWithOptions(x, y float64, utf8 string, options
 	glyphs = make([]Glyph, 0)
 	clusters = make([]TextCluster, 0)
 	var curY float64
+	var pos int // byte offset of the current line in utf8
+	var rtl bool
 
 	for lineIdx, line := range lines {
+		// The line break belongs to no glyph; it gets a glyph-less cluster
+		pos += len(line)
+		breakLen := lineBreakLen(utf8[pos:])
+		pos += breakLen
+
 		if line == "" {
 			// Empty line, just advance Y
+			clusters = appendLineBreakCluster(clusters, breakLen)
 			curY += lineHeight
 			continue
 		}
@@ -1148,22 +1181,25 @@ func (s *scaledFont) TextToGlyphsWithOptions(x, y float64, utf8 string, options
 		// 1. Shape the text with advanced options
 		runes := []rune(line)
 		input := shaping.Input{
-			Text:      runes,
-			RunStart:  0,
-			RunEnd:    len(runes),
-			Direction: convertDirection(options.Direction, line),
-			Face:      realFace,
-			Size:      fixed.I(int(fontSize)),
-			Language:  convertLanguage(options.Language),
-			Script:    convertScript(options.Script),
+			Text:         runes,
+			RunStart:     0,
+			RunEnd:       len(runes),
+			Direction:    convertDirection(options.Direction, line),
+			Face:         realFace,
+			Size:         fixed.I(int(fontSize)),
+			Language:     convertLanguage(options.Language),
+			Script:       convertScript(options.Script),
+			FontFeatures: convertFeatures(options.Features),
 		}
 		output := (&shaping.HarfbuzzShaper{}).Shape(input)
+		rtl = input.Direction == di.DirectionRTL
 
 		// 2. Convert shaped output to gopdf's Glyph and TextCluster structures
 		var curX float64
 
-		// Process each glyph with proper spacing
-		for glyphIdx, g := range output.Glyphs {
+		// Process each glyph with proper spacing. The shaped advances already
+		// include kern and GPOS kerning, so no pair kerning is added here.
+		for _, g := range output.Glyphs {
 			// Position is in user space, relative to the start point (x, y)
 			glyph := Glyph{
 				Index: uint64(g.GlyphID),
@@ -1176,28 +1212,13 @@ func (s *scaledFont) TextToGlyphsWithOptions(x, y float64, utf8 string, options
 			advance := float64(g.XAdvance) / 64.0
 			curX += advance
 
-			// Add kerning between characters if this is not the last glyph
-			if glyphIdx < len(runes)-1 {
-				// Get kerning adjustment between current and next glyph
-				kerning, kernStatus := s.GetKerning(runes[glyphIdx], runes[glyphIdx+1])
-				// Only apply kerning if successfully obtained
-				if kernStatus == StatusSuccess {
-					curX += kerning
-				}
-			}
-
 			// Add vertical advance
 			curY += float64(g.YAdvance) / 64.0
 		}
 
 		// Create clusters for this line
-		for range output.Glyphs {
-			cluster := TextCluster{
-				NumBytes:  1, // Simplified: assume 1 byte per glyph
-				NumGlyphs: 1,
-			}
-			clusters = append(clusters, cluster)
-		}
+		clusters = appendShapedClusters(clusters, line, output.Glyphs)
+		clusters = appendLineBreakCluster(clusters, breakLen)
 
 		// Move to next line (reset X, advance Y)
 		if lineIdx < len(lines)-1 {
@@ -1205,8 +1226,11 @@ func (s *scaledFont) TextToGlyphsWithOptions(x, y float64, utf8 string, options
 		}
 	}
 
-	// Cluster flags (simplified)
-	clusterFlags = 0
+	// RTL output is in visual order, so the clusters run backward through
+	// the text. A single flag can only describe that for a single line.
+	if len(lines) == 1 && rtl {
+		clusterFlags = TextClusterFlagBackward
+	}
 
 	return glyphs, clusters, clusterFlags, StatusSuccess
 }
@@ -1253,6 +1277,56 @@ func splitLines(text string) []string {
 	return lines
 }
 
+// lineBreakLen returns the length of the line break at the start of text:
+// 2 for \r\n, 1 for \r or \n, 0 at the end of the text
+func lineBreakLen(text string) int {
+	switch {
+	case strings.HasPrefix(text, "\r\n"):
+		return 2
+	case text != "":
+		return 1
+	}
+	return 0
+}
+
+// appendLineBreakCluster appends a cluster for a line break, which maps to no glyph
+func appendLineBreakCluster(clusters []TextCluster, breakLen int) []TextCluster {
+	if breakLen == 0 {
+		return clusters
+	}
+	return append(clusters, TextCluster{NumBytes: breakLen, NumGlyphs: 0})
+}
+
+// appendShapedClusters appends the clusters of one shaped run of line.
+// Consecutive glyphs sharing a ClusterIndex form one cluster, which covers the
+// UTF-8 bytes of the RuneCount runes starting at that index. This handles
+// multibyte characters, ligatures (many runes, one glyph) and decompositions
+// (one rune, many glyphs).
+func appendShapedClusters(clusters []TextCluster, line string, glyphs []shaping.Glyph) []TextCluster {
+	// offsets[i] is the byte offset of rune i; the extra entry is the line end
+	offsets := make([]int, 0, len(line)+1)
+	for i := range line {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(line))
+
+	for i := 0; i < len(glyphs); {
+		g := glyphs[i]
+		n := 1
+		for i+n < len(glyphs) && glyphs[i+n].ClusterIndex == g.ClusterIndex {
+			n++
+		}
+		start, end := g.ClusterIndex, g.ClusterIndex+g.RuneCount
+		if start < 0 || end >= len(offsets) || end < start {
+			// Out of range cluster info: treat the glyphs as covering no text
+			start, end = 0, 0
+		}
+		clusters = append(clusters, TextCluster{NumBytes: offsets[end] - offsets[start], NumGlyphs: n})
+		i += n
+	}
+	return clusters
+}
+
 // toyTextToGlyphsFallback performs a trivial Unicode->glyph mapping similar to
 // gopdf_scaled_font_text_to_glyphs but without complex shaping.
 func (s *scaledFont) toyTextToGlyphsFallback(x, y float64, utf8 string) (glyphs []Glyph, clusters []TextCluster, clusterFlags TextClusterFlags, status Status) {
@@ -0,0 +1,853 @@
+package gopdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// SubsetFont 从字体的嵌入数据构建只包含 usedGlyphs 中字形的子集字体，返回可重新嵌入的字体数据
+// 字形 ID 保持不变（未使用的字形变为空字形），因此子集字体可以直接配合 Identity CIDToGIDMap 使用；
+// .notdef（字形 0）和复合字形引用的组件字形总是保留。
+// TrueType 字体重建 glyf/loca 表，CFF 字体（OTTO）把未使用字形的 CharString 替换为 endchar；
+// 两者都会去掉 PDF 嵌入不需要的排版表（GSUB、GPOS、kern 等）。字体集合（TTC）使用其中的第一个字体
+func SubsetFont(face FontFace, usedGlyphs []uint64) ([]byte, error) {
+	data := fontFaceData(face)
+	if len(data) == 0 {
+		return nil, errors.New("font face has no embedded font data")
+	}
+	return subsetSFNT(data, usedGlyphs)
+}
+
+// fontFaceData 返回字体外观的原始字体文件数据
+func fontFaceData(face FontFace) []byte {
+	switch f := face.(type) {
+	case *PangoPdfFont:
+		return f.fontData
+	case *toyFontFace:
+		return f.fontData
+	}
+	return nil
+}
+
+const (
+	sfntVersionTrueType = 0x00010000
+	sfntVersionApple    = 0x74727565 // 'true'
+	sfntVersionCFF      = 0x4F54544F // 'OTTO'
+	sfntCollectionTag   = 0x74746366 // 'ttcf'
+)
+
+// subsetTrueTypeTables 子集 TrueType 字体保留的表
+var subsetTrueTypeTables = map[string]bool{
+	"head": true, "hhea": true, "maxp": true, "hmtx": true, "loca": true, "glyf": true,
+	"cmap": true, "name": true, "post": true, "OS/2": true,
+	"cvt ": true, "fpgm": true, "prep": true, "gasp": true, "vhea": true, "vmtx": true,
+}
+
+// subsetCFFTables 子集 CFF 字体保留的表
+var subsetCFFTables = map[string]bool{
+	"head": true, "hhea": true, "maxp": true, "hmtx": true, "CFF ": true,
+	"cmap": true, "name": true, "post": true, "OS/2": true, "vhea": true, "vmtx": true,
+}
+
+// subsetSFNT 对 sfnt 字体数据（TrueType/OpenType/TTC）做字形子集
+func subsetSFNT(data []byte, usedGlyphs []uint64) ([]byte, error) {
+	version, tables, err := readSFNTTables(data)
+	if err != nil {
+		return nil, err
+	}
+
+	maxp := tables["maxp"]
+	if len(maxp) < 6 {
+		return nil, errors.New("font has no valid maxp table")
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+
+	keep := make([]bool, numGlyphs)
+	if numGlyphs > 0 {
+		keep[0] = true // .notdef
+	}
+	for _, gid := range usedGlyphs {
+		if gid < uint64(numGlyphs) {
+			keep[gid] = true
+		}
+	}
+
+	var allowed map[string]bool
+	switch version {
+	case sfntVersionTrueType, sfntVersionApple:
+		if err := subsetGlyf(tables, keep); err != nil {
+			return nil, err
+		}
+		allowed = subsetTrueTypeTables
+	case sfntVersionCFF:
+		cff, ok := tables["CFF "]
+		if !ok {
+			return nil, errors.New("OpenType font has no CFF table")
+		}
+		subset, err := subsetCFF(cff, keep)
+		if err != nil {
+			return nil, fmt.Errorf("failed to subset CFF table: %w", err)
+		}
+		tables["CFF "] = subset
+		allowed = subsetCFFTables
+	default:
+		return nil, fmt.Errorf("unsupported font format 0x%08x", version)
+	}
+
+	for tag := range tables {
+		if !allowed[tag] {
+			delete(tables, tag)
+		}
+	}
+	return writeSFNT(version, tables)
+}
+
+// readSFNTTables 读取 sfnt 表目录，返回字体版本和表数据（按标签索引）
+func readSFNTTables(data []byte) (uint32, map[string][]byte, error) {
+	if len(data) < 12 {
+		return 0, nil, errors.New("font data too short")
+	}
+
+	dir := 0
+	if binary.BigEndian.Uint32(data) == sfntCollectionTag {
+		if len(data) < 16 || binary.BigEndian.Uint32(data[8:]) == 0 {
+			return 0, nil, errors.New("empty font collection")
+		}
+		dir = int(binary.BigEndian.Uint32(data[12:]))
+		if dir+12 > len(data) {
+			return 0, nil, errors.New("font collection offset out of range")
+		}
+	}
+
+	version := binary.BigEndian.Uint32(data[dir:])
+	numTables := int(binary.BigEndian.Uint16(data[dir+4:]))
+	if dir+12+numTables*16 > len(data) {
+		return 0, nil, errors.New("font table directory out of range")
+	}
+
+	tables := make(map[string][]byte, numTables)
+	for i := 0; i < numTables; i++ {
+		entry := data[dir+12+i*16:]
+		tag := string(entry[:4])
+		offset := int(binary.BigEndian.Uint32(entry[8:]))
+		length := int(binary.BigEndian.Uint32(entry[12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return 0, nil, fmt.Errorf("font table %q out of range", tag)
+		}
+		tables[tag] = data[offset : offset+length]
+	}
+	return version, tables, nil
+}
+
+// writeSFNT 按标签顺序写出 sfnt 字体，并重新计算表校验和与 head.checkSumAdjustment
+func writeSFNT(version uint32, tables map[string][]byte) ([]byte, error) {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	numTables := len(tags)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= numTables {
+		entrySelector++
+	}
+	searchRange := (1 << entrySelector) * 16
+
+	out := make([]byte, 12+16*numTables)
+	binary.BigEndian.PutUint32(out, version)
+	binary.BigEndian.PutUint16(out[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(out[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:], uint16(numTables*16-searchRange))
+
+	headOffset := -1
+	for i, tag := range tags {
+		table := tables[tag]
+		if tag == "head" {
+			if len(table) < 12 {
+				return nil, errors.New("font has no valid head table")
+			}
+			// checkSumAdjustment 在计算校验和时必须为 0
+			table = append([]byte(nil), table...)
+			binary.BigEndian.PutUint32(table[8:], 0)
+			headOffset = len(out)
+		}
+
+		entry := out[12+i*16:]
+		copy(entry, tag)
+		binary.BigEndian.PutUint32(entry[4:], sfntChecksum(table))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(entry[12:], uint32(len(table)))
+
+		out = append(out, table...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+
+	if headOffset >= 0 {
+		binary.BigEndian.PutUint32(out[headOffset+8:], 0xB1B0AFBA-sfntChecksum(out))
+	}
+	return out, nil
+}
+
+// sfntChecksum 计算表校验和（按大端 uint32 求和，末尾不足 4 字节补 0）
+func sfntChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// subsetGlyf 重建 glyf/loca 表，只保留 keep 中的字形（以及复合字形引用的组件）
+// 新的 loca 表总是使用长格式
+func subsetGlyf(tables map[string][]byte, keep []bool) error {
+	head, loca, glyf := tables["head"], tables["loca"], tables["glyf"]
+	if len(head) < 54 {
+		return errors.New("font has no valid head table")
+	}
+	numGlyphs := len(keep)
+
+	longOffsets := int16(binary.BigEndian.Uint16(head[50:])) != 0
+	offsets := make([]int, numGlyphs+1)
+	for i := range offsets {
+		if longOffsets {
+			if (i+1)*4 > len(loca) {
+				return errors.New("loca table too short")
+			}
+			offsets[i] = int(binary.BigEndian.Uint32(loca[i*4:]))
+		} else {
+			if (i+1)*2 > len(loca) {
+				return errors.New("loca table too short")
+			}
+			offsets[i] = int(binary.BigEndian.Uint16(loca[i*2:])) * 2
+		}
+	}
+	glyph := func(gid int) []byte {
+		start, end := offsets[gid], offsets[gid+1]
+		if start >= end || end > len(glyf) {
+			return nil
+		}
+		return glyf[start:end]
+	}
+
+	// 复合字形引用的组件字形也必须保留
+	var pending []int
+	for gid, k := range keep {
+		if k {
+			pending = append(pending, gid)
+		}
+	}
+	for len(pending) > 0 {
+		gid := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for _, component := range glyfComponents(glyph(gid)) {
+			if int(component) < numGlyphs && !keep[component] {
+				keep[component] = true
+				pending = append(pending, int(component))
+			}
+		}
+	}
+
+	var newGlyf []byte
+	newLoca := make([]byte, (numGlyphs+1)*4)
+	for gid := 0; gid < numGlyphs; gid++ {
+		binary.BigEndian.PutUint32(newLoca[gid*4:], uint32(len(newGlyf)))
+		if keep[gid] {
+			newGlyf = append(newGlyf, glyph(gid)...)
+			for len(newGlyf)%4 != 0 {
+				newGlyf = append(newGlyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(newLoca[numGlyphs*4:], uint32(len(newGlyf)))
+
+	newHead := append([]byte(nil), head...)
+	binary.BigEndian.PutUint16(newHead[50:], 1) // indexToLocFormat: long
+
+	tables["head"] = newHead
+	tables["loca"] = newLoca
+	tables["glyf"] = newGlyf
+	return nil
+}
+
+// glyfComponents 返回复合字形引用的组件字形 ID；简单字形返回 nil
+func glyfComponents(glyph []byte) []uint16 {
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph)) >= 0 {
+		return nil
+	}
+
+	const (
+		argsAreWords    = 0x0001
+		haveScale       = 0x0008
+		moreComponents  = 0x0020
+		haveXYScale     = 0x0040
+		haveTwoByTwo    = 0x0080
+		componentHeader = 4 // flags + glyphIndex
+	)
+
+	var components []uint16
+	for p := 10; p+componentHeader <= len(glyph); {
+		flags := binary.BigEndian.Uint16(glyph[p:])
+		components = append(components, binary.BigEndian.Uint16(glyph[p+2:]))
+		p += componentHeader
+		if flags&argsAreWords != 0 {
+			p += 4
+		} else {
+			p += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			p += 2
+		case flags&haveXYScale != 0:
+			p += 4
+		case flags&haveTwoByTwo != 0:
+			p += 8
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// CFF DICT 操作符
+const (
+	cffOpCharset     = 15
+	cffOpEncoding    = 16
+	cffOpCharStrings = 17
+	cffOpPrivate     = 18
+	cffOpSubrs       = 19
+	cffOpFDArray     = 0x0c24
+	cffOpFDSelect    = 0x0c25
+)
+
+// cffEndchar 未使用字形的 CharString（只有 endchar）
+var cffEndchar = []byte{14}
+
+// cffDictEntry DICT 中的一项：操作数保留原始编码
+type cffDictEntry struct {
+	op       int
+	operands [][]byte
+}
+
+// cffPrivate Private DICT 及其局部 Subrs INDEX（原始字节）
+type cffPrivate struct {
+	dict  []cffDictEntry
+	subrs []byte
+}
+
+// subsetCFF 把未使用字形的 CharString 替换为 endchar，并重新排列 CFF 数据
+// 新布局：头、Name、Top DICT、String、Global Subrs、charset、Encoding、FDSelect、
+// CharStrings、FDArray、各个 Private DICT（每个后面紧跟其 Subrs）
+// 偏移量操作数统一编码为 5 字节整数，因此 DICT 的长度与偏移值无关
+func subsetCFF(cff []byte, keep []bool) ([]byte, error) {
+	if len(cff) < 4 {
+		return nil, errors.New("CFF data too short")
+	}
+	hdrSize := int(cff[2])
+	if hdrSize < 4 || hdrSize > len(cff) {
+		return nil, errors.New("invalid CFF header size")
+	}
+
+	_, topStart, err := readCFFIndex(cff, hdrSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Name INDEX: %w", err)
+	}
+	topDicts, stringStart, err := readCFFIndex(cff, topStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Top DICT INDEX: %w", err)
+	}
+	if len(topDicts) != 1 {
+		return nil, fmt.Errorf("CFF table has %d fonts, want 1", len(topDicts))
+	}
+	_, gsubrStart, err := readCFFIndex(cff, stringStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read String INDEX: %w", err)
+	}
+	_, gsubrEnd, err := readCFFIndex(cff, gsubrStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Global Subrs INDEX: %w", err)
+	}
+
+	top, err := parseCFFDict(topDicts[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Top DICT: %w", err)
+	}
+
+	// CharStrings
+	csOffset, ok := cffDictInt(top, cffOpCharStrings, 0)
+	if !ok {
+		return nil, errors.New("Top DICT has no CharStrings")
+	}
+	charStrings, _, err := readCFFIndex(cff, csOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CharStrings: %w", err)
+	}
+	numGlyphs := len(charStrings)
+	for gid := range charStrings {
+		if gid >= len(keep) || !keep[gid] {
+			charStrings[gid] = cffEndchar
+		}
+	}
+
+	// charset 和 Encoding（0-2 / 0-1 是预定义值，不是偏移量）
+	var charset, encoding []byte
+	if off, ok := cffDictInt(top, cffOpCharset, 0); ok && off > 2 {
+		if charset, err = cffCharset(cff, off, numGlyphs); err != nil {
+			return nil, err
+		}
+	}
+	if off, ok := cffDictInt(top, cffOpEncoding, 0); ok && off > 1 {
+		if encoding, err = cffEncoding(cff, off); err != nil {
+			return nil, err
+		}
+	}
+
+	// Private DICT（非 CID 字体）或 FDArray/FDSelect（CID 字体）
+	var private *cffPrivate
+	if size, ok := cffDictInt(top, cffOpPrivate, 0); ok {
+		offset, _ := cffDictInt(top, cffOpPrivate, 1)
+		if private, err = readCFFPrivate(cff, offset, size); err != nil {
+			return nil, err
+		}
+	}
+	var fdSelect []byte
+	var fdDicts [][]cffDictEntry
+	var fdPrivates []*cffPrivate
+	if off, ok := cffDictInt(top, cffOpFDArray, 0); ok {
+		fdArray, _, err := readCFFIndex(cff, off)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read FDArray: %w", err)
+		}
+		for _, raw := range fdArray {
+			fd, err := parseCFFDict(raw)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse Font DICT: %w", err)
+			}
+			var fdPrivate *cffPrivate
+			if size, ok := cffDictInt(fd, cffOpPrivate, 0); ok {
+				offset, _ := cffDictInt(fd, cffOpPrivate, 1)
+				if fdPrivate, err = readCFFPrivate(cff, offset, size); err != nil {
+					return nil, err
+				}
+			}
+			fdDicts = append(fdDicts, fd)
+			fdPrivates = append(fdPrivates, fdPrivate)
+		}
+		selOff, ok := cffDictInt(top, cffOpFDSelect, 0)
+		if !ok {
+			return nil, errors.New("CID font has no FDSelect")
+		}
+		if fdSelect, err = cffFDSelect(cff, selOff, numGlyphs); err != nil {
+			return nil, err
+		}
+	}
+
+	// 先用占位偏移量确定各部分长度，再计算真实偏移量
+	layout := func(base int) (topDict, fdArray []byte, privates [][]byte, next int) {
+		pos := base
+		charsetOff := pos
+		pos += len(charset)
+		encodingOff := pos
+		pos += len(encoding)
+		fdSelectOff := pos
+		pos += len(fdSelect)
+		csOff := pos
+		pos += len(encodeCFFIndex(charStrings))
+
+		// FDArray 的长度与偏移值无关，先用占位值编码
+		fdArrayOff := pos
+		if fdDicts != nil {
+			placeholder := make([][]byte, len(fdDicts))
+			for i, fd := range fdDicts {
+				placeholder[i] = encodeCFFDict(withCFFPrivate(fd, fdPrivates[i], 0))
+			}
+			pos += len(encodeCFFIndex(placeholder))
+		}
+
+		privateOffsets := make([]int, 0, 1+len(fdPrivates))
+		for _, p := range append([]*cffPrivate{private}, fdPrivates...) {
+			privateOffsets = append(privateOffsets, pos)
+			if p != nil {
+				encoded := p.encode()
+				privates = append(privates, encoded)
+				pos += len(encoded)
+			}
+		}
+
+		fds := make([][]byte, len(fdDicts))
+		for i, fd := range fdDicts {
+			fds[i] = encodeCFFDict(withCFFPrivate(fd, fdPrivates[i], privateOffsets[1+i]))
+		}
+		fdArray = encodeCFFIndex(fds)
+
+		entries := make([]cffDictEntry, 0, len(top))
+		for _, e := range top {
+			switch e.op {
+			case cffOpCharset:
+				if charset != nil {
+					e = cffDictOffsets(e.op, charsetOff)
+				}
+			case cffOpEncoding:
+				if encoding != nil {
+					e = cffDictOffsets(e.op, encodingOff)
+				}
+			case cffOpCharStrings:
+				e = cffDictOffsets(e.op, csOff)
+			case cffOpPrivate:
+				if private != nil {
+					e = cffDictOffsets(e.op, len(privates[0])-len(private.subrs), privateOffsets[0])
+				}
+			case cffOpFDArray:
+				e = cffDictOffsets(e.op, fdArrayOff)
+			case cffOpFDSelect:
+				e = cffDictOffsets(e.op, fdSelectOff)
+			}
+			entries = append(entries, e)
+		}
+		return encodeCFFDict(entries), fdArray, privates, pos
+	}
+
+	// 头、Name INDEX、Top DICT INDEX、String 和 Global Subrs INDEX 的总长度
+	prefixLen := func(topDict []byte) int {
+		return topStart + len(encodeCFFIndex([][]byte{topDict})) + (gsubrEnd - stringStart)
+	}
+	placeholderTop, _, _, _ := layout(0)
+	topDict, fdArray, privates, _ := layout(prefixLen(placeholderTop))
+
+	out := make([]byte, 0, len(cff))
+	out = append(out, cff[:topStart]...) // 头和 Name INDEX
+	out = append(out, encodeCFFIndex([][]byte{topDict})...)
+	out = append(out, cff[stringStart:gsubrEnd]...) // String 和 Global Subrs INDEX
+	out = append(out, charset...)
+	out = append(out, encoding...)
+	out = append(out, fdSelect...)
+	out = append(out, encodeCFFIndex(charStrings)...)
+	if fdDicts != nil {
+		out = append(out, fdArray...)
+	}
+	for _, p := range privates {
+		out = append(out, p...)
+	}
+	return out, nil
+}
+
+// readCFFIndex 读取 pos 处的 INDEX，返回各项数据和 INDEX 之后的位置
+func readCFFIndex(data []byte, pos int) ([][]byte, int, error) {
+	if pos < 0 || pos+2 > len(data) {
+		return nil, 0, errors.New("INDEX out of range")
+	}
+	count := int(binary.BigEndian.Uint16(data[pos:]))
+	if count == 0 {
+		return nil, pos + 2, nil
+	}
+	if pos+3 > len(data) {
+		return nil, 0, errors.New("INDEX out of range")
+	}
+	offSize := int(data[pos+2])
+	if offSize < 1 || offSize > 4 {
+		return nil, 0, fmt.Errorf("invalid INDEX offset size %d", offSize)
+	}
+	offsetsStart := pos + 3
+	dataStart := offsetsStart + (count+1)*offSize - 1 // 偏移量从 1 开始
+	if dataStart >= len(data) {
+		return nil, 0, errors.New("INDEX offsets out of range")
+	}
+
+	offset := func(i int) int {
+		v := 0
+		for _, b := range data[offsetsStart+i*offSize : offsetsStart+(i+1)*offSize] {
+			v = v<<8 | int(b)
+		}
+		return dataStart + v
+	}
+
+	items := make([][]byte, count)
+	for i := range items {
+		start, end := offset(i), offset(i+1)
+		if start > end || end > len(data) {
+			return nil, 0, errors.New("INDEX item out of range")
+		}
+		items[i] = data[start:end]
+	}
+	return items, offset(count), nil
+}
+
+// encodeCFFIndex 编码 INDEX，偏移量使用能容纳数据长度的最小字节数
+func encodeCFFIndex(items [][]byte) []byte {
+	if len(items) == 0 {
+		return []byte{0, 0}
+	}
+	total := 1
+	for _, item := range items {
+		total += len(item)
+	}
+	offSize := 1
+	for total >= 1<<(8*offSize) {
+		offSize++
+	}
+
+	out := make([]byte, 3, 3+(len(items)+1)*offSize+total)
+	binary.BigEndian.PutUint16(out, uint16(len(items)))
+	out[2] = byte(offSize)
+	putOffset := func(v int) {
+		for i := offSize - 1; i >= 0; i-- {
+			out = append(out, byte(v>>(8*i)))
+		}
+	}
+	offset := 1
+	putOffset(offset)
+	for _, item := range items {
+		offset += len(item)
+		putOffset(offset)
+	}
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// parseCFFDict 解析 DICT 数据，操作数保留原始编码
+func parseCFFDict(data []byte) ([]cffDictEntry, error) {
+	var entries []cffDictEntry
+	var operands [][]byte
+	for p := 0; p < len(data); {
+		b0 := data[p]
+		n := 0
+		switch {
+		case b0 <= 21: // 操作符
+			op := int(b0)
+			p++
+			if b0 == 12 {
+				if p >= len(data) {
+					return nil, errors.New("truncated DICT operator")
+				}
+				op = 0x0c00 | int(data[p])
+				p++
+			}
+			entries = append(entries, cffDictEntry{op: op, operands: operands})
+			operands = nil
+			continue
+		case b0 == 28:
+			n = 3
+		case b0 == 29:
+			n = 5
+		case b0 == 30: // 实数：半字节编码，以 0xf 结束
+			n = 1
+			for {
+				if p+n >= len(data) {
+					return nil, errors.New("truncated DICT real")
+				}
+				b := data[p+n]
+				n++
+				if b&0x0f == 0x0f || b>>4 == 0x0f {
+					break
+				}
+			}
+		case b0 >= 32 && b0 <= 246:
+			n = 1
+		case b0 >= 247 && b0 <= 254:
+			n = 2
+		default:
+			return nil, fmt.Errorf("invalid DICT byte %d", b0)
+		}
+		if p+n > len(data) {
+			return nil, errors.New("truncated DICT operand")
+		}
+		operands = append(operands, data[p:p+n])
+		p += n
+	}
+	return entries, nil
+}
+
+// encodeCFFDict 编码 DICT
+func encodeCFFDict(entries []cffDictEntry) []byte {
+	var out []byte
+	for _, e := range entries {
+		for _, operand := range e.operands {
+			out = append(out, operand...)
+		}
+		if e.op >= 0x0c00 {
+			out = append(out, 12, byte(e.op&0xff))
+		} else {
+			out = append(out, byte(e.op))
+		}
+	}
+	return out
+}
+
+// cffDictInt 返回 DICT 中操作符 op 的第 index 个整数操作数
+func cffDictInt(entries []cffDictEntry, op, index int) (int, bool) {
+	for _, e := range entries {
+		if e.op != op || index >= len(e.operands) {
+			continue
+		}
+		operand := e.operands[index]
+		switch b0 := int(operand[0]); {
+		case b0 == 28:
+			return int(int16(binary.BigEndian.Uint16(operand[1:]))), true
+		case b0 == 29:
+			return int(int32(binary.BigEndian.Uint32(operand[1:]))), true
+		case b0 >= 32 && b0 <= 246:
+			return b0 - 139, true
+		case b0 >= 247 && b0 <= 250:
+			return (b0-247)*256 + int(operand[1]) + 108, true
+		case b0 >= 251 && b0 <= 254:
+			return -(b0-251)*256 - int(operand[1]) - 108, true
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+// cffDictOffsets 构造操作数为 5 字节整数的 DICT 项
+func cffDictOffsets(op int, values ...int) cffDictEntry {
+	operands := make([][]byte, len(values))
+	for i, v := range values {
+		operand := make([]byte, 5)
+		operand[0] = 29
+		binary.BigEndian.PutUint32(operand[1:], uint32(int32(v)))
+		operands[i] = operand
+	}
+	return cffDictEntry{op: op, operands: operands}
+}
+
+// withCFFPrivate 返回 Private 操作数指向 offset 的 Font DICT 副本
+func withCFFPrivate(dict []cffDictEntry, private *cffPrivate, offset int) []cffDictEntry {
+	out := make([]cffDictEntry, len(dict))
+	copy(out, dict)
+	for i, e := range out {
+		if e.op == cffOpPrivate && private != nil {
+			out[i] = cffDictOffsets(cffOpPrivate, len(private.encode())-len(private.subrs), offset)
+		}
+	}
+	return out
+}
+
+// readCFFPrivate 读取 Private DICT 和它引用的局部 Subrs INDEX
+func readCFFPrivate(cff []byte, offset, size int) (*cffPrivate, error) {
+	if offset < 0 || size < 0 || offset+size > len(cff) {
+		return nil, errors.New("Private DICT out of range")
+	}
+	dict, err := parseCFFDict(cff[offset : offset+size])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Private DICT: %w", err)
+	}
+	private := &cffPrivate{dict: dict}
+	if subrsOff, ok := cffDictInt(dict, cffOpSubrs, 0); ok {
+		start := offset + subrsOff // Subrs 偏移量相对于 Private DICT 开头
+		_, end, err := readCFFIndex(cff, start)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local Subrs: %w", err)
+		}
+		private.subrs = cff[start:end]
+	}
+	return private, nil
+}
+
+// encode 编码 Private DICT，Subrs 紧跟在 DICT 之后
+func (p *cffPrivate) encode() []byte {
+	entries := make([]cffDictEntry, 0, len(p.dict))
+	hasSubrs := false
+	for _, e := range p.dict {
+		if e.op == cffOpSubrs {
+			hasSubrs = true
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if !hasSubrs || p.subrs == nil {
+		return encodeCFFDict(entries)
+	}
+	// Subrs 操作数固定为 5 字节，偏移量就是 DICT 的长度
+	size := len(encodeCFFDict(entries)) + 6
+	entries = append(entries, cffDictOffsets(cffOpSubrs, size))
+	return append(encodeCFFDict(entries), p.subrs...)
+}
+
+// cffSection 返回 data[pos:pos+length]，越界时返回错误
+func cffSection(data []byte, pos, length int, name string) ([]byte, error) {
+	if pos < 0 || length < 0 || pos+length > len(data) {
+		return nil, fmt.Errorf("%s out of range", name)
+	}
+	return data[pos : pos+length], nil
+}
+
+// cffCharset 返回 pos 处 charset 的原始数据
+func cffCharset(data []byte, pos, numGlyphs int) ([]byte, error) {
+	if pos >= len(data) {
+		return nil, errors.New("charset out of range")
+	}
+	switch format := data[pos]; format {
+	case 0:
+		return cffSection(data, pos, 1+2*(numGlyphs-1), "charset")
+	case 1, 2:
+		// 每个范围：首个 SID（2 字节）+ 剩余数量（格式 1 为 1 字节，格式 2 为 2 字节）
+		rangeSize := 3 + int(format) - 1
+		p := pos + 1
+		for covered := 0; covered < numGlyphs-1; p += rangeSize {
+			if p+rangeSize > len(data) {
+				return nil, errors.New("charset out of range")
+			}
+			nLeft := int(data[p+2])
+			if format == 2 {
+				nLeft = int(binary.BigEndian.Uint16(data[p+2:]))
+			}
+			covered += nLeft + 1
+		}
+		return data[pos:p], nil
+	default:
+		return nil, fmt.Errorf("unsupported charset format %d", format)
+	}
+}
+
+// cffEncoding 返回 pos 处 Encoding 的原始数据
+func cffEncoding(data []byte, pos int) ([]byte, error) {
+	if pos+2 > len(data) {
+		return nil, errors.New("Encoding out of range")
+	}
+	format := data[pos]
+	n := int(data[pos+1])
+	var length int
+	switch format & 0x7f {
+	case 0:
+		length = 2 + n
+	case 1:
+		length = 2 + 2*n
+	default:
+		return nil, fmt.Errorf("unsupported Encoding format %d", format&0x7f)
+	}
+	if format&0x80 != 0 { // 补充编码
+		if pos+length >= len(data) {
+			return nil, errors.New("Encoding out of range")
+		}
+		length += 1 + 3*int(data[pos+length])
+	}
+	return cffSection(data, pos, length, "Encoding")
+}
+
+// cffFDSelect 返回 pos 处 FDSelect 的原始数据
+func cffFDSelect(data []byte, pos, numGlyphs int) ([]byte, error) {
+	if pos >= len(data) {
+		return nil, errors.New("FDSelect out of range")
+	}
+	switch format := data[pos]; format {
+	case 0:
+		return cffSection(data, pos, 1+numGlyphs, "FDSelect")
+	case 3:
+		if pos+3 > len(data) {
+			return nil, errors.New("FDSelect out of range")
+		}
+		nRanges := int(binary.BigEndian.Uint16(data[pos+1:]))
+		return cffSection(data, pos, 3+3*nRanges+2, "FDSelect")
+	default:
+		return nil, fmt.Errorf("unsupported FDSelect format %d", format)
+	}
+}
@@ -1,16 +1,24 @@
 package gopdf
 
+import "strings"
+
 // FormField 表示 PDF 表单字段
 // 表单字段是交互式表单元素，如文本框、复选框、单选按钮等
 type FormField struct {
-	FieldType    string                 // 字段类型（Tx, Btn, Ch, Sig）
-	FieldName    string                 // 字段名称
-	Value        string                 // 字段当前值
-	DefaultValue string                 // 字段默认值
-	Rect         []float64              // 字段矩形 [x1 y1 x2 y2]
-	Appearance   map[string]interface{} // 外观流字典（AP entry）
-	Flags        int                    // 字段标志
-	Options      []string               // 选项列表（用于选择字段）
+	FieldType        string                 // 字段类型（Tx, Btn, Ch, Sig）
+	FieldName        string                 // 字段名称
+	Value            string                 // 字段当前值
+	DefaultValue     string                 // 字段默认值
+	Rect             []float64              // 字段矩形 [x1 y1 x2 y2]
+	Appearance       map[string]interface{} // 外观流字典（AP entry）
+	AppearanceState  string                 // 外观状态（AS entry，如 Yes、Off）
+	NormalAppearance *XObject               // 按外观状态选出的正常外观流（AP /N）
+	Flags            int                    // 字段标志
+	Options          []string               // 选项列表（用于选择字段）
+
+	DefaultAppearance string     // 默认外观字符串（DA entry，如 "/Helv 12 Tf 0 g"）
+	Quadding          int        // 文本对齐方式（Q entry：0 左对齐，1 居中，2 右对齐）
+	Resources         *Resources // 默认资源（AcroForm DR entry），DA 中的字体从这里查找
 }
 
 // NewFormField 创建新的表单字段
@@ -46,26 +54,40 @@ func (f *FormField) IsRequired() bool {
 
 // IsCheckbox 检查是否为复选框
 func (f *FormField) IsCheckbox() bool {
-	return f.FieldType == "/Btn" && (f.Flags&0x8000) == 0 // 非 Radio 按钮
+	return f.hasFieldType("Btn") && (f.Flags&0x8000) == 0 // 非 Radio 按钮
 }
 
 // IsRadioButton 检查是否为单选按钮
 func (f *FormField) IsRadioButton() bool {
-	return f.FieldType == "/Btn" && (f.Flags&0x8000) != 0 // Radio 标志
+	return f.hasFieldType("Btn") && (f.Flags&0x8000) != 0 // Radio 标志
 }
 
 // IsTextField 检查是否为文本字段
 func (f *FormField) IsTextField() bool {
-	return f.FieldType == "/Tx"
+	return f.hasFieldType("Tx")
+}
+
+// IsMultiline 检查文本字段是否为多行（Ff bit 13）
+func (f *FormField) IsMultiline() bool {
+	return (f.Flags & 0x1000) != 0
 }
 
 // IsChoiceField 检查是否为选择字段（下拉列表或列表框）
 func (f *FormField) IsChoiceField() bool {
-	return f.FieldType == "/Ch"
+	return f.hasFieldType("Ch")
+}
+
+// hasFieldType 检查字段类型，兼容带或不带前缀 "/" 的名称
+func (f *FormField) hasFieldType(fieldType string) bool {
+	return strings.TrimPrefix(f.FieldType, "/") == fieldType
 }
 
 // IsChecked 检查复选框/单选按钮是否被选中
 func (f *FormField) IsChecked() bool {
+	// 有外观状态时以 /AS 为准：除 Off 以外的状态都表示选中
+	if f.AppearanceState != "" {
+		return f.AppearanceState != "Off"
+	}
 	// 值为 "Yes" 或 "On" 表示选中
 	return f.Value == "Yes" || f.Value == "On" || f.Value == "/Yes" || f.Value == "/On"
 }
@@ -59,6 +59,25 @@ func ExtractFormFields(ctx *model.Context) ([]*FormField, error) {
 		return nil, fmt.Errorf("fields is not an array")
 	}
 
+	// 文档级默认外观（DA）、对齐方式（Q）和默认资源（DR）
+	var defaultAppearance string
+	if da, found := acroFormDict.Find("DA"); found {
+		defaultAppearance = formFieldValue(da)
+	}
+	defaultQuadding := 0
+	if q, found := acroFormDict.Find("Q"); found {
+		if num, ok := q.(types.Integer); ok {
+			defaultQuadding = int(num)
+		}
+	}
+	var defaultResources *Resources
+	if dr, found := acroFormDict.Find("DR"); found {
+		defaultResources = NewResources()
+		if err := loadResources(ctx, dr, defaultResources); err != nil {
+			debugPrintf("Warning: failed to load AcroForm default resources: %v\n", err)
+		}
+	}
+
 	// 遍历每个字段
 	for _, fieldObj := range fieldsArray {
 		// 解引用字段对象
@@ -84,6 +103,13 @@ func ExtractFormFields(ctx *model.Context) ([]*FormField, error) {
 			debugPrintf("Warning: failed to parse form field: %v\n", err)
 			continue
 		}
+		if field.DefaultAppearance == "" {
+			field.DefaultAppearance = defaultAppearance
+		}
+		if _, found := fieldDict.Find("Q"); !found {
+			field.Quadding = defaultQuadding
+		}
+		field.Resources = defaultResources
 
 		formFields = append(formFields, field)
 	}
@@ -111,21 +137,21 @@ func parseFormField(ctx *model.Context, fieldDict types.Dict) (*FormField, error
 
 	// 获取字段值
 	if v, found := fieldDict.Find("V"); found {
-		switch val := v.(type) {
-		case types.StringLiteral:
-			field.Value = val.String()
-		case types.Name:
-			field.Value = val.String()
-		}
+		field.Value = formFieldValue(v)
 	}
 
 	// 获取默认值
 	if dv, found := fieldDict.Find("DV"); found {
-		switch val := dv.(type) {
-		case types.StringLiteral:
-			field.DefaultValue = val.String()
-		case types.Name:
-			field.DefaultValue = val.String()
+		field.DefaultValue = formFieldValue(dv)
+	}
+
+	// 获取默认外观字符串（DA）和对齐方式（Q），缺省时继承 AcroForm 的设置
+	if da, found := fieldDict.Find("DA"); found {
+		field.DefaultAppearance = formFieldValue(da)
+	}
+	if q, found := fieldDict.Find("Q"); found {
+		if num, ok := q.(types.Integer); ok {
+			field.Quadding = int(num)
 		}
 	}
 
@@ -168,6 +194,22 @@ func parseFormField(ctx *model.Context, fieldDict types.Dict) (*FormField, error
 		}
 	}
 
+	// 获取外观状态（AS），复选框和单选按钮据此选择 /AP /N 中的子外观
+	if as, found := fieldDict.Find("AS"); found {
+		if name, ok := as.(types.Name); ok {
+			field.AppearanceState = name.Value()
+		}
+	}
+
+	if n, ok := field.Appearance["N"].(types.Object); ok {
+		xobj, err := loadAppearanceStream(ctx, n, field.AppearanceState)
+		if err != nil {
+			debugPrintf("Warning: failed to load normal appearance: %v\n", err)
+		} else {
+			field.NormalAppearance = xobj
+		}
+	}
+
 	// 获取选项（用于选择字段）
 	if opt, found := fieldDict.Find("Opt"); found {
 		if arr, ok := opt.(types.Array); ok {
@@ -182,3 +224,16 @@ func parseFormField(ctx *model.Context, fieldDict types.Dict) (*FormField, error
 
 	return field, nil
 }
+
+// formFieldValue 将字段值对象转换为字符串：文本字符串按 PDFDocEncoding/UTF-16BE 解码，名称保持原样
+func formFieldValue(obj types.Object) string {
+	switch val := obj.(type) {
+	case types.StringLiteral, types.HexLiteral:
+		if s, err := types.StringOrHexLiteral(val); err == nil {
+			return *s
+		}
+	case types.Name:
+		return val.String()
+	}
+	return ""
+}
@@ -2,11 +2,14 @@ package gopdf
 
 import (
 	"fmt"
+	"math"
+	"strings"
 )
 
 // FormRenderer 表单字段渲染器
 type FormRenderer struct {
 	gopdfCtx Context
+	budget   *renderBudget // 外观流计入的页面渲染预算，nil 表示不限制
 }
 
 // NewFormRenderer 创建新的表单字段渲染器
@@ -64,9 +67,9 @@ func (r *FormRenderer) RenderTextField(field *FormField) error {
 	}
 
 	if displayValue != "" {
-		// 简化实现：显示文本（实际应该使用字体渲染）
 		debugPrintf("[FormField] Text field value: %s\n", displayValue)
-		// TODO: 使用字体渲染文本
+		// 按 DA 的字体、字号和颜色排版绘制（裁剪到字段矩形内）
+		r.renderTextFieldValue(field, displayValue)
 	}
 
 	debugPrintf("[FormField] Rendered text field at (%.2f, %.2f)\n", x1, y1)
@@ -81,10 +84,10 @@ func (r *FormRenderer) RenderCheckbox(field *FormField) error {
 	r.gopdfCtx.Save()
 	defer r.gopdfCtx.Restore()
 
-	// 如果有外观流，优先使用外观流
-	if len(field.Appearance) > 0 {
-		debugPrintf("[FormField] Checkbox has appearance stream\n")
-		// TODO: 渲染外观流
+	// 如果有外观流，优先使用当前状态（/AS）对应的外观流
+	if field.NormalAppearance != nil {
+		debugPrintf("[FormField] Rendering checkbox appearance for state %s\n", field.AppearanceState)
+		return renderAppearanceXObject(r.gopdfCtx, field.NormalAppearance, field.Rect, r.budget)
 	}
 
 	// 绘制复选框边框
@@ -123,6 +126,12 @@ func (r *FormRenderer) RenderRadioButton(field *FormField) error {
 	r.gopdfCtx.Save()
 	defer r.gopdfCtx.Restore()
 
+	// 如果有外观流，优先使用当前状态（/AS）对应的外观流
+	if field.NormalAppearance != nil {
+		debugPrintf("[FormField] Rendering radio button appearance for state %s\n", field.AppearanceState)
+		return renderAppearanceXObject(r.gopdfCtx, field.NormalAppearance, field.Rect, r.budget)
+	}
+
 	// 计算圆心和半径
 	centerX := (x1 + x2) / 2
 	centerY := (y1 + y2) / 2
@@ -194,3 +203,157 @@ func (r *FormRenderer) RenderFieldAppearance(field *FormField, appearanceKey str
 	_ = apObj // 避免未使用变量警告
 	return nil
 }
+
+// ===== 文本字段值绘制 =====
+
+// 文本字段的内边距和行距（相对字号）
+const (
+	textFieldPadding     = 2.0
+	textFieldLineSpacing = 1.15
+	textFieldAscent      = 0.8
+	textFieldDescent     = 0.2
+	textFieldDefaultSize = 12.0
+)
+
+// renderTextFieldValue 按字段的默认外观字符串（DA）绘制文本值
+// 文本经 PangoPdf 布局排版和绘制，与 Tj 相同按字体回退显示 CJK 等 Latin-1 之外的字符；
+// DA 中的字号为 0 时自动选择字号，多行字段（Ff bit 13）按字段宽度自动换行，Q 决定对齐方式
+func (r *FormRenderer) renderTextFieldValue(field *FormField, text string) {
+	x1, y1, x2, y2 := field.GetRect()
+	left, bottom := min(x1, x2), min(y1, y2)
+	width, height := math.Abs(x2-x1), math.Abs(y2-y1)
+
+	resources := field.Resources
+	if resources == nil {
+		resources = NewResources()
+	}
+
+	fontName, fontSize := parseDefaultAppearance(field.DefaultAppearance)
+	if fontName == "" {
+		fontName = "Helv"
+	}
+	if fontSize <= 0 {
+		fontSize = textFieldDefaultSize
+		if !field.IsMultiline() {
+			// 单行字段：字号随字段高度
+			fontSize = max((height-2*textFieldPadding)/textFieldLineSpacing, 1)
+		}
+	}
+
+	// 与 Tf 相同的字体映射
+	layout := r.gopdfCtx.PangoPdfCreateLayout().(*PangoPdfLayout)
+	defer layout.Destroy()
+	fontDesc := NewPangoFontDescription()
+	fontDesc.SetFamily(mapPDFFont(textFieldFont(resources, fontName).BaseFont))
+	fontDesc.SetSize(fontSize)
+	layout.SetFontDescription(fontDesc)
+	layout.SetLineSpacing(fontSize * textFieldLineSpacing)
+
+	availableWidth := width - 2*textFieldPadding
+	x := textFieldPadding
+	// 单行字段垂直居中，多行字段从顶部开始
+	baseline := (height-fontSize*(textFieldAscent+textFieldDescent))/2 + fontSize*textFieldDescent
+	if field.IsMultiline() {
+		layout.SetText(strings.Join(splitFieldLines(text), "\n"))
+		layout.SetWidth(int(availableWidth * 1024))
+		layout.SetWrap(PangoWrapWordChar)
+		layout.SetAlignment(fieldTextAlignment(field.Quadding))
+		baseline = height - textFieldPadding - fontSize*textFieldAscent
+	} else {
+		layout.SetText(strings.Join(splitFieldLines(text), " "))
+		ext := layout.GetPixelExtents()
+		switch field.Quadding {
+		case 1:
+			x = (width - ext.X - ext.Width) / 2
+		case 2:
+			x = width - textFieldPadding - ext.X - ext.Width
+		}
+	}
+
+	r.gopdfCtx.Save()
+	defer r.gopdfCtx.Restore()
+
+	color := fieldTextColor(r.gopdfCtx, field.DefaultAppearance)
+	r.gopdfCtx.Translate(left, bottom)
+	r.gopdfCtx.Rectangle(1, 1, width-2, height-2)
+	r.gopdfCtx.Clip()
+
+	// 布局的 Y 轴向下，从第一行基线开始逐行向下排列
+	r.gopdfCtx.Translate(x, baseline)
+	r.gopdfCtx.Scale(1, -1)
+	r.gopdfCtx.SetSourceRGBA(color.R, color.G, color.B, color.A)
+	r.gopdfCtx.NewPath()
+	r.gopdfCtx.MoveTo(0, 0)
+	r.gopdfCtx.PangoPdfShowText(layout)
+}
+
+// parseDefaultAppearance 从 DA 字符串中解析字体名称和字号（Tf 操作符）
+func parseDefaultAppearance(da string) (fontName string, fontSize float64) {
+	if da == "" {
+		return "", 0
+	}
+	operators, err := ParseContentStream([]byte(da))
+	if err != nil {
+		debugPrintf("[FormField] Warning: failed to parse DA %q: %v\n", da, err)
+		return "", 0
+	}
+	for _, op := range operators {
+		if tf, ok := op.(*OpSetFont); ok {
+			fontName, fontSize = tf.FontName, tf.FontSize
+		}
+	}
+	return fontName, fontSize
+}
+
+// fieldTextColor 执行 DA 字符串中的颜色操作符，返回其设置的填充颜色（默认黑色）
+func fieldTextColor(gopdfCtx Context, da string) *Color {
+	black := &Color{A: 1}
+	operators, err := ParseContentStream([]byte(da))
+	if err != nil {
+		return black
+	}
+	ctx := NewRenderContext(gopdfCtx, 0, 0)
+	for _, op := range operators {
+		if _, ok := op.(*OpSetFont); ok {
+			continue
+		}
+		if err := op.Execute(ctx); err != nil {
+			debugPrintf("[FormField] Warning: DA operator %s failed: %v\n", op.Name(), err)
+		}
+	}
+	if state := ctx.GetCurrentState(); state != nil && state.FillColor != nil {
+		return state.FillColor
+	}
+	return black
+}
+
+// fieldTextAlignment 把字段的 Q（0 左对齐，1 居中，2 右对齐）转换为布局的对齐方式
+func fieldTextAlignment(quadding int) PangoAlignment {
+	switch quadding {
+	case 1:
+		return PangoAlignCenter
+	case 2:
+		return PangoAlignRight
+	}
+	return PangoAlignLeft
+}
+
+// textFieldFont 返回 Tf 操作符会选用的字体（资源中找不到时与 OpSetFont 一样回退到 Helvetica）
+func textFieldFont(resources *Resources, fontName string) *Font {
+	if font := resources.GetFont(fontName); font != nil {
+		return font
+	}
+	return &Font{
+		Name:     fontName,
+		BaseFont: "Helvetica",
+		Subtype:  "Type1",
+		Encoding: "WinAnsiEncoding",
+	}
+}
+
+// splitFieldLines 按字段值中的换行符（CR、LF 或 CRLF）拆分段落
+func splitFieldLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,390 @@
+package gopdf
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// PDFFunction PDF 函数（PDF 32000-1 7.10）：把 m 个输入映射为 n 个输出
+// 用于阴影颜色、Separation/DeviceN 的 tint 变换和软遮罩传递函数
+type PDFFunction struct {
+	FunctionType int       // 函数类型：0 = 采样, 2 = 指数插值, 3 = 缝合, 4 = PostScript
+	Domain       []float64 // 定义域，每个输入一对 [min, max]
+	Range        []float64 // 值域，每个输出一对 [min, max]（类型 0 和 4 必需）
+	C0           []float64 // 起始颜色（类型 2，默认 [0]）
+	C1           []float64 // 结束颜色（类型 2，默认 [1]）
+	N            float64   // 指数（用于类型 2）
+
+	// 用于缝合函数（类型 3）
+	Functions []*PDFFunction // 子函数数组
+	Bounds    []float64      // 边界数组
+	Encode    []float64      // 编码数组（类型 0 也使用）
+
+	// 用于采样函数（类型 0）
+	Size          []int     // 各输入维度的采样数
+	BitsPerSample int       // 每个采样值的位数
+	Decode        []float64 // 解码数组，默认与 Range 相同
+	Samples       []float64 // 原始采样值，第一个输入维度变化最快，按输出分量交错存放
+
+	// 用于 PostScript 计算器函数（类型 4）
+	Program []psInstr
+}
+
+// pdfFunctionMaxDepth 缝合函数的最大嵌套深度
+const pdfFunctionMaxDepth = 10
+
+// EvaluateFunction 计算单输入函数在 t 处的值
+func (f *PDFFunction) EvaluateFunction(t float64) []float64 {
+	return f.Eval([]float64{t})
+}
+
+// Eval 计算函数值：输入先限制在 Domain 内，输出限制在 Range 内
+// 不支持的函数类型返回全零输出
+func (f *PDFFunction) Eval(inputs []float64) []float64 {
+	in := make([]float64, len(inputs))
+	for i, x := range inputs {
+		if 2*i+1 < len(f.Domain) {
+			x = math.Max(f.Domain[2*i], math.Min(f.Domain[2*i+1], x))
+		}
+		in[i] = x
+	}
+
+	var out []float64
+	switch f.FunctionType {
+	case 0:
+		out = f.evalSampled(in)
+	case 2:
+		out = f.evalExponential(in)
+	case 3:
+		out = f.evalStitching(in)
+	case 4:
+		var err error
+		out, err = evalPSFunction(f.Program, in)
+		if err != nil {
+			debugPrintf("Warning: PostScript function failed: %v\n", err)
+			out = nil
+		}
+		if n := len(f.Range) / 2; n > 0 && len(out) > n {
+			out = out[len(out)-n:]
+		}
+	default:
+		debugPrintf("Warning: Unsupported function type %d\n", f.FunctionType)
+	}
+
+	if out == nil {
+		n := len(f.Range) / 2
+		if n == 0 {
+			n = len(f.C0)
+		}
+		out = make([]float64, n)
+	}
+	for j := range out {
+		if 2*j+1 < len(f.Range) {
+			out[j] = math.Max(f.Range[2*j], math.Min(f.Range[2*j+1], out[j]))
+		}
+	}
+	return out
+}
+
+// evalExponential 计算指数插值：C0 + x^N * (C1 - C0)
+func (f *PDFFunction) evalExponential(in []float64) []float64 {
+	if len(in) == 0 {
+		return nil
+	}
+	c0, c1 := f.C0, f.C1
+	if c0 == nil {
+		c0 = []float64{0}
+	}
+	if c1 == nil {
+		c1 = []float64{1}
+	}
+	n := len(c0)
+	if len(c1) < n {
+		n = len(c1)
+	}
+
+	xn := math.Pow(in[0], f.N)
+	out := make([]float64, n)
+	for j := range out {
+		out[j] = c0[j] + xn*(c1[j]-c0[j])
+	}
+	return out
+}
+
+// evalStitching 计算缝合函数：按 Bounds 选择子函数，并把子区间按 Encode 映射到子函数定义域
+func (f *PDFFunction) evalStitching(in []float64) []float64 {
+	k := len(f.Functions)
+	if len(in) == 0 || k == 0 || len(f.Bounds) < k-1 || len(f.Encode) < 2*k {
+		return nil
+	}
+	x := in[0]
+
+	d0, d1 := 0.0, 1.0
+	if len(f.Domain) >= 2 {
+		d0, d1 = f.Domain[0], f.Domain[1]
+	}
+	i := 0
+	for i < k-1 && x >= f.Bounds[i] {
+		i++
+	}
+	lo, hi := d0, d1
+	if i > 0 {
+		lo = f.Bounds[i-1]
+	}
+	if i < k-1 {
+		hi = f.Bounds[i]
+	}
+	return f.Functions[i].Eval([]float64{interpolate(x, lo, hi, f.Encode[2*i], f.Encode[2*i+1])})
+}
+
+// evalSampled 计算采样函数：按 Encode 映射到采样索引后多线性插值，再按 Decode 映射到输出
+func (f *PDFFunction) evalSampled(in []float64) []float64 {
+	m, n := len(f.Size), len(f.Range)/2
+	if m == 0 || n == 0 || len(in) < m {
+		return nil
+	}
+	total := n
+	for _, size := range f.Size {
+		if size <= 0 {
+			return nil
+		}
+		total *= size
+	}
+	if len(f.Samples) < total {
+		return nil
+	}
+
+	// 每个输入维度的下界索引、插值比例和步长
+	base := make([]int, m)
+	frac := make([]float64, m)
+	stride := make([]int, m)
+	step := n
+	for i := 0; i < m; i++ {
+		size := f.Size[i]
+		d0, d1 := 0.0, 1.0
+		if 2*i+1 < len(f.Domain) {
+			d0, d1 = f.Domain[2*i], f.Domain[2*i+1]
+		}
+		e0, e1 := 0.0, float64(size-1)
+		if 2*i+1 < len(f.Encode) {
+			e0, e1 = f.Encode[2*i], f.Encode[2*i+1]
+		}
+		e := math.Max(0, math.Min(float64(size-1), interpolate(in[i], d0, d1, e0, e1)))
+		base[i] = int(e)
+		frac[i] = e - float64(base[i])
+		if base[i] == size-1 {
+			frac[i] = 0
+		}
+		stride[i] = step
+		step *= size
+	}
+
+	maxSample := math.Exp2(float64(f.BitsPerSample)) - 1
+	decode := f.Decode
+	if len(decode) < 2*n {
+		decode = f.Range
+	}
+
+	// 遍历 2^m 个相邻采样点
+	out := make([]float64, n)
+	for corner := 0; corner < 1<<m; corner++ {
+		w := 1.0
+		offset := 0
+		for i := 0; i < m; i++ {
+			idx := base[i]
+			if corner&(1<<i) != 0 {
+				if frac[i] == 0 {
+					w = 0
+					break
+				}
+				idx++
+				w *= frac[i]
+			} else {
+				w *= 1 - frac[i]
+			}
+			offset += idx * stride[i]
+		}
+		if w == 0 {
+			continue
+		}
+		for j := range out {
+			out[j] += w * f.Samples[offset+j]
+		}
+	}
+	for j := range out {
+		out[j] = interpolate(out[j], 0, maxSample, decode[2*j], decode[2*j+1])
+	}
+	return out
+}
+
+// interpolate 把 x 从 [x0, x1] 线性映射到 [y0, y1]
+func interpolate(x, x0, x1, y0, y1 float64) float64 {
+	if x1 == x0 {
+		return y0
+	}
+	return y0 + (x-x0)*(y1-y0)/(x1-x0)
+}
+
+// sampleTableCount 计算采样表的采样值个数（各维 Size 之积乘以输出个数）
+// Size 来自文件，分配前检查每一维为正、乘积不溢出，且不超过 dataLen 字节的数据能提供的采样数，
+// 防止构造的 PDF 导致超大分配
+func sampleTableCount(size []int, outputs, bits, dataLen int) (int, error) {
+	available := dataLen * 8 / bits
+	count := outputs
+	if count <= 0 || count > available {
+		return 0, fmt.Errorf("sampled function data too short: %d bytes", dataLen)
+	}
+	for _, v := range size {
+		if v <= 0 {
+			return 0, fmt.Errorf("invalid sampled function Size %v", size)
+		}
+		if count > available/v {
+			return 0, fmt.Errorf("sampled function Size %v needs more samples than %d bytes of data provide", size, dataLen)
+		}
+		count *= v
+	}
+	return count, nil
+}
+
+// readSampleTable 按大端位序读取 count 个 bits 位的采样值
+func readSampleTable(data []byte, bits, count int) []float64 {
+	samples := make([]float64, 0, count)
+	for i := 0; i < count; i++ {
+		bitPos := i * bits
+		if (bitPos+bits+7)/8 > len(data) {
+			break
+		}
+		var v uint64
+		for b := 0; b < bits; b++ {
+			pos := bitPos + b
+			v = v<<1 | uint64(data[pos/8]>>(7-pos%8)&1)
+		}
+		samples = append(samples, float64(v))
+	}
+	return samples
+}
+
+// loadPDFFunction 从函数字典（类型 2、3）或函数流（类型 0、4）加载 PDF 函数
+func loadPDFFunction(ctx *model.Context, obj types.Object) (*PDFFunction, error) {
+	return loadPDFFunctionDepth(ctx, obj, 0)
+}
+
+func loadPDFFunctionDepth(ctx *model.Context, obj types.Object, depth int) (*PDFFunction, error) {
+	if depth > pdfFunctionMaxDepth {
+		return nil, fmt.Errorf("function nesting too deep")
+	}
+
+	// 解引用
+	if indRef, ok := obj.(types.IndirectRef); ok {
+		derefObj, err := ctx.Dereference(indRef)
+		if err != nil {
+			return nil, err
+		}
+		obj = derefObj
+	}
+
+	// 类型 0 和 4 的函数是流，字典部分与其他类型相同
+	var dict types.Dict
+	var streamDict *types.StreamDict
+	switch v := obj.(type) {
+	case types.Dict:
+		dict = v
+	case types.StreamDict:
+		dict = v.Dict
+		streamDict = &v
+	default:
+		return nil, fmt.Errorf("function is not a dictionary: %T", obj)
+	}
+
+	f := &PDFFunction{
+		FunctionType: 2,
+		Domain:       []float64{0, 1},
+		N:            1,
+	}
+	if ft, found := dict.Find("FunctionType"); found {
+		if num, ok := ft.(types.Integer); ok {
+			f.FunctionType = int(num)
+		}
+	}
+	if domain := getNumberArray(ctx, dict, "Domain"); len(domain) >= 2 {
+		f.Domain = domain
+	}
+	f.Range = getNumberArray(ctx, dict, "Range")
+	f.Encode = getNumberArray(ctx, dict, "Encode")
+	f.Decode = getNumberArray(ctx, dict, "Decode")
+
+	switch f.FunctionType {
+	case 2:
+		f.C0 = getNumberArray(ctx, dict, "C0")
+		f.C1 = getNumberArray(ctx, dict, "C1")
+		if nObj, found := dict.Find("N"); found {
+			if num, ok := getNumber(nObj); ok {
+				f.N = num
+			}
+		}
+
+	case 3:
+		f.Bounds = getNumberArray(ctx, dict, "Bounds")
+		fnsObj, _ := dict.Find("Functions")
+		if indRef, ok := fnsObj.(types.IndirectRef); ok {
+			if derefObj, err := ctx.Dereference(indRef); err == nil {
+				fnsObj = derefObj
+			}
+		}
+		fns, ok := fnsObj.(types.Array)
+		if !ok || len(fns) == 0 {
+			return nil, fmt.Errorf("stitching function has no Functions")
+		}
+		for i, fnObj := range fns {
+			sub, err := loadPDFFunctionDepth(ctx, fnObj, depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("stitching function %d: %w", i, err)
+			}
+			f.Functions = append(f.Functions, sub)
+		}
+
+	case 0, 4:
+		if streamDict == nil {
+			return nil, fmt.Errorf("function type %d is not a stream", f.FunctionType)
+		}
+		if len(f.Range) < 2 {
+			return nil, fmt.Errorf("function type %d requires Range", f.FunctionType)
+		}
+		if err := streamDict.Decode(); err != nil {
+			return nil, fmt.Errorf("failed to decode function stream: %w", err)
+		}
+		if f.FunctionType == 4 {
+			program, err := parsePSFunction(streamDict.Content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse PostScript function: %w", err)
+			}
+			f.Program = program
+			break
+		}
+
+		for _, v := range getNumberArray(ctx, dict, "Size") {
+			f.Size = append(f.Size, int(v))
+		}
+		if bps, found := dict.Find("BitsPerSample"); found {
+			if num, ok := bps.(types.Integer); ok {
+				f.BitsPerSample = int(num)
+			}
+		}
+		if len(f.Size) == 0 || len(f.Size) > 8 || f.BitsPerSample <= 0 || f.BitsPerSample > 32 {
+			return nil, fmt.Errorf("invalid sampled function (Size %v, BitsPerSample %d)", f.Size, f.BitsPerSample)
+		}
+		count, err := sampleTableCount(f.Size, len(f.Range)/2, f.BitsPerSample, len(streamDict.Content))
+		if err != nil {
+			return nil, err
+		}
+		f.Samples = readSampleTable(streamDict.Content, f.BitsPerSample, count)
+
+	default:
+		return nil, fmt.Errorf("unsupported function type %d", f.FunctionType)
+	}
+
+	return f, nil
+}
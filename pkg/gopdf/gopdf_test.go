@@ -1,8 +1,24 @@
 package gopdf
 
 import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
 	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/opentype/api"
+	"github.com/go-text/typesetting/opentype/api/font/cff"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
 )
 
 func TestMatrixOperations(t *testing.T) {
@@ -68,6 +84,50 @@ func TestMatrixInvert(t *testing.T) {
 	}
 }
 
+func TestMatrixMethodsMatchFreeFunctions(t *testing.T) {
+	m := &Matrix{XX: 2, YX: 1, XY: -1, YY: 3, X0: 5, Y0: -7}
+	o := &Matrix{XX: 0.5, YX: -2, XY: 4, YY: 1, X0: 3, Y0: 2}
+
+	inv, ok := m.Invert()
+	if !ok {
+		t.Fatal("Invert reported a regular matrix as singular")
+	}
+	// m × m⁻¹ = 单位矩阵
+	if id := m.Multiply(&inv); math.Abs(id.XX-1) > 1e-12 || math.Abs(id.YY-1) > 1e-12 ||
+		math.Abs(id.XY) > 1e-12 || math.Abs(id.YX) > 1e-12 || math.Abs(id.X0) > 1e-12 || math.Abs(id.Y0) > 1e-12 {
+		t.Errorf("m × Invert(m) = %s, want identity", id.String())
+	}
+	inPlace := *m
+	if MatrixInvert(&inPlace) != StatusSuccess || inPlace != inv {
+		t.Errorf("MatrixInvert = %+v, want %+v", inPlace, inv)
+	}
+
+	var product Matrix
+	MatrixMultiply(&product, m, o)
+	if product != *m.Multiply(o) {
+		t.Errorf("MatrixMultiply = %+v, want %+v", product, *m.Multiply(o))
+	}
+	// result 与操作数相同时也应得到正确结果
+	aliased := *m
+	MatrixMultiply(&aliased, &aliased, o)
+	if aliased != product {
+		t.Errorf("aliased MatrixMultiply = %+v, want %+v", aliased, product)
+	}
+
+	if x, y := MatrixTransformPoint(m, 1, 2); x != 5 || y != 0 {
+		t.Errorf("MatrixTransformPoint = (%v, %v), want (5, 0)", x, y)
+	}
+
+	singular := &Matrix{XX: 1, YX: 2, XY: 2, YY: 4}
+	if _, ok := singular.Invert(); ok {
+		t.Error("Invert should report a singular matrix")
+	}
+	before := *singular
+	if MatrixInvert(singular) != StatusInvalidMatrix || *singular != before {
+		t.Error("MatrixInvert should leave a singular matrix unchanged and report StatusInvalidMatrix")
+	}
+}
+
 func TestSurfaceCreation(t *testing.T) {
 	surface := NewImageSurface(FormatARGB32, 100, 200)
 	if surface.Status() != StatusSuccess {
@@ -379,3 +439,2167 @@ func TestMatrixTransformations(t *testing.T) {
 		t.Errorf("IdentityMatrix failed, got %+v", matrix)
 	}
 }
+
+func TestConvertGopdfSurfaceToImageUnpremultiply(t *testing.T) {
+	tests := []struct {
+		bgra [4]uint8 // 预乘 BGRA 输入
+		rgba [4]uint8 // 期望的非预乘 RGBA 输出
+	}{
+		{[4]uint8{0, 0, 255, 255}, [4]uint8{255, 0, 0, 255}},    // 不透明红色保持不变
+		{[4]uint8{0, 64, 128, 128}, [4]uint8{255, 128, 0, 128}}, // 半透明：64*255/128=127.5 四舍五入为 128
+		{[4]uint8{10, 20, 30, 51}, [4]uint8{150, 100, 50, 51}},  // 20% alpha 精确还原
+		{[4]uint8{0, 0, 200, 100}, [4]uint8{255, 0, 0, 100}},    // 偏差数据 r > a：限制为 255 而不是溢出
+		{[4]uint8{40, 80, 120, 0}, [4]uint8{0, 0, 0, 0}},        // 完全透明输出透明黑色
+	}
+
+	surface := NewImageSurface(FormatARGB32, len(tests), 1)
+	defer surface.Destroy()
+	imgSurf := surface.(ImageSurface)
+	data := imgSurf.GetData()
+	for i, tt := range tests {
+		copy(data[i*4:], tt.bgra[:])
+	}
+	// 写入 BGRA 数据后通过 MarkDirty 同步到 Go 图像
+	surface.MarkDirty()
+
+	img, ok := ConvertGopdfSurfaceToImage(imgSurf).(*image.RGBA)
+	if !ok {
+		t.Fatal("expected *image.RGBA")
+	}
+	for i, tt := range tests {
+		checkPixel(t, img, i, 0, tt.rgba[0], tt.rgba[1], tt.rgba[2], tt.rgba[3])
+	}
+}
+
+func TestMarkDirtyRectangleConvertsBGRA(t *testing.T) {
+	surface := NewImageSurface(FormatARGB32, 3, 2)
+	defer surface.Destroy()
+	imgSurf := surface.(ImageSurface)
+	data := imgSurf.GetData()
+	stride := imgSurf.GetStride()
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			// 预乘 BGRA：半透明蓝色
+			copy(data[y*stride+x*4:], []uint8{128, 0, 0, 128})
+		}
+	}
+
+	// 矩形超出表面时裁剪到边界，只同步 (1,1)-(3,2)
+	surface.MarkDirtyRectangle(1, 1, 5, 5)
+
+	img := imgSurf.GetGoImage().(*image.RGBA)
+	checkPixel(t, img, 1, 1, 0, 0, 255, 128)
+	checkPixel(t, img, 2, 1, 0, 0, 255, 128)
+	checkPixel(t, img, 0, 1, 0, 0, 0, 0)
+	checkPixel(t, img, 1, 0, 0, 0, 0, 0)
+}
+
+func TestUnpremultiplyAlphaRectByteOrder(t *testing.T) {
+	// 各通道取不同的值，B/R 互换或把首字节当作 alpha 都会得到错误结果
+	surface := NewImageSurface(FormatARGB32, 2, 1)
+	defer surface.Destroy()
+	imgSurf := surface.(ImageSurface)
+	copy(imgSurf.GetData(), []uint8{
+		10, 20, 30, 255, // 不透明 BGRA
+		10, 20, 30, 51, // 预乘的半透明 BGRA
+	})
+
+	surface.MarkDirty()
+
+	img := imgSurf.GetGoImage().(*image.RGBA)
+	checkPixel(t, img, 0, 0, 30, 20, 10, 255)
+	checkPixel(t, img, 1, 0, 150, 100, 50, 51)
+}
+
+func TestImageSurfaceRGBARoundTrip(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	copy(src.Pix, []uint8{
+		255, 0, 0, 255, // 不透明红色
+		255, 128, 0, 128, // 半透明橙色
+		40, 80, 120, 0, // 完全透明
+	})
+
+	surface := NewImageSurface(FormatARGB32, 3, 1)
+	defer surface.Destroy()
+	imgSurf := surface.(ImageSurface)
+	imgSurf.SetFromRGBA(src)
+
+	// BGRA 数据为预乘值
+	wantData := []uint8{0, 0, 255, 255, 0, 64, 128, 128, 0, 0, 0, 0}
+	if got := imgSurf.GetData()[:12]; !bytes.Equal(got, wantData) {
+		t.Errorf("GetData() = %v, want %v", got, wantData)
+	}
+
+	// AsRGBA 返回副本；完全透明像素变为透明黑色
+	img := imgSurf.AsRGBA()
+	checkPixel(t, img, 0, 0, 255, 0, 0, 255)
+	checkPixel(t, img, 1, 0, 255, 128, 0, 128)
+	checkPixel(t, img, 2, 0, 0, 0, 0, 0)
+	img.Pix[0] = 0
+	if r, _, _, _ := imgSurf.AsRGBA().At(0, 0).RGBA(); r>>8 != 255 {
+		t.Error("AsRGBA should return a copy of the surface pixels")
+	}
+}
+
+func TestConsecutiveTjAdvance(t *testing.T) {
+	// 文本矩阵带 10 倍缩放、字号为 1：推进量在文本空间中计算，需经过文本矩阵缩放
+	font := &Font{
+		Name:    "F1",
+		Subtype: "Type1",
+		Widths:  &FontWidths{FirstChar: 65, LastChar: 67, Widths: []float64{600, 500, 700}},
+	}
+
+	for _, renderMode := range []int{0, 3} {
+		surface := NewImageSurface(FormatARGB32, 200, 100)
+		gopdfCtx := NewContext(surface)
+		ctx := NewRenderContext(gopdfCtx, 200, 100)
+		ctx.Resources.SetFont("F1", font)
+
+		ops := []PDFOperator{
+			&OpBeginText{},
+			&OpSetFont{FontName: "F1", FontSize: 1},
+			&OpSetTextRenderMode{Mode: renderMode},
+			&OpSetTextMatrix{Matrix: &Matrix{XX: 10, YY: 10, X0: 20, Y0: 50}},
+		}
+		for _, op := range ops {
+			if err := op.Execute(ctx); err != nil {
+				t.Fatalf("%s failed: %v", op.Name(), err)
+			}
+		}
+
+		// 第一段 "AB" 宽 (600+500)/1000 × 10 = 11，第二段从其末尾开始
+		if err := (&OpShowText{Text: "AB"}).Execute(ctx); err != nil {
+			t.Fatalf("first Tj failed: %v", err)
+		}
+		secondStart, y := ctx.TextState.TextMatrix.Transform(0, 0)
+		if math.Abs(secondStart-31) > 1e-9 || y != 50 {
+			t.Errorf("Tr %d: second run starts at (%.4f, %.4f), want (31, 50)", renderMode, secondStart, y)
+		}
+
+		if err := (&OpShowText{Text: "C"}).Execute(ctx); err != nil {
+			t.Fatalf("second Tj failed: %v", err)
+		}
+		end, _ := ctx.TextState.TextMatrix.Transform(0, 0)
+		if math.Abs(end-38) > 1e-9 {
+			t.Errorf("Tr %d: text ends at %.4f, want 38", renderMode, end)
+		}
+		if ctx.TextState.TextMatrix.XX != 10 || ctx.TextState.TextMatrix.YY != 10 {
+			t.Errorf("Tr %d: text matrix scale changed to %.4f/%.4f", renderMode,
+				ctx.TextState.TextMatrix.XX, ctx.TextState.TextMatrix.YY)
+		}
+
+		gopdfCtx.Destroy()
+		surface.Destroy()
+	}
+}
+
+func TestLigatureToUnicodeGlyphText(t *testing.T) {
+	// CID 1 是 "fi" 连字，解码结果 "fi x" 比 CID 多一个字符：
+	// 之后的字形仍须与各自的 CID 配对，CID 2 是空格（应用 Tw），CID 3 是 "x"
+	cidMap, err := ParseToUnicodeCMap([]byte(`begincmap
+3 beginbfchar
+<0001> <00660069>
+<0002> <0020>
+<0003> <0078>
+endbfchar
+endcmap
+`))
+	if err != nil {
+		t.Fatalf("ParseToUnicodeCMap failed: %v", err)
+	}
+	font := &Font{
+		Name:         "F1",
+		Subtype:      "/Type0",
+		DefaultWidth: 1000,
+		ToUnicodeMap: cidMap,
+	}
+
+	surface := NewImageSurface(FormatARGB32, 140, 60)
+	defer surface.Destroy()
+	gopdfCtx := NewContext(surface)
+	defer gopdfCtx.Destroy()
+
+	gopdfCtx.SetSourceRGB(1, 1, 1)
+	gopdfCtx.Paint()
+	gopdfCtx.Translate(0, 60)
+	gopdfCtx.Scale(1, -1)
+
+	ctx := NewRenderContext(gopdfCtx, 140, 60)
+	ctx.Resources.SetFont("F1", font)
+	ops := []PDFOperator{
+		&OpBeginText{},
+		&OpSetFont{FontName: "F1", FontSize: 1},
+		&OpSetWordSpacing{Spacing: 0.5},
+		&OpSetTextMatrix{Matrix: &Matrix{XX: 30, YY: 30, X0: 10, Y0: 15}},
+		&OpShowText{Text: "<000100020003>", Hex: true},
+		&OpEndText{},
+	}
+	for _, op := range ops {
+		if err := op.Execute(ctx); err != nil {
+			t.Fatalf("%s failed: %v", op.Name(), err)
+		}
+	}
+
+	// 每个字形宽 30，空格额外加 Tw × 30 = 15：fi 在 10..40，空格在 40..85，x 在 85..115
+	if end := ctx.TextState.TextMatrix.X0; math.Abs(end-115) > 1e-9 {
+		t.Errorf("text ends at %.4f, want 115 (word spacing applied to CID 2 only)", end)
+	}
+
+	img := surface.(ImageSurface).GetGoImage()
+	inked := func(x0, x1 int) bool {
+		for y := 0; y < 60; y++ {
+			for x := x0; x < x1; x++ {
+				if r, _, _, _ := img.At(x, y).RGBA(); r>>8 < 128 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if inked(45, 84) {
+		t.Errorf("ink found where the space glyph is drawn")
+	}
+	if !inked(85, 115) {
+		t.Errorf("no ink for the x glyph after the ligature")
+	}
+}
+
+func TestVerticalTextAdvance(t *testing.T) {
+	// Identity-V 字体沿 Y 轴向下推进：推进量取自 W2/DW2，TJ 数字调整也作用于 Y
+	font := &Font{
+		Name:            "F1",
+		Subtype:         "/Type0",
+		Encoding:        "/Identity-V",
+		IsIdentity:      true,
+		Vertical:        true,
+		DefaultWidth:    1000,
+		Widths:          &FontWidths{CIDWidths: map[uint16]float64{}},
+		VerticalMetrics: map[uint16]VerticalMetric{0x4E01: {W1: -800, VX: 400, VY: 900}},
+	}
+
+	surface := NewImageSurface(FormatARGB32, 200, 200)
+	defer surface.Destroy()
+	gopdfCtx := NewContext(surface)
+	defer gopdfCtx.Destroy()
+	ctx := NewRenderContext(gopdfCtx, 200, 200)
+	ctx.Resources.SetFont("F1", font)
+
+	ops := []PDFOperator{
+		&OpBeginText{},
+		&OpSetFont{FontName: "F1", FontSize: 1},
+		&OpSetTextMatrix{Matrix: &Matrix{XX: 10, YY: 10, X0: 50, Y0: 150}},
+		&OpShowText{Text: "<4E004E01>", Hex: true},
+	}
+	for _, op := range ops {
+		if err := op.Execute(ctx); err != nil {
+			t.Fatalf("%s failed: %v", op.Name(), err)
+		}
+	}
+	// DW2 默认推进 -1000，加上 W2 中的 -800：(1 + 0.8) × 10 = 18
+	if x, y := ctx.TextState.TextMatrix.Transform(0, 0); math.Abs(x-50) > 1e-9 || math.Abs(y-132) > 1e-9 {
+		t.Errorf("after vertical Tj: (%.4f, %.4f), want (50, 132)", x, y)
+	}
+
+	if err := (&OpShowTextArray{Array: []any{HexString("<4E00>"), 200.0, HexString("<4E00>")}}).Execute(ctx); err != nil {
+		t.Fatalf("TJ failed: %v", err)
+	}
+	if x, y := ctx.TextState.TextMatrix.Transform(0, 0); math.Abs(x-50) > 1e-9 || math.Abs(y-110) > 1e-9 {
+		t.Errorf("after vertical TJ: (%.4f, %.4f), want (50, 110)", x, y)
+	}
+
+	// 字形按位置向量从竖排原点偏移：默认 v = (w0/2, 880)，W2 中的字形使用自己的 v
+	if x, y := ctx.TextState.glyphOrigin(0x4E00, -1, 1); math.Abs(x+0.5) > 1e-9 || math.Abs(y+1.88) > 1e-9 {
+		t.Errorf("default glyph origin = (%.4f, %.4f), want (-0.5, -1.88)", x, y)
+	}
+	if x, y := ctx.TextState.glyphOrigin(0x4E01, 0, 1); math.Abs(x+0.4) > 1e-9 || math.Abs(y+0.9) > 1e-9 {
+		t.Errorf("W2 glyph origin = (%.4f, %.4f), want (-0.4, -0.9)", x, y)
+	}
+}
+
+func TestTextLineMovesInTextSpace(t *testing.T) {
+	// Td/T* 的偏移量在文本空间中，需经过文本矩阵缩放；T* 之后 X 回到行首
+	surface := NewImageSurface(FormatARGB32, 200, 200)
+	defer surface.Destroy()
+	gopdfCtx := NewContext(surface)
+	defer gopdfCtx.Destroy()
+	ctx := NewRenderContext(gopdfCtx, 200, 200)
+
+	ops := []PDFOperator{
+		&OpBeginText{},
+		&OpSetFont{FontName: "F1", FontSize: 1},
+		&OpSetLeading{Leading: 1.5},
+		&OpSetTextMatrix{Matrix: &Matrix{XX: 10, YY: 10, X0: 20, Y0: 150}},
+		&OpShowText{Text: "AB"},
+		&OpMoveTextPosition{Tx: 2, Ty: -1},
+	}
+	for _, op := range ops {
+		if err := op.Execute(ctx); err != nil {
+			t.Fatalf("%s failed: %v", op.Name(), err)
+		}
+	}
+	if x, y := ctx.TextState.TextMatrix.Transform(0, 0); x != 40 || y != 140 {
+		t.Errorf("after Td: (%.2f, %.2f), want (40, 140)", x, y)
+	}
+
+	for _, op := range []PDFOperator{&OpShowText{Text: "AB"}, &OpMoveToNextLine{}} {
+		if err := op.Execute(ctx); err != nil {
+			t.Fatalf("%s failed: %v", op.Name(), err)
+		}
+	}
+	if x, y := ctx.TextState.TextMatrix.Transform(0, 0); x != 40 || y != 125 {
+		t.Errorf("after T*: (%.2f, %.2f), want (40, 125)", x, y)
+	}
+}
+
+func TestGlyphOrientationInPDFSpace(t *testing.T) {
+	// 页面坐标系 Y 轴向上（与 RenderPage 相同）：字形应位于基线之上，
+	// 且字号 1 + 文本矩阵 40 倍缩放得到 40pt 的字形
+	surface := NewImageSurface(FormatARGB32, 100, 100)
+	defer surface.Destroy()
+	gopdfCtx := NewContext(surface)
+	defer gopdfCtx.Destroy()
+
+	gopdfCtx.SetSourceRGB(1, 1, 1)
+	gopdfCtx.Paint()
+	gopdfCtx.Translate(0, 100)
+	gopdfCtx.Scale(1, -1)
+
+	ctx := NewRenderContext(gopdfCtx, 100, 100)
+	ops := []PDFOperator{
+		&OpBeginText{},
+		&OpSetFont{FontName: "F1", FontSize: 1},
+		&OpSetTextMatrix{Matrix: &Matrix{XX: 40, YY: 40, X0: 20, Y0: 30}},
+		&OpShowText{Text: "H"},
+		&OpEndText{},
+	}
+	for _, op := range ops {
+		if err := op.Execute(ctx); err != nil {
+			t.Fatalf("%s failed: %v", op.Name(), err)
+		}
+	}
+
+	// 基线 y=30 对应图像第 70 行
+	img := surface.(ImageSurface).GetGoImage()
+	top, bottom := 100.0, -1.0
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r>>8 < 128 {
+				top, bottom = min(top, float64(y)), max(bottom, float64(y))
+			}
+		}
+	}
+	if bottom < 0 {
+		t.Fatalf("no glyph ink rendered")
+	}
+	if bottom > 71 {
+		t.Errorf("glyph extends below the baseline: ink rows %.0f..%.0f, baseline row 70", top, bottom)
+	}
+	if height := bottom - top; height < 20 || height > 40 {
+		t.Errorf("glyph height %.0f px, want a cap height of a 40pt font", height)
+	}
+}
+
+func TestTextRenderModes(t *testing.T) {
+	// 蓝色填充 + 红色描边绘制 60pt 的 "H"，统计各颜色的像素
+	render := func(mode int) (ctx *RenderContext, blue, red int) {
+		surface := NewImageSurface(FormatARGB32, 100, 100)
+		t.Cleanup(surface.Destroy)
+		gopdfCtx := NewContext(surface)
+		t.Cleanup(gopdfCtx.Destroy)
+
+		gopdfCtx.SetSourceRGB(1, 1, 1)
+		gopdfCtx.Paint()
+		gopdfCtx.Translate(0, 100)
+		gopdfCtx.Scale(1, -1)
+
+		ctx = NewRenderContext(gopdfCtx, 100, 100)
+		ops := []PDFOperator{
+			&OpSetFillColorRGB{R: 0, G: 0, B: 1},
+			&OpSetStrokeColorRGB{R: 1, G: 0, B: 0},
+			&OpSetLineWidth{Width: 4},
+			&OpBeginText{},
+			&OpSetFont{FontName: "F1", FontSize: 1},
+			&OpSetTextRenderMode{Mode: mode},
+			&OpSetTextMatrix{Matrix: &Matrix{XX: 60, YY: 60, X0: 20, Y0: 30}},
+			&OpShowText{Text: "H"},
+		}
+		for _, op := range ops {
+			if err := op.Execute(ctx); err != nil {
+				t.Fatalf("%s failed: %v", op.Name(), err)
+			}
+		}
+
+		img := surface.(ImageSurface).GetGoImage()
+		for y := 0; y < 100; y++ {
+			for x := 0; x < 100; x++ {
+				r, g, b, _ := img.At(x, y).RGBA()
+				switch {
+				case b>>8 > 200 && r>>8 < 100 && g>>8 < 100:
+					blue++
+				case r>>8 > 200 && b>>8 < 150 && g>>8 < 150:
+					red++
+				}
+			}
+		}
+		return ctx, blue, red
+	}
+
+	if _, blue, red := render(0); blue == 0 || red != 0 {
+		t.Errorf("Tr 0: blue=%d red=%d, want fill only", blue, red)
+	}
+	if _, blue, red := render(1); blue != 0 || red == 0 {
+		t.Errorf("Tr 1: blue=%d red=%d, want stroke only", blue, red)
+	}
+	if _, blue, red := render(2); blue == 0 || red == 0 {
+		t.Errorf("Tr 2: blue=%d red=%d, want fill and stroke", blue, red)
+	}
+
+	// Tr 7：不绘制，字形轮廓在 ET 时加入裁剪路径
+	ctx, blue, red := render(7)
+	if blue != 0 || red != 0 {
+		t.Errorf("Tr 7: blue=%d red=%d, want nothing painted", blue, red)
+	}
+	if ctx.TextClipPath == nil || ctx.TextClipPath.IsEmpty() {
+		t.Fatal("Tr 7: expected glyph outlines in the text clip path")
+	}
+	if err := (&OpEndText{}).Execute(ctx); err != nil {
+		t.Fatalf("ET failed: %v", err)
+	}
+	if ctx.TextClipPath != nil {
+		t.Error("ET should consume the text clip path")
+	}
+	if ctx.GopdfCtx.(*context).gstate.clip == nil {
+		t.Error("ET should install the text clip")
+	}
+}
+
+func TestGlyphFollowsRotatedTextMatrix(t *testing.T) {
+	// 文本矩阵旋转 90°（逆时针）：字形沿 +Y 方向排列，字高朝向 -X，
+	// 因此墨迹位于原点 (50,20) 的左侧和上方
+	surface := NewImageSurface(FormatARGB32, 100, 100)
+	defer surface.Destroy()
+	gopdfCtx := NewContext(surface)
+	defer gopdfCtx.Destroy()
+
+	gopdfCtx.SetSourceRGB(1, 1, 1)
+	gopdfCtx.Paint()
+	gopdfCtx.Translate(0, 100)
+	gopdfCtx.Scale(1, -1)
+
+	ctx := NewRenderContext(gopdfCtx, 100, 100)
+	ops := []PDFOperator{
+		&OpBeginText{},
+		&OpSetFont{FontName: "F1", FontSize: 1},
+		&OpSetTextMatrix{Matrix: &Matrix{XX: 0, YX: 40, XY: -40, YY: 0, X0: 50, Y0: 20}},
+		&OpShowText{Text: "H"},
+		&OpEndText{},
+	}
+	for _, op := range ops {
+		if err := op.Execute(ctx); err != nil {
+			t.Fatalf("%s failed: %v", op.Name(), err)
+		}
+	}
+
+	// 原点 (50,20) 对应图像第 50 列、第 80 行
+	img := surface.(ImageSurface).GetGoImage()
+	left, right, top, bottom := 100.0, -1.0, 100.0, -1.0
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r>>8 < 128 {
+				left, right = min(left, float64(x)), max(right, float64(x))
+				top, bottom = min(top, float64(y)), max(bottom, float64(y))
+			}
+		}
+	}
+	if right < 0 {
+		t.Fatalf("no glyph ink rendered")
+	}
+	if right > 51 || bottom > 81 {
+		t.Errorf("glyph ink %.0f..%.0f x %.0f..%.0f extends right of or below the rotated origin (50,80)",
+			left, right, top, bottom)
+	}
+	if width := right - left; width < 20 || width > 40 {
+		t.Errorf("glyph extent along -X %.0f px, want a cap height of a 40pt font", width)
+	}
+}
+
+func TestSubUnitFontSizeScalesWithTextMatrix(t *testing.T) {
+	// "/F1 0.5 Tf 80 0 0 80 x y Tm" 得到 40pt 字形：小于 1 的字号不能被替换为默认字号
+	surface := NewImageSurface(FormatARGB32, 100, 100)
+	defer surface.Destroy()
+	gopdfCtx := NewContext(surface)
+	defer gopdfCtx.Destroy()
+
+	gopdfCtx.SetSourceRGB(1, 1, 1)
+	gopdfCtx.Paint()
+	gopdfCtx.Translate(0, 100)
+	gopdfCtx.Scale(1, -1)
+
+	ctx := NewRenderContext(gopdfCtx, 100, 100)
+	ops := []PDFOperator{
+		&OpBeginText{},
+		&OpSetFont{FontName: "F1", FontSize: 0.5},
+		&OpSetTextMatrix{Matrix: &Matrix{XX: 80, YY: 80, X0: 10, Y0: 20}},
+		&OpShowText{Text: "H"},
+		&OpEndText{},
+	}
+	for _, op := range ops {
+		if err := op.Execute(ctx); err != nil {
+			t.Fatalf("%s failed: %v", op.Name(), err)
+		}
+	}
+
+	img := surface.(ImageSurface).GetGoImage()
+	top, bottom := 100.0, -1.0
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r>>8 < 128 {
+				top, bottom = min(top, float64(y)), max(bottom, float64(y))
+			}
+		}
+	}
+	if bottom < 0 {
+		t.Fatalf("no glyph ink rendered")
+	}
+	if height := bottom - top; height < 20 || height > 40 {
+		t.Errorf("glyph height %.0f px, want a cap height of a 40pt font", height)
+	}
+}
+
+func TestRecordingSurfaceReplay(t *testing.T) {
+	recording := NewRecordingSurface(ContentColorAlpha, 100, 100)
+	defer recording.Destroy()
+
+	ctx := NewContext(recording)
+	ctx.Translate(10, 10)
+	ctx.SetSourceRGB(1, 0, 0)
+	ctx.Rectangle(0, 0, 20, 20)
+	ctx.Fill()
+	ctx.SetSourceRGB(0, 0, 1)
+	ctx.SetLineWidth(4)
+	ctx.MoveTo(40, 50)
+	ctx.LineTo(60, 50)
+	ctx.Stroke()
+	ctx.Destroy()
+
+	rs := recording.(RecordingSurface)
+	ink := rs.InkExtents()
+	// 矩形 (10,10)-(30,30)，线段 (50,60)-(70,60) 外扩半个线宽
+	if ink != (Rectangle{X: 10, Y: 10, Width: 62, Height: 52}) {
+		t.Errorf("InkExtents = %+v, want {10 10 62 52}", ink)
+	}
+
+	// 同一份记录重放到不同缩放（DPI）的目标
+	for _, scale := range []float64{1, 2} {
+		size := int(100 * scale)
+		target := NewImageSurface(FormatARGB32, size, size)
+		targetCtx := NewContext(target)
+		targetCtx.Scale(scale, scale)
+		if err := rs.Replay(targetCtx); err != nil {
+			t.Fatalf("Replay at scale %.0f failed: %v", scale, err)
+		}
+		if m := targetCtx.GetMatrix(); m.XX != scale || m.X0 != 0 {
+			t.Errorf("Replay changed the target matrix: %+v", m)
+		}
+		targetCtx.Destroy()
+
+		img := target.(ImageSurface).GetGoImage().(*image.RGBA)
+		px := func(v float64) int { return int(v * scale) }
+		checkPixel(t, img, px(20), px(20), 255, 0, 0, 255)
+		checkPixel(t, img, px(60), px(60), 0, 0, 255, 255)
+		checkPixel(t, img, px(5), px(5), 0, 0, 0, 0)
+		checkPixel(t, img, px(40), px(20), 0, 0, 0, 0)
+		target.Destroy()
+	}
+}
+
+func TestVectorSurfacesRecordPaintAlpha(t *testing.T) {
+	recording := NewRecordingSurface(ContentColorAlpha, 10, 10)
+	defer recording.Destroy()
+
+	// PaintWithAlpha 和纯色 Mask 都记录为带整体透明度的 Paint
+	ctx := NewContext(recording)
+	ctx.SetSourceRGB(1, 0, 0)
+	ctx.PaintWithAlpha(0.5)
+	ctx.SetSourceRGB(0, 0, 1)
+	mask := NewPatternRGBA(0, 0, 0, 0.25)
+	ctx.Mask(mask)
+	mask.Destroy()
+	ctx.Destroy()
+
+	ops := recording.(*recordingSurface).operations
+	if len(ops) != 2 || ops[0].alpha != 0.5 || ops[1].alpha != 0.25 {
+		t.Fatalf("recorded paints = %d, want alphas 0.5 and 0.25", len(ops))
+	}
+
+	// 重放时按记录的透明度绘制：红色 0.5 之上叠加 0.25 的蓝色
+	target := NewImageSurface(FormatARGB32, 10, 10)
+	defer target.Destroy()
+	targetCtx := NewContext(target)
+	if err := recording.(RecordingSurface).Replay(targetCtx); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	targetCtx.Destroy()
+	got := target.(ImageSurface).GetGoImage().(*image.RGBA).RGBAAt(5, 5)
+	if got.A < 157 || got.A > 160 || got.R < 93 || got.R > 97 || got.B < 62 || got.B > 66 {
+		t.Errorf("replayed pixel = %v, want about {95 0 64 159}", got)
+	}
+}
+
+func TestTranslucentSolidFill(t *testing.T) {
+	surface := NewImageSurface(FormatARGB32, 4, 4)
+	defer surface.Destroy()
+	ctx := NewContext(surface)
+	defer ctx.Destroy()
+
+	ctx.SetSourceRGB(1, 1, 1)
+	ctx.Paint()
+	// 半透明颜色只与背景按 alpha 混合一次：0.5×(1,0,0) + 0.5×白色
+	ctx.SetSourceRGBA(1, 0, 0, 0.5)
+	ctx.Rectangle(0, 0, 4, 4)
+	ctx.Fill()
+
+	img := surface.(ImageSurface).GetGoImage().(*image.RGBA)
+	checkPixel(t, img, 2, 2, 255, 128, 128, 255)
+}
+
+func TestTranslucentFillOverTranslucentDestination(t *testing.T) {
+	surface := NewImageSurface(FormatARGB32, 4, 4)
+	defer surface.Destroy()
+	ctx := NewContext(surface)
+	defer ctx.Destroy()
+
+	// 透明背景上先画半透明红色，再画半透明蓝色：
+	// alpha = 0.5 + 0.5×0.5，预乘分量为蓝 0.5、红 0.5×0.5
+	ctx.SetSourceRGBA(1, 0, 0, 0.5)
+	ctx.Rectangle(0, 0, 4, 4)
+	ctx.Fill()
+	img := surface.(ImageSurface).GetGoImage().(*image.RGBA)
+	checkPixel(t, img, 1, 1, 127, 0, 0, 127)
+
+	ctx.SetSourceRGBA(0, 0, 1, 0.5)
+	ctx.Rectangle(0, 0, 4, 4)
+	ctx.Fill()
+	got := img.RGBAAt(1, 1)
+	want := color.RGBA{64, 0, 128, 191}
+	near := func(a, b uint8) bool { return int(a)-int(b) <= 2 && int(b)-int(a) <= 2 }
+	if !near(got.R, want.R) || got.G != 0 || !near(got.B, want.B) || !near(got.A, want.A) {
+		t.Errorf("pixel = %v, want %v", got, want)
+	}
+}
+
+func TestPDFBlendColorUnpremultipliesSource(t *testing.T) {
+	// color.RGBA 是预乘值：{128,0,0,128} 即 alpha 0.5 的纯红
+	src := color.RGBA{128, 0, 0, 128}
+	for _, op := range []Operator{OperatorOver, OperatorSource} {
+		if got := pdfBlendColor(src, op); got != (color.NRGBA{255, 0, 0, 128}) {
+			t.Errorf("op %v: pdfBlendColor = %v, want {255 0 0 128}", op, got)
+		}
+	}
+}
+
+func TestPorterDuffBlend(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	gray := color.NRGBA{128, 128, 128, 255}
+	tests := []struct {
+		name     string
+		src, dst color.NRGBA
+		op       Operator
+		want     color.NRGBA
+	}{
+		{"over opaque", gray, red, OperatorOver, gray},
+		{"over translucent", color.NRGBA{0, 0, 255, 128}, red, OperatorOver, color.NRGBA{127, 0, 128, 255}},
+		{"multiply", gray, red, OperatorMultiply, color.NRGBA{128, 0, 0, 255}},
+		{"screen", gray, red, OperatorScreen, color.NRGBA{255, 128, 128, 255}},
+		{"multiply onto transparent", gray, color.NRGBA{}, OperatorMultiply, gray},
+	}
+	near := func(got, want uint8) bool {
+		d := int(got) - int(want)
+		return d >= -1 && d <= 1
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PorterDuffBlend(tt.src, tt.dst, tt.op)
+			if !near(got.R, tt.want.R) || !near(got.G, tt.want.G) || !near(got.B, tt.want.B) || !near(got.A, tt.want.A) {
+				t.Errorf("PorterDuffBlend = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskAndPaintWithAlpha(t *testing.T) {
+	// 遮罩表面：左半不透明，右半透明
+	maskSurface := NewImageSurface(FormatARGB32, 4, 4)
+	defer maskSurface.Destroy()
+	maskCtx := NewContext(maskSurface)
+	maskCtx.SetSourceRGBA(0, 0, 0, 1)
+	maskCtx.Rectangle(0, 0, 2, 4)
+	maskCtx.Fill()
+	maskCtx.Destroy()
+
+	// 纯红源色经遮罩绘制
+	surface := NewImageSurface(FormatARGB32, 4, 4)
+	defer surface.Destroy()
+	ctx := NewContext(surface)
+	defer ctx.Destroy()
+	ctx.SetSourceRGB(1, 1, 1)
+	ctx.Paint()
+	ctx.SetSourceRGB(1, 0, 0)
+	ctx.MaskSurface(maskSurface, 0, 0)
+
+	img := surface.(ImageSurface).GetGoImage().(*image.RGBA)
+	checkPixel(t, img, 0, 1, 255, 0, 0, 255)
+	checkPixel(t, img, 3, 1, 255, 255, 255, 255)
+
+	// PaintWithAlpha：纯色源
+	ctx.SetSourceRGB(0, 0, 1)
+	if err := ctx.PaintWithAlpha(0.5); err != nil {
+		t.Fatalf("PaintWithAlpha failed: %v", err)
+	}
+	checkPixel(t, img, 3, 1, 127, 127, 255, 255)
+
+	// PaintWithAlpha：表面图案源
+	dst := NewImageSurface(FormatARGB32, 4, 4)
+	defer dst.Destroy()
+	dstCtx := NewContext(dst)
+	defer dstCtx.Destroy()
+	dstCtx.SetSourceRGB(1, 1, 1)
+	dstCtx.Paint()
+	dstCtx.SetSourceSurface(maskSurface, 0, 0)
+	if err := dstCtx.PaintWithAlpha(0.5); err != nil {
+		t.Fatalf("PaintWithAlpha with surface source failed: %v", err)
+	}
+	dstImg := dst.(ImageSurface).GetGoImage().(*image.RGBA)
+	checkPixel(t, dstImg, 0, 1, 127, 127, 127, 255)
+	checkPixel(t, dstImg, 3, 1, 255, 255, 255, 255)
+}
+
+func TestPushPopGroupRedirectsDrawing(t *testing.T) {
+	surface := NewImageSurface(FormatARGB32, 4, 4)
+	defer surface.Destroy()
+	ctx := NewContext(surface)
+	defer ctx.Destroy()
+
+	ctx.SetSourceRGB(1, 1, 1)
+	ctx.Paint()
+	img := surface.(ImageSurface).GetGoImage().(*image.RGBA)
+
+	// 组内绘制不能直接落到原目标上
+	ctx.PushGroup()
+	ctx.SetSourceRGB(1, 0, 0)
+	ctx.Rectangle(0, 0, 2, 4)
+	ctx.Fill()
+	checkPixel(t, img, 0, 1, 255, 255, 255, 255)
+
+	// PopGroupToSource 后 Paint 把组结果合成回原目标
+	ctx.PopGroupToSource()
+	if err := ctx.Paint(); err != nil {
+		t.Fatalf("Paint after PopGroupToSource failed: %v", err)
+	}
+	checkPixel(t, img, 0, 1, 255, 0, 0, 255)
+	checkPixel(t, img, 3, 1, 255, 255, 255, 255)
+	if ctx.Status() != StatusSuccess {
+		t.Fatalf("unexpected status after group: %v", ctx.Status())
+	}
+
+	// 没有对应 PushGroup 的 PopGroup 是错误
+	ctx.Save()
+	pattern := ctx.PopGroup()
+	defer pattern.Destroy()
+	if ctx.Status() != StatusInvalidPopGroup {
+		t.Errorf("PopGroup without PushGroup: expected %v, got %v", StatusInvalidPopGroup, ctx.Status())
+	}
+}
+
+func TestPaintedBoundsTracksGroupDrawing(t *testing.T) {
+	surface := NewImageSurface(FormatARGB32, 20, 20)
+	defer surface.Destroy()
+	ctx := NewContext(surface)
+	defer ctx.Destroy()
+
+	// 新压入的组表面还没有绘制任何像素
+	ctx.PushGroup()
+	groupImg := contextImage(ctx)
+	if got := paintedBounds(ctx, groupImg); !got.Empty() {
+		t.Errorf("painted bounds of an empty group = %v, want empty", got)
+	}
+
+	ctx.SetSourceRGB(1, 0, 0)
+	ctx.Rectangle(2, 3, 4, 5)
+	ctx.Fill()
+	if got, want := paintedBounds(ctx, groupImg), image.Rect(2, 3, 6, 8); got != want {
+		t.Errorf("painted bounds = %v, want %v", got, want)
+	}
+	pattern := ctx.PopGroup()
+	pattern.Destroy()
+}
+
+func TestClipMaskBoundedToPath(t *testing.T) {
+	surface := NewImageSurface(FormatARGB32, 200, 200)
+	defer surface.Destroy()
+	ctx := NewContext(surface)
+	defer ctx.Destroy()
+
+	// 裁剪遮罩只覆盖路径包围盒（外扩 1 像素），嵌套裁剪再与外层求交
+	ctx.Rectangle(10, 20, 30, 40)
+	ctx.Clip()
+	if got, want := ctx.(*context).gstate.clip.mask.Rect, image.Rect(9, 19, 41, 61); got != want {
+		t.Errorf("clip mask bounds = %v, want %v", got, want)
+	}
+	ctx.Rectangle(0, 0, 20, 200)
+	ctx.Clip()
+	if got, want := ctx.(*context).gstate.clip.mask.Rect, image.Rect(9, 19, 21, 61); got != want {
+		t.Errorf("nested clip mask bounds = %v, want %v", got, want)
+	}
+
+	ctx.SetSourceRGB(1, 0, 0)
+	ctx.Paint()
+	img := surface.(ImageSurface).GetGoImage().(*image.RGBA)
+	checkPixel(t, img, 15, 30, 255, 0, 0, 255)
+	checkPixel(t, img, 35, 30, 0, 0, 0, 0)
+	checkPixel(t, img, 5, 5, 0, 0, 0, 0)
+	checkPixel(t, img, 150, 150, 0, 0, 0, 0)
+}
+
+func TestBidiVisualRuns(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		base TextDirection
+		want []bidiRun
+	}{
+		{"ltr", "abc def", TextDirectionAuto, []bidiRun{{0, 7, false}}},
+		{"rtl embedded in ltr", "abc אבג def", TextDirectionAuto,
+			[]bidiRun{{0, 4, false}, {4, 7, true}, {7, 11, false}}},
+		// 从右到左的段落：逻辑上靠后的段在视觉上靠左
+		{"ltr embedded in rtl", "אבג abc דה", TextDirectionRTL,
+			[]bidiRun{{7, 10, true}, {4, 7, false}, {0, 4, true}}},
+		{"auto detects rtl paragraph", "אבג abc", TextDirectionAuto,
+			[]bidiRun{{4, 7, false}, {0, 4, true}}},
+	}
+	for _, tt := range tests {
+		got := bidiVisualRuns([]rune(tt.text), tt.base)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got runs %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got runs %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestTextToGlyphsMixedDirection(t *testing.T) {
+	// 混合方向的行按视觉顺序从左到右排列字形，不会重叠或倒退
+	sf := newTestScaledFont(t, 12)
+
+	for _, base := range []TextDirection{TextDirectionAuto, TextDirectionRTL} {
+		options := NewShapingOptions()
+		options.Direction = base
+		glyphs, _, _, status := sf.TextToGlyphsWithOptions(10, 20, "abc אבג def", options)
+		if status != StatusSuccess {
+			t.Fatalf("TextToGlyphsWithOptions failed: %v", status)
+		}
+		if len(glyphs) != 11 {
+			t.Fatalf("got %d glyphs, want 11", len(glyphs))
+		}
+		if glyphs[0].X != 10 {
+			t.Errorf("base %d: first glyph at x=%.2f, want 10", base, glyphs[0].X)
+		}
+		for i := 1; i < len(glyphs); i++ {
+			if glyphs[i].X <= glyphs[i-1].X {
+				t.Errorf("base %d: glyph %d at x=%.2f does not follow x=%.2f", base, i, glyphs[i].X, glyphs[i-1].X)
+			}
+		}
+	}
+}
+
+func TestTextToGlyphsClusters(t *testing.T) {
+	// 簇覆盖文本的全部字节，字形数之和等于字形总数
+	sf := newTestScaledFont(t, 12)
+
+	tests := []struct {
+		text     string
+		backward bool
+	}{
+		{"héllo wörld", false},
+		{"中文\r\nab\n", false},
+		{"office", false},
+		{"אבג דה", true},
+	}
+	for _, tt := range tests {
+		glyphs, clusters, flags, status := sf.TextToGlyphs(0, 0, tt.text)
+		if status != StatusSuccess {
+			t.Fatalf("%q: TextToGlyphs failed: %v", tt.text, status)
+		}
+		numBytes, numGlyphs := 0, 0
+		for _, c := range clusters {
+			if c.NumBytes == 0 && c.NumGlyphs == 0 {
+				t.Errorf("%q: empty cluster", tt.text)
+			}
+			numBytes += c.NumBytes
+			numGlyphs += c.NumGlyphs
+		}
+		if numBytes != len(tt.text) || numGlyphs != len(glyphs) {
+			t.Errorf("%q: clusters cover %d bytes/%d glyphs, want %d/%d",
+				tt.text, numBytes, numGlyphs, len(tt.text), len(glyphs))
+		}
+		if got := flags&TextClusterFlagBackward != 0; got != tt.backward {
+			t.Errorf("%q: backward = %v, want %v", tt.text, got, tt.backward)
+		}
+	}
+
+	// 多字节字符的簇包含该字符的全部字节
+	_, clusters, _, _ := sf.TextToGlyphs(0, 0, "aé中")
+	want := []TextCluster{{1, 1}, {2, 1}, {3, 1}}
+	if len(clusters) != len(want) {
+		t.Fatalf("clusters = %v, want %v", clusters, want)
+	}
+	for i := range want {
+		if clusters[i] != want[i] {
+			t.Errorf("cluster %d = %v, want %v", i, clusters[i], want[i])
+		}
+	}
+}
+
+func TestScaledFontTextExtentsAtFontSize(t *testing.T) {
+	// 文本范围按字体矩阵给出的实际字号整形，与整形后的字形前进量一致
+	fontFace := NewToyFontFace("sans-serif", FontSlantNormal, FontWeightNormal)
+	defer fontFace.Destroy()
+	fontMatrix := NewMatrix()
+	fontMatrix.InitScale(30, 30)
+	ctm := NewMatrix()
+	ctm.InitIdentity()
+	sf := NewScaledFont(fontFace, fontMatrix, ctm, nil)
+	defer sf.Destroy()
+
+	text := "Wave AV"
+	ext := sf.TextExtents(text)
+	glyphs, _, _, status := sf.TextToGlyphs(0, 0, text)
+	if status != StatusSuccess || len(glyphs) == 0 {
+		t.Fatalf("TextToGlyphs failed: %v", status)
+	}
+	last := glyphs[len(glyphs)-1]
+	if ext.XAdvance <= last.X {
+		t.Errorf("XAdvance = %.2f, want beyond the last glyph origin %.2f", ext.XAdvance, last.X)
+	}
+	if ext.Width <= 0 || ext.Width > ext.XAdvance+5 {
+		t.Errorf("Width = %.2f, want ink width close to XAdvance %.2f", ext.Width, ext.XAdvance)
+	}
+	// 大写字母的墨迹高度约为字号的 0.7
+	if ext.Height < 15 || ext.Height > 30 {
+		t.Errorf("Height = %.2f, want about 21 for a 30pt font", ext.Height)
+	}
+	if ext.YBearing >= 0 {
+		t.Errorf("YBearing = %.2f, want negative (ink above the baseline)", ext.YBearing)
+	}
+
+	pango := newTestScaledFont(t, 30).TextExtents(text)
+	if math.Abs(ext.XAdvance-pango.XAdvance) > 0.01 {
+		t.Errorf("XAdvance = %.2f, want %.2f as measured by PangoPdfScaledFont", ext.XAdvance, pango.XAdvance)
+	}
+}
+
+func TestScaledFontTextExtentsFractionalSize(t *testing.T) {
+	// 小数字号和小于 1 的字号按实际大小整形，前进量与字号成正比，不能截断为整数
+	fontFace := NewToyFontFace("sans-serif", FontSlantNormal, FontWeightNormal)
+	defer fontFace.Destroy()
+	ctm := NewMatrix()
+	ctm.InitIdentity()
+	extentsAt := func(size float64) *TextExtents {
+		fontMatrix := NewMatrix()
+		fontMatrix.InitScale(size, size)
+		sf := NewScaledFont(fontFace, fontMatrix, ctm, nil)
+		defer sf.Destroy()
+		return sf.TextExtents("Wave AV")
+	}
+
+	base := extentsAt(30)
+	for _, size := range []float64{10.5, 0.5} {
+		ext := extentsAt(size)
+		want := base.XAdvance * size / 30
+		// 26.6 定点数的舍入误差每个字形不超过 1/64
+		if math.Abs(ext.XAdvance-want) > 7.0/64 {
+			t.Errorf("size %g: XAdvance = %.3f, want about %.3f", size, ext.XAdvance, want)
+		}
+		if ext.Height <= 0 || ext.Height > size {
+			t.Errorf("size %g: Height = %.3f, want within (0, %g]", size, ext.Height, size)
+		}
+	}
+}
+
+func TestGetKerningMatchesShaping(t *testing.T) {
+	// 字距与整形结果一致：字符对的前进量 = 单个字符前进量之和 + 字距
+	sf := newTestScaledFont(t, 40)
+	for _, pair := range []string{"AV", "To", "Ty", "ab"} {
+		runes := []rune(pair)
+		kerning, status := sf.GetKerning(runes[0], runes[1])
+		if status != StatusSuccess {
+			t.Fatalf("GetKerning(%q) failed: %v", pair, status)
+		}
+		separate := sf.TextExtents(string(runes[0])).XAdvance + sf.TextExtents(string(runes[1])).XAdvance
+		if got := sf.TextExtents(pair).XAdvance; math.Abs(got-(separate+kerning)) > 0.05 {
+			t.Errorf("%q: advance %.3f, want %.3f + kerning %.3f", pair, got, separate, kerning)
+		}
+
+		// TextToGlyphs 的字形位置已包含字距，不会重复计算
+		glyphs, _, _, _ := sf.TextToGlyphs(0, 0, pair)
+		if len(glyphs) == 2 {
+			want := sf.TextExtents(string(runes[0])).XAdvance + kerning
+			if math.Abs(glyphs[1].X-want) > 0.05 {
+				t.Errorf("%q: second glyph at %.3f, want %.3f", pair, glyphs[1].X, want)
+			}
+		}
+	}
+}
+
+func TestConvertFeatures(t *testing.T) {
+	// 特性标签转换为整形器的 4 字节标签，无效标签被忽略
+	features := convertFeatures([]OpenTypeFeature{
+		{Tag: FeatureSmallCaps, Value: 1},
+		{Tag: "bad", Value: 1},
+		{Tag: FeatureLigatures, Value: 0},
+		{Tag: FeatureStylisticSet01, Value: 1},
+	})
+	want := []struct {
+		tag   string
+		value uint32
+	}{{"smcp", 1}, {"liga", 0}, {"ss01", 1}}
+	if len(features) != len(want) {
+		t.Fatalf("got %d features, want %d", len(features), len(want))
+	}
+	for i, w := range want {
+		if features[i].Tag.String() != w.tag || features[i].Value != w.value {
+			t.Errorf("feature %d = %s=%d, want %s=%d", i, features[i].Tag, features[i].Value, w.tag, w.value)
+		}
+	}
+	if convertFeatures(nil) != nil {
+		t.Error("convertFeatures(nil) should be nil")
+	}
+
+	// 带特性的整形仍然成功
+	sf := newTestScaledFont(t, 12)
+	options := NewShapingOptions()
+	SetDefaultFeatures(options, "small-caps")
+	if glyphs, _, _, status := sf.TextToGlyphsWithOptions(0, 0, "Heading 123", options); status != StatusSuccess || len(glyphs) == 0 {
+		t.Errorf("shaping with features failed: %v", status)
+	}
+}
+
+func TestGlyphPathQuadraticCurves(t *testing.T) {
+	// "O" 的 TrueType 轮廓全部由二次曲线组成；转换后的三次曲线必须与原二次曲线重合
+	sf := newTestScaledFont(t, 100)
+	realFace, status := sf.getRealFace()
+	if status != StatusSuccess {
+		t.Fatalf("getRealFace failed: %v", status)
+	}
+	gid, ok := realFace.NominalGlyph('O')
+	if !ok {
+		t.Skip("font has no glyph for 'O'")
+	}
+	outline, ok := realFace.GlyphData(gid).(api.GlyphOutline)
+	if !ok {
+		t.Skip("glyph has no outline")
+	}
+	path, err := sf.GlyphPath(uint64(gid))
+	if err != nil {
+		t.Fatalf("GlyphPath failed: %v", err)
+	}
+	// 每个轮廓都以 ClosePath 结束，其余段与轮廓段一一对应
+	var segments []PathData
+	for _, pd := range path.Data {
+		if pd.Type != PathClosePath {
+			segments = append(segments, pd)
+		}
+	}
+	if len(segments) != len(outline.Segments) {
+		t.Fatalf("path has %d segments, outline has %d", len(segments), len(outline.Segments))
+	}
+	if last := path.Data[len(path.Data)-1]; last.Type != PathClosePath {
+		t.Errorf("last segment type %v, want ClosePath", last.Type)
+	}
+	for i := 1; i < len(path.Data); i++ {
+		if path.Data[i].Type == PathMoveTo && path.Data[i-1].Type != PathClosePath {
+			t.Errorf("segment %d starts a contour without closing the previous one", i)
+		}
+	}
+
+	scale := 100 / float64(realFace.Upem())
+	toUser := func(p api.SegmentPoint) Point {
+		return Point{X: float64(p.X) * scale, Y: -float64(p.Y) * scale}
+	}
+	quads := 0
+	var p0 Point
+	for i, seg := range outline.Segments {
+		pd := segments[i]
+		if seg.Op == api.SegmentOpQuadTo {
+			quads++
+			if pd.Type != PathCurveTo {
+				t.Fatalf("segment %d: type %v, want curve", i, pd.Type)
+			}
+			q, p2 := toUser(seg.Args[0]), toUser(seg.Args[1])
+			for _, u := range []float64{0.25, 0.5, 0.75} {
+				v := 1 - u
+				wantX := v*v*p0.X + 2*u*v*q.X + u*u*p2.X
+				wantY := v*v*p0.Y + 2*u*v*q.Y + u*u*p2.Y
+				c1, c2, c3 := pd.Points[0], pd.Points[1], pd.Points[2]
+				gotX := v*v*v*p0.X + 3*u*v*v*c1.X + 3*u*u*v*c2.X + u*u*u*c3.X
+				gotY := v*v*v*p0.Y + 3*u*v*v*c1.Y + 3*u*u*v*c2.Y + u*u*u*c3.Y
+				if math.Hypot(gotX-wantX, gotY-wantY) > 1e-9 {
+					t.Fatalf("segment %d at t=%.2f: cubic (%.3f,%.3f), quadratic (%.3f,%.3f)", i, u, gotX, gotY, wantX, wantY)
+				}
+			}
+		}
+		p0 = pd.Points[len(pd.Points)-1]
+	}
+	if quads == 0 {
+		t.Skip("glyph outline has no quadratic segments")
+	}
+
+	// scaledFont 生成相同的路径
+	fontFace := NewToyFontFace("sans-serif", FontSlantNormal, FontWeightNormal)
+	defer fontFace.Destroy()
+	fontMatrix := NewMatrix()
+	fontMatrix.InitScale(100, 100)
+	ctm := NewMatrix()
+	ctm.InitIdentity()
+	toy := NewScaledFont(fontFace, fontMatrix, ctm, nil)
+	defer toy.Destroy()
+	toyPath, err := toy.GlyphPath(uint64(gid))
+	if err != nil {
+		t.Fatalf("scaledFont.GlyphPath failed: %v", err)
+	}
+	if len(toyPath.Data) != len(path.Data) {
+		t.Fatalf("scaledFont path has %d segments, want %d", len(toyPath.Data), len(path.Data))
+	}
+	for i := range path.Data {
+		if toyPath.Data[i].Type != path.Data[i].Type {
+			t.Fatalf("segment %d: type %v, want %v", i, toyPath.Data[i].Type, path.Data[i].Type)
+		}
+		for j, p := range path.Data[i].Points {
+			if q := toyPath.Data[i].Points[j]; math.Hypot(p.X-q.X, p.Y-q.Y) > 1e-9 {
+				t.Fatalf("segment %d point %d: (%.3f,%.3f), want (%.3f,%.3f)", i, j, q.X, q.Y, p.X, p.Y)
+			}
+		}
+	}
+}
+
+func TestSubsetFontTrueType(t *testing.T) {
+	face := NewToyFontFace("Go", FontSlantNormal, FontWeightNormal)
+	defer face.Destroy()
+	original := face.(*toyFontFace).realFace
+
+	gid := func(r rune) uint64 {
+		g, ok := original.NominalGlyph(r)
+		if !ok {
+			t.Fatalf("font has no glyph for %q", r)
+		}
+		return uint64(g)
+	}
+	used := []uint64{gid('H'), gid('i'), gid('é')}
+
+	data, err := SubsetFont(face, used)
+	if err != nil {
+		t.Fatalf("SubsetFont failed: %v", err)
+	}
+	if full := len(face.(*toyFontFace).fontData); len(data) >= full/2 {
+		t.Errorf("subset is %d bytes, want well under the original %d", len(data), full)
+	}
+	if sum := sfntChecksum(data); sum != 0xB1B0AFBA {
+		t.Errorf("font checksum = %#x, want 0xB1B0AFBA", sum)
+	}
+
+	subset, err := font.ParseTTF(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to parse subset font: %v", err)
+	}
+	segments := func(g uint64) int {
+		outline, _ := subset.GlyphData(api.GID(g)).(api.GlyphOutline)
+		return len(outline.Segments)
+	}
+	// 使用的字形（包括复合字形 é 的组件）保留轮廓，字形 ID 不变
+	for _, g := range used {
+		if segments(g) == 0 {
+			t.Errorf("glyph %d lost its outline", g)
+		}
+	}
+	if segments(gid('Z')) != 0 {
+		t.Error("unused glyph 'Z' still has an outline")
+	}
+	if subset.HorizontalAdvance(api.GID(gid('H'))) != original.HorizontalAdvance(api.GID(gid('H'))) {
+		t.Error("subset changed glyph advances")
+	}
+
+	if _, err := SubsetFont(NewUserFontFace(), used); err == nil {
+		t.Error("SubsetFont should fail for a font face without font data")
+	}
+}
+
+func TestSubsetCFF(t *testing.T) {
+	// 构造最小的 CFF 字体（普通字体和 CID 字体）：字形 2 通过局部 Subrs 绘制
+	num := func(v int) []byte { return []byte{byte(v + 139)} }
+	glyphs := [][]byte{
+		{14}, // .notdef: endchar
+		append(append(append(num(0), num(0)...), 21), append(append(num(100), num(0)...), 5, 14)...), // 0 0 rmoveto 100 0 rlineto endchar
+		append(append(num(0), num(0)...), append([]byte{21}, append(num(-107), 10, 14)...)...),       // 0 0 rmoveto 0 callsubr endchar
+		append(append(num(0), num(50)...), 5, 14),                                                    // 0 50 rlineto endchar
+	}
+	subrs := encodeCFFIndex([][]byte{append(append(num(50), num(50)...), 5, 11)}) // 50 50 rlineto return
+	charset := []byte{0, 0, 1, 0, 2, 0, 3}                                        // 格式 0
+	fdSelect := []byte{3, 0, 1, 0, 0, 0, 0, 4}                                    // 格式 3：全部字形使用 FD 0
+	private := (&cffPrivate{
+		dict:  []cffDictEntry{{op: 20, operands: [][]byte{num(0)}}, cffDictOffsets(cffOpSubrs, 0)},
+		subrs: subrs,
+	}).encode()
+	privateSize := len(private) - len(subrs)
+	charStrings := encodeCFFIndex(glyphs)
+
+	build := func(cid bool) []byte {
+		// 各部分依次为 charset、FDSelect、CharStrings、FDArray、Private
+		assemble := func(base int) []byte {
+			charsetOff := base
+			fdSelectOff := charsetOff + len(charset)
+			csOff := fdSelectOff
+			if cid {
+				csOff += len(fdSelect)
+			}
+			fdArray := encodeCFFIndex([][]byte{encodeCFFDict([]cffDictEntry{
+				cffDictOffsets(cffOpPrivate, privateSize, 0),
+			})})
+			privateOff := csOff + len(charStrings)
+			if cid {
+				privateOff += len(fdArray)
+				fdArray = encodeCFFIndex([][]byte{encodeCFFDict([]cffDictEntry{
+					cffDictOffsets(cffOpPrivate, privateSize, privateOff),
+				})})
+			}
+
+			var top []cffDictEntry
+			stringIndex := encodeCFFIndex(nil)
+			if cid {
+				top = append(top, cffDictOffsets(0x0c1e, 391, 392, 0)) // ROS: Adobe-Identity-0
+				stringIndex = encodeCFFIndex([][]byte{[]byte("Adobe"), []byte("Identity")})
+			}
+			top = append(top, cffDictOffsets(cffOpCharset, charsetOff), cffDictOffsets(cffOpCharStrings, csOff))
+			if cid {
+				top = append(top, cffDictOffsets(cffOpFDArray, csOff+len(charStrings)), cffDictOffsets(cffOpFDSelect, fdSelectOff))
+			} else {
+				top = append(top, cffDictOffsets(cffOpPrivate, privateSize, privateOff))
+			}
+
+			out := []byte{1, 0, 4, 1}
+			out = append(out, encodeCFFIndex([][]byte{[]byte("Test")})...)
+			out = append(out, encodeCFFIndex([][]byte{encodeCFFDict(top)})...)
+			out = append(out, stringIndex...)
+			out = append(out, encodeCFFIndex(nil)...) // Global Subrs
+			if base == 0 {
+				return out
+			}
+			out = append(out, charset...)
+			if cid {
+				out = append(out, fdSelect...)
+			}
+			out = append(out, charStrings...)
+			if cid {
+				out = append(out, fdArray...)
+			}
+			return append(out, private...)
+		}
+		return assemble(len(assemble(0)))
+	}
+
+	for _, cid := range []bool{false, true} {
+		cffData := build(cid)
+		if _, err := cff.Parse(cffData); err != nil {
+			t.Fatalf("cid=%v: test CFF is invalid: %v", cid, err)
+		}
+
+		data, err := subsetCFF(cffData, []bool{true, false, true, false})
+		if err != nil {
+			t.Fatalf("cid=%v: subsetCFF failed: %v", cid, err)
+		}
+		subset, err := cff.Parse(data)
+		if err != nil {
+			t.Fatalf("cid=%v: failed to parse subset CFF: %v", cid, err)
+		}
+		if len(subset.Charstrings) != len(glyphs) {
+			t.Fatalf("cid=%v: subset has %d glyphs, want %d", cid, len(subset.Charstrings), len(glyphs))
+		}
+		for gid, want := range [][]byte{glyphs[0], {14}, glyphs[2], {14}} {
+			if !bytes.Equal(subset.Charstrings[gid], want) {
+				t.Errorf("cid=%v: glyph %d charstring = %v, want %v", cid, gid, subset.Charstrings[gid], want)
+			}
+		}
+		// 字形 2 调用的局部 Subr 仍然可用
+		segs, _, err := subset.LoadGlyph(2)
+		if err != nil || len(segs) == 0 {
+			t.Errorf("cid=%v: glyph 2 failed to load after subsetting: %v (%d segments)", cid, err, len(segs))
+		}
+	}
+}
+
+// newTestScaledFont 创建默认字体的 PangoPdf 缩放字体
+func newTestScaledFont(t *testing.T, size float64) *PangoPdfScaledFont {
+	t.Helper()
+	fontFace := NewPangoPdfFont("sans-serif", FontSlantNormal, FontWeightNormal)
+	t.Cleanup(fontFace.Destroy)
+	fontMatrix := NewMatrix()
+	fontMatrix.InitScale(size, size)
+	ctm := NewMatrix()
+	ctm.InitIdentity()
+	sf := NewPangoPdfScaledFont(fontFace, fontMatrix, ctm, nil)
+	t.Cleanup(sf.Destroy)
+	return sf
+}
+
+func TestLayoutWrapsToWidth(t *testing.T) {
+	sf := newTestScaledFont(t, 12)
+	layout := NewPangoPdfLayout(NewPangoPdfContext(NewPangoPdfFontMap()))
+	layout.SetText("aaa bbb ccc\nddd")
+
+	// 未设置宽度时只按换行符分段
+	if lines := wrapLayoutText(sf, layout); len(lines) != 2 {
+		t.Fatalf("unwrapped lines = %v, want 2 paragraphs", lines)
+	}
+
+	// 宽度（Pango 单位）刚好容纳 "aaa bbb"；每段最后一行标记为段尾
+	width := sf.TextExtents("aaa bbb").XAdvance + 1
+	layout.SetWidth(int(width * 1024))
+	want := []pangoLayoutLine{{"aaa bbb", 0, false}, {"ccc", 8, true}, {"ddd", 12, true}}
+	wrapped := wrapLayoutText(sf, layout)
+	if len(wrapped) != len(want) {
+		t.Fatalf("word wrap = %v, want %v", wrapped, want)
+	}
+	for i := range want {
+		if wrapped[i] != want[i] {
+			t.Errorf("word wrap = %v, want %v", wrapped, want)
+			break
+		}
+	}
+
+	// 单个单词超过宽度：word 模式允许溢出，char / word-char 模式按字符断开
+	measure := func(text string) float64 { return sf.TextExtents(text).XAdvance }
+	word := "abcdefghij"
+	narrow := sf.TextExtents("abcd").XAdvance + 0.5
+	if lines := wrapParagraph(measure, word, narrow, PangoWrapWord); len(lines) != 1 {
+		t.Errorf("word mode broke a single word: %q", lines)
+	}
+	for _, mode := range []PangoWrapMode{PangoWrapChar, PangoWrapWordChar} {
+		lines := wrapParagraph(measure, word, narrow, mode)
+		if len(lines) < 2 || strings.Join(lines, "") != word {
+			t.Errorf("mode %d: lines = %q, want %q split into several lines", mode, lines, word)
+		}
+		for _, line := range lines {
+			if w := sf.TextExtents(line).XAdvance; w > narrow {
+				t.Errorf("mode %d: line %q is %.2f wide, limit %.2f", mode, line, w, narrow)
+			}
+		}
+	}
+
+	// word-char 模式优先在单词边界断行
+	lines := wrapParagraph(measure, "ab "+word, narrow, PangoWrapWordChar)
+	if len(lines) < 2 || lines[0] != "ab" {
+		t.Errorf("word-char lines = %q, want the first line to be \"ab\"", lines)
+	}
+}
+
+func TestLayoutLetterAndWordSpacing(t *testing.T) {
+	sf := newTestScaledFont(t, 12)
+	layout := NewPangoPdfLayout(NewPangoPdfContext(NewPangoPdfFontMap()))
+	desc := NewPangoFontDescription()
+	desc.SetFamily("sans-serif")
+	desc.SetSize(12)
+	layout.SetFontDescription(desc)
+	layout.SetText("a bc")
+
+	plain := layout.GetPixelExtents().Width
+	plainGlyphs, _, _, _ := sf.TextToGlyphs(0, 0, "a bc")
+
+	layout.SetLetterSpacing(2)
+	layout.SetWordSpacing(5)
+
+	// 每个字形之后加 2，空格之后再加 5
+	glyphs, _, _, _ := sf.TextToGlyphs(0, 0, "a bc")
+	applyLayoutSpacing(sf, glyphs, layout)
+	wantShift := []float64{0, 2, 9, 11}
+	for i, g := range glyphs {
+		if shift := g.X - plainGlyphs[i].X; math.Abs(shift-wantShift[i]) > 1e-9 {
+			t.Errorf("glyph %d shifted by %.2f, want %.2f", i, shift, wantShift[i])
+		}
+	}
+
+	if got := layout.GetPixelExtents().Width; math.Abs(got-(plain+13)) > 1e-9 {
+		t.Errorf("spaced width = %.2f, want %.2f", got, plain+13)
+	}
+}
+
+func TestLayoutJustify(t *testing.T) {
+	sf := newTestScaledFont(t, 12)
+	layout := NewPangoPdfLayout(NewPangoPdfContext(NewPangoPdfFontMap()))
+	layout.SetAlignment(PangoAlignJustify)
+	layoutWidth := sf.TextExtents("aa bb cc").Width + 30
+	layout.SetWidth(int(layoutWidth * 1024))
+
+	line := pangoLayoutLine{text: "aa bb cc"}
+	plain, _, _, _ := sf.TextToGlyphs(0, 0, line.text)
+	textWidth := sf.TextExtents(line.text).Width
+	slack := pangoUnitsToDouble(layout.GetWidth()) - textWidth
+
+	// 两个词间空隙平分剩余宽度
+	glyphs, _, _, _ := sf.TextToGlyphs(0, 0, line.text)
+	alignLineGlyphs(sf, glyphs, layout, line)
+	wantShift := []float64{0, 0, 0, slack / 2, slack / 2, slack / 2, slack, slack}
+	for i, g := range glyphs {
+		if shift := g.X - plain[i].X; math.Abs(shift-wantShift[i]) > 1e-9 {
+			t.Errorf("glyph %d shifted by %.3f, want %.3f", i, shift, wantShift[i])
+		}
+	}
+
+	// 段落最后一行保持左对齐
+	glyphs, _, _, _ = sf.TextToGlyphs(0, 0, line.text)
+	line.paragraphEnd = true
+	alignLineGlyphs(sf, glyphs, layout, line)
+	for i, g := range glyphs {
+		if g.X != plain[i].X {
+			t.Errorf("last line glyph %d moved from %.3f to %.3f", i, plain[i].X, g.X)
+		}
+	}
+}
+
+func TestLayoutSetMarkup(t *testing.T) {
+	layout := NewPangoPdfLayout(NewPangoPdfContext(NewPangoPdfFontMap()))
+	layout.SetMarkup(`plain <b>bold <i>both</i></b> <span foreground="#f00" size="18pt">red</span> &amp; <span size="10240">ten</span>`)
+
+	if want := "plain bold both red & ten"; layout.GetText() != want {
+		t.Fatalf("text = %q, want %q", layout.GetText(), want)
+	}
+	red := Color{R: 1, A: 1}
+	want := []pangoAttrRun{
+		{start: 6, end: 11, pangoTextStyle: pangoTextStyle{bold: true}},
+		{start: 11, end: 15, pangoTextStyle: pangoTextStyle{bold: true, italic: true}},
+		{start: 16, end: 19, pangoTextStyle: pangoTextStyle{size: 18, color: red, hasColor: true}},
+		{start: 22, end: 25, pangoTextStyle: pangoTextStyle{size: 10}},
+	}
+	if len(layout.attrs) != len(want) {
+		t.Fatalf("attrs = %+v, want %+v", layout.attrs, want)
+	}
+	for i := range want {
+		if layout.attrs[i] != want[i] {
+			t.Errorf("attr %d = %+v, want %+v", i, layout.attrs[i], want[i])
+		}
+	}
+
+	// 无效标记保持布局不变；SetText 清除属性
+	layout.SetMarkup("<b>unclosed")
+	if layout.GetText() != "plain bold both red & ten" || len(layout.attrs) != len(want) {
+		t.Error("invalid markup changed the layout")
+	}
+	layout.SetText("plain")
+	if len(layout.attrs) != 0 {
+		t.Error("SetText kept markup attributes")
+	}
+}
+
+func TestShowMarkupColorsRuns(t *testing.T) {
+	// 红色 span 使用自己的颜色绘制，其余文本使用当前源颜色（黑色）
+	surface := NewImageSurface(FormatARGB32, 200, 40)
+	defer surface.Destroy()
+	ctx := NewContext(surface)
+	defer ctx.Destroy()
+	ctx.SetSourceRGB(1, 1, 1)
+	ctx.Paint()
+	ctx.SetSourceRGB(0, 0, 0)
+
+	layout := ctx.PangoPdfCreateLayout().(*PangoPdfLayout)
+	desc := NewPangoFontDescription()
+	desc.SetFamily("sans-serif")
+	desc.SetSize(20)
+	layout.SetFontDescription(desc)
+	layout.SetMarkup(`MMM<span foreground="red">MMM</span>`)
+	ctx.MoveTo(5, 30)
+	ctx.PangoPdfShowText(layout)
+
+	img := surface.(ImageSurface).GetGoImage().(*image.RGBA)
+	var redLeft, blackRight bool
+	split := 5 + newTestScaledFont(t, 20).TextExtents("MMM").XAdvance
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 200; x++ {
+			c := img.RGBAAt(x, y)
+			isRed := c.R > 200 && c.G < 60 && c.B < 60
+			isBlack := c.R < 60 && c.G < 60 && c.B < 60
+			if isRed && float64(x) < split-1 {
+				redLeft = true
+			}
+			if isBlack && float64(x) > split+1 {
+				blackRight = true
+			}
+		}
+	}
+	if redLeft || blackRight {
+		t.Errorf("colour runs misplaced: red before split=%v, black after split=%v", redLeft, blackRight)
+	}
+
+	hasColor := func(x0, x1 int, match func(r, g, b uint8) bool) bool {
+		for y := 0; y < 40; y++ {
+			for x := x0; x < x1; x++ {
+				c := img.RGBAAt(x, y)
+				if match(c.R, c.G, c.B) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if !hasColor(0, int(split), func(r, g, b uint8) bool { return r < 60 && g < 60 && b < 60 }) {
+		t.Error("no black glyph pixels in the plain run")
+	}
+	if !hasColor(int(split), 200, func(r, g, b uint8) bool { return r > 200 && g < 60 && b < 60 }) {
+		t.Error("no red glyph pixels in the red span")
+	}
+}
+
+func TestStandardFontWidths(t *testing.T) {
+	names := []struct {
+		baseFont string
+		want     string
+		ok       bool
+	}{
+		{"/Helvetica", "Helvetica", true},
+		{"ABCDEF+Arial,BoldItalic", "Helvetica-BoldOblique", true},
+		{"TimesNewRomanPS-BoldMT", "Times-Bold", true},
+		{"Times-Roman", "Times-Roman", true},
+		{"Courier-Oblique", "Courier-Oblique", true},
+		{"/ZapfDingbats", "ZapfDingbats", true},
+		{"Helvetica-Narrow", "", false},
+		{"Foo", "", false},
+	}
+	for _, tc := range names {
+		got, ok := standardFontName(tc.baseFont)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("standardFontName(%q) = %q, %v; want %q, %v", tc.baseFont, got, ok, tc.want, tc.ok)
+		}
+	}
+
+	widths := []struct {
+		name     string
+		encoding string
+		code     uint16
+		want     float64
+	}{
+		{"Helvetica", "/WinAnsiEncoding", 'a', 556},
+		{"Helvetica", "/WinAnsiEncoding", 'i', 222},
+		{"Helvetica", "/WinAnsiEncoding", ' ', 278},
+		{"Times-Roman", "/WinAnsiEncoding", 'a', 444},
+		{"Courier", "", 'W', 600},
+		{"Helvetica", "/WinAnsiEncoding", 0x27, 191}, // quotesingle
+		{"Helvetica", "", 0x27, 222},                 // StandardEncoding: quoteright
+	}
+	for _, tc := range widths {
+		font := &Font{Subtype: "/Type1", Widths: standardFontWidths(tc.name, tc.encoding)}
+		if got := font.GetWidth(tc.code); got != tc.want {
+			t.Errorf("%s %q width of %#x = %v, want %v", tc.name, tc.encoding, tc.code, got, tc.want)
+		}
+	}
+}
+
+func TestStrokeJoinsAndCaps(t *testing.T) {
+	stroke := func(setup func(ctx Context), path func(ctx Context)) *image.RGBA {
+		surface := NewImageSurface(FormatARGB32, 100, 100)
+		t.Cleanup(surface.Destroy)
+		ctx := NewContext(surface)
+		defer ctx.Destroy()
+		ctx.SetSourceRGB(0, 0, 0)
+		ctx.SetLineWidth(10)
+		setup(ctx)
+		path(ctx)
+		ctx.Stroke()
+		return surface.(ImageSurface).GetGoImage().(*image.RGBA)
+	}
+	painted := func(img *image.RGBA, x, y int) bool { return img.RGBAAt(x, y).A > 200 }
+	blank := func(img *image.RGBA, x, y int) bool { return img.RGBAAt(x, y).A == 0 }
+
+	// 直角拐角 (20,80)-(20,20)-(80,20)：外角在 (15,15)-(20,20)
+	corner := func(ctx Context) {
+		ctx.MoveTo(20, 80)
+		ctx.LineTo(20, 20)
+		ctx.LineTo(80, 20)
+	}
+	joins := []struct {
+		name        string
+		setup       func(ctx Context)
+		miterCorner bool // (15,15) 只被斜接覆盖
+		roundEdge   bool // (17,16) 在圆角内、斜角外
+	}{
+		{"miter", func(ctx Context) { ctx.SetLineJoin(LineJoinMiter) }, true, true},
+		{"round", func(ctx Context) { ctx.SetLineJoin(LineJoinRound) }, false, true},
+		{"bevel", func(ctx Context) { ctx.SetLineJoin(LineJoinBevel) }, false, false},
+		{"miter limit", func(ctx Context) { ctx.SetLineJoin(LineJoinMiter); ctx.SetMiterLimit(1.2) }, false, false},
+	}
+	for _, tc := range joins {
+		img := stroke(tc.setup, corner)
+		if got := painted(img, 15, 15); got != tc.miterCorner || (!got && !blank(img, 15, 15)) {
+			t.Errorf("%s join: outer corner painted = %v, want %v", tc.name, got, tc.miterCorner)
+		}
+		if got := painted(img, 17, 16); got != tc.roundEdge || (!got && !blank(img, 17, 16)) {
+			t.Errorf("%s join: (17,16) painted = %v, want %v", tc.name, got, tc.roundEdge)
+		}
+		if !painted(img, 20, 50) || !painted(img, 50, 20) {
+			t.Errorf("%s join: segment bodies not painted", tc.name)
+		}
+	}
+
+	// 线帽：水平线 (30,60)-(70,60)
+	line := func(ctx Context) {
+		ctx.MoveTo(30, 60)
+		ctx.LineTo(70, 60)
+	}
+	caps := []struct {
+		name       string
+		cap        LineCap
+		beyondEnd  bool // (27,60) 在端点之外
+		squareOnly bool // (25,55) 只被方形线帽覆盖
+	}{
+		{"butt", LineCapButt, false, false},
+		{"round", LineCapRound, true, false},
+		{"square", LineCapSquare, true, true},
+	}
+	for _, tc := range caps {
+		img := stroke(func(ctx Context) { ctx.SetLineCap(tc.cap) }, line)
+		if got := painted(img, 27, 60); got != tc.beyondEnd || (!got && !blank(img, 27, 60)) {
+			t.Errorf("%s cap: (27,60) painted = %v, want %v", tc.name, got, tc.beyondEnd)
+		}
+		if got := painted(img, 25, 55); got != tc.squareOnly {
+			t.Errorf("%s cap: (25,55) painted = %v, want %v", tc.name, got, tc.squareOnly)
+		}
+		if got := painted(img, 72, 60); got != tc.beyondEnd {
+			t.Errorf("%s cap: (72,60) painted = %v, want %v", tc.name, got, tc.beyondEnd)
+		}
+	}
+
+	// 半透明描边的拐角只混合一次
+	img := stroke(func(ctx Context) {
+		ctx.SetSourceRGBA(0, 0, 0, 0.5)
+		ctx.SetLineJoin(LineJoinRound)
+	}, corner)
+	if body, joint := img.RGBAAt(20, 50).A, img.RGBAAt(20, 20).A; body != joint {
+		t.Errorf("translucent stroke alpha at the join = %d, want %d as on the segment", joint, body)
+	}
+}
+
+func TestCopyPathFlat(t *testing.T) {
+	surface := NewImageSurface(FormatARGB32, 100, 100)
+	defer surface.Destroy()
+	ctx := NewContext(surface)
+	defer ctx.Destroy()
+
+	bezier := func(tt float64) (float64, float64) {
+		mt := 1 - tt
+		x := mt*mt*mt*10 + 3*mt*mt*tt*10 + 3*mt*tt*tt*90 + tt*tt*tt*90
+		y := mt*mt*mt*90 + 3*mt*mt*tt*10 + 3*mt*tt*tt*10 + tt*tt*tt*90
+		return x, y
+	}
+	flatten := func(tolerance, scale float64) *Path {
+		ctx.NewPath()
+		ctx.IdentityMatrix()
+		ctx.Scale(scale, scale)
+		ctx.SetTolerance(tolerance)
+		ctx.MoveTo(10, 90)
+		ctx.CurveTo(10, 10, 90, 10, 90, 90)
+		ctx.ClosePath()
+		return ctx.CopyPathFlat()
+	}
+
+	path := flatten(0.1, 1)
+	if path.Data[0].Type != PathMoveTo || path.Data[len(path.Data)-1].Type != PathClosePath {
+		t.Fatalf("flattened path should keep MoveTo and ClosePath: %+v", path.Data)
+	}
+	var prev Point
+	for i, data := range path.Data {
+		switch data.Type {
+		case PathCurveTo:
+			t.Fatalf("entry %d is a CurveTo", i)
+		case PathLineTo:
+			// 线段中点到曲线的距离不超过容差
+			p := data.Points[0]
+			mx, my := (prev.X+p.X)/2, (prev.Y+p.Y)/2
+			dist := math.MaxFloat64
+			for s := 0; s <= 2000; s++ {
+				x, y := bezier(float64(s) / 2000)
+				dist = math.Min(dist, math.Hypot(x-mx, y-my))
+			}
+			if dist > 0.1 {
+				t.Errorf("segment %d midpoint is %.3f from the curve", i, dist)
+			}
+		}
+		if len(data.Points) > 0 {
+			prev = data.Points[0]
+		}
+	}
+	if end := path.Data[len(path.Data)-2].Points[0]; end != (Point{X: 90, Y: 90}) {
+		t.Errorf("flattened curve ends at %+v, want (90,90)", end)
+	}
+
+	// 容差越小或设备缩放越大，线段越多
+	coarse := len(flatten(1, 1).Data)
+	if fine := len(path.Data); fine <= coarse {
+		t.Errorf("tolerance 0.1 gave %d entries, tolerance 1 gave %d", fine, coarse)
+	}
+	if scaled := len(flatten(1, 10).Data); scaled <= coarse {
+		t.Errorf("scaled path gave %d entries, unscaled %d", scaled, coarse)
+	}
+
+	// CopyPath 保留曲线
+	flatten(0.1, 1)
+	if data := ctx.CopyPath().Data; len(data) != 3 || data[1].Type != PathCurveTo {
+		t.Errorf("CopyPath should keep the curve: %+v", data)
+	}
+}
+
+func TestMeshShading(t *testing.T) {
+	decode := types.Array{
+		types.Integer(0), types.Integer(100), types.Integer(0), types.Integer(100),
+		types.Integer(0), types.Integer(1), types.Integer(0), types.Integer(1), types.Integer(0), types.Integer(1),
+	}
+	meshDict := func(shadingType int, content []byte) types.StreamDict {
+		return types.StreamDict{
+			Dict: types.Dict{
+				"ShadingType":       types.Integer(shadingType),
+				"ColorSpace":        types.Name("DeviceRGB"),
+				"BitsPerCoordinate": types.Integer(8),
+				"BitsPerComponent":  types.Integer(8),
+				"BitsPerFlag":       types.Integer(8),
+				"Decode":            decode,
+			},
+			Content: content,
+		}
+	}
+
+	// 类型 4：左下半个页面的三角形，(0,0) 红、(100,0) 绿、(0,100) 蓝；每个顶点为 flag x y r g b
+	resources := NewResources()
+	freeForm := meshDict(4, []byte{
+		0, 0, 0, 255, 0, 0,
+		0, 255, 0, 0, 255, 0,
+		0, 0, 255, 0, 0, 255,
+	})
+	if err := loadShading(nil, "Sh1", freeForm, resources); err != nil {
+		t.Fatalf("loadShading: %v", err)
+	}
+
+	// 类型 6：覆盖整个页面的单个 Coons 曲面片（直线边界），四角均为绿色
+	patch := []byte{0}
+	for _, p := range [][2]byte{{0, 0}, {0, 85}, {0, 170}, {0, 255}, {85, 255}, {170, 255},
+		{255, 255}, {255, 170}, {255, 85}, {255, 0}, {170, 0}, {85, 0}} {
+		patch = append(patch, p[0], p[1])
+	}
+	for i := 0; i < 4; i++ {
+		patch = append(patch, 0, 255, 0)
+	}
+	if err := loadShading(nil, "Sh2", meshDict(6, patch), resources); err != nil {
+		t.Fatalf("loadShading: %v", err)
+	}
+	if sh := resources.GetShading("Sh2").(*Shading); len(sh.Triangles) != 2*meshPatchSteps*meshPatchSteps {
+		t.Fatalf("patch triangles = %d, want %d", len(sh.Triangles), 2*meshPatchSteps*meshPatchSteps)
+	}
+
+	render := func(name string) *image.RGBA {
+		surface := NewImageSurface(FormatARGB32, 100, 100)
+		t.Cleanup(surface.Destroy)
+		gopdfCtx := NewContext(surface)
+		t.Cleanup(gopdfCtx.Destroy)
+		gopdfCtx.SetSourceRGB(1, 1, 1)
+		gopdfCtx.Paint()
+		gopdfCtx.Translate(0, 100)
+		gopdfCtx.Scale(1, -1)
+
+		ctx := NewRenderContext(gopdfCtx, 100, 100)
+		ctx.Resources = resources
+		if err := (&OpPaintShading{ShadingName: name}).Execute(ctx); err != nil {
+			t.Fatalf("sh %s: %v", name, err)
+		}
+		return surface.(ImageSurface).GetGoImage().(*image.RGBA)
+	}
+
+	img := render("Sh1")
+	// 用户空间 (2,2) 接近红色顶点，(2,95) 接近蓝色顶点；三角形外保持白色
+	if c := img.RGBAAt(2, 97); c.R < 230 || c.G > 25 || c.B > 25 {
+		t.Errorf("near red vertex = %v", c)
+	}
+	if c := img.RGBAAt(2, 4); c.B < 200 || c.R > 25 {
+		t.Errorf("near blue vertex = %v", c)
+	}
+	if c := img.RGBAAt(25, 74); math.Abs(float64(c.R)-127) > 10 || math.Abs(float64(c.G)-64) > 10 || math.Abs(float64(c.B)-64) > 10 {
+		t.Errorf("interior color = %v, want about (127, 64, 64)", c)
+	}
+	if c := img.RGBAAt(90, 10); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("outside the triangle = %v, want white", c)
+	}
+
+	img = render("Sh2")
+	for _, p := range [][2]int{{0, 0}, {50, 50}, {99, 99}} {
+		if c := img.RGBAAt(p[0], p[1]); c != (color.RGBA{0, 255, 0, 255}) {
+			t.Errorf("patch pixel %v = %v, want green", p, c)
+		}
+	}
+}
+
+func TestImageXObjectCache(t *testing.T) {
+	surface := NewImageSurface(FormatARGB32, 40, 20)
+	defer surface.Destroy()
+	gopdfCtx := NewContext(surface)
+	defer gopdfCtx.Destroy()
+	gopdfCtx.Translate(0, 20)
+	gopdfCtx.Scale(1, -1)
+	ctx := NewRenderContext(gopdfCtx, 40, 20)
+
+	// 两个资源名引用同一个图像对象（如两个表单各自的资源字典）；
+	// 第二个 XObject 的数据流不完整，只有命中缓存时才能正确绘制
+	red := &XObject{Subtype: "/Image", Width: 1, Height: 1, ColorSpace: "/DeviceRGB",
+		BitsPerComponent: 8, Stream: []byte{255, 0, 0}, ObjectNumber: 7}
+	alias := *red
+	alias.Stream = nil
+	ctx.Resources.SetXObject("Im1", red)
+	ctx.Resources.SetXObject("Im2", &alias)
+
+	draw := func(name string, x float64) {
+		t.Helper()
+		ops := []PDFOperator{
+			&OpSaveState{},
+			&OpConcatMatrix{Matrix: &Matrix{XX: 20, YY: 20, X0: x}},
+			&OpDoXObject{XObjectName: name},
+			&OpRestoreState{},
+		}
+		for _, op := range ops {
+			if err := op.Execute(ctx); err != nil {
+				t.Fatalf("%s: %v", op.Name(), err)
+			}
+		}
+	}
+	draw("Im1", 0)
+	draw("Im2", 20)
+
+	if len(ctx.XObjectCache) != 1 {
+		t.Fatalf("cache entries = %d, want 1", len(ctx.XObjectCache))
+	}
+	img := surface.(ImageSurface).GetGoImage()
+	for _, x := range []int{10, 30} {
+		if r, g, b, a := img.At(x, 10).RGBA(); r>>8 != 255 || g != 0 || b != 0 || a>>8 != 255 {
+			t.Errorf("pixel (%d,10) = (%d,%d,%d,%d), want red", x, r>>8, g>>8, b>>8, a>>8)
+		}
+	}
+
+	ctx.releaseXObjectCache()
+	if len(ctx.XObjectCache) != 0 {
+		t.Errorf("cache not released: %d entries", len(ctx.XObjectCache))
+	}
+}
+
+func TestTilingPatternSharesRenderBudget(t *testing.T) {
+	surface := NewImageSurface(FormatARGB32, 20, 20)
+	defer surface.Destroy()
+	gopdfCtx := NewContext(surface)
+	defer gopdfCtx.Destroy()
+
+	newPattern := func(content string) *PatternImpl {
+		p := NewPattern()
+		p.BBox = []float64{0, 0, 10, 10}
+		p.XStep, p.YStep = 10, 10
+		p.Stream = []byte(content)
+		return p
+	}
+	limit := func(err error) string {
+		t.Helper()
+		var limitErr *RenderLimitError
+		if !errors.As(err, &limitErr) {
+			t.Fatalf("error = %v, want *RenderLimitError", err)
+		}
+		return limitErr.Limit
+	}
+
+	// 图案单元的操作符计入页面预算：scn 本身 1 个 + 单元 3 个
+	ctx := NewRenderContext(gopdfCtx, 20, 20)
+	ctx.Resources.SetPattern("P1", newPattern("1 0 0 rg 0 0 10 10 re f"))
+	scn := &OpSetFillPattern{PatternName: "P1"}
+	ctx.budget = newRenderBudget(4, 0)
+	if err := executeOperator(ctx, scn); err != nil {
+		t.Fatalf("within operator limit: %v", err)
+	}
+	ctx.budget = newRenderBudget(3, 0)
+	if got := limit(executeOperator(ctx, scn)); got != "operators" {
+		t.Errorf("limit = %q, want operators", got)
+	}
+
+	// 使用自身的图案在表单深度限制处中止，而不是无限递归
+	self := newPattern("/Pattern cs /P1 scn 0 0 10 10 re f")
+	self.Resources.SetPattern("P1", self)
+	ctx.Resources.SetPattern("P1", self)
+	ctx.budget = newRenderBudget(0, 0)
+	if got := limit(executeOperator(ctx, scn)); got != "form depth" {
+		t.Errorf("limit = %q, want form depth", got)
+	}
+}
+
+func TestFillSeparateRectangles(t *testing.T) {
+	// 一条路径中的多个 re 子路径：矩形之间的空隙不应被填充
+	surface := NewImageSurface(FormatARGB32, 40, 10)
+	defer surface.Destroy()
+	gopdfCtx := NewContext(surface)
+	defer gopdfCtx.Destroy()
+	gopdfCtx.SetSourceRGB(1, 1, 1)
+	gopdfCtx.Paint()
+
+	ctx := NewRenderContext(gopdfCtx, 40, 10)
+	for _, op := range []PDFOperator{
+		&OpRectangle{X: 0, Y: 0, Width: 10, Height: 10},
+		&OpRectangle{X: 20, Y: 0, Width: 10, Height: 10},
+		&OpFill{},
+	} {
+		if err := op.Execute(ctx); err != nil {
+			t.Fatalf("%s failed: %v", op.Name(), err)
+		}
+	}
+
+	img := surface.(ImageSurface).GetGoImage()
+	for x, want := range map[int]uint32{5: 0, 15: 255, 25: 0, 35: 255} {
+		if r, _, _, _ := img.At(x, 5).RGBA(); r>>8 != want {
+			t.Errorf("pixel (%d,5) = %d, want %d", x, r>>8, want)
+		}
+	}
+}
+
+func TestFillCurvedShapes(t *testing.T) {
+	// 在透明表面上填充，覆盖面积 = alpha 之和 / 255
+	fill := func(rule FillRule, build func(ctx Context)) *image.RGBA {
+		surface := NewImageSurface(FormatARGB32, 100, 100)
+		t.Cleanup(surface.Destroy)
+		ctx := NewContext(surface)
+		defer ctx.Destroy()
+		ctx.SetSourceRGB(1, 0, 0)
+		ctx.SetFillRule(rule)
+		build(ctx)
+		ctx.Fill()
+		return surface.(ImageSurface).GetGoImage().(*image.RGBA)
+	}
+	area := func(img *image.RGBA) float64 {
+		sum := 0
+		for i := 3; i < len(img.Pix); i += 4 {
+			sum += int(img.Pix[i])
+		}
+		return float64(sum) / 255
+	}
+	circle := func(ctx Context, r float64) {
+		ctx.NewSubPath()
+		ctx.Arc(50, 50, r, 0, 2*math.Pi)
+		ctx.ClosePath()
+	}
+
+	// 圆：面积接近 πr²，边界内侧 1 像素处完全覆盖
+	img := fill(FillRuleWinding, func(ctx Context) { circle(ctx, 40) })
+	if got, want := area(img), math.Pi*40*40; math.Abs(got-want) > want*0.005 {
+		t.Errorf("circle area = %.1f, want %.1f", got, want)
+	}
+	if a := img.RGBAAt(77, 77).A; a != 255 {
+		t.Errorf("pixel just inside the circle has alpha %d, want 255", a)
+	}
+	if a := img.RGBAAt(79, 79).A; a != 0 {
+		t.Errorf("pixel just outside the circle has alpha %d, want 0", a)
+	}
+
+	// 圆角矩形：面积 = w*h - (4-π)r²
+	img = fill(FillRuleWinding, func(ctx Context) {
+		const x, y, w, h, r = 10.0, 20.0, 80.0, 60.0, 15.0
+		ctx.NewSubPath()
+		ctx.Arc(x+w-r, y+r, r, -math.Pi/2, 0)
+		ctx.Arc(x+w-r, y+h-r, r, 0, math.Pi/2)
+		ctx.Arc(x+r, y+h-r, r, math.Pi/2, math.Pi)
+		ctx.Arc(x+r, y+r, r, math.Pi, 3*math.Pi/2)
+		ctx.ClosePath()
+	})
+	if got, want := area(img), 80.0*60-(4-math.Pi)*15*15; math.Abs(got-want) > want*0.005 {
+		t.Errorf("rounded rectangle area = %.1f, want %.1f", got, want)
+	}
+	if a := img.RGBAAt(13, 23).A; a != 0 {
+		t.Errorf("rounded corner pixel has alpha %d, want 0", a)
+	}
+
+	// 同向的两个同心圆：非零环绕规则填满中心，奇偶规则留出圆环中的洞
+	rings := func(ctx Context) { circle(ctx, 40); circle(ctx, 20) }
+	if a := fill(FillRuleWinding, rings).RGBAAt(50, 50).A; a != 255 {
+		t.Errorf("winding rule: centre alpha = %d, want 255", a)
+	}
+	img = fill(FillRuleEvenOdd, rings)
+	if a := img.RGBAAt(50, 50).A; a != 0 {
+		t.Errorf("even-odd rule: centre alpha = %d, want 0", a)
+	}
+	if a := img.RGBAAt(50, 80).A; a != 255 {
+		t.Errorf("even-odd rule: ring alpha = %d, want 255", a)
+	}
+}
+
+func TestFormatPageNumber(t *testing.T) {
+	tests := []struct {
+		style string
+		n     int
+		want  string
+	}{
+		{"D", 42, "42"},
+		{"R", 1994, "MCMXCIV"},
+		{"r", 4, "iv"},
+		{"A", 1, "A"},
+		{"A", 27, "AA"},
+		{"a", 53, "aaa"},
+		{"", 3, ""},
+	}
+	for _, tt := range tests {
+		if got := formatPageNumber(tt.style, tt.n); got != tt.want {
+			t.Errorf("formatPageNumber(%q, %d) = %q, want %q", tt.style, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestGammaCorrectBlending(t *testing.T) {
+	// 50% 红色叠加在绿色上：sRGB 混合得到偏暗的 (128,128,0)，线性光混合得到约 (188,188,0)
+	blend := func(gammaCorrect bool) (uint32, uint32, uint32) {
+		surface := NewImageSurface(FormatARGB32, 4, 4)
+		defer surface.Destroy()
+		ctx := NewContext(surface)
+		defer ctx.Destroy()
+		ctx.SetGammaCorrectBlending(gammaCorrect)
+		if ctx.GetGammaCorrectBlending() != gammaCorrect {
+			t.Fatalf("GetGammaCorrectBlending() = %v, want %v", ctx.GetGammaCorrectBlending(), gammaCorrect)
+		}
+
+		ctx.SetSourceRGB(0, 1, 0)
+		ctx.Paint()
+		ctx.SetSourceRGBA(1, 0, 0, 0.5)
+		ctx.Rectangle(0, 0, 4, 4)
+		if err := ctx.Fill(); err != nil {
+			t.Fatalf("Fill failed: %v", err)
+		}
+
+		r, g, b, _ := surface.(ImageSurface).GetGoImage().At(2, 2).RGBA()
+		return r >> 8, g >> 8, b >> 8
+	}
+
+	near := func(got, want uint32) bool { return got+2 >= want && got <= want+2 }
+
+	if r, g, b := blend(false); !near(r, 128) || !near(g, 128) || b != 0 {
+		t.Errorf("sRGB blending = (%d,%d,%d), want about (128,128,0)", r, g, b)
+	}
+	if r, g, b := blend(true); !near(r, 188) || !near(g, 188) || b != 0 {
+		t.Errorf("gamma-correct blending = (%d,%d,%d), want about (188,188,0)", r, g, b)
+	}
+}
+
+func TestImageSurfaceWriteToStream(t *testing.T) {
+	surface := NewImageSurface(FormatARGB32, 8, 6)
+	defer surface.Destroy()
+	ctx := NewContext(surface)
+	defer ctx.Destroy()
+	ctx.SetSourceRGB(1, 0, 0)
+	ctx.Paint()
+	ctx.SetSourceRGB(0, 0, 1)
+	ctx.Rectangle(4, 0, 4, 6)
+	ctx.Fill()
+	imgSurface := surface.(ImageSurface)
+
+	var pngBuf bytes.Buffer
+	if status := imgSurface.WriteToPNGStream(&pngBuf); status != StatusSuccess {
+		t.Fatalf("WriteToPNGStream status = %v", status)
+	}
+	decoded, err := png.Decode(&pngBuf)
+	if err != nil {
+		t.Fatalf("failed to decode PNG stream: %v", err)
+	}
+	want := imgSurface.AsRGBA()
+	for _, p := range []image.Point{{1, 1}, {6, 4}} {
+		if got := color.NRGBAModel.Convert(decoded.At(p.X, p.Y)); got != color.NRGBAModel.Convert(want.At(p.X, p.Y)) {
+			t.Errorf("PNG pixel %v = %v, want %v", p, got, want.At(p.X, p.Y))
+		}
+	}
+
+	// 文件版本委托给流版本，输出应完全相同
+	path := filepath.Join(t.TempDir(), "surface.png")
+	if status := imgSurface.WriteToPNG(path); status != StatusSuccess {
+		t.Fatalf("WriteToPNG status = %v", status)
+	}
+	var again bytes.Buffer
+	imgSurface.WriteToPNGStream(&again)
+	if fileData, err := os.ReadFile(path); err != nil || !bytes.Equal(fileData, again.Bytes()) {
+		t.Errorf("WriteToPNG output differs from WriteToPNGStream (err=%v)", err)
+	}
+
+	var jpegBuf bytes.Buffer
+	if status := imgSurface.WriteToJPEGStream(&jpegBuf, 95); status != StatusSuccess {
+		t.Fatalf("WriteToJPEGStream status = %v", status)
+	}
+	decoded, err = jpeg.Decode(&jpegBuf)
+	if err != nil {
+		t.Fatalf("failed to decode JPEG stream: %v", err)
+	}
+	if r, _, b, _ := decoded.At(1, 1).RGBA(); r>>8 < 200 || b>>8 > 60 {
+		t.Errorf("JPEG pixel (1,1) = r %d b %d, want red", r>>8, b>>8)
+	}
+}
+
+// TestLazyContentStreams 测试延迟解码的内容流：读取到时才解码，读完后释放解码数据，
+// 数组中解码失败的流视为空流，单个流解码失败返回错误
+func TestLazyContentStreams(t *testing.T) {
+	flate := func(data string) types.StreamDict {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write([]byte(data))
+		zw.Close()
+		return types.StreamDict{
+			Dict:           types.Dict{"Filter": types.Name("FlateDecode")},
+			FilterPipeline: []types.PDFFilter{{Name: "FlateDecode"}},
+			Raw:            buf.Bytes(),
+		}
+	}
+	broken := types.StreamDict{
+		Dict:           types.Dict{"Filter": types.Name("FlateDecode")},
+		FilterPipeline: []types.PDFFilter{{Name: "FlateDecode"}},
+		Raw:            []byte("not flate data"),
+	}
+
+	streams, err := lazyContentStreams(nil, types.Array{flate("0 0 1 rg"), broken, flate(" 0 0 10 10 re f")}, false)
+	if err != nil || len(streams) != 3 {
+		t.Fatalf("lazyContentStreams = %d streams, %v; want 3 streams", len(streams), err)
+	}
+	for i, stream := range streams {
+		if stream.sd.Content != nil {
+			t.Errorf("stream %d decoded before it was read", i)
+		}
+	}
+
+	var names []string
+	sources := []io.Reader{streams[0], streams[1], streams[2]}
+	err = parseContentSources(sources, func(op PDFOperator) error {
+		names = append(names, op.Name())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("parseContentSources: %v", err)
+	}
+	if got := strings.Join(names, " "); got != "rg re f" {
+		t.Errorf("operators = %q, want %q", got, "rg re f")
+	}
+	for i, stream := range streams {
+		if stream.sd.Content != nil {
+			t.Errorf("stream %d still holds its decoded data after being read", i)
+		}
+	}
+	if streams[0].read != 8 || streams[1].read != 0 {
+		t.Errorf("read = %d, %d; want 8, 0", streams[0].read, streams[1].read)
+	}
+
+	single, err := lazyContentStreams(nil, broken, false)
+	if err != nil || len(single) != 1 {
+		t.Fatalf("lazyContentStreams = %d streams, %v; want 1 stream", len(single), err)
+	}
+	if err := parseContentSources([]io.Reader{single[0]}, func(PDFOperator) error { return nil }); err == nil {
+		t.Errorf("expected a decode error for a broken single content stream")
+	}
+}
@@ -99,10 +99,10 @@ func (gr *GradientRenderer) addColorStops(pattern Pattern, shading *Shading) err
 	numStops := 10 // 可以根据需要调整
 	for i := 0; i <= numStops; i++ {
 		t := float64(i) / float64(numStops)
-		colors := shading.Function.EvaluateFunction(t)
+		colors := shading.Function.Eval([]float64{t})
 
 		// 转换颜色到 RGB
-		r, g, b, a := gr.convertColorToRGBA(colors, shading.ColorSpace)
+		r, g, b, a := gr.shadingColorToRGBA(colors, shading)
 		if gradPattern, ok := pattern.(GradientPattern); ok {
 			gradPattern.AddColorStopRGBA(t, r, g, b, a)
 		}
@@ -111,6 +111,18 @@ func (gr *GradientRenderer) addColorStops(pattern Pattern, shading *Shading) err
 	return nil
 }
 
+// shadingColorToRGBA 把阴影颜色空间中的颜色转换为 RGBA
+func (gr *GradientRenderer) shadingColorToRGBA(colors []float64, shading *Shading) (float64, float64, float64, float64) {
+	if shading.ColorSpaceObj == nil {
+		return gr.convertColorToRGBA(colors, shading.ColorSpace)
+	}
+	r, g, b, a, err := shading.ColorSpaceObj.ConvertToRGBA(colors, 1)
+	if err != nil {
+		debugPrintf("Warning: Failed to convert shading color: %v\n", err)
+	}
+	return r, g, b, a
+}
+
 // convertColorToRGBA 将颜色转换为 RGBA
 func (gr *GradientRenderer) convertColorToRGBA(colors []float64, colorSpace string) (float64, float64, float64, float64) {
 	// 默认 alpha 为 1.0
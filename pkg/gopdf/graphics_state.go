@@ -23,6 +23,8 @@ type GraphicsState struct {
 	AlphaIsShape      bool                 // Alpha 是否为形状（AIS）
 	TextKnockout      bool                 // 文本敲除（TK）
 	OverprintMode     int                  // 叠印模式（OPM）
+	FillNoMarks       bool                 // 填充颜色空间为 None 着色剂，填充不产生标记
+	StrokeNoMarks     bool                 // 描边颜色空间为 None 着色剂，描边不产生标记
 }
 
 // NewGraphicsState 创建新的图形状态
@@ -73,6 +75,8 @@ func (gs *GraphicsState) Clone() *GraphicsState {
 		AlphaIsShape:      gs.AlphaIsShape,
 		TextKnockout:      gs.TextKnockout,
 		OverprintMode:     gs.OverprintMode,
+		FillNoMarks:       gs.FillNoMarks,
+		StrokeNoMarks:     gs.StrokeNoMarks,
 	}
 
 	if gs.DashPattern != nil {
@@ -0,0 +1,120 @@
+package gopdf
+
+import (
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// HitResult 设备像素处的页面内容
+// 没有命中的内容对应字段为 nil
+type HitResult struct {
+	X, Y float64 // 像素中心在页面用户空间中的坐标（左下角为原点，Y 轴向上）
+
+	Text       *TextRun          // 命中的文本片段
+	GlyphIndex int               // 命中的字形在 Text.Glyphs 中的下标
+	Link       *Annotation       // 命中的链接注释，目标见 URI、DestPage、DestName
+	Image      *ImageElementInfo // 命中的图像
+}
+
+// HitTest 把按 dpi 渲染的页面图像中的像素 (px, py) 映射回页面用户空间，返回该处的文本、链接和图像
+// 坐标映射与 RenderPageToImage 等渲染方法一致，包括 MediaBox 原点偏移、CropBox、/Rotate 和 /UserUnit；
+// dpi 为 0 时使用渲染的默认值 150。多个内容重叠时取最上层（最后绘制）的一个
+func (r *PDFReader) HitTest(pageNum int, px, py int, dpi float64) (*HitResult, error) {
+	if dpi == 0 {
+		dpi = 150
+	}
+	if dpi < 0 {
+		return nil, fmt.Errorf("invalid DPI: %g", dpi)
+	}
+
+	ctx, err := r.readContext()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF context: %w", err)
+	}
+	if pageNum < 1 || pageNum > ctx.PageCount {
+		return nil, fmt.Errorf("invalid page number: %d (total pages: %d)", pageNum, ctx.PageCount)
+	}
+	pageDict, _, _, err := ctx.PageDict(pageNum, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page dict: %w", err)
+	}
+
+	// 取像素中心，用渲染变换的逆变换映射到用户空间
+	m := pageUserToDeviceMatrix(ctx, pageDict, dpi)
+	inv, ok := m.Invert()
+	if !ok {
+		return nil, fmt.Errorf("page transformation is not invertible")
+	}
+	result := &HitResult{}
+	result.X, result.Y = inv.Transform(float64(px)+0.5, float64(py)+0.5)
+
+	// 链接注释：后出现的注释位于上层
+	annotations, err := ExtractAnnotations(ctx, pageDict)
+	if err != nil {
+		debugPrintf("Warning: failed to extract annotations for hit test: %v\n", err)
+	}
+	for i := len(annotations) - 1; i >= 0; i-- {
+		annot := annotations[i]
+		if annot.Subtype != "Link" {
+			continue
+		}
+		x1, y1, x2, y2 := annot.GetRect()
+		if result.X >= min(x1, x2) && result.X <= max(x1, x2) && result.Y >= min(y1, y2) && result.Y <= max(y1, y2) {
+			result.Link = annot
+			break
+		}
+	}
+
+	// 文本和图像的包围盒使用提取坐标（左上角为原点，Y 轴向下）
+	_, images, textRuns, err := r.extractPageContent(pageNum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract page content: %w", err)
+	}
+	pageInfo, _ := r.GetPageInfo(pageNum)
+	ex, ey := pageVisibleOrigin(pageInfo).Transform(result.X, result.Y)
+	ey = pageInfo.Height - ey
+
+	for i := len(textRuns) - 1; i >= 0 && result.Text == nil; i-- {
+		for j := len(textRuns[i].Glyphs) - 1; j >= 0; j-- {
+			g := textRuns[i].Glyphs[j]
+			if boxContains(g.X, g.Y, g.Width, g.Height, ex, ey) {
+				result.Text = &textRuns[i]
+				result.GlyphIndex = j
+				break
+			}
+		}
+	}
+
+	for i := len(images) - 1; i >= 0; i-- {
+		img := images[i]
+		if boxContains(img.X, img.Y, img.Width, img.Height, ex, ey) {
+			result.Image = &images[i]
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// pageUserToDeviceMatrix 返回按 dpi 渲染页面时从用户空间到设备像素的变换矩阵
+// 在临时表面上按渲染的顺序应用变换，保证与实际渲染一致
+func pageUserToDeviceMatrix(ctx *model.Context, pageDict types.Dict, dpi float64) *Matrix {
+	geom := getPageGeometry(ctx, pageDict)
+	scale := dpi / 72.0 * geom.UserUnit
+
+	surface := NewImageSurface(FormatARGB32, 1, 1)
+	defer surface.Destroy()
+	gopdfCtx := NewContext(surface)
+	defer gopdfCtx.Destroy()
+
+	gopdfCtx.Scale(scale, scale)
+	applyPageDeviceTransform(ctx, pageDict, gopdfCtx, geom.Width, geom.Height)
+	return gopdfCtx.GetMatrix()
+}
+
+// boxContains 判断点 (x, y) 是否在以 (bx, by) 为左上角的包围盒内（含边界）
+func boxContains(bx, by, bw, bh, x, y float64) bool {
+	return x >= bx && x <= bx+bw && y >= by && y <= by+bh
+}
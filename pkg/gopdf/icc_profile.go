@@ -0,0 +1,279 @@
+package gopdf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// iccTransformEnabled 是否使用嵌入的 ICC 配置文件转换 ICCBased 图像
+// 默认关闭：按分量数把采样值直接当作 RGB/Gray/CMYK 处理
+// 渲染中的 goroutine 会并发读取，使用 atomic.Bool 避免数据竞争
+var iccTransformEnabled atomic.Bool
+
+// SetICCTransformEnabled 启用或禁用 ICCBased 图像的 ICC 颜色转换
+// 启用后，使用矩阵/TRC 型 RGB 和灰度配置文件把采样值转换到 sRGB；
+// 无法解析的配置文件（如基于 LUT 的 CMYK 配置文件）仍使用按分量数的快速路径
+// 该开关影响进程内的所有渲染；只需对某次渲染启用时使用 RenderOptions.ICC
+func SetICCTransformEnabled(enabled bool) {
+	iccTransformEnabled.Store(enabled)
+}
+
+// iccProfile 解析后的矩阵/TRC 型 ICC 配置文件（RGB 或灰度）
+type iccProfile struct {
+	colorSpace string        // 数据颜色空间签名："RGB " 或 "GRAY"
+	matrix     [3][3]float64 // 线性 RGB 到 PCS XYZ（D50）的矩阵，各列为 rXYZ、gXYZ、bXYZ
+	trc        [3]iccCurve   // 各通道的色调响应曲线，灰度只使用 trc[0]
+}
+
+// iccCurve 把 [0,1] 的编码值映射为线性值
+type iccCurve func(float64) float64
+
+// xyzD50ToLinearSRGB PCS XYZ（D50）到线性 sRGB（D65）的矩阵，包含 Bradford 白点适应
+var xyzD50ToLinearSRGB = [3][3]float64{
+	{3.1338561, -1.6168667, -0.4906146},
+	{-0.9787684, 1.9161415, 0.0334540},
+	{0.0719453, -0.2289914, 1.4052427},
+}
+
+// parseICCProfile 解析 ICC 配置文件头和矩阵/TRC 标签
+func parseICCProfile(data []byte) (*iccProfile, error) {
+	if len(data) < 132 {
+		return nil, fmt.Errorf("ICC profile too short: %d bytes", len(data))
+	}
+	if pcs := string(data[20:24]); pcs != "XYZ " {
+		return nil, fmt.Errorf("unsupported ICC PCS %q", pcs)
+	}
+
+	tags := make(map[string][]byte)
+	count := int(binary.BigEndian.Uint32(data[128:132]))
+	for i := 0; i < count; i++ {
+		entry := 132 + i*12
+		if entry+12 > len(data) {
+			return nil, fmt.Errorf("ICC tag table truncated")
+		}
+		offset := int(binary.BigEndian.Uint32(data[entry+4:]))
+		size := int(binary.BigEndian.Uint32(data[entry+8:]))
+		if offset < 0 || size < 0 || offset+size > len(data) {
+			return nil, fmt.Errorf("ICC tag %q out of range", data[entry:entry+4])
+		}
+		tags[string(data[entry:entry+4])] = data[offset : offset+size]
+	}
+
+	p := &iccProfile{colorSpace: string(data[16:20])}
+	switch p.colorSpace {
+	case "RGB ":
+		for i, sig := range [3]string{"rXYZ", "gXYZ", "bXYZ"} {
+			xyz, err := parseICCXYZ(tags[sig])
+			if err != nil {
+				return nil, fmt.Errorf("ICC tag %s: %w", sig, err)
+			}
+			for row := range xyz {
+				p.matrix[row][i] = xyz[row]
+			}
+		}
+		for i, sig := range [3]string{"rTRC", "gTRC", "bTRC"} {
+			curve, err := parseICCCurve(tags[sig])
+			if err != nil {
+				return nil, fmt.Errorf("ICC tag %s: %w", sig, err)
+			}
+			p.trc[i] = curve
+		}
+	case "GRAY":
+		curve, err := parseICCCurve(tags["kTRC"])
+		if err != nil {
+			return nil, fmt.Errorf("ICC tag kTRC: %w", err)
+		}
+		p.trc[0] = curve
+	default:
+		return nil, fmt.Errorf("unsupported ICC color space %q", p.colorSpace)
+	}
+	return p, nil
+}
+
+// iccS15Fixed16 读取 s15Fixed16Number
+func iccS15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// parseICCXYZ 解析 XYZType 标签
+func parseICCXYZ(tag []byte) ([3]float64, error) {
+	if len(tag) < 20 || string(tag[:4]) != "XYZ " {
+		return [3]float64{}, fmt.Errorf("missing or invalid XYZ tag")
+	}
+	return [3]float64{iccS15Fixed16(tag[8:]), iccS15Fixed16(tag[12:]), iccS15Fixed16(tag[16:])}, nil
+}
+
+// parseICCCurve 解析 curveType（curv）或 parametricCurveType（para）标签
+func parseICCCurve(tag []byte) (iccCurve, error) {
+	if len(tag) < 12 {
+		return nil, fmt.Errorf("missing or invalid curve tag")
+	}
+	switch string(tag[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(tag[8:12]))
+		if len(tag) < 12+2*n {
+			return nil, fmt.Errorf("curve table truncated")
+		}
+		switch n {
+		case 0:
+			return func(x float64) float64 { return x }, nil
+		case 1:
+			// u8Fixed8Number 伽马值
+			gamma := float64(binary.BigEndian.Uint16(tag[12:])) / 256
+			return func(x float64) float64 { return math.Pow(x, gamma) }, nil
+		}
+		table := make([]float64, n)
+		for i := range table {
+			table[i] = float64(binary.BigEndian.Uint16(tag[12+2*i:])) / 65535
+		}
+		return func(x float64) float64 {
+			pos := clamp01(x) * float64(n-1)
+			i := int(pos)
+			if i >= n-1 {
+				return table[n-1]
+			}
+			t := pos - float64(i)
+			return table[i]*(1-t) + table[i+1]*t
+		}, nil
+
+	case "para":
+		funcType := int(binary.BigEndian.Uint16(tag[8:10]))
+		numParams := [...]int{1, 3, 4, 5, 7}
+		if funcType >= len(numParams) || len(tag) < 12+4*numParams[funcType] {
+			return nil, fmt.Errorf("invalid parametric curve type %d", funcType)
+		}
+		var params [7]float64
+		for i := 0; i < numParams[funcType]; i++ {
+			params[i] = iccS15Fixed16(tag[12+4*i:])
+		}
+		g, a, b, c, d, e, f := params[0], params[1], params[2], params[3], params[4], params[5], params[6]
+		return func(x float64) float64 {
+			switch funcType {
+			case 0:
+				return math.Pow(x, g)
+			case 1:
+				if a != 0 && x >= -b/a {
+					return math.Pow(a*x+b, g)
+				}
+				return 0
+			case 2:
+				if a != 0 && x >= -b/a {
+					return math.Pow(a*x+b, g) + c
+				}
+				return c
+			case 3:
+				if x >= d {
+					return math.Pow(a*x+b, g)
+				}
+				return c * x
+			default:
+				if x >= d {
+					return math.Pow(a*x+b, g) + e
+				}
+				return c*x + f
+			}
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported curve type %q", tag[:4])
+}
+
+// iccComponents 配置文件数据颜色空间的分量数
+func (p *iccProfile) iccComponents() int {
+	if p.colorSpace == "GRAY" {
+		return 1
+	}
+	return 3
+}
+
+// srgbEncodeTable 线性值（按 1/4095 量化）到 8 位 sRGB 编码值的查找表
+var (
+	srgbEncodeOnce  sync.Once
+	srgbEncodeTable [4096]uint8
+)
+
+// srgbDecodeTable 8 位 sRGB 编码值到线性值的查找表
+var (
+	srgbDecodeOnce  sync.Once
+	srgbDecodeTable [256]float64
+)
+
+// srgbDecode8 把 8 位 sRGB 值解码为线性值（srgbEncode8 的逆变换）
+func srgbDecode8(v uint8) float64 {
+	srgbDecodeOnce.Do(func() {
+		for i := range srgbDecodeTable {
+			c := float64(i) / 255
+			if c <= 0.04045 {
+				srgbDecodeTable[i] = c / 12.92
+			} else {
+				srgbDecodeTable[i] = math.Pow((c+0.055)/1.055, 2.4)
+			}
+		}
+	})
+	return srgbDecodeTable[v]
+}
+
+// srgbEncode8 把线性值编码为 8 位 sRGB 值
+func srgbEncode8(v float64) uint8 {
+	srgbEncodeOnce.Do(func() {
+		for i := range srgbEncodeTable {
+			srgbEncodeTable[i] = uint8(math.Round(srgbGamma(float64(i)/4095) * 255))
+		}
+	})
+	return srgbEncodeTable[int(math.Round(clamp01(v)*4095))]
+}
+
+// transformImage 把按配置文件编码的不透明图像原地转换为 sRGB
+// 灰度图像的 R、G、B 通道相同，只读取 R
+func (p *iccProfile) transformImage(img *image.RGBA) {
+	// 8 位采样值到线性值的查找表
+	var linear [3][256]float64
+	for ch := 0; ch < p.iccComponents(); ch++ {
+		for v := range linear[ch] {
+			linear[ch][v] = p.trc[ch](float64(v) / 255)
+		}
+	}
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(bounds.Min.X, y):img.PixOffset(bounds.Max.X, y)]
+		for i := 0; i+3 < len(row); i += 4 {
+			if p.colorSpace == "GRAY" {
+				gray := srgbEncode8(linear[0][row[i]])
+				row[i], row[i+1], row[i+2] = gray, gray, gray
+				continue
+			}
+
+			lin := [3]float64{linear[0][row[i]], linear[1][row[i+1]], linear[2][row[i+2]]}
+			var xyz [3]float64
+			for r := range xyz {
+				xyz[r] = p.matrix[r][0]*lin[0] + p.matrix[r][1]*lin[1] + p.matrix[r][2]*lin[2]
+			}
+			for c := 0; c < 3; c++ {
+				m := xyzD50ToLinearSRGB[c]
+				row[i+c] = srgbEncode8(m[0]*xyz[0] + m[1]*xyz[1] + m[2]*xyz[2])
+			}
+		}
+	}
+}
+
+// applyICCProfile 在启用 ICC 转换（全局开关或 xobj.ICCTransform）时使用 xobj 的嵌入配置文件转换解码后的图像
+// 配置文件缺失、无法解析或分量数不符时保持原样
+func applyICCProfile(img *image.RGBA, xobj *XObject, numComponents int) {
+	if !(iccTransformEnabled.Load() || xobj.ICCTransform) || len(xobj.ICCProfile) == 0 {
+		return
+	}
+	profile, err := parseICCProfile(xobj.ICCProfile)
+	if err != nil {
+		debugPrintf("[applyICCProfile] Skipping ICC transform: %v\n", err)
+		return
+	}
+	if profile.iccComponents() != numComponents {
+		debugPrintf("[applyICCProfile] ICC profile has %d components, image has %d\n", profile.iccComponents(), numComponents)
+		return
+	}
+	profile.transformImage(img)
+}
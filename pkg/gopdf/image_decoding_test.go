@@ -1,8 +1,16 @@
 package gopdf
 
 import (
+	"bytes"
+	"encoding/binary"
 	"image"
+	"image/color"
+	"math"
+	"sort"
+	"sync"
 	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
 )
 
 func TestDecodeImageXObject_SMask(t *testing.T) {
@@ -85,6 +93,380 @@ func TestDecodeImageXObject_SMask(t *testing.T) {
 	}
 }
 
+func TestDecodeImageXObject_SMaskMatte(t *testing.T) {
+	// 原色为纯红 (255,0,0)，以白色 Matte 预混合，alpha=128：
+	// c' = m + a*(c - m) => (255, 127, 127)
+	smaskXObj := &XObject{
+		Subtype:          "Image",
+		Width:            2,
+		Height:           1,
+		ColorSpace:       "DeviceGray",
+		BitsPerComponent: 8,
+		Stream:           []byte{128, 0},
+		Matte:            []float64{1, 1, 1},
+	}
+	xobj := &XObject{
+		Subtype:          "Image",
+		Width:            2,
+		Height:           1,
+		ColorSpace:       "DeviceRGB",
+		BitsPerComponent: 8,
+		Stream:           []byte{255, 127, 127, 255, 255, 255},
+		SMask:            smaskXObj,
+	}
+
+	img, err := decodeImageXObject(xobj)
+	if err != nil {
+		t.Fatalf("Failed to decode image with matted SMask: %v", err)
+	}
+
+	// (0,0): 还原后应接近纯红
+	if r, g, b, a := img.Pix[0], img.Pix[1], img.Pix[2], img.Pix[3]; r != 255 || g > 2 || b > 2 || a != 128 {
+		t.Errorf("Pix(0,0): expected un-matted red with alpha 128, got (%d,%d,%d,%d)", r, g, b, a)
+	}
+	// (1,0): alpha=0 的像素颜色保持不变
+	if r, g, b, a := img.Pix[4], img.Pix[5], img.Pix[6], img.Pix[7]; r != 255 || g != 255 || b != 255 || a != 0 {
+		t.Errorf("Pix(1,0): expected untouched color with alpha 0, got (%d,%d,%d,%d)", r, g, b, a)
+	}
+}
+
+func TestDecodeImageXObject_SMaskTransfer(t *testing.T) {
+	// 遮罩采样值 0、64、255 经 /TR 传递函数重新映射后作为 alpha
+	newImage := func(transfer *PDFFunction) *XObject {
+		return &XObject{
+			Subtype:          "Image",
+			Width:            3,
+			Height:           1,
+			ColorSpace:       "DeviceRGB",
+			BitsPerComponent: 8,
+			Stream:           []byte{255, 0, 0, 255, 0, 0, 255, 0, 0},
+			SMask: &XObject{
+				Subtype:          "Image",
+				Width:            3,
+				Height:           1,
+				ColorSpace:       "DeviceGray",
+				BitsPerComponent: 8,
+				Stream:           []byte{0, 64, 255},
+				Transfer:         transfer,
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		transfer *PDFFunction
+		alpha    [3]uint8
+	}{
+		{"identity", nil, [3]uint8{0, 64, 255}},
+		{"inverted", &PDFFunction{FunctionType: 2, Domain: []float64{0, 1}, C0: []float64{1}, C1: []float64{0}, N: 1}, [3]uint8{255, 191, 0}},
+		{"squared", &PDFFunction{FunctionType: 4, Domain: []float64{0, 1}, Range: []float64{0, 1},
+			Program: mustParsePSFunction(t, "{ dup mul }")}, [3]uint8{0, 16, 255}},
+	}
+	for _, tt := range tests {
+		img, err := decodeImageXObject(newImage(tt.transfer))
+		if err != nil {
+			t.Fatalf("%s: failed to decode image: %v", tt.name, err)
+		}
+		for x, want := range tt.alpha {
+			if got := img.Pix[x*4+3]; got != want {
+				t.Errorf("%s: alpha at x=%d = %d, want %d", tt.name, x, got, want)
+			}
+		}
+	}
+}
+
+// TestBlitPageImage_SMaskPremultiplied 单图像页面的直接写入路径把 SMask 得到的非预乘像素预乘后再合成
+func TestBlitPageImage_SMaskPremultiplied(t *testing.T) {
+	surface := NewImageSurface(FormatARGB32, 3, 1)
+	defer surface.Destroy()
+	gopdfCtx := NewContext(surface)
+	defer gopdfCtx.Destroy()
+	gopdfCtx.SetSourceRGB(1, 1, 1)
+	gopdfCtx.Paint()
+	gopdfCtx.Translate(0, 1)
+	gopdfCtx.Scale(1, -1)
+	ctx := NewRenderContext(gopdfCtx, 3, 1)
+
+	// 3x1 蓝色图像，SMask 依次为不透明、全透明、半透明
+	xobj := &XObject{
+		Subtype: "Image", Width: 3, Height: 1, ColorSpace: "DeviceRGB", BitsPerComponent: 8,
+		Stream: []byte{0, 0, 255, 0, 0, 255, 0, 0, 255},
+		SMask: &XObject{
+			Subtype: "Image", Width: 3, Height: 1, ColorSpace: "DeviceGray", BitsPerComponent: 8,
+			Stream: []byte{255, 0, 128},
+		},
+	}
+	if err := (&OpConcatMatrix{Matrix: &Matrix{XX: 3, YY: 1}}).Execute(ctx); err != nil {
+		t.Fatalf("cm: %v", err)
+	}
+	if err := blitPageImage(ctx, xobj); err != nil {
+		t.Fatalf("blitPageImage: %v", err)
+	}
+
+	img := surface.(ImageSurface).GetGoImage().(*image.RGBA)
+	checkPixel(t, img, 0, 0, 0, 0, 255, 255)
+	checkPixel(t, img, 1, 0, 255, 255, 255, 255)
+	checkPixel(t, img, 2, 0, 127, 127, 255, 255)
+}
+
+func mustParsePSFunction(t *testing.T, src string) []psInstr {
+	t.Helper()
+	prog, err := parsePSFunction([]byte(src))
+	if err != nil {
+		t.Fatalf("parsePSFunction(%q): %v", src, err)
+	}
+	return prog
+}
+
+func TestLoadPDFFunction_Streams(t *testing.T) {
+	// 类型 0：3 个 8 位采样 0、255、0，线性插值为三角形
+	sampled := types.StreamDict{
+		Dict: types.Dict{
+			"FunctionType":  types.Integer(0),
+			"Domain":        types.Array{types.Integer(0), types.Integer(1)},
+			"Range":         types.Array{types.Integer(0), types.Integer(1)},
+			"Size":          types.Array{types.Integer(3)},
+			"BitsPerSample": types.Integer(8),
+		},
+		Content: []byte{0, 255, 0},
+	}
+	fn, err := loadPDFFunction(nil, sampled)
+	if err != nil {
+		t.Fatalf("sampled function: %v", err)
+	}
+	for _, tt := range []struct{ in, want float64 }{{0, 0}, {0.25, 0.5}, {0.5, 1}, {1, 0}} {
+		if got := fn.EvaluateFunction(tt.in)[0]; math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("sampled f(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	// 类型 4：x < 0.5 时输出 2x，否则输出 1，并限制在 Range 内
+	calc := types.StreamDict{
+		Dict: types.Dict{
+			"FunctionType": types.Integer(4),
+			"Domain":       types.Array{types.Integer(0), types.Integer(1)},
+			"Range":        types.Array{types.Integer(0), types.Integer(1)},
+		},
+		Content: []byte("{ dup 0.5 lt { 2 mul } { pop 1.5 } ifelse }"),
+	}
+	fn, err = loadPDFFunction(nil, calc)
+	if err != nil {
+		t.Fatalf("PostScript function: %v", err)
+	}
+	for _, tt := range []struct{ in, want float64 }{{0.2, 0.4}, {0.7, 1}} {
+		if got := fn.EvaluateFunction(tt.in)[0]; math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("PostScript f(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoadPDFFunction_RejectsOversizedSampleTable(t *testing.T) {
+	// /Size 来自文件：非正数、乘积溢出或超出数据长度时在分配采样表之前返回错误
+	for _, tt := range []struct {
+		name string
+		size types.Array
+		data []byte
+	}{
+		{"huge", types.Array{types.Integer(1 << 30), types.Integer(1 << 30)}, []byte{0, 255}},
+		{"overflow", types.Array{types.Integer(1 << 40), types.Integer(1 << 40), types.Integer(1 << 40)}, []byte{0, 255}},
+		{"zero", types.Array{types.Integer(0)}, []byte{0, 255}},
+		{"negative", types.Array{types.Integer(-2), types.Integer(-2)}, []byte{0, 255, 0, 255}},
+		{"short data", types.Array{types.Integer(3)}, []byte{0, 255}},
+	} {
+		fn := types.StreamDict{
+			Dict: types.Dict{
+				"FunctionType":  types.Integer(0),
+				"Domain":        types.Array{types.Integer(0), types.Integer(1)},
+				"Range":         types.Array{types.Integer(0), types.Integer(1)},
+				"Size":          tt.size,
+				"BitsPerSample": types.Integer(8),
+			},
+			Content: tt.data,
+		}
+		if _, err := loadPDFFunction(nil, fn); err == nil {
+			t.Errorf("%s: expected error for Size %v with %d bytes", tt.name, tt.size, len(tt.data))
+		}
+	}
+}
+
+func TestPDFFunction_StitchingAndMultiInput(t *testing.T) {
+	// 类型 3：[0, 0.5) 从 0 升到 1，[0.5, 1] 从 1 降到 0（第二个子函数 Encode 反向）
+	stitching := types.Dict{
+		"FunctionType": types.Integer(3),
+		"Domain":       types.Array{types.Integer(0), types.Integer(1)},
+		"Bounds":       types.Array{types.Float(0.5)},
+		"Encode":       types.Array{types.Integer(0), types.Integer(1), types.Integer(1), types.Integer(0)},
+		"Functions": types.Array{
+			types.Dict{"FunctionType": types.Integer(2), "N": types.Integer(1)},
+			types.Dict{"FunctionType": types.Integer(2), "N": types.Integer(1)},
+		},
+	}
+	fn, err := loadPDFFunction(nil, stitching)
+	if err != nil {
+		t.Fatalf("stitching function: %v", err)
+	}
+	for _, tt := range []struct{ in, want float64 }{{0, 0}, {0.25, 0.5}, {0.5, 1}, {0.75, 0.5}, {2, 0}} {
+		if got := fn.EvaluateFunction(tt.in)[0]; math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("stitching f(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	// 类型 0，两个输入：2x2 采样表 f(0,0)=0, f(1,0)=255, f(0,1)=255, f(1,1)=0
+	sampled := types.StreamDict{
+		Dict: types.Dict{
+			"FunctionType":  types.Integer(0),
+			"Domain":        types.Array{types.Integer(0), types.Integer(1), types.Integer(0), types.Integer(1)},
+			"Range":         types.Array{types.Integer(0), types.Integer(1)},
+			"Size":          types.Array{types.Integer(2), types.Integer(2)},
+			"BitsPerSample": types.Integer(8),
+		},
+		Content: []byte{0, 255, 255, 0},
+	}
+	fn, err = loadPDFFunction(nil, sampled)
+	if err != nil {
+		t.Fatalf("sampled function: %v", err)
+	}
+	for _, tt := range []struct{ x, y, want float64 }{{0, 0, 0}, {1, 0, 1}, {0, 1, 1}, {0.5, 0.5, 0.5}, {0.5, 0, 0.5}} {
+		if got := fn.Eval([]float64{tt.x, tt.y})[0]; math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("sampled f(%v, %v) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestSeparationColorSpace(t *testing.T) {
+	// 专色 tint 0..1 映射为 DeviceRGB 白色到纯红
+	tint := types.Dict{
+		"FunctionType": types.Integer(2),
+		"Domain":       types.Array{types.Integer(0), types.Integer(1)},
+		"C0":           types.Array{types.Integer(1), types.Integer(1), types.Integer(1)},
+		"C1":           types.Array{types.Integer(1), types.Integer(0), types.Integer(0)},
+		"N":            types.Integer(1),
+	}
+	csArr := types.Array{types.Name("Separation"), types.Name("Spot Red"), types.Name("DeviceRGB"), tint}
+	cs, err := parseColorSpaceObject(nil, csArr, 0)
+	if err != nil {
+		t.Fatalf("parseColorSpaceObject: %v", err)
+	}
+	sep, ok := cs.(*DeviceNColorSpace)
+	if !ok || sep.GetName() != "Separation" || sep.GetNumComponents() != 1 {
+		t.Fatalf("unexpected color space %#v", cs)
+	}
+	if r, g, b := initialColorRGB(cs); r != 1 || g != 0 || b != 0 {
+		t.Errorf("initial color = (%v, %v, %v), want full tint red", r, g, b)
+	}
+
+	// 1x3 图像，8 位 tint 0、128、255
+	xobj := &XObject{
+		Subtype:          "Image",
+		Width:            3,
+		Height:           1,
+		BitsPerComponent: 8,
+		Stream:           []byte{0, 128, 255},
+	}
+	applyColorSpaceToXObject(xobj, cs)
+	img, err := decodeImageXObject(xobj)
+	if err != nil {
+		t.Fatalf("decode Separation image: %v", err)
+	}
+	checkPixel(t, img, 0, 0, 255, 255, 255, 255)
+	checkPixel(t, img, 1, 0, 255, 127, 127, 255)
+	checkPixel(t, img, 2, 0, 255, 0, 0, 255)
+
+	// DeviceN：两个 1 位着色剂，tint 变换输出 DeviceGray 1 - max(c0, c1)
+	calc := types.StreamDict{
+		Dict: types.Dict{
+			"FunctionType": types.Integer(4),
+			"Domain":       types.Array{types.Integer(0), types.Integer(1), types.Integer(0), types.Integer(1)},
+			"Range":        types.Array{types.Integer(0), types.Integer(1)},
+		},
+		Content: []byte("{ 2 copy lt { exch } if pop 1 exch sub }"),
+	}
+	csArr = types.Array{types.Name("DeviceN"), types.Array{types.Name("A"), types.Name("B")}, types.Name("DeviceGray"), calc}
+	cs, err = parseColorSpaceObject(nil, csArr, 0)
+	if err != nil {
+		t.Fatalf("parseColorSpaceObject(DeviceN): %v", err)
+	}
+	// 3 像素 x 2 位 = 6 位，每行填充到 1 字节：00 10 01 → 0b00100100
+	xobj = &XObject{Subtype: "Image", Width: 3, Height: 1, BitsPerComponent: 1, Stream: []byte{0x24}}
+	applyColorSpaceToXObject(xobj, cs)
+	img, err = decodeImageXObject(xobj)
+	if err != nil {
+		t.Fatalf("decode DeviceN image: %v", err)
+	}
+	checkPixel(t, img, 0, 0, 255, 255, 255, 255)
+	checkPixel(t, img, 1, 0, 0, 0, 0, 255)
+	checkPixel(t, img, 2, 0, 0, 0, 0, 255)
+}
+
+func TestEvalPSFunctionOperators(t *testing.T) {
+	tests := []struct {
+		prog string
+		want []float64
+	}{
+		{"{ 3 4 exch sub }", []float64{1}},
+		{"{ 7 2 idiv 7 2 mod }", []float64{3, 1}},
+		{"{ 1 2 3 3 1 roll }", []float64{3, 1, 2}},
+		{"{ 1 2 2 copy 3 index }", []float64{1, 2, 1, 2, 1}},
+		{"{ 1 0 atan 90 eq { 1 } if 2.5 round 1 3 bitshift }", []float64{1, 3, 8}},
+		{"{ true false xor not { 5 } { 6 } ifelse }", []float64{6}},
+	}
+	for _, tt := range tests {
+		got, err := evalPSFunction(mustParsePSFunction(t, tt.prog), nil)
+		if err != nil {
+			t.Errorf("%s: %v", tt.prog, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.prog, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s = %v, want %v", tt.prog, got, tt.want)
+				break
+			}
+		}
+	}
+
+	if _, err := evalPSFunction(mustParsePSFunction(t, "{ pop }"), nil); err == nil {
+		t.Error("expected stack underflow error")
+	}
+}
+
+func TestDecodeImageXObject_SMaskBilinear(t *testing.T) {
+	// 2x1 mask (0, 255) 放大到 4x1 图像，应产生平滑过渡而不是阶跃
+	smaskXObj := &XObject{
+		Subtype:          "Image",
+		Width:            2,
+		Height:           1,
+		ColorSpace:       "DeviceGray",
+		BitsPerComponent: 8,
+		Stream:           []byte{0, 255},
+	}
+	xobj := &XObject{
+		Subtype:          "Image",
+		Width:            4,
+		Height:           1,
+		ColorSpace:       "DeviceGray",
+		BitsPerComponent: 8,
+		Stream:           []byte{0, 0, 0, 0},
+		SMask:            smaskXObj,
+	}
+
+	img, err := decodeImageXObject(xobj)
+	if err != nil {
+		t.Fatalf("Failed to decode image with SMask: %v", err)
+	}
+
+	// 像素中心映射到 mask 坐标 -0.25, 0.25, 0.75, 1.25
+	want := []uint8{0, 64, 191, 255}
+	for x, w := range want {
+		if got := img.Pix[x*4+3]; got != w {
+			t.Errorf("Pix(%d,0) Alpha: expected %d, got %d", x, w, got)
+		}
+	}
+}
+
 func TestDecodeImageXObject_Indexed(t *testing.T) {
 	// 2x2 image, Indexed 2 colors
 	// Palette: color 0 = Black (0,0,0), color 1 = Green (0,255,0)
@@ -116,6 +498,35 @@ func TestDecodeImageXObject_Indexed(t *testing.T) {
 	checkPixel(t, img, 1, 0, 0, 255, 0, 255)
 }
 
+func TestDecodeImage_OneBitRowPadding(t *testing.T) {
+	// 5 像素宽的 1 位图像：每行占 1 个字节，低 3 位为填充
+	// 行 0：10110|000，行 1：01001|000，行 2：11111|000
+	stream := []byte{0xB0, 0x48, 0xF8}
+	want := [3][5]uint8{
+		{1, 0, 1, 1, 0},
+		{0, 1, 0, 0, 1},
+		{1, 1, 1, 1, 1},
+	}
+
+	gray, err := DecodeImage(stream, 5, 3, 1, "DeviceGray", nil)
+	if err != nil {
+		t.Fatalf("Failed to decode 1-bit DeviceGray image: %v", err)
+	}
+	// 调色板：0 = 红色，1 = 蓝色
+	indexed, err := DecodeImage(stream, 5, 3, 1, "Indexed", []byte{255, 0, 0, 0, 0, 255})
+	if err != nil {
+		t.Fatalf("Failed to decode 1-bit Indexed image: %v", err)
+	}
+
+	for y, row := range want {
+		for x, bit := range row {
+			g := bit * 255
+			checkPixel(t, gray, x, y, g, g, g, 255)
+			checkPixel(t, indexed, x, y, 255-g, 0, g, 255)
+		}
+	}
+}
+
 func TestDecodeImageXObject_ICCBased_CMYK(t *testing.T) {
 	// Simulate ICCBased with 4 components (CMYK)
 	// 1 pixel: Cyan (1.0, 0, 0, 0) -> should be R=0, G=255, B=255 (roughly)
@@ -171,6 +582,304 @@ func TestDecodeImageXObject_DeviceCMYK_Conversion(t *testing.T) {
 	checkPixel(t, img, 0, 0, 0, 255, 0, 255)
 }
 
+func TestDecodeImageXObject_DeviceCMYK_SWOP(t *testing.T) {
+	// 白、100% K、纯青：SWOP 近似与朴素公式的差异
+	stream := []byte{0, 0, 0, 0, 0, 0, 0, 255, 255, 0, 0, 0}
+	newXObject := func(mode CMYKConversion) *XObject {
+		return &XObject{
+			Subtype:          "Image",
+			Width:            3,
+			Height:           1,
+			ColorSpace:       "DeviceCMYK",
+			BitsPerComponent: 8,
+			Stream:           stream,
+			CMYKConversion:   mode,
+		}
+	}
+	near := func(got color.RGBA, r, g, b uint8) bool {
+		d := func(a, b uint8) int {
+			if a > b {
+				return int(a - b)
+			}
+			return int(b - a)
+		}
+		return d(got.R, r) <= 3 && d(got.G, g) <= 3 && d(got.B, b) <= 3 && got.A == 255
+	}
+
+	img, err := decodeImageXObject(newXObject(CMYKSWOP))
+	if err != nil {
+		t.Fatalf("Failed to decode DeviceCMYK: %v", err)
+	}
+	want := [][3]uint8{{255, 255, 255}, {44, 47, 53}, {0, 184, 242}}
+	for x, w := range want {
+		if got := img.RGBAAt(x, 0); !near(got, w[0], w[1], w[2]) {
+			t.Errorf("SWOP pixel %d = %v, want about %v", x, got, w)
+		}
+	}
+
+	// 默认仍使用朴素公式；全局设置对未指定转换方式的图像生效
+	img, err = decodeImageXObject(newXObject(CMYKDefault))
+	if err != nil {
+		t.Fatalf("Failed to decode DeviceCMYK: %v", err)
+	}
+	checkPixel(t, img, 1, 0, 0, 0, 0, 255)
+	checkPixel(t, img, 2, 0, 0, 255, 255, 255)
+
+	SetCMYKConversion(CMYKSWOP)
+	defer SetCMYKConversion(CMYKDefault)
+	img, err = decodeImageXObject(newXObject(CMYKDefault))
+	if err != nil {
+		t.Fatalf("Failed to decode DeviceCMYK: %v", err)
+	}
+	if got := img.RGBAAt(1, 0); !near(got, 44, 47, 53) {
+		t.Errorf("global SWOP pixel = %v, want about (44,47,53)", got)
+	}
+
+	// 显式指定 CMYKNaive 时不受全局 SWOP 设置影响
+	img, err = decodeImageXObject(newXObject(CMYKNaive))
+	if err != nil {
+		t.Fatalf("Failed to decode DeviceCMYK: %v", err)
+	}
+	checkPixel(t, img, 1, 0, 0, 0, 0, 255)
+	ctx := NewRenderContext(NewContext(NewImageSurface(FormatARGB32, 1, 1)), 1, 1)
+	ctx.CMYKConversion = CMYKNaive
+	if err := (&OpSetFillColorCMYK{K: 1}).Execute(ctx); err != nil {
+		t.Fatalf("k failed: %v", err)
+	}
+	if c := ctx.GetCurrentState().FillColor; c.R != 0 || c.G != 0 || c.B != 0 {
+		t.Errorf("naive k fill color = %+v, want black", c)
+	}
+
+	// k 操作符使用渲染上下文的转换方式（RenderOptions.CMYK）
+	SetCMYKConversion(CMYKDefault)
+	ctx = NewRenderContext(NewContext(NewImageSurface(FormatARGB32, 1, 1)), 1, 1)
+	ctx.CMYKConversion = CMYKSWOP
+	if err := (&OpSetFillColorCMYK{K: 1}).Execute(ctx); err != nil {
+		t.Fatalf("k failed: %v", err)
+	}
+	if c := ctx.GetCurrentState().FillColor; math.Abs(c.R*255-44) > 3 || math.Abs(c.B*255-53) > 3 {
+		t.Errorf("k fill color = %+v, want about (44,47,53)/255", c)
+	}
+}
+
+func TestSetCMYKConversionDuringDecode(t *testing.T) {
+	// 在其它 goroutine 解码时切换全局转换方式，配合 -race 检查数据竞争
+	t.Cleanup(func() { SetCMYKConversion(CMYKDefault) })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				img, err := decodeImageXObject(&XObject{
+					Subtype: "Image", Width: 1, Height: 1, BitsPerComponent: 8,
+					ColorSpace: "DeviceCMYK", Stream: []byte{0, 0, 0, 255},
+				})
+				if err != nil {
+					t.Errorf("Failed to decode DeviceCMYK image: %v", err)
+					return
+				}
+				// 转换方式随时可能变化，只能是朴素公式的黑色或 SWOP 的深灰之一
+				if v := img.Pix[0]; v != 0 && (v < 41 || v > 47) {
+					t.Errorf("Pix[0] = %d, want 0 or about 44", v)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		mode := CMYKNaive
+		if i%2 == 0 {
+			mode = CMYKSWOP
+		}
+		SetCMYKConversion(mode)
+	}
+	wg.Wait()
+}
+
+func TestDecodeInlineImage_Indexed(t *testing.T) {
+	// BI /W 3 /H 1 /BPC 8 /CS [/I /RGB 2 <FF0000 00FF00 0000FF>] ID ... EI
+	dict := map[string]interface{}{
+		"/W":   3.0,
+		"/H":   1.0,
+		"/BPC": 8.0,
+		"/CS":  []interface{}{"/I", "/RGB", 2.0, HexString("<FF0000 00FF00 0000FF>")},
+	}
+
+	img, err := DecodeInlineImage(dict, []byte{2, 0, 1}, nil)
+	if err != nil {
+		t.Fatalf("Failed to decode inline Indexed image: %v", err)
+	}
+
+	checkPixel(t, img, 0, 0, 0, 0, 255, 255) // index 2 -> Blue
+	checkPixel(t, img, 1, 0, 255, 0, 0, 255) // index 0 -> Red
+	checkPixel(t, img, 2, 0, 0, 255, 0, 255) // index 1 -> Green
+}
+
+func TestDecodeInlineImage_IndexedLiteralLookup(t *testing.T) {
+	// 字面量查找表中含反斜杠字节（\134）：parseValue 已处理转义，不能再按 "\n" 转义一次
+	ops, err := ParseContentStream([]byte("BI /W 2 /H 1 /BPC 8 /CS [/I /RGB 1 (\\134n\\000\\000\\000\\377)] ID \x00\x01 EI"))
+	if err != nil {
+		t.Fatalf("ParseContentStream failed: %v", err)
+	}
+	var id *OpInlineImageData
+	for _, op := range ops {
+		if op, ok := op.(*OpInlineImageData); ok {
+			id = op
+		}
+	}
+	if id == nil {
+		t.Fatalf("no inline image operator in %v", ops)
+	}
+
+	img, err := DecodeInlineImage(id.ImageDict, id.ImageData, nil)
+	if err != nil {
+		t.Fatalf("Failed to decode inline Indexed image: %v", err)
+	}
+	checkPixel(t, img, 0, 0, 92, 110, 0, 255) // index 0 -> ('\\', 'n', 0)
+	checkPixel(t, img, 1, 0, 0, 0, 255, 255)  // index 1 -> (0, 0, 255)
+}
+
+func TestInlineImageMaskPaintsFillColor(t *testing.T) {
+	// 8x1 模板掩码 0x0F：默认 /Decode [0 1] 时前 4 个样本（0）以填充颜色绘制，后 4 个透明；/D [1 0] 相反
+	tests := []struct {
+		name          string
+		dict          string
+		painted, bare int
+	}{
+		{"default decode", "/W 8 /H 1 /IM true", 0, 7},
+		{"inverted decode", "/W 8 /H 1 /IM true /D [1 0]", 7, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			surface := NewImageSurface(FormatARGB32, 8, 1)
+			defer surface.Destroy()
+			gopdfCtx := NewContext(surface)
+			defer gopdfCtx.Destroy()
+			gopdfCtx.SetSourceRGB(1, 1, 1)
+			gopdfCtx.Paint()
+			gopdfCtx.Translate(0, 1)
+			gopdfCtx.Scale(1, -1)
+			ctx := NewRenderContext(gopdfCtx, 8, 1)
+
+			ops, err := ParseContentStream([]byte("1 0 0 rg 8 0 0 1 0 0 cm BI " + tt.dict + " ID \x0f EI"))
+			if err != nil {
+				t.Fatalf("ParseContentStream failed: %v", err)
+			}
+			for _, op := range ops {
+				if err := op.Execute(ctx); err != nil {
+					t.Fatalf("%s failed: %v", op.Name(), err)
+				}
+			}
+
+			img := surface.(ImageSurface).GetGoImage().(*image.RGBA)
+			checkPixel(t, img, tt.painted, 0, 255, 0, 0, 255)
+			checkPixel(t, img, tt.bare, 0, 255, 255, 255, 255)
+		})
+	}
+}
+
+func TestImageMaskCacheKeyIncludesFillColor(t *testing.T) {
+	// 同一模板掩码 XObject 先以红色、再以蓝色绘制：缓存不能复用红色的表面
+	surface := NewImageSurface(FormatARGB32, 2, 1)
+	defer surface.Destroy()
+	gopdfCtx := NewContext(surface)
+	defer gopdfCtx.Destroy()
+	gopdfCtx.Translate(0, 1)
+	gopdfCtx.Scale(1, -1)
+	ctx := NewRenderContext(gopdfCtx, 2, 1)
+	defer ctx.releaseXObjectCache()
+
+	mask := &XObject{Subtype: "Image", Width: 1, Height: 1, ImageMask: true, BitsPerComponent: 1,
+		Stream: []byte{0x00}, ObjectNumber: 5}
+	ops := []PDFOperator{
+		&OpSetFillColorRGB{R: 1},
+		&OpSaveState{}, &OpConcatMatrix{Matrix: &Matrix{XX: 1, YY: 1}},
+		&OpRestoreState{},
+		&OpSetFillColorRGB{B: 1},
+		&OpConcatMatrix{Matrix: &Matrix{XX: 1, YY: 1, X0: 1}},
+	}
+	for i, op := range ops {
+		if err := op.Execute(ctx); err != nil {
+			t.Fatalf("%s failed: %v", op.Name(), err)
+		}
+		// 在 cm 之后绘制掩码
+		if _, ok := op.(*OpConcatMatrix); ok {
+			if err := renderImageXObject(ctx, mask); err != nil {
+				t.Fatalf("renderImageXObject after op %d: %v", i, err)
+			}
+		}
+	}
+	if len(ctx.XObjectCache) != 2 {
+		t.Errorf("XObjectCache has %d entries, want one per fill color", len(ctx.XObjectCache))
+	}
+
+	img := surface.(ImageSurface).GetGoImage().(*image.RGBA)
+	checkPixel(t, img, 0, 0, 255, 0, 0, 255)
+	checkPixel(t, img, 1, 0, 0, 0, 255, 255)
+}
+
+func TestDecodeInlineImage_IndexedResourcePalette(t *testing.T) {
+	// 命名颜色空间来自资源字典：Indexed，基础颜色空间为 DeviceCMYK
+	resources := NewResources()
+	resources.SetColorSpace("CS0", &IndexedColorSpace{
+		Base:   &DeviceCMYKColorSpace{},
+		HiVal:  1,
+		Lookup: []byte{0, 0, 0, 255, 255, 0, 255, 0}, // 0 -> Black, 1 -> Green
+	})
+
+	dict := map[string]interface{}{
+		"/W":  2.0,
+		"/H":  1.0,
+		"/CS": "/CS0",
+	}
+
+	img, err := DecodeInlineImage(dict, []byte{1, 0}, resources)
+	if err != nil {
+		t.Fatalf("Failed to decode inline image with resource palette: %v", err)
+	}
+
+	checkPixel(t, img, 0, 0, 0, 255, 0, 255)
+	checkPixel(t, img, 1, 0, 0, 0, 0, 255)
+
+	// Indexed 的基础颜色空间引用资源中的命名颜色空间
+	resources.SetColorSpace("Base", &DeviceGrayColorSpace{})
+	dict["/CS"] = []interface{}{"/I", "/Base", 1.0, HexString("<00 FF>")}
+	img, err = DecodeInlineImage(dict, []byte{1, 0}, resources)
+	if err != nil {
+		t.Fatalf("Failed to decode inline image with named base: %v", err)
+	}
+
+	checkPixel(t, img, 0, 0, 255, 255, 255, 255)
+	checkPixel(t, img, 1, 0, 0, 0, 0, 255)
+}
+
+func TestDecodeInlineImage_Abbreviations(t *testing.T) {
+	// /CMYK 颜色空间 + /AHx 滤镜：C=255 M=0 Y=255 K=0 -> Green
+	dict := map[string]interface{}{
+		"/W":  1.0,
+		"/H":  1.0,
+		"/CS": "/CMYK",
+		"/F":  "/AHx",
+	}
+
+	img, err := DecodeInlineImage(dict, []byte("FF00FF00>"), nil)
+	if err != nil {
+		t.Fatalf("Failed to decode inline CMYK image: %v", err)
+	}
+	checkPixel(t, img, 0, 0, 0, 255, 0, 255)
+
+	// /G 灰度
+	dict = map[string]interface{}{"/W": 2.0, "/H": 1.0, "/CS": "/G", "/BPC": 8.0}
+	img, err = DecodeInlineImage(dict, []byte{0, 255}, nil)
+	if err != nil {
+		t.Fatalf("Failed to decode inline gray image: %v", err)
+	}
+	checkPixel(t, img, 0, 0, 0, 0, 0, 255)
+	checkPixel(t, img, 1, 0, 255, 255, 255, 255)
+}
+
 func checkPixel(t *testing.T, img *image.RGBA, x, y int, r, g, b, a uint8) {
 	t.Helper()
 	idx := img.PixOffset(x, y)
@@ -180,3 +889,499 @@ func checkPixel(t *testing.T, img *image.RGBA, x, y int, r, g, b, a uint8) {
 			img.Pix[idx+0], img.Pix[idx+1], img.Pix[idx+2], img.Pix[idx+3])
 	}
 }
+
+func TestDecodeImage_DeviceRGB(t *testing.T) {
+	// 2x2 DeviceRGB：红、绿 / 蓝、白
+	stream := []byte{
+		255, 0, 0, 0, 255, 0,
+		0, 0, 255, 255, 255, 255,
+	}
+
+	img, err := DecodeImage(stream, 2, 2, 8, "DeviceRGB", nil)
+	if err != nil {
+		t.Fatalf("Failed to decode DeviceRGB image: %v", err)
+	}
+
+	checkPixel(t, img, 0, 0, 255, 0, 0, 255)
+	checkPixel(t, img, 1, 0, 0, 255, 0, 255)
+	checkPixel(t, img, 0, 1, 0, 0, 255, 255)
+	checkPixel(t, img, 1, 1, 255, 255, 255, 255)
+
+	// 缩写名称与 Indexed 调色板
+	img, err = DecodeImage([]byte{1, 0}, 2, 1, 8, "/I", []byte{255, 0, 0, 0, 0, 255})
+	if err != nil {
+		t.Fatalf("Failed to decode Indexed image: %v", err)
+	}
+	checkPixel(t, img, 0, 0, 0, 0, 255, 255)
+	checkPixel(t, img, 1, 0, 255, 0, 0, 255)
+
+	if _, err := DecodeImage(stream, 0, 2, 8, "DeviceRGB", nil); err == nil {
+		t.Error("Expected error for zero width")
+	}
+}
+
+func TestDecodeImage_DeviceGraySubByte(t *testing.T) {
+	// 3x2 图像：每行从字节边界开始，行尾有填充位
+	tests := []struct {
+		bpc    int
+		stream []byte
+		gray   [2][3]uint8
+	}{
+		// 1 位：行 101|00000、011|11111（填充位为 1，不能串到下一行）
+		{1, []byte{0xA0, 0x7F}, [2][3]uint8{{255, 0, 255}, {0, 255, 255}}},
+		// 2 位：行 00 01 10|00、11 10 01|11
+		{2, []byte{0x18, 0xE7}, [2][3]uint8{{0, 85, 170}, {255, 170, 85}}},
+		// 4 位：行 0 5 | A 0、F 1 | 2 F
+		{4, []byte{0x05, 0xA0, 0xF1, 0x2F}, [2][3]uint8{{0, 85, 170}, {255, 17, 34}}},
+	}
+	for _, tt := range tests {
+		img, err := DecodeImage(tt.stream, 3, 2, tt.bpc, "DeviceGray", nil)
+		if err != nil {
+			t.Fatalf("bpc %d: failed to decode DeviceGray image: %v", tt.bpc, err)
+		}
+		for y, row := range tt.gray {
+			for x, g := range row {
+				checkPixel(t, img, x, y, g, g, g, 255)
+			}
+		}
+	}
+}
+
+// buildTestICCProfile 构造只含标签表的最小 ICC 配置文件
+func buildTestICCProfile(colorSpace string, tags map[string][]byte) []byte {
+	sigs := make([]string, 0, len(tags))
+	for sig := range tags {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+
+	data := make([]byte, 132+12*len(sigs))
+	copy(data[16:], colorSpace)
+	copy(data[20:], "XYZ ")
+	binary.BigEndian.PutUint32(data[128:], uint32(len(sigs)))
+	for i, sig := range sigs {
+		entry := 132 + 12*i
+		copy(data[entry:], sig)
+		binary.BigEndian.PutUint32(data[entry+4:], uint32(len(data)))
+		binary.BigEndian.PutUint32(data[entry+8:], uint32(len(tags[sig])))
+		data = append(data, tags[sig]...)
+	}
+	binary.BigEndian.PutUint32(data[0:], uint32(len(data)))
+	return data
+}
+
+func testICCFixed(values ...float64) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(b[4*i:], uint32(int32(math.Round(v*65536))))
+	}
+	return b
+}
+
+func testICCXYZ(x, y, z float64) []byte {
+	return append([]byte("XYZ \x00\x00\x00\x00"), testICCFixed(x, y, z)...)
+}
+
+// testSRGBProfile sRGB 原色（D50 适应）的矩阵/TRC 配置文件
+func testSRGBProfile(trc []byte) []byte {
+	return buildTestICCProfile("RGB ", map[string][]byte{
+		"rXYZ": testICCXYZ(0.4360747, 0.2225045, 0.0139322),
+		"gXYZ": testICCXYZ(0.3850649, 0.7168786, 0.0971045),
+		"bXYZ": testICCXYZ(0.1430804, 0.0606169, 0.7141733),
+		"rTRC": trc, "gTRC": trc, "bTRC": trc,
+	})
+}
+
+func TestDecodeImageXObject_ICCProfile(t *testing.T) {
+	t.Cleanup(func() { SetICCTransformEnabled(false) })
+
+	linearTRC := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x00")
+	srgbTRC := append([]byte("para\x00\x00\x00\x00\x00\x03\x00\x00"),
+		testICCFixed(2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045)...)
+
+	decode := func(profile []byte, n int, stream []byte) *image.RGBA {
+		img, err := decodeImageXObject(&XObject{
+			Subtype: "Image", Width: 1, Height: 1, BitsPerComponent: 8,
+			ColorSpace: "ICCBased", ColorComponents: n, ICCProfile: profile, Stream: stream,
+		})
+		if err != nil {
+			t.Fatalf("Failed to decode ICCBased image: %v", err)
+		}
+		return img
+	}
+	near := func(name string, img *image.RGBA, want ...uint8) {
+		t.Helper()
+		for i, w := range want {
+			if d := int(img.Pix[i]) - int(w); d < -2 || d > 2 {
+				t.Errorf("%s: Pix = %v, want about %v", name, img.Pix[:3], want)
+				return
+			}
+		}
+	}
+
+	// 默认不做 ICC 转换
+	near("disabled", decode(testSRGBProfile(linearTRC), 3, []byte{128, 128, 128}), 128, 128, 128)
+
+	// 单个图像的 ICCTransform（RenderOptions.ICC）不依赖全局开关
+	perImage, err := decodeImageXObject(&XObject{
+		Subtype: "Image", Width: 1, Height: 1, BitsPerComponent: 8, ICCTransform: true,
+		ColorSpace: "ICCBased", ColorComponents: 3, ICCProfile: testSRGBProfile(linearTRC), Stream: []byte{128, 128, 128},
+	})
+	if err != nil {
+		t.Fatalf("Failed to decode ICCBased image: %v", err)
+	}
+	near("per-image", perImage, 188, 188, 188)
+
+	SetICCTransformEnabled(true)
+	// 线性 TRC：采样值是线性光，输出为 sRGB 编码值
+	near("linear RGB", decode(testSRGBProfile(linearTRC), 3, []byte{128, 128, 128}), 188, 188, 188)
+	// sRGB 配置文件的转换结果保持不变
+	near("sRGB", decode(testSRGBProfile(srgbTRC), 3, []byte{200, 30, 90}), 200, 30, 90)
+
+	gray := buildTestICCProfile("GRAY", map[string][]byte{"kTRC": linearTRC})
+	near("linear gray", decode(gray, 1, []byte{128}), 188, 188, 188)
+
+	// 不支持的配置文件（CMYK）和分量数不符时使用快速路径
+	cmyk := buildTestICCProfile("CMYK", map[string][]byte{"A2B0": []byte("mft2")})
+	near("CMYK", decode(cmyk, 4, []byte{0, 0, 0, 0}), 255, 255, 255)
+	near("mismatch", decode(gray, 3, []byte{128, 128, 128}), 128, 128, 128)
+}
+
+func TestSetICCTransformEnabledDuringDecode(t *testing.T) {
+	// 在其它 goroutine 解码时切换全局开关，配合 -race 检查数据竞争
+	t.Cleanup(func() { SetICCTransformEnabled(false) })
+
+	linearTRC := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x00")
+	profile := testSRGBProfile(linearTRC)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				img, err := decodeImageXObject(&XObject{
+					Subtype: "Image", Width: 1, Height: 1, BitsPerComponent: 8,
+					ColorSpace: "ICCBased", ColorComponents: 3, ICCProfile: profile, Stream: []byte{128, 128, 128},
+				})
+				if err != nil {
+					t.Errorf("Failed to decode ICCBased image: %v", err)
+					return
+				}
+				// 开关状态随时可能变化，只能是未转换或已转换两种结果之一
+				if v := img.Pix[0]; v != 128 && (v < 186 || v > 190) {
+					t.Errorf("Pix[0] = %d, want 128 or about 188", v)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		SetICCTransformEnabled(i%2 == 0)
+	}
+	wg.Wait()
+}
+
+// testMQEncoder T.88 附录 E.2 的 MQ 算术编码器，用于生成 JBIG2 测试数据
+type testMQEncoder struct {
+	out []byte // out[0] 是编码开始前的占位字节
+	c   uint32
+	a   uint32
+	ct  int
+}
+
+func newTestMQEncoder() *testMQEncoder {
+	return &testMQEncoder{out: []byte{0}, a: 0x8000, ct: 12}
+}
+
+func (e *testMQEncoder) encode(contexts []byte, cx int, bit byte) {
+	index := contexts[cx] >> 1
+	mps := contexts[cx] & 1
+	state := &mqStates[index]
+	e.a -= state.qe
+	if bit == mps {
+		if e.a&0x8000 != 0 {
+			e.c += state.qe
+			return
+		}
+		if e.a < state.qe {
+			e.a = state.qe
+		} else {
+			e.c += state.qe
+		}
+		index = state.nmps
+	} else {
+		if e.a < state.qe {
+			e.c += state.qe
+		} else {
+			e.a = state.qe
+		}
+		if state.switchMPS {
+			mps ^= 1
+		}
+		index = state.nlps
+	}
+	contexts[cx] = index<<1 | mps
+	for {
+		e.a <<= 1
+		e.c <<= 1
+		e.ct--
+		if e.ct == 0 {
+			e.byteOut()
+		}
+		if e.a&0x8000 != 0 {
+			break
+		}
+	}
+}
+
+func (e *testMQEncoder) byteOut() {
+	last := &e.out[len(e.out)-1]
+	if *last != 0xFF && e.c >= 0x8000000 {
+		*last++
+		if *last == 0xFF {
+			e.c &= 0x7FFFFFF
+		}
+	}
+	if *last == 0xFF {
+		e.out = append(e.out, byte(e.c>>20))
+		e.c &= 0xFFFFF
+		e.ct = 7
+		return
+	}
+	e.out = append(e.out, byte(e.c>>19))
+	e.c &= 0x7FFFF
+	e.ct = 8
+}
+
+func (e *testMQEncoder) flush() []byte {
+	temp := e.c + e.a
+	e.c |= 0xFFFF
+	if e.c >= temp {
+		e.c -= 0x8000
+	}
+	e.c <<= uint(e.ct)
+	e.byteOut()
+	e.c <<= uint(e.ct)
+	e.byteOut()
+	out := e.out[1:]
+	if out[len(out)-1] != 0xFF {
+		out = append(out, 0xFF)
+	}
+	return append(out, 0xAC)
+}
+
+// encodeTestGenericRegion 按通用区域解码过程的逆过程对位图做算术编码
+func encodeTestGenericRegion(bm *jbig2Bitmap, template int, tpgdon bool, at [][2]int) []byte {
+	enc := newTestMQEncoder()
+	pixels := jbig2TemplatePixels(template, at)
+	contexts := make([]byte, 1<<len(pixels))
+	ltp := false
+	for y := 0; y < bm.height; y++ {
+		row := bm.pix[y*bm.width : (y+1)*bm.width]
+		if tpgdon {
+			prev := make([]byte, bm.width)
+			if y > 0 {
+				prev = bm.pix[(y-1)*bm.width : y*bm.width]
+			}
+			typical := bytes.Equal(row, prev)
+			var sltp byte
+			if typical != ltp {
+				sltp = 1
+			}
+			enc.encode(contexts, jbig2SLTPContexts[template], sltp)
+			ltp = typical
+			if typical {
+				continue
+			}
+		}
+		for x := range row {
+			cx := 0
+			for _, p := range pixels {
+				cx = cx<<1 | int(bm.at(x+p[0], y+p[1]))
+			}
+			enc.encode(contexts, cx, row[x])
+		}
+	}
+	return enc.flush()
+}
+
+// testJBIG2Segment 生成无引用段、关联到页面 1 的段
+func testJBIG2Segment(number uint32, kind int, data []byte) []byte {
+	seg := binary.BigEndian.AppendUint32(nil, number)
+	seg = append(seg, byte(kind), 0, 1)
+	seg = binary.BigEndian.AppendUint32(seg, uint32(len(data)))
+	return append(seg, data...)
+}
+
+func testJBIG2PageInfo(width, height uint32, flags byte) []byte {
+	data := binary.BigEndian.AppendUint32(nil, width)
+	data = binary.BigEndian.AppendUint32(data, height)
+	data = append(data, make([]byte, 8)...)
+	return append(data, flags, 0, 0)
+}
+
+// testJBIG2GenericRegion 生成通用区域段数据：区域信息、标志、自适应像素和编码数据
+func testJBIG2GenericRegion(width, height, x, y int, op, flags byte, at [][2]int, coded []byte) []byte {
+	data := binary.BigEndian.AppendUint32(nil, uint32(width))
+	data = binary.BigEndian.AppendUint32(data, uint32(height))
+	data = binary.BigEndian.AppendUint32(data, uint32(x))
+	data = binary.BigEndian.AppendUint32(data, uint32(y))
+	data = append(data, op, flags)
+	for _, p := range at {
+		data = append(data, byte(int8(p[0])), byte(int8(p[1])))
+	}
+	return append(data, coded...)
+}
+
+// testJBIG2Pattern 生成带有空白行、重复行、斜线和实心块的测试位图
+func testJBIG2Pattern(width, height int) *jbig2Bitmap {
+	bm, _ := newJBIG2Bitmap(width, height, 0)
+	for y := 4; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var v byte
+			switch {
+			case y >= 10 && y < 14:
+				v = byte(x/3) & 1 // 连续的重复行
+			case (x+y)%7 == 0 || (x*3+y)%11 == 0:
+				v = 1
+			case x >= 20 && x < 30 && y >= 15:
+				v = 1
+			}
+			bm.pix[y*width+x] = v
+		}
+	}
+	return bm
+}
+
+func TestDecodeJBIG2_GenericRegion(t *testing.T) {
+	nominal := [4][][2]int{
+		{{3, -1}, {-3, -1}, {2, -2}, {-2, -2}},
+		{{3, -1}},
+		{{2, -1}},
+		{{2, -1}},
+	}
+	tests := []struct {
+		name     string
+		template int
+		tpgdon   bool
+		at       [][2]int
+	}{
+		{"template 0", 0, false, nominal[0]},
+		{"template 0 TPGDON", 0, true, nominal[0]},
+		{"template 0 moved AT", 0, true, [][2]int{{-1, -3}, {4, -1}, {-5, -2}, {0, -4}}},
+		{"template 1", 1, false, nominal[1]},
+		{"template 1 TPGDON", 1, true, nominal[1]},
+		{"template 2", 2, false, nominal[2]},
+		{"template 2 TPGDON", 2, true, nominal[2]},
+		{"template 3", 3, false, nominal[3]},
+		{"template 3 TPGDON", 3, true, nominal[3]},
+	}
+
+	bm := testJBIG2Pattern(37, 23)
+	want := bm.pack()
+	for _, tt := range tests {
+		flags := byte(tt.template) << 1
+		if tt.tpgdon {
+			flags |= 8
+		}
+		coded := encodeTestGenericRegion(bm, tt.template, tt.tpgdon, tt.at)
+		var data []byte
+		data = append(data, testJBIG2Segment(0, jbig2SegPageInformation, testJBIG2PageInfo(37, 23, 0))...)
+		data = append(data, testJBIG2Segment(1, jbig2SegImmediateLosslessGeneric,
+			testJBIG2GenericRegion(37, 23, 0, 0, 0, flags, tt.at, coded))...)
+		data = append(data, testJBIG2Segment(2, jbig2SegEndOfPage, nil)...)
+
+		w, h, got, err := decodeJBIG2(data, nil)
+		if err != nil {
+			t.Errorf("%s: decodeJBIG2 failed: %v", tt.name, err)
+			continue
+		}
+		if w != 37 || h != 23 {
+			t.Errorf("%s: size = %dx%d, want 37x23", tt.name, w, h)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: decoded bitmap differs from the encoded one", tt.name)
+		}
+	}
+}
+
+func TestDecodeJBIG2_PageComposition(t *testing.T) {
+	// 4x2 区域：上行全黑，下行左半黑
+	region, _ := newJBIG2Bitmap(4, 2, 0)
+	copy(region.pix, []byte{1, 1, 1, 1, 1, 1, 0, 0})
+	coded := encodeTestGenericRegion(region, 0, false, [][2]int{{3, -1}, {-3, -1}, {2, -2}, {-2, -2}})
+	at := [][2]int{{3, -1}, {-3, -1}, {2, -2}, {-2, -2}}
+
+	// 默认像素为黑（标志位 2）的 8x3 页面，区域以 XOR 合成到 (2, 1)，超出页面的部分被裁掉
+	var data []byte
+	data = append(data, testJBIG2Segment(0, jbig2SegPageInformation, testJBIG2PageInfo(8, 3, 1<<2))...)
+	data = append(data, testJBIG2Segment(1, jbig2SegImmediateGenericRegion,
+		testJBIG2GenericRegion(4, 2, 2, 1, 2, 0, at, coded))...)
+	data = append(data, testJBIG2Segment(2, jbig2SegEndOfPage, nil)...)
+
+	_, _, got, err := decodeJBIG2(data, nil)
+	if err != nil {
+		t.Fatalf("decodeJBIG2 failed: %v", err)
+	}
+	// 输出 0 为黑：第 0 行全黑；第 1 行 x=2..5 被 XOR 为白；第 2 行 x=2,3 为白
+	want := []byte{0x00, 0x3C, 0x30}
+	if !bytes.Equal(got, want) {
+		t.Errorf("composed page = % X, want % X", got, want)
+	}
+
+	// 高度未知的条带页面：高度由区域和条带结束段决定
+	data = data[:0]
+	data = append(data, testJBIG2Segment(0, jbig2SegPageInformation, testJBIG2PageInfo(8, 0xFFFFFFFF, 0))...)
+	data = append(data, testJBIG2Segment(1, jbig2SegImmediateGenericRegion,
+		testJBIG2GenericRegion(4, 2, 0, 1, 4, 0, at, coded))...)
+	data = append(data, testJBIG2Segment(2, jbig2SegEndOfStripe, []byte{0, 0, 0, 4})...)
+	data = append(data, testJBIG2Segment(3, jbig2SegEndOfPage, nil)...)
+	w, h, got, err := decodeJBIG2(data, nil)
+	if err != nil {
+		t.Fatalf("decodeJBIG2 (striped) failed: %v", err)
+	}
+	if w != 8 || h != 5 {
+		t.Fatalf("striped page size = %dx%d, want 8x5", w, h)
+	}
+	if want := []byte{0xFF, 0x0F, 0x3F, 0xFF, 0xFF}; !bytes.Equal(got, want) {
+		t.Errorf("striped page = % X, want % X", got, want)
+	}
+}
+
+func TestDecodeJBIG2_MMR(t *testing.T) {
+	// T.6 编码的 8x2 区域，两行都是 x=2..5 为黑：
+	// 第 1 行为水平模式（白 2、黑 4）加 V0，第 2 行为三个 V0，之后是 EOFB
+	mmr := []byte{0x2E, 0xFC, 0x00, 0x40, 0x04}
+	var data []byte
+	data = append(data, testJBIG2Segment(0, jbig2SegPageInformation, testJBIG2PageInfo(8, 2, 0))...)
+	data = append(data, testJBIG2Segment(1, jbig2SegImmediateLosslessGeneric,
+		testJBIG2GenericRegion(8, 2, 0, 0, 0, 1, nil, mmr))...)
+
+	_, _, got, err := decodeJBIG2(data, nil)
+	if err != nil {
+		t.Fatalf("decodeJBIG2 failed: %v", err)
+	}
+	if want := []byte{0xC3, 0xC3}; !bytes.Equal(got, want) {
+		t.Errorf("MMR page = % X, want % X", got, want)
+	}
+}
+
+func TestDecodeJBIG2_Unsupported(t *testing.T) {
+	var data []byte
+	data = append(data, testJBIG2Segment(0, jbig2SegPageInformation, testJBIG2PageInfo(8, 2, 0))...)
+	data = append(data, testJBIG2Segment(1, jbig2SegImmediateTextRegion, make([]byte, 20))...)
+	if _, _, _, err := decodeJBIG2(data, nil); err == nil {
+		t.Error("expected an error for a text region segment")
+	}
+
+	// 没有页面信息段
+	if _, _, _, err := decodeJBIG2(testJBIG2Segment(0, jbig2SegEndOfPage, nil), nil); err == nil {
+		t.Error("expected an error for data without a page information segment")
+	}
+
+	// 截断的段数据
+	truncated := testJBIG2Segment(0, jbig2SegPageInformation, testJBIG2PageInfo(8, 2, 0))
+	if _, _, _, err := decodeJBIG2(truncated[:len(truncated)-3], nil); err == nil {
+		t.Error("expected an error for a truncated segment")
+	}
+}
@@ -0,0 +1,127 @@
+package gopdf
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// imagePageOps 单图像页面中允许出现的非绘制操作符
+// 只包含图形状态保存/恢复、变换和标记内容，出现其他任何操作符时走完整渲染流程
+var imagePageOps = map[string]bool{
+	"q": true, "Q": true, "cm": true,
+	"BMC": true, "BDC": true, "EMC": true,
+	"IGNORE": true,
+}
+
+// singleImagePageXObject 判断页面内容是否只绘制一个图像 XObject（如扫描页面）
+// 是则返回该图像，否则返回 nil
+func singleImagePageXObject(operators []PDFOperator, resources *Resources) *XObject {
+	var pageImage *XObject
+	for _, op := range operators {
+		if imagePageOps[op.Name()] {
+			continue
+		}
+		do, ok := op.(*OpDoXObject)
+		if !ok || pageImage != nil {
+			return nil
+		}
+		xobj := resources.GetXObject(do.XObjectName)
+		if xobj == nil || (xobj.Subtype != "Image" && xobj.Subtype != "/Image") {
+			return nil
+		}
+		pageImage = xobj
+	}
+	return pageImage
+}
+
+// blitPageImage 把单图像页面的图像直接写入目标表面，不经过光栅化器的路径填充
+// 图像单位正方形按当前 CTM 映射到设备空间，逐个设备像素反算图像采样位置；
+// 目标不是图像表面，或 CTM 的 Y 方向与 renderImageXObject 的翻转判断不一致时，
+// 回退到 renderImageXObject 以保持两条路径的输出相同
+func blitPageImage(ctx *RenderContext, xobj *XObject) error {
+	if !ctx.shouldRender(ContentImages) {
+		return nil
+	}
+
+	target := contextImage(ctx.GopdfCtx)
+	state := ctx.GetCurrentState()
+	if target == nil || state == nil || state.CTM == nil || state.CTM.YY <= 0 {
+		return renderImageXObject(ctx, xobj)
+	}
+
+	if xobj.ImageData == nil {
+		xobj.ICCTransform = xobj.ICCTransform || ctx.ICCTransform
+		if xobj.CMYKConversion == CMYKDefault {
+			xobj.CMYKConversion = ctx.CMYKConversion
+		}
+		imgData, err := decodeImageXObject(xobj)
+		if err != nil {
+			return fmt.Errorf("failed to decode image: %w", err)
+		}
+		xobj.ImageData = imgData
+	}
+	img := xobj.ImageData
+	src := img.Bounds()
+	if src.Empty() {
+		return fmt.Errorf("invalid image dimensions: %dx%d", src.Dx(), src.Dy())
+	}
+
+	// 图像空间 (0,0)-(1,1) 到设备空间的变换；不可逆时图像退化为零面积，不绘制
+	m := ctx.GopdfCtx.GetMatrix()
+	inv, ok := m.Invert()
+	if !ok {
+		return nil
+	}
+
+	// 单位正方形在设备空间中的包围盒
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [4][2]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		x, y := m.Transform(corner[0], corner[1])
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	dst := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).
+		Intersect(target.Bounds())
+
+	debugPrintf("[blitPageImage] Blitting %dx%d image into %v (Interpolate=%v)\n",
+		src.Dx(), src.Dy(), dst, xobj.Interpolate)
+
+	w, h := float64(src.Dx()), float64(src.Dy())
+	for y := dst.Min.Y; y < dst.Max.Y; y++ {
+		for x := dst.Min.X; x < dst.Max.X; x++ {
+			// 设备像素中心在图像单位正方形中的位置；图像第 0 行位于 v=1
+			u, v := inv.Transform(float64(x)+0.5, float64(y)+0.5)
+			if u < 0 || u >= 1 || v <= 0 || v > 1 {
+				continue
+			}
+			px, py := float64(src.Min.X)+u*w, float64(src.Min.Y)+(1-v)*h
+
+			var r, g, b, a uint32
+			if xobj.Interpolate {
+				r, g, b, a = sampleBilinear(img, px, py, ExtendPad).RGBA()
+			} else {
+				ix, iy := clampTexel(src, int(px), int(py))
+				r, g, b, a = img.At(ix, iy).RGBA()
+			}
+			if a == 0 {
+				continue
+			}
+			// 解码后的图像（含 SMask 得到的 alpha）按非预乘分量存放，与 imageXObjectSurface 一致，合成前先预乘
+			if a < 0xffff {
+				r, g, b = r*a/0xffff, g*a/0xffff, b*a/0xffff
+			}
+
+			// 预乘分量的 source-over 合成
+			i := target.PixOffset(x, y)
+			pix := target.Pix[i : i+4 : i+4]
+			rest := 0xffff - a
+			pix[0] = uint8((r + uint32(pix[0])*0x101*rest/0xffff) >> 8)
+			pix[1] = uint8((g + uint32(pix[1])*0x101*rest/0xffff) >> 8)
+			pix[2] = uint8((b + uint32(pix[2])*0x101*rest/0xffff) >> 8)
+			pix[3] = uint8((a + uint32(pix[3])*0x101*rest/0xffff) >> 8)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package gopdf
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image"
+	"strings"
+)
+
+// ===== 内联图像（BI ... ID ... EI）=====
+
+// inlineImageKeyAbbreviations 内联图像字典键缩写（PDF 32000-1 表 93）
+var inlineImageKeyAbbreviations = map[string]string{
+	"BPC": "BitsPerComponent",
+	"CS":  "ColorSpace",
+	"D":   "Decode",
+	"DP":  "DecodeParms",
+	"F":   "Filter",
+	"H":   "Height",
+	"IM":  "ImageMask",
+	"I":   "Interpolate",
+	"L":   "Length",
+	"W":   "Width",
+}
+
+// inlineImageColorSpaceAbbreviations 内联图像颜色空间缩写（PDF 32000-1 表 94）
+var inlineImageColorSpaceAbbreviations = map[string]string{
+	"G":    "DeviceGray",
+	"RGB":  "DeviceRGB",
+	"CMYK": "DeviceCMYK",
+	"I":    "Indexed",
+}
+
+// inlineImageFilterAbbreviations 内联图像滤镜缩写（PDF 32000-1 表 94）
+var inlineImageFilterAbbreviations = map[string]string{
+	"AHx": "ASCIIHexDecode",
+	"A85": "ASCII85Decode",
+	"LZW": "LZWDecode",
+	"Fl":  "FlateDecode",
+	"RL":  "RunLengthDecode",
+	"CCF": "CCITTFaxDecode",
+	"DCT": "DCTDecode",
+}
+
+// NewInlineImageXObject 根据内联图像字典和 ID 之后的图像数据构造等价的图像 XObject
+// 字典键、颜色空间和滤镜名称可以使用缩写形式；颜色空间也可以是 resources 中的命名颜色空间
+func NewInlineImageXObject(dict map[string]interface{}, data []byte, resources *Resources) (*XObject, error) {
+	params := normalizeInlineImageDict(dict)
+
+	xobj := &XObject{
+		Subtype:          "Image",
+		Width:            int(toFloat(params["Width"])),
+		Height:           int(toFloat(params["Height"])),
+		BitsPerComponent: 8,
+		ColorSpace:       "DeviceGray",
+	}
+
+	if xobj.Width <= 0 || xobj.Height <= 0 {
+		return nil, fmt.Errorf("invalid inline image size: %dx%d", xobj.Width, xobj.Height)
+	}
+
+	if bpc, ok := params["BitsPerComponent"]; ok {
+		xobj.BitsPerComponent = int(toFloat(bpc))
+	}
+	if interp, ok := params["Interpolate"].(bool); ok {
+		xobj.Interpolate = interp
+	}
+
+	// 解码滤镜
+	filters := inlineImageFilters(params["Filter"])
+	stream := data
+	if len(filters) > 0 {
+		decoded, err := DecodeImageWithFilters(data, filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode inline image data: %w", err)
+		}
+		stream = decoded
+	}
+	xobj.Stream = stream
+
+	// 模板掩码：1 位、单分量，以当前填充颜色绘制；/D [1 0] 反转样本含义
+	if isMask, ok := params["ImageMask"].(bool); ok && isMask {
+		xobj.ImageMask = true
+		xobj.BitsPerComponent = 1
+		xobj.ColorSpace = "DeviceGray"
+		if decode, ok := params["Decode"].([]interface{}); ok && len(decode) >= 2 {
+			xobj.DecodeInverted = toFloat(decode[0]) == 1 && toFloat(decode[1]) == 0
+		}
+		return xobj, nil
+	}
+
+	if csValue, ok := params["ColorSpace"]; ok {
+		cs, err := resolveInlineColorSpace(csValue, resources, 0)
+		if err != nil {
+			return nil, err
+		}
+		applyColorSpaceToXObject(xobj, cs)
+	}
+
+	// DCTDecode 滤镜输出的是 8 位 RGB 数据
+	for _, f := range filters {
+		if f == "DCTDecode" {
+			xobj.ColorSpace = "DeviceRGB"
+			xobj.BitsPerComponent = 8
+			xobj.Palette = nil
+		}
+	}
+
+	return xobj, nil
+}
+
+// DecodeInlineImage 解码内联图像为 RGBA 图像
+func DecodeInlineImage(dict map[string]interface{}, data []byte, resources *Resources) (*image.RGBA, error) {
+	xobj, err := NewInlineImageXObject(dict, data, resources)
+	if err != nil {
+		return nil, err
+	}
+	return decodeImageXObject(xobj)
+}
+
+// normalizeInlineImageDict 去掉键名前缀 "/" 并展开缩写键
+func normalizeInlineImageDict(dict map[string]interface{}) map[string]interface{} {
+	params := make(map[string]interface{}, len(dict))
+	for key, value := range dict {
+		key = strings.TrimPrefix(key, "/")
+		if full, ok := inlineImageKeyAbbreviations[key]; ok {
+			key = full
+		}
+		params[key] = value
+	}
+	return params
+}
+
+// inlineImageFilters 展开滤镜名称（单个名称或名称数组）
+func inlineImageFilters(value interface{}) []string {
+	var names []interface{}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		names = v
+	default:
+		names = []interface{}{v}
+	}
+
+	filters := make([]string, 0, len(names))
+	for _, n := range names {
+		name, ok := inlineName(n)
+		if !ok {
+			continue
+		}
+		if full, ok := inlineImageFilterAbbreviations[name]; ok {
+			name = full
+		}
+		filters = append(filters, name)
+	}
+	return filters
+}
+
+// resolveInlineColorSpace 解析内联图像的颜色空间
+// 支持缩写名称（/G /RGB /CMYK）、资源中的命名颜色空间以及 [/I base hival lookup] 数组
+func resolveInlineColorSpace(value interface{}, resources *Resources, depth int) (ColorSpace, error) {
+	if depth > 10 {
+		return nil, fmt.Errorf("inline image color space nesting too deep")
+	}
+
+	switch v := value.(type) {
+	case string:
+		name, _ := inlineName(v)
+		if full, ok := inlineImageColorSpaceAbbreviations[name]; ok {
+			name = full
+		}
+		if cs, err := deviceColorSpaceByName(name); err == nil {
+			return cs, nil
+		}
+		// 资源字典中的命名颜色空间
+		if resources != nil {
+			if cs, ok := resources.GetColorSpace(name).(ColorSpace); ok && cs != nil {
+				return cs, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown inline image color space: %s", name)
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("empty inline image color space array")
+		}
+		family, _ := inlineName(v[0])
+		if full, ok := inlineImageColorSpaceAbbreviations[family]; ok {
+			family = full
+		}
+		if family != "Indexed" {
+			if len(v) == 1 {
+				return resolveInlineColorSpace(v[0], resources, depth+1)
+			}
+			return nil, fmt.Errorf("unsupported inline image color space family: %s", family)
+		}
+		if len(v) < 4 {
+			return nil, fmt.Errorf("indexed color space requires 4 elements, got %d", len(v))
+		}
+
+		base, err := resolveInlineColorSpace(v[1], resources, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("invalid indexed base color space: %w", err)
+		}
+
+		return &IndexedColorSpace{
+			Base:   base,
+			HiVal:  int(toFloat(v[2])),
+			Lookup: inlineStringBytes(v[3]),
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid inline image color space: %T", value)
+	}
+}
+
+// applyColorSpaceToXObject 根据解析出的颜色空间填充 XObject 的颜色字段
+func applyColorSpaceToXObject(xobj *XObject, cs ColorSpace) {
+	switch c := cs.(type) {
+	case *IndexedColorSpace:
+		xobj.ColorSpace = "Indexed"
+		xobj.Palette = paletteToRGB(c)
+	case *ICCBasedColorSpace:
+		xobj.ColorSpace = "ICCBased"
+		xobj.ColorComponents = c.NumComponents
+		xobj.ICCProfile = c.Metadata
+	case *DeviceNColorSpace:
+		xobj.ColorSpace = c.GetName()
+		xobj.DeviceN = c
+	case *CalRGBColorSpace, *CalGrayColorSpace:
+		xobj.ColorSpace = cs.GetName()
+		xobj.ColorComponents = cs.GetNumComponents()
+		xobj.Calibrated = cs
+	case *DeviceRGBColorSpace, *DeviceGrayColorSpace, *DeviceCMYKColorSpace:
+		xobj.ColorSpace = cs.GetName()
+	default:
+		// 其他颜色空间按分量数近似为设备颜色空间
+		switch cs.GetNumComponents() {
+		case 1:
+			xobj.ColorSpace = "DeviceGray"
+		case 4:
+			xobj.ColorSpace = "DeviceCMYK"
+		default:
+			xobj.ColorSpace = "DeviceRGB"
+		}
+	}
+}
+
+// inlineName 获取名称值（去掉前缀 "/"）
+func inlineName(v interface{}) (string, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return "", false
+	}
+	return strings.TrimPrefix(s, "/"), true
+}
+
+// inlineStringBytes 将内容流中的字符串值（十六进制 <...> 或字面量）转换为字节
+func inlineStringBytes(v interface{}) []byte {
+	switch s := v.(type) {
+	case HexString:
+		hexStr := hexStringDigits(string(s))
+		// 奇数个十六进制数字时末尾补 0
+		if len(hexStr)%2 == 1 {
+			hexStr += "0"
+		}
+		b, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return nil
+		}
+		return b
+	case string:
+		// 字面量字符串已由 parseValue 去掉括号并处理转义，直接使用其字节
+		return []byte(s)
+	}
+	return nil
+}
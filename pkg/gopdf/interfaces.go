@@ -111,6 +111,12 @@ type Context interface {
 	SetAntialias(antialias Antialias)
 	GetAntialias() Antialias
 
+	// Gamma-correct blending composites in linear light instead of sRGB.
+	// It is off by default: sRGB blending is faster but darkens
+	// anti-aliased edges between saturated colours.
+	SetGammaCorrectBlending(enabled bool)
+	GetGammaCorrectBlending() bool
+
 	// Fill properties
 	SetFillRule(fillRule FillRule)
 	GetFillRule() FillRule
@@ -213,6 +219,7 @@ type Context interface {
 	PangoPdfCreateLayout() interface{}
 	PangoPdfUpdateLayout(layout interface{})
 	PangoPdfShowText(layout interface{})
+	PangoPdfLayoutPath(layout interface{})
 }
 
 // Pattern represents gopdf_pattern_t - paint source interface
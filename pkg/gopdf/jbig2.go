@@ -0,0 +1,636 @@
+package gopdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/image/ccitt"
+)
+
+// JBIG2Decode 滤镜（ITU-T T.88）的解码，pdfcpu 不支持该滤镜
+//
+// 支持的子集是扫描页面常用的通用区域编码：
+//   - 立即通用区域段和无损立即通用区域段，MMR 编码（T.6）或算术编码
+//     （模板 0-3、自适应模板像素、TPGDON 典型预测）
+//   - 页面信息段（包括高度未知的条带页面）、条带结束、页面结束、文件结束段
+//   - 符号字典、图案字典、中间区域、码表、配置文件和扩展段会被跳过
+//
+// 文本区域、半色调区域和立即细化区域依赖未实现的解码过程，遇到时返回错误；
+// 长度未知（0xFFFFFFFF）的段和扩展模板（EXTTEMPLATE）同样不支持
+
+// JBIG2 段类型（T.88 7.3）
+const (
+	jbig2SegTextRegion                = 4
+	jbig2SegImmediateTextRegion       = 6
+	jbig2SegImmediateLosslessText     = 7
+	jbig2SegHalftoneRegion            = 20
+	jbig2SegImmediateHalftone         = 22
+	jbig2SegImmediateLosslessHalftone = 23
+	jbig2SegImmediateGenericRegion    = 38
+	jbig2SegImmediateLosslessGeneric  = 39
+	jbig2SegImmediateRefinement       = 42
+	jbig2SegImmediateLosslessRefine   = 43
+	jbig2SegPageInformation           = 48
+	jbig2SegEndOfPage                 = 49
+	jbig2SegEndOfStripe               = 50
+	jbig2SegEndOfFile                 = 51
+)
+
+// jbig2MaxPixels 页面和区域位图的像素数上限（每像素一个字节），防止损坏的尺寸耗尽内存
+const jbig2MaxPixels = 1 << 28
+
+// jbig2Segment 一个已拆分的段
+type jbig2Segment struct {
+	number uint32
+	kind   int
+	data   []byte
+}
+
+// jbig2Bitmap 解码中的二值位图，每像素一个字节，1 为黑、0 为白
+type jbig2Bitmap struct {
+	width, height int
+	pix           []byte
+}
+
+// newJBIG2Bitmap 创建填充为 fill 的位图
+func newJBIG2Bitmap(width, height int, fill byte) (*jbig2Bitmap, error) {
+	if width < 0 || height < 0 || (width > 0 && height > jbig2MaxPixels/width) {
+		return nil, fmt.Errorf("invalid JBIG2 bitmap size %dx%d", width, height)
+	}
+	bm := &jbig2Bitmap{width: width, height: height, pix: make([]byte, width*height)}
+	if fill != 0 {
+		for i := range bm.pix {
+			bm.pix[i] = fill
+		}
+	}
+	return bm, nil
+}
+
+// at 返回 (x, y) 处的像素，位图之外视为 0
+func (bm *jbig2Bitmap) at(x, y int) byte {
+	if x < 0 || y < 0 || x >= bm.width || y >= bm.height {
+		return 0
+	}
+	return bm.pix[y*bm.width+x]
+}
+
+// growTo 把高度未知的页面扩展到 height 行，新行填充 fill
+func (bm *jbig2Bitmap) growTo(height int, fill byte) error {
+	if height <= bm.height {
+		return nil
+	}
+	if bm.width > 0 && height > jbig2MaxPixels/bm.width {
+		return fmt.Errorf("invalid JBIG2 page height %d", height)
+	}
+	start := len(bm.pix)
+	bm.pix = append(bm.pix, make([]byte, (height-bm.height)*bm.width)...)
+	if fill != 0 {
+		for i := start; i < len(bm.pix); i++ {
+			bm.pix[i] = fill
+		}
+	}
+	bm.height = height
+	return nil
+}
+
+// compose 以组合操作符 op（0 OR、1 AND、2 XOR、3 XNOR、4 REPLACE）把区域位图合成到 (x, y)
+func (bm *jbig2Bitmap) compose(region *jbig2Bitmap, x, y int, op byte) {
+	for sy := 0; sy < region.height; sy++ {
+		dy := y + sy
+		if dy < 0 || dy >= bm.height {
+			continue
+		}
+		for sx := 0; sx < region.width; sx++ {
+			dx := x + sx
+			if dx < 0 || dx >= bm.width {
+				continue
+			}
+			s := region.pix[sy*region.width+sx]
+			d := &bm.pix[dy*bm.width+dx]
+			switch op {
+			case 0:
+				*d |= s
+			case 1:
+				*d &= s
+			case 2:
+				*d ^= s
+			case 3:
+				*d = 1 ^ (*d ^ s)
+			default:
+				*d = s
+			}
+		}
+	}
+}
+
+// pack 把位图按行打包为 1 位数据（每行按字节对齐、高位在前）
+// JBIG2 中 1 为黑，PDF 规定 JBIG2Decode 的输出以 0 表示黑，因此打包时取反
+func (bm *jbig2Bitmap) pack() []byte {
+	stride := (bm.width + 7) / 8
+	out := make([]byte, stride*bm.height)
+	for y := 0; y < bm.height; y++ {
+		row := out[y*stride : (y+1)*stride]
+		for i := range row {
+			row[i] = 0xFF
+		}
+		for x, v := range bm.pix[y*bm.width : (y+1)*bm.width] {
+			if v != 0 {
+				row[x>>3] &^= 0x80 >> uint(x&7)
+			}
+		}
+	}
+	return out
+}
+
+// readJBIG2Segments 按顺序组织（PDF 嵌入格式，无文件头）拆分段头和段数据（T.88 7.2）
+func readJBIG2Segments(data []byte) ([]jbig2Segment, error) {
+	var segments []jbig2Segment
+	pos := 0
+	need := func(n int) error {
+		if pos+n > len(data) {
+			return fmt.Errorf("truncated JBIG2 segment header at offset %d", pos)
+		}
+		return nil
+	}
+
+	for pos < len(data) {
+		if err := need(6); err != nil {
+			return nil, err
+		}
+		seg := jbig2Segment{number: binary.BigEndian.Uint32(data[pos:])}
+		flags := data[pos+4]
+		seg.kind = int(flags & 0x3F)
+		pos += 5
+
+		// 引用段的数目和保留标志
+		count := int(data[pos] >> 5)
+		switch {
+		case count <= 4:
+			pos++
+		case count == 7:
+			if err := need(4); err != nil {
+				return nil, err
+			}
+			count = int(binary.BigEndian.Uint32(data[pos:]) & 0x1FFFFFFF)
+			pos += 4 + (count+8)/8
+		default:
+			return nil, fmt.Errorf("invalid JBIG2 referred-to segment count in segment %d", seg.number)
+		}
+
+		// 引用段的段号宽度取决于本段的段号
+		refSize := 1
+		if seg.number > 65536 {
+			refSize = 4
+		} else if seg.number > 256 {
+			refSize = 2
+		}
+		pos += count * refSize
+
+		// 页面关联字段
+		if flags&0x40 != 0 {
+			pos += 4
+		} else {
+			pos++
+		}
+
+		if err := need(4); err != nil {
+			return nil, err
+		}
+		length := binary.BigEndian.Uint32(data[pos:])
+		pos += 4
+		if length == 0xFFFFFFFF {
+			return nil, fmt.Errorf("JBIG2 segment %d has unknown data length (not supported)", seg.number)
+		}
+		if uint64(pos)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("truncated JBIG2 segment %d: need %d bytes, have %d", seg.number, length, len(data)-pos)
+		}
+		seg.data = data[pos : pos+int(length)]
+		pos += int(length)
+
+		segments = append(segments, seg)
+		if seg.kind == jbig2SegEndOfFile {
+			break
+		}
+	}
+	return segments, nil
+}
+
+// decodeJBIG2 解码 PDF 中嵌入的 JBIG2 数据，globals 是 JBIG2Globals 流的内容（可为 nil）
+// 返回页面尺寸和按行打包的 1 位数据，0 为黑、1 为白，可直接作为 DeviceGray 的 1 位采样
+func decodeJBIG2(data, globals []byte) (width, height int, packed []byte, err error) {
+	globalSegments, err := readJBIG2Segments(globals)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("JBIG2 globals: %w", err)
+	}
+	pageSegments, err := readJBIG2Segments(data)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	var page *jbig2Bitmap
+	var defaultPixel byte
+	unknownHeight := false
+
+segments:
+	for _, seg := range append(globalSegments, pageSegments...) {
+		switch seg.kind {
+		case jbig2SegPageInformation:
+			if len(seg.data) < 19 {
+				return 0, 0, nil, fmt.Errorf("truncated JBIG2 page information segment")
+			}
+			w := int(binary.BigEndian.Uint32(seg.data[0:]))
+			h := binary.BigEndian.Uint32(seg.data[4:])
+			defaultPixel = (seg.data[16] >> 2) & 1
+			unknownHeight = h == 0xFFFFFFFF
+			if unknownHeight {
+				h = 0
+			}
+			if page, err = newJBIG2Bitmap(w, int(h), defaultPixel); err != nil {
+				return 0, 0, nil, err
+			}
+
+		case jbig2SegImmediateGenericRegion, jbig2SegImmediateLosslessGeneric:
+			if page == nil {
+				return 0, 0, nil, fmt.Errorf("JBIG2 region segment %d before page information", seg.number)
+			}
+			region, x, y, op, err := decodeJBIG2GenericRegion(seg.data)
+			if err != nil {
+				return 0, 0, nil, fmt.Errorf("JBIG2 generic region segment %d: %w", seg.number, err)
+			}
+			if unknownHeight {
+				if err := page.growTo(y+region.height, defaultPixel); err != nil {
+					return 0, 0, nil, err
+				}
+			}
+			page.compose(region, x, y, op)
+
+		case jbig2SegEndOfStripe:
+			if page != nil && unknownHeight && len(seg.data) >= 4 {
+				if err := page.growTo(int(binary.BigEndian.Uint32(seg.data))+1, defaultPixel); err != nil {
+					return 0, 0, nil, err
+				}
+			}
+
+		case jbig2SegEndOfPage, jbig2SegEndOfFile:
+			break segments
+
+		case jbig2SegTextRegion, jbig2SegImmediateTextRegion, jbig2SegImmediateLosslessText,
+			jbig2SegHalftoneRegion, jbig2SegImmediateHalftone, jbig2SegImmediateLosslessHalftone,
+			jbig2SegImmediateRefinement, jbig2SegImmediateLosslessRefine:
+			return 0, 0, nil, fmt.Errorf("unsupported JBIG2 segment type %d (segment %d)", seg.kind, seg.number)
+
+		default:
+			// 符号字典、图案字典、中间区域等只被不支持的区域引用；码表、配置文件和扩展段不影响页面
+			debugPrintf("[JBIG2] Skipping segment %d of type %d\n", seg.number, seg.kind)
+		}
+	}
+
+	if page == nil {
+		return 0, 0, nil, fmt.Errorf("JBIG2 data has no page information segment")
+	}
+	return page.width, page.height, page.pack(), nil
+}
+
+// decodeJBIG2GenericRegion 解码通用区域段（T.88 7.4.6），返回区域位图、位置和外部组合操作符
+func decodeJBIG2GenericRegion(data []byte) (*jbig2Bitmap, int, int, byte, error) {
+	// 区域段信息字段（17 字节）和通用区域段标志
+	if len(data) < 18 {
+		return nil, 0, 0, 0, fmt.Errorf("truncated region segment")
+	}
+	w := int(binary.BigEndian.Uint32(data[0:]))
+	h := int(binary.BigEndian.Uint32(data[4:]))
+	x := int(int32(binary.BigEndian.Uint32(data[8:])))
+	y := int(int32(binary.BigEndian.Uint32(data[12:])))
+	op := data[16] & 7
+	flags := data[17]
+	if flags&0x10 != 0 {
+		return nil, 0, 0, 0, fmt.Errorf("extended generic templates are not supported")
+	}
+	mmr := flags&1 != 0
+	template := int(flags>>1) & 3
+	tpgdon := flags&8 != 0
+	data = data[18:]
+
+	if mmr {
+		region, err := decodeJBIG2MMR(data, w, h)
+		return region, x, y, op, err
+	}
+
+	// 自适应模板像素：模板 0 有 A1-A4，其余模板只有 A1
+	atCount := 1
+	if template == 0 {
+		atCount = 4
+	}
+	if len(data) < atCount*2 {
+		return nil, 0, 0, 0, fmt.Errorf("truncated adaptive template pixels")
+	}
+	at := make([][2]int, atCount)
+	for i := range at {
+		at[i] = [2]int{int(int8(data[2*i])), int(int8(data[2*i+1]))}
+	}
+
+	region, err := decodeJBIG2Generic(newMQDecoder(data[atCount*2:]), w, h, template, tpgdon, at)
+	return region, x, y, op, err
+}
+
+// decodeJBIG2MMR 解码 MMR（T.6 二维编码）的通用区域；JBIG2 与 T.6 一样以 1 表示黑
+func decodeJBIG2MMR(data []byte, width, height int) (*jbig2Bitmap, error) {
+	region, err := newJBIG2Bitmap(width, height, 0)
+	if err != nil {
+		return nil, err
+	}
+	stride := (width + 7) / 8
+	packed := make([]byte, stride*height)
+	r := ccitt.NewReader(bytes.NewReader(data), ccitt.MSB, ccitt.Group4, width, height, &ccitt.Options{Invert: true})
+	if _, err := io.ReadFull(r, packed); err != nil {
+		return nil, fmt.Errorf("failed to decode MMR data: %w", err)
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			region.pix[y*width+x] = (packed[y*stride+x>>3] >> (7 - uint(x&7))) & 1
+		}
+	}
+	return region, nil
+}
+
+// jbig2TemplatePixel 通用区域模板中的参考像素（相对当前像素）；at > 0 表示自适应像素 A<at>，坐标取自段数据
+type jbig2TemplatePixel struct {
+	x, y, at int
+}
+
+// jbig2GenericTemplates 各模板的参考像素，按上下文从高位到低位排列
+// （按行、再按列排序，自适应像素位于其默认位置对应的槽位，与 TPGDON 的固定上下文一致）
+var jbig2GenericTemplates = [4][]jbig2TemplatePixel{
+	{
+		{-2, -2, 4}, {-1, -2, 0}, {0, -2, 0}, {1, -2, 0}, {2, -2, 3},
+		{-3, -1, 2}, {-2, -1, 0}, {-1, -1, 0}, {0, -1, 0}, {1, -1, 0}, {2, -1, 0}, {3, -1, 1},
+		{-4, 0, 0}, {-3, 0, 0}, {-2, 0, 0}, {-1, 0, 0},
+	},
+	{
+		{-1, -2, 0}, {0, -2, 0}, {1, -2, 0}, {2, -2, 0},
+		{-2, -1, 0}, {-1, -1, 0}, {0, -1, 0}, {1, -1, 0}, {2, -1, 0}, {3, -1, 1},
+		{-3, 0, 0}, {-2, 0, 0}, {-1, 0, 0},
+	},
+	{
+		{-1, -2, 0}, {0, -2, 0}, {1, -2, 0},
+		{-2, -1, 0}, {-1, -1, 0}, {0, -1, 0}, {1, -1, 0}, {2, -1, 1},
+		{-2, 0, 0}, {-1, 0, 0},
+	},
+	{
+		{-3, -1, 0}, {-2, -1, 0}, {-1, -1, 0}, {0, -1, 0}, {1, -1, 0}, {2, -1, 1},
+		{-4, 0, 0}, {-3, 0, 0}, {-2, 0, 0}, {-1, 0, 0},
+	},
+}
+
+// jbig2SLTPContexts TPGDON 中解码 SLTP 伪像素使用的固定上下文（T.88 6.2.5.7）
+var jbig2SLTPContexts = [4]int{0x9B25, 0x0795, 0x00E5, 0x0195}
+
+// jbig2TemplatePixels 返回模板的参考像素坐标，自适应像素替换为段数据中给出的位置
+func jbig2TemplatePixels(template int, at [][2]int) [][2]int {
+	pixels := make([][2]int, len(jbig2GenericTemplates[template]))
+	for i, p := range jbig2GenericTemplates[template] {
+		if p.at > 0 && p.at <= len(at) {
+			pixels[i] = at[p.at-1]
+		} else {
+			pixels[i] = [2]int{p.x, p.y}
+		}
+	}
+	return pixels
+}
+
+// decodeJBIG2Generic 算术编码的通用区域解码过程（T.88 6.2.5）
+func decodeJBIG2Generic(dec *mqDecoder, width, height, template int, tpgdon bool, at [][2]int) (*jbig2Bitmap, error) {
+	region, err := newJBIG2Bitmap(width, height, 0)
+	if err != nil {
+		return nil, err
+	}
+	pixels := jbig2TemplatePixels(template, at)
+	contexts := make([]byte, 1<<len(pixels))
+
+	ltp := false
+	for y := 0; y < height; y++ {
+		row := region.pix[y*width : (y+1)*width]
+		if tpgdon {
+			if dec.decodeBit(contexts, jbig2SLTPContexts[template]) == 1 {
+				ltp = !ltp
+			}
+			// 典型行：与上一行相同（第一行的上一行视为全白）
+			if ltp {
+				if y > 0 {
+					copy(row, region.pix[(y-1)*width:y*width])
+				}
+				continue
+			}
+		}
+		for x := 0; x < width; x++ {
+			cx := 0
+			for _, p := range pixels {
+				cx = cx<<1 | int(region.at(x+p[0], y+p[1]))
+			}
+			row[x] = dec.decodeBit(contexts, cx)
+		}
+	}
+	return region, nil
+}
+
+// mqState MQ 算术编码器的概率估计状态（T.88 表 E.1）
+type mqState struct {
+	qe         uint32
+	nmps, nlps uint8
+	switchMPS  bool
+}
+
+var mqStates = [47]mqState{
+	{0x5601, 1, 1, true}, {0x3401, 2, 6, false}, {0x1801, 3, 9, false}, {0x0AC1, 4, 12, false},
+	{0x0521, 5, 29, false}, {0x0221, 38, 33, false}, {0x5601, 7, 6, true}, {0x5401, 8, 14, false},
+	{0x4801, 9, 14, false}, {0x3801, 10, 14, false}, {0x3001, 11, 17, false}, {0x2401, 12, 18, false},
+	{0x1C01, 13, 20, false}, {0x1601, 29, 21, false}, {0x5601, 15, 14, true}, {0x5401, 16, 14, false},
+	{0x5101, 17, 15, false}, {0x4801, 18, 16, false}, {0x3801, 19, 17, false}, {0x3401, 20, 18, false},
+	{0x3001, 21, 19, false}, {0x2801, 22, 19, false}, {0x2401, 23, 20, false}, {0x2201, 24, 21, false},
+	{0x1C01, 25, 22, false}, {0x1801, 26, 23, false}, {0x1601, 27, 24, false}, {0x1401, 28, 25, false},
+	{0x1201, 29, 26, false}, {0x1101, 30, 27, false}, {0x0AC1, 31, 28, false}, {0x09C1, 32, 29, false},
+	{0x08A1, 33, 30, false}, {0x0521, 34, 31, false}, {0x0441, 35, 32, false}, {0x02A1, 36, 33, false},
+	{0x0221, 37, 34, false}, {0x0141, 38, 35, false}, {0x0111, 39, 36, false}, {0x0085, 40, 37, false},
+	{0x0049, 41, 38, false}, {0x0025, 42, 39, false}, {0x0015, 43, 40, false}, {0x0009, 44, 41, false},
+	{0x0005, 45, 42, false}, {0x0001, 45, 43, false}, {0x5601, 46, 46, false},
+}
+
+// mqDecoder MQ 算术解码器（T.88 附录 E.3）
+// 上下文状态存放在调用方的字节切片中：高 7 位为状态索引，最低位为 MPS
+type mqDecoder struct {
+	data        []byte
+	pos         int
+	chigh, clow uint32
+	a           uint32
+	ct          int
+}
+
+// newMQDecoder 创建解码器并执行 INITDEC
+func newMQDecoder(data []byte) *mqDecoder {
+	d := &mqDecoder{data: data}
+	d.chigh = uint32(d.byteAt(0))
+	d.byteIn()
+	d.chigh = (d.chigh<<7)&0xFFFF | (d.clow>>9)&0x7F
+	d.clow = (d.clow << 7) & 0xFFFF
+	d.ct -= 7
+	d.a = 0x8000
+	return d
+}
+
+// byteAt 返回第 i 个字节，数据之后按规范视为 0xFF
+func (d *mqDecoder) byteAt(i int) uint32 {
+	if i < len(d.data) {
+		return uint32(d.data[i])
+	}
+	return 0xFF
+}
+
+// byteIn 读入下一个字节（BYTEIN），处理 0xFF 之后的填充位和标记
+func (d *mqDecoder) byteIn() {
+	if d.byteAt(d.pos) == 0xFF {
+		if d.byteAt(d.pos+1) > 0x8F {
+			d.clow += 0xFF00
+			d.ct = 8
+		} else {
+			d.pos++
+			d.clow += d.byteAt(d.pos) << 9
+			d.ct = 7
+		}
+	} else {
+		d.pos++
+		d.clow += d.byteAt(d.pos) << 8
+		d.ct = 8
+	}
+	if d.clow > 0xFFFF {
+		d.chigh += d.clow >> 16
+		d.clow &= 0xFFFF
+	}
+}
+
+// decodeBit 用上下文 contexts[cx] 解码一位（DECODE），并更新该上下文的状态
+func (d *mqDecoder) decodeBit(contexts []byte, cx int) byte {
+	index := contexts[cx] >> 1
+	mps := contexts[cx] & 1
+	state := &mqStates[index]
+	qe := state.qe
+
+	var bit byte
+	a := d.a - qe
+	if d.chigh < qe {
+		// LPS 子区间，条件交换
+		if a < qe {
+			bit = mps
+			index = state.nmps
+		} else {
+			bit = 1 ^ mps
+			if state.switchMPS {
+				mps = bit
+			}
+			index = state.nlps
+		}
+		a = qe
+	} else {
+		d.chigh -= qe
+		if a&0x8000 != 0 {
+			d.a = a
+			return mps
+		}
+		// MPS 子区间，需要重新归一化
+		if a < qe {
+			bit = 1 ^ mps
+			if state.switchMPS {
+				mps = bit
+			}
+			index = state.nlps
+		} else {
+			bit = mps
+			index = state.nmps
+		}
+	}
+
+	// RENORMD
+	for {
+		if d.ct == 0 {
+			d.byteIn()
+		}
+		a <<= 1
+		d.chigh = (d.chigh<<1)&0xFFFF | (d.clow>>15)&1
+		d.clow = (d.clow << 1) & 0xFFFF
+		d.ct--
+		if a&0x8000 != 0 {
+			break
+		}
+	}
+	d.a = a
+	contexts[cx] = index<<1 | mps
+	return bit
+}
+
+// isJBIG2Stream 判断流的滤镜链是否以 JBIG2Decode 结束
+func isJBIG2Stream(filters []string) bool {
+	n := len(filters)
+	return n > 0 && (filters[n-1] == "JBIG2Decode" || filters[n-1] == "/JBIG2Decode")
+}
+
+// streamFilterNames 返回流字典的 Filter 滤镜名列表（单个名称或数组）
+func streamFilterNames(streamDict types.StreamDict) []string {
+	var filters []string
+	filterObj, _ := streamDict.Find("Filter")
+	switch f := filterObj.(type) {
+	case types.Name:
+		filters = append(filters, f.Value())
+	case types.Array:
+		for _, item := range f {
+			if name, ok := item.(types.Name); ok {
+				filters = append(filters, name.Value())
+			}
+		}
+	}
+	return filters
+}
+
+// decodeJBIG2Stream 解码以 JBIG2Decode 结束的图像流，返回 1 位 DeviceGray 采样（0 为黑）
+// JBIG2Decode 之前的滤镜（如 FlateDecode）先用 DecodeImageWithFilters 解开；
+// JBIG2Globals 从对应滤镜的 DecodeParms 中读取
+func decodeJBIG2Stream(ctx *model.Context, streamDict types.StreamDict, filters []string) ([]byte, error) {
+	data := streamDict.Raw
+	if len(filters) > 1 {
+		var err error
+		if data, err = DecodeImageWithFilters(data, filters[:len(filters)-1]); err != nil {
+			return nil, err
+		}
+	}
+
+	// DecodeParms 可以是字典，或与 Filter 数组一一对应的数组
+	var parms types.Dict
+	parmsObj, _ := streamDict.Find("DecodeParms")
+	if arr, ok := parmsObj.(types.Array); ok && len(arr) == len(filters) {
+		parmsObj = arr[len(arr)-1]
+	}
+	if obj, err := ctx.Dereference(parmsObj); err == nil {
+		parms, _ = obj.(types.Dict)
+	}
+
+	var globals []byte
+	if globalsObj, found := parms.Find("JBIG2Globals"); found {
+		sd, _, err := ctx.DereferenceStreamDict(globalsObj)
+		if err != nil || sd == nil {
+			return nil, fmt.Errorf("failed to load JBIG2Globals: %v", err)
+		}
+		if err := sd.Decode(); err != nil {
+			return nil, fmt.Errorf("failed to decode JBIG2Globals: %w", err)
+		}
+		globals = sd.Content
+	}
+
+	width, height, packed, err := decodeJBIG2(data, globals)
+	if err != nil {
+		return nil, err
+	}
+	debugPrintf("[JBIG2] Decoded %dx%d page (%d globals bytes)\n", width, height, len(globals))
+	return packed, nil
+}
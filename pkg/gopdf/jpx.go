@@ -0,0 +1,173 @@
+package gopdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrJPXUnsupported JPXDecode（JPEG 2000）图像可以识别但不能解码
+// 解码这类图像返回 *JPXUnsupportedError，errors.Is(err, ErrJPXUnsupported) 为 true
+var ErrJPXUnsupported = errors.New("gopdf: JPEG 2000 (JPXDecode) image decoding is not supported")
+
+// JPXInfo JPEG 2000 图像的头部信息
+type JPXInfo struct {
+	Width            int    // 图像宽度（码流 SIZ 段的参考网格尺寸减去偏移）
+	Height           int    // 图像高度
+	Components       int    // 分量数
+	BitsPerComponent int    // 第一个分量的位深
+	Signed           bool   // 第一个分量的采样是否有符号
+	ColorSpace       string // JP2 colr 框的枚举颜色空间：DeviceRGB、DeviceGray 或 sYCC；ICC 或原始码流为空
+	HasICCProfile    bool   // colr 框使用嵌入的 ICC 配置文件
+}
+
+// JPXUnsupportedError 已解析头部、但无法解码的 JPEG 2000 图像
+type JPXUnsupportedError struct {
+	Info JPXInfo
+}
+
+func (e *JPXUnsupportedError) Error() string {
+	return fmt.Sprintf("%v: %dx%d, %d components, %d bits", ErrJPXUnsupported,
+		e.Info.Width, e.Info.Height, e.Info.Components, e.Info.BitsPerComponent)
+}
+
+// Unwrap 使 errors.Is(err, ErrJPXUnsupported) 成立
+func (e *JPXUnsupportedError) Unwrap() error {
+	return ErrJPXUnsupported
+}
+
+// jp2Signature JP2 文件开头的签名框
+var jp2Signature = []byte{0, 0, 0, 0x0C, 'j', 'P', ' ', ' ', 0x0D, 0x0A, 0x87, 0x0A}
+
+// ParseJPXHeader 解析 JPXDecode 流（JP2 文件或原始 JPEG 2000 码流）的头部
+// JP2 文件读取 ihdr 和 colr 框，并以码流 SIZ 段的尺寸和位深为准；原始码流只有 SIZ 段的信息
+func ParseJPXHeader(data []byte) (JPXInfo, error) {
+	if len(data) >= 4 && data[0] == 0xFF && data[1] == 0x4F && data[2] == 0xFF && data[3] == 0x51 {
+		return parseJPXCodestream(data)
+	}
+	if len(data) < len(jp2Signature) || string(data[:len(jp2Signature)]) != string(jp2Signature) {
+		return JPXInfo{}, fmt.Errorf("not a JPEG 2000 file or codestream")
+	}
+
+	var info JPXInfo
+	foundHeader := false
+	err := walkJP2Boxes(data[len(jp2Signature):], func(boxType string, body []byte) (bool, error) {
+		switch boxType {
+		case "jp2h":
+			foundHeader = true
+			return true, walkJP2Boxes(body, func(boxType string, body []byte) (bool, error) {
+				switch boxType {
+				case "ihdr":
+					if len(body) < 14 {
+						return false, fmt.Errorf("truncated ihdr box")
+					}
+					info.Height = int(binary.BigEndian.Uint32(body[0:]))
+					info.Width = int(binary.BigEndian.Uint32(body[4:]))
+					info.Components = int(binary.BigEndian.Uint16(body[8:]))
+					if bpc := body[10]; bpc != 0xFF {
+						info.BitsPerComponent = int(bpc&0x7F) + 1
+						info.Signed = bpc&0x80 != 0
+					}
+				case "colr":
+					// 只使用第一个 colr 框
+					if info.ColorSpace != "" || info.HasICCProfile || len(body) < 3 {
+						return true, nil
+					}
+					switch body[0] {
+					case 1:
+						if len(body) >= 7 {
+							switch binary.BigEndian.Uint32(body[3:]) {
+							case 16:
+								info.ColorSpace = "DeviceRGB"
+							case 17:
+								info.ColorSpace = "DeviceGray"
+							case 18:
+								info.ColorSpace = "sYCC"
+							}
+						}
+					case 2, 3:
+						info.HasICCProfile = true
+					}
+				}
+				return true, nil
+			})
+		case "jp2c":
+			cs, err := parseJPXCodestream(body)
+			if err != nil {
+				return false, err
+			}
+			info.Width, info.Height = cs.Width, cs.Height
+			info.Components = cs.Components
+			info.BitsPerComponent, info.Signed = cs.BitsPerComponent, cs.Signed
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return JPXInfo{}, err
+	}
+	if !foundHeader || info.Width <= 0 || info.Height <= 0 || info.Components <= 0 {
+		return JPXInfo{}, fmt.Errorf("JP2 file has no valid image header")
+	}
+	return info, nil
+}
+
+// walkJP2Boxes 依次遍历 data 中的框，fn 返回 false 时停止
+func walkJP2Boxes(data []byte, fn func(boxType string, body []byte) (bool, error)) error {
+	for pos := 0; pos+8 <= len(data); {
+		length := uint64(binary.BigEndian.Uint32(data[pos:]))
+		boxType := string(data[pos+4 : pos+8])
+		header := uint64(8)
+		switch length {
+		case 0:
+			// 最后一个框延伸到数据末尾
+			length = uint64(len(data) - pos)
+		case 1:
+			if pos+16 > len(data) {
+				return fmt.Errorf("truncated JP2 box %q", boxType)
+			}
+			length = binary.BigEndian.Uint64(data[pos+8:])
+			header = 16
+		}
+		if length < header || uint64(pos)+length > uint64(len(data)) {
+			return fmt.Errorf("invalid length for JP2 box %q", boxType)
+		}
+		more, err := fn(boxType, data[pos+int(header):pos+int(length)])
+		if err != nil || !more {
+			return err
+		}
+		pos += int(length)
+	}
+	return nil
+}
+
+// parseJPXCodestream 读取码流开头的 SOC 和 SIZ 段
+func parseJPXCodestream(data []byte) (JPXInfo, error) {
+	// SOC(2) + SIZ 标记(2) + Lsiz(2) + Rsiz(2) + 8 个 32 位字段 + Csiz(2) + 至少一个分量(3)
+	if len(data) < 43 || data[0] != 0xFF || data[1] != 0x4F || data[2] != 0xFF || data[3] != 0x51 {
+		return JPXInfo{}, fmt.Errorf("invalid JPEG 2000 codestream header")
+	}
+	siz := data[4:]
+	xsiz, ysiz := binary.BigEndian.Uint32(siz[4:]), binary.BigEndian.Uint32(siz[8:])
+	xosiz, yosiz := binary.BigEndian.Uint32(siz[12:]), binary.BigEndian.Uint32(siz[16:])
+	if xosiz >= xsiz || yosiz >= ysiz {
+		return JPXInfo{}, fmt.Errorf("invalid JPEG 2000 image size")
+	}
+	ssiz := siz[38]
+	return JPXInfo{
+		Width:            int(xsiz - xosiz),
+		Height:           int(ysiz - yosiz),
+		Components:       int(binary.BigEndian.Uint16(siz[36:])),
+		BitsPerComponent: int(ssiz&0x7F) + 1,
+		Signed:           ssiz&0x80 != 0,
+	}, nil
+}
+
+// jpxImageError 返回 JPXDecode 图像的解码错误：头部有效时为 *JPXUnsupportedError
+func jpxImageError(data []byte) error {
+	info, err := ParseJPXHeader(data)
+	if err != nil {
+		return fmt.Errorf("invalid JPXDecode image: %w", err)
+	}
+	return &JPXUnsupportedError{Info: info}
+}
@@ -0,0 +1,424 @@
+package gopdf
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// meshPatchSteps 曲面片（类型 6、7）每个方向的细分段数
+const meshPatchSteps = 16
+
+// meshBitReader 按大端位序读取网格阴影顶点流
+type meshBitReader struct {
+	data []byte
+	pos  int // 当前位位置
+}
+
+// read 读取 bits 位无符号整数，数据不足时返回 false
+func (r *meshBitReader) read(bits int) (uint64, bool) {
+	if bits <= 0 || r.pos+bits > len(r.data)*8 {
+		return 0, false
+	}
+	var v uint64
+	for i := 0; i < bits; i++ {
+		p := r.pos + i
+		v = v<<1 | uint64(r.data[p/8]>>(7-p%8)&1)
+	}
+	r.pos += bits
+	return v, true
+}
+
+// align 跳到下一个字节边界
+func (r *meshBitReader) align() {
+	r.pos = (r.pos + 7) &^ 7
+}
+
+// meshStreamParams 网格阴影流字典中的编码参数
+type meshStreamParams struct {
+	bitsPerCoordinate int
+	bitsPerComponent  int
+	bitsPerFlag       int
+	verticesPerRow    int
+	decode            []float64
+	numComponents     int // 每个顶点的颜色分量数；有 Function 时为 1
+}
+
+// decodeValue 按 Decode 数组第 i 对把原始值映射到实际范围
+func (p *meshStreamParams) decodeValue(raw uint64, bits, i int) float64 {
+	maxVal := math.Exp2(float64(bits)) - 1
+	return interpolate(float64(raw), 0, maxVal, p.decode[2*i], p.decode[2*i+1])
+}
+
+// readVertex 读取一个顶点的坐标和颜色分量
+func (p *meshStreamParams) readVertex(r *meshBitReader) (MeshVertex, bool) {
+	xr, ok1 := r.read(p.bitsPerCoordinate)
+	yr, ok2 := r.read(p.bitsPerCoordinate)
+	if !ok1 || !ok2 {
+		return MeshVertex{}, false
+	}
+	c, ok := p.readColor(r)
+	if !ok {
+		return MeshVertex{}, false
+	}
+	return MeshVertex{
+		X:     p.decodeValue(xr, p.bitsPerCoordinate, 0),
+		Y:     p.decodeValue(yr, p.bitsPerCoordinate, 1),
+		Color: c,
+	}, true
+}
+
+// readColor 读取一组颜色分量
+func (p *meshStreamParams) readColor(r *meshBitReader) ([]float64, bool) {
+	c := make([]float64, p.numComponents)
+	for i := range c {
+		raw, ok := r.read(p.bitsPerComponent)
+		if !ok {
+			return nil, false
+		}
+		c[i] = p.decodeValue(raw, p.bitsPerComponent, 2+i)
+	}
+	return c, true
+}
+
+// readPoint 读取一个控制点坐标
+func (p *meshStreamParams) readPoint(r *meshBitReader) (Point, bool) {
+	xr, ok1 := r.read(p.bitsPerCoordinate)
+	yr, ok2 := r.read(p.bitsPerCoordinate)
+	if !ok1 || !ok2 {
+		return Point{}, false
+	}
+	return Point{X: p.decodeValue(xr, p.bitsPerCoordinate, 0), Y: p.decodeValue(yr, p.bitsPerCoordinate, 1)}, true
+}
+
+// loadMeshShading 解码网格阴影（类型 4-7）的顶点流，结果存入 shading.Triangles
+func loadMeshShading(ctx *model.Context, shading *Shading, sd *types.StreamDict) error {
+	p := &meshStreamParams{numComponents: 1}
+	intParam := func(key string) int {
+		if obj, found := sd.Dict.Find(key); found {
+			if num, ok := getNumber(obj); ok {
+				return int(num)
+			}
+		}
+		return 0
+	}
+	p.bitsPerCoordinate = intParam("BitsPerCoordinate")
+	p.bitsPerComponent = intParam("BitsPerComponent")
+	p.bitsPerFlag = intParam("BitsPerFlag")
+	p.verticesPerRow = intParam("VerticesPerRow")
+	p.decode = getNumberArray(ctx, sd.Dict, "Decode")
+
+	if shading.Function == nil {
+		cs := shadingColorSpace(shading)
+		if cs == nil {
+			return fmt.Errorf("unsupported mesh shading color space %q", shading.ColorSpace)
+		}
+		p.numComponents = cs.GetNumComponents()
+	}
+
+	if p.bitsPerCoordinate <= 0 || p.bitsPerCoordinate > 32 || p.bitsPerComponent <= 0 || p.bitsPerComponent > 16 {
+		return fmt.Errorf("invalid BitsPerCoordinate %d or BitsPerComponent %d", p.bitsPerCoordinate, p.bitsPerComponent)
+	}
+	if len(p.decode) < 4+2*p.numComponents {
+		return fmt.Errorf("mesh shading Decode requires %d values, got %d", 4+2*p.numComponents, len(p.decode))
+	}
+	if shading.ShadingType != 5 && p.bitsPerFlag != 2 && p.bitsPerFlag != 4 && p.bitsPerFlag != 8 {
+		return fmt.Errorf("invalid BitsPerFlag %d", p.bitsPerFlag)
+	}
+
+	if err := sd.Decode(); err != nil {
+		return fmt.Errorf("failed to decode mesh shading stream: %w", err)
+	}
+	r := &meshBitReader{data: sd.Content}
+
+	switch shading.ShadingType {
+	case 4:
+		shading.Triangles = decodeFreeFormMesh(r, p)
+	case 5:
+		if p.verticesPerRow < 2 {
+			return fmt.Errorf("invalid VerticesPerRow %d", p.verticesPerRow)
+		}
+		shading.Triangles = decodeLatticeMesh(r, p)
+	case 6, 7:
+		shading.Triangles = decodePatchMesh(r, p, shading.ShadingType == 7)
+	}
+	debugPrintf("✓ Decoded mesh shading (type %d): %d triangles\n", shading.ShadingType, len(shading.Triangles))
+	return nil
+}
+
+// decodeFreeFormMesh 解码自由三角形网格（类型 4）
+// 标志 0 开始新三角形（后跟两个顶点），1 与上一三角形的 (b, c) 边相连，2 与 (a, c) 边相连
+func decodeFreeFormMesh(r *meshBitReader, p *meshStreamParams) [][3]MeshVertex {
+	var triangles [][3]MeshVertex
+	var a, b, c MeshVertex
+	started := false
+	readFlagged := func() (uint64, MeshVertex, bool) {
+		flag, ok := r.read(p.bitsPerFlag)
+		if !ok {
+			return 0, MeshVertex{}, false
+		}
+		v, ok := p.readVertex(r)
+		r.align()
+		return flag, v, ok
+	}
+	for {
+		flag, v, ok := readFlagged()
+		if !ok {
+			break
+		}
+		switch flag {
+		case 0:
+			_, v2, ok2 := readFlagged()
+			_, v3, ok3 := readFlagged()
+			if !ok2 || !ok3 {
+				return triangles
+			}
+			a, b, c = v, v2, v3
+			started = true
+		case 1, 2:
+			if !started {
+				continue
+			}
+			if flag == 1 {
+				a, b, c = b, c, v
+			} else {
+				b, c = c, v
+			}
+		default:
+			continue
+		}
+		triangles = append(triangles, [3]MeshVertex{a, b, c})
+	}
+	return triangles
+}
+
+// decodeLatticeMesh 解码格点三角形网格（类型 5）：相邻两行的每个四边形拆成两个三角形
+func decodeLatticeMesh(r *meshBitReader, p *meshStreamParams) [][3]MeshVertex {
+	var triangles [][3]MeshVertex
+	var prev []MeshVertex
+	for {
+		row := make([]MeshVertex, 0, p.verticesPerRow)
+		for len(row) < p.verticesPerRow {
+			v, ok := p.readVertex(r)
+			if !ok {
+				return triangles
+			}
+			row = append(row, v)
+		}
+		if prev != nil {
+			for j := 0; j+1 < len(row); j++ {
+				triangles = append(triangles,
+					[3]MeshVertex{prev[j], prev[j+1], row[j]},
+					[3]MeshVertex{prev[j+1], row[j+1], row[j]})
+			}
+		}
+		prev = row
+	}
+}
+
+// decodePatchMesh 解码 Coons 曲面片（类型 6）或张量积曲面片（类型 7）
+// 张量积曲面片的 4 个内部控制点被忽略，按边界曲线近似为 Coons 曲面片；
+// 每个曲面片细分为 meshPatchSteps x meshPatchSteps 个四边形再拆成三角形
+func decodePatchMesh(r *meshBitReader, p *meshStreamParams, tensor bool) [][3]MeshVertex {
+	var triangles [][3]MeshVertex
+	// 边界控制点按流中顺序：p00 p01 p02 p03 p13 p23 p33 p32 p31 p30 p20 p10；
+	// 角点颜色依次对应 p00 p03 p33 p30
+	var pts [12]Point
+	var colors [4][]float64
+	started := false
+	for {
+		flag, ok := r.read(p.bitsPerFlag)
+		if !ok {
+			break
+		}
+		first := 0
+		firstColor := 0
+		if flag != 0 {
+			if !started || flag > 3 {
+				break
+			}
+			// 与上一曲面片共享的边成为新曲面片的 p00-p03 边
+			var edge [4]Point
+			start := int(flag) * 3
+			for i := range edge {
+				edge[i] = pts[(start+i)%12]
+			}
+			c0, c1 := colors[flag], colors[(flag+1)%4]
+			copy(pts[:4], edge[:])
+			colors[0], colors[1] = c0, c1
+			first, firstColor = 4, 2
+		}
+
+		ok = true
+		for i := first; i < 12 && ok; i++ {
+			pts[i], ok = p.readPoint(r)
+		}
+		if tensor {
+			for i := 0; i < 4 && ok; i++ {
+				_, ok = p.readPoint(r)
+			}
+		}
+		for i := firstColor; i < 4 && ok; i++ {
+			colors[i], ok = p.readColor(r)
+		}
+		r.align()
+		if !ok {
+			break
+		}
+		started = true
+		triangles = append(triangles, triangulateCoonsPatch(pts, colors)...)
+	}
+	return triangles
+}
+
+// triangulateCoonsPatch 在 (u, v) 网格上计算 Coons 曲面并拆分为三角形，颜色按角点双线性插值
+func triangulateCoonsPatch(pts [12]Point, colors [4][]float64) [][3]MeshVertex {
+	// 四条边界曲线：v=0 为 p00..p03，v=1 为 p30..p33，u=0 为 p00..p30，u=1 为 p03..p33
+	bottom := [4]Point{pts[0], pts[1], pts[2], pts[3]}
+	top := [4]Point{pts[9], pts[8], pts[7], pts[6]}
+	left := [4]Point{pts[0], pts[11], pts[10], pts[9]}
+	right := [4]Point{pts[3], pts[4], pts[5], pts[6]}
+
+	const n = meshPatchSteps
+	grid := make([]MeshVertex, (n+1)*(n+1))
+	for j := 0; j <= n; j++ {
+		v := float64(j) / n
+		for i := 0; i <= n; i++ {
+			u := float64(i) / n
+			c1, c2 := bezierPoint(bottom, u), bezierPoint(top, u)
+			d1, d2 := bezierPoint(left, v), bezierPoint(right, v)
+			x := (1-v)*c1.X + v*c2.X + (1-u)*d1.X + u*d2.X -
+				((1-u)*(1-v)*pts[0].X + u*(1-v)*pts[3].X + (1-u)*v*pts[9].X + u*v*pts[6].X)
+			y := (1-v)*c1.Y + v*c2.Y + (1-u)*d1.Y + u*d2.Y -
+				((1-u)*(1-v)*pts[0].Y + u*(1-v)*pts[3].Y + (1-u)*v*pts[9].Y + u*v*pts[6].Y)
+
+			col := make([]float64, len(colors[0]))
+			for k := range col {
+				col[k] = (1-u)*(1-v)*colors[0][k] + u*(1-v)*colors[1][k] +
+					u*v*colors[2][k] + (1-u)*v*colors[3][k]
+			}
+			grid[j*(n+1)+i] = MeshVertex{X: x, Y: y, Color: col}
+		}
+	}
+
+	triangles := make([][3]MeshVertex, 0, 2*n*n)
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			v00, v10 := grid[j*(n+1)+i], grid[j*(n+1)+i+1]
+			v01, v11 := grid[(j+1)*(n+1)+i], grid[(j+1)*(n+1)+i+1]
+			triangles = append(triangles, [3]MeshVertex{v00, v10, v11}, [3]MeshVertex{v00, v11, v01})
+		}
+	}
+	return triangles
+}
+
+// bezierPoint 计算三次贝塞尔曲线在 t 处的点
+func bezierPoint(p [4]Point, t float64) Point {
+	mt := 1 - t
+	a, b, c, d := mt*mt*mt, 3*mt*mt*t, 3*mt*t*t, t*t*t
+	return Point{
+		X: a*p[0].X + b*p[1].X + c*p[2].X + d*p[3].X,
+		Y: a*p[0].Y + b*p[1].Y + c*p[2].Y + d*p[3].Y,
+	}
+}
+
+// shadingColorSpace 返回阴影的颜色空间对象：数组形式直接使用，名称形式按设备颜色空间解析
+func shadingColorSpace(shading *Shading) ColorSpace {
+	if shading.ColorSpaceObj != nil {
+		return shading.ColorSpaceObj
+	}
+	cs, err := deviceColorSpaceByName(strings.TrimPrefix(shading.ColorSpace, "/"))
+	if err != nil {
+		return nil
+	}
+	return cs
+}
+
+// RenderMesh 渲染网格阴影：在设备空间逐三角形按重心坐标插值颜色，
+// 结果作为表面图案返回，由调用方在当前裁剪区域内绘制
+func (gr *GradientRenderer) RenderMesh(shading *Shading) (Pattern, error) {
+	if !shading.IsMesh() {
+		return nil, fmt.Errorf("not a mesh shading (ShadingType=%d)", shading.ShadingType)
+	}
+	target, ok := gr.ctx.GetTarget().(ImageSurface)
+	if !ok {
+		return nil, fmt.Errorf("mesh shading requires an image surface target")
+	}
+	if len(shading.Triangles) == 0 {
+		return nil, nil
+	}
+
+	ctm := *gr.ctx.GetMatrix()
+	img := image.NewRGBA(image.Rect(0, 0, target.GetWidth(), target.GetHeight()))
+	for _, tri := range shading.Triangles {
+		gr.fillMeshTriangle(img, &ctm, tri, shading)
+	}
+
+	surface := NewImageSurface(FormatARGB32, img.Bounds().Dx(), img.Bounds().Dy())
+	defer surface.Destroy()
+	meshSurface, ok := surface.(ImageSurface)
+	if !ok {
+		return nil, fmt.Errorf("failed to create mesh surface")
+	}
+	meshSurface.SetFromRGBA(img)
+
+	// 图案空间即设备空间：图案矩阵（用户空间到图案空间）取当前 CTM
+	pattern := NewPatternForSurface(surface)
+	pattern.SetMatrix(&ctm)
+
+	debugPrintf("✓ Created mesh shading (type %d): %d triangles\n", shading.ShadingType, len(shading.Triangles))
+	return pattern, nil
+}
+
+// fillMeshTriangle 填充一个三角形，像素中心落在三角形内（含边界）时按重心坐标插值颜色
+func (gr *GradientRenderer) fillMeshTriangle(img *image.RGBA, ctm *Matrix, tri [3]MeshVertex, shading *Shading) {
+	var xs, ys [3]float64
+	for i, v := range tri {
+		xs[i], ys[i] = ctm.Transform(v.X, v.Y)
+	}
+	area := (xs[1]-xs[0])*(ys[2]-ys[0]) - (xs[2]-xs[0])*(ys[1]-ys[0])
+	if math.Abs(area) < 1e-12 {
+		return
+	}
+
+	minX := math.Floor(math.Min(xs[0], math.Min(xs[1], xs[2])))
+	maxX := math.Ceil(math.Max(xs[0], math.Max(xs[1], xs[2])))
+	minY := math.Floor(math.Min(ys[0], math.Min(ys[1], ys[2])))
+	maxY := math.Ceil(math.Max(ys[0], math.Max(ys[1], ys[2])))
+	bounds := image.Rect(int(minX), int(minY), int(maxX), int(maxY)).Intersect(img.Bounds())
+
+	const eps = 1e-9
+	n := len(tri[0].Color)
+	comps := make([]float64, n)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		py := float64(y) + 0.5
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			px := float64(x) + 0.5
+			w0 := ((xs[1]-px)*(ys[2]-py) - (xs[2]-px)*(ys[1]-py)) / area
+			w1 := ((xs[2]-px)*(ys[0]-py) - (xs[0]-px)*(ys[2]-py)) / area
+			w2 := 1 - w0 - w1
+			if w0 < -eps || w1 < -eps || w2 < -eps {
+				continue
+			}
+			for k := range comps {
+				comps[k] = w0*tri[0].Color[k] + w1*tri[1].Color[k] + w2*tri[2].Color[k]
+			}
+			colors := comps
+			if shading.Function != nil {
+				colors = shading.Function.Eval(comps[:1])
+			}
+			r, g, b, _ := gr.shadingColorToRGBA(colors, shading)
+			img.SetRGBA(x, y, color.RGBA{
+				R: uint8(clamp01(r)*255 + 0.5),
+				G: uint8(clamp01(g)*255 + 0.5),
+				B: uint8(clamp01(b)*255 + 0.5),
+				A: 255,
+			})
+		}
+	}
+}
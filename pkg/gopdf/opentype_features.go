@@ -5,6 +5,9 @@ import (
 
 	"github.com/go-text/typesetting/di"
 	"github.com/go-text/typesetting/language"
+	"github.com/go-text/typesetting/opentype/loader"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/text/unicode/bidi"
 )
 
 // TextDirection represents text direction
@@ -310,6 +313,89 @@ func DetectLanguage(text string) string {
 	}
 }
 
+// bidiRun is a directional run of a line, in rune indices [Start, End)
+type bidiRun struct {
+	Start, End int
+	RTL        bool
+}
+
+// bidiVisualRuns runs the Unicode bidi algorithm over one line of text and
+// returns its directional runs in visual (left to right) order.
+// With base TextDirectionRTL the paragraph is right-to-left; any other value
+// resolves the paragraph direction from the first strong character (rules P2/P3).
+func bidiVisualRuns(line []rune, base TextDirection) []bidiRun {
+	if len(line) == 0 {
+		return nil
+	}
+
+	def := bidi.LeftToRight
+	paraRTL := DetectTextDirection(string(line)) == TextDirectionRTL
+	if base == TextDirectionRTL {
+		def = bidi.RightToLeft
+		paraRTL = true
+	}
+	whole := []bidiRun{{Start: 0, End: len(line), RTL: paraRTL}}
+
+	var p bidi.Paragraph
+	if _, err := p.SetString(string(line), bidi.DefaultDirection(def)); err != nil {
+		return whole
+	}
+	order, err := p.Order()
+	if err != nil || order.NumRuns() == 0 {
+		return whole
+	}
+
+	// Runs come back in logical order; derive embedding levels from the
+	// paragraph level so they can be reordered (rule L2)
+	paraLevel := 0
+	if paraRTL {
+		paraLevel = 1
+	}
+	runs := make([]bidiRun, 0, order.NumRuns())
+	levels := make([]int, 0, order.NumRuns())
+	maxLevel := 0
+	for i := 0; i < order.NumRuns(); i++ {
+		r := order.Run(i)
+		start, end := r.Pos()
+		rtl := r.Direction() == bidi.RightToLeft
+		level := paraLevel
+		if rtl != paraRTL {
+			level++
+		}
+		runs = append(runs, bidiRun{Start: start, End: end + 1, RTL: rtl})
+		levels = append(levels, level)
+		if level > maxLevel {
+			maxLevel = level
+		}
+	}
+	// A paragraph separator stops the algorithm early; keep the rest as is
+	if last := runs[len(runs)-1].End; last < len(line) {
+		runs = append(runs, bidiRun{Start: last, End: len(line), RTL: paraRTL})
+		levels = append(levels, paraLevel)
+	}
+
+	// L2: from the highest level down to 1, reverse every maximal sequence of
+	// runs at that level or higher
+	for lvl := maxLevel; lvl >= 1; lvl-- {
+		for i := 0; i < len(runs); {
+			if levels[i] < lvl {
+				i++
+				continue
+			}
+			j := i
+			for j < len(runs) && levels[j] >= lvl {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				runs[a], runs[b] = runs[b], runs[a]
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+			i = j
+		}
+	}
+	return runs
+}
+
 // convertDirection converts TextDirection to di.Direction
 func convertDirection(dir TextDirection, text string) di.Direction {
 	switch dir {
@@ -363,6 +449,27 @@ func convertScript(script string) language.Script {
 	return s
 }
 
+// convertFeatures converts OpenType features to shaping font features.
+// Tags that are not 4 characters long are skipped.
+func convertFeatures(features []OpenTypeFeature) []shaping.FontFeature {
+	if len(features) == 0 {
+		return nil
+	}
+
+	result := make([]shaping.FontFeature, 0, len(features))
+	for _, f := range features {
+		if len(f.Tag) != 4 {
+			debugPrintf("[OpenType] ignoring invalid feature tag %q\n", f.Tag)
+			continue
+		}
+		result = append(result, shaping.FontFeature{
+			Tag:   loader.NewTag(f.Tag[0], f.Tag[1], f.Tag[2], f.Tag[3]),
+			Value: f.Value,
+		})
+	}
+	return result
+}
+
 // ShapeText performs text shaping with advanced OpenType features
 // SetDefaultFeatures sets default OpenType features for common use cases
 func SetDefaultFeatures(options *ShapingOptions, preset string) {
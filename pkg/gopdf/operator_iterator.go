@@ -0,0 +1,280 @@
+package gopdf
+
+import (
+	"fmt"
+)
+
+// GraphicsSnapshot 内容流中某个操作符执行前的图形状态快照（只读副本）
+// 坐标均为 PDF 用户空间（左下角为原点，Y 轴向上）
+type GraphicsSnapshot struct {
+	CTM            Matrix // 当前变换矩阵
+	TextMatrix     Matrix // 文本矩阵 Tm
+	TextLineMatrix Matrix // 文本行矩阵 Tlm
+	InText         bool   // 是否位于 BT/ET 之间
+
+	// 文本状态
+	FontName          string  // Tf 设置的字体资源名
+	FontSize          float64 // Tf 设置的字号（文本空间）
+	CharSpacing       float64 // Tc
+	WordSpacing       float64 // Tw
+	HorizontalScaling float64 // Tz（百分比，默认 100）
+	Leading           float64 // TL
+	Rise              float64 // Ts
+	RenderMode        int     // Tr
+
+	// 颜色与线条
+	FillColor        [3]float64 // 填充颜色（RGB，0-1；Separation/DeviceN 色调经 tint 变换转换）
+	StrokeColor      [3]float64 // 描边颜色（RGB，0-1）
+	FillColorSpace   string     // 填充颜色空间：cs 的操作数（设备颜色空间名或资源名），g/rg/k 对应的设备颜色空间
+	StrokeColorSpace string     // 描边颜色空间
+	FillNoMarks      bool       // 填充颜色空间为 None 着色剂，填充不产生标记
+	StrokeNoMarks    bool       // 描边颜色空间为 None 着色剂，描边不产生标记
+	LineWidth        float64
+	LineCap          int
+	LineJoin         int
+	MiterLimit       float64
+	DashPattern      []float64
+	DashPhase        float64
+
+	fillSpace, strokeSpace ColorSpace // 解析后的颜色空间，用于转换 sc/scn 的分量
+}
+
+// newGraphicsSnapshot 返回页面开始时的默认图形状态
+func newGraphicsSnapshot() GraphicsSnapshot {
+	return GraphicsSnapshot{
+		CTM:               *NewIdentityMatrix(),
+		TextMatrix:        *NewIdentityMatrix(),
+		TextLineMatrix:    *NewIdentityMatrix(),
+		HorizontalScaling: 100,
+		FillColorSpace:    "DeviceGray",
+		StrokeColorSpace:  "DeviceGray",
+		LineWidth:         1,
+		MiterLimit:        10,
+	}
+}
+
+// clone 深拷贝快照（切片字段不与原状态共享）
+func (s GraphicsSnapshot) clone() GraphicsSnapshot {
+	s.DashPattern = append([]float64(nil), s.DashPattern...)
+	return s
+}
+
+// graphicsTracker 按 PDF 语义跟踪内容流的图形状态和文本状态
+// 只处理状态操作符；文本显示后的前进量取决于字体宽度，由调用方通过 advanceText 推进
+type graphicsTracker struct {
+	state     GraphicsSnapshot
+	stack     []GraphicsSnapshot
+	resources *Resources // 解析 cs/CS 的命名颜色空间，可以为 nil
+}
+
+func newGraphicsTracker(resources *Resources) *graphicsTracker {
+	return &graphicsTracker{state: newGraphicsSnapshot(), resources: resources}
+}
+
+// snapshot 返回当前状态的只读副本
+func (t *graphicsTracker) snapshot() GraphicsSnapshot {
+	return t.state.clone()
+}
+
+// moveTextLine 按 Td 语义移动到新行：Tlm = [1 0 0 1 tx ty] × Tlm，Tm = Tlm
+// （X 回到行首，偏移量在文本空间中，会随文本矩阵缩放和旋转）
+func (t *graphicsTracker) moveTextLine(tx, ty float64) {
+	translation := NewTranslationMatrix(tx, ty)
+	t.state.TextLineMatrix = *translation.Multiply(&t.state.TextLineMatrix)
+	t.state.TextMatrix = t.state.TextLineMatrix
+}
+
+// advanceText 沿文本基线方向前进 displacement（用户空间长度）
+// 旋转文本的基线不是水平方向
+func (t *graphicsTracker) advanceText(displacement float64) {
+	if displacement == 0 {
+		return
+	}
+	ux, uy := baselineDirection(&t.state.TextMatrix)
+	translation := NewTranslationMatrix(displacement*ux, displacement*uy)
+	t.state.TextMatrix = *t.state.TextMatrix.Multiply(translation)
+}
+
+// apply 把状态操作符的效果应用到当前状态
+// ' 和 " 在显示文本前换行（" 还会设置 Tw/Tc），这部分效果也在这里完成
+func (t *graphicsTracker) apply(op PDFOperator) {
+	s := &t.state
+
+	switch o := op.(type) {
+	case *OpSaveState:
+		t.stack = append(t.stack, s.clone())
+	case *OpRestoreState:
+		if len(t.stack) == 0 {
+			// 栈为空时保持当前状态（不匹配的 Q）
+			debugPrintf("[graphicsTracker] Q without matching q, ignoring\n")
+			return
+		}
+		t.state = t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+	case *OpConcatMatrix:
+		// CTM' = cm × CTM
+		s.CTM = *o.Matrix.Multiply(&s.CTM)
+
+	case *OpBeginText:
+		s.TextMatrix = *NewIdentityMatrix()
+		s.TextLineMatrix = *NewIdentityMatrix()
+		s.InText = true
+	case *OpEndText:
+		s.InText = false
+	case *OpSetFont:
+		s.FontName = o.FontName
+		s.FontSize = o.FontSize
+	case *OpSetCharSpacing:
+		s.CharSpacing = o.Spacing
+	case *OpSetWordSpacing:
+		s.WordSpacing = o.Spacing
+	case *OpSetHorizontalScaling:
+		s.HorizontalScaling = o.Scale
+	case *OpSetLeading:
+		s.Leading = o.Leading
+	case *OpSetTextRise:
+		s.Rise = o.Rise
+	case *OpSetTextRenderMode:
+		s.RenderMode = o.Mode
+	case *OpSetTextMatrix:
+		s.TextMatrix = *o.Matrix
+		s.TextLineMatrix = *o.Matrix
+	case *OpMoveTextPosition:
+		t.moveTextLine(o.Tx, o.Ty)
+	case *OpMoveTextPositionSetLeading:
+		s.Leading = -o.Ty
+		t.moveTextLine(o.Tx, o.Ty)
+	case *OpMoveToNextLine:
+		t.moveTextLine(0, -s.Leading)
+	case *OpShowTextNextLine:
+		t.moveTextLine(0, -s.Leading)
+	case *OpShowTextWithSpacing:
+		s.WordSpacing = o.WordSpacing
+		s.CharSpacing = o.CharSpacing
+		t.moveTextLine(0, -s.Leading)
+
+	case *OpSetLineWidth:
+		s.LineWidth = o.Width
+	case *OpSetLineCap:
+		s.LineCap = o.Cap
+	case *OpSetLineJoin:
+		s.LineJoin = o.Join
+	case *OpSetMiterLimit:
+		s.MiterLimit = o.Limit
+	case *OpSetDash:
+		s.DashPattern = append([]float64(nil), o.Pattern...)
+		s.DashPhase = o.Offset
+	case *OpSetFillColorRGB:
+		s.setFillDeviceColor("DeviceRGB", o.R, o.G, o.B)
+	case *OpSetStrokeColorRGB:
+		s.setStrokeDeviceColor("DeviceRGB", o.R, o.G, o.B)
+	case *OpSetFillColorGray:
+		s.setFillDeviceColor("DeviceGray", o.Gray, o.Gray, o.Gray)
+	case *OpSetStrokeColorGray:
+		s.setStrokeDeviceColor("DeviceGray", o.Gray, o.Gray, o.Gray)
+	case *OpSetFillColorCMYK:
+		r, g, b := cmykToRGB(o.C, o.M, o.Y, o.K)
+		s.setFillDeviceColor("DeviceCMYK", r, g, b)
+	case *OpSetStrokeColorCMYK:
+		r, g, b := cmykToRGB(o.C, o.M, o.Y, o.K)
+		s.setStrokeDeviceColor("DeviceCMYK", r, g, b)
+	case *OpSetFillColorSpace:
+		cs := lookupColorSpace(t.resources, o.ColorSpaceName)
+		s.FillColorSpace, s.fillSpace = o.ColorSpaceName, cs
+		s.FillNoMarks = colorSpaceMarksNothing(cs)
+		s.FillColor = colorArray(initialColorRGB(cs))
+	case *OpSetStrokeColorSpace:
+		cs := lookupColorSpace(t.resources, o.ColorSpaceName)
+		s.StrokeColorSpace, s.strokeSpace = o.ColorSpaceName, cs
+		s.StrokeNoMarks = colorSpaceMarksNothing(cs)
+		s.StrokeColor = colorArray(initialColorRGB(cs))
+	case *OpSetFillColor:
+		s.FillColor = colorArray(colorComponentsToRGB(s.fillSpace, o.Components))
+	case *OpSetStrokeColor:
+		s.StrokeColor = colorArray(colorComponentsToRGB(s.strokeSpace, o.Components))
+	}
+}
+
+// setFillDeviceColor g/rg/k：设置填充颜色并切换到对应的设备颜色空间
+func (s *GraphicsSnapshot) setFillDeviceColor(space string, r, g, b float64) {
+	s.FillColor = [3]float64{r, g, b}
+	s.FillColorSpace, s.fillSpace, s.FillNoMarks = space, nil, false
+}
+
+// setStrokeDeviceColor G/RG/K：设置描边颜色并切换到对应的设备颜色空间
+func (s *GraphicsSnapshot) setStrokeDeviceColor(space string, r, g, b float64) {
+	s.StrokeColor = [3]float64{r, g, b}
+	s.StrokeColorSpace, s.strokeSpace, s.StrokeNoMarks = space, nil, false
+}
+
+// colorArray 把 RGB 分量组成数组
+func colorArray(r, g, b float64) [3]float64 {
+	return [3]float64{r, g, b}
+}
+
+// pageOperators 读取页面资源并解析合并后的内容流
+func (r *PDFReader) pageOperators(pageNum int) (*Resources, []PDFOperator, error) {
+	ctx, err := r.readContext()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read PDF context: %w", err)
+	}
+
+	pageDict, _, _, err := ctx.PageDict(pageNum, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get page dict: %w", err)
+	}
+	if pageDict == nil {
+		return nil, nil, fmt.Errorf("page %d not found", pageNum)
+	}
+
+	resources := NewResources()
+	if resourcesObj, found := resolvePageResources(ctx, pageDict); found {
+		if err := loadResources(ctx, resourcesObj, resources); err != nil {
+			debugPrintf("Failed to load resources: %v\n", err)
+		}
+	}
+
+	contents, found := pageDict.Find("Contents")
+	if !found {
+		return resources, nil, nil
+	}
+
+	contentStreams, err := ExtractContentStreams(ctx, contents)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to extract content streams: %w", err)
+	}
+
+	operators, err := ParseContentStreams(contentStreams)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse content stream: %w", err)
+	}
+	return resources, operators, nil
+}
+
+// IterateOperators 按顺序遍历页面内容流中的操作符
+// fn 收到每个操作符以及该操作符执行前的图形状态快照（CTM、文本状态、颜色等）；
+// 文本显示操作符之后的文本矩阵按字体宽度前进。fn 返回错误时停止遍历并返回该错误
+func (r *PDFReader) IterateOperators(pageNum int, fn func(op PDFOperator, state *GraphicsSnapshot) error) error {
+	resources, operators, err := r.pageOperators(pageNum)
+	if err != nil {
+		return err
+	}
+
+	tracker := newGraphicsTracker(resources)
+	for _, op := range operators {
+		if op.Name() == "IGNORE" {
+			continue
+		}
+
+		snapshot := tracker.snapshot()
+		if err := fn(op, &snapshot); err != nil {
+			return err
+		}
+
+		tracker.apply(op)
+		if m := measureShownText(op, &tracker.state, resources); m.text != "" {
+			tracker.advanceText(m.width + m.kerning)
+		}
+	}
+	return nil
+}
@@ -1,9 +1,14 @@
 package gopdf
 
 import (
+	"errors"
 	"fmt"
 )
 
+// ErrUnbalancedContent 内容流中的 q/Q 或 BT/ET 不配对
+// 严格模式（RenderOptions.Strict）下渲染遇到不匹配的 Q 或没有 BT 的 ET 时返回包装此错误的错误
+var ErrUnbalancedContent = errors.New("unbalanced content stream operators")
+
 // PDFOperator 表示 PDF 操作符接口
 type PDFOperator interface {
 	Execute(ctx *RenderContext) error
@@ -19,6 +24,15 @@ type RenderContext struct {
 	TextState          *TextState
 	Resources          *Resources
 	XObjectCache       map[string]Surface
+	ContentFilter      ContentFilter  // 要渲染的内容类别，零值表示全部
+	ICCTransform       bool           // 解码图像时使用嵌入的 ICC 配置文件（RenderOptions.ICC）
+	CMYKConversion     CMYKConversion // DeviceCMYK 颜色和图像的转换方式（RenderOptions.CMYK）
+	TextClipPath       *PathImpl      // 文本渲染模式 4-7 累积的字形轮廓，在 ET 时加入裁剪路径
+	Strict             bool           // 严格模式（RenderOptions.Strict）：不配对的 Q、ET 返回错误
+
+	stateBase    int           // 当前内容流开始时的图形状态栈深度，Q 不能恢复到此深度以下
+	inTextObject bool          // 位于 BT 和 ET 之间
+	budget       *renderBudget // 操作符数量和表单嵌套深度限制
 }
 
 // NewRenderContext 创建新的渲染上下文
@@ -31,6 +45,59 @@ func NewRenderContext(gopdfCtx Context, width, height float64) *RenderContext {
 		TextState:          NewTextState(),
 		Resources:          NewResources(),
 		XObjectCache:       make(map[string]Surface),
+		stateBase:          1,
+		budget:             newRenderBudget(0, 0),
+	}
+}
+
+// abortOnError 判断操作符错误是否应中止渲染：超出渲染限制，或严格模式下的 q/Q、BT/ET 不配对
+func (rc *RenderContext) abortOnError(err error) bool {
+	return errors.Is(err, ErrRenderLimitExceeded) || (rc.Strict && errors.Is(err, ErrUnbalancedContent))
+}
+
+// enterContentStream 开始执行嵌套的内容流（表单 XObject），返回恢复外层状态的函数
+// 嵌套内容流中的 Q 只能恢复它自己保存的状态
+func (rc *RenderContext) enterContentStream() func() {
+	base, inText := rc.stateBase, rc.inTextObject
+	rc.stateBase = rc.GraphicsStack.Depth()
+	rc.inTextObject = false
+	return func() {
+		rc.stateBase, rc.inTextObject = base, inText
+	}
+}
+
+// shouldRender 判断当前过滤器是否允许渲染指定类别的内容
+func (rc *RenderContext) shouldRender(c ContentFilter) bool {
+	return rc.ContentFilter.Has(c)
+}
+
+// discardPath 丢弃当前路径而不绘制（等同于 n），用于跳过被过滤的绘制操作
+func (rc *RenderContext) discardPath() {
+	rc.GopdfCtx.NewPath()
+	rc.CurrentPath.Clear()
+}
+
+// appendTextClipPath 把字形轮廓（经 m 变换到当前用户空间）追加到文本裁剪路径
+func (rc *RenderContext) appendTextClipPath(path *Path, m *Matrix) {
+	if rc.TextClipPath == nil {
+		rc.TextClipPath = NewPath()
+	}
+	for _, data := range path.Data {
+		pts := make([]float64, 0, 2*len(data.Points))
+		for _, p := range data.Points {
+			x, y := m.Transform(p.X, p.Y)
+			pts = append(pts, x, y)
+		}
+		switch {
+		case data.Type == PathMoveTo && len(pts) >= 2:
+			rc.TextClipPath.MoveTo(pts[0], pts[1])
+		case data.Type == PathLineTo && len(pts) >= 2:
+			rc.TextClipPath.LineTo(pts[0], pts[1])
+		case data.Type == PathCurveTo && len(pts) >= 6:
+			rc.TextClipPath.CurveTo(pts[0], pts[1], pts[2], pts[3], pts[4], pts[5])
+		case data.Type == PathClosePath:
+			rc.TextClipPath.ClosePath()
+		}
 	}
 }
 
@@ -59,6 +126,12 @@ type OpRestoreState struct{}
 func (op *OpRestoreState) Name() string { return "Q" }
 
 func (op *OpRestoreState) Execute(ctx *RenderContext) error {
+	if ctx.GraphicsStack.Depth() <= ctx.stateBase {
+		if ctx.Strict {
+			return fmt.Errorf("%w: Q without matching q", ErrUnbalancedContent)
+		}
+		debugPrintf("[Q] Warning: Q without matching q\n")
+	}
 	ctx.GraphicsStack.Pop()
 	ctx.GopdfCtx.Restore()
 	debugPrintf("[Q] Restore graphics state - Stack depth: %d\n", ctx.GraphicsStack.Depth())
@@ -244,7 +317,7 @@ func (op *OpSetGraphicsState) Execute(ctx *RenderContext) error {
 	// 软遮罩
 	if smask, ok := extGState["SMask"]; ok && smask != nil {
 		// 软遮罩可以是字典或 /None
-		if smaskStr, ok := smask.(string); ok && smaskStr == "/None" {
+		if smaskStr, ok := smask.(string); ok && (smaskStr == "/None" || smaskStr == "None") {
 			state.SoftMask = nil
 			debugPrintf("[gs] Removed soft mask\n")
 		} else if smaskDict, ok := smask.(map[string]interface{}); ok {
@@ -262,6 +335,21 @@ func (op *OpSetGraphicsState) Execute(ctx *RenderContext) error {
 				if bc, ok := smaskDict["BC"].([]float64); ok {
 					softMask.BC = bc
 				}
+				if tr, ok := smaskDict["TR"].(*PDFFunction); ok {
+					softMask.TR = tr
+				}
+
+				// 遮罩在设置时按当前 CTM 渲染为设备空间的遮罩值，作用于之后的所有绘制
+				if target := contextImage(ctx.GopdfCtx); target != nil {
+					b := target.Bounds()
+					if err := softMask.RenderSoftMask(ctx, b.Dx(), b.Dy()); err != nil {
+						// 超出渲染限制或严格模式下遮罩内容不配对时中止，与表单 XObject 一致
+						if ctx.abortOnError(err) {
+							return err
+						}
+						debugPrintf("[gs] Failed to render soft mask: %v\n", err)
+					}
+				}
 
 				state.SoftMask = softMask
 				debugPrintf("[gs] Set soft mask: type=%s\n", maskType)
@@ -402,7 +490,16 @@ type OpStroke struct{}
 func (op *OpStroke) Name() string { return "S" }
 
 func (op *OpStroke) Execute(ctx *RenderContext) error {
+	if !ctx.shouldRender(ContentVectors) {
+		ctx.discardPath()
+		return nil
+	}
+
 	state := ctx.GetCurrentState()
+	if state.StrokeNoMarks {
+		ctx.discardPath()
+		return nil
+	}
 	filler := NewPathFiller(ctx.GopdfCtx)
 
 	if err := filler.StrokePath(ctx.CurrentPath, state.StrokeColor, state.LineWidth); err != nil {
@@ -429,7 +526,16 @@ type OpFill struct{}
 func (op *OpFill) Name() string { return "f" }
 
 func (op *OpFill) Execute(ctx *RenderContext) error {
+	if !ctx.shouldRender(ContentVectors) {
+		ctx.discardPath()
+		return nil
+	}
+
 	state := ctx.GetCurrentState()
+	if state.FillNoMarks {
+		ctx.discardPath()
+		return nil
+	}
 	filler := NewPathFiller(ctx.GopdfCtx)
 	filler.SetFillRule(FillRuleWinding)
 
@@ -447,7 +553,16 @@ type OpFillEvenOdd struct{}
 func (op *OpFillEvenOdd) Name() string { return "f*" }
 
 func (op *OpFillEvenOdd) Execute(ctx *RenderContext) error {
+	if !ctx.shouldRender(ContentVectors) {
+		ctx.discardPath()
+		return nil
+	}
+
 	state := ctx.GetCurrentState()
+	if state.FillNoMarks {
+		ctx.discardPath()
+		return nil
+	}
 	filler := NewPathFiller(ctx.GopdfCtx)
 	filler.SetFillRule(FillRuleEvenOdd)
 
@@ -465,7 +580,22 @@ type OpFillAndStroke struct{}
 func (op *OpFillAndStroke) Name() string { return "B" }
 
 func (op *OpFillAndStroke) Execute(ctx *RenderContext) error {
+	if !ctx.shouldRender(ContentVectors) {
+		ctx.discardPath()
+		return nil
+	}
+
 	state := ctx.GetCurrentState()
+	// None 着色剂的一侧不产生标记，只执行另一侧
+	switch {
+	case state.FillNoMarks && state.StrokeNoMarks:
+		ctx.discardPath()
+		return nil
+	case state.FillNoMarks:
+		return (&OpStroke{}).Execute(ctx)
+	case state.StrokeNoMarks:
+		return (&OpFill{}).Execute(ctx)
+	}
 	filler := NewPathFiller(ctx.GopdfCtx)
 	filler.SetFillRule(FillRuleWinding)
 
@@ -493,8 +623,7 @@ type OpEndPath struct{}
 func (op *OpEndPath) Name() string { return "n" }
 
 func (op *OpEndPath) Execute(ctx *RenderContext) error {
-	ctx.GopdfCtx.NewPath()
-	ctx.CurrentPath.Clear()
+	ctx.discardPath()
 	return nil
 }
 
@@ -532,6 +661,8 @@ func (op *OpSetStrokeColorRGB) Name() string { return "RG" }
 func (op *OpSetStrokeColorRGB) Execute(ctx *RenderContext) error {
 	state := ctx.GetCurrentState()
 	state.SetStrokeColor(op.R, op.G, op.B, 1.0)
+	state.StrokeColorSpace = &DeviceRGBColorSpace{}
+	state.StrokeNoMarks = false
 	return nil
 }
 
@@ -545,6 +676,8 @@ func (op *OpSetFillColorRGB) Name() string { return "rg" }
 func (op *OpSetFillColorRGB) Execute(ctx *RenderContext) error {
 	state := ctx.GetCurrentState()
 	state.SetFillColor(op.R, op.G, op.B, 1.0)
+	state.FillColorSpace = &DeviceRGBColorSpace{}
+	state.FillNoMarks = false
 	return nil
 }
 
@@ -558,6 +691,8 @@ func (op *OpSetStrokeColorGray) Name() string { return "G" }
 func (op *OpSetStrokeColorGray) Execute(ctx *RenderContext) error {
 	state := ctx.GetCurrentState()
 	state.SetStrokeColor(op.Gray, op.Gray, op.Gray, 1.0)
+	state.StrokeColorSpace = &DeviceGrayColorSpace{}
+	state.StrokeNoMarks = false
 	return nil
 }
 
@@ -571,6 +706,8 @@ func (op *OpSetFillColorGray) Name() string { return "g" }
 func (op *OpSetFillColorGray) Execute(ctx *RenderContext) error {
 	state := ctx.GetCurrentState()
 	state.SetFillColor(op.Gray, op.Gray, op.Gray, 1.0)
+	state.FillColorSpace = &DeviceGrayColorSpace{}
+	state.FillNoMarks = false
 	return nil
 }
 
@@ -582,9 +719,11 @@ type OpSetStrokeColorCMYK struct {
 func (op *OpSetStrokeColorCMYK) Name() string { return "K" }
 
 func (op *OpSetStrokeColorCMYK) Execute(ctx *RenderContext) error {
-	r, g, b := cmykToRGB(op.C, op.M, op.Y, op.K)
+	r, g, b := cmykToRGBWith(ctx.CMYKConversion, op.C, op.M, op.Y, op.K)
 	state := ctx.GetCurrentState()
 	state.SetStrokeColor(r, g, b, 1.0)
+	state.StrokeColorSpace = &DeviceCMYKColorSpace{}
+	state.StrokeNoMarks = false
 	return nil
 }
 
@@ -596,18 +735,160 @@ type OpSetFillColorCMYK struct {
 func (op *OpSetFillColorCMYK) Name() string { return "k" }
 
 func (op *OpSetFillColorCMYK) Execute(ctx *RenderContext) error {
-	r, g, b := cmykToRGB(op.C, op.M, op.Y, op.K)
+	r, g, b := cmykToRGBWith(ctx.CMYKConversion, op.C, op.M, op.Y, op.K)
+	state := ctx.GetCurrentState()
+	state.SetFillColor(r, g, b, 1.0)
+	state.FillColorSpace = &DeviceCMYKColorSpace{}
+	state.FillNoMarks = false
+	return nil
+}
+
+// OpSetStrokeColorSpace CS - 设置描边颜色空间
+type OpSetStrokeColorSpace struct {
+	ColorSpaceName string
+}
+
+func (op *OpSetStrokeColorSpace) Name() string { return "CS" }
+
+func (op *OpSetStrokeColorSpace) Execute(ctx *RenderContext) error {
+	cs := resolveColorSpaceName(ctx, op.ColorSpaceName)
+	state := ctx.GetCurrentState()
+	state.StrokeNoMarks = colorSpaceMarksNothing(cs)
+	if cs == nil {
+		// Pattern 颜色空间由 SCN 的图案名称处理
+		return nil
+	}
+	state.StrokeColorSpace = cs
+	r, g, b := initialColorRGB(cs)
+	state.SetStrokeColor(r, g, b, 1.0)
+	return nil
+}
+
+// OpSetFillColorSpace cs - 设置填充颜色空间
+type OpSetFillColorSpace struct {
+	ColorSpaceName string
+}
+
+func (op *OpSetFillColorSpace) Name() string { return "cs" }
+
+func (op *OpSetFillColorSpace) Execute(ctx *RenderContext) error {
+	cs := resolveColorSpaceName(ctx, op.ColorSpaceName)
 	state := ctx.GetCurrentState()
+	state.FillNoMarks = colorSpaceMarksNothing(cs)
+	if cs == nil {
+		// Pattern 颜色空间由 scn 的图案名称处理
+		return nil
+	}
+	state.FillColorSpace = cs
+	r, g, b := initialColorRGB(cs)
 	state.SetFillColor(r, g, b, 1.0)
 	return nil
 }
 
-// cmykToRGB 将 CMYK 转换为 RGB
+// OpSetStrokeColor SC/SCN - 在当前描边颜色空间中设置颜色
+type OpSetStrokeColor struct {
+	Components []float64
+}
+
+func (op *OpSetStrokeColor) Name() string { return "SC" }
+
+func (op *OpSetStrokeColor) Execute(ctx *RenderContext) error {
+	state := ctx.GetCurrentState()
+	r, g, b := ctx.convertColor(state.StrokeColorSpace, op.Components)
+	state.SetStrokeColor(r, g, b, 1.0)
+	return nil
+}
+
+// OpSetFillColor sc/scn - 在当前填充颜色空间中设置颜色
+type OpSetFillColor struct {
+	Components []float64
+}
+
+func (op *OpSetFillColor) Name() string { return "sc" }
+
+func (op *OpSetFillColor) Execute(ctx *RenderContext) error {
+	state := ctx.GetCurrentState()
+	r, g, b := ctx.convertColor(state.FillColorSpace, op.Components)
+	state.SetFillColor(r, g, b, 1.0)
+	return nil
+}
+
+// resolveColorSpaceName 解析 cs/CS 的颜色空间名称
+// 先查找设备颜色空间，再查找资源字典 /ColorSpace 中的命名颜色空间（如 ICCBased）
+// 返回 nil 表示 Pattern 颜色空间或无法识别的名称
+func resolveColorSpaceName(ctx *RenderContext, name string) ColorSpace {
+	return lookupColorSpace(ctx.Resources, name)
+}
+
+// lookupColorSpace 在资源中解析颜色空间名称，resources 可以为 nil（只解析设备颜色空间）
+func lookupColorSpace(resources *Resources, name string) ColorSpace {
+	if name == "Pattern" {
+		return nil
+	}
+	if cs, err := deviceColorSpaceByName(name); err == nil {
+		return cs
+	}
+	if resources != nil {
+		if cs, ok := resources.GetColorSpace(name).(ColorSpace); ok && cs != nil {
+			return cs
+		}
+	}
+	debugPrintf("Warning: ColorSpace %s not found\n", name)
+	return nil
+}
+
+// initialColorRGB 返回颜色空间的初始颜色（PDF 32000-1 8.6.8）
+// 设备颜色空间均为黑色：Gray/RGB 分量全为 0，CMYK 为 0 0 0 1；Indexed 为索引 0，
+// Separation/DeviceN 为各分量 tint 1.0
+func initialColorRGB(cs ColorSpace) (r, g, b float64) {
+	switch c := cs.(type) {
+	case *IndexedColorSpace:
+		return colorComponentsToRGB(cs, []float64{0})
+	case *DeviceNColorSpace:
+		tints := make([]float64, len(c.Names))
+		for i := range tints {
+			tints[i] = 1
+		}
+		return colorComponentsToRGB(cs, tints)
+	}
+	return 0, 0, 0
+}
+
+// colorComponentsToRGB 将颜色分量按颜色空间转换为 RGB
+// 颜色空间缺失或转换失败时按分量个数回退到设备颜色空间
+func colorComponentsToRGB(cs ColorSpace, components []float64) (r, g, b float64) {
+	if cs != nil {
+		if r, g, b, err := cs.ConvertToRGB(components); err == nil {
+			return r, g, b
+		}
+	}
+
+	switch len(components) {
+	case 1:
+		gray := clamp01(components[0])
+		return gray, gray, gray
+	case 3:
+		return clamp01(components[0]), clamp01(components[1]), clamp01(components[2])
+	case 4:
+		return cmykToRGB(clamp01(components[0]), clamp01(components[1]), clamp01(components[2]), clamp01(components[3]))
+	default:
+		return 0, 0, 0
+	}
+}
+
+// convertColor 将 sc/SC 的颜色分量按颜色空间转换为 RGB
+// DeviceCMYK 使用本次渲染的 CMYK 转换方式，与 k/K 和图像解码一致
+func (rc *RenderContext) convertColor(cs ColorSpace, components []float64) (r, g, b float64) {
+	if _, ok := cs.(*DeviceCMYKColorSpace); ok && len(components) >= 4 {
+		return cmykToRGBWith(rc.CMYKConversion, clamp01(components[0]), clamp01(components[1]),
+			clamp01(components[2]), clamp01(components[3]))
+	}
+	return colorComponentsToRGB(cs, components)
+}
+
+// cmykToRGB 按全局转换方式（SetCMYKConversion）将 CMYK 转换为 RGB
 func cmykToRGB(c, m, y, k float64) (float64, float64, float64) {
-	r := (1 - c) * (1 - k)
-	g := (1 - m) * (1 - k)
-	b := (1 - y) * (1 - k)
-	return r, g, b
+	return cmykToRGBWith(CMYKDefault, c, m, y, k)
 }
 
 // OpIgnore - 忽略的操作符（用于标记内容等）
@@ -630,6 +911,10 @@ type OpPaintShading struct {
 func (op *OpPaintShading) Name() string { return "sh" }
 
 func (op *OpPaintShading) Execute(ctx *RenderContext) error {
+	if !ctx.shouldRender(ContentVectors) {
+		return nil
+	}
+
 	// 从资源中获取 shading
 	shadingObj := ctx.Resources.GetShading(op.ShadingName)
 	if shadingObj == nil {
@@ -654,6 +939,8 @@ func (op *OpPaintShading) Execute(ctx *RenderContext) error {
 		pattern, err = renderer.RenderLinearGradient(shading)
 	} else if shading.IsRadialGradient() {
 		pattern, err = renderer.RenderRadialGradient(shading)
+	} else if shading.IsMesh() {
+		pattern, err = renderer.RenderMesh(shading)
 	} else {
 		debugPrintf("Warning: Unsupported shading type %d\n", shading.ShadingType)
 		return nil
@@ -703,9 +990,13 @@ func (op *OpSetFillPattern) Execute(ctx *RenderContext) error {
 
 	// 创建图案渲染器
 	renderer := NewPatternRenderer(ctx.GopdfCtx)
+	renderer.budget = ctx.budget
 
 	// 应用图案填充
 	if err := renderer.ApplyPatternFill(pattern); err != nil {
+		if ctx.abortOnError(err) {
+			return err
+		}
 		debugPrintf("Warning: Failed to apply pattern fill: %v\n", err)
 		return nil
 	}
@@ -737,9 +1028,13 @@ func (op *OpSetStrokePattern) Execute(ctx *RenderContext) error {
 
 	// 创建图案渲染器
 	renderer := NewPatternRenderer(ctx.GopdfCtx)
+	renderer.budget = ctx.budget
 
 	// 应用图案描边
 	if err := renderer.ApplyPatternStroke(pattern); err != nil {
+		if ctx.abortOnError(err) {
+			return err
+		}
 		debugPrintf("Warning: Failed to apply pattern stroke: %v\n", err)
 		return nil
 	}
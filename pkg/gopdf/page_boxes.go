@@ -0,0 +1,70 @@
+package gopdf
+
+import (
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// PageBoxes 页面的五个边界框，均为 [x1 y1 x2 y2]（已规范化为 x1<x2、y1<y2）
+// 未定义的框按 PDF 规范取默认值：CropBox 默认为 MediaBox，BleedBox、TrimBox、ArtBox 默认为 CropBox
+type PageBoxes struct {
+	MediaBox [4]float64 // 已解析页面树继承
+	CropBox  [4]float64 // 已解析页面树继承
+	BleedBox [4]float64 // 出血框：印刷时裁切前的内容范围
+	TrimBox  [4]float64 // 裁切框：成品页面尺寸
+	ArtBox   [4]float64 // 作品框：有意义内容的范围
+
+	HasCropBox  bool // 页面或其祖先节点定义了 CropBox
+	HasBleedBox bool // 页面定义了 BleedBox
+	HasTrimBox  bool // 页面定义了 TrimBox
+	HasArtBox   bool // 页面定义了 ArtBox
+}
+
+// GetPageBoxes 获取页面的 MediaBox、CropBox、BleedBox、TrimBox 和 ArtBox
+// 印前流程可以用 TrimBox 裁切到成品尺寸；Has* 字段指出哪些框是显式定义的
+func (r *PDFReader) GetPageBoxes(pageNum int) (PageBoxes, error) {
+	ctx, err := r.readContext()
+	if err != nil {
+		return PageBoxes{}, fmt.Errorf("failed to read PDF context: %w", err)
+	}
+	if pageNum < 1 || pageNum > ctx.PageCount {
+		return PageBoxes{}, fmt.Errorf("invalid page number: %d (total pages: %d)", pageNum, ctx.PageCount)
+	}
+
+	pageDict, _, _, err := ctx.PageDict(pageNum, false)
+	if err != nil || pageDict == nil {
+		return PageBoxes{}, fmt.Errorf("failed to get page dict: %w", err)
+	}
+	return readPageBoxes(ctx, pageDict), nil
+}
+
+// readPageBoxes 读取页面的五个边界框，缺失的框使用默认值
+// MediaBox 和 CropBox 可从页面树继承，BleedBox、TrimBox 和 ArtBox 只能定义在页面上
+func readPageBoxes(ctx *model.Context, pageDict types.Dict) PageBoxes {
+	var boxes PageBoxes
+
+	media, ok := getPageBox(ctx, pageDict, "MediaBox")
+	if !ok {
+		media = [4]float64{0, 0, 612, 792} // 默认 Letter 尺寸
+	}
+	boxes.MediaBox = media
+	boxes.CropBox, boxes.HasCropBox = getPageBox(ctx, pageDict, "CropBox")
+	if !boxes.HasCropBox {
+		boxes.CropBox = media
+	}
+
+	pageBox := func(key string) ([4]float64, bool) {
+		if _, found := pageDict.Find(key); found {
+			if box, ok := getPageBox(ctx, pageDict, key); ok {
+				return box, true
+			}
+		}
+		return boxes.CropBox, false
+	}
+	boxes.BleedBox, boxes.HasBleedBox = pageBox("BleedBox")
+	boxes.TrimBox, boxes.HasTrimBox = pageBox("TrimBox")
+	boxes.ArtBox, boxes.HasArtBox = pageBox("ArtBox")
+	return boxes
+}
@@ -0,0 +1,201 @@
+package gopdf
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// maxNumberTreeDepth 数字树递归的最大深度，防止 /Kids 循环引用
+const maxNumberTreeDepth = 32
+
+// maxPageLabelStart /St 的上限，避免与页索引相加时溢出
+const maxPageLabelStart = math.MaxInt32
+
+// maxAlphabeticPageNumber 罗马数字和字母编号的最大页码，更大的页码按十进制输出，
+// 避免异常的 /St 为每页生成巨大的标签字符串
+const maxAlphabeticPageNumber = 100000
+
+// pageLabelRange 页面标签范围（PDF 32000-1 12.4.2，表 159）
+// 从 StartIndex 开始的页面使用同一编号样式，直到下一个范围开始
+type pageLabelRange struct {
+	StartIndex int    // 范围第一页的物理页索引（从 0 开始）
+	Style      string // /S：D、R、r、A、a，空表示只有前缀
+	Prefix     string // /P：标签前缀
+	Start      int    // /St：范围第一页的编号，默认 1
+}
+
+// ExtractPageLabels 读取文档目录中的 /PageLabels，返回每个物理页面的逻辑页码标签
+// 例如前言使用小写罗马数字、正文使用阿拉伯数字时返回 ["i", "ii", "iii", "1", "2", ...]
+// 文档没有 /PageLabels 时按物理页码返回 "1"、"2"……
+func (r *PDFReader) ExtractPageLabels() ([]string, error) {
+	ctx, err := r.readContext()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF context: %w", err)
+	}
+	return readPageLabels(ctx)
+}
+
+// readPageLabels 解析 /PageLabels 数字树并为每一页生成标签
+func readPageLabels(ctx *model.Context) ([]string, error) {
+	var ranges []pageLabelRange
+	if ctx.RootDict != nil {
+		if treeObj, found := ctx.RootDict.Find("PageLabels"); found {
+			var err error
+			ranges, err = collectPageLabelRanges(ctx, treeObj, 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse PageLabels: %w", err)
+			}
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].StartIndex < ranges[j].StartIndex })
+
+	labels := make([]string, ctx.PageCount)
+	next := 0
+	for i := range labels {
+		for next < len(ranges) && ranges[next].StartIndex <= i {
+			next++
+		}
+		if next == 0 {
+			// 第一个范围之前的页面（规范要求从 0 开始，但容错处理）使用物理页码
+			labels[i] = strconv.Itoa(i + 1)
+			continue
+		}
+		rng := ranges[next-1]
+		labels[i] = rng.Prefix + formatPageNumber(rng.Style, rng.Start+i-rng.StartIndex)
+	}
+	return labels, nil
+}
+
+// collectPageLabelRanges 遍历数字树节点，收集叶子节点 /Nums 中的所有标签范围
+func collectPageLabelRanges(ctx *model.Context, nodeObj types.Object, depth int) ([]pageLabelRange, error) {
+	if depth > maxNumberTreeDepth {
+		return nil, fmt.Errorf("number tree nesting too deep")
+	}
+	node, err := ctx.DereferenceDict(nodeObj)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, nil
+	}
+
+	var ranges []pageLabelRange
+	if kidsObj, found := node.Find("Kids"); found {
+		kids, err := ctx.DereferenceArray(kidsObj)
+		if err != nil {
+			return nil, err
+		}
+		for _, kid := range kids {
+			kidRanges, err := collectPageLabelRanges(ctx, kid, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, kidRanges...)
+		}
+	}
+
+	if numsObj, found := node.Find("Nums"); found {
+		nums, err := ctx.DereferenceArray(numsObj)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i+1 < len(nums); i += 2 {
+			key, ok := getInteger(nums[i])
+			if !ok || key < 0 {
+				debugPrintf("⚠️  Skipping invalid page label key: %v\n", nums[i])
+				continue
+			}
+			labelDict, err := ctx.DereferenceDict(nums[i+1])
+			if err != nil || labelDict == nil {
+				debugPrintf("⚠️  Skipping invalid page label dictionary for page index %d\n", key)
+				continue
+			}
+			ranges = append(ranges, parsePageLabel(int(key), labelDict))
+		}
+	}
+
+	return ranges, nil
+}
+
+// parsePageLabel 解析页面标签字典的 /S、/P、/St
+func parsePageLabel(startIndex int, dict types.Dict) pageLabelRange {
+	rng := pageLabelRange{StartIndex: startIndex, Start: 1}
+	if s, found := dict.Find("S"); found {
+		if name, ok := s.(types.Name); ok {
+			rng.Style = name.Value()
+		}
+	}
+	if p, found := dict.Find("P"); found {
+		if prefix, err := types.StringOrHexLiteral(p); err == nil {
+			rng.Prefix = *prefix
+		}
+	}
+	if st, found := dict.Find("St"); found {
+		if n, ok := getInteger(st); ok && n >= 1 {
+			if n > maxPageLabelStart {
+				n = maxPageLabelStart
+			}
+			rng.Start = int(n)
+		}
+	}
+	return rng
+}
+
+// formatPageNumber 按编号样式格式化页码
+// D：十进制；R/r：大/小写罗马数字；A/a：大/小写字母（A..Z、AA..ZZ、AAA……）；其他样式没有数字部分
+// 页码超过 maxAlphabeticPageNumber 时 R/r/A/a 也按十进制输出
+func formatPageNumber(style string, n int) string {
+	if n > maxAlphabeticPageNumber {
+		switch style {
+		case "R", "r", "A", "a":
+			return strconv.Itoa(n)
+		}
+	}
+	switch style {
+	case "D":
+		return strconv.Itoa(n)
+	case "R":
+		return toRoman(n)
+	case "r":
+		return strings.ToLower(toRoman(n))
+	case "A":
+		return toLetters(n)
+	case "a":
+		return strings.ToLower(toLetters(n))
+	default:
+		return ""
+	}
+}
+
+// toRoman 把正整数转换为大写罗马数字，超过 3999 时继续重复 M
+func toRoman(n int) string {
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+
+	var sb strings.Builder
+	for i, v := range values {
+		for n >= v {
+			sb.WriteString(symbols[i])
+			n -= v
+		}
+	}
+	return sb.String()
+}
+
+// toLetters 把正整数转换为字母编号：1..26 为 A..Z，27..52 为 AA..ZZ，依此类推
+func toLetters(n int) string {
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	letter := byte('A' + (n-1)%26)
+	return strings.Repeat(string(letter), (n-1)/26+1)
+}
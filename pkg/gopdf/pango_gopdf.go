@@ -5,12 +5,12 @@ import (
 	"math"
 	"strings"
 	"sync/atomic"
+	"unicode"
 	"unsafe"
 
 	"github.com/go-text/typesetting/di"
 	"github.com/go-text/typesetting/font"
 	"github.com/go-text/typesetting/opentype/api"
-	apifont "github.com/go-text/typesetting/opentype/api/font"
 	"github.com/go-text/typesetting/shaping"
 	"golang.org/x/image/math/fixed"
 )
@@ -61,7 +61,12 @@ type PangoPdfLayout struct {
 	align       PangoAlignment
 	spacing     float64
 	lineSpacing float64
-	userData    map[*UserDataKey]interface{}
+	// letterSpacing is added after every glyph, wordSpacing additionally
+	// after every space (both in user space units)
+	letterSpacing float64
+	wordSpacing   float64
+	attrs         []pangoAttrRun // attribute runs from SetMarkup
+	userData      map[*UserDataKey]interface{}
 }
 
 // PangoPdfContext represents a Pango context integrated with Gopdf
@@ -157,6 +162,7 @@ const (
 	PangoAlignLeft PangoAlignment = iota
 	PangoAlignCenter
 	PangoAlignRight
+	PangoAlignJustify // Stretch inter-word gaps to the layout width (except a paragraph's last line)
 )
 
 const (
@@ -436,6 +442,7 @@ func (l *PangoPdfLayout) Status() Status {
 // Layout property setters and getters
 func (l *PangoPdfLayout) SetText(text string) {
 	l.text = text
+	l.attrs = nil
 }
 
 func (l *PangoPdfLayout) GetText() string {
@@ -506,6 +513,25 @@ func (l *PangoPdfLayout) GetLineSpacing() float64 {
 	return l.lineSpacing
 }
 
+// SetLetterSpacing sets the extra space added after every glyph (tracking)
+func (l *PangoPdfLayout) SetLetterSpacing(spacing float64) {
+	l.letterSpacing = spacing
+}
+
+func (l *PangoPdfLayout) GetLetterSpacing() float64 {
+	return l.letterSpacing
+}
+
+// SetWordSpacing sets the extra space added after every space glyph,
+// on top of the letter spacing
+func (l *PangoPdfLayout) SetWordSpacing(spacing float64) {
+	l.wordSpacing = spacing
+}
+
+func (l *PangoPdfLayout) GetWordSpacing() float64 {
+	return l.wordSpacing
+}
+
 // UserData management for PangoPdfLayout
 func (l *PangoPdfLayout) SetUserData(key *UserDataKey, userData unsafe.Pointer, destroy DestroyFunc) Status {
 	if l.status != StatusSuccess {
@@ -993,18 +1019,8 @@ func (s *PangoPdfScaledFont) GlyphPath(glyphID uint64) (*Path, error) {
 		return nil, newError(StatusFontTypeMismatch, "glyph has no outline")
 	}
 
-	// Convert the outline to Path
-	pdfPath := &Path{
-		Status: StatusSuccess,
-		Data:   make([]PathData, 0),
-	}
-
-	// Check if we need to flip the Y axis based on the font matrix
-	// Font glyphs are designed for Y growing upward, but our coordinate system has Y growing downward.
-	// Since we now use positive Y scale in font matrix, we always need to flip.
-	flipY := true
-
 	// Get font units per em and scale factor for coordinate transformation
+	// Note: The outline coordinates from go-text/typesetting are in font units (float32)
 	unitsPerEm := float64(realFace.Upem())
 	scaleX := math.Hypot(s.fontMatrix.XX, s.fontMatrix.YX)
 	scaleY := math.Hypot(s.fontMatrix.XY, s.fontMatrix.YY)
@@ -1015,83 +1031,8 @@ func (s *PangoPdfScaledFont) GlyphPath(glyphID uint64) (*Path, error) {
 		scaleY = 1.0
 	}
 
-	// Iterate over the path segments
-	// Note: The outline coordinates from go-text/typesetting are in font units (float32)
-	// We need to scale them to user space and preserve the segment types
-	for _, seg := range outline.Segments {
-		var pd PathData
-
-		switch seg.Op {
-		case api.SegmentOpMoveTo:
-			// Convert from font units to user space
-			x := (float64(seg.Args[0].X) / unitsPerEm) * scaleX
-			y := (float64(seg.Args[0].Y) / unitsPerEm) * scaleY
-			// Apply Y flip if needed
-			if flipY {
-				y = -y
-			}
-			pd.Type = PathMoveTo
-			pd.Points = []Point{{X: x, Y: y}}
-
-		case api.SegmentOpLineTo:
-			x := (float64(seg.Args[0].X) / unitsPerEm) * scaleX
-			y := (float64(seg.Args[0].Y) / unitsPerEm) * scaleY
-			// Apply Y flip if needed
-			if flipY {
-				y = -y
-			}
-			pd.Type = PathLineTo
-			pd.Points = []Point{{X: x, Y: y}}
-
-		case api.SegmentOpQuadTo:
-			// Convert quadratic Bezier to cubic Bezier
-			// For a quadratic curve with control point Q and end point P2,
-			// the cubic equivalent has control points:
-			// C1 = current_point + 2/3 * (Q - current_point)
-			// C2 = P2 + 2/3 * (Q - P2)
-			// However, since we don't track current point here, we'll use a simpler conversion
-			x1 := (float64(seg.Args[0].X) / unitsPerEm) * scaleX
-			y1 := (float64(seg.Args[0].Y) / unitsPerEm) * scaleY
-			x2 := (float64(seg.Args[1].X) / unitsPerEm) * scaleX
-			y2 := (float64(seg.Args[1].Y) / unitsPerEm) * scaleY
-			// Apply Y flip if needed
-			if flipY {
-				y1 = -y1
-				y2 = -y2
-			}
-			// Simplified: use the control point twice for cubic conversion
-			pd.Type = PathCurveTo
-			pd.Points = []Point{
-				{X: x1, Y: y1},
-				{X: x1, Y: y1},
-				{X: x2, Y: y2},
-			}
-
-		case api.SegmentOpCubeTo:
-			x1 := (float64(seg.Args[0].X) / unitsPerEm) * scaleX
-			y1 := (float64(seg.Args[0].Y) / unitsPerEm) * scaleY
-			x2 := (float64(seg.Args[1].X) / unitsPerEm) * scaleX
-			y2 := (float64(seg.Args[1].Y) / unitsPerEm) * scaleY
-			x3 := (float64(seg.Args[2].X) / unitsPerEm) * scaleX
-			y3 := (float64(seg.Args[2].Y) / unitsPerEm) * scaleY
-			// Apply Y flip if needed
-			if flipY {
-				y1 = -y1
-				y2 = -y2
-				y3 = -y3
-			}
-			pd.Type = PathCurveTo
-			pd.Points = []Point{
-				{X: x1, Y: y1},
-				{X: x2, Y: y2},
-				{X: x3, Y: y3},
-			}
-		}
-
-		pdfPath.Data = append(pdfPath.Data, pd)
-	}
-
-	return pdfPath, nil
+	// Convert the outline to Path
+	return glyphOutlinePath(outline, scaleX/unitsPerEm, scaleY/unitsPerEm), nil
 }
 
 // GetTextBearingMetrics returns the bearing metrics for a text string
@@ -1112,48 +1053,16 @@ func (s *PangoPdfScaledFont) GetTextAlignmentOffset(alignment TextAlignment) (fl
 	return GetAlignmentOffset(alignment, fontExtents), StatusSuccess
 }
 
-// GetKerning returns the kerning adjustment between two runes
+// GetKerning returns the kerning adjustment between two runes, in user space.
+// It reads the legacy kern/kerx tables and falls back to GPOS pair kerning
+// by shaping the pair. Advances from TextToGlyphs and TextExtents already
+// include this kerning, so it must not be added to them again.
 func (s *PangoPdfScaledFont) GetKerning(r1, r2 rune) (float64, Status) {
 	realFace, status := s.getRealFace()
 	if status != StatusSuccess {
 		return 0, status
 	}
-
-	// Get the glyph indices for the runes
-	gid1, ok1 := realFace.NominalGlyph(r1)
-	gid2, ok2 := realFace.NominalGlyph(r2)
-	if !ok1 || !ok2 {
-		return 0, StatusInvalidGlyph
-	}
-
-	// Check if we have kerning data
-	var kernValue int16
-	if len(realFace.Kern) > 0 {
-		// Try Kern tables first
-		for _, kernSubtable := range realFace.Kern {
-			if kd, ok := kernSubtable.Data.(apifont.Kern0); ok {
-				kernValue = kd.KernPair(gid1, gid2)
-				break
-			}
-		}
-	} else if len(realFace.Kerx) > 0 {
-		// Try Kerx tables if no Kern tables
-		for _, kerxSubtable := range realFace.Kerx {
-			if kd, ok := kerxSubtable.Data.(apifont.Kern0); ok {
-				kernValue = kd.KernPair(gid1, gid2)
-				break
-			}
-		}
-	}
-
-	// Scale factor from font matrix
-	sx := math.Hypot(s.fontMatrix.XX, s.fontMatrix.YX)
-	unitsPerEm := float64(realFace.Upem())
-
-	// Convert kerning value to user space units
-	kerning := float64(kernValue) * sx / unitsPerEm
-
-	return kerning, StatusSuccess
+	return faceKerning(realFace, r1, r2, math.Hypot(s.fontMatrix.XX, s.fontMatrix.YX))
 }
 
 // applyHinting applies font hinting based on the font options
@@ -1367,16 +1276,20 @@ func (s *PangoPdfScaledFont) TextToGlyphsWithOptions(x, y float64, utf8 string,
 		options = NewShapingOptions()
 	}
 
-	// Auto-detect missing options
+	// Auto-detect missing options. The paragraph direction of each line is
+	// resolved by the bidi algorithm below, so only vertical and RTL are kept.
+	baseDirection := options.Direction
 	if options.Direction == TextDirectionAuto {
 		options.Direction = DetectTextDirection(utf8)
 	}
 	if options.Language == "" {
 		options.Language = DetectLanguage(utf8)
 	}
-	if options.Script == "" {
+	autoScript := options.Script == ""
+	if autoScript {
 		options.Script = DetectScript(utf8)
 	}
+	vertical := options.Direction == TextDirectionTTB || options.Direction == TextDirectionBTT
 
 	// Split text into lines, supporting different line ending styles
 	// \r\n (Windows), \n (Unix/Linux/macOS), \r (old Mac)
@@ -1386,55 +1299,78 @@ func (s *PangoPdfScaledFont) TextToGlyphsWithOptions(x, y float64, utf8 string,
 	glyphs = make([]Glyph, 0)
 	clusters = make([]TextCluster, 0)
 	var curY float64
+	var pos int // byte offset of the current line in utf8
+	allRTL := true
 
 	for lineIdx, line := range lines {
+		// The line break belongs to no glyph; it gets a glyph-less cluster
+		pos += len(line)
+		breakLen := lineBreakLen(utf8[pos:])
+		pos += breakLen
+
 		if line == "" {
 			// Empty line, just advance Y
+			clusters = appendLineBreakCluster(clusters, breakLen)
 			curY += lineHeight
 			continue
 		}
 
-		// 1. Shape the text with advanced options
-		// fixed.I() converts an integer to 26.6 fixed point format
+		// 1. Split the line into directional runs (in visual order) and shape
+		// each run with its own direction, so RTL runs come out right to left
 		runes := []rune(line)
-		input := shaping.Input{
-			Text:      runes,
-			RunStart:  0,
-			RunEnd:    len(runes),
-			Direction: convertDirection(options.Direction, line),
-			Face:      realFace,
-			Size:      fixed.I(int(fontSize)), // Convert to 26.6 fixed point
-			Language:  convertLanguage(options.Language),
-			Script:    convertScript(options.Script),
+		runs := bidiVisualRuns(runes, baseDirection)
+		if vertical {
+			runs = []bidiRun{{Start: 0, End: len(runes)}}
 		}
-		output := (&shaping.HarfbuzzShaper{}).Shape(input)
 
 		// 2. Convert shaped output to gopdf's Glyph and TextCluster structures
 		var curX float64
-
-		// Process each glyph with proper spacing
-		for _, g := range output.Glyphs {
-			// Position is in user space, relative to the start point (x, y)
-			glyph := Glyph{
-				Index: uint64(g.GlyphID),
-				X:     x + curX + float64(g.XOffset)/64.0,
-				Y:     y + curY - float64(g.YOffset)/64.0, // Subtract because glyph offsets are in font coordinate system
+		for _, run := range runs {
+			direction := di.DirectionLTR
+			if vertical {
+				direction = convertDirection(options.Direction, line)
+			} else if run.RTL {
+				direction = di.DirectionRTL
+			}
+			script := options.Script
+			if autoScript {
+				script = DetectScript(string(runes[run.Start:run.End]))
 			}
-			glyphs = append(glyphs, glyph)
 
-			// Add the advance width for the next glyph
-			// The shaper returns advances in 26.6 fixed point format
-			curX += float64(g.XAdvance) / 64.0
-		}
+			// fixed.I() converts an integer to 26.6 fixed point format
+			input := shaping.Input{
+				Text:         runes,
+				RunStart:     run.Start,
+				RunEnd:       run.End,
+				Direction:    direction,
+				Face:         realFace,
+				Size:         fixed.I(int(fontSize)), // Convert to 26.6 fixed point
+				Language:     convertLanguage(options.Language),
+				Script:       convertScript(script),
+				FontFeatures: convertFeatures(options.Features),
+			}
+			output := (&shaping.HarfbuzzShaper{}).Shape(input)
+			allRTL = allRTL && direction == di.DirectionRTL
+
+			// Shaped glyphs are in visual order, so runs are laid out left to right
+			for _, g := range output.Glyphs {
+				// Position is in user space, relative to the start point (x, y)
+				glyph := Glyph{
+					Index: uint64(g.GlyphID),
+					X:     x + curX + float64(g.XOffset)/64.0,
+					Y:     y + curY - float64(g.YOffset)/64.0, // Subtract because glyph offsets are in font coordinate system
+				}
+				glyphs = append(glyphs, glyph)
 
-		// Create clusters for this line
-		for range output.Glyphs {
-			cluster := TextCluster{
-				NumBytes:  1, // Simplified: assume 1 byte per glyph
-				NumGlyphs: 1,
+				// Add the advance width for the next glyph
+				// The shaper returns advances in 26.6 fixed point format
+				curX += float64(g.XAdvance) / 64.0
 			}
-			clusters = append(clusters, cluster)
+			// Clusters follow the glyphs, so within an RTL run they step
+			// backward through the text
+			clusters = appendShapedClusters(clusters, line, output.Glyphs)
 		}
+		clusters = appendLineBreakCluster(clusters, breakLen)
 
 		// Move to next line (reset X, advance Y)
 		if lineIdx < len(lines)-1 {
@@ -1442,8 +1378,12 @@ func (s *PangoPdfScaledFont) TextToGlyphsWithOptions(x, y float64, utf8 string,
 		}
 	}
 
-	// Cluster flags (simplified)
-	clusterFlags = 0
+	// A single line shaped entirely right to left has its clusters in
+	// backward text order; mixed-direction or multi-line text cannot be
+	// described by one flag and keeps the forward flag
+	if len(lines) == 1 && lines[0] != "" && allRTL {
+		clusterFlags = TextClusterFlagBackward
+	}
 
 	return glyphs, clusters, clusterFlags, StatusSuccess
 }
@@ -1491,6 +1431,20 @@ func (s *PangoPdfScaledFont) toyTextToGlyphsFallback(x, y float64, utf8 string)
 
 // PangoPdfShowText renders text using PangoPdf directly to the surface
 func PangoPdfShowText(ctx Context, layout *PangoPdfLayout) {
+	pangoPdfLayoutLines(ctx, layout, renderLineGlyphs)
+}
+
+// PangoPdfLayoutPath adds the glyph outlines of the layout to the current path
+// at the current point, without filling (like pango_cairo_layout_path).
+// The caller can then stroke, fill or clip the path.
+func PangoPdfLayoutPath(ctx Context, layout *PangoPdfLayout) {
+	pangoPdfLayoutLines(ctx, layout, pathLineGlyphs)
+}
+
+// pangoPdfLayoutLines shapes each line of the layout at the current point and
+// hands the positioned glyphs to emit
+func pangoPdfLayoutLines(ctx Context, layout *PangoPdfLayout,
+	emit func(ctx Context, sf *PangoPdfScaledFont, glyphs []Glyph)) {
 	if ctx.Status() != StatusSuccess {
 		return
 	}
@@ -1535,28 +1489,52 @@ func PangoPdfShowText(ctx Context, layout *PangoPdfLayout) {
 		lineHeight = layout.fontDesc.size * 1.2 // 120% of font size
 	}
 
-	// Split text into lines
-	text := layout.GetText()
-	lines := strings.Split(text, "\n")
+	// Split text into lines, wrapping paragraphs to the layout width
+	lines := wrapLayoutText(sf, layout)
+
+	// Markup attribute runs each get their own scaled font
+	var styled *pangoStyledFonts
+	if len(layout.attrs) > 0 {
+		styled = newPangoStyledFonts(layout, sf)
+		defer styled.destroy()
+	}
 
 	// Render each line
 	currentY := y
 	for _, line := range lines {
 		// Skip empty lines but still advance Y position
-		if line == "" {
+		if line.text == "" {
 			currentY += lineHeight
 			continue
 		}
 
-		// Perform text shaping to get glyphs for this line
-		glyphs, _, _, status := sf.TextToGlyphs(x, currentY, line)
+		// Perform text shaping to get glyphs for this line; the context's base
+		// direction sets the paragraph direction for the bidi algorithm
+		options := NewShapingOptions()
+		if layout.context != nil && layout.context.baseDir == PangoDirectionRTL {
+			options.Direction = TextDirectionRTL
+		}
+
+		if styled != nil {
+			height, status := showStyledLine(ctx, layout, styled, line, x, currentY, options, emit)
+			if status != StatusSuccess {
+				ctx.(*context).status = status
+				return
+			}
+			currentY += max(lineHeight, height)
+			continue
+		}
+
+		glyphs, _, _, status := sf.TextToGlyphsWithOptions(x, currentY, line.text, options)
 		if status != StatusSuccess {
 			ctx.(*context).status = status
 			return
 		}
 
 		// Render this line's glyphs
-		renderLineGlyphs(ctx, sf, glyphs, layout, x, line)
+		applyLayoutSpacing(sf, glyphs, layout)
+		alignLineGlyphs(sf, glyphs, layout, line)
+		emit(ctx, sf, glyphs)
 
 		// Move to next line
 		currentY += lineHeight
@@ -1564,11 +1542,11 @@ func PangoPdfShowText(ctx Context, layout *PangoPdfLayout) {
 
 	// Update current point to the position after the last line
 	if len(lines) > 0 {
-		lastLine := lines[len(lines)-1]
+		lastLine := lines[len(lines)-1].text
 		if lastLine != "" {
 			extents := sf.TextExtents(lastLine)
 			c := ctx.(*context)
-			c.currentPoint.x = x + extents.XAdvance
+			c.currentPoint.x = x + extents.XAdvance + layoutSpacingExtra(sf, layout, lastLine)
 			c.currentPoint.y = currentY - lineHeight + extents.YAdvance
 			c.currentPoint.hasPoint = true
 		}
@@ -1576,27 +1554,7 @@ func PangoPdfShowText(ctx Context, layout *PangoPdfLayout) {
 }
 
 // renderLineGlyphs renders glyphs for a single line of text
-func renderLineGlyphs(ctx Context, sf *PangoPdfScaledFont, glyphs []Glyph, layout *PangoPdfLayout, x float64, lineText string) {
-
-	// Apply alignment adjustments
-	if layout.align != PangoAlignLeft && layout.width > 0 {
-		// Calculate text width for this line
-		textExtents := sf.TextExtents(lineText)
-		layoutWidth := float64(layout.width) / 1024.0 // Convert from Pango units
-
-		var offsetX float64
-		switch layout.align {
-		case PangoAlignRight:
-			offsetX = layoutWidth - textExtents.Width
-		case PangoAlignCenter:
-			offsetX = (layoutWidth - textExtents.Width) / 2
-		}
-
-		// Adjust all glyph positions
-		for i := range glyphs {
-			glyphs[i].X += offsetX
-		}
-	}
+func renderLineGlyphs(ctx Context, sf *PangoPdfScaledFont, glyphs []Glyph) {
 
 	// Render glyphs directly to surface using PangoPdf
 	c := ctx.(*context)
@@ -1631,39 +1589,7 @@ func renderLineGlyphs(ctx Context, sf *PangoPdfScaledFont, glyphs []Glyph, layou
 
 		// Clear current path and create a new one for this glyph
 		c.NewPath()
-
-		// Translate the glyph path to the correct position and add to context
-		// The glyph path is in font space, we need to translate it to the glyph position
-		pathSegments := 0
-		for _, pathData := range glyphPath.Data {
-			switch pathData.Type {
-			case PathMoveTo:
-				if len(pathData.Points) > 0 {
-					c.MoveTo(pathData.Points[0].X+glyph.X, pathData.Points[0].Y+glyph.Y)
-					pathSegments++
-				}
-			case PathLineTo:
-				if len(pathData.Points) > 0 {
-					c.LineTo(pathData.Points[0].X+glyph.X, pathData.Points[0].Y+glyph.Y)
-					pathSegments++
-				}
-			case PathCurveTo:
-				if len(pathData.Points) >= 3 {
-					c.CurveTo(
-						pathData.Points[0].X+glyph.X, pathData.Points[0].Y+glyph.Y,
-						pathData.Points[1].X+glyph.X, pathData.Points[1].Y+glyph.Y,
-						pathData.Points[2].X+glyph.X, pathData.Points[2].Y+glyph.Y,
-					)
-					pathSegments++
-				}
-			case PathClosePath:
-				c.ClosePath()
-				pathSegments++
-			}
-		}
-
-		// Debug: print glyph info (commented out for production)
-		// fmt.Printf("[DEBUG] Glyph %d at (%.2f, %.2f): added %d path segments\n", glyph.Index, glyph.X, glyph.Y, pathSegments)
+		appendGlyphOutline(c, glyphPath, glyph)
 
 		// Fill the glyph
 		c.Fill()
@@ -1868,7 +1794,7 @@ func (l *PangoPdfLayout) GetPixelExtents() *PangoRectangle {
 	return &PangoRectangle{
 		X:      extents.XBearing,
 		Y:      extents.YBearing,
-		Width:  extents.Width,
+		Width:  extents.Width + layoutSpacingExtra(scaledFont, l, l.text),
 		Height: extents.Height,
 	}
 }
@@ -1895,3 +1821,320 @@ func (l *PangoPdfLayout) GetFontExtents() *FontExtents {
 
 	return scaledFont.Extents()
 }
+
+// pangoUnitsToDouble converts Pango units (1024ths of a point) to user space
+func pangoUnitsToDouble(v int) float64 {
+	return float64(v) / 1024.0
+}
+
+// pangoLayoutLine is one line of a layout after wrapping
+type pangoLayoutLine struct {
+	text         string
+	start        int  // byte offset of text in the layout text
+	paragraphEnd bool // last line of its paragraph (never justified)
+}
+
+// wrapLayoutText splits the layout text into paragraphs at newlines and, when
+// a width is set, breaks each paragraph into lines that fit that width
+func wrapLayoutText(sf *PangoPdfScaledFont, layout *PangoPdfLayout) []pangoLayoutLine {
+	paragraphs := strings.Split(layout.GetText(), "\n")
+	if layout.width <= 0 {
+		lines := make([]pangoLayoutLine, len(paragraphs))
+		start := 0
+		for i, paragraph := range paragraphs {
+			lines[i] = pangoLayoutLine{text: paragraph, start: start, paragraphEnd: true}
+			start += len(paragraph) + 1
+		}
+		return lines
+	}
+
+	maxWidth := pangoUnitsToDouble(layout.width)
+	measure := func(text string) float64 {
+		return sf.TextExtents(text).XAdvance + layoutSpacingExtra(sf, layout, text)
+	}
+	var lines []pangoLayoutLine
+	paragraphStart := 0
+	for _, paragraph := range paragraphs {
+		wrapped := wrapParagraph(measure, paragraph, maxWidth, layout.wrap)
+		// Wrapped lines are consecutive substrings of the paragraph with the
+		// whitespace at breaks dropped
+		offset := 0
+		for i, text := range wrapped {
+			offset += strings.Index(paragraph[offset:], text)
+			lines = append(lines, pangoLayoutLine{
+				text:         text,
+				start:        paragraphStart + offset,
+				paragraphEnd: i == len(wrapped)-1,
+			})
+			offset += len(text)
+		}
+		paragraphStart += len(paragraph) + 1
+	}
+	return lines
+}
+
+// layoutSpacingExtra returns the width the layout's letter and word spacing
+// add to text
+func layoutSpacingExtra(sf *PangoPdfScaledFont, layout *PangoPdfLayout, text string) float64 {
+	if layout.letterSpacing == 0 && layout.wordSpacing == 0 {
+		return 0
+	}
+	glyphs, status := sf.GetGlyphs(text)
+	if status != StatusSuccess {
+		return 0
+	}
+	extra := 0.0
+	spaceGID, hasSpace := sf.spaceGlyph()
+	for _, g := range glyphs {
+		extra += layout.letterSpacing
+		if hasSpace && g.Index == spaceGID {
+			extra += layout.wordSpacing
+		}
+	}
+	return extra
+}
+
+// applyLayoutSpacing shifts a line's glyphs by the layout's letter and word
+// spacing, as if it were added to each glyph advance
+func applyLayoutSpacing(sf *PangoPdfScaledFont, glyphs []Glyph, layout *PangoPdfLayout) {
+	if layout.letterSpacing == 0 && layout.wordSpacing == 0 {
+		return
+	}
+	spaceGID, hasSpace := sf.spaceGlyph()
+	shift := 0.0
+	for i := range glyphs {
+		glyphs[i].X += shift
+		shift += layout.letterSpacing
+		if hasSpace && glyphs[i].Index == spaceGID {
+			shift += layout.wordSpacing
+		}
+	}
+}
+
+// spaceGlyph returns the glyph index of U+0020 in the real font face
+func (s *PangoPdfScaledFont) spaceGlyph() (uint64, bool) {
+	realFace, status := s.getRealFace()
+	if status != StatusSuccess {
+		return 0, false
+	}
+	gid, ok := realFace.NominalGlyph(' ')
+	return uint64(gid), ok
+}
+
+// wrapParagraph greedily breaks one paragraph at the last break opportunity
+// that still fits maxWidth (measured with measure, normally TextExtents).
+//   - PangoWrapWord breaks after whitespace and around CJK characters; a word
+//     wider than the line is left to overflow
+//   - PangoWrapChar breaks between any two characters
+//   - PangoWrapWordChar breaks like PangoWrapWord, falling back to characters
+//     when a single word does not fit
+//
+// Whitespace at a break is dropped from both lines.
+func wrapParagraph(measure func(text string) float64, paragraph string, maxWidth float64, mode PangoWrapMode) []string {
+	runes := []rune(paragraph)
+	fits := func(line []rune) bool {
+		text := strings.TrimRightFunc(string(line), unicode.IsSpace)
+		return measure(text) <= maxWidth
+	}
+	// canBreak reports whether a line may end between runes[i-1] and runes[i]
+	canBreak := func(i int) bool {
+		if mode == PangoWrapChar {
+			return true
+		}
+		return unicode.IsSpace(runes[i-1]) && !unicode.IsSpace(runes[i]) ||
+			isCJKCharacterRune(runes[i-1]) || isCJKCharacterRune(runes[i])
+	}
+
+	var lines []string
+	emit := func(line []rune) {
+		lines = append(lines, strings.TrimRightFunc(string(line), unicode.IsSpace))
+	}
+
+	start := 0
+	for start < len(runes) {
+		lastBreak := -1
+		end := start + 1
+		for ; end <= len(runes); end++ {
+			if end > start+1 && canBreak(end-1) {
+				lastBreak = end - 1
+			}
+			if !fits(runes[start:end]) {
+				break
+			}
+		}
+		if end > len(runes) {
+			// The rest of the paragraph fits
+			emit(runes[start:])
+			break
+		}
+
+		// runes[start:end] overflows; pick where to break
+		switch {
+		case lastBreak > start:
+			end = lastBreak
+		case mode == PangoWrapWord:
+			// No break opportunity yet: overflow up to the next one
+			for end < len(runes) && !canBreak(end) {
+				end++
+			}
+		default:
+			// Character fallback; always keep at least one character per line
+			end--
+			if end == start {
+				end = start + 1
+			}
+		}
+		emit(runes[start:end])
+
+		start = end
+		for start < len(runes) && unicode.IsSpace(runes[start]) {
+			start++
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// glyphRun is a run of positioned glyphs shaped with one scaled font
+type glyphRun struct {
+	sf     *PangoPdfScaledFont
+	glyphs []Glyph
+}
+
+// effectiveAlignment returns the layout alignment relative to the paragraph
+// direction: like Pango, right-to-left paragraphs start from the right edge
+func (l *PangoPdfLayout) effectiveAlignment() PangoAlignment {
+	align := l.align
+	if l.context != nil && l.context.baseDir == PangoDirectionRTL {
+		switch align {
+		case PangoAlignLeft:
+			align = PangoAlignRight
+		case PangoAlignRight:
+			align = PangoAlignLeft
+		}
+	}
+	return align
+}
+
+// alignLineGlyphs shifts the glyphs of a line according to the layout alignment
+func alignLineGlyphs(sf *PangoPdfScaledFont, glyphs []Glyph, layout *PangoPdfLayout, line pangoLayoutLine) {
+	if layout.effectiveAlignment() == PangoAlignLeft || layout.width <= 0 {
+		return
+	}
+
+	// Calculate text width for this line
+	textWidth := sf.TextExtents(line.text).Width + layoutSpacingExtra(sf, layout, line.text)
+	alignGlyphRuns(layout, line, textWidth, glyphRun{sf: sf, glyphs: glyphs})
+}
+
+// alignGlyphRuns shifts the glyph runs of a line that is textWidth wide
+// according to the layout alignment
+func alignGlyphRuns(layout *PangoPdfLayout, line pangoLayoutLine, textWidth float64, runs ...glyphRun) {
+	align := layout.effectiveAlignment()
+	if align == PangoAlignLeft || layout.width <= 0 {
+		return
+	}
+	layoutWidth := pangoUnitsToDouble(layout.width)
+
+	if align == PangoAlignJustify {
+		if !line.paragraphEnd {
+			justifyGlyphRuns(runs, layoutWidth-textWidth)
+		}
+		return
+	}
+
+	var offsetX float64
+	switch align {
+	case PangoAlignRight:
+		offsetX = layoutWidth - textWidth
+	case PangoAlignCenter:
+		offsetX = (layoutWidth - textWidth) / 2
+	}
+
+	// Adjust all glyph positions
+	for _, run := range runs {
+		for i := range run.glyphs {
+			run.glyphs[i].X += offsetX
+		}
+	}
+}
+
+// justifyGlyphRuns spreads slack evenly over the inter-word gaps of a line
+// (the space glyphs), shifting every glyph after a gap
+func justifyGlyphRuns(runs []glyphRun, slack float64) {
+	if slack <= 0 {
+		return
+	}
+
+	isSpace := func(run glyphRun, g Glyph) bool {
+		spaceGID, ok := run.sf.spaceGlyph()
+		return ok && g.Index == spaceGID
+	}
+	gaps := 0
+	for _, run := range runs {
+		for _, g := range run.glyphs {
+			if isSpace(run, g) {
+				gaps++
+			}
+		}
+	}
+	if gaps == 0 {
+		return
+	}
+
+	perGap := slack / float64(gaps)
+	shift := 0.0
+	for _, run := range runs {
+		for i := range run.glyphs {
+			run.glyphs[i].X += shift
+			if isSpace(run, run.glyphs[i]) {
+				shift += perGap
+			}
+		}
+	}
+}
+
+// pathLineGlyphs adds the outlines of a line's glyphs to the current path
+func pathLineGlyphs(ctx Context, sf *PangoPdfScaledFont, glyphs []Glyph) {
+
+	c := ctx.(*context)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, glyph := range glyphs {
+		glyphPath, err := sf.GlyphPath(glyph.Index)
+		if err != nil || glyphPath == nil {
+			continue
+		}
+		appendGlyphOutline(c, glyphPath, glyph)
+	}
+}
+
+// appendGlyphOutline adds a glyph path to the current path.
+// The glyph path is in font space, so it is translated to the glyph position.
+func appendGlyphOutline(c *context, glyphPath *Path, glyph Glyph) {
+	for _, pathData := range glyphPath.Data {
+		switch pathData.Type {
+		case PathMoveTo:
+			if len(pathData.Points) > 0 {
+				c.MoveTo(pathData.Points[0].X+glyph.X, pathData.Points[0].Y+glyph.Y)
+			}
+		case PathLineTo:
+			if len(pathData.Points) > 0 {
+				c.LineTo(pathData.Points[0].X+glyph.X, pathData.Points[0].Y+glyph.Y)
+			}
+		case PathCurveTo:
+			if len(pathData.Points) >= 3 {
+				c.CurveTo(
+					pathData.Points[0].X+glyph.X, pathData.Points[0].Y+glyph.Y,
+					pathData.Points[1].X+glyph.X, pathData.Points[1].Y+glyph.Y,
+					pathData.Points[2].X+glyph.X, pathData.Points[2].Y+glyph.Y,
+				)
+			}
+		case PathClosePath:
+			c.ClosePath()
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package gopdf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// pangoAttrRun is a span of layout text (byte offsets [start, end)) with the
+// attributes set by markup
+type pangoAttrRun struct {
+	start, end int
+	pangoTextStyle
+}
+
+// pangoTextStyle holds the attributes a markup element can set
+type pangoTextStyle struct {
+	bold     bool
+	italic   bool
+	size     float64 // font size in points; 0 uses the layout font size
+	color    Color   // foreground colour, used when hasColor is set
+	hasColor bool
+}
+
+// SetMarkup sets the layout text from a subset of Pango markup:
+// <b>, <i> and <span> with the foreground (fgcolor, color) and size
+// attributes. Sizes are in 1024ths of a point or written as "12pt"; colours
+// are "#rgb", "#rrggbb" or a basic colour name.
+// Like pango_layout_set_markup, invalid markup leaves the layout unchanged.
+// Line wrapping still measures with the layout's font description.
+func (l *PangoPdfLayout) SetMarkup(markup string) {
+	text, attrs, err := parsePangoMarkup(markup)
+	if err != nil {
+		debugPrintf("[PangoPdfLayout] invalid markup: %v\n", err)
+		return
+	}
+	l.text = text
+	l.attrs = attrs
+}
+
+// parsePangoMarkup parses markup into plain text and attribute runs.
+// Text outside any element gets no run.
+func parsePangoMarkup(markup string) (string, []pangoAttrRun, error) {
+	decoder := xml.NewDecoder(strings.NewReader("<markup>" + markup + "</markup>"))
+
+	var text strings.Builder
+	var runs []pangoAttrRun
+	var stack []pangoTextStyle
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to parse markup: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "markup" && stack == nil {
+				stack = []pangoTextStyle{{}}
+				continue
+			}
+			style := stack[len(stack)-1]
+			switch t.Name.Local {
+			case "b":
+				style.bold = true
+			case "i":
+				style.italic = true
+			case "span":
+				if err := applySpanAttributes(&style, t.Attr); err != nil {
+					return "", nil, err
+				}
+			default:
+				return "", nil, fmt.Errorf("unsupported markup element <%s>", t.Name.Local)
+			}
+			stack = append(stack, style)
+
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+
+		case xml.CharData:
+			if len(t) == 0 {
+				continue
+			}
+			start := text.Len()
+			text.Write(t)
+			style := stack[len(stack)-1]
+			if style == (pangoTextStyle{}) {
+				continue
+			}
+			// Merge with the previous run when the attributes continue
+			if n := len(runs); n > 0 && runs[n-1].end == start && runs[n-1].pangoTextStyle == style {
+				runs[n-1].end = text.Len()
+				continue
+			}
+			runs = append(runs, pangoAttrRun{start: start, end: text.Len(), pangoTextStyle: style})
+		}
+	}
+	return text.String(), runs, nil
+}
+
+// applySpanAttributes applies the attributes of a <span> element to style
+func applySpanAttributes(style *pangoTextStyle, attrs []xml.Attr) error {
+	for _, attr := range attrs {
+		switch attr.Name.Local {
+		case "foreground", "fgcolor", "color":
+			c, ok := parseMarkupColor(attr.Value)
+			if !ok {
+				return fmt.Errorf("invalid color %q", attr.Value)
+			}
+			style.color, style.hasColor = c, true
+		case "size":
+			size, ok := parseMarkupSize(attr.Value)
+			if !ok {
+				return fmt.Errorf("invalid size %q", attr.Value)
+			}
+			style.size = size
+		default:
+			return fmt.Errorf("unsupported span attribute %q", attr.Name.Local)
+		}
+	}
+	return nil
+}
+
+// parseMarkupSize parses a span size: 1024ths of a point, or points with a
+// "pt" suffix
+func parseMarkupSize(value string) (float64, bool) {
+	if pt, ok := strings.CutSuffix(value, "pt"); ok {
+		size, err := strconv.ParseFloat(pt, 64)
+		return size, err == nil && size > 0
+	}
+	units, err := strconv.Atoi(value)
+	if err != nil || units <= 0 {
+		return 0, false
+	}
+	return pangoUnitsToDouble(units), true
+}
+
+// markupColorNames are the colour names accepted in markup
+var markupColorNames = map[string]Color{
+	"black":   {0, 0, 0, 1},
+	"white":   {1, 1, 1, 1},
+	"red":     {1, 0, 0, 1},
+	"green":   {0, 0.5, 0, 1},
+	"lime":    {0, 1, 0, 1},
+	"blue":    {0, 0, 1, 1},
+	"yellow":  {1, 1, 0, 1},
+	"cyan":    {0, 1, 1, 1},
+	"magenta": {1, 0, 1, 1},
+	"gray":    {0.5, 0.5, 0.5, 1},
+	"grey":    {0.5, 0.5, 0.5, 1},
+	"orange":  {1, 0.647, 0, 1},
+	"purple":  {0.5, 0, 0.5, 1},
+}
+
+// parseMarkupColor parses "#rgb", "#rrggbb" or a colour name
+func parseMarkupColor(value string) (Color, bool) {
+	if c, ok := markupColorNames[strings.ToLower(value)]; ok {
+		return c, true
+	}
+	hex, ok := strings.CutPrefix(value, "#")
+	if !ok || (len(hex) != 3 && len(hex) != 6) {
+		return Color{}, false
+	}
+	digits := len(hex) / 3
+	var rgb [3]float64
+	for i := range rgb {
+		v, err := strconv.ParseUint(hex[i*digits:(i+1)*digits], 16, 8)
+		if err != nil {
+			return Color{}, false
+		}
+		if digits == 1 {
+			v *= 17 // #f00 == #ff0000
+		}
+		rgb[i] = float64(v) / 255
+	}
+	return Color{R: rgb[0], G: rgb[1], B: rgb[2], A: 1}, true
+}
+
+// pangoStyledFonts caches the scaled fonts used for a layout's attribute runs
+type pangoStyledFonts struct {
+	layout *PangoPdfLayout
+	base   *PangoPdfScaledFont
+	fonts  map[pangoTextStyle]*PangoPdfScaledFont
+}
+
+func newPangoStyledFonts(layout *PangoPdfLayout, base *PangoPdfScaledFont) *pangoStyledFonts {
+	return &pangoStyledFonts{layout: layout, base: base, fonts: make(map[pangoTextStyle]*PangoPdfScaledFont)}
+}
+
+// get returns the scaled font for style; plain text uses the layout's font
+func (f *pangoStyledFonts) get(style pangoTextStyle) *PangoPdfScaledFont {
+	if !style.bold && !style.italic && style.size == 0 {
+		return f.base
+	}
+	key := pangoTextStyle{bold: style.bold, italic: style.italic, size: style.size}
+	if sf, ok := f.fonts[key]; ok {
+		return sf
+	}
+
+	slant, weight := FontSlantNormal, FontWeightNormal
+	if style.italic {
+		slant = FontSlantItalic
+	}
+	if style.bold {
+		weight = FontWeightBold
+	}
+	size := style.size
+	if size == 0 {
+		size = f.layout.fontDesc.size
+	}
+
+	fontFace := NewPangoPdfFont(f.layout.fontDesc.family, slant, weight)
+	defer fontFace.Destroy()
+	fontMatrix := NewMatrix()
+	fontMatrix.InitScale(size, size)
+	ctm := NewMatrix()
+	ctm.InitIdentity()
+
+	sf := NewPangoPdfScaledFont(fontFace, fontMatrix, ctm, nil)
+	f.fonts[key] = sf
+	return sf
+}
+
+func (f *pangoStyledFonts) destroy() {
+	for _, sf := range f.fonts {
+		sf.Destroy()
+	}
+}
+
+// lineStyles splits a layout line into runs of uniform attributes, with byte
+// offsets relative to the line text
+func (l *PangoPdfLayout) lineStyles(line pangoLayoutLine) []pangoAttrRun {
+	lineEnd := line.start + len(line.text)
+	var runs []pangoAttrRun
+	pos := line.start
+	add := func(end int, style pangoTextStyle) {
+		if end > pos {
+			runs = append(runs, pangoAttrRun{start: pos - line.start, end: end - line.start, pangoTextStyle: style})
+			pos = end
+		}
+	}
+	for _, attr := range l.attrs {
+		if attr.end <= pos || attr.start >= lineEnd {
+			continue
+		}
+		add(attr.start, pangoTextStyle{})
+		end := attr.end
+		if end > lineEnd {
+			end = lineEnd
+		}
+		add(end, attr.pangoTextStyle)
+	}
+	add(lineEnd, pangoTextStyle{})
+	return runs
+}
+
+// showStyledLine lays out one line of a layout with markup attributes: each
+// attribute run is shaped with its own font and drawn in its own colour.
+// It returns the line height needed by the largest font on the line.
+func showStyledLine(ctx Context, layout *PangoPdfLayout, fonts *pangoStyledFonts, line pangoLayoutLine,
+	x, y float64, options *ShapingOptions, emit func(ctx Context, sf *PangoPdfScaledFont, glyphs []Glyph)) (float64, Status) {
+	styles := layout.lineStyles(line)
+	runs := make([]glyphRun, len(styles))
+
+	curX := x
+	height := 0.0
+	for i, style := range styles {
+		sf := fonts.get(style.pangoTextStyle)
+		text := line.text[style.start:style.end]
+		glyphs, _, _, status := sf.TextToGlyphsWithOptions(curX, y, text, options)
+		if status != StatusSuccess {
+			return 0, status
+		}
+		applyLayoutSpacing(sf, glyphs, layout)
+		runs[i] = glyphRun{sf: sf, glyphs: glyphs}
+
+		curX += sf.TextExtents(text).XAdvance + layoutSpacingExtra(sf, layout, text)
+		height = max(height, sf.Extents().Height)
+	}
+
+	// Runs are laid out by advance; the line ends at the last run's ink edge
+	textWidth := curX - x
+	if n := len(styles); n > 0 {
+		last := runs[n-1].sf
+		lastText := line.text[styles[n-1].start:styles[n-1].end]
+		textWidth += last.TextExtents(lastText).Width - last.TextExtents(lastText).XAdvance
+	}
+	alignGlyphRuns(layout, line, textWidth, runs...)
+
+	for i, run := range runs {
+		if !styles[i].hasColor {
+			emit(ctx, run.sf, run.glyphs)
+			continue
+		}
+		c := styles[i].color
+		ctx.Save()
+		ctx.SetSourceRGBA(c.R, c.G, c.B, c.A)
+		emit(ctx, run.sf, run.glyphs)
+		ctx.Restore()
+	}
+	return height, StatusSuccess
+}
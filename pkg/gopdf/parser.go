@@ -1,186 +1,442 @@
 package gopdf
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 )
 
 // ParseContentStream 解析 PDF 内容流
 func ParseContentStream(stream []byte) ([]PDFOperator, error) {
-	content := string(stream)
-	tokens := tokenize(content)
-	return parseTokens(tokens)
+	return collectOperators([]io.Reader{bytes.NewReader(stream)})
 }
 
-// tokenize 将内容流分词
-func tokenize(content string) []string {
-	var tokens []string
-	var current strings.Builder
-	inString := false
-	inHexString := false
-	escape := false
+// ParseContentStreams 把页面 /Contents 数组中的多个内容流作为一个整体解析
+// 流之间插入换行作为分隔符；不规范的文件可能把字符串跨流拆开，
+// 此时分隔符落在字符串内部，分词时会被丢弃，不会出现在字符串内容中
+func ParseContentStreams(streams [][]byte) ([]PDFOperator, error) {
+	return collectOperators(contentStreamReaders(streams))
+}
 
-	for i := 0; i < len(content); i++ {
-		ch := content[i]
+// ParseContentStreamReader 增量解析内容流：逐块读取 r 并分词，每解析出一个操作符就调用 emit，
+// 不构建完整的 token 和操作符切片，适合数十 MB 的大型矢量内容
+// emit 返回错误时停止解析并返回该错误
+func ParseContentStreamReader(r io.Reader, emit func(PDFOperator) error) error {
+	return parseContentSources([]io.Reader{r}, emit)
+}
 
-		if escape {
-			current.WriteByte(ch)
-			escape = false
-			continue
+// contentStreamReaders 为每个内容流创建一个读取器
+func contentStreamReaders(streams [][]byte) []io.Reader {
+	sources := make([]io.Reader, len(streams))
+	for i, stream := range streams {
+		sources[i] = bytes.NewReader(stream)
+	}
+	return sources
+}
+
+// collectOperators 解析所有内容源并收集操作符
+func collectOperators(sources []io.Reader) ([]PDFOperator, error) {
+	var operators []PDFOperator
+	err := parseContentSources(sources, func(op PDFOperator) error {
+		operators = append(operators, op)
+		return nil
+	})
+	return operators, err
+}
+
+// parseContentSources 依次读取多个内容源，边分词边构建操作符
+func parseContentSources(sources []io.Reader, emit func(PDFOperator) error) error {
+	lexer := newContentLexer(sources)
+	var builder operatorBuilder
+	for {
+		token, err := lexer.next()
+		if err == io.EOF {
+			break
 		}
+		if err != nil {
+			return fmt.Errorf("failed to read content stream: %w", err)
+		}
+		if err := builder.feed(token, emit); err != nil {
+			return err
+		}
+	}
+	return builder.flush(emit)
+}
 
-		if inString {
-			current.WriteByte(ch)
-			if ch == '\\' {
-				escape = true
-			} else if ch == ')' {
-				inString = false
-				tokens = append(tokens, current.String())
-				current.Reset()
-			}
-			continue
+// inlineImageDataToken 内联图像数据 token 的前缀，后面紧跟 ID 与 EI 之间的原始字节
+// （以 NUL 开头，不会与内容流中的普通 token 冲突）
+const inlineImageDataToken = "\x00ID:"
+
+// contentLexer 内容流分词器，按字节读取，一次产生一个 token
+// 多个内容源之间视为有一个换行分隔符，位于字符串内部的分隔符被丢弃
+type contentLexer struct {
+	sources []io.Reader   // 尚未读取的内容源
+	r       *bufio.Reader // 当前内容源，nil 表示已读完
+	pending []string      // 已分出、尚未返回的 token
+
+	current       []byte
+	inString      bool
+	stringDepth   int // 字符串中未转义括号的嵌套深度，如 (a (b) c)
+	inHexString   bool
+	escape        bool
+	inInlineImage bool // 位于 BI 与 ID 之间
+}
+
+func newContentLexer(sources []io.Reader) *contentLexer {
+	lx := &contentLexer{sources: sources}
+	lx.advance()
+	return lx
+}
+
+// advance 切换到下一个内容源
+func (lx *contentLexer) advance() {
+	lx.r = nil
+	if len(lx.sources) > 0 {
+		lx.r = bufio.NewReader(lx.sources[0])
+		lx.sources = lx.sources[1:]
+	}
+}
+
+// readByte 读取下一个字节；boundary 表示这是内容源之间的分隔换行
+func (lx *contentLexer) readByte() (ch byte, boundary bool, err error) {
+	if lx.r == nil {
+		return 0, false, io.EOF
+	}
+	ch, err = lx.r.ReadByte()
+	if err == io.EOF {
+		lx.advance()
+		if lx.r == nil {
+			return 0, false, io.EOF
 		}
+		return '\n', true, nil
+	}
+	return ch, false, err
+}
 
-		if inHexString {
-			current.WriteByte(ch)
-			if ch == '>' {
-				inHexString = false
-				tokens = append(tokens, current.String())
-				current.Reset()
+// peekByte 查看下一个字节但不读取，ok 为 false 表示已到末尾
+func (lx *contentLexer) peekByte() (byte, bool) {
+	if lx.r == nil {
+		return 0, false
+	}
+	if b, err := lx.r.Peek(1); err == nil {
+		return b[0], true
+	}
+	if len(lx.sources) > 0 {
+		return '\n', true
+	}
+	return 0, false
+}
+
+// emit 把 token 加入待返回队列
+func (lx *contentLexer) emit(token string) {
+	lx.pending = append(lx.pending, token)
+}
+
+// flushCurrent 结束当前 token
+func (lx *contentLexer) flushCurrent() {
+	if len(lx.current) > 0 {
+		lx.emit(string(lx.current))
+		lx.current = lx.current[:0]
+	}
+}
+
+// next 返回下一个 token，读完时返回 io.EOF
+func (lx *contentLexer) next() (string, error) {
+	for len(lx.pending) == 0 {
+		if err := lx.step(); err != nil {
+			if err != io.EOF {
+				return "", err
+			}
+			lx.flushCurrent()
+			if len(lx.pending) == 0 {
+				return "", io.EOF
 			}
-			continue
 		}
+	}
+	token := lx.pending[0]
+	lx.pending = lx.pending[1:]
+	return token, nil
+}
+
+// step 处理一个字节
+func (lx *contentLexer) step() error {
+	ch, boundary, err := lx.readByte()
+	if err != nil {
+		return err
+	}
+
+	if (lx.inString || lx.inHexString) && boundary {
+		return nil
+	}
 
+	if lx.escape {
+		lx.current = append(lx.current, ch)
+		lx.escape = false
+		return nil
+	}
+
+	if lx.inString {
+		lx.current = append(lx.current, ch)
 		switch ch {
+		case '\\':
+			lx.escape = true
 		case '(':
-			if current.Len() > 0 {
-				tokens = append(tokens, current.String())
-				current.Reset()
-			}
-			inString = true
-			current.WriteByte(ch)
-
-		case '<':
-			if i+1 < len(content) && content[i+1] == '<' {
-				if current.Len() > 0 {
-					tokens = append(tokens, current.String())
-					current.Reset()
-				}
-				tokens = append(tokens, "<<")
-				i++
-			} else {
-				if current.Len() > 0 {
-					tokens = append(tokens, current.String())
-					current.Reset()
-				}
-				inHexString = true
-				current.WriteByte(ch)
+			lx.stringDepth++
+		case ')':
+			lx.stringDepth--
+			if lx.stringDepth == 0 {
+				lx.inString = false
+				lx.flushCurrent()
 			}
+		}
+		return nil
+	}
 
-		case '>':
-			if i+1 < len(content) && content[i+1] == '>' {
-				if current.Len() > 0 {
-					tokens = append(tokens, current.String())
-					current.Reset()
-				}
-				tokens = append(tokens, ">>")
-				i++
-			}
+	if lx.inHexString {
+		lx.current = append(lx.current, ch)
+		if ch == '>' {
+			lx.inHexString = false
+			lx.flushCurrent()
+		}
+		return nil
+	}
 
-		case '[', ']':
-			if current.Len() > 0 {
-				tokens = append(tokens, current.String())
-				current.Reset()
-			}
-			tokens = append(tokens, string(ch))
+	switch ch {
+	case '(':
+		lx.flushCurrent()
+		lx.inString = true
+		lx.stringDepth = 1
+		lx.current = append(lx.current, ch)
+
+	case '<':
+		lx.flushCurrent()
+		if next, ok := lx.peekByte(); ok && next == '<' {
+			lx.readByte()
+			lx.emit("<<")
+		} else {
+			lx.inHexString = true
+			lx.current = append(lx.current, ch)
+		}
 
-		case ' ', '\t', '\r', '\n':
-			// 只在非字符串上下文中作为分隔符
-			if current.Len() > 0 {
-				tokens = append(tokens, current.String())
-				current.Reset()
-			}
+	case '>':
+		if next, ok := lx.peekByte(); ok && next == '>' {
+			lx.flushCurrent()
+			lx.readByte()
+			lx.emit(">>")
+		}
 
-		default:
-			current.WriteByte(ch)
+	case '[', ']':
+		lx.flushCurrent()
+		lx.emit(string(ch))
+
+	case ' ', '\t', '\r', '\n':
+		// 只在非字符串上下文中作为分隔符
+		if len(lx.current) == 0 {
+			return nil
 		}
+		token := string(lx.current)
+		lx.flushCurrent()
+
+		switch {
+		case token == "BI":
+			lx.inInlineImage = true
+		case token == "ID" && lx.inInlineImage:
+			// ID 后紧跟一个空白字符（即当前字符），之后是二进制数据，直到 EI
+			lx.inInlineImage = false
+			return lx.readInlineImageData()
+		}
+
+	default:
+		lx.current = append(lx.current, ch)
 	}
+	return nil
+}
+
+// readInlineImageData 读取 ID 之后的内联图像数据，直到结束数据的 EI
+// EI 前后必须是空白（或位于数据末尾），以免把二进制数据中的 "EI" 误认为结束标记
+func (lx *contentLexer) readInlineImageData() error {
+	var data []byte
+	for {
+		ch, _, err := lx.readByte()
+		if err == io.EOF {
+			// 没有 EI：剩余内容都是图像数据
+			lx.emit(inlineImageDataToken + string(trimInlineImageData(data)))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		data = append(data, ch)
 
-	if current.Len() > 0 {
-		tokens = append(tokens, current.String())
+		n := len(data)
+		if n < 2 || data[n-2] != 'E' || data[n-1] != 'I' || (n > 2 && !isPDFWhitespace(data[n-3])) {
+			continue
+		}
+		if next, ok := lx.peekByte(); ok && !isPDFWhitespace(next) {
+			continue
+		}
+		lx.emit(inlineImageDataToken + string(trimInlineImageData(data[:n-2])))
+		lx.emit("EI")
+		return nil
 	}
+}
+
+// trimInlineImageData 去掉 EI 前的一个空白，它不属于图像数据
+func trimInlineImageData(data []byte) []byte {
+	if n := len(data); n > 0 && isPDFWhitespace(data[n-1]) {
+		return data[:n-1]
+	}
+	return data
+}
 
-	return tokens
+// isPDFWhitespace 判断是否为 PDF 空白字符
+func isPDFWhitespace(ch byte) bool {
+	switch ch {
+	case ' ', '\t', '\r', '\n', '\f', 0:
+		return true
+	}
+	return false
 }
 
 // ParseTokens 解析 token 为操作符（导出供测试使用）
 func ParseTokens(tokens []string) ([]PDFOperator, error) {
-	return parseTokens(tokens)
+	var operators []PDFOperator
+	collect := func(op PDFOperator) error {
+		operators = append(operators, op)
+		return nil
+	}
+
+	var builder operatorBuilder
+	for _, token := range tokens {
+		if err := builder.feed(token, collect); err != nil {
+			return nil, err
+		}
+	}
+	if err := builder.flush(collect); err != nil {
+		return nil, err
+	}
+	return operators, nil
 }
 
-// parseTokens 解析 token 为操作符
-func parseTokens(tokens []string) ([]PDFOperator, error) {
-	var operators []PDFOperator
-	var stack []interface{}
+// operatorBuilder 把 token 逐个组装为操作符
+// 操作数压入栈中，遇到操作符名称时创建操作符并清空栈；
+// [ ... ] 和 << ... >> 分别收集为数组和字典操作数
+type operatorBuilder struct {
+	stack []interface{}
 
-	for i := 0; i < len(tokens); i++ {
-		token := tokens[i]
+	inArray bool
+	array   []interface{}
 
-		if token == "" {
-			continue
+	inDict  bool
+	dict    map[string]interface{}
+	dictKey string
+	hasKey  bool
+
+	// inlineImage 等待图像数据的 ID 操作符，数据 token 到达后才发出
+	inlineImage *OpInlineImageData
+}
+
+// feed 处理一个 token，产生的操作符交给 emit
+func (b *operatorBuilder) feed(token string, emit func(PDFOperator) error) error {
+	if token == "" {
+		return nil
+	}
+
+	// ID 之后的原始图像数据
+	if b.inlineImage != nil {
+		op := b.inlineImage
+		b.inlineImage = nil
+		if strings.HasPrefix(token, inlineImageDataToken) {
+			op.ImageData = []byte(token[len(inlineImageDataToken):])
+			return emit(op)
 		}
+		if err := emit(op); err != nil {
+			return err
+		}
+	}
 
-		if token == "[" {
-			array := []interface{}{}
-			i++
-			for i < len(tokens) && tokens[i] != "]" {
-				if val := parseValue(tokens[i]); val != nil {
-					array = append(array, val)
-				}
-				i++
-			}
-			stack = append(stack, array)
-			continue
+	if b.inArray {
+		if token == "]" {
+			b.inArray = false
+			b.stack = append(b.stack, b.array)
+			b.array = nil
+		} else if val := parseValue(token); val != nil {
+			b.array = append(b.array, val)
 		}
+		return nil
+	}
 
-		if token == "<<" {
-			dict := make(map[string]interface{})
-			i++
-			for i < len(tokens) && tokens[i] != ">>" {
-				if i+1 < len(tokens) {
-					key := tokens[i]
-					i++
-					value := parseValue(tokens[i])
-					dict[key] = value
-				}
-				i++
-			}
-			stack = append(stack, dict)
-			continue
+	if b.inDict {
+		switch {
+		case b.hasKey:
+			b.dict[b.dictKey] = parseValue(token)
+			b.hasKey = false
+		case token == ">>":
+			b.inDict = false
+			b.stack = append(b.stack, b.dict)
+			b.dict = nil
+		default:
+			b.dictKey = token
+			b.hasKey = true
 		}
+		return nil
+	}
 
-		if op := createOperator(token, stack); op != nil {
-			operators = append(operators, op)
-			stack = nil
-		} else {
-			if val := parseValue(token); val != nil {
-				stack = append(stack, val)
-			}
+	switch {
+	case token == "[":
+		b.inArray = true
+		b.array = []interface{}{}
+		return nil
+	case token == "<<":
+		b.inDict = true
+		b.dict = make(map[string]interface{})
+		return nil
+	case strings.HasPrefix(token, inlineImageDataToken):
+		// 没有对应 ID 的图像数据
+		return nil
+	}
+
+	if op := createOperator(token, b.stack); op != nil {
+		b.stack = nil
+		if id, ok := op.(*OpInlineImageData); ok {
+			b.inlineImage = id
+			return nil
 		}
+		return emit(op)
+	}
+	if val := parseValue(token); val != nil {
+		b.stack = append(b.stack, val)
 	}
+	return nil
+}
 
-	return operators, nil
+// flush 发出仍在等待图像数据的 ID 操作符
+func (b *operatorBuilder) flush(emit func(PDFOperator) error) error {
+	if b.inlineImage == nil {
+		return nil
+	}
+	op := b.inlineImage
+	b.inlineImage = nil
+	return emit(op)
 }
 
+// HexString 内容流中的十六进制字符串，保留原始形式 <...>
+// 字面量字符串 (...) 解析为去掉括号并处理转义后的 string，
+// 两者需要区分：十六进制字符串通常是 2 字节的 CID，字面量字符串按单字节字符码处理
+type HexString string
+
 // parseValue 解析值
 func parseValue(token string) interface{} {
 	if strings.HasPrefix(token, "(") && strings.HasSuffix(token, ")") {
-		return token[1 : len(token)-1]
+		// 字符串字面量：移除括号并处理转义序列
+		return unescapePDFString(token[1 : len(token)-1])
 	}
 
 	if strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">") {
-		return token
+		return HexString(token)
 	}
 
 	if strings.HasPrefix(token, "/") {
@@ -343,6 +599,24 @@ func createOperator(name string, args []interface{}) PDFOperator {
 				Y: toFloat(args[2]), K: toFloat(args[3]),
 			}
 		}
+	case "CS":
+		if len(args) >= 1 {
+			return &OpSetStrokeColorSpace{ColorSpaceName: toString(args[0])}
+		}
+	case "cs":
+		if len(args) >= 1 {
+			return &OpSetFillColorSpace{ColorSpaceName: toString(args[0])}
+		}
+	case "SC", "SCN":
+		if name, values, ok := patternColorArgs(args); ok {
+			return &OpSetStrokePattern{PatternName: name, ColorValues: values}
+		}
+		return &OpSetStrokeColor{Components: toFloats(args)}
+	case "sc", "scn":
+		if name, values, ok := patternColorArgs(args); ok {
+			return &OpSetFillPattern{PatternName: name, ColorValues: values}
+		}
+		return &OpSetFillColor{Components: toFloats(args)}
 	case "BT":
 		return &OpBeginText{}
 	case "ET":
@@ -422,18 +696,22 @@ func createOperator(name string, args []interface{}) PDFOperator {
 		}
 	case "Tj":
 		if len(args) >= 1 {
-			return &OpShowText{Text: toString(args[0])}
+			text, hex := textOperand(args[0])
+			return &OpShowText{Text: text, Hex: hex}
 		}
 	case "'":
 		if len(args) >= 1 {
-			return &OpShowTextNextLine{Text: toString(args[0])}
+			text, hex := textOperand(args[0])
+			return &OpShowTextNextLine{Text: text, Hex: hex}
 		}
 	case "\"":
 		if len(args) >= 3 {
+			text, hex := textOperand(args[2])
 			return &OpShowTextWithSpacing{
 				WordSpacing: toFloat(args[0]),
 				CharSpacing: toFloat(args[1]),
-				Text:        toString(args[2]),
+				Text:        text,
+				Hex:         hex,
 			}
 		}
 	case "TJ":
@@ -452,7 +730,14 @@ func createOperator(name string, args []interface{}) PDFOperator {
 		}
 		return &OpBeginInlineImage{ImageDict: make(map[string]interface{})}
 	case "ID":
-		return &OpInlineImageData{}
+		// BI 与 ID 之间的参数是交替出现的键和值
+		dict := make(map[string]interface{})
+		for i := 0; i+1 < len(args); i += 2 {
+			if key, ok := args[i].(string); ok {
+				dict[key] = args[i+1]
+			}
+		}
+		return &OpInlineImageData{ImageDict: dict}
 	case "EI":
 		return &OpEndInlineImage{}
 	case "sh":
@@ -479,23 +764,50 @@ func toFloat(v interface{}) float64 {
 	return 0
 }
 
-func toString(v interface{}) string {
-	if s, ok := v.(string); ok {
-		// 移除名称前缀 /
-		s = strings.TrimPrefix(s, "/")
-
-		// 如果是字符串字面量 (...)，移除括号并处理转义序列
-		if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
-			s = s[1 : len(s)-1]
-			// 处理 PDF 字符串中的转义序列
-			s = unescapePDFString(s)
+// toFloats 将操作数转换为数值数组（忽略非数值操作数）
+func toFloats(args []interface{}) []float64 {
+	values := make([]float64, 0, len(args))
+	for _, arg := range args {
+		if f, ok := arg.(float64); ok {
+			values = append(values, f)
 		}
+	}
+	return values
+}
 
-		return s
+// patternColorArgs 判断 scn/SCN 的最后一个操作数是否为图案名称
+// 返回图案名称和前面的颜色分量（用于无色图案）
+func patternColorArgs(args []interface{}) (string, []float64, bool) {
+	if len(args) == 0 {
+		return "", nil, false
+	}
+	name, ok := args[len(args)-1].(string)
+	if !ok || !strings.HasPrefix(name, "/") {
+		return "", nil, false
+	}
+	return toString(name), toFloats(args[:len(args)-1]), true
+}
+
+func toString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		// 移除名称前缀 /（字符串字面量已在 parseValue 中去掉括号并处理转义）
+		return strings.TrimPrefix(s, "/")
 	}
 	return fmt.Sprintf("%v", v)
 }
 
+// textOperand 取文本显示操作符的字符串操作数，并返回它是否为十六进制字符串
+// 字面量字符串可能以 "/" 开头，不能像名称那样用 toString 去掉前缀
+func textOperand(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case HexString:
+		return string(s), true
+	case string:
+		return s, false
+	}
+	return toString(v), false
+}
+
 // unescapePDFString 处理 PDF 字符串中的转义序列
 func unescapePDFString(s string) string {
 	var result strings.Builder
@@ -6,7 +6,8 @@ import (
 
 // PatternRenderer 图案渲染器
 type PatternRenderer struct {
-	ctx Context
+	ctx    Context
+	budget *renderBudget // 图案单元内容计入的渲染预算，nil 表示不限制
 }
 
 // NewPatternRenderer 创建新的图案渲染器
@@ -56,12 +57,20 @@ func (pr *PatternRenderer) CreatePatternSurface(pattern *PatternImpl) (Surface,
 		return nil, fmt.Errorf("invalid pattern bbox: %.2f,%.2f,%.2f,%.2f", x1, y1, x2, y2)
 	}
 
+	// 图案单元与表单 XObject 一样计入嵌套深度，防止引用自身的图案无限递归
+	leave, err := pr.budget.enterForm()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
 	// 创建图像表面用于渲染图案单元
 	surface := NewImageSurface(FormatARGB32, int(width), int(height))
 
 	// 创建上下文
 	patternCtx := NewContext(surface)
 	defer patternCtx.Destroy()
+	patternCtx.SetGammaCorrectBlending(pr.ctx.GetGammaCorrectBlending())
 
 	// 设置透明背景
 	patternCtx.SetSourceRGBA(0, 0, 0, 0)
@@ -82,10 +91,17 @@ func (pr *PatternRenderer) CreatePatternSurface(pattern *PatternImpl) (Surface,
 		// 创建渲染上下文
 		renderCtx := NewRenderContext(patternCtx, width, height)
 		renderCtx.Resources = pattern.Resources
+		if pr.budget != nil {
+			renderCtx.budget = pr.budget // 图案内容计入页面的渲染预算
+		}
 
 		// 执行操作符
 		for _, op := range operators {
-			if err := op.Execute(renderCtx); err != nil {
+			if err := executeOperator(renderCtx, op); err != nil {
+				if renderCtx.abortOnError(err) {
+					surface.Destroy()
+					return nil, nestedAbortError("tiling pattern", err)
+				}
 				debugPrintf("Warning: pattern operator %s failed: %v\n", op.Name(), err)
 			}
 		}
@@ -0,0 +1,479 @@
+package gopdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// ===== PDF 输出表面 =====
+
+// pdfImageObject 页面内容引用的图像 XObject（RGB 数据及可选的 alpha 软遮罩）
+type pdfImageObject struct {
+	width, height int
+	rgb           []byte
+	alpha         []byte
+}
+
+// recordDrawOp 将绘制操作转换为 PDF 内容流操作符
+// 设备空间为 Y 向下，页面内容流开头的 1 0 0 -1 0 H cm 将其翻转为 PDF 的 Y 向上
+func (s *pdfSurface) recordDrawOp(op *vectorDrawOp) {
+	if s.finished {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("q\n")
+	m := op.matrix
+	fmt.Fprintf(&sb, "%s %s %s %s %s %s cm\n",
+		formatNum(m.XX), formatNum(m.YX), formatNum(m.XY), formatNum(m.YY), formatNum(m.X0), formatNum(m.Y0))
+	if len(op.clip) > 0 {
+		writePDFPath(&sb, op.clip)
+		sb.WriteString(pdfClipOperator(op.clipRule) + " n\n")
+	}
+
+	// 表面图案（图像）：以路径为裁剪区域绘制图像
+	if sp, ok := op.source.(*surfacePattern); ok {
+		if op.kind == vectorOpStroke {
+			// 描边不支持图像图案，使用黑色代替
+			debugPrintln("[PDFSurface] Stroking with a surface pattern is not supported, using black")
+		} else {
+			if len(op.path) > 0 {
+				writePDFPath(&sb, op.path)
+				sb.WriteString(pdfClipOperator(op.fillRule) + " n\n")
+			}
+			if op.alpha < 1 {
+				fmt.Fprintf(&sb, "/%s gs\n", s.alphaState(op.alpha))
+			}
+			s.writeImagePaint(&sb, sp)
+			sb.WriteString("Q\n")
+			s.content.WriteString(sb.String())
+			return
+		}
+	}
+
+	// 渐变：以路径为裁剪区域绘制轴向/径向 Shading；描边无法使用 Shading，退化为第一个色标的颜色
+	if op.kind == vectorOpStroke {
+		switch op.source.(type) {
+		case *linearGradient, *radialGradient:
+			debugPrintln("[PDFSurface] Stroking with a gradient is not supported, using the first color stop")
+		}
+	} else if name := s.addShading(op.source); name != "" {
+		data := op.path
+		if op.kind == vectorOpPaint && len(data) == 0 {
+			data = pageRectPath(op.matrix, s.width, s.height)
+		}
+		writePDFPath(&sb, data)
+		sb.WriteString(pdfClipOperator(op.fillRule) + " n\n")
+		if op.alpha < 1 {
+			fmt.Fprintf(&sb, "/%s gs\n", s.alphaState(op.alpha))
+		}
+		inv := *op.source.GetMatrix()
+		if MatrixInvert(&inv) != StatusSuccess {
+			inv.InitIdentity()
+		}
+		fmt.Fprintf(&sb, "%s %s %s %s %s %s cm\n/%s sh\nQ\n",
+			formatNum(inv.XX), formatNum(inv.YX), formatNum(inv.XY), formatNum(inv.YY), formatNum(inv.X0), formatNum(inv.Y0), name)
+		s.content.WriteString(sb.String())
+		return
+	}
+
+	r, g, b, a := vectorSourceColor(op.source)
+	a *= op.alpha
+	if a < 1 {
+		fmt.Fprintf(&sb, "/%s gs\n", s.alphaState(a))
+	}
+
+	switch op.kind {
+	case vectorOpStroke:
+		fmt.Fprintf(&sb, "%s %s %s RG\n", formatNum(r), formatNum(g), formatNum(b))
+		fmt.Fprintf(&sb, "%s w %d J %d j %s M\n", formatNum(op.lineWidth), int(op.lineCap), int(op.lineJoin), formatNum(op.miterLimit))
+		if len(op.dash) > 0 {
+			dashes := make([]string, len(op.dash))
+			for i, d := range op.dash {
+				dashes[i] = formatNum(d)
+			}
+			fmt.Fprintf(&sb, "[%s] %s d\n", strings.Join(dashes, " "), formatNum(op.dashOffset))
+		}
+		writePDFPath(&sb, op.path)
+		sb.WriteString("S\n")
+	case vectorOpFill, vectorOpPaint:
+		fmt.Fprintf(&sb, "%s %s %s rg\n", formatNum(r), formatNum(g), formatNum(b))
+		if op.kind == vectorOpPaint && len(op.path) == 0 {
+			// 没有裁剪路径的 Paint 填充整个页面
+			writePDFPath(&sb, pageRectPath(op.matrix, s.width, s.height))
+		} else {
+			writePDFPath(&sb, op.path)
+		}
+		if op.fillRule == FillRuleEvenOdd {
+			sb.WriteString("f*\n")
+		} else {
+			sb.WriteString("f\n")
+		}
+	}
+
+	sb.WriteString("Q\n")
+	s.content.WriteString(sb.String())
+}
+
+// writeImagePaint 输出绘制表面图案图像的操作符
+// 图案矩阵把用户空间映射到图案空间，其逆矩阵把图像所在的 [0,w]x[0,h] 放回用户空间
+func (s *pdfSurface) writeImagePaint(sb *strings.Builder, sp *surfacePattern) {
+	imgSurf, ok := sp.surface.(ImageSurface)
+	if !ok {
+		debugPrintln("[PDFSurface] Surface pattern source is not an image surface, skipped")
+		return
+	}
+
+	name := s.addImage(imgSurf)
+	if name == "" {
+		return
+	}
+
+	inv := *sp.GetMatrix()
+	if MatrixInvert(&inv) != StatusSuccess {
+		inv.InitIdentity()
+	}
+	w, h := float64(imgSurf.GetWidth()), float64(imgSurf.GetHeight())
+	fmt.Fprintf(sb, "%s %s %s %s %s %s cm\n",
+		formatNum(inv.XX), formatNum(inv.YX), formatNum(inv.XY), formatNum(inv.YY), formatNum(inv.X0), formatNum(inv.Y0))
+	// 图像第一行位于单位正方形顶部（y=1），映射到 Y 向下用户空间的 y=0
+	fmt.Fprintf(sb, "%s 0 0 %s 0 %s cm\n/%s Do\n", formatNum(w), formatNum(-h), formatNum(h), name)
+}
+
+// addImage 复制图像表面当前的像素并注册为图像 XObject，返回资源名
+func (s *pdfSurface) addImage(imgSurf ImageSurface) string {
+	img := ConvertGopdfSurfaceToImage(imgSurf)
+	if img == nil {
+		return ""
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 {
+		return ""
+	}
+
+	rgb := make([]byte, 0, width*height*3)
+	alpha := make([]byte, 0, width*height)
+	opaque := true
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			if c.A != 255 {
+				opaque = false
+			}
+		}
+	}
+
+	obj := pdfImageObject{width: width, height: height, rgb: rgb}
+	if !opaque {
+		obj.alpha = alpha
+	}
+	s.images = append(s.images, obj)
+	return fmt.Sprintf("Im%d", len(s.images))
+}
+
+// alphaState 返回设置填充和描边透明度的 ExtGState 资源名
+func (s *pdfSurface) alphaState(alpha float64) string {
+	if s.alphaStates == nil {
+		s.alphaStates = make(map[float64]string)
+	}
+	if name, ok := s.alphaStates[alpha]; ok {
+		return name
+	}
+	name := fmt.Sprintf("GS%d", len(s.alphaStates)+1)
+	s.alphaStates[alpha] = name
+	return name
+}
+
+// addShading 为线性/径向渐变注册轴向（ShadingType 2）或径向（ShadingType 3）Shading，返回资源名；
+// 其它图案返回空字符串。色标之间用指数插值函数（N=1）拼接，
+// 色标的 alpha 不输出；Repeat/Reflect 无法表示，按 Pad 处理
+func (s *pdfSurface) addShading(source Pattern) string {
+	var grad *gradientPattern
+	var shading string
+	switch p := source.(type) {
+	case *linearGradient:
+		grad = &p.gradientPattern
+		shading = fmt.Sprintf("/ShadingType 2 /Coords [%s %s %s %s]",
+			formatNum(p.x0), formatNum(p.y0), formatNum(p.x1), formatNum(p.y1))
+	case *radialGradient:
+		grad = &p.gradientPattern
+		shading = fmt.Sprintf("/ShadingType 3 /Coords [%s %s %s %s %s %s]",
+			formatNum(p.cx0), formatNum(p.cy0), formatNum(p.radius0),
+			formatNum(p.cx1), formatNum(p.cy1), formatNum(p.radius1))
+	default:
+		return ""
+	}
+	if len(grad.stops) == 0 {
+		return ""
+	}
+
+	extend := "true"
+	switch grad.GetExtend() {
+	case ExtendNone:
+		extend = "false"
+	case ExtendRepeat, ExtendReflect:
+		debugPrintln("[PDFSurface] Repeating gradients are not supported, extending the end colors instead")
+	}
+
+	s.shadings = append(s.shadings, fmt.Sprintf("<< %s /ColorSpace /DeviceRGB /Function %s /Extend [%s %s] >>",
+		shading, pdfGradientFunction(grad.stops), extend, extend))
+	return fmt.Sprintf("Sh%d", len(s.shadings))
+}
+
+// pdfGradientFunction 把色标转换为定义域 [0 1] 的 PDF 函数：
+// 相邻色标之间为指数插值函数，多段时用拼接函数（FunctionType 3）连接，首尾色标之外取端点颜色
+func pdfGradientFunction(stops []gradientStop) string {
+	stops = append([]gradientStop(nil), stops...)
+	if first := stops[0]; first.offset > 0 {
+		first.offset = 0
+		stops = append([]gradientStop{first}, stops...)
+	}
+	if last := stops[len(stops)-1]; last.offset < 1 || len(stops) == 1 {
+		last.offset = 1
+		stops = append(stops, last)
+	}
+
+	segment := func(a, b gradientStop) string {
+		return fmt.Sprintf("<< /FunctionType 2 /Domain [0 1] /C0 [%s %s %s] /C1 [%s %s %s] /N 1 >>",
+			formatNum(a.red), formatNum(a.green), formatNum(a.blue), formatNum(b.red), formatNum(b.green), formatNum(b.blue))
+	}
+	if len(stops) == 2 {
+		return segment(stops[0], stops[1])
+	}
+
+	functions := make([]string, 0, len(stops)-1)
+	bounds := make([]string, 0, len(stops)-2)
+	encode := make([]string, 0, len(stops)-1)
+	for i := 0; i+1 < len(stops); i++ {
+		functions = append(functions, segment(stops[i], stops[i+1]))
+		encode = append(encode, "0 1")
+		if i > 0 {
+			bounds = append(bounds, formatNum(stops[i].offset))
+		}
+	}
+	return fmt.Sprintf("<< /FunctionType 3 /Domain [0 1] /Functions [%s] /Bounds [%s] /Encode [%s] >>",
+		strings.Join(functions, " "), strings.Join(bounds, " "), strings.Join(encode, " "))
+}
+
+// writePDFPath 输出路径构造操作符
+func writePDFPath(sb *strings.Builder, data []pathOp) {
+	for _, op := range data {
+		switch op.op {
+		case PathMoveTo:
+			fmt.Fprintf(sb, "%s %s m\n", formatNum(op.points[0].x), formatNum(op.points[0].y))
+		case PathLineTo:
+			fmt.Fprintf(sb, "%s %s l\n", formatNum(op.points[0].x), formatNum(op.points[0].y))
+		case PathCurveTo:
+			fmt.Fprintf(sb, "%s %s %s %s %s %s c\n",
+				formatNum(op.points[0].x), formatNum(op.points[0].y),
+				formatNum(op.points[1].x), formatNum(op.points[1].y),
+				formatNum(op.points[2].x), formatNum(op.points[2].y))
+		case PathClosePath:
+			sb.WriteString("h\n")
+		}
+	}
+}
+
+// pdfClipOperator 按填充规则返回裁剪操作符
+func pdfClipOperator(fillRule FillRule) string {
+	if fillRule == FillRuleEvenOdd {
+		return "W*"
+	}
+	return "W"
+}
+
+// ShowPage 结束当前页面，后续绘制进入新的一页
+func (s *pdfSurface) ShowPage() {
+	if s.finished {
+		return
+	}
+	s.pages = append(s.pages, append([]byte(nil), s.content.Bytes()...))
+	s.content.Reset()
+}
+
+// CopyPage 结束当前页面，新的一页保留当前页面的内容
+func (s *pdfSurface) CopyPage() {
+	if s.finished {
+		return
+	}
+	s.pages = append(s.pages, append([]byte(nil), s.content.Bytes()...))
+}
+
+func (s *pdfSurface) Destroy() {
+	if atomic.AddInt32(&s.refCount, -1) == 0 {
+		s.Finish()
+		s.cleanup()
+	}
+}
+
+// Finish 将记录的页面写入 PDF 文件；重复调用不会再次写入
+func (s *pdfSurface) Finish() error {
+	if s.finished {
+		return nil
+	}
+	// 未调用 ShowPage 的内容作为最后一页；没有任何页面时输出一个空白页
+	if s.content.Len() > 0 || len(s.pages) == 0 {
+		s.ShowPage()
+	}
+	s.finished = true
+
+	if err := s.writeFile(); err != nil {
+		s.status = StatusWriteError
+		return fmt.Errorf("failed to write PDF surface %s: %w", s.filename, err)
+	}
+	return nil
+}
+
+// writeFile 按对象顺序输出：目录、页面树、共享资源、图像、各页面及其内容流
+func (s *pdfSurface) writeFile() error {
+	w := &pdfObjectWriter{}
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 预先分配对象号
+	catalogNum, pagesNum, resourcesNum := 1, 2, 3
+	next := 4
+	imageNums := make([]int, len(s.images))
+	maskNums := make([]int, len(s.images))
+	for i, img := range s.images {
+		imageNums[i] = next
+		next++
+		if img.alpha != nil {
+			maskNums[i] = next
+			next++
+		}
+	}
+	pageNums := make([]int, len(s.pages))
+	for i := range s.pages {
+		pageNums[i] = next
+		next += 2
+	}
+
+	w.writeObject(catalogNum, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesNum))
+
+	kids := make([]string, len(pageNums))
+	for i, num := range pageNums {
+		kids[i] = fmt.Sprintf("%d 0 R", num)
+	}
+	w.writeObject(pagesNum, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pageNums)))
+
+	var res strings.Builder
+	res.WriteString("<< /ProcSet [/PDF /ImageC]")
+	if len(s.images) > 0 {
+		res.WriteString(" /XObject <<")
+		for i, num := range imageNums {
+			fmt.Fprintf(&res, " /Im%d %d 0 R", i+1, num)
+		}
+		res.WriteString(" >>")
+	}
+	if len(s.alphaStates) > 0 {
+		alphas := make([]float64, 0, len(s.alphaStates))
+		for a := range s.alphaStates {
+			alphas = append(alphas, a)
+		}
+		sort.Slice(alphas, func(i, j int) bool { return s.alphaStates[alphas[i]] < s.alphaStates[alphas[j]] })
+		res.WriteString(" /ExtGState <<")
+		for _, a := range alphas {
+			fmt.Fprintf(&res, " /%s << /Type /ExtGState /ca %s /CA %s >>", s.alphaStates[a], formatNum(a), formatNum(a))
+		}
+		res.WriteString(" >>")
+	}
+	if len(s.shadings) > 0 {
+		res.WriteString(" /Shading <<")
+		for i, sh := range s.shadings {
+			fmt.Fprintf(&res, " /Sh%d %s", i+1, sh)
+		}
+		res.WriteString(" >>")
+	}
+	res.WriteString(" >>")
+	w.writeObject(resourcesNum, res.String())
+
+	for i, img := range s.images {
+		dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8",
+			img.width, img.height)
+		if img.alpha != nil {
+			dict += fmt.Sprintf(" /SMask %d 0 R", maskNums[i])
+		}
+		if err := w.writeStream(imageNums[i], dict, img.rgb); err != nil {
+			return err
+		}
+		if img.alpha != nil {
+			maskDict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8",
+				img.width, img.height)
+			if err := w.writeStream(maskNums[i], maskDict, img.alpha); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, content := range s.pages {
+		w.writeObject(pageNums[i], fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %d 0 R /Contents %d 0 R >>",
+			pagesNum, formatNum(s.width), formatNum(s.height), resourcesNum, pageNums[i]+1))
+
+		// 设备空间 Y 向下，翻转为 PDF 用户空间
+		var stream bytes.Buffer
+		fmt.Fprintf(&stream, "1 0 0 -1 0 %s cm\n", formatNum(s.height))
+		stream.Write(content)
+		if err := w.writeStream(pageNums[i]+1, "", stream.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	w.writeTrailer(next, catalogNum)
+	return os.WriteFile(s.filename, w.buf.Bytes(), 0644)
+}
+
+// pdfObjectWriter 顺序写入 PDF 对象并记录交叉引用表偏移
+type pdfObjectWriter struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+}
+
+// writeObject 写入一个间接对象并记录其偏移
+func (w *pdfObjectWriter) writeObject(num int, body string) {
+	if w.offsets == nil {
+		w.offsets = make(map[int]int)
+	}
+	w.offsets[num] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", num, body)
+}
+
+// writeStream 以 FlateDecode 压缩写入流对象，dict 为附加的字典条目
+func (w *pdfObjectWriter) writeStream(num int, dict string, data []byte) error {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	data = compressed.Bytes()
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "<< %s >>\nstream\n", strings.TrimSpace(fmt.Sprintf("%s /Filter /FlateDecode /Length %d", dict, len(data))))
+	body.Write(data)
+	body.WriteString("\nendstream")
+	w.writeObject(num, body.String())
+	return nil
+}
+
+// writeTrailer 写入交叉引用表和文件尾
+func (w *pdfObjectWriter) writeTrailer(size, rootNum int) {
+	xrefOffset := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for num := 1; num < size; num++ {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", w.offsets[num])
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", size, rootNum, xrefOffset)
+}
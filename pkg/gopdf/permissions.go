@@ -0,0 +1,180 @@
+package gopdf
+
+import (
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Permissions 文档的访问权限和加密信息（PDF 32000-1 7.6.3，表 22）
+// 未加密的文档所有权限均为 true
+type Permissions struct {
+	Encrypted bool  // 文档是否加密
+	P         int32 // 原始 /P 权限位
+
+	CanPrint                   bool // 位 3：打印
+	CanModify                  bool // 位 4：修改内容
+	CanCopy                    bool // 位 5：复制或提取文本和图形
+	CanAnnotate                bool // 位 6：添加或修改注释、填写表单
+	CanFillForms               bool // 位 9：填写表单字段
+	CanExtractForAccessibility bool // 位 10：为辅助功能提取内容
+	CanAssemble                bool // 位 11：组装文档（插入、旋转、删除页面等）
+	CanPrintHighQuality        bool // 位 12：高质量打印
+
+	Filter    string // 安全处理程序（通常为 Standard）
+	Version   int    // /V 算法版本
+	Revision  int    // /R 安全处理程序修订号
+	Algorithm string // 加密算法：RC4、AES 或 None
+	KeyLength int    // 密钥长度（位）
+}
+
+// GetPermissions 读取文档的权限位和加密方式
+// 加密文档需要能以空用户密码或读取器的密码（NewPDFReaderWithPassword）打开
+func (r *PDFReader) GetPermissions() (*Permissions, error) {
+	ctx, err := r.readContext()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF context: %w", err)
+	}
+	return readPermissions(ctx)
+}
+
+// readPermissions 从 /Encrypt 字典解析权限和加密信息
+func readPermissions(ctx *model.Context) (*Permissions, error) {
+	if ctx.Encrypt == nil {
+		return &Permissions{
+			P:                          -1,
+			CanPrint:                   true,
+			CanModify:                  true,
+			CanCopy:                    true,
+			CanAnnotate:                true,
+			CanFillForms:               true,
+			CanExtractForAccessibility: true,
+			CanAssemble:                true,
+			CanPrintHighQuality:        true,
+			Algorithm:                  "None",
+		}, nil
+	}
+
+	encryptDict, err := ctx.DereferenceDict(*ctx.Encrypt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dereference Encrypt dictionary: %w", err)
+	}
+	if encryptDict == nil {
+		return nil, fmt.Errorf("encrypt dictionary not found")
+	}
+
+	perms := &Permissions{Encrypted: true}
+	if name, ok := encryptDict.Find("Filter"); ok {
+		if n, ok := name.(types.Name); ok {
+			perms.Filter = n.Value()
+		}
+	}
+	if v, ok := encryptDict.Find("V"); ok {
+		if n, ok := getInteger(v); ok {
+			perms.Version = int(n)
+		}
+	}
+	if rv, ok := encryptDict.Find("R"); ok {
+		if n, ok := getInteger(rv); ok {
+			perms.Revision = int(n)
+		}
+	}
+	if p, ok := encryptDict.Find("P"); ok {
+		if n, ok := getInteger(p); ok {
+			perms.P = int32(n)
+		}
+	}
+
+	bit := func(n uint) bool { return perms.P&(1<<(n-1)) != 0 }
+	perms.CanPrint = bit(3)
+	perms.CanModify = bit(4)
+	perms.CanCopy = bit(5)
+	perms.CanAnnotate = bit(6)
+	if perms.Revision >= 3 {
+		perms.CanFillForms = bit(9)
+		perms.CanExtractForAccessibility = bit(10)
+		perms.CanAssemble = bit(11)
+		perms.CanPrintHighQuality = bit(12)
+	} else {
+		// 修订号 2 没有位 9-12，对应权限由基本权限位决定
+		perms.CanFillForms = perms.CanAnnotate
+		perms.CanExtractForAccessibility = perms.CanCopy
+		perms.CanAssemble = perms.CanModify
+		perms.CanPrintHighQuality = perms.CanPrint
+	}
+
+	perms.Algorithm, perms.KeyLength = encryptionAlgorithm(encryptDict, perms.Version)
+	return perms, nil
+}
+
+// encryptionAlgorithm 根据 /V 和加密过滤器（/CF /StmF 的 /CFM）确定加密算法和密钥长度
+func encryptionAlgorithm(encryptDict types.Dict, version int) (string, int) {
+	keyLength := 40
+	if l, ok := encryptDict.Find("Length"); ok {
+		if n, ok := getInteger(l); ok && n > 0 {
+			keyLength = int(n)
+		}
+	}
+
+	switch version {
+	case 1:
+		return "RC4", 40
+	case 2, 3:
+		return "RC4", keyLength
+	case 4, 5:
+		switch cryptFilterMethod(encryptDict) {
+		case "AESV2":
+			return "AES", 128
+		case "AESV3":
+			return "AES", 256
+		case "V2":
+			if version == 4 && keyLength == 40 {
+				keyLength = 128
+			}
+			return "RC4", keyLength
+		case "None":
+			return "None", 0
+		}
+		if version == 5 {
+			return "AES", 256
+		}
+	}
+	return "Unknown", keyLength
+}
+
+// cryptFilterMethod 返回流加密过滤器（/StmF）在 /CF 中的 /CFM 方法名
+func cryptFilterMethod(encryptDict types.Dict) string {
+	stmF := "StdCF"
+	if obj, ok := encryptDict.Find("StmF"); ok {
+		if n, ok := obj.(types.Name); ok {
+			stmF = n.Value()
+		}
+	}
+	if stmF == "Identity" {
+		return "None"
+	}
+
+	cf, ok := encryptDict.Find("CF")
+	if !ok {
+		return ""
+	}
+	cfDict, ok := cf.(types.Dict)
+	if !ok {
+		return ""
+	}
+	filter, ok := cfDict.Find(stmF)
+	if !ok {
+		return ""
+	}
+	filterDict, ok := filter.(types.Dict)
+	if !ok {
+		return ""
+	}
+	if cfm, ok := filterDict.Find("CFM"); ok {
+		if n, ok := cfm.(types.Name); ok {
+			return n.Value()
+		}
+	}
+	return ""
+}
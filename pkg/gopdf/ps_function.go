@@ -0,0 +1,414 @@
+package gopdf
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// psStackLimit PostScript 计算器函数的操作数栈上限（PDF 32000-1 7.10.5）
+const psStackLimit = 100
+
+// psValue PostScript 计算器函数栈中的值：整数、实数或布尔值
+type psValue struct {
+	num    float64
+	isInt  bool
+	isBool bool
+}
+
+func psReal(v float64) psValue { return psValue{num: v} }
+func psInt(v int64) psValue    { return psValue{num: float64(v), isInt: true} }
+func psBool(b bool) psValue {
+	if b {
+		return psValue{num: 1, isBool: true}
+	}
+	return psValue{isBool: true}
+}
+
+// psInstr 解析后的计算器函数指令：压入常量、执行操作符，或 if/ifelse 条件分支
+type psInstr struct {
+	op      string
+	value   psValue
+	push    bool
+	then    []psInstr
+	elseArm []psInstr
+}
+
+// parsePSFunction 解析类型 4 函数的程序（最外层为 { ... } 过程）
+func parsePSFunction(src []byte) ([]psInstr, error) {
+	tokens := tokenizePSFunction(src)
+	if len(tokens) == 0 || tokens[0] != "{" {
+		return nil, fmt.Errorf("PostScript function must start with '{'")
+	}
+	pos := 1
+	prog, err := parsePSProc(tokens, &pos)
+	if err != nil {
+		return nil, err
+	}
+	return prog, nil
+}
+
+// tokenizePSFunction 按空白和花括号切分程序，跳过 % 注释
+func tokenizePSFunction(src []byte) []string {
+	var tokens []string
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, string(src[start:end]))
+			start = -1
+		}
+	}
+	for i := 0; i < len(src); i++ {
+		switch c := src[i]; c {
+		case ' ', '\t', '\r', '\n', '\f', 0:
+			flush(i)
+		case '{', '}':
+			flush(i)
+			tokens = append(tokens, string(c))
+		case '%':
+			flush(i)
+			for i < len(src) && src[i] != '\n' && src[i] != '\r' {
+				i++
+			}
+		default:
+			if start < 0 {
+				start = i
+			}
+		}
+	}
+	flush(len(src))
+	return tokens
+}
+
+// parsePSProc 解析到匹配的 '}' 为止；{...} if 和 {...} {...} ifelse 组合为条件指令
+func parsePSProc(tokens []string, pos *int) ([]psInstr, error) {
+	var prog []psInstr
+	var pending [][]psInstr
+	for *pos < len(tokens) {
+		tok := tokens[*pos]
+		*pos++
+		switch tok {
+		case "}":
+			if len(pending) > 0 {
+				return nil, fmt.Errorf("procedure without if/ifelse")
+			}
+			return prog, nil
+		case "{":
+			proc, err := parsePSProc(tokens, pos)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, proc)
+			continue
+		case "if":
+			if len(pending) != 1 {
+				return nil, fmt.Errorf("if expects one procedure, got %d", len(pending))
+			}
+			prog = append(prog, psInstr{op: tok, then: pending[0]})
+		case "ifelse":
+			if len(pending) != 2 {
+				return nil, fmt.Errorf("ifelse expects two procedures, got %d", len(pending))
+			}
+			prog = append(prog, psInstr{op: tok, then: pending[0], elseArm: pending[1]})
+		default:
+			if len(pending) > 0 {
+				return nil, fmt.Errorf("procedure without if/ifelse before %q", tok)
+			}
+			if i, err := strconv.ParseInt(tok, 10, 64); err == nil {
+				prog = append(prog, psInstr{push: true, value: psInt(i)})
+			} else if f, err := strconv.ParseFloat(tok, 64); err == nil {
+				prog = append(prog, psInstr{push: true, value: psReal(f)})
+			} else if tok == "true" || tok == "false" {
+				prog = append(prog, psInstr{push: true, value: psBool(tok == "true")})
+			} else {
+				prog = append(prog, psInstr{op: tok})
+			}
+		}
+		pending = pending[:0]
+	}
+	return nil, fmt.Errorf("unterminated procedure")
+}
+
+// psMachine 计算器函数的执行状态
+type psMachine struct {
+	stack []psValue
+}
+
+func (m *psMachine) push(v psValue) error {
+	if len(m.stack) >= psStackLimit {
+		return fmt.Errorf("stack overflow")
+	}
+	m.stack = append(m.stack, v)
+	return nil
+}
+
+func (m *psMachine) pop() (psValue, error) {
+	if len(m.stack) == 0 {
+		return psValue{}, fmt.Errorf("stack underflow")
+	}
+	v := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return v, nil
+}
+
+func (m *psMachine) pop2() (psValue, psValue, error) {
+	b, err := m.pop()
+	if err != nil {
+		return psValue{}, psValue{}, err
+	}
+	a, err := m.pop()
+	return a, b, err
+}
+
+// evalPSFunction 以 inputs 为初始栈执行程序，返回结束时的栈（自底向上）
+func evalPSFunction(prog []psInstr, inputs []float64) ([]float64, error) {
+	m := &psMachine{}
+	for _, v := range inputs {
+		if err := m.push(psReal(v)); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.run(prog); err != nil {
+		return nil, err
+	}
+	out := make([]float64, len(m.stack))
+	for i, v := range m.stack {
+		out[i] = v.num
+	}
+	return out, nil
+}
+
+func (m *psMachine) run(prog []psInstr) error {
+	for _, in := range prog {
+		if in.push {
+			if err := m.push(in.value); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := m.exec(in); err != nil {
+			return fmt.Errorf("%s: %w", in.op, err)
+		}
+	}
+	return nil
+}
+
+func (m *psMachine) exec(in psInstr) error {
+	switch in.op {
+	case "if", "ifelse":
+		cond, err := m.pop()
+		if err != nil {
+			return err
+		}
+		if !cond.isBool {
+			return fmt.Errorf("condition is not a boolean")
+		}
+		if cond.num != 0 {
+			return m.run(in.then)
+		}
+		return m.run(in.elseArm)
+
+	// 栈操作符
+	case "pop":
+		_, err := m.pop()
+		return err
+	case "dup":
+		v, err := m.pop()
+		if err != nil {
+			return err
+		}
+		m.stack = append(m.stack, v)
+		return m.push(v)
+	case "exch":
+		a, b, err := m.pop2()
+		if err != nil {
+			return err
+		}
+		m.stack = append(m.stack, b, a)
+		return nil
+	case "copy":
+		n, err := m.pop()
+		if err != nil {
+			return err
+		}
+		k := int(n.num)
+		if k < 0 || k > len(m.stack) {
+			return fmt.Errorf("invalid count %d", k)
+		}
+		for _, v := range m.stack[len(m.stack)-k:] {
+			if err := m.push(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "index":
+		n, err := m.pop()
+		if err != nil {
+			return err
+		}
+		k := int(n.num)
+		if k < 0 || k >= len(m.stack) {
+			return fmt.Errorf("invalid index %d", k)
+		}
+		return m.push(m.stack[len(m.stack)-1-k])
+	case "roll":
+		nv, jv, err := m.pop2()
+		if err != nil {
+			return err
+		}
+		n, j := int(nv.num), int(jv.num)
+		if n < 0 || n > len(m.stack) {
+			return fmt.Errorf("invalid count %d", n)
+		}
+		if n == 0 {
+			return nil
+		}
+		j = ((j % n) + n) % n
+		top := m.stack[len(m.stack)-n:]
+		rolled := append(append([]psValue{}, top[n-j:]...), top[:n-j]...)
+		copy(top, rolled)
+		return nil
+
+	// 布尔常量由解析器处理；以下为一元操作符
+	case "abs", "neg", "ceiling", "floor", "round", "truncate", "sqrt", "sin", "cos",
+		"ln", "log", "cvi", "cvr", "not":
+		a, err := m.pop()
+		if err != nil {
+			return err
+		}
+		return m.push(psUnary(in.op, a))
+	}
+
+	a, b, err := m.pop2()
+	if err != nil {
+		return err
+	}
+	v, err := psBinary(in.op, a, b)
+	if err != nil {
+		return err
+	}
+	return m.push(v)
+}
+
+// psUnary 计算一元算术、类型转换和 not 操作符
+func psUnary(op string, a psValue) psValue {
+	keepInt := func(v float64) psValue {
+		if a.isInt {
+			return psInt(int64(v))
+		}
+		return psReal(v)
+	}
+	switch op {
+	case "abs":
+		return keepInt(math.Abs(a.num))
+	case "neg":
+		return keepInt(-a.num)
+	case "ceiling":
+		return keepInt(math.Ceil(a.num))
+	case "floor":
+		return keepInt(math.Floor(a.num))
+	case "round":
+		// PostScript 的 round 把 .5 向上取整
+		return keepInt(math.Floor(a.num + 0.5))
+	case "truncate":
+		return keepInt(math.Trunc(a.num))
+	case "sqrt":
+		return psReal(math.Sqrt(a.num))
+	case "sin":
+		return psReal(math.Sin(a.num * math.Pi / 180))
+	case "cos":
+		return psReal(math.Cos(a.num * math.Pi / 180))
+	case "ln":
+		return psReal(math.Log(a.num))
+	case "log":
+		return psReal(math.Log10(a.num))
+	case "cvi":
+		return psInt(int64(math.Trunc(a.num)))
+	case "cvr":
+		return psReal(a.num)
+	default: // not
+		if a.isBool {
+			return psBool(a.num == 0)
+		}
+		return psInt(^int64(a.num))
+	}
+}
+
+// psBinary 计算二元算术、关系、逻辑和位操作符
+func psBinary(op string, a, b psValue) (psValue, error) {
+	bothInt := a.isInt && b.isInt
+	arith := func(v float64) psValue {
+		if bothInt {
+			return psInt(int64(v))
+		}
+		return psReal(v)
+	}
+	switch op {
+	case "add":
+		return arith(a.num + b.num), nil
+	case "sub":
+		return arith(a.num - b.num), nil
+	case "mul":
+		return arith(a.num * b.num), nil
+	case "div":
+		if b.num == 0 {
+			return psValue{}, fmt.Errorf("division by zero")
+		}
+		return psReal(a.num / b.num), nil
+	case "idiv", "mod":
+		if !bothInt || b.num == 0 {
+			return psValue{}, fmt.Errorf("invalid operands")
+		}
+		if op == "idiv" {
+			return psInt(int64(a.num) / int64(b.num)), nil
+		}
+		return psInt(int64(a.num) % int64(b.num)), nil
+	case "atan":
+		// 结果为角度，范围 [0, 360)
+		deg := math.Atan2(a.num, b.num) * 180 / math.Pi
+		if deg < 0 {
+			deg += 360
+		}
+		return psReal(deg), nil
+	case "exp":
+		return psReal(math.Pow(a.num, b.num)), nil
+	case "eq":
+		return psBool(a.num == b.num), nil
+	case "ne":
+		return psBool(a.num != b.num), nil
+	case "gt":
+		return psBool(a.num > b.num), nil
+	case "ge":
+		return psBool(a.num >= b.num), nil
+	case "lt":
+		return psBool(a.num < b.num), nil
+	case "le":
+		return psBool(a.num <= b.num), nil
+	case "and", "or", "xor":
+		if a.isBool && b.isBool {
+			x, y := a.num != 0, b.num != 0
+			switch op {
+			case "and":
+				return psBool(x && y), nil
+			case "or":
+				return psBool(x || y), nil
+			}
+			return psBool(x != y), nil
+		}
+		x, y := int64(a.num), int64(b.num)
+		switch op {
+		case "and":
+			return psInt(x & y), nil
+		case "or":
+			return psInt(x | y), nil
+		}
+		return psInt(x ^ y), nil
+	case "bitshift":
+		x, shift := int64(a.num), int64(b.num)
+		if shift >= 0 {
+			return psInt(x << uint(shift)), nil
+		}
+		return psInt(x >> uint(-shift)), nil
+	}
+	return psValue{}, fmt.Errorf("unsupported operator")
+}
@@ -23,6 +23,7 @@ type rasterContext struct {
 	// Line properties
 	lineCap    LineCap
 	lineJoin   LineJoin
+	miterLimit float64
 	lineDash   []float64
 	dashOffset float64
 
@@ -31,6 +32,26 @@ type rasterContext struct {
 
 	// Surface pattern (if set)
 	surfacePattern Pattern
+
+	// PDF 混合模式（Multiply 等）；其他合成操作符按 Over 处理
+	operator Operator
+
+	// 当前裁剪区域的设备空间覆盖率遮罩，nil 表示不裁剪
+	clip *image.Alpha
+
+	// Blend in linear light: decode sRGB before the Porter-Duff math and
+	// re-encode afterwards. Off by default for speed and compatibility.
+	linearBlend bool
+
+	// AntialiasNone: sample each pixel once at its centre instead of 4x4
+	aliased bool
+
+	// Fill rule used by Fill and ClipMask (nonzero winding or even-odd)
+	fillRule FillRule
+
+	// Device-space bounding box of every pixel written so far, so callers
+	// compositing a freshly pushed group only need to visit that area
+	painted image.Rectangle
 }
 
 type pathPoint struct {
@@ -59,11 +80,12 @@ type transformedPoint struct {
 // newRasterContext creates a new raster context for the given image
 func newRasterContext(img *image.RGBA) *rasterContext {
 	return &rasterContext{
-		img:    img,
-		color:  color.Black,
-		stroke: color.Black,
-		width:  1.0,
-		path:   make([]pathPoint, 0),
+		img:        img,
+		color:      color.Black,
+		stroke:     color.Black,
+		width:      1.0,
+		miterLimit: 10.0,
+		path:       make([]pathPoint, 0),
 	}
 }
 
@@ -115,6 +137,11 @@ func (r *rasterContext) SetLineJoin(join LineJoin) {
 	r.lineJoin = join
 }
 
+// SetMiterLimit sets the miter limit used for miter joins
+func (r *rasterContext) SetMiterLimit(limit float64) {
+	r.miterLimit = limit
+}
+
 // SetLineDash sets the line dash pattern
 func (r *rasterContext) SetLineDash(dash []float64, offset float64) {
 	r.lineDash = dash
@@ -126,6 +153,11 @@ func (r *rasterContext) SetFillColor(c color.Color) {
 	r.color = c
 }
 
+// SetOperator sets the compositing operator used by blendPixel
+func (r *rasterContext) SetOperator(op Operator) {
+	r.operator = op
+}
+
 // SetStrokeColor sets the stroke color
 func (r *rasterContext) SetStrokeColor(c color.Color) {
 	r.stroke = c
@@ -159,89 +191,47 @@ func (r *rasterContext) SetSurfacePattern(pattern Pattern) {
 	}
 }
 
-// Stroke strokes the current path
-func (r *rasterContext) Stroke() {
-	if len(r.path) == 0 {
-		return
-	}
-
-	var lastX, lastY float64
-	var startX, startY float64
-	hasStart := false
-
-	for _, pt := range r.path {
-		switch pt.op {
-		case opMoveTo:
-			lastX, lastY = pt.x, pt.y
-			startX, startY = pt.x, pt.y
-			hasStart = true
-		case opLineTo:
-			if hasStart {
-				r.drawLine(lastX, lastY, pt.x, pt.y, r.stroke)
-			}
-			lastX, lastY = pt.x, pt.y
-		case opCurveTo:
-			if hasStart {
-				// Draw curve by flattening it with high quality
-				r.drawCurve(lastX, lastY, pt.cp1x, pt.cp1y, pt.cp2x, pt.cp2y, pt.x, pt.y, r.stroke)
-			}
-			lastX, lastY = pt.x, pt.y
-		case opClose:
-			if hasStart {
-				r.drawLine(lastX, lastY, startX, startY, r.stroke)
-			}
+// Fill fills the current path with antialiasing
+func (r *rasterContext) Fill() {
+	r.pathCoverage(func(x, y int, alpha float64) {
+		// Use surface pattern, gradient, or solid color
+		pixelColor := r.color
+		if r.surfacePattern != nil {
+			pixelColor = r.getSurfacePatternColor(float64(x), float64(y))
+		} else if r.gradientPattern != nil {
+			pixelColor = r.getGradientColor(float64(x), float64(y))
 		}
-	}
+		r.blendPixel(x, y, pixelColor, alpha)
+	})
 }
 
-// drawCurve draws a cubic Bezier curve by flattening it adaptively
-func (r *rasterContext) drawCurve(x0, y0, x1, y1, x2, y2, x3, y3 float64, c color.Color) {
-	// Adaptive subdivision with high quality tolerance (smaller = smoother)
-	r.drawCurveRecursive(x0, y0, x1, y1, x2, y2, x3, y3, c, 0.05, 0)
+// ClipMask 把当前路径光栅化为设备空间的覆盖率遮罩，并与现有裁剪区域求交
+// 遮罩只覆盖路径的设备空间包围盒与现有裁剪的交集，之外的 AlphaAt 为 0，即完全裁掉
+func (r *rasterContext) ClipMask() *image.Alpha {
+	path, area := r.devicePath()
+	mask := image.NewAlpha(area)
+	r.coverage(path, area, func(x, y int, alpha float64) {
+		if r.clip != nil {
+			alpha *= float64(r.clip.AlphaAt(x, y).A) / 255
+		}
+		mask.SetAlpha(x, y, color.Alpha{A: uint8(math.Round(alpha * 255))})
+	})
+	return mask
 }
 
-// drawCurveRecursive recursively subdivides and draws a cubic Bezier curve
-func (r *rasterContext) drawCurveRecursive(x0, y0, x1, y1, x2, y2, x3, y3 float64, c color.Color, tolerance float64, depth int) {
-	// Limit recursion depth to prevent stack overflow
-	if depth > 12 {
-		r.drawLine(x0, y0, x3, y3, c)
-		return
-	}
-
-	// Check if curve is flat enough using distance from control points to line
-	dx := x3 - x0
-	dy := y3 - y0
-	d2 := math.Abs((x1-x3)*dy - (y1-y3)*dx)
-	d3 := math.Abs((x2-x3)*dy - (y2-y3)*dx)
-
-	if (d2+d3)*(d2+d3) < tolerance*(dx*dx+dy*dy) {
-		r.drawLine(x0, y0, x3, y3, c)
-		return
-	}
-
-	// Subdivide curve using De Casteljau's algorithm
-	x01 := (x0 + x1) / 2
-	y01 := (y0 + y1) / 2
-	x12 := (x1 + x2) / 2
-	y12 := (y1 + y2) / 2
-	x23 := (x2 + x3) / 2
-	y23 := (y2 + y3) / 2
-	x012 := (x01 + x12) / 2
-	y012 := (y01 + y12) / 2
-	x123 := (x12 + x23) / 2
-	y123 := (y12 + y23) / 2
-	x0123 := (x012 + x123) / 2
-	y0123 := (y012 + y123) / 2
-
-	// Recursively draw both halves
-	r.drawCurveRecursive(x0, y0, x01, y01, x012, y012, x0123, y0123, c, tolerance, depth+1)
-	r.drawCurveRecursive(x0123, y0123, x123, y123, x23, y23, x3, y3, c, tolerance, depth+1)
+// pathCoverage computes the antialiased coverage of the current path and
+// calls fn for every device pixel with non-zero coverage
+func (r *rasterContext) pathCoverage(fn func(x, y int, alpha float64)) {
+	path, area := r.devicePath()
+	r.coverage(path, area, fn)
 }
 
-// Fill fills the current path with antialiasing
-func (r *rasterContext) Fill() {
+// devicePath transforms the current path to device space and returns it with
+// the pixel area it can cover: its bounding box clipped to the image and, when
+// set, to the clip mask's bounds
+func (r *rasterContext) devicePath() ([]transformedPoint, image.Rectangle) {
 	if len(r.path) == 0 {
-		return
+		return nil, image.Rectangle{}
 	}
 
 	bounds := r.img.Bounds()
@@ -314,14 +304,32 @@ func (r *rasterContext) Fill() {
 	y1 := int(math.Max(minY-1, float64(bounds.Min.Y)))
 	x2 := int(math.Min(maxX+1, float64(bounds.Max.X)))
 	y2 := int(math.Min(maxY+1, float64(bounds.Max.Y)))
+	area := image.Rectangle{Min: image.Pt(x1, y1), Max: image.Pt(x2, y2)}
+	if r.clip != nil {
+		area = area.Intersect(r.clip.Rect)
+	}
+	if area.Empty() {
+		return nil, image.Rectangle{}
+	}
+
+	// Curves are flattened once here so the per-sample test only sees lines
+	return flattenTransformedPath(transformedPath, flattenTolerance), area
+}
+
+// coverage supersamples every pixel of area against the device-space path
+// and calls fn for the pixels with non-zero coverage
+func (r *rasterContext) coverage(transformedPath []transformedPoint, area image.Rectangle, fn func(x, y int, alpha float64)) {
 
 	// Fill using supersampling antialiasing (4x4 grid per pixel)
-	const samples = 4
-	const invSamples = 1.0 / (samples * samples)
+	samples := 4
+	if r.aliased {
+		samples = 1
+	}
+	invSamples := 1.0 / float64(samples*samples)
 
 	pixelCount := 0
-	for y := y1; y < y2; y++ {
-		for x := x1; x < x2; x++ {
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
 			pixelCount++
 			// Count how many subpixel samples are inside the path
 			coverage := 0
@@ -338,15 +346,7 @@ func (r *rasterContext) Fill() {
 
 			// Apply antialiasing based on coverage
 			if coverage > 0 {
-				alpha := float64(coverage) * invSamples
-				// Use surface pattern, gradient, or solid color
-				pixelColor := r.color
-				if r.surfacePattern != nil {
-					pixelColor = r.getSurfacePatternColor(float64(x), float64(y))
-				} else if r.gradientPattern != nil {
-					pixelColor = r.getGradientColor(float64(x), float64(y))
-				}
-				r.blendPixel(x, y, pixelColor, alpha)
+				fn(x, y, float64(coverage)*invSamples)
 			}
 		}
 	}
@@ -359,20 +359,44 @@ func (r *rasterContext) blendPixel(x, y int, c color.Color, alpha float64) {
 		return
 	}
 
+	// 裁剪区域之外（或部分覆盖的边缘）按遮罩覆盖率衰减
+	if r.clip != nil {
+		alpha *= float64(r.clip.AlphaAt(x, y).A) / 255
+		if alpha <= 0 {
+			return
+		}
+	}
+	r.painted = r.painted.Union(image.Rect(x, y, x+1, y+1))
+
+	// PDF 分离/非分离混合模式：按 PDF 32000-1 11.3.5 与背景混合
+	if isBlendModeOperator(r.operator) {
+		src := color.NRGBAModel.Convert(c).(color.NRGBA)
+		src.A = uint8(math.Round(float64(src.A) * alpha))
+		dst := color.NRGBAModel.Convert(r.img.At(x, y)).(color.NRGBA)
+		r.img.Set(x, y, PorterDuffBlend(src, dst, r.operator))
+		return
+	}
+
 	// Get source color components (non-premultiplied)
-	sr, sg, sb, sa := c.RGBA()
-	srcR := float64(sr>>8) / 255.0
-	srcG := float64(sg>>8) / 255.0
-	srcB := float64(sb>>8) / 255.0
-	srcA := float64(sa>>8) / 255.0 * alpha
-
-	// Get destination color (RGBA format is non-premultiplied)
-	dst := r.img.At(x, y)
-	dr, dg, db, da := dst.RGBA()
-	dstR := float64(dr>>8) / 255.0
-	dstG := float64(dg>>8) / 255.0
-	dstB := float64(db>>8) / 255.0
-	dstA := float64(da>>8) / 255.0
+	// color.Color.RGBA() 返回预乘值，先转换为 NRGBA 再参与下面的预乘计算
+	src := color.NRGBAModel.Convert(c).(color.NRGBA)
+	srcR := float64(src.R) / 255.0
+	srcG := float64(src.G) / 255.0
+	srcB := float64(src.B) / 255.0
+	srcA := float64(src.A) / 255.0 * alpha
+
+	// Get destination color (non-premultiplied)
+	dst := color.NRGBAModel.Convert(r.img.At(x, y)).(color.NRGBA)
+	dstR := float64(dst.R) / 255.0
+	dstG := float64(dst.G) / 255.0
+	dstB := float64(dst.B) / 255.0
+	dstA := float64(dst.A) / 255.0
+
+	// Gamma-correct mode: mix colour in linear light (alpha is already linear)
+	if r.linearBlend {
+		srcR, srcG, srcB = srgbDecode8(src.R), srgbDecode8(src.G), srgbDecode8(src.B)
+		dstR, dstG, dstB = srgbDecode8(dst.R), srgbDecode8(dst.G), srgbDecode8(dst.B)
+	}
 
 	// Premultiply source color
 	srcRp := srcR * srcA
@@ -406,215 +430,105 @@ func (r *rasterContext) blendPixel(x, y int, c color.Color, alpha float64) {
 		B: uint8(math.Min(math.Max(outB*255, 0), 255)),
 		A: uint8(math.Min(math.Max(outA*255, 0), 255)),
 	}
+	if r.linearBlend {
+		result.R, result.G, result.B = srgbEncode8(outR), srgbEncode8(outG), srgbEncode8(outB)
+	}
 
 	r.img.Set(x, y, result)
 }
 
-// pointInTransformedPath checks if a point is inside a transformed path
+// isBlendModeOperator reports whether op is one of the PDF blend modes (Multiply … Luminosity)
+func isBlendModeOperator(op Operator) bool {
+	return op >= OperatorMultiply && op <= OperatorHslLuminosity
+}
+
+// pointInTransformedPath checks if a point is inside a flattened transformed
+// path (moveTo/lineTo/close only, see flattenTransformedPath) using the
+// context's fill rule
 func (r *rasterContext) pointInTransformedPath(x, y float64, path []transformedPoint) bool {
-	winding := 0
+	winding, crossings := 0, 0
 	var lastX, lastY float64
 	var startX, startY float64
 	hasStart := false
 
+	edge := func(x1, y1, x2, y2 float64) {
+		if crossesRay(x1, y1, x2, y2, x, y) {
+			crossings++
+			if y1 <= y {
+				winding++
+			} else {
+				winding--
+			}
+		}
+	}
+
 	for _, pt := range path {
 		switch pt.op {
 		case opMoveTo:
 			lastX, lastY = pt.x, pt.y
 			startX, startY = pt.x, pt.y
 			hasStart = true
-		case opLineTo:
+		case opLineTo, opCurveTo:
+			// Curves are expected to be flattened already; treat a stray one as its chord
 			if hasStart {
-				if crossesRay(lastX, lastY, pt.x, pt.y, x, y) {
-					if lastY <= y {
-						winding++
-					} else {
-						winding--
-					}
-				}
-			}
-			lastX, lastY = pt.x, pt.y
-		case opCurveTo:
-			if hasStart {
-				// For curves, check crossings along the curve
-				winding += curveCrossings(lastX, lastY, pt.cp1x, pt.cp1y, pt.cp2x, pt.cp2y, pt.x, pt.y, x, y)
+				edge(lastX, lastY, pt.x, pt.y)
 			}
 			lastX, lastY = pt.x, pt.y
 		case opClose:
 			if hasStart {
-				if crossesRay(lastX, lastY, startX, startY, x, y) {
-					if lastY <= y {
-						winding++
-					} else {
-						winding--
-					}
-				}
+				edge(lastX, lastY, startX, startY)
 			}
+			// The current point returns to the subpath start, so a repeated
+			// close (re followed by h) does not count the closing edge twice
+			lastX, lastY = startX, startY
 		}
 	}
 
+	if r.fillRule == FillRuleEvenOdd {
+		return crossings%2 != 0
+	}
 	return winding != 0
 }
 
-// drawLine draws an antialiased line with specified width
-func (r *rasterContext) drawLine(x0, y0, x1, y1 float64, c color.Color) {
-	// Transform points
-	x0t, y0t := MatrixTransformPoint(&r.matrix, x0, y0)
-	x1t, y1t := MatrixTransformPoint(&r.matrix, x1, y1)
-
-	// Calculate line direction and length
-	dx := x1t - x0t
-	dy := y1t - y0t
-	length := math.Sqrt(dx*dx + dy*dy)
-
-	if length < 0.01 {
-		// Line is too short, just draw a point
-		r.drawAntialiasedCircle(x0t, y0t, r.width/2, c)
-		return
-	}
-
-	// Normalize direction
-	dx /= length
-	dy /= length
-
-	// Calculate bounding box
-	halfWidth := r.width / 2
-	minX := math.Min(x0t, x1t) - halfWidth - 1
-	maxX := math.Max(x0t, x1t) + halfWidth + 1
-	minY := math.Min(y0t, y1t) - halfWidth - 1
-	maxY := math.Max(y0t, y1t) + halfWidth + 1
-
-	bounds := r.img.Bounds()
-	x1i := int(math.Max(minX, float64(bounds.Min.X)))
-	y1i := int(math.Max(minY, float64(bounds.Min.Y)))
-	x2i := int(math.Min(maxX, float64(bounds.Max.X)))
-	y2i := int(math.Min(maxY, float64(bounds.Max.Y)))
-
-	// Draw antialiased line using distance field
-	for y := y1i; y < y2i; y++ {
-		for x := x1i; x < x2i; x++ {
-			// Calculate distance from pixel center to line segment
-			px_center := float64(x) + 0.5
-			py_center := float64(y) + 0.5
-
-			dist := r.pointToLineSegmentDistance(px_center, py_center, x0t, y0t, x1t, y1t)
-
-			// Calculate coverage based on distance
-			coverage := 1.0 - math.Max(0, math.Min(1, dist-halfWidth+0.5))
-
-			if coverage > 0 {
-				r.blendPixel(x, y, c, coverage)
-			}
-		}
-	}
-}
+// flattenTolerance is the maximum distance in device pixels between a curve
+// and the line segments that replace it
+const flattenTolerance = 0.1
 
-// drawAntialiasedCircle draws an antialiased circle (used for line caps)
-func (r *rasterContext) drawAntialiasedCircle(cx, cy, radius float64, c color.Color) {
-	bounds := r.img.Bounds()
-	x1 := int(math.Max(cx-radius-1, float64(bounds.Min.X)))
-	y1 := int(math.Max(cy-radius-1, float64(bounds.Min.Y)))
-	x2 := int(math.Min(cx+radius+1, float64(bounds.Max.X)))
-	y2 := int(math.Min(cy+radius+1, float64(bounds.Max.Y)))
-
-	for y := y1; y < y2; y++ {
-		for x := x1; x < x2; x++ {
-			px := float64(x) + 0.5
-			py := float64(y) + 0.5
-			dx := px - cx
-			dy := py - cy
-			dist := math.Sqrt(dx*dx + dy*dy)
-
-			// Antialiased edge
-			coverage := 1.0 - math.Max(0, math.Min(1, dist-radius+0.5))
-
-			if coverage > 0 {
-				r.blendPixel(x, y, c, coverage)
-			}
+// flattenTransformedPath replaces every cubic curve in path with line
+// segments that stay within tolerance of the curve (see flattenCubic)
+func flattenTransformedPath(path []transformedPoint, tolerance float64) []transformedPoint {
+	hasCurve := false
+	for _, pt := range path {
+		if pt.op == opCurveTo {
+			hasCurve = true
+			break
 		}
 	}
-}
-
-// pointToLineSegmentDistance calculates the distance from a point to a line segment
-func (r *rasterContext) pointToLineSegmentDistance(px, py, x0, y0, x1, y1 float64) float64 {
-	dx := x1 - x0
-	dy := y1 - y0
-	lengthSq := dx*dx + dy*dy
-
-	if lengthSq < 0.0001 {
-		// Line segment is a point
-		dpx := px - x0
-		dpy := py - y0
-		return math.Sqrt(dpx*dpx + dpy*dpy)
-	}
-
-	// Calculate projection parameter
-	t := ((px-x0)*dx + (py-y0)*dy) / lengthSq
-	t = math.Max(0, math.Min(1, t))
-
-	// Calculate closest point on segment
-	closestX := x0 + t*dx
-	closestY := y0 + t*dy
-
-	// Calculate distance
-	dpx := px - closestX
-	dpy := py - closestY
-	return math.Sqrt(dpx*dpx + dpy*dpy)
-}
-
-// curveCrossings counts how many times a cubic Bezier curve crosses a horizontal ray
-func curveCrossings(x0, y0, x1, y1, x2, y2, x3, y3, px, py float64) int {
-	// Subdivide curve and count crossings
-	return curveCrossingsRecursive(x0, y0, x1, y1, x2, y2, x3, y3, px, py, 0)
-}
-
-// curveCrossingsRecursive recursively subdivides curve to count ray crossings
-func curveCrossingsRecursive(x0, y0, x1, y1, x2, y2, x3, y3, px, py float64, depth int) int {
-	// Limit recursion depth
-	if depth > 12 {
-		if crossesRay(x0, y0, x3, y3, px, py) {
-			if y0 <= py {
-				return 1
-			}
-			return -1
-		}
-		return 0
+	if !hasCurve {
+		return path
 	}
 
-	// Check if curve is flat enough
-	dx := x3 - x0
-	dy := y3 - y0
-	d2 := math.Abs((x1-x3)*dy - (y1-y3)*dx)
-	d3 := math.Abs((x2-x3)*dy - (y2-y3)*dx)
-
-	if (d2+d3)*(d2+d3) < 0.05*(dx*dx+dy*dy) {
-		if crossesRay(x0, y0, x3, y3, px, py) {
-			if y0 <= py {
-				return 1
-			}
-			return -1
+	out := make([]transformedPoint, 0, len(path)*4)
+	var lastX, lastY, startX, startY float64
+	for _, pt := range path {
+		switch pt.op {
+		case opMoveTo:
+			lastX, lastY, startX, startY = pt.x, pt.y, pt.x, pt.y
+			out = append(out, pt)
+		case opCurveTo:
+			flattenCubic(lastX, lastY, pt.cp1x, pt.cp1y, pt.cp2x, pt.cp2y, pt.x, pt.y, tolerance, 0, func(x, y float64) {
+				out = append(out, transformedPoint{x: x, y: y, op: opLineTo})
+			})
+			lastX, lastY = pt.x, pt.y
+		case opClose:
+			lastX, lastY = startX, startY
+			out = append(out, pt)
+		default:
+			lastX, lastY = pt.x, pt.y
+			out = append(out, pt)
 		}
-		return 0
 	}
-
-	// Subdivide curve
-	x01 := (x0 + x1) / 2
-	y01 := (y0 + y1) / 2
-	x12 := (x1 + x2) / 2
-	y12 := (y1 + y2) / 2
-	x23 := (x2 + x3) / 2
-	y23 := (y2 + y3) / 2
-	x012 := (x01 + x12) / 2
-	y012 := (y01 + y12) / 2
-	x123 := (x12 + x23) / 2
-	y123 := (y12 + y23) / 2
-	x0123 := (x012 + x123) / 2
-	y0123 := (y012 + y123) / 2
-
-	// Count crossings in both halves
-	count := curveCrossingsRecursive(x0, y0, x01, y01, x012, y012, x0123, y0123, px, py, depth+1)
-	count += curveCrossingsRecursive(x0123, y0123, x123, y123, x23, y23, x3, y3, px, py, depth+1)
-	return count
+	return out
 }
 
 // crossesRay checks if a line segment crosses a horizontal ray from the point
@@ -634,6 +548,7 @@ func crossesRay(x1, y1, x2, y2, px, py float64) bool {
 // Clear fills the image with a color
 func (r *rasterContext) Clear(c color.Color) {
 	draw.Draw(r.img, r.img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
+	r.painted = r.img.Bounds()
 }
 
 // getGradientColor calculates the color at a given point for the current gradient pattern
@@ -893,14 +808,20 @@ func (r *rasterContext) getSurfacePatternColor(x, y float64) color.Color {
 		return r.color
 	}
 
-	bounds := goImg.Bounds()
-
-	// Convert to integer coordinates
-	ix := int(math.Floor(px))
-	iy := int(math.Floor(py))
-
-	// Handle extend modes
 	extend := surfPattern.GetExtend()
+	switch surfPattern.GetFilter() {
+	case FilterGood, FilterBest, FilterBilinear, FilterGaussian:
+		// The device pixel centre is sampled between the four nearest texel centres
+		cx, cy := MatrixTransformPoint(&invMatrix, x+0.5, y+0.5)
+		cx, cy = MatrixTransformPoint(patternMatrix, cx, cy)
+		return sampleBilinear(goImg, cx, cy, extend)
+	}
+	return sampleNearest(goImg, int(math.Floor(px)), int(math.Floor(py)), extend)
+}
+
+// surfaceTexel maps texel coordinates into the image according to the
+// extend mode; ok is false for texels outside an ExtendNone image
+func surfaceTexel(bounds image.Rectangle, ix, iy int, extend Extend) (int, int, bool) {
 	switch extend {
 	case ExtendRepeat:
 		// Wrap coordinates
@@ -933,26 +854,87 @@ func (r *rasterContext) getSurfacePatternColor(x, y float64) color.Color {
 			}
 		}
 	case ExtendPad:
-		// Clamp to edges
-		if ix < bounds.Min.X {
-			ix = bounds.Min.X
-		}
-		if ix >= bounds.Max.X {
-			ix = bounds.Max.X - 1
-		}
-		if iy < bounds.Min.Y {
-			iy = bounds.Min.Y
-		}
-		if iy >= bounds.Max.Y {
-			iy = bounds.Max.Y - 1
-		}
+		ix, iy = clampTexel(bounds, ix, iy)
 	default: // ExtendNone
+		if ix < bounds.Min.X || ix >= bounds.Max.X || iy < bounds.Min.Y || iy >= bounds.Max.Y {
+			return 0, 0, false
+		}
+	}
+	return ix, iy, true
+}
+
+// clampTexel clamps texel coordinates to the image edges
+func clampTexel(bounds image.Rectangle, ix, iy int) (int, int) {
+	if ix < bounds.Min.X {
+		ix = bounds.Min.X
+	}
+	if ix >= bounds.Max.X {
+		ix = bounds.Max.X - 1
+	}
+	if iy < bounds.Min.Y {
+		iy = bounds.Min.Y
+	}
+	if iy >= bounds.Max.Y {
+		iy = bounds.Max.Y - 1
+	}
+	return ix, iy
+}
+
+// sampleNearest returns the texel containing (ix, iy)
+func sampleNearest(img image.Image, ix, iy int, extend Extend) color.Color {
+	ix, iy, ok := surfaceTexel(img.Bounds(), ix, iy, extend)
+	if !ok {
 		// Return transparent for out-of-bounds
+		return color.NRGBA{R: 0, G: 0, B: 0, A: 0}
+	}
+	return img.At(ix, iy)
+}
+
+// sampleBilinear interpolates the four texels around the point (px, py) in
+// image space. Premultiplied components are interpolated so transparent
+// texels do not bleed their colour.
+func sampleBilinear(img image.Image, px, py float64, extend Extend) color.Color {
+	bounds := img.Bounds()
+	if extend == ExtendNone {
+		ix, iy := int(math.Floor(px)), int(math.Floor(py))
 		if ix < bounds.Min.X || ix >= bounds.Max.X || iy < bounds.Min.Y || iy >= bounds.Max.Y {
 			return color.NRGBA{R: 0, G: 0, B: 0, A: 0}
 		}
 	}
 
-	// Get the color at the calculated position
-	return goImg.At(ix, iy)
+	// Texel centres are at integer + 0.5
+	fx, fy := px-0.5, py-0.5
+	x0, y0 := int(math.Floor(fx)), int(math.Floor(fy))
+	tx, ty := fx-float64(x0), fy-float64(y0)
+
+	var sum [4]float64
+	for _, s := range [4]struct {
+		dx, dy int
+		w      float64
+	}{
+		{0, 0, (1 - tx) * (1 - ty)},
+		{1, 0, tx * (1 - ty)},
+		{0, 1, (1 - tx) * ty},
+		{1, 1, tx * ty},
+	} {
+		if s.w == 0 {
+			continue
+		}
+		ix, iy, ok := surfaceTexel(bounds, x0+s.dx, y0+s.dy, extend)
+		if !ok {
+			// ExtendNone: the image edge is extended for interpolation
+			ix, iy = clampTexel(bounds, x0+s.dx, y0+s.dy)
+		}
+		r, g, b, a := img.At(ix, iy).RGBA()
+		sum[0] += s.w * float64(r)
+		sum[1] += s.w * float64(g)
+		sum[2] += s.w * float64(b)
+		sum[3] += s.w * float64(a)
+	}
+	return color.RGBA64{
+		R: uint16(math.Round(sum[0])),
+		G: uint16(math.Round(sum[1])),
+		B: uint16(math.Round(sum[2])),
+		A: uint16(math.Round(sum[3])),
+	}
 }
@@ -0,0 +1,352 @@
+package gopdf
+
+import (
+	"math"
+)
+
+// strokeSubpath is a subpath flattened to device space for stroking
+type strokeSubpath struct {
+	pts    []point
+	closed bool
+	// degenerate is set for a subpath that has segments but no length
+	degenerate bool
+}
+
+// flattenStrokePath transforms the current path to device space and
+// flattens curves into polylines. Consecutive duplicate points are dropped.
+func (r *rasterContext) flattenStrokePath() []strokeSubpath {
+	var subpaths []strokeSubpath
+	var cur *strokeSubpath
+	var lastX, lastY float64 // user space, for curve control points
+	hasSegment := false
+
+	add := func(x, y float64) {
+		p := point{x, y}
+		if n := len(cur.pts); n > 0 && math.Abs(cur.pts[n-1].x-p.x) < 1e-9 && math.Abs(cur.pts[n-1].y-p.y) < 1e-9 {
+			return
+		}
+		cur.pts = append(cur.pts, p)
+	}
+	finish := func() {
+		if cur != nil && hasSegment {
+			cur.degenerate = len(cur.pts) < 2
+			subpaths = append(subpaths, *cur)
+		}
+		cur = nil
+		hasSegment = false
+	}
+	start := func(x, y float64) {
+		finish()
+		cur = &strokeSubpath{}
+		dx, dy := MatrixTransformPoint(&r.matrix, x, y)
+		add(dx, dy)
+		lastX, lastY = x, y
+	}
+
+	for _, pt := range r.path {
+		switch pt.op {
+		case opMoveTo:
+			start(pt.x, pt.y)
+		case opLineTo:
+			if cur == nil {
+				start(pt.x, pt.y)
+			}
+			dx, dy := MatrixTransformPoint(&r.matrix, pt.x, pt.y)
+			add(dx, dy)
+			hasSegment = true
+			lastX, lastY = pt.x, pt.y
+		case opCurveTo:
+			if cur == nil {
+				start(lastX, lastY)
+			}
+			x0, y0 := MatrixTransformPoint(&r.matrix, lastX, lastY)
+			x1, y1 := MatrixTransformPoint(&r.matrix, pt.cp1x, pt.cp1y)
+			x2, y2 := MatrixTransformPoint(&r.matrix, pt.cp2x, pt.cp2y)
+			x3, y3 := MatrixTransformPoint(&r.matrix, pt.x, pt.y)
+			flattenCubic(x0, y0, x1, y1, x2, y2, x3, y3, 0.2, 0, add)
+			hasSegment = true
+			lastX, lastY = pt.x, pt.y
+		case opClose:
+			if cur != nil {
+				// Drop the closing point when it repeats the start
+				if n := len(cur.pts); n > 2 && math.Abs(cur.pts[n-1].x-cur.pts[0].x) < 1e-9 && math.Abs(cur.pts[n-1].y-cur.pts[0].y) < 1e-9 {
+					cur.pts = cur.pts[:n-1]
+				}
+				cur.closed = true
+				hasSegment = true
+				// A new subpath after closepath starts at the same point
+				first := cur.pts[0]
+				finish()
+				cur = &strokeSubpath{pts: []point{first}}
+			}
+		}
+	}
+	finish()
+	return subpaths
+}
+
+// flattenCubic adaptively subdivides a cubic Bezier curve and calls add for
+// every point after the start point. A piece is drawn as a line once its
+// control points are within tolerance of the chord.
+func flattenCubic(x0, y0, x1, y1, x2, y2, x3, y3, tolerance float64, depth int, add func(x, y float64)) {
+	var d2, d3 float64
+	dx := x3 - x0
+	dy := y3 - y0
+	if length := math.Hypot(dx, dy); length > 1e-12 {
+		d2 = math.Abs((x1-x3)*dy-(y1-y3)*dx) / length
+		d3 = math.Abs((x2-x3)*dy-(y2-y3)*dx) / length
+	} else {
+		// Closed loop: measure the control points from the end points
+		d2 = math.Hypot(x1-x0, y1-y0)
+		d3 = math.Hypot(x2-x0, y2-y0)
+	}
+
+	// Limit recursion depth to prevent stack overflow
+	if depth > 12 || d2+d3 < tolerance {
+		add(x3, y3)
+		return
+	}
+
+	// Subdivide curve using De Casteljau's algorithm
+	x01, y01 := (x0+x1)/2, (y0+y1)/2
+	x12, y12 := (x1+x2)/2, (y1+y2)/2
+	x23, y23 := (x2+x3)/2, (y2+y3)/2
+	x012, y012 := (x01+x12)/2, (y01+y12)/2
+	x123, y123 := (x12+x23)/2, (y12+y23)/2
+	x0123, y0123 := (x012+x123)/2, (y012+y123)/2
+
+	flattenCubic(x0, y0, x01, y01, x012, y012, x0123, y0123, tolerance, depth+1, add)
+	flattenCubic(x0123, y0123, x123, y123, x23, y23, x3, y3, tolerance, depth+1, add)
+}
+
+// strokeCoverage accumulates antialiased stroke coverage in device space.
+// Pieces of the stroke (segment bodies, joins, caps) overlap, so coverage is
+// combined with max and every pixel is blended once.
+type strokeCoverage struct {
+	x0, y0, w, h int
+	cov          []float64
+}
+
+func newStrokeCoverage(x0, y0, x1, y1 int) *strokeCoverage {
+	w, h := x1-x0, y1-y0
+	if w < 0 {
+		w = 0
+	}
+	if h < 0 {
+		h = 0
+	}
+	return &strokeCoverage{x0: x0, y0: y0, w: w, h: h, cov: make([]float64, w*h)}
+}
+
+// region calls fn for the pixels of the device rectangle that are inside the
+// coverage area and stores the larger coverage
+func (s *strokeCoverage) region(minX, minY, maxX, maxY float64, fn func(px, py float64) float64) {
+	x1 := int(math.Max(math.Floor(minX)-1, float64(s.x0)))
+	y1 := int(math.Max(math.Floor(minY)-1, float64(s.y0)))
+	x2 := int(math.Min(math.Ceil(maxX)+1, float64(s.x0+s.w)))
+	y2 := int(math.Min(math.Ceil(maxY)+1, float64(s.y0+s.h)))
+	for y := y1; y < y2; y++ {
+		for x := x1; x < x2; x++ {
+			c := fn(float64(x)+0.5, float64(y)+0.5)
+			i := (y-s.y0)*s.w + (x - s.x0)
+			if c > s.cov[i] {
+				s.cov[i] = c
+			}
+		}
+	}
+}
+
+// segment covers the rectangle of half-width hw around p0-p1, extended by
+// ext0 before p0 and ext1 after p1 (square caps)
+func (s *strokeCoverage) segment(p0, p1 point, hw, ext0, ext1 float64) {
+	dx, dy := p1.x-p0.x, p1.y-p0.y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+	dx, dy = dx/length, dy/length
+	pad := hw + math.Max(ext0, ext1)
+	s.region(math.Min(p0.x, p1.x)-pad, math.Min(p0.y, p1.y)-pad, math.Max(p0.x, p1.x)+pad, math.Max(p0.y, p1.y)+pad,
+		func(px, py float64) float64 {
+			u := (px-p0.x)*dx + (py-p0.y)*dy
+			v := (px-p0.x)*-dy + (py-p0.y)*dx
+			return clamp01(hw-math.Abs(v)+0.5) * clamp01(u+ext0+0.5) * clamp01(length+ext1-u+0.5)
+		})
+}
+
+// disc covers a circle of radius hw (round joins and caps)
+func (s *strokeCoverage) disc(c point, hw float64) {
+	s.region(c.x-hw, c.y-hw, c.x+hw, c.y+hw, func(px, py float64) float64 {
+		return clamp01(hw - math.Hypot(px-c.x, py-c.y) + 0.5)
+	})
+}
+
+// polygon covers a small convex polygon using 4x4 supersampling
+func (s *strokeCoverage) polygon(pts ...point) {
+	minX, minY := math.MaxFloat64, math.MaxFloat64
+	maxX, maxY := -math.MaxFloat64, -math.MaxFloat64
+	path := make([]transformedPoint, 0, len(pts)+1)
+	for i, p := range pts {
+		op := opLineTo
+		if i == 0 {
+			op = opMoveTo
+		}
+		path = append(path, transformedPoint{x: p.x, y: p.y, op: op})
+		minX, minY = math.Min(minX, p.x), math.Min(minY, p.y)
+		maxX, maxY = math.Max(maxX, p.x), math.Max(maxY, p.y)
+	}
+	path = append(path, transformedPoint{op: opClose})
+
+	const samples = 4
+	var r rasterContext
+	s.region(minX, minY, maxX, maxY, func(px, py float64) float64 {
+		inside := 0
+		for sy := 0; sy < samples; sy++ {
+			for sx := 0; sx < samples; sx++ {
+				if r.pointInTransformedPath(px-0.5+(float64(sx)+0.5)/samples, py-0.5+(float64(sy)+0.5)/samples, path) {
+					inside++
+				}
+			}
+		}
+		return float64(inside) / (samples * samples)
+	})
+}
+
+// join covers the outside of the corner at p between the segment arriving
+// in direction d0 and the one leaving in direction d1 (unit vectors)
+func (s *strokeCoverage) join(p point, d0, d1 point, hw float64, join LineJoin, miterLimit float64) {
+	cross := d0.x*d1.y - d0.y*d1.x
+	dot := d0.x*d1.x + d0.y*d1.y
+	if math.Abs(cross) < 1e-9 && dot > 0 {
+		return // collinear, the segment bodies already meet
+	}
+	if join == LineJoinRound {
+		s.disc(p, hw)
+		return
+	}
+
+	// The outer side is opposite to the turn direction
+	side := 1.0
+	if cross > 0 {
+		side = -1
+	}
+	a := point{p.x - side*hw*d0.y, p.y + side*hw*d0.x}
+	b := point{p.x - side*hw*d1.y, p.y + side*hw*d1.x}
+
+	// Miter length / line width = 1 / sin(phi/2), phi being the angle
+	// between the segments; past the limit the join falls back to bevel
+	if join == LineJoinMiter && 1+dot > 1e-9 {
+		if ratio := 1 / math.Sqrt((1+dot)/2); ratio <= miterLimit {
+			k := side * hw / (1 + dot)
+			tip := point{p.x + k*(-d0.y-d1.y), p.y + k*(d0.x+d1.x)}
+			s.polygon(p, a, tip, b)
+			return
+		}
+	}
+	s.polygon(p, a, b)
+}
+
+// Stroke strokes the current path, drawing line joins at corners and line
+// caps at the ends of open subpaths. The line width is in user space and is
+// scaled by the CTM (by the geometric mean of its axis scales when they
+// differ); a zero width draws a one-pixel hairline.
+func (r *rasterContext) Stroke() {
+	if len(r.path) == 0 || r.width < 0 {
+		return
+	}
+
+	subpaths := r.flattenStrokePath()
+	if len(subpaths) == 0 {
+		return
+	}
+
+	width := r.width * math.Sqrt(math.Abs(r.matrix.XX*r.matrix.YY-r.matrix.XY*r.matrix.YX))
+	if r.width == 0 {
+		width = 1
+	}
+	if width <= 0 {
+		return
+	}
+	hw := width / 2
+	miterLimit := r.miterLimit
+	if miterLimit < 1 {
+		miterLimit = 10
+	}
+
+	// Coverage area: the flattened points plus the widest possible join
+	reach := hw*math.Max(miterLimit, math.Sqrt2) + 2
+	minX, minY := math.MaxFloat64, math.MaxFloat64
+	maxX, maxY := -math.MaxFloat64, -math.MaxFloat64
+	for _, sp := range subpaths {
+		for _, p := range sp.pts {
+			minX, minY = math.Min(minX, p.x), math.Min(minY, p.y)
+			maxX, maxY = math.Max(maxX, p.x), math.Max(maxY, p.y)
+		}
+	}
+	bounds := r.img.Bounds()
+	cov := newStrokeCoverage(
+		int(math.Max(math.Floor(minX-reach), float64(bounds.Min.X))),
+		int(math.Max(math.Floor(minY-reach), float64(bounds.Min.Y))),
+		int(math.Min(math.Ceil(maxX+reach), float64(bounds.Max.X))),
+		int(math.Min(math.Ceil(maxY+reach), float64(bounds.Max.Y))),
+	)
+	if cov.w == 0 || cov.h == 0 {
+		return
+	}
+
+	for _, sp := range subpaths {
+		r.strokeSubpath(cov, sp, hw, miterLimit)
+	}
+
+	for i, c := range cov.cov {
+		if c > 0 {
+			r.blendPixel(cov.x0+i%cov.w, cov.y0+i/cov.w, r.stroke, c)
+		}
+	}
+}
+
+// strokeSubpath adds one flattened subpath to the stroke coverage
+func (r *rasterContext) strokeSubpath(cov *strokeCoverage, sp strokeSubpath, hw, miterLimit float64) {
+	pts := sp.pts
+	if sp.degenerate {
+		// Zero-length subpaths are only painted with round caps
+		if r.lineCap == LineCapRound && len(pts) > 0 {
+			cov.disc(pts[0], hw)
+		}
+		return
+	}
+
+	n := len(pts)
+	segments := n - 1
+	if sp.closed {
+		segments = n
+	}
+	dir := func(i int) point {
+		p0, p1 := pts[i%n], pts[(i+1)%n]
+		length := math.Hypot(p1.x-p0.x, p1.y-p0.y)
+		return point{(p1.x - p0.x) / length, (p1.y - p0.y) / length}
+	}
+
+	for i := 0; i < segments; i++ {
+		ext0, ext1 := 0.0, 0.0
+		if !sp.closed && r.lineCap == LineCapSquare {
+			if i == 0 {
+				ext0 = hw
+			}
+			if i == segments-1 {
+				ext1 = hw
+			}
+		}
+		cov.segment(pts[i], pts[(i+1)%n], hw, ext0, ext1)
+
+		// Join with the next segment
+		if i+1 < segments || sp.closed {
+			cov.join(pts[(i+1)%n], dir(i), dir((i+1)%segments), hw, r.lineJoin, miterLimit)
+		}
+	}
+
+	if !sp.closed && r.lineCap == LineCapRound {
+		cov.disc(pts[0], hw)
+		cov.disc(pts[n-1], hw)
+	}
+}
@@ -1,23 +1,40 @@
 package gopdf
 
 import (
+	"bytes"
+	gocontext "context"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
+	"image/jpeg"
 	"image/png"
+	"io"
+	"math"
 	"os"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/validate"
+	"golang.org/x/image/tiff"
 )
 
 // PDFReader 用于读取和渲染 PDF 文件
+// 构造后可被多个 goroutine 并发使用：所有缓存字段都由 mu 保护，
+// 渲染时每次调用读取独立的 PDF 上下文
 type PDFReader struct {
 	pdfPath        string
+	data           []byte             // 内存中的 PDF 数据（NewPDFReaderFromBytes），非 nil 时不读取 pdfPath
+	password       string             // 加密文档的打开密码（用户密码或所有者密码）
+	mu             sync.RWMutex       // 保护以下缓存字段
 	resourceCache  map[int]*Resources // 页面资源缓存
-	contextCache   *model.Context     // PDF 上下文缓存
 	pageCountCache int                // 页数缓存
 	pageDimsCache  []PageInfo         // 页面尺寸缓存
 }
@@ -31,167 +48,544 @@ func NewPDFReader(pdfPath string) *PDFReader {
 	}
 }
 
+// NewPDFReaderWithPassword 创建用于读取加密 PDF 的读取器
+// password 可以是用户密码（打开密码）或所有者密码；密码错误时各方法返回 ErrWrongPassword
+func NewPDFReaderWithPassword(pdfPath, password string) *PDFReader {
+	reader := NewPDFReader(pdfPath)
+	reader.password = password
+	return reader
+}
+
+// NewPDFReaderFromBytes 创建读取内存中 PDF 数据的读取器，无需先写入临时文件
+// 读取器直接引用 data，调用方在读取器使用期间不能修改它
+func NewPDFReaderFromBytes(data []byte) *PDFReader {
+	reader := NewPDFReader("")
+	reader.data = data
+	return reader
+}
+
+// NewPDFReaderFromReader 从 io.ReadSeeker（如 HTTP 请求体缓存、对象存储下载流）读取完整的 PDF 数据并创建读取器
+// 总是从偏移 0 读取全部数据；之后各方法都使用内存中的副本，不再访问 rs
+func NewPDFReaderFromReader(rs io.ReadSeeker) (*PDFReader, error) {
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek PDF reader: %w", err)
+	}
+	data, err := io.ReadAll(rs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF data: %w", err)
+	}
+	return NewPDFReaderFromBytes(data), nil
+}
+
+// ErrPasswordRequired 文档已加密，需要提供密码才能打开
+var ErrPasswordRequired = errors.New("gopdf: password required to open encrypted PDF")
+
+// ErrWrongPassword 提供的密码无法打开加密文档
+var ErrWrongPassword = errors.New("gopdf: wrong password for encrypted PDF")
+
+// readContext 读取并校验 PDF 上下文，加密文档使用读取器的密码解密
+// 内存中的文档每次从同一份数据解析出独立的上下文，与文件读取器一样可以并发使用
+func (r *PDFReader) readContext() (*model.Context, error) {
+	if r.data != nil {
+		return readContextFrom(bytes.NewReader(r.data), r.password)
+	}
+	return readContextFile(r.pdfPath, r.password)
+}
+
+// worker 返回读取同一文档、使用相同密码的新读取器，供并行渲染的各个 goroutine 使用
+func (r *PDFReader) worker() *PDFReader {
+	worker := NewPDFReaderWithPassword(r.pdfPath, r.password)
+	worker.data = r.data
+	return worker
+}
+
+// readContextFile 与 api.ReadContextFile 相同，但可以指定解密密码；
+// 密码缺失或错误时分别返回 ErrPasswordRequired 和 ErrWrongPassword
+func readContextFile(pdfPath, password string) (*model.Context, error) {
+	f, err := os.Open(pdfPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readContextFrom(f, password)
+}
+
+// readContextFrom 从 io.ReadSeeker 读取并校验 PDF 上下文，错误处理与 readContextFile 相同
+func readContextFrom(rs io.ReadSeeker, password string) (*model.Context, error) {
+	conf := model.NewDefaultConfiguration()
+	conf.UserPW = password
+	conf.OwnerPW = password
+
+	ctx, err := api.ReadContext(rs, conf)
+	if err != nil {
+		if errors.Is(err, pdfcpu.ErrWrongPassword) {
+			if password == "" {
+				return nil, ErrPasswordRequired
+			}
+			return nil, ErrWrongPassword
+		}
+		return nil, err
+	}
+
+	if err := validate.XRefTable(ctx); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
 // Close 关闭 PDF 读取器并清理缓存
 func (r *PDFReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.resourceCache = nil
-	r.contextCache = nil
 	r.pageDimsCache = nil
+	r.pageCountCache = -1
 	return nil
 }
 
+// Reset 让读取器改为读取 newPath 处的文档，复用已分配的缓存空间
+// 清除上一个文档的所有缓存、内存数据和密码；包级别的 CID 映射缓存保留，
+// 批量处理文件时可以用同一个读取器依次 Reset，避免每个文件重新分配和加载
+// Reset 不能与该读取器上正在进行的其他调用并发
+func (r *PDFReader) Reset(newPath string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pdfPath = newPath
+	r.data = nil
+	r.password = ""
+	if r.resourceCache == nil {
+		r.resourceCache = make(map[int]*Resources)
+	} else {
+		clear(r.resourceCache)
+	}
+	r.pageDimsCache = nil // 切片可能已返回给调用方，不复用其底层数组
+	r.pageCountCache = -1
+}
+
 // RenderPageToPNG 将 PDF 的指定页面渲染为 PNG 图片
 // pageNum: 页码（从 1 开始）
 // outputPath: 输出 PNG 文件路径
 // dpi: 渲染分辨率，默认 150
 func (r *PDFReader) RenderPageToPNG(pageNum int, outputPath string, dpi float64) error {
-	if dpi == 0 {
-		dpi = 150
+	return r.RenderPageToPNGContext(gocontext.Background(), pageNum, outputPath, dpi)
+}
+
+// RenderPageToPNGContext 与 RenderPageToPNG 相同，但可通过 ctx 取消或设置超时
+// 取消时返回的错误满足 errors.Is(err, ctx.Err())
+func (r *PDFReader) RenderPageToPNGContext(ctx gocontext.Context, pageNum int, outputPath string, dpi float64) error {
+	return r.renderPageToPNG(ctx, pageNum, outputPath, &RenderOptions{DPI: dpi})
+}
+
+// RenderPageToPNGWithOptions 使用渲染选项将页面渲染为 PNG 图片
+// 使用 opts.DPI（默认 150）、opts.OutputPath、opts.Content 和背景选项；
+// 例如 Content: ContentText 只渲染文本（用于 OCR 对比），ContentImages|ContentVectors 只渲染图形，
+// TransparentBackground: true 导出带透明度的 PNG
+func (r *PDFReader) RenderPageToPNGWithOptions(pageNum int, opts *RenderOptions) error {
+	if opts == nil || opts.OutputPath == "" {
+		return fmt.Errorf("output path is required")
 	}
+	return r.renderPageToPNG(gocontext.Background(), pageNum, opts.OutputPath, opts)
+}
 
-	// 获取页面数量
-	pageCount, err := api.PageCountFile(r.pdfPath)
+// renderPageToPNG 渲染页面并保存为 PNG
+func (r *PDFReader) renderPageToPNG(ctx gocontext.Context, pageNum int, outputPath string, opts *RenderOptions) error {
+	imgSurf, err := r.renderPageToSurface(ctx, pageNum, opts)
 	if err != nil {
-		return fmt.Errorf("failed to get page count: %w", err)
+		return err
 	}
+	defer imgSurf.Destroy()
 
-	if pageNum < 1 || pageNum > pageCount {
-		return fmt.Errorf("invalid page number: %d (total pages: %d)", pageNum, pageCount)
+	// 直接使用 Gopdf 保存 PNG
+	status := imgSurf.WriteToPNG(outputPath)
+	if status != StatusSuccess {
+		return fmt.Errorf("failed to write PNG: %v", status)
 	}
+	return nil
+}
+
+// RenderPageToImage 将 PDF 页面渲染为 image.Image
+// 优化：避免临时文件，直接从 surface 转换
+func (r *PDFReader) RenderPageToImage(pageNum int, dpi float64) (image.Image, error) {
+	return r.RenderPageToImageContext(gocontext.Background(), pageNum, dpi)
+}
 
-	// 获取页面尺寸
-	pageDims, err := api.PageDimsFile(r.pdfPath)
+// RenderPageToImageContext 与 RenderPageToImage 相同，但可通过 ctx 取消或设置超时
+// 渲染过程中按批检查 ctx，取消时返回的错误满足 errors.Is(err, ctx.Err())
+func (r *PDFReader) RenderPageToImageContext(ctx gocontext.Context, pageNum int, dpi float64) (image.Image, error) {
+	imgSurf, err := r.renderPageToSurface(ctx, pageNum, &RenderOptions{DPI: dpi})
 	if err != nil {
-		return fmt.Errorf("failed to get page dimensions: %w", err)
+		return nil, err
 	}
+	defer imgSurf.Destroy()
 
-	// 默认页面尺寸（Letter size: 8.5 x 11 inches）
-	widthPoints := 612.0  // 8.5 * 72
-	heightPoints := 792.0 // 11 * 72
+	// 优化：直接从 surface 转换，避免临时文件
+	return ConvertGopdfSurfaceToImage(imgSurf), nil
+}
 
-	if pageNum <= len(pageDims) {
-		dim := pageDims[pageNum-1]
-		widthPoints = dim.Width
-		heightPoints = dim.Height
+// RenderPageToImageWithOptions 使用渲染选项将页面渲染为 image.Image
+// opts 为 nil 时与 RenderPageToImage 相同（150 DPI、白色背景、渲染全部内容）；
+// 透明背景下未绘制的区域 alpha 为 0
+func (r *PDFReader) RenderPageToImageWithOptions(pageNum int, opts *RenderOptions) (image.Image, error) {
+	imgSurf, err := r.renderPageToSurface(gocontext.Background(), pageNum, opts)
+	if err != nil {
+		return nil, err
 	}
+	defer imgSurf.Destroy()
 
-	// 根据 DPI 计算渲染尺寸
-	scale := dpi / 72.0
-	width := int(widthPoints * scale)
-	height := int(heightPoints * scale)
+	return ConvertGopdfSurfaceToImage(imgSurf), nil
+}
 
-	// 使用 go-pdf 创建渲染表面
-	surface := NewImageSurface(FormatARGB32, width, height)
-	defer surface.Destroy()
+// FitMode 按目标像素尺寸渲染页面时的适配方式
+type FitMode int
 
-	gopdfCtx := NewContext(surface)
-	defer gopdfCtx.Destroy()
+const (
+	FitContain FitMode = iota // 等比缩放使整个页面可见，宽高比不同时两侧或上下留出背景色
+	FitCover                  // 等比缩放铺满目标区域，超出部分居中裁掉
+	FitStretch                // 宽高分别缩放到目标尺寸，不保持宽高比
+)
 
-	// 设置白色背景
-	gopdfCtx.SetSourceRGB(1, 1, 1)
-	gopdfCtx.Paint()
+// RenderPageToSize 把页面渲染为 targetW x targetH 像素的图像
+// 缩放比例由页面实际尺寸（已考虑 CropBox、/Rotate 和 /UserUnit）和 fit 计算，页面居中，空白处为白色背景
+func (r *PDFReader) RenderPageToSize(pageNum, targetW, targetH int, fit FitMode) (image.Image, error) {
+	if targetW <= 0 || targetH <= 0 {
+		return nil, fmt.Errorf("invalid target size: %dx%d", targetW, targetH)
+	}
+	imgSurf, err := r.renderPageToSurfaceSized(gocontext.Background(), pageNum, nil, &pageTargetSize{width: targetW, height: targetH, fit: fit})
+	if err != nil {
+		return nil, err
+	}
+	defer imgSurf.Destroy()
 
-	// 缩放以匹配 DPI
-	gopdfCtx.Scale(scale, scale)
+	return ConvertGopdfSurfaceToImage(imgSurf), nil
+}
 
-	// 渲染 PDF 内容到 Gopdf context
-	if err := renderPDFPageToGopdf(r.pdfPath, pageNum, gopdfCtx, widthPoints, heightPoints); err != nil {
-		return fmt.Errorf("failed to render PDF page: %w", err)
+// fitPageToSize 计算把 pageW x pageH 点的页面放入 width x height 像素时的缩放和居中偏移
+func fitPageToSize(pageW, pageH float64, width, height int, fit FitMode) (scaleX, scaleY, offsetX, offsetY float64) {
+	if pageW <= 0 || pageH <= 0 {
+		return 1, 1, 0, 0
+	}
+	scaleX = float64(width) / pageW
+	scaleY = float64(height) / pageH
+	switch fit {
+	case FitStretch:
+		return scaleX, scaleY, 0, 0
+	case FitCover:
+		scaleX = math.Max(scaleX, scaleY)
+	default:
+		scaleX = math.Min(scaleX, scaleY)
 	}
+	scaleY = scaleX
+	offsetX = (float64(width) - pageW*scaleX) / 2
+	offsetY = (float64(height) - pageH*scaleY) / 2
+	return scaleX, scaleY, offsetX, offsetY
+}
 
-	// 直接使用 Gopdf 保存 PNG
-	if imgSurf, ok := surface.(ImageSurface); ok {
-		status := imgSurf.WriteToPNG(outputPath)
-		if status != StatusSuccess {
-			return fmt.Errorf("failed to write PNG: %v", status)
+// thumbnailMinDPI 缩略图超采样渲染的最低分辨率，决定超采样倍数
+const thumbnailMinDPI = 144
+
+// thumbnailMaxSupersample 缩略图的最大超采样倍数
+const thumbnailMaxSupersample = 4
+
+// RenderPageThumbnail 渲染页面缩略图，较长边为 maxDim 像素
+// 直接以低 DPI 渲染会产生锯齿；这里先以整数倍超采样渲染，再按面积平均（盒式滤波）缩小。
+// 超采样倍数取使内部分辨率不低于 thumbnailMinDPI 的最小整数，最多 thumbnailMaxSupersample 倍
+func (r *PDFReader) RenderPageThumbnail(pageNum int, maxDim int) (image.Image, error) {
+	if maxDim <= 0 {
+		return nil, fmt.Errorf("invalid thumbnail size: %d", maxDim)
+	}
+	info, err := r.GetPageInfo(pageNum)
+	if err != nil {
+		return nil, err
+	}
+	longest := math.Max(info.Width, info.Height) * info.UserUnit
+	if longest <= 0 {
+		return nil, fmt.Errorf("invalid page size: %.2fx%.2f", info.Width, info.Height)
+	}
+
+	dpi := float64(maxDim) * 72 / longest
+	factor := int(math.Ceil(thumbnailMinDPI / dpi))
+	if factor < 1 {
+		factor = 1
+	} else if factor > thumbnailMaxSupersample {
+		factor = thumbnailMaxSupersample
+	}
+
+	imgSurf, err := r.renderPageToSurface(gocontext.Background(), pageNum, &RenderOptions{DPI: dpi * float64(factor)})
+	if err != nil {
+		return nil, err
+	}
+	defer imgSurf.Destroy()
+
+	width := int(math.Round(info.Width * info.UserUnit / longest * float64(maxDim)))
+	height := int(math.Round(info.Height * info.UserUnit / longest * float64(maxDim)))
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	debugPrintf("[RenderPageThumbnail] Page %d: %dx%d thumbnail, %dx supersampled at %.1f DPI\n",
+		pageNum, width, height, factor, dpi*float64(factor))
+	return boxDownsample(imgSurf.AsRGBA(), width, height), nil
+}
+
+// boxDownsample 按面积平均把 src 缩小到 width x height
+// 每个目标像素取其覆盖的源像素区域（含部分覆盖的边缘像素）的加权平均；
+// src 的颜色为非预乘值，按 alpha 加权平均以免透明像素的颜色渗入
+func boxDownsample(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	b := src.Bounds()
+	if b.Empty() {
+		return dst
+	}
+	sx := float64(b.Dx()) / float64(width)
+	sy := float64(b.Dy()) / float64(height)
+
+	for y := 0; y < height; y++ {
+		y0, y1 := float64(y)*sy, float64(y+1)*sy
+		for x := 0; x < width; x++ {
+			x0, x1 := float64(x)*sx, float64(x+1)*sx
+			var r, g, bl, a, area float64
+			for iy := int(y0); iy < b.Dy() && float64(iy) < y1; iy++ {
+				wy := math.Min(y1, float64(iy+1)) - math.Max(y0, float64(iy))
+				row := src.Pix[iy*src.Stride:]
+				for ix := int(x0); ix < b.Dx() && float64(ix) < x1; ix++ {
+					w := wy * (math.Min(x1, float64(ix+1)) - math.Max(x0, float64(ix)))
+					p := row[ix*4 : ix*4+4 : ix*4+4]
+					wa := w * float64(p[3])
+					r += wa * float64(p[0])
+					g += wa * float64(p[1])
+					bl += wa * float64(p[2])
+					a += wa
+					area += w
+				}
+			}
+			if a == 0 || area == 0 {
+				continue
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r/a + 0.5)
+			dst.Pix[i+1] = uint8(g/a + 0.5)
+			dst.Pix[i+2] = uint8(bl/a + 0.5)
+			dst.Pix[i+3] = uint8(a/area + 0.5)
 		}
-		return nil
 	}
+	return dst
+}
 
-	return fmt.Errorf("failed to convert surface to image surface")
+// ImageFormat 渲染输出的图像编码格式
+type ImageFormat int
+
+const (
+	ImageFormatPNG  ImageFormat = iota // PNG（无损）
+	ImageFormatJPEG                    // JPEG（有损，无 alpha）
+)
+
+// DefaultJPEGQuality JPEG 编码的默认质量
+const DefaultJPEGQuality = 90
+
+// RenderPageToWriter 将页面渲染并编码为 PNG 或 JPEG 写入 w，不产生临时文件
+// 适用于直接输出到 http.ResponseWriter 等场景；JPEG 使用 DefaultJPEGQuality
+func (r *PDFReader) RenderPageToWriter(pageNum int, dpi float64, w io.Writer, format ImageFormat) error {
+	return r.RenderPageToWriterWithQuality(pageNum, dpi, w, format, DefaultJPEGQuality)
 }
 
-// RenderPageToImage 将 PDF 页面渲染为 image.Image
-// 优化：避免临时文件，直接从 surface 转换
-func (r *PDFReader) RenderPageToImage(pageNum int, dpi float64) (image.Image, error) {
+// RenderPageToWriterWithQuality 与 RenderPageToWriter 相同，可指定 JPEG 质量（1-100）
+// PNG 格式忽略 jpegQuality
+func (r *PDFReader) RenderPageToWriterWithQuality(pageNum int, dpi float64, w io.Writer, format ImageFormat, jpegQuality int) error {
+	imgSurf, err := r.renderPageToSurface(gocontext.Background(), pageNum, &RenderOptions{DPI: dpi})
+	if err != nil {
+		return err
+	}
+	defer imgSurf.Destroy()
+
+	return encodeImage(w, ConvertGopdfSurfaceToImage(imgSurf), format, jpegQuality)
+}
+
+// encodeImage 按指定格式编码图像
+func encodeImage(w io.Writer, img image.Image, format ImageFormat, jpegQuality int) error {
+	switch format {
+	case ImageFormatPNG:
+		if err := png.Encode(w, img); err != nil {
+			return fmt.Errorf("failed to encode PNG: %w", err)
+		}
+	case ImageFormatJPEG:
+		if jpegQuality <= 0 || jpegQuality > 100 {
+			jpegQuality = DefaultJPEGQuality
+		}
+		if err := jpeg.Encode(w, flattenImageOnWhite(img), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return fmt.Errorf("failed to encode JPEG: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported image format: %d", format)
+	}
+	return nil
+}
+
+// renderPageToSurface 将页面渲染到新建的图像表面，调用方负责 Destroy
+// 输出尺寸按页面 /Rotate 计算：90°/270° 时宽高互换，与 PDF 阅读器显示一致
+// opts 为 nil 时使用默认选项
+func (r *PDFReader) renderPageToSurface(goCtx gocontext.Context, pageNum int, opts *RenderOptions) (ImageSurface, error) {
+	return r.renderPageToSurfaceSized(goCtx, pageNum, opts, nil)
+}
+
+// pageTargetSize 按目标像素尺寸渲染时的输出尺寸和适配方式
+type pageTargetSize struct {
+	width, height int
+	fit           FitMode
+}
+
+// renderPageToSurfaceSized 渲染页面到图像表面；target 为 nil 时按 opts.DPI 决定尺寸，
+// 否则输出 target 指定的像素尺寸，页面按适配方式缩放并居中，空白处为背景色
+func (r *PDFReader) renderPageToSurfaceSized(goCtx gocontext.Context, pageNum int, opts *RenderOptions, target *pageTargetSize) (ImageSurface, error) {
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+	dpi := opts.DPI
 	if dpi == 0 {
 		dpi = 150
 	}
 
-	// 使用缓存的页面数量
-	pageCount, err := r.GetPageCount()
+	if err := goCtx.Err(); err != nil {
+		return nil, err
+	}
+
+	// 渲染需要读取上下文，直接用它检查页码，不再单独获取页数
+	ctx, err := r.readContext()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get page count: %w", err)
+		return nil, fmt.Errorf("failed to read PDF context: %w", err)
 	}
 
-	if pageNum < 1 || pageNum > pageCount {
-		return nil, fmt.Errorf("invalid page number: %d (total pages: %d)", pageNum, pageCount)
+	if pageNum < 1 || pageNum > ctx.PageCount {
+		return nil, fmt.Errorf("invalid page number: %d (total pages: %d)", pageNum, ctx.PageCount)
 	}
 
-	// 使用缓存的页面信息
-	pageInfo, err := r.GetPageInfo(pageNum)
+	pageDict, _, _, err := ctx.PageDict(pageNum, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get page info: %w", err)
+		return nil, fmt.Errorf("failed to get page dict: %w", err)
 	}
 
-	widthPoints := pageInfo.Width
-	heightPoints := pageInfo.Height
+	// 旋转后的显示尺寸
+	geom := getPageGeometry(ctx, pageDict)
 
-	// 根据 DPI 计算渲染尺寸
-	scale := dpi / 72.0
-	width := int(widthPoints * scale)
-	height := int(heightPoints * scale)
+	// 根据 DPI 计算渲染尺寸，/UserUnit 放大每个用户空间单位的物理尺寸
+	scale := dpi / 72.0 * geom.UserUnit
+	width := int(geom.Width * scale)
+	height := int(geom.Height * scale)
+	scaleX, scaleY := scale, scale
+	var offsetX, offsetY float64
+	if target != nil {
+		width, height = target.width, target.height
+		scaleX, scaleY, offsetX, offsetY = fitPageToSize(geom.Width*geom.UserUnit, geom.Height*geom.UserUnit, width, height, target.fit)
+		scaleX *= geom.UserUnit
+		scaleY *= geom.UserUnit
+	}
 
 	// 使用 go-pdf 创建渲染表面
 	surface := NewImageSurface(FormatARGB32, width, height)
-	if surface == nil {
-		return nil, fmt.Errorf("failed to create image surface")
+	if surface.Status() != StatusSuccess {
+		return nil, fmt.Errorf("failed to create image surface: %v", surface.Status())
+	}
+
+	imgSurf, ok := surface.(ImageSurface)
+	if !ok {
+		surface.Destroy()
+		return nil, fmt.Errorf("failed to convert surface to image surface")
 	}
-	defer surface.Destroy()
 
 	gopdfCtx := NewContext(surface)
 	defer gopdfCtx.Destroy()
+	gopdfCtx.SetGammaCorrectBlending(opts.GammaCorrectBlending)
+	gopdfCtx.SetAntialias(opts.Antialias)
+
+	// 绘制背景：默认白色，TransparentBackground 时保持全透明
+	if !opts.TransparentBackground {
+		bg := RGB{R: 1, G: 1, B: 1}
+		if opts.Background != nil {
+			bg = *opts.Background
+		}
+		gopdfCtx.SetSourceRGB(bg.R, bg.G, bg.B)
+		gopdfCtx.Paint()
+	}
 
-	// 设置白色背景
-	gopdfCtx.SetSourceRGB(1, 1, 1)
-	gopdfCtx.Paint()
-
-	// 缩放以匹配 DPI
-	gopdfCtx.Scale(scale, scale)
+	// 缩放以匹配 DPI（或目标尺寸），按目标尺寸渲染时先平移使页面居中
+	gopdfCtx.Translate(offsetX, offsetY)
+	gopdfCtx.Scale(scaleX, scaleY)
 
 	// 渲染 PDF 内容到 Gopdf context
-	if err := renderPDFPageToGopdf(r.pdfPath, pageNum, gopdfCtx, widthPoints, heightPoints); err != nil {
+	if err := renderPageDictToGopdf(goCtx, ctx, pageDict, gopdfCtx, geom.Width, geom.Height, opts); err != nil {
+		surface.Destroy()
 		return nil, fmt.Errorf("failed to render PDF page: %w", err)
 	}
 
-	// 优化：直接从 surface 转换，避免临时文件
-	if imgSurf, ok := surface.(ImageSurface); ok {
-		img := ConvertGopdfSurfaceToImage(imgSurf)
-		return img, nil
-	}
-
-	return nil, fmt.Errorf("failed to convert surface to image")
+	return imgSurf, nil
 }
 
 // GetPageCount 获取 PDF 的页数
-// 优化：使用缓存避免重复读取
+// 优化：使用缓存避免重复读取；首次调用与 loadPageCache 共用一次读取，同时填充页数和页面尺寸缓存，
+// 不保留读取的上下文
 func (r *PDFReader) GetPageCount() (int, error) {
-	if r.pageCountCache > 0 {
-		return r.pageCountCache, nil
+	r.mu.RLock()
+	cached := r.pageCountCache
+	r.mu.RUnlock()
+	if cached >= 0 {
+		return cached, nil
 	}
 
-	count, err := api.PageCountFile(r.pdfPath)
-	if err != nil {
+	if _, err := r.loadPageCache(); err != nil {
 		return 0, err
 	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pageCountCache, nil
+}
+
+// loadPageCache 返回页面尺寸缓存，未缓存时从同一次读取的 PDF 上下文同时填充页数和页面尺寸缓存
+func (r *PDFReader) loadPageCache() ([]PageInfo, error) {
+	r.mu.RLock()
+	dims := r.pageDimsCache
+	r.mu.RUnlock()
+	if dims != nil {
+		return dims, nil
+	}
+
+	// 在锁外读取文件，并发调用可能重复读取，但结果相同
+	ctx, err := r.readContext()
+	if err != nil {
+		return nil, err
+	}
+	// PageBoundaries 已沿页面树解析继承的 MediaBox、CropBox 和 Rotate
+	boundaries, err := ctx.PageBoundaries(nil)
+	if err != nil {
+		return nil, err
+	}
 
-	r.pageCountCache = count
-	return count, nil
+	dims = make([]PageInfo, len(boundaries))
+	for i, pb := range boundaries {
+		dims[i] = pageInfoFromBoundaries(pb)
+		if pageDict, _, _, err := ctx.PageDict(i+1, false); err == nil && pageDict != nil {
+			dims[i].UserUnit = getPageUserUnit(pageDict)
+		}
+	}
+
+	r.mu.Lock()
+	r.pageDimsCache = dims
+	r.pageCountCache = ctx.PageCount
+	r.mu.Unlock()
+	return dims, nil
 }
 
 // PageInfo 页面信息
 type PageInfo struct {
-	Width  float64
-	Height float64
+	Width    float64    // 旋转后的可见宽度（MediaBox 与 CropBox 的交集）
+	Height   float64    // 旋转后的可见高度
+	Rotation int        // 页面 /Rotate，规范化到 0、90、180、270
+	MediaBox [4]float64 // [x1 y1 x2 y2]，已解析页面树继承
+	CropBox  [4]float64 // [x1 y1 x2 y2]，缺失时等于 MediaBox
+	UserUnit float64    // 页面 /UserUnit，默认 1；物理尺寸为 Width*UserUnit/72 英寸
 }
 
 // TextElementInfo 文本元素信息
@@ -201,6 +595,9 @@ type TextElementInfo struct {
 	Y        float64
 	FontName string
 	FontSize float64
+	Width    float64    // 文本沿基线方向的宽度
+	Angle    float64    // 基线相对水平方向的旋转角度（度，逆时针为正）
+	Color    [3]float64 // 填充颜色（RGB，0-1），由 rg/g/k/cs/sc/scn 设置
 }
 
 // ImageElementInfo 图片元素信息
@@ -215,8 +612,124 @@ type ImageElementInfo struct {
 // ExtractImageData 从 PDF 中提取图像数据
 // 🔥 新增：完整的图像提取功能，支持解码和导出
 func (r *PDFReader) ExtractImageData(pageNum int, imageName string) (*image.RGBA, error) {
+	resources, err := r.loadPageResources(pageNum)
+	if err != nil {
+		return nil, err
+	}
+
+	// 获取图像 XObject
+	xobj := resources.GetXObject(imageName)
+	if xobj == nil {
+		return nil, fmt.Errorf("image %s not found", imageName)
+	}
+
+	if xobj.Subtype != "/Image" && xobj.Subtype != "Image" {
+		return nil, fmt.Errorf("%s is not an image (subtype: %s)", imageName, xobj.Subtype)
+	}
+
+	// 解码图像数据
+	return decodeImageXObject(xobj)
+}
+
+// ExtractImageWithMask 分别提取图像的基础颜色和软遮罩，用于在其他地方自行合成
+// base 为不应用 SMask 的颜色图像（alpha 全为 255；带 /Matte 时颜色保持预混合后的原始值），
+// mask 为按自身分辨率解码的 SMask 灰度图（已应用 /TR），图像没有 SMask 时为 nil
+func (r *PDFReader) ExtractImageWithMask(pageNum int, imageName string) (*image.RGBA, *image.Gray, error) {
+	resources, err := r.loadPageResources(pageNum)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	xobj := resources.GetXObject(imageName)
+	if xobj == nil {
+		return nil, nil, fmt.Errorf("image %s not found", imageName)
+	}
+	if xobj.Subtype != "/Image" && xobj.Subtype != "Image" {
+		return nil, nil, fmt.Errorf("%s is not an image (subtype: %s)", imageName, xobj.Subtype)
+	}
+
+	// 去掉 SMask 的副本解码出未合成的颜色图像
+	unmasked := *xobj
+	unmasked.SMask = nil
+	base, err := decodeImageXObject(&unmasked)
+	if err != nil {
+		return nil, nil, err
+	}
+	if xobj.SMask == nil {
+		return base, nil, nil
+	}
+
+	maskData, err := decodeImageXObject(xobj.SMask)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode soft mask: %w", err)
+	}
+	transfer := transferTable(xobj.SMask.Transfer)
+	bounds := maskData.Bounds()
+	mask := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			v := maskGray(maskData, bounds.Min.X+x, bounds.Min.Y+y)
+			if transfer != nil {
+				v = transfer[v]
+			}
+			mask.Pix[y*mask.Stride+x] = v
+		}
+	}
+	return base, mask, nil
+}
+
+// ExtractAllImages 提取页面资源中的所有图像 XObject，返回以 XObject 名称为键的解码图像
+// 解码失败的图像会被跳过（记录日志），不会中断整个页面的提取
+func (r *PDFReader) ExtractAllImages(pageNum int) (map[string]*image.RGBA, error) {
+	resources, err := r.loadPageResources(pageNum)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make(map[string]*image.RGBA)
+	for name, xobj := range resources.XObject {
+		if xobj == nil || (xobj.Subtype != "/Image" && xobj.Subtype != "Image") {
+			continue
+		}
+
+		img, err := decodeImageXObject(xobj)
+		if err != nil {
+			debugPrintf("⚠️  Failed to decode image %s on page %d: %v\n", name, pageNum, err)
+			continue
+		}
+		images[name] = img
+	}
+
+	return images, nil
+}
+
+// ExtractImagesToDir 提取页面中的所有图像并以 PNG 格式写入 dir，文件名为 XObject 名称（如 Im1.png）
+func (r *PDFReader) ExtractImagesToDir(pageNum int, dir string) error {
+	images, err := r.ExtractAllImages(pageNum)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for name, img := range images {
+		// XObject 名称中的路径分隔符不能出现在文件名中
+		fileName := strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+		outputPath := fmt.Sprintf("%s/%s.png", dir, fileName)
+		if err := SaveImageToPNG(img, outputPath); err != nil {
+			return fmt.Errorf("failed to save image %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// loadPageResources 读取 PDF 上下文并加载指定页面的资源字典
+func (r *PDFReader) loadPageResources(pageNum int) (*Resources, error) {
 	// 打开 PDF 文件并读取上下文
-	ctx, err := api.ReadContextFile(r.pdfPath)
+	ctx, err := r.readContext()
 	if err != nil {
 		return nil, fmt.Errorf("failed to read PDF context: %w", err)
 	}
@@ -229,24 +742,43 @@ func (r *PDFReader) ExtractImageData(pageNum int, imageName string) (*image.RGBA
 
 	// 提取资源
 	resources := NewResources()
-	if resourcesObj, found := pageDict.Find("Resources"); found {
+	if resourcesObj, found := resolvePageResources(ctx, pageDict); found {
 		if err := loadResources(ctx, resourcesObj, resources); err != nil {
 			return nil, fmt.Errorf("failed to load resources: %w", err)
 		}
 	}
 
-	// 获取图像 XObject
-	xobj := resources.GetXObject(imageName)
-	if xobj == nil {
-		return nil, fmt.Errorf("image %s not found", imageName)
+	return resources, nil
+}
+
+// DecodeImage 解码独立的图像采样数据（已去除滤镜），无需构造 XObject 或 PDFReader
+// colorSpace 支持 DeviceRGB、DeviceGray、DeviceCMYK、Indexed（需提供 RGB 调色板，每条目 3 字节）
+// 和 ICCBased（按数据大小推断分量数），也接受内联图像缩写 RGB、G、CMYK、I；bpc 为 0 时默认 8
+func DecodeImage(stream []byte, width, height, bpc int, colorSpace string, palette []byte) (*image.RGBA, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid image size: %dx%d", width, height)
+	}
+	if bpc == 0 {
+		bpc = 8
 	}
 
-	if xobj.Subtype != "/Image" && xobj.Subtype != "Image" {
-		return nil, fmt.Errorf("%s is not an image (subtype: %s)", imageName, xobj.Subtype)
+	colorSpace = strings.TrimPrefix(colorSpace, "/")
+	if full, ok := inlineImageColorSpaceAbbreviations[colorSpace]; ok {
+		colorSpace = full
+	}
+	if colorSpace == "Indexed" && len(palette) == 0 {
+		return nil, fmt.Errorf("indexed color space requires a palette")
 	}
 
-	// 解码图像数据
-	return decodeImageXObject(xobj)
+	return decodeImageXObject(&XObject{
+		Subtype:          "Image",
+		Width:            width,
+		Height:           height,
+		BitsPerComponent: bpc,
+		ColorSpace:       colorSpace,
+		Stream:           stream,
+		Palette:          palette,
+	})
 }
 
 // decodeImageXObject 解码图像 XObject 为 RGBA 图像
@@ -255,6 +787,10 @@ func decodeImageXObject(xobj *XObject) (*image.RGBA, error) {
 	if len(xobj.Stream) == 0 {
 		return nil, fmt.Errorf("image stream is empty")
 	}
+	// JPEG 2000 数据不能按采样解码，返回可识别的错误而不是绘制噪声
+	if xobj.JPX {
+		return nil, jpxImageError(xobj.Stream)
+	}
 
 	width := xobj.Width
 	height := xobj.Height
@@ -282,7 +818,7 @@ func decodeImageXObject(xobj *XObject) (*image.RGBA, error) {
 		}
 		return applySMask(img, xobj)
 	case "DeviceCMYK", "/DeviceCMYK":
-		img, err := decodeDeviceCMYK(xobj.Stream, width, height, bpc)
+		img, err := decodeDeviceCMYK(xobj.Stream, width, height, bpc, xobj.CMYKConversion)
 		if err != nil {
 			return nil, err
 		}
@@ -327,7 +863,7 @@ func decodeImageXObject(xobj *XObject) (*image.RGBA, error) {
 
 		if numComponents == 4 {
 			debugPrintf("[decodeImageXObject] ICCBased with 4 components, treating as CMYK\n")
-			img, err := decodeDeviceCMYK(xobj.Stream, width, height, bpc)
+			img, err := decodeDeviceCMYK(xobj.Stream, width, height, bpc, xobj.CMYKConversion)
 			if err != nil {
 				return nil, err
 			}
@@ -338,6 +874,7 @@ func decodeImageXObject(xobj *XObject) (*image.RGBA, error) {
 			if err != nil {
 				return nil, err
 			}
+			applyICCProfile(img, xobj, numComponents)
 			return applySMask(img, xobj)
 		} else if numComponents == 1 {
 			debugPrintf("[decodeImageXObject] ICCBased with 1 component, treating as Gray\n")
@@ -345,6 +882,7 @@ func decodeImageXObject(xobj *XObject) (*image.RGBA, error) {
 			if err != nil {
 				return nil, err
 			}
+			applyICCProfile(img, xobj, numComponents)
 			return applySMask(img, xobj)
 		} else {
 			// 默认尝试 RGB
@@ -397,23 +935,43 @@ func decodeImageXObject(xobj *XObject) (*image.RGBA, error) {
 			return nil, err
 		}
 		return applySMask(img, xobj)
-	default:
-		debugPrintf("[decodeImageXObject] Unknown color space %s, trying RGB\n", colorSpace)
+	case "CalRGB", "/CalRGB":
 		img, err := decodeDeviceRGB(xobj.Stream, width, height, bpc)
 		if err != nil {
 			return nil, err
 		}
+		applyCalibratedColorSpace(img, xobj.Calibrated)
 		return applySMask(img, xobj)
-	}
-}
-
-// applySMask 应用软遮罩（如果存在）
-func applySMask(img *image.RGBA, xobj *XObject) (*image.RGBA, error) {
-	if xobj.SMask == nil {
-		return img, nil
-	}
-
-	debugPrintf("[applySMask] Applying SMask to image (%dx%d)\n", xobj.Width, xobj.Height)
+	case "CalGray", "/CalGray":
+		img, err := decodeDeviceGray(xobj.Stream, width, height, bpc)
+		if err != nil {
+			return nil, err
+		}
+		applyCalibratedColorSpace(img, xobj.Calibrated)
+		return applySMask(img, xobj)
+	case "Separation", "/Separation", "DeviceN", "/DeviceN":
+		img, err := decodeDeviceNImage(xobj.Stream, width, height, bpc, xobj.DeviceN)
+		if err != nil {
+			return nil, err
+		}
+		return applySMask(img, xobj)
+	default:
+		debugPrintf("[decodeImageXObject] Unknown color space %s, trying RGB\n", colorSpace)
+		img, err := decodeDeviceRGB(xobj.Stream, width, height, bpc)
+		if err != nil {
+			return nil, err
+		}
+		return applySMask(img, xobj)
+	}
+}
+
+// applySMask 应用软遮罩（如果存在）
+func applySMask(img *image.RGBA, xobj *XObject) (*image.RGBA, error) {
+	if xobj.SMask == nil {
+		return img, nil
+	}
+
+	debugPrintf("[applySMask] Applying SMask to image (%dx%d)\n", xobj.Width, xobj.Height)
 
 	// 解码 SMask 图像
 	// SMask 通常是 DeviceGray
@@ -431,24 +989,32 @@ func applySMask(img *image.RGBA, xobj *XObject) (*image.RGBA, error) {
 	maskWidth := maskBounds.Dx()
 	maskHeight := maskBounds.Dy()
 
+	sameSize := maskWidth == width && maskHeight == height
+
+	// 🔥 Matte：基础图像颜色为 c' = m + a*(c - m)，需要还原 c = m + (c' - m)/a
+	matte, hasMatte := matteToRGB(xobj.SMask.Matte)
+
+	// TR 传递函数：预先计算 256 项查找表
+	transfer := transferTable(xobj.SMask.Transfer)
+
 	// 应用 mask 到 alpha 通道
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
-			// 计算 mask 坐标 (简单缩放)
-			mx := x * maskWidth / width
-			my := y * maskHeight / height
-
-			if mx >= maskWidth {
-				mx = maskWidth - 1
+			// 获取 mask 值 (使用其红色通道作为 alpha 值，因为 mask 应该是灰度的)
+			var maskVal uint8
+			if sameSize {
+				// 快速路径：尺寸一致时无需缩放
+				maskVal = maskGray(maskData, maskBounds.Min.X+x, maskBounds.Min.Y+y)
+			} else {
+				// 以像素中心对齐，将原图坐标映射到 mask 坐标后双线性插值
+				mx := (float64(x)+0.5)*float64(maskWidth)/float64(width) - 0.5
+				my := (float64(y)+0.5)*float64(maskHeight)/float64(height) - 0.5
+				maskVal = sampleMaskBilinear(maskData, mx, my)
 			}
-			if my >= maskHeight {
-				my = maskHeight - 1
+			if transfer != nil {
+				maskVal = transfer[maskVal]
 			}
 
-			// 获取 mask 像素 (使用其红色通道作为 alpha 值，因为 mask 应该是灰度的)
-			r, _, _, _ := maskData.At(mx, my).RGBA()
-			maskVal := uint8(r >> 8)
-
 			// 获取原图像素
 			offset := img.PixOffset(x, y)
 
@@ -464,6 +1030,15 @@ func applySMask(img *image.RGBA, xobj *XObject) (*image.RGBA, error) {
 			// Normalize to 0-1 range then multiply
 			newAlpha := uint8(float64(currentAlpha) * float64(maskVal) / 255.0)
 
+			if hasMatte && maskVal > 0 {
+				a := float64(maskVal) / 255.0
+				for i := 0; i < 3; i++ {
+					m := matte[i] * 255.0
+					c := m + (float64(img.Pix[offset+i])-m)/a
+					img.Pix[offset+i] = uint8(math.Max(0, math.Min(255, math.Round(c))))
+				}
+			}
+
 			img.Pix[offset+3] = newAlpha
 		}
 	}
@@ -472,6 +1047,72 @@ func applySMask(img *image.RGBA, xobj *XObject) (*image.RGBA, error) {
 	return img, nil
 }
 
+// transferTable 把传递函数展开为 8 位查找表，fn 为 nil 时返回 nil（恒等映射）
+func transferTable(fn *PDFFunction) *[256]uint8 {
+	if fn == nil {
+		return nil
+	}
+	table := new([256]uint8)
+	for i := range table {
+		var v float64
+		if out := fn.EvaluateFunction(float64(i) / 255); len(out) > 0 {
+			v = out[0]
+		}
+		table[i] = uint8(math.Round(clamp01(v) * 255))
+	}
+	return table
+}
+
+// maskGray 读取 mask 在 (x, y) 处的灰度值（红色通道）
+func maskGray(mask image.Image, x, y int) uint8 {
+	r, _, _, _ := mask.At(x, y).RGBA()
+	return uint8(r >> 8)
+}
+
+// sampleMaskBilinear 在 mask 坐标 (fx, fy) 处对相邻四个像素做双线性插值
+// 坐标以 mask 像素中心为整数点，超出边界时钳制到边缘像素
+func sampleMaskBilinear(mask image.Image, fx, fy float64) uint8 {
+	b := mask.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	fx = math.Max(0, math.Min(float64(w-1), fx))
+	fy = math.Max(0, math.Min(float64(h-1), fy))
+
+	x0, y0 := int(fx), int(fy)
+	x1, y1 := x0+1, y0+1
+	if x1 >= w {
+		x1 = w - 1
+	}
+	if y1 >= h {
+		y1 = h - 1
+	}
+	tx, ty := fx-float64(x0), fy-float64(y0)
+
+	v00 := float64(maskGray(mask, b.Min.X+x0, b.Min.Y+y0))
+	v10 := float64(maskGray(mask, b.Min.X+x1, b.Min.Y+y0))
+	v01 := float64(maskGray(mask, b.Min.X+x0, b.Min.Y+y1))
+	v11 := float64(maskGray(mask, b.Min.X+x1, b.Min.Y+y1))
+
+	top := v00 + (v10-v00)*tx
+	bottom := v01 + (v11-v01)*tx
+	return uint8(math.Round(top + (bottom-top)*ty))
+}
+
+// matteToRGB 将 /Matte 颜色分量转换为 0-1 范围的 RGB
+// 分量数决定颜色空间：1=Gray，3=RGB，4=CMYK；其他情况视为无 Matte
+func matteToRGB(matte []float64) ([3]float64, bool) {
+	switch len(matte) {
+	case 1:
+		return [3]float64{matte[0], matte[0], matte[0]}, true
+	case 3:
+		return [3]float64{matte[0], matte[1], matte[2]}, true
+	case 4:
+		r, g, b := cmykToRGB(matte[0], matte[1], matte[2], matte[3])
+		return [3]float64{r, g, b}, true
+	}
+	return [3]float64{}, false
+}
+
 // decodeDeviceRGB 解码 DeviceRGB 图像
 func decodeDeviceRGB(data []byte, width, height, bpc int) (*image.RGBA, error) {
 	return DecodeDeviceRGBPublic(data, width, height, bpc)
@@ -575,20 +1216,19 @@ func decodeDeviceGray(data []byte, width, height, bpc int) (*image.RGBA, error)
 				img.Pix[dstIdx+3] = 255
 			}
 		}
-	} else if bpc == 1 {
-		// 1 位黑白
+	} else if bpc == 1 || bpc == 2 || bpc == 4 {
+		// 1/2/4 位灰度：每行从字节边界开始，采样值扩展到 0-255
+		rowBytes := (width*bpc + 7) / 8
+		maxVal := (1 << bpc) - 1
 		for y := 0; y < height; y++ {
 			for x := 0; x < width; x++ {
-				byteIdx := (y*width + x) / 8
-				bitIdx := 7 - ((y*width + x) % 8)
+				bitPos := x * bpc
+				byteIdx := y*rowBytes + bitPos/8
 				if byteIdx >= len(data) {
 					break
 				}
-				bit := (data[byteIdx] >> bitIdx) & 1
-				gray := uint8(0)
-				if bit == 1 {
-					gray = 255
-				}
+				sample := int(data[byteIdx]>>(8-bpc-bitPos%8)) & maxVal
+				gray := uint8(sample * 255 / maxVal)
 				dstIdx := img.PixOffset(x, y)
 				img.Pix[dstIdx+0] = gray
 				img.Pix[dstIdx+1] = gray
@@ -603,9 +1243,34 @@ func decodeDeviceGray(data []byte, width, height, bpc int) (*image.RGBA, error)
 	return img, nil
 }
 
-// decodeDeviceCMYK 解码 DeviceCMYK 图像
-func decodeDeviceCMYK(data []byte, width, height, bpc int) (*image.RGBA, error) {
-	return DecodeDeviceCMYKPublic(data, width, height, bpc)
+// decodeDeviceCMYK 解码 DeviceCMYK 图像，mode 选择 CMYK 到 RGB 的转换方式
+func decodeDeviceCMYK(data []byte, width, height, bpc int, mode CMYKConversion) (*image.RGBA, error) {
+	if mode.resolve() != CMYKSWOP {
+		return DecodeDeviceCMYKPublic(data, width, height, bpc)
+	}
+	if bpc != 8 {
+		return nil, fmt.Errorf("unsupported bits per component: %d", bpc)
+	}
+	expectedSize := width * height * 4
+	if len(data) < expectedSize {
+		return nil, fmt.Errorf("insufficient data: expected %d bytes, got %d", expectedSize, len(data))
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	// 照片中相同的 CMYK 值很常见，按 32 位颜色缓存多项式结果
+	cache := make(map[uint32][3]uint8)
+	for i := 0; i < width*height; i++ {
+		p := data[i*4 : i*4+4 : i*4+4]
+		key := uint32(p[0])<<24 | uint32(p[1])<<16 | uint32(p[2])<<8 | uint32(p[3])
+		rgb, ok := cache[key]
+		if !ok {
+			r, g, b := swopCMYKToRGB(float64(p[0])/255, float64(p[1])/255, float64(p[2])/255, float64(p[3])/255)
+			rgb = [3]uint8{uint8(r*255 + 0.5), uint8(g*255 + 0.5), uint8(b*255 + 0.5)}
+			cache[key] = rgb
+		}
+		img.Pix[i*4+0], img.Pix[i*4+1], img.Pix[i*4+2], img.Pix[i*4+3] = rgb[0], rgb[1], rgb[2], 255
+	}
+	return img, nil
 }
 
 // DecodeDeviceCMYKPublic 公开的CMYK解码函数，供测试使用
@@ -668,6 +1333,120 @@ func DecodeDeviceCMYKPublic(data []byte, width, height, bpc int) (*image.RGBA, e
 	return img, nil
 }
 
+// applyCalibratedColorSpace 把按设备颜色解码的 CalRGB/CalGray 图像就地转换为 sRGB
+// cs 为 nil（颜色空间解析失败）时保持设备颜色不变
+func applyCalibratedColorSpace(img *image.RGBA, cs ColorSpace) {
+	if cs == nil {
+		return
+	}
+	_, gray := cs.(*CalGrayColorSpace)
+	components := make([]float64, cs.GetNumComponents())
+
+	// 转换涉及幂运算和矩阵：按 8 位颜色缓存结果
+	cache := make(map[uint32][3]uint8)
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		p := img.Pix[i : i+3 : i+3]
+		key := uint32(p[0])<<16 | uint32(p[1])<<8 | uint32(p[2])
+		rgb, ok := cache[key]
+		if !ok {
+			if gray {
+				components[0] = float64(p[0]) / 255
+			} else {
+				components[0], components[1], components[2] = float64(p[0])/255, float64(p[1])/255, float64(p[2])/255
+			}
+			r, g, b, err := cs.ConvertToRGB(components)
+			if err != nil {
+				return
+			}
+			rgb = [3]uint8{uint8(r*255 + 0.5), uint8(g*255 + 0.5), uint8(b*255 + 0.5)}
+			cache[key] = rgb
+		}
+		p[0], p[1], p[2] = rgb[0], rgb[1], rgb[2]
+	}
+}
+
+// decodeDeviceNImage 解码 Separation/DeviceN 颜色空间图像
+// 每个像素的 tint 分量经 tint 变换转换到备用颜色空间再转为 RGB；
+// cs 为 nil（颜色空间解析失败）时按单分量 tint 近似为反相灰度（tint 1.0 为满墨）
+func decodeDeviceNImage(data []byte, width, height, bpc int, cs *DeviceNColorSpace) (*image.RGBA, error) {
+	n := 1
+	if cs != nil {
+		n = cs.GetNumComponents()
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("DeviceN color space has no colorants")
+	}
+	switch bpc {
+	case 1, 2, 4, 8, 16:
+	default:
+		return nil, fmt.Errorf("unsupported bits per component for DeviceN: %d", bpc)
+	}
+
+	// 每行按字节对齐
+	rowBytes := (width*n*bpc + 7) / 8
+	if len(data) < rowBytes*height {
+		return nil, fmt.Errorf("insufficient data for DeviceN image: got %d, need %d", len(data), rowBytes*height)
+	}
+	debugPrintf("[decodeDeviceNImage] Decoding %dx%d image, %d colorants, BPC=%d\n", width, height, n, bpc)
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	maxVal := float64(uint32(1)<<uint(bpc) - 1)
+	tints := make([]float64, n)
+
+	// tint 变换可能很慢：分量总位数不超过 64 时按原始采样值缓存转换结果
+	var cache map[uint64]color.RGBA
+	if n*bpc <= 64 {
+		cache = make(map[uint64]color.RGBA)
+	}
+
+	for y := 0; y < height; y++ {
+		row := data[y*rowBytes : (y+1)*rowBytes]
+		bitPos := 0
+		for x := 0; x < width; x++ {
+			var key uint64
+			for i := 0; i < n; i++ {
+				var v uint32
+				for b := 0; b < bpc; b++ {
+					pos := bitPos + b
+					v = v<<1 | uint32(row[pos/8]>>(7-pos%8)&1)
+				}
+				bitPos += bpc
+				key = key<<uint(bpc) | uint64(v)
+				tints[i] = float64(v) / maxVal
+			}
+
+			if cache != nil {
+				if c, ok := cache[key]; ok {
+					img.SetRGBA(x, y, c)
+					continue
+				}
+			}
+
+			var r, g, b float64
+			if cs != nil {
+				var err error
+				r, g, b, err = cs.ConvertToRGB(tints)
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				r, g, b = 1-tints[0], 1-tints[0], 1-tints[0]
+			}
+			c := color.RGBA{
+				R: uint8(clamp01(r)*255 + 0.5),
+				G: uint8(clamp01(g)*255 + 0.5),
+				B: uint8(clamp01(b)*255 + 0.5),
+				A: 255,
+			}
+			if cache != nil {
+				cache[key] = c
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img, nil
+}
+
 // decodeIndexedColorSpace 解码索引颜色空间图像
 // 🔥 新增：支持 Indexed 颜色空间的调色板解码
 func decodeIndexedColorSpace(data []byte, width, height, bpc int, palette []byte) (*image.RGBA, error) {
@@ -709,55 +1488,21 @@ func decodeIndexedColorSpace(data []byte, width, height, bpc int, palette []byte
 				img.Pix[dstIdx+3] = 255
 			}
 		}
-	} else if bpc == 4 {
-		// 4 bpc: 2 pixels per byte
+	} else if bpc == 1 || bpc == 2 || bpc == 4 {
+		// 支持 1、2、4 bpc 索引：每行从字节边界开始
+		rowBytes := (width*bpc + 7) / 8
+		mask := byte((1 << bpc) - 1)
 		for y := 0; y < height; y++ {
 			for x := 0; x < width; x++ {
-				byteIdx := (y*width + x) / 2
-				isHigh := ((y*width + x) % 2) == 0
-
-				if byteIdx >= len(data) {
-					break
-				}
-
-				b := data[byteIdx]
-				var idxVal uint8
-				if isHigh {
-					idxVal = (b >> 4) & 0x0F
-				} else {
-					idxVal = b & 0x0F
-				}
-
-				pIdx := int(idxVal) * bytesPerEntry
-				r, g, b := uint8(0), uint8(0), uint8(0)
-
-				if pIdx+2 < len(palette) {
-					r = palette[pIdx]
-					g = palette[pIdx+1]
-					b = palette[pIdx+2]
-				}
-
-				dstIdx := img.PixOffset(x, y)
-				img.Pix[dstIdx+0] = r
-				img.Pix[dstIdx+1] = g
-				img.Pix[dstIdx+2] = b
-				img.Pix[dstIdx+3] = 255
-			}
-		}
-	} else if bpc == 1 || bpc == 2 {
-		// 支持 1 和 2 bpc 索引
-		for y := 0; y < height; y++ {
-			for x := 0; x < width; x++ {
-				// 获取 bit stream 中的值
-				bitOffset := (y*width + x) * bpc
-				byteIdx := bitOffset / 8
+				// 获取行内 bit stream 中的值
+				bitOffset := x * bpc
+				byteIdx := y*rowBytes + bitOffset/8
 				bitShift := 8 - bpc - (bitOffset % 8)
 
 				if byteIdx >= len(data) {
 					break
 				}
 
-				mask := byte((1 << bpc) - 1)
 				idxVal := (data[byteIdx] >> bitShift) & mask
 
 				pIdx := int(idxVal) * bytesPerEntry
@@ -786,488 +1531,355 @@ func decodeIndexedColorSpace(data []byte, width, height, bpc int, palette []byte
 // GetPageInfo 获取页面信息
 // 优化：使用缓存避免重复读取
 func (r *PDFReader) GetPageInfo(pageNum int) (PageInfo, error) {
-	// 检查缓存
-	if r.pageDimsCache != nil && pageNum > 0 && pageNum <= len(r.pageDimsCache) {
-		return r.pageDimsCache[pageNum-1], nil
-	}
-
-	// 加载所有页面尺寸到缓存
-	if r.pageDimsCache == nil {
-		pageDims, err := api.PageDimsFile(r.pdfPath)
-		if err != nil {
-			return PageInfo{Width: 612, Height: 792}, fmt.Errorf("failed to get page dimensions: %w", err)
-		}
-
-		r.pageDimsCache = make([]PageInfo, len(pageDims))
-		for i, dim := range pageDims {
-			r.pageDimsCache[i] = PageInfo{
-				Width:  dim.Width,
-				Height: dim.Height,
-			}
-		}
+	// 加载所有页面尺寸到缓存（缓存切片创建后只读）
+	dims, err := r.loadPageCache()
+	if err != nil {
+		return PageInfo{Width: 612, Height: 792}, fmt.Errorf("failed to get page dimensions: %w", err)
 	}
 
-	if pageNum < 1 || pageNum > len(r.pageDimsCache) {
+	if pageNum < 1 || pageNum > len(dims) {
 		return PageInfo{Width: 612, Height: 792}, nil // 默认 Letter 尺寸
 	}
 
-	return r.pageDimsCache[pageNum-1], nil
+	return dims[pageNum-1], nil
 }
 
-// ExtractPageElements 提取页面中的文本和图片元素
-func (r *PDFReader) ExtractPageElements(pageNum int) ([]TextElementInfo, []ImageElementInfo) {
-	var textElements []TextElementInfo
-	var imageElements []ImageElementInfo
+// pageInfoFromBoundaries 根据页面边界框和旋转计算页面信息
+func pageInfoFromBoundaries(pb model.PageBoundaries) PageInfo {
+	info := PageInfo{Width: 612, Height: 792, UserUnit: 1}
 
-	// 打开 PDF 文件并读取上下文
-	ctx, err := api.ReadContextFile(r.pdfPath)
-	if err != nil {
-		debugPrintf("Failed to read PDF context: %v\n", err)
-		return textElements, imageElements
-	}
+	if media := pb.MediaBox(); media != nil {
+		info.MediaBox = normalizePageBox([4]float64{media.LL.X, media.LL.Y, media.UR.X, media.UR.Y})
+		info.CropBox = info.MediaBox
+		if crop := pb.CropBox(); crop != nil {
+			info.CropBox = normalizePageBox([4]float64{crop.LL.X, crop.LL.Y, crop.UR.X, crop.UR.Y})
+		}
 
-	// 获取页面字典
-	pageDict, _, _, err := ctx.PageDict(pageNum, false)
-	if err != nil {
-		debugPrintf("Failed to get page dict: %v\n", err)
-		return textElements, imageElements
+		visible := intersectPageBoxes(info.MediaBox, info.CropBox)
+		info.Width = visible[2] - visible[0]
+		info.Height = visible[3] - visible[1]
 	}
 
-	// 获取页面尺寸
-	pageInfo, _ := r.GetPageInfo(pageNum)
-
-	// 提取资源
-	resources := NewResources()
-	if resourcesObj, found := pageDict.Find("Resources"); found {
-		if err := loadResources(ctx, resourcesObj, resources); err != nil {
-			debugPrintf("Failed to load resources: %v\n", err)
-		}
+	info.Rotation = normalizeRotation(pb.Rot)
+	if info.Rotation == 90 || info.Rotation == 270 {
+		info.Width, info.Height = info.Height, info.Width
 	}
 
-	// 提取内容流
-	contents, found := pageDict.Find("Contents")
-	if !found {
-		return textElements, imageElements
-	}
+	return info
+}
 
-	contentStreams, err := ExtractContentStreams(ctx, contents)
+// ExtractPageElements 提取页面中的文本和图片元素
+func (r *PDFReader) ExtractPageElements(pageNum int) ([]TextElementInfo, []ImageElementInfo) {
+	textElements, imageElements, _, err := r.extractPageContent(pageNum)
 	if err != nil {
-		debugPrintf("Failed to extract content streams: %v\n", err)
-		return textElements, imageElements
+		debugPrintf("Failed to extract page elements: %v\n", err)
 	}
+	return textElements, imageElements
+}
 
-	// 合并所有内容流
-	var allContent []byte
-	for _, stream := range contentStreams {
-		allContent = append(allContent, stream...)
-		allContent = append(allContent, '\n')
-	}
+// extractPageContent 分析页面内容流的操作符，提取文本元素、图片元素和带逐字形包围盒的文本片段
+func (r *PDFReader) extractPageContent(pageNum int) ([]TextElementInfo, []ImageElementInfo, []TextRun, error) {
+	var textElements []TextElementInfo
+	var imageElements []ImageElementInfo
+	var textRuns []TextRun
 
-	// 解析操作符
-	operators, err := ParseContentStream(allContent)
+	// 读取页面资源并解析内容流
+	resources, operators, err := r.pageOperators(pageNum)
 	if err != nil {
-		debugPrintf("Failed to parse content stream: %v\n", err)
-		return textElements, imageElements
+		return nil, nil, nil, err
 	}
 
-	// 分析操作符以提取文本和图片信息
-	currentFont := ""
-	baseFontSize := 0.0                     // Tf 操作符设置的基础字体大小
-	currentMatrix := &Matrix{XX: 1, YY: 1}  // 单位矩阵
-	textLineMatrix := &Matrix{XX: 1, YY: 1} // 文本行矩阵
-	ctm := NewIdentityMatrix()              // 当前变换矩阵 (Current Transformation Matrix)
-
-	// 图形状态栈，用于保存和恢复完整的图形状态
-	type GraphicsState struct {
-		ctm            *Matrix
-		currentFont    string
-		baseFontSize   float64
-		currentMatrix  *Matrix
-		textLineMatrix *Matrix
-		fillColor      [3]float64
-		strokeColor    [3]float64
-		lineWidth      float64
-		lineCap        int
-		lineJoin       int
-		miterLimit     float64
-		dashPattern    []float64
-		dashPhase      float64
-	}
-	var graphicsStateStack []*GraphicsState
-
-	// 初始化图形状态
-	fillColor := [3]float64{0, 0, 0}
-	strokeColor := [3]float64{0, 0, 0}
-	lineWidth := 1.0
-	lineCap := 0
-	lineJoin := 0
-	miterLimit := 10.0
-	var dashPattern []float64
-	dashPhase := 0.0
+	// 获取页面尺寸；坐标相对于可见区域（与渲染的页面图像一致）
+	pageInfo, _ := r.GetPageInfo(pageNum)
+	toVisible := pageVisibleOrigin(pageInfo)
 
+	// 图形状态和文本状态（q/Q、cm、BT、Tf、Td 等）由 graphicsTracker 跟踪
+	tracker := newGraphicsTracker(resources)
+	inlineCount := 0 // 内联图像没有资源名，按出现顺序命名
+
+	// 分析操作符以提取文本和图片信息
 	for _, op := range operators {
 		// 跳过忽略的操作符
 		if op.Name() == "IGNORE" {
 			continue
 		}
 
+		tracker.apply(op)
+		s := &tracker.state
+
 		switch op.Name() {
-		case "q": // 保存图形状态
-			// 保存完整的图形状态到栈
-			graphicsStateStack = append(graphicsStateStack, &GraphicsState{
-				ctm:            ctm.Clone(),
-				currentFont:    currentFont,
-				baseFontSize:   baseFontSize,
-				currentMatrix:  currentMatrix.Clone(),
-				textLineMatrix: textLineMatrix.Clone(),
-				fillColor:      fillColor,
-				strokeColor:    strokeColor,
-				lineWidth:      lineWidth,
-				lineCap:        lineCap,
-				lineJoin:       lineJoin,
-				miterLimit:     miterLimit,
-				dashPattern:    append([]float64(nil), dashPattern...),
-				dashPhase:      dashPhase,
-			})
-			debugPrintf("[DEBUG] q operator: Saved graphics state, stack depth=%d\n", len(graphicsStateStack))
-
-		case "Q": // 恢复图形状态
-			// 从栈中弹出并恢复完整的图形状态
-			if len(graphicsStateStack) > 0 {
-				state := graphicsStateStack[len(graphicsStateStack)-1]
-				graphicsStateStack = graphicsStateStack[:len(graphicsStateStack)-1]
-				ctm = state.ctm
-				currentFont = state.currentFont
-				baseFontSize = state.baseFontSize
-				currentMatrix = state.currentMatrix
-				textLineMatrix = state.textLineMatrix
-				fillColor = state.fillColor
-				strokeColor = state.strokeColor
-				lineWidth = state.lineWidth
-				lineCap = state.lineCap
-				lineJoin = state.lineJoin
-				miterLimit = state.miterLimit
-				dashPattern = state.dashPattern
-				dashPhase = state.dashPhase
-				debugPrintf("[DEBUG] Q operator: Restored graphics state, stack depth=%d, CTM=%s\n",
-					len(graphicsStateStack), ctm.String())
-			} else {
-				// 🔥 修复：栈为空时报错，符合 PDF 规范
-				debugPrintf("[DEBUG] Q operator: ERROR - graphics state stack is empty (unmatched Q without q)\n")
-				// 保持当前状态，但记录错误
-				// 在生产环境中，可以考虑返回错误或设置错误标志
-			}
-
-		case "BT": // 开始文本对象
-			// 🔥 修复：重置文本矩阵和文本行矩阵为单位矩阵
-			// 同时重置文本状态（字符间距、单词间距等）
-			currentMatrix = NewIdentityMatrix()
-			textLineMatrix = NewIdentityMatrix()
-			debugPrintf("[DEBUG] BT operator: Reset text matrices and text state\n")
-
-		case "ET": // 结束文本对象
-			debugPrintf("[DEBUG] ET operator: End text object\n")
-
-		case "Tf": // 设置字体
-			if tfOp, ok := op.(*OpSetFont); ok {
-				currentFont = tfOp.FontName
-				baseFontSize = tfOp.FontSize
-				// 🔥 修复：验证字体是否存在
-				font := resources.GetFont(currentFont)
-				if font == nil {
-					debugPrintf("[DEBUG] Tf operator: WARNING - Font %s not found in resources\n", currentFont)
-				} else {
-					debugPrintf("[DEBUG] Tf operator: Font=%s (BaseFont=%s), Size=%.2f\n",
-						currentFont, font.BaseFont, baseFontSize)
-				}
-			}
-
-		case "Tm": // 设置文本矩阵
-			if tmOp, ok := op.(*OpSetTextMatrix); ok {
-				currentMatrix = tmOp.Matrix.Clone()
-				textLineMatrix = tmOp.Matrix.Clone()
-				debugPrintf("[DEBUG] Tm operator: Matrix=%s\n", currentMatrix.String())
-			}
-
-		case "cm": // 连接变换矩阵
-			if cmOp, ok := op.(*OpConcatMatrix); ok {
-				// 更新当前变换矩阵：CTM' = cm × CTM
-				ctm = cmOp.Matrix.Multiply(ctm)
-				debugPrintf("[DEBUG] cm operator: Matrix=%s, new CTM=%s\n", cmOp.Matrix.String(), ctm.String())
-			}
-
-		case "Td": // 文本位置偏移
-			if tdOp, ok := op.(*OpMoveTextPosition); ok {
-				translation := &Matrix{XX: 1, YY: 1, X0: tdOp.Tx, Y0: tdOp.Ty}
-				textLineMatrix = translation.Multiply(textLineMatrix)
-				currentMatrix = textLineMatrix.Clone()
-				debugPrintf("[DEBUG] Td operator: Tx=%.2f, Ty=%.2f, new X0=%.2f, Y0=%.2f\n",
-					tdOp.Tx, tdOp.Ty, currentMatrix.X0, currentMatrix.Y0)
-			}
-
-		case "Tj", "TJ", "'", "\"": // 显示文本
-			var text string
-			var textDisplacement float64 // 文本位移（用于更新文本矩阵）
-
-			switch t := op.(type) {
-			case *OpShowText:
-				text = t.Text
-			case *OpShowTextArray:
-				// TJ 操作符：处理文本数组，包括字距调整
-				for _, elem := range t.Array {
-					if s, ok := elem.(string); ok {
-						text += s
-					} else if num, ok := elem.(float64); ok {
-						// 数字元素表示字距调整
-						// 负值表示向右移动（收紧间距），正值表示向左移动（放宽间距）
-						// 调整量 = -num / 1000 * fontSize * horizScale
-						// 这里我们累积位移，稍后应用到文本矩阵
-						adjustment := -num / 1000.0 * baseFontSize
-						textDisplacement += adjustment
-						debugPrintf("[DEBUG] TJ kerning: num=%.0f, adjustment=%.4f, cumulative=%.4f\n",
-							num, adjustment, textDisplacement)
-					} else if num, ok := elem.(int); ok {
-						adjustment := -float64(num) / 1000.0 * baseFontSize
-						textDisplacement += adjustment
-						debugPrintf("[DEBUG] TJ kerning: num=%d, adjustment=%.4f, cumulative=%.4f\n",
-							num, adjustment, textDisplacement)
-					}
-				}
-			case *OpShowTextNextLine:
-				text = t.Text
-			case *OpShowTextWithSpacing:
-				text = t.Text
-			}
-
-			// 解码文本（处理CID字体和十六进制字符串）
-			// 同时保存原始 CID 数组用于宽度计算
-			var originalCIDs []uint16
-			if text != "" {
-				font := resources.GetFont(currentFont)
-				if font != nil {
-					// 提取 CID 数组
-					originalCIDs = extractCIDsFromText(text)
-					text = decodeTextStringWithFontAndIdentity(text, font.ToUnicodeMap, font.IsIdentity)
-				} else {
-					text = decodeTextString(text)
-				}
+		case "Tj", "TJ", "'", "\"": // 显示文本（' 和 " 的换行已由 tracker 处理）
+			m := measureShownText(op, s, resources)
+			if m.text == "" {
+				continue
 			}
 
-			if text != "" && currentMatrix != nil {
-				// 应用当前变换矩阵 (CTM) 到文本矩阵
-				// 根据 PDF 规范：最终坐标 = Tm × CTM
-				// 这里文本位置是 (0, 0)，所以最终位置就是 Tm × CTM 的平移部分
-				finalMatrix := currentMatrix.Multiply(ctm)
-
-				// PDF 坐标系：左下角为原点，Y 轴向上
-				// 转换为屏幕坐标系：左上角为原点，Y 轴向下
-				x := finalMatrix.X0
-				y := pageInfo.Height - finalMatrix.Y0
-
-				// 计算有效字体大小：基础大小 * 文本矩阵的垂直缩放
-				// 文本矩阵的 YY 分量表示垂直缩放
-				// 特殊情况：如果 Tf 设置的字体大小为 0，则直接使用文本矩阵的缩放作为字体大小
-				effectiveFontSize := baseFontSize
-				scale := currentMatrix.YY
-				if scale < 0 {
-					scale = -scale
-				}
-				if baseFontSize == 0 {
-					// 当 Tf 设置字体大小为 0 时，字体大小完全由文本矩阵决定
-					effectiveFontSize = scale
-				} else {
-					effectiveFontSize = baseFontSize * scale
-				}
-
-				debugPrintf("[DEBUG] Text element: baseFontSize=%.2f, scale=%.2f, effectiveFontSize=%.2f\n",
-					baseFontSize, currentMatrix.YY, effectiveFontSize)
-
-				textElements = append(textElements, TextElementInfo{
-					Text:     text,
-					X:        x,
-					Y:        y,
-					FontName: currentFont,
-					FontSize: effectiveFontSize,
-				})
-
-				// 🔥 修复：改进文本宽度计算，考虑字体默认宽度和缺失宽度
-				var textWidth float64
-				font := resources.GetFont(currentFont)
-				if font != nil && len(originalCIDs) > 0 {
-					// 使用 CID 数组进行精确的字体宽度计算
-					for _, cid := range originalCIDs {
-						width := font.GetWidth(cid)
-						// 🔥 修复：确保宽度不为 0
-						if width == 0 {
-							if font.DefaultWidth > 0 {
-								width = font.DefaultWidth
-							} else if font.MissingWidth > 0 {
-								width = font.MissingWidth
-							} else {
-								width = 1000.0 // 使用 1 em 作为默认值
-							}
-						}
-						textWidth += (width / 1000.0) * effectiveFontSize
-					}
-					debugPrintf("[DEBUG] Calculated text width from CIDs: %.2f (%d CIDs)\n", textWidth, len(originalCIDs))
-				} else if font != nil {
-					// 回退到基于字符数的估算
-					// 🔥 修复：改进 CJK 字符宽度估算
-					runeCount := 0
-					totalWidthFactor := 0.0
-					for _, r := range text {
-						runeCount++
-						// 更精确的 CJK 字符范围检测
-						if (r >= 0x4E00 && r <= 0x9FFF) || // CJK统一表意文字
-							(r >= 0x3400 && r <= 0x4DBF) || // CJK扩展A
-							(r >= 0x20000 && r <= 0x2A6DF) || // CJK扩展B
-							(r >= 0x2A700 && r <= 0x2B73F) || // CJK扩展C
-							(r >= 0x2B740 && r <= 0x2B81F) || // CJK扩展D
-							(r >= 0x2B820 && r <= 0x2CEAF) || // CJK扩展E
-							(r >= 0xF900 && r <= 0xFAFF) || // CJK兼容表意文字
-							(r >= 0x2F800 && r <= 0x2FA1F) || // CJK兼容表意文字补充
-							(r >= 0x3040 && r <= 0x309F) || // 平假名
-							(r >= 0x30A0 && r <= 0x30FF) || // 片假名
-							(r >= 0xAC00 && r <= 0xD7AF) { // 韩文音节
-							totalWidthFactor += 1.0 // CJK字符通常是全角
-						} else if r >= 0xFF00 && r <= 0xFFEF {
-							// 全角ASCII和半角片假名
-							totalWidthFactor += 1.0
-						} else {
-							totalWidthFactor += 0.5 // 拉丁字符通常是半角
-						}
-					}
-					if runeCount > 0 {
-						textWidth = totalWidthFactor * effectiveFontSize
-					} else {
-						textWidth = 0
-					}
-					debugPrintf("[DEBUG] Estimated text width: %.2f (totalFactor=%.2f, runeCount=%d)\n",
-						textWidth, totalWidthFactor, runeCount)
-				} else {
-					// 最后的回退：简单估算
-					runeCount := float64(len([]rune(text)))
-					textWidth = runeCount * effectiveFontSize * 0.5
-					debugPrintf("[DEBUG] Fallback text width: %.2f (no font info)\n", textWidth)
-				}
+			// 应用当前变换矩阵 (CTM) 到文本矩阵
+			// 根据 PDF 规范：最终坐标 = Tm × CTM
+			finalMatrix := s.TextMatrix.Multiply(&s.CTM).Multiply(toVisible)
+
+			// PDF 坐标系：左下角为原点，Y 轴向上
+			// 转换为屏幕坐标系：左上角为原点，Y 轴向下
+			// 文本上升（Ts）是文本空间中相对基线的 Y 偏移
+			x, baselineY := finalMatrix.Transform(0, s.Rise)
+			y := pageInfo.Height - baselineY
+
+			textElements = append(textElements, TextElementInfo{
+				Text:     m.text,
+				X:        x,
+				Y:        y,
+				FontName: s.FontName,
+				FontSize: m.fontSize,
+				Width:    m.width,
+				Angle:    baselineAngle(finalMatrix),
+				Color:    s.FillColor,
+			})
 
-				// 先应用字距调整，再应用文本宽度
-				totalDisplacement := textWidth + textDisplacement
-				if totalDisplacement != 0 {
-					translation := &Matrix{XX: 1, YY: 1, X0: totalDisplacement, Y0: 0}
-					currentMatrix = currentMatrix.Multiply(translation)
-					debugPrintf("[DEBUG] Total displacement: %.2f (width=%.2f, kerning=%.2f), new X0=%.2f\n",
-						totalDisplacement, textWidth, textDisplacement, currentMatrix.X0)
-				}
+			// 逐字形包围盒：按操作符中的原始字符串和字距调整在文本空间中排列字形
+			// （Tf 字号为 0 时字号由文本矩阵决定，文本空间中按 1 处理）
+			textSpaceFontSize := s.FontSize
+			if textSpaceFontSize == 0 {
+				textSpaceFontSize = 1
+			}
+			runState := &TextState{
+				Font:              resources.GetFont(s.FontName),
+				FontSize:          textSpaceFontSize,
+				CharSpacing:       s.CharSpacing,
+				WordSpacing:       s.WordSpacing,
+				HorizontalScaling: s.HorizontalScaling,
+				Leading:           s.Leading,
+				Rise:              s.Rise,
+			}
+			if textRun := buildTextRun(op, runState, finalMatrix, pageInfo.Height); len(textRun.Glyphs) > 0 {
+				textRun.FontName = s.FontName
+				textRun.FontSize = m.fontSize
+				textRuns = append(textRuns, textRun)
 			}
 
+			// 先应用字距调整，再应用文本宽度
+			tracker.advanceText(m.width + m.kerning)
+			debugPrintf("[DEBUG] Text %q: width=%.2f, kerning=%.2f, new X0=%.2f\n",
+				m.text, m.width, m.kerning, s.TextMatrix.X0)
+
 		case "Do": // 绘制 XObject（可能是图片）
 			if doOp, ok := op.(*OpDoXObject); ok {
 				xobj := resources.GetXObject(doOp.XObjectName)
 				if xobj != nil && (xobj.Subtype == "/Image" || xobj.Subtype == "Image") {
-					// 🔥 修复：使用完整的矩阵变换来计算图片位置和尺寸
-					// PDF图像XObject占据单位正方形(0,0)到(1,1)
-					// 需要通过CTM变换这四个角点来获取实际位置
-
-					// 计算图片的四个角点在用户空间中的位置
-					// 左下角 (0, 0)
-					x0, y0 := ctm.Transform(0, 0)
-					// 右下角 (1, 0)
-					x1, y1 := ctm.Transform(1, 0)
-					// 左上角 (0, 1)
-					x2, y2 := ctm.Transform(0, 1)
-					// 右上角 (1, 1)
-					x3, y3 := ctm.Transform(1, 1)
-
-					// 计算边界框
-					minX := min(min(x0, x1), min(x2, x3))
-					maxX := max(max(x0, x1), max(x2, x3))
-					minY := min(min(y0, y1), min(y2, y3))
-					maxY := max(max(y0, y1), max(y2, y3))
-
-					// 计算实际宽度和高度
-					actualWidth := maxX - minX
-					actualHeight := maxY - minY
-
-					// PDF 坐标系转换为屏幕坐标系
-					// PDF: 左下角为原点，Y轴向上
-					// 屏幕: 左上角为原点，Y轴向下
-					x := minX
-					y := pageInfo.Height - maxY
-
-					// 🔥 修复：添加图像流数据和完整的元数据
-					imageElements = append(imageElements, ImageElementInfo{
-						Name:   doOp.XObjectName,
-						X:      x,
-						Y:      y,
-						Width:  actualWidth,
-						Height: actualHeight,
-					})
+					info := imageElementAt(doOp.XObjectName, s.CTM.Multiply(toVisible), pageInfo.Height)
+					imageElements = append(imageElements, info)
 
 					debugPrintf("[DEBUG] Do operator: Image %s at (%.2f, %.2f), size: %.2fx%.2f (original: %dx%d)\n",
-						doOp.XObjectName, x, y, actualWidth, actualHeight, xobj.Width, xobj.Height)
-					debugPrintf("[DEBUG]   Corners: (%.2f,%.2f) (%.2f,%.2f) (%.2f,%.2f) (%.2f,%.2f)\n",
-						x0, y0, x1, y1, x2, y2, x3, y3)
+						doOp.XObjectName, info.X, info.Y, info.Width, info.Height, xobj.Width, xobj.Height)
 					debugPrintf("[DEBUG]   ColorSpace: %s, BitsPerComponent: %d, Stream size: %d bytes\n",
 						xobj.ColorSpace, xobj.BitsPerComponent, len(xobj.Stream))
 				}
 			}
 
-		// 图形状态操作符 - 用于提取元素时记录状态
-		case "w": // 设置线宽
-			if wOp, ok := op.(*OpSetLineWidth); ok {
-				lineWidth = wOp.Width
-				debugPrintf("[DEBUG] w operator: LineWidth=%.2f\n", lineWidth)
-			}
+		case "ID": // 内联图像（BI ... ID ... EI）
+			inlineCount++
+			name := fmt.Sprintf("inline%d", inlineCount)
+			imageElements = append(imageElements, imageElementAt(name, s.CTM.Multiply(toVisible), pageInfo.Height))
+			debugPrintf("[DEBUG] ID operator: Inline image %s\n", name)
+		}
+	}
 
-		case "J": // 设置线端点样式
-			if jOp, ok := op.(*OpSetLineCap); ok {
-				lineCap = jOp.Cap
-				debugPrintf("[DEBUG] J operator: LineCap=%d\n", lineCap)
-			}
+	return textElements, imageElements, textRuns, nil
+}
 
-		case "j": // 设置线连接样式
-			if jOp, ok := op.(*OpSetLineJoin); ok {
-				lineJoin = jOp.Join
-				debugPrintf("[DEBUG] j operator: LineJoin=%d\n", lineJoin)
-			}
+// pageVisibleOrigin 返回把用户空间原点移到页面可见区域（MediaBox 与 CropBox 的交集）左下角的平移矩阵，
+// CropBox 不从 (0,0) 开始时，提取的坐标仍以可见区域的左上角为原点
+func pageVisibleOrigin(info PageInfo) *Matrix {
+	visible := intersectPageBoxes(info.MediaBox, info.CropBox)
+	return NewTranslationMatrix(-visible[0], -visible[1])
+}
 
-		case "M": // 设置斜接限制
-			if mOp, ok := op.(*OpSetMiterLimit); ok {
-				miterLimit = mOp.Limit
-				debugPrintf("[DEBUG] M operator: MiterLimit=%.2f\n", miterLimit)
-			}
+// imageElementAt 计算图像在页面上的位置和尺寸
+// PDF 图像占据单位正方形 (0,0)-(1,1)，通过 CTM 变换四个角点后取边界框，
+// 并转换为屏幕坐标系（左上角为原点，Y 轴向下）
+func imageElementAt(name string, ctm *Matrix, pageHeight float64) ImageElementInfo {
+	x0, y0 := ctm.Transform(0, 0)
+	x1, y1 := ctm.Transform(1, 0)
+	x2, y2 := ctm.Transform(0, 1)
+	x3, y3 := ctm.Transform(1, 1)
+
+	minX := min(min(x0, x1), min(x2, x3))
+	maxX := max(max(x0, x1), max(x2, x3))
+	minY := min(min(y0, y1), min(y2, y3))
+	maxY := max(max(y0, y1), max(y2, y3))
+
+	return ImageElementInfo{
+		Name:   name,
+		X:      minX,
+		Y:      pageHeight - maxY,
+		Width:  maxX - minX,
+		Height: maxY - minY,
+	}
+}
 
-		case "d": // 设置虚线模式
-			if dOp, ok := op.(*OpSetDash); ok {
-				dashPattern = dOp.Pattern
-				dashPhase = dOp.Offset
-				debugPrintf("[DEBUG] d operator: DashPattern=%v, Phase=%.2f\n", dashPattern, dashPhase)
-			}
+// shownText 文本显示操作符解码后的文本及其在用户空间中的排版度量
+type shownText struct {
+	text     string  // 解码后的文本
+	fontSize float64 // 有效字号：Tf 字号 × 文本矩阵的缩放
+	width    float64 // 文本宽度（含 Tc/Tw，已按 Tz 缩放）
+	kerning  float64 // TJ 字距调整带来的位移（已按文本矩阵和 Tz 缩放）
+}
 
-		case "rg": // 设置填充颜色 (RGB)
-			if rgOp, ok := op.(*OpSetFillColorRGB); ok {
-				fillColor[0] = rgOp.R
-				fillColor[1] = rgOp.G
-				fillColor[2] = rgOp.B
-				debugPrintf("[DEBUG] rg operator: FillColor=(%.2f, %.2f, %.2f)\n", fillColor[0], fillColor[1], fillColor[2])
+// measureShownText 解码文本显示操作符并计算其宽度，s 为显示文本时的图形状态
+func measureShownText(op PDFOperator, s *GraphicsSnapshot, resources *Resources) shownText {
+	var m shownText
+	var text string
+	var hex bool
+
+	switch t := op.(type) {
+	case *OpShowText:
+		text, hex = t.Text, t.Hex
+	case *OpShowTextArray:
+		// TJ 操作符：处理文本数组，包括字距调整
+		// 十六进制字符串拼接其十六进制数字，整体按第一个字符串的类型解码
+		for _, elem := range t.Array {
+			if str, isHex, ok := textItem(elem); ok {
+				if text == "" {
+					hex = isHex
+				}
+				if isHex {
+					str = hexStringDigits(str)
+				}
+				text += str
+			} else if num, ok := elem.(float64); ok {
+				// 数字元素表示字距调整
+				// 负值表示向右移动（收紧间距），正值表示向左移动（放宽间距）
+				m.kerning += -num / 1000.0 * s.FontSize
+			} else if num, ok := elem.(int); ok {
+				m.kerning += -float64(num) / 1000.0 * s.FontSize
 			}
+		}
+	case *OpShowTextNextLine:
+		text, hex = t.Text, t.Hex
+	case *OpShowTextWithSpacing:
+		text, hex = t.Text, t.Hex
+	}
+	if text == "" {
+		return m
+	}
+
+	// 解码文本（处理CID字体和十六进制字符串）
+	// 同时保存原始 CID 数组用于宽度计算
+	var originalCIDs []uint16
+	font := resources.GetFont(s.FontName)
+	if font != nil {
+		originalCIDs = extractCIDsFromText(text, hex)
+		m.text = decodeTextStringWithFontAndIdentity(text, hex, font.ToUnicodeMap, font.IsIdentity)
+	} else {
+		m.text = decodeTextString(text, hex)
+	}
+	if m.text == "" {
+		return m
+	}
 
-		case "RG": // 设置描边颜色 (RGB)
-			if rgOp, ok := op.(*OpSetStrokeColorRGB); ok {
-				strokeColor[0] = rgOp.R
-				strokeColor[1] = rgOp.G
-				strokeColor[2] = rgOp.B
-				debugPrintf("[DEBUG] RG operator: StrokeColor=(%.2f, %.2f, %.2f)\n", strokeColor[0], strokeColor[1], strokeColor[2])
+	// 计算有效字体大小：基础大小 * 文本矩阵的缩放
+	// 旋转文本的 YY 分量可能为 0，因此取文本空间 y 轴向量的长度
+	// 特殊情况：如果 Tf 设置的字体大小为 0，则直接使用文本矩阵的缩放作为字体大小
+	scale := math.Hypot(s.TextMatrix.XY, s.TextMatrix.YY)
+	if s.FontSize == 0 {
+		m.fontSize = scale
+	} else {
+		m.fontSize = s.FontSize * scale
+	}
+
+	// 字符间距（Tc）和单词间距（Tw）是文本空间单位，按文本矩阵缩放后计入宽度；
+	// 单词间距只作用于单字节编码的空格（字面字符串中的 32）
+	singleByte := !hex
+	spacing := func(space bool) float64 {
+		if space {
+			return (s.CharSpacing + s.WordSpacing) * scale
+		}
+		return s.CharSpacing * scale
+	}
+	if font != nil && len(originalCIDs) > 0 {
+		// 使用 CID 数组进行精确的字体宽度计算
+		for _, cid := range originalCIDs {
+			width := font.GetWidth(cid)
+			// 🔥 修复：确保宽度不为 0
+			if width == 0 {
+				if font.DefaultWidth > 0 {
+					width = font.DefaultWidth
+				} else if font.MissingWidth > 0 {
+					width = font.MissingWidth
+				} else {
+					width = 1000.0 // 使用 1 em 作为默认值
+				}
+			}
+			m.width += (width/1000.0)*m.fontSize + spacing(singleByte && cid == ' ')
+		}
+	} else if font != nil {
+		// 回退到基于字符数的估算
+		// 🔥 修复：改进 CJK 字符宽度估算
+		runeCount := 0
+		totalWidthFactor := 0.0
+		for _, r := range m.text {
+			runeCount++
+			// 更精确的 CJK 字符范围检测
+			if (r >= 0x4E00 && r <= 0x9FFF) || // CJK统一表意文字
+				(r >= 0x3400 && r <= 0x4DBF) || // CJK扩展A
+				(r >= 0x20000 && r <= 0x2A6DF) || // CJK扩展B
+				(r >= 0x2A700 && r <= 0x2B73F) || // CJK扩展C
+				(r >= 0x2B740 && r <= 0x2B81F) || // CJK扩展D
+				(r >= 0x2B820 && r <= 0x2CEAF) || // CJK扩展E
+				(r >= 0xF900 && r <= 0xFAFF) || // CJK兼容表意文字
+				(r >= 0x2F800 && r <= 0x2FA1F) || // CJK兼容表意文字补充
+				(r >= 0x3040 && r <= 0x309F) || // 平假名
+				(r >= 0x30A0 && r <= 0x30FF) || // 片假名
+				(r >= 0xAC00 && r <= 0xD7AF) { // 韩文音节
+				totalWidthFactor += 1.0 // CJK字符通常是全角
+			} else if r >= 0xFF00 && r <= 0xFFEF {
+				// 全角ASCII和半角片假名
+				totalWidthFactor += 1.0
+			} else {
+				totalWidthFactor += 0.5 // 拉丁字符通常是半角
 			}
+			m.width += spacing(r == ' ')
+		}
+		if runeCount > 0 {
+			m.width += totalWidthFactor * m.fontSize
+		} else {
+			m.width = 0
+		}
+	} else {
+		// 最后的回退：简单估算
+		runeCount := float64(len([]rune(m.text)))
+		m.width = runeCount * m.fontSize * 0.5
+		for _, r := range m.text {
+			m.width += spacing(r == ' ')
 		}
 	}
 
-	return textElements, imageElements
+	// 水平缩放（Tz）作用于字形宽度、间距和字距调整
+	// （字距调整是文本空间单位，同样按文本矩阵缩放）
+	m.width *= s.HorizontalScaling / 100.0
+	m.kerning *= scale * s.HorizontalScaling / 100.0
+
+	return m
 }
 
 // RenderAllPagesToPNG 将所有页面渲染为 PNG 文件
 func (r *PDFReader) RenderAllPagesToPNG(outputDir string, dpi float64) error {
+	return r.RenderAllPagesToPNGContext(gocontext.Background(), outputDir, dpi)
+}
+
+// RenderAllPagesToPNGContext 与 RenderAllPagesToPNG 相同，但可通过 ctx 取消或设置超时
+// 每页之间以及页面渲染过程中都会检查 ctx，取消时返回 ctx.Err()
+func (r *PDFReader) RenderAllPagesToPNGContext(ctx gocontext.Context, outputDir string, dpi float64) error {
+	return r.renderAllPagesToPNG(ctx, outputDir, &RenderOptions{DPI: dpi})
+}
+
+// RenderAllPagesToPNGWithOptions 使用渲染选项将所有页面渲染为 PNG 文件，忽略 opts.OutputPath
+// 例如 SkipAnnotations 和 SkipForms 都为 true 时导出不含注释和表单的干净页面（用于 OCR 或归档）
+func (r *PDFReader) RenderAllPagesToPNGWithOptions(outputDir string, opts *RenderOptions) error {
+	return r.renderAllPagesToPNG(gocontext.Background(), outputDir, opts)
+}
+
+// renderAllPagesToPNG 按页顺序渲染所有页面到 outputDir/page_N.png
+func (r *PDFReader) renderAllPagesToPNG(ctx gocontext.Context, outputDir string, opts *RenderOptions) error {
 	pageCount, err := r.GetPageCount()
 	if err != nil {
 		return err
@@ -1279,8 +1891,12 @@ func (r *PDFReader) RenderAllPagesToPNG(outputDir string, dpi float64) error {
 	}
 
 	for i := 1; i <= pageCount; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		outputPath := fmt.Sprintf("%s/page_%d.png", outputDir, i)
-		if err := r.RenderPageToPNG(i, outputPath, dpi); err != nil {
+		if err := r.renderPageToPNG(ctx, i, outputPath, opts); err != nil {
 			return fmt.Errorf("failed to render page %d: %w", i, err)
 		}
 	}
@@ -1288,10 +1904,78 @@ func (r *PDFReader) RenderAllPagesToPNG(outputDir string, dpi float64) error {
 	return nil
 }
 
+// RenderAllPagesToPNGParallel 使用 workers 个 goroutine 并行渲染所有页面为 PNG 文件
+// 每个 worker 使用独立的 PDFReader（各自读取 PDF 上下文），互不共享渲染状态；
+// workers <= 0 时使用 CPU 核数。任一页面失败时停止分发剩余页面并返回第一个错误
+func (r *PDFReader) RenderAllPagesToPNGParallel(outputDir string, dpi float64, workers int) error {
+	pageCount, err := r.GetPageCount()
+	if err != nil {
+		return err
+	}
+
+	// 确保输出目录存在
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > pageCount {
+		workers = pageCount
+	}
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	defer cancel()
+
+	pages := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			worker := r.worker()
+			defer worker.Close()
+
+			for pageNum := range pages {
+				outputPath := fmt.Sprintf("%s/page_%d.png", outputDir, pageNum)
+				if err := worker.RenderPageToPNGContext(ctx, pageNum, outputPath, dpi); err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("failed to render page %d: %w", pageNum, err)
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for i := 1; i <= pageCount; i++ {
+		select {
+		case pages <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(pages)
+	wg.Wait()
+
+	return firstErr
+}
+
+// cancelCheckInterval 渲染时每执行多少个操作符检查一次取消
+const cancelCheckInterval = 256
+
 // renderPDFPageToGopdf 将 PDF 页面内容渲染到 Gopdf context
 func renderPDFPageToGopdf(pdfPath string, pageNum int, gopdfCtx Context, width, height float64) error {
 	// 打开 PDF 文件并读取上下文
-	ctx, err := api.ReadContextFile(pdfPath)
+	ctx, err := readContextFile(pdfPath, "")
 	if err != nil {
 		return fmt.Errorf("failed to read PDF context: %w", err)
 	}
@@ -1302,6 +1986,21 @@ func renderPDFPageToGopdf(pdfPath string, pageNum int, gopdfCtx Context, width,
 		return fmt.Errorf("failed to get page dict: %w", err)
 	}
 
+	return renderPageDictToGopdf(gocontext.Background(), ctx, pageDict, gopdfCtx, width, height, nil)
+}
+
+// renderPageDictToGopdf 将已解析的页面字典渲染到 Gopdf context
+// width/height 为旋转后的显示尺寸（点）；goCtx 取消时尽快返回 goCtx.Err()
+// opts.Content 决定渲染哪些内容类别，opts 为 nil 时全部渲染
+func renderPageDictToGopdf(goCtx gocontext.Context, ctx *model.Context, pageDict types.Dict, gopdfCtx Context, width, height float64, opts *RenderOptions) error {
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+	filter := ContentAll
+	if opts.Content != 0 {
+		filter = opts.Content
+	}
+
 	// 保存 Gopdf 状态
 	gopdfCtx.Save()
 	defer gopdfCtx.Restore()
@@ -1312,22 +2011,20 @@ func renderPDFPageToGopdf(pdfPath string, pageNum int, gopdfCtx Context, width,
 	// gopdfCtx.Rectangle(0, 0, width, height)
 	// gopdfCtx.Clip()
 
-	// PDF 坐标系转换：PDF 使用左下角为原点，Y 轴向上
-	// Gopdf 使用左上角为原点，Y 轴向下
-	// 需要翻转 Y 轴并平移
-	gopdfCtx.Translate(0, height)
-	gopdfCtx.Scale(1, -1)
-
-	// 处理页面的 MediaBox, CropBox, Rotate 等属性
-	if err := applyPageTransformations(pageDict, gopdfCtx, width, height); err != nil {
-		debugPrintf("Warning: failed to apply page transformations: %v\n", err)
-	}
+	// 从页面用户空间变换到显示坐标系
+	applyPageDeviceTransform(ctx, pageDict, gopdfCtx, width, height)
 
 	// 创建渲染上下文
 	renderCtx := NewRenderContext(gopdfCtx, width, height)
+	renderCtx.ContentFilter = filter
+	renderCtx.ICCTransform = opts.ICC
+	renderCtx.CMYKConversion = opts.CMYK
+	renderCtx.Strict = opts.Strict
+	renderCtx.budget = newRenderBudget(opts.MaxOperators, opts.MaxFormDepth)
+	defer renderCtx.releaseXObjectCache()
 
 	// 提取页面资源
-	if resourcesObj, found := pageDict.Find("Resources"); found {
+	if resourcesObj, found := resolvePageResources(ctx, pageDict); found {
 		if err := loadResources(ctx, resourcesObj, renderCtx.Resources); err != nil {
 			debugPrintf("Warning: failed to load resources: %v\n", err)
 		}
@@ -1340,46 +2037,118 @@ func renderPDFPageToGopdf(pdfPath string, pageNum int, gopdfCtx Context, width,
 		return nil
 	}
 
-	// 解析并渲染内容流
-	contentStreams, err := ExtractContentStreams(ctx, contents)
+	// 解析并渲染内容流；StreamContent 模式下内容流在解析到时才逐个解码
+	var contentStreams [][]byte
+	var lazyStreams []*lazyContentStream
+	var err error
+	if opts.StreamContent {
+		lazyStreams, err = lazyContentStreams(ctx, contents, false)
+	} else {
+		contentStreams, err = ExtractContentStreams(ctx, contents)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to extract content streams: %w", err)
 	}
 
-	// 合并所有内容流
-	var allContent []byte
-	for _, stream := range contentStreams {
-		allContent = append(allContent, stream...)
-		allContent = append(allContent, '\n')
-	}
-
-	// 如果内容流为空或太小，PDF 可能没有矢量内容
-	if len(allContent) < 10 {
-		debugPrintln("⚠️  Content stream is empty or too small, PDF may have no vector content")
-		return nil
+	// 如果内容流为空或太小，PDF 可能没有矢量内容（StreamContent 模式下在解析之后检查）
+	tooSmall := func(contentLen int) bool {
+		if contentLen < 10 {
+			debugPrintln("⚠️  Content stream is empty or too small, PDF may have no vector content")
+			return true
+		}
+		return false
 	}
-
-	// 解析操作符
-	operators, err := ParseContentStream(allContent)
-	if err != nil {
-		return fmt.Errorf("failed to parse content stream: %w", err)
+	if !opts.StreamContent {
+		contentLen := 0
+		for _, stream := range contentStreams {
+			contentLen += len(stream) + 1
+		}
+		if tooSmall(contentLen) {
+			return nil
+		}
 	}
 
-	// 执行所有操作符
-	debugPrintf("📊 Executing %d PDF operators...\n", len(operators))
-
 	opCount := make(map[string]int)
-	for _, op := range operators {
+	executed := 0
+	var pageImage *XObject
+	var abortErr error // 中止渲染的错误：超出渲染限制，或严格模式下的不配对错误
+	execute := func(op PDFOperator) error {
+		// 每执行一批操作符检查一次是否已取消
+		if executed%cancelCheckInterval == 0 {
+			if err := goCtx.Err(); err != nil {
+				return err
+			}
+		}
+		executed++
+
 		// 跳过忽略的操作符
 		if op.Name() == "IGNORE" {
-			continue
+			return nil
 		}
 
 		opCount[op.Name()]++
-		if err := op.Execute(renderCtx); err != nil {
+		var err error
+		if pageImage != nil && op.Name() == "Do" {
+			err = blitPageImage(renderCtx, pageImage)
+		} else {
+			err = executeOperator(renderCtx, op)
+		}
+		if err != nil {
+			if renderCtx.abortOnError(err) {
+				abortErr = fmt.Errorf("operator %d (%s): %w", executed, op.Name(), err)
+				return abortErr
+			}
 			// 继续执行，不中断渲染
 			debugPrintf("⚠️  Operator %s failed: %v\n", op.Name(), err)
 		}
+		return nil
+	}
+
+	if opts.StreamContent {
+		// 边解析边执行，不构建完整的操作符切片
+		debugPrintln("📊 Streaming PDF operators...")
+		sources := make([]io.Reader, len(lazyStreams))
+		for i, stream := range lazyStreams {
+			sources[i] = stream
+		}
+		if err := parseContentSources(sources, execute); err != nil {
+			if abortErr != nil {
+				return abortErr
+			}
+			if goErr := goCtx.Err(); goErr != nil {
+				return goErr
+			}
+			return fmt.Errorf("failed to parse content stream: %w", err)
+		}
+		contentLen := 0
+		for _, stream := range lazyStreams {
+			if stream.read > 0 {
+				contentLen += stream.read + 1
+			}
+		}
+		if tooSmall(contentLen) {
+			return nil
+		}
+	} else {
+		// 合并所有内容流并解析操作符
+		operators, err := ParseContentStreams(contentStreams)
+		if err != nil {
+			return fmt.Errorf("failed to parse content stream: %w", err)
+		}
+
+		// 只绘制一个图像的页面（如扫描页面）直接写入图像，跳过光栅化器
+		pageImage = singleImagePageXObject(operators, renderCtx.Resources)
+		if pageImage != nil {
+			debugPrintln("🖼️  Single-image page, using direct image blit")
+		}
+
+		// 执行所有操作符
+		debugPrintf("📊 Executing %d PDF operators...\n", len(operators))
+		for _, op := range operators {
+			if err := execute(op); err != nil {
+				return err
+			}
+		}
 	}
 
 	// 显示操作符统计
@@ -1390,20 +2159,40 @@ func renderPDFPageToGopdf(pdfPath string, pageNum int, gopdfCtx Context, width,
 		}
 	}
 
-	// 渲染注释（在页面内容之后）
+	if err := goCtx.Err(); err != nil {
+		return err
+	}
+
+	if !filter.Has(ContentAnnotations) || (opts.SkipAnnotations && opts.SkipForms) {
+		return nil
+	}
+
+	// 渲染注释（在页面内容之后），Widget 注释属于表单
 	annotations, err := ExtractAnnotations(ctx, pageDict)
 	if err != nil {
 		debugPrintf("⚠️  Failed to extract annotations: %v\n", err)
 	} else if len(annotations) > 0 {
 		debugPrintf("\n📌 Rendering %d annotations...\n", len(annotations))
 		annotRenderer := NewAnnotationRenderer(gopdfCtx)
+		annotRenderer.budget = renderCtx.budget
 		for i, annot := range annotations {
+			isWidget := strings.TrimPrefix(annot.Subtype, "/") == "Widget"
+			if (isWidget && opts.SkipForms) || (!isWidget && opts.SkipAnnotations) {
+				continue
+			}
 			if err := annotRenderer.RenderAnnotation(annot); err != nil {
+				if renderCtx.abortOnError(err) {
+					return err
+				}
 				debugPrintf("⚠️  Failed to render annotation %d: %v\n", i, err)
 			}
 		}
 	}
 
+	if opts.SkipForms {
+		return nil
+	}
+
 	// 渲染表单字段（在注释之后）
 	formFields, err := ExtractFormFields(ctx)
 	if err != nil {
@@ -1411,68 +2200,297 @@ func renderPDFPageToGopdf(pdfPath string, pageNum int, gopdfCtx Context, width,
 	} else if len(formFields) > 0 {
 		debugPrintf("\n📝 Rendering %d form fields...\n", len(formFields))
 		formRenderer := NewFormRenderer(gopdfCtx)
+		formRenderer.budget = renderCtx.budget
 		for i, field := range formFields {
+			// 有外观流的字段已由其 Widget 注释在注释阶段绘制
+			if field.NormalAppearance != nil {
+				continue
+			}
 			if err := formRenderer.RenderFormField(field); err != nil {
+				if renderCtx.abortOnError(err) {
+					return err
+				}
 				debugPrintf("⚠️  Failed to render form field %d: %v\n", i, err)
 			}
 		}
 	}
-
-	return nil
+
+	return nil
+}
+
+// applyPageDeviceTransform 把页面用户空间变换到显示坐标系（左上角为原点，Y 轴向下）
+// 渲染和命中测试共用，保证两者的坐标映射一致
+func applyPageDeviceTransform(ctx *model.Context, pageDict types.Dict, gopdfCtx Context, width, height float64) {
+	// PDF 坐标系转换：PDF 使用左下角为原点，Y 轴向上
+	// Gopdf 使用左上角为原点，Y 轴向下
+	// 需要翻转 Y 轴并平移
+	gopdfCtx.Translate(0, height)
+	gopdfCtx.Scale(1, -1)
+
+	// 处理页面的 MediaBox, CropBox, Rotate 等属性
+	if err := applyPageTransformations(ctx, pageDict, gopdfCtx, width, height); err != nil {
+		debugPrintf("Warning: failed to apply page transformations: %v\n", err)
+	}
+}
+
+// applyPageTransformations 应用页面级别的变换（旋转、裁剪等）
+// width/height 为旋转后的显示尺寸，PDF /Rotate 表示显示时顺时针旋转的角度
+func applyPageTransformations(ctx *model.Context, pageDict types.Dict, gopdfCtx Context, width, height float64) error {
+	// 处理页面旋转（此时坐标系 Y 轴向上）
+	switch getPageRotation(ctx, pageDict) {
+	case 90:
+		// (x, y) -> (y, height - x)
+		gopdfCtx.Translate(0, height)
+		gopdfCtx.Rotate(-math.Pi / 2)
+	case 180:
+		// (x, y) -> (width - x, height - y)
+		gopdfCtx.Translate(width, height)
+		gopdfCtx.Rotate(math.Pi)
+	case 270:
+		// (x, y) -> (width - y, x)
+		gopdfCtx.Translate(width, 0)
+		gopdfCtx.Rotate(math.Pi / 2)
+	}
+
+	// 将可见区域（MediaBox 与 CropBox 的交集）的左下角平移到原点
+	// MediaBox 原点不一定是 (0, 0)，例如 [-10 -10 602 782]
+	if box, ok := getPageVisibleBox(ctx, pageDict); ok {
+		if box[0] != 0 || box[1] != 0 {
+			gopdfCtx.Translate(-box[0], -box[1])
+		}
+	}
+
+	return nil
+}
+
+// pageGeometry 页面几何信息
+type pageGeometry struct {
+	Width    float64 // 旋转后的显示宽度（用户空间单位）
+	Height   float64 // 旋转后的显示高度（用户空间单位）
+	Rotation int     // 规范化后的旋转角度：0、90、180、270
+	UserUnit float64 // 页面 /UserUnit：一个用户空间单位对应的 1/72 英寸数，默认 1
+}
+
+// getPageGeometry 根据可见区域（MediaBox 与 CropBox 的交集）和 /Rotate 计算页面的显示尺寸
+// 90°/270° 旋转时宽高互换
+func getPageGeometry(ctx *model.Context, pageDict types.Dict) pageGeometry {
+	// 默认页面尺寸（Letter size: 8.5 x 11 inches）
+	geom := pageGeometry{Width: 612, Height: 792}
+
+	if box, ok := getPageVisibleBox(ctx, pageDict); ok {
+		geom.Width = box[2] - box[0]
+		geom.Height = box[3] - box[1]
+	}
+
+	geom.Rotation = getPageRotation(ctx, pageDict)
+	if geom.Rotation == 90 || geom.Rotation == 270 {
+		geom.Width, geom.Height = geom.Height, geom.Width
+	}
+	geom.UserUnit = getPageUserUnit(pageDict)
+
+	return geom
+}
+
+// getPageUserUnit 读取页面 /UserUnit（不继承），缺失或无效时为 1
+func getPageUserUnit(pageDict types.Dict) float64 {
+	if obj, found := pageDict.Find("UserUnit"); found {
+		if v, ok := getNumber(obj); ok && v > 0 {
+			return v
+		}
+	}
+	return 1
+}
+
+// getPageVisibleBox 返回页面可见区域：MediaBox 与 CropBox 的交集
+// CropBox 缺失或与 MediaBox 不相交时使用 MediaBox
+func getPageVisibleBox(ctx *model.Context, pageDict types.Dict) ([4]float64, bool) {
+	mediaBox, ok := getPageBox(ctx, pageDict, "MediaBox")
+	if !ok || mediaBox[2] <= mediaBox[0] || mediaBox[3] <= mediaBox[1] {
+		return mediaBox, false
+	}
+
+	cropBox, ok := getPageBox(ctx, pageDict, "CropBox")
+	if !ok {
+		return mediaBox, true
+	}
+
+	return intersectPageBoxes(mediaBox, cropBox), true
+}
+
+// intersectPageBoxes 返回 MediaBox 与 CropBox 的交集，不相交时返回 MediaBox
+func intersectPageBoxes(mediaBox, cropBox [4]float64) [4]float64 {
+	visible := [4]float64{
+		max(mediaBox[0], cropBox[0]),
+		max(mediaBox[1], cropBox[1]),
+		min(mediaBox[2], cropBox[2]),
+		min(mediaBox[3], cropBox[3]),
+	}
+	if visible[2] <= visible[0] || visible[3] <= visible[1] {
+		return mediaBox
+	}
+	return visible
+}
+
+// getPageRotation 读取页面 /Rotate（含从页面树继承的值）并规范化到 0、90、180、270
+func getPageRotation(ctx *model.Context, pageDict types.Dict) int {
+	rotateObj, found := findInheritedPageAttr(ctx, pageDict, "Rotate")
+	if !found {
+		return 0
+	}
+	if ctx != nil {
+		if derefObj, err := ctx.Dereference(rotateObj); err == nil {
+			rotateObj = derefObj
+		}
+	}
+
+	v, ok := getNumber(rotateObj)
+	if !ok {
+		return 0
+	}
+
+	return normalizeRotation(int(v))
+}
+
+// normalizeRotation 把旋转角度规范化到 0、90、180、270
+// 处理负角度（如 -90 等价于 270）以及非 90 倍数的非法值
+func normalizeRotation(v int) int {
+	rotation := ((v % 360) + 360) % 360
+	return rotation - rotation%90
+}
+
+// maxPageTreeDepth 沿 /Parent 向上查找继承属性时的最大层数，防止循环引用
+const maxPageTreeDepth = 64
+
+// findInheritedPageAttr 查找页面属性，页面自身缺失时沿 /Parent 链向上查找
+// 可继承的属性包括 Resources、MediaBox、CropBox 和 Rotate
+func findInheritedPageAttr(ctx *model.Context, pageDict types.Dict, key string) (types.Object, bool) {
+	dict := pageDict
+	for depth := 0; dict != nil && depth < maxPageTreeDepth; depth++ {
+		if obj, found := dict.Find(key); found && obj != nil {
+			return obj, true
+		}
+		if ctx == nil {
+			break
+		}
+		parentObj, found := dict.Find("Parent")
+		if !found {
+			break
+		}
+		parent, err := ctx.DereferenceDict(parentObj)
+		if err != nil {
+			debugPrintf("⚠️  Failed to resolve page tree parent: %v\n", err)
+			break
+		}
+		dict = parent
+	}
+	return nil, false
+}
+
+// resolvePageResources 解析页面的 /Resources
+// 页面自身没有 Resources 时沿 /Parent 链继承；页面和祖先节点都有 Resources 时按类别
+// （Font、XObject 等）合并，同名资源以离页面最近的节点为准，兼容只在页面上补充部分资源的文件
+func resolvePageResources(ctx *model.Context, pageDict types.Dict) (types.Dict, bool) {
+	var chain []types.Dict
+	dict := pageDict
+	for depth := 0; dict != nil && depth < maxPageTreeDepth; depth++ {
+		if obj, found := dict.Find("Resources"); found && obj != nil {
+			if resDict, err := ctx.DereferenceDict(obj); err == nil && resDict != nil {
+				chain = append(chain, resDict)
+			}
+		}
+		parentObj, found := dict.Find("Parent")
+		if !found {
+			break
+		}
+		parent, err := ctx.DereferenceDict(parentObj)
+		if err != nil {
+			debugPrintf("⚠️  Failed to resolve page tree parent: %v\n", err)
+			break
+		}
+		dict = parent
+	}
+
+	switch len(chain) {
+	case 0:
+		return nil, false
+	case 1:
+		return chain[0], true
+	}
+
+	merged := types.Dict{}
+	for _, resDict := range chain {
+		for key, obj := range resDict {
+			category, err := ctx.DereferenceDict(obj)
+			if err != nil || category == nil {
+				// ProcSet 等非字典条目：离页面最近的优先
+				if _, exists := merged[key]; !exists {
+					merged[key] = obj
+				}
+				continue
+			}
+			target, ok := merged[key].(types.Dict)
+			if !ok {
+				if _, exists := merged[key]; exists {
+					continue
+				}
+				target = types.Dict{}
+				merged[key] = target
+			}
+			for name, entry := range category {
+				if _, exists := target[name]; !exists {
+					target[name] = entry
+				}
+			}
+		}
+	}
+	return merged, true
 }
 
-// applyPageTransformations 应用页面级别的变换（旋转、裁剪等）
-func applyPageTransformations(pageDict types.Dict, gopdfCtx Context, width, height float64) error {
-	// 处理页面旋转
-	if rotateObj, found := pageDict.Find("Rotate"); found {
-		var rotation int
-		switch v := rotateObj.(type) {
-		case types.Integer:
-			rotation = int(v)
-		case types.Float:
-			rotation = int(v)
-		}
+// getPageBox 读取页面边界框（MediaBox、CropBox 等，含从页面树继承的值），返回规范化后的 [x1 y1 x2 y2]
+func getPageBox(ctx *model.Context, pageDict types.Dict, key string) ([4]float64, bool) {
+	var box [4]float64
 
-		// 应用旋转（90, 180, 270 度）
-		if rotation != 0 {
-			rotation = rotation % 360
-			switch rotation {
-			case 90:
-				gopdfCtx.Translate(width, 0)
-				gopdfCtx.Rotate(1.5707963267948966) // π/2
-			case 180:
-				gopdfCtx.Translate(width, height)
-				gopdfCtx.Rotate(3.141592653589793) // π
-			case 270:
-				gopdfCtx.Translate(0, height)
-				gopdfCtx.Rotate(4.71238898038469) // 3π/2
-			}
+	obj, found := findInheritedPageAttr(ctx, pageDict, key)
+	if !found {
+		return box, false
+	}
+
+	if ctx != nil {
+		if derefObj, err := ctx.Dereference(obj); err == nil {
+			obj = derefObj
 		}
 	}
 
-	// 处理 CropBox（如果存在）
-	if cropBoxObj, found := pageDict.Find("CropBox"); found {
-		if arr, ok := cropBoxObj.(types.Array); ok && len(arr) == 4 {
-			var x1, y1 float64
-			if v, ok := arr[0].(types.Float); ok {
-				x1 = float64(v)
-			} else if v, ok := arr[0].(types.Integer); ok {
-				x1 = float64(v)
-			}
-			if v, ok := arr[1].(types.Float); ok {
-				y1 = float64(v)
-			} else if v, ok := arr[1].(types.Integer); ok {
-				y1 = float64(v)
-			}
+	arr, ok := obj.(types.Array)
+	if !ok || len(arr) != 4 {
+		return box, false
+	}
 
-			// 应用裁剪框的平移
-			if x1 != 0 || y1 != 0 {
-				gopdfCtx.Translate(-x1, -y1)
+	for i, item := range arr {
+		if ctx != nil {
+			if derefObj, err := ctx.Dereference(item); err == nil {
+				item = derefObj
 			}
 		}
+		v, ok := getNumber(item)
+		if !ok {
+			return box, false
+		}
+		box[i] = v
 	}
 
-	return nil
+	return normalizePageBox(box), true
+}
+
+// normalizePageBox 规范化边界框：保证 x1 < x2, y1 < y2
+func normalizePageBox(box [4]float64) [4]float64 {
+	if box[0] > box[2] {
+		box[0], box[2] = box[2], box[0]
+	}
+	if box[1] > box[3] {
+		box[1], box[3] = box[3], box[1]
+	}
+	return box
 }
 
 // ExtractContentStreams 提取页面的所有内容流（公开函数）
@@ -1531,6 +2549,75 @@ func ExtractContentStreams(ctx *model.Context, contents types.Object) ([][]byte,
 	return streams, nil
 }
 
+// lazyContentStream 延迟解码的内容流：第一次读取时才解码，读完后释放解码数据，
+// 依次读取多个内容流时同一时刻只保留一个流的解码结果
+type lazyContentStream struct {
+	sd      types.StreamDict
+	lenient bool // 解码失败时视为空流（数组中的内容流，与 ExtractContentStreams 一致）
+	r       *bytes.Reader
+	done    bool
+	read    int // 已读取的字节数
+}
+
+func (s *lazyContentStream) Read(p []byte) (int, error) {
+	if s.done {
+		return 0, io.EOF
+	}
+	if s.r == nil {
+		if len(s.sd.Content) == 0 && len(s.sd.Raw) > 0 {
+			if err := s.sd.Decode(); err != nil {
+				s.done = true
+				if s.lenient {
+					debugPrintf("   ⚠️  Decode error: %v\n", err)
+					return 0, io.EOF
+				}
+				return 0, fmt.Errorf("failed to decode stream: %w", err)
+			}
+		}
+		s.r = bytes.NewReader(s.sd.Content)
+	}
+	n, err := s.r.Read(p)
+	s.read += n
+	if err == io.EOF {
+		// 释放解码数据
+		s.done = true
+		s.r = nil
+		s.sd.Content = nil
+	}
+	return n, err
+}
+
+// lazyContentStreams 与 ExtractContentStreams 一样解析 Contents，但不立即解码内容流
+func lazyContentStreams(ctx *model.Context, contents types.Object, lenient bool) ([]*lazyContentStream, error) {
+	switch obj := contents.(type) {
+	case types.IndirectRef:
+		derefObj, err := ctx.Dereference(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dereference contents: %w", err)
+		}
+		return lazyContentStreams(ctx, derefObj, lenient)
+
+	case types.StreamDict:
+		return []*lazyContentStream{{sd: obj, lenient: lenient}}, nil
+
+	case types.Array:
+		var streams []*lazyContentStream
+		for i, item := range obj {
+			itemStreams, err := lazyContentStreams(ctx, item, true)
+			if err != nil {
+				debugPrintf("   ⚠️  Error extracting item %d: %v\n", i, err)
+				continue
+			}
+			streams = append(streams, itemStreams...)
+		}
+		return streams, nil
+
+	default:
+		debugPrintf("   ⚠️  Unknown contents type: %T\n", obj)
+		return nil, nil
+	}
+}
+
 // loadResources 加载页面资源
 func loadResources(ctx *model.Context, resourcesObj types.Object, resources *Resources) error {
 	return loadResourcesWithDepth(ctx, resourcesObj, resources, 0)
@@ -1591,6 +2678,22 @@ func loadResourcesWithDepth(ctx *model.Context, resourcesObj types.Object, resou
 		}
 	}
 
+	// 加载颜色空间
+	if colorSpaceObj, found := resourcesDict.Find("ColorSpace"); found {
+		if indRef, ok := colorSpaceObj.(types.IndirectRef); ok {
+			if derefObj, err := ctx.Dereference(indRef); err == nil {
+				colorSpaceObj = derefObj
+			}
+		}
+		if colorSpaceDict, ok := colorSpaceObj.(types.Dict); ok {
+			for csName, csObj := range colorSpaceDict {
+				if err := loadColorSpace(ctx, csName, csObj, resources); err != nil {
+					debugPrintf("Warning: failed to load ColorSpace %s: %v\n", csName, err)
+				}
+			}
+		}
+	}
+
 	// 加载 Shading（渐变）
 	if shadingObj, found := resourcesDict.Find("Shading"); found {
 		if shadingDict, ok := shadingObj.(types.Dict); ok {
@@ -1640,37 +2743,62 @@ func loadFont(ctx *model.Context, fontName string, fontObj types.Object, resourc
 	if encoding, found := fontDict.Find("Encoding"); found {
 		if name, ok := encoding.(types.Name); ok {
 			font.Encoding = name.String()
+			// 预定义 CMap 中以 -V 结尾的是竖排编码（如 Identity-V、UniGB-UCS2-V）
+			font.Vertical = strings.HasSuffix(font.Encoding, "-V")
+		} else if indRef, ok := encoding.(types.IndirectRef); ok {
+			// 嵌入的 CMap 流通过 WMode 声明书写方向
+			if sd, _, err := ctx.DereferenceStreamDict(indRef); err == nil && sd != nil {
+				if wmode := sd.IntEntry("WMode"); wmode != nil && *wmode == 1 {
+					font.Vertical = true
+				}
+			}
+		}
+		if font.Vertical {
+			debugPrintf("✓ Detected vertical writing mode for font %s\n", fontName)
 		}
 	}
 
-	// 加载字体文件数据（用于嵌入字体）
-	if fontDescriptorObj, found := fontDict.Find("FontDescriptor"); found {
-		if indRef, ok := fontDescriptorObj.(types.IndirectRef); ok {
-			derefObj, err := ctx.Dereference(indRef)
-			if err == nil {
-				if fontDescriptorDict, ok := derefObj.(types.Dict); ok {
-					// 尝试加载 FontFile2 (TTF) 或 FontFile3 (CFF)
-					if fontFileObj, found := fontDescriptorDict.Find("FontFile2"); found {
-						if fontFileRef, ok := fontFileObj.(types.IndirectRef); ok {
-							fontFileData, err := loadFontFileData(ctx, fontFileRef)
-							if err == nil {
-								font.EmbeddedFontData = fontFileData
-								debugPrintf("✓ Loaded embedded TTF font data for font %s (%d bytes)\n", fontName, len(fontFileData))
-							} else {
-								debugPrintf("Warning: failed to load FontFile2 data for font %s: %v\n", fontName, err)
-							}
-						}
-					} else if fontFileObj, found := fontDescriptorDict.Find("FontFile3"); found {
-						if fontFileRef, ok := fontFileObj.(types.IndirectRef); ok {
-							fontFileData, err := loadFontFileData(ctx, fontFileRef)
-							if err == nil {
-								font.EmbeddedFontData = fontFileData
-								debugPrintf("✓ Loaded embedded CFF font data for font %s (%d bytes)\n", fontName, len(fontFileData))
-							} else {
-								debugPrintf("Warning: failed to load FontFile3 data for font %s: %v\n", fontName, err)
-							}
-						}
-					}
+	// 子集字体的 BaseFont 带有 6 个大写字母加 "+" 的前缀，如 ABCDEF+Arial
+	font.IsSubset = isSubsetFontName(font.BaseFont)
+
+	// 读取字体描述符：度量标志和嵌入的字体文件数据
+	if fontDescriptorDict := findFontDescriptor(ctx, fontDict); fontDescriptorDict != nil {
+		if flags := fontDescriptorDict.IntEntry("Flags"); flags != nil {
+			font.Flags = *flags
+		}
+		if obj, found := fontDescriptorDict.Find("ItalicAngle"); found {
+			font.ItalicAngle, _ = getNumber(obj)
+		}
+		if obj, found := fontDescriptorDict.Find("StemV"); found {
+			font.StemV, _ = getNumber(obj)
+		}
+
+		// FontFile (Type 1)、FontFile2 (TTF)、FontFile3 (CFF/OpenType) 任一存在即为嵌入字体
+		for _, key := range []string{"FontFile", "FontFile2", "FontFile3"} {
+			if _, found := fontDescriptorDict.Find(key); found {
+				font.IsEmbedded = true
+			}
+		}
+
+		// 尝试加载 FontFile2 (TTF) 或 FontFile3 (CFF)
+		if fontFileObj, found := fontDescriptorDict.Find("FontFile2"); found {
+			if fontFileRef, ok := fontFileObj.(types.IndirectRef); ok {
+				fontFileData, err := loadFontFileData(ctx, fontFileRef)
+				if err == nil {
+					font.EmbeddedFontData = fontFileData
+					debugPrintf("✓ Loaded embedded TTF font data for font %s (%d bytes)\n", fontName, len(fontFileData))
+				} else {
+					debugPrintf("Warning: failed to load FontFile2 data for font %s: %v\n", fontName, err)
+				}
+			}
+		} else if fontFileObj, found := fontDescriptorDict.Find("FontFile3"); found {
+			if fontFileRef, ok := fontFileObj.(types.IndirectRef); ok {
+				fontFileData, err := loadFontFileData(ctx, fontFileRef)
+				if err == nil {
+					font.EmbeddedFontData = fontFileData
+					debugPrintf("✓ Loaded embedded CFF font data for font %s (%d bytes)\n", fontName, len(fontFileData))
+				} else {
+					debugPrintf("Warning: failed to load FontFile3 data for font %s: %v\n", fontName, err)
 				}
 			}
 		}
@@ -1715,7 +2843,7 @@ func loadFont(ctx *model.Context, fontName string, fontObj types.Object, resourc
 		debugPrintf("Warning: failed to load font widths for %s: %v\n", fontName, err)
 	} else {
 		if font.Widths != nil {
-			if font.Subtype == "/Type0" {
+			if font.Subtype == "/Type0" || font.Subtype == "Type0" {
 				debugPrintf("✓ Loaded font widths for %s: %d CID mappings, %d ranges, default=%.0f\n",
 					fontName, len(font.Widths.CIDWidths), len(font.Widths.CIDRanges), font.DefaultWidth)
 			} else {
@@ -1735,7 +2863,7 @@ func loadFont(ctx *model.Context, fontName string, fontObj types.Object, resourc
 	}
 
 	// 如果没有 ToUnicode，尝试从 poppler-data 加载
-	if font.ToUnicodeMap == nil && font.Subtype == "/Type0" {
+	if font.ToUnicodeMap == nil && (font.Subtype == "/Type0" || font.Subtype == "Type0") {
 		// 尝试从字体名称推断 CID 系统信息
 		// 例如: MicrosoftYaHeiUI-Bold 可能是中文字体
 		registry := guessCIDRegistry(font.BaseFont)
@@ -1765,6 +2893,49 @@ func loadFont(ctx *model.Context, fontName string, fontObj types.Object, resourc
 	return nil
 }
 
+// findFontDescriptor 返回字体的 FontDescriptor 字典
+// Type0 字体本身没有 FontDescriptor，从 DescendantFonts 的第一个 CIDFont 中读取
+func findFontDescriptor(ctx *model.Context, fontDict types.Dict) types.Dict {
+	if obj, found := fontDict.Find("FontDescriptor"); found {
+		if dict, err := ctx.DereferenceDict(obj); err == nil && dict != nil {
+			return dict
+		}
+	}
+
+	descendantsObj, found := fontDict.Find("DescendantFonts")
+	if !found {
+		return nil
+	}
+	descendants, err := ctx.DereferenceArray(descendantsObj)
+	if err != nil || len(descendants) == 0 {
+		return nil
+	}
+	cidFontDict, err := ctx.DereferenceDict(descendants[0])
+	if err != nil || cidFontDict == nil {
+		return nil
+	}
+	if obj, found := cidFontDict.Find("FontDescriptor"); found {
+		if dict, err := ctx.DereferenceDict(obj); err == nil {
+			return dict
+		}
+	}
+	return nil
+}
+
+// isSubsetFontName 判断 BaseFont 是否带有子集前缀（6 个大写字母加 "+"）
+func isSubsetFontName(baseFont string) bool {
+	baseFont = strings.TrimPrefix(baseFont, "/")
+	if len(baseFont) < 7 || baseFont[6] != '+' {
+		return false
+	}
+	for i := 0; i < 6; i++ {
+		if baseFont[i] < 'A' || baseFont[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
 // guessCIDRegistry 从字体名称推断 CID 注册表
 func guessCIDRegistry(fontName string) string {
 	fontName = strings.ToLower(fontName)
@@ -1800,13 +2971,17 @@ func guessCIDRegistry(fontName string) string {
 
 // loadXObject 加载 XObject 资源
 func loadXObject(ctx *model.Context, xobjName string, xobjObj types.Object, resources *Resources) error {
-	// 解引用
+	xobj := &XObject{}
+
+	// 解引用，并记录对象号用于页面内的图像缓存
 	if indRef, ok := xobjObj.(types.IndirectRef); ok {
 		derefObj, err := ctx.Dereference(indRef)
 		if err != nil {
 			return err
 		}
 		xobjObj = derefObj
+		xobj.ObjectNumber = indRef.ObjectNumber.Value()
+		xobj.Generation = indRef.GenerationNumber.Value()
 	}
 
 	streamDict, ok := xobjObj.(types.StreamDict)
@@ -1814,8 +2989,6 @@ func loadXObject(ctx *model.Context, xobjName string, xobjObj types.Object, reso
 		return fmt.Errorf("XObject is not a stream")
 	}
 
-	xobj := &XObject{}
-
 	// 获取子类型
 	if subtype, found := streamDict.Find("Subtype"); found {
 		if name, ok := subtype.(types.Name); ok {
@@ -1827,28 +3000,37 @@ func loadXObject(ctx *model.Context, xobjName string, xobjObj types.Object, reso
 	debugPrintf("[loadXObject] Decoding stream for %s...\n", xobjName)
 	debugPrintf("[loadXObject] Raw stream length: %d bytes\n", len(streamDict.Raw))
 
-	// 先尝试使用 DereferenceStreamDict
-	decoded, _, err := ctx.DereferenceStreamDict(streamDict)
-	if err != nil {
-		debugPrintf("[loadXObject] ERROR: Failed to decode stream: %v\n", err)
-		return fmt.Errorf("failed to decode XObject stream: %w", err)
-	}
-
-	if decoded != nil && len(decoded.Content) > 0 {
-		xobj.Stream = decoded.Content
-		debugPrintf("[loadXObject] Stream decoded via DereferenceStreamDict: %d bytes\n", len(xobj.Stream))
+	if filters := streamFilterNames(streamDict); isJBIG2Stream(filters) {
+		// pdfcpu 不支持 JBIG2Decode，解码为 1 位 DeviceGray 采样
+		jbig2Data, err := decodeJBIG2Stream(ctx, streamDict, filters)
+		if err != nil {
+			return fmt.Errorf("failed to decode JBIG2 image %s: %w", xobjName, err)
+		}
+		xobj.Stream = jbig2Data
 	} else {
-		// 如果 DereferenceStreamDict 返回空内容，尝试直接解码
-		debugPrintf("[loadXObject] DereferenceStreamDict returned empty, trying direct decode...\n")
-		if len(streamDict.Content) == 0 && len(streamDict.Raw) > 0 {
-			err := streamDict.Decode()
-			if err != nil {
-				debugPrintf("[loadXObject] ERROR: Direct decode failed: %v\n", err)
-				return fmt.Errorf("failed to decode XObject stream: %w", err)
+		// 先尝试使用 DereferenceStreamDict
+		decoded, _, err := ctx.DereferenceStreamDict(streamDict)
+		if err != nil {
+			debugPrintf("[loadXObject] ERROR: Failed to decode stream: %v\n", err)
+			return fmt.Errorf("failed to decode XObject stream: %w", err)
+		}
+
+		if decoded != nil && len(decoded.Content) > 0 {
+			xobj.Stream = decoded.Content
+			debugPrintf("[loadXObject] Stream decoded via DereferenceStreamDict: %d bytes\n", len(xobj.Stream))
+		} else {
+			// 如果 DereferenceStreamDict 返回空内容，尝试直接解码
+			debugPrintf("[loadXObject] DereferenceStreamDict returned empty, trying direct decode...\n")
+			if len(streamDict.Content) == 0 && len(streamDict.Raw) > 0 {
+				err := streamDict.Decode()
+				if err != nil {
+					debugPrintf("[loadXObject] ERROR: Direct decode failed: %v\n", err)
+					return fmt.Errorf("failed to decode XObject stream: %w", err)
+				}
 			}
+			xobj.Stream = streamDict.Content
+			debugPrintf("[loadXObject] Stream decoded via direct Decode(): %d bytes\n", len(xobj.Stream))
 		}
-		xobj.Stream = streamDict.Content
-		debugPrintf("[loadXObject] Stream decoded via direct Decode(): %d bytes\n", len(xobj.Stream))
 	}
 
 	// 🔥 新增:应用额外的图像滤镜(如果需要)
@@ -1947,23 +3129,24 @@ func loadXObject(ctx *model.Context, xobjName string, xobjObj types.Object, reso
 		if matrix, found := streamDict.Find("Matrix"); found {
 			if arr, ok := matrix.(types.Array); ok && len(arr) == 6 {
 				xobj.Matrix = &Matrix{}
-				if v, ok := arr[0].(types.Float); ok {
-					xobj.Matrix.XX = float64(v)
+				// 矩阵元素可以是整数或浮点数（如 [1 0 0 1 0 0]）
+				if v, ok := getNumber(arr[0]); ok {
+					xobj.Matrix.XX = v
 				}
-				if v, ok := arr[1].(types.Float); ok {
-					xobj.Matrix.YX = float64(v)
+				if v, ok := getNumber(arr[1]); ok {
+					xobj.Matrix.YX = v
 				}
-				if v, ok := arr[2].(types.Float); ok {
-					xobj.Matrix.XY = float64(v)
+				if v, ok := getNumber(arr[2]); ok {
+					xobj.Matrix.XY = v
 				}
-				if v, ok := arr[3].(types.Float); ok {
-					xobj.Matrix.YY = float64(v)
+				if v, ok := getNumber(arr[3]); ok {
+					xobj.Matrix.YY = v
 				}
-				if v, ok := arr[4].(types.Float); ok {
-					xobj.Matrix.X0 = float64(v)
+				if v, ok := getNumber(arr[4]); ok {
+					xobj.Matrix.X0 = v
 				}
-				if v, ok := arr[5].(types.Float); ok {
-					xobj.Matrix.Y0 = float64(v)
+				if v, ok := getNumber(arr[5]); ok {
+					xobj.Matrix.Y0 = v
 				}
 			}
 		}
@@ -1981,7 +3164,7 @@ func loadXObject(ctx *model.Context, xobjName string, xobjObj types.Object, reso
 			if groupDict, ok := group.(types.Dict); ok {
 				// 检查是否为透明度组
 				if subtype, found := groupDict.Find("S"); found {
-					if name, ok := subtype.(types.Name); ok && name.String() == "/Transparency" {
+					if name, ok := subtype.(types.Name); ok && name.Value() == "Transparency" {
 						isolated := false
 						knockout := false
 						colorSpace := "DeviceRGB"
@@ -2033,6 +3216,13 @@ func loadXObject(ctx *model.Context, xobjName string, xobjObj types.Object, reso
 			}
 		}
 
+		// Interpolate 标志：放大显示时使用平滑插值
+		if interp, found := streamDict.Find("Interpolate"); found {
+			if b, ok := interp.(types.Boolean); ok {
+				xobj.Interpolate = bool(b)
+			}
+		}
+
 		// 解析颜色空间
 		colorSpaceFound := false
 		if colorSpace, found := streamDict.Find("ColorSpace"); found {
@@ -2078,6 +3268,11 @@ func loadXObject(ctx *model.Context, xobjName string, xobjObj types.Object, reso
 					obj, err := ctx.Dereference(indRef)
 					if err == nil {
 						if streamDict, ok := obj.(types.StreamDict); ok {
+							// 保存配置文件数据，启用 ICC 转换时使用
+							if decoded, _, err := ctx.DereferenceStreamDict(streamDict); err == nil && decoded != nil {
+								xobj.ICCProfile = decoded.Content
+							}
+
 							// 获取 N (颜色分量数)
 							if nObj, found := streamDict.Find("N"); found {
 								if n, ok := nObj.(types.Integer); ok {
@@ -2122,6 +3317,26 @@ func loadXObject(ctx *model.Context, xobjName string, xobjObj types.Object, reso
 					}
 				}
 			}
+		} else if xobj.ColorSpace == "CalRGB" || xobj.ColorSpace == "/CalRGB" || xobj.ColorSpace == "CalGray" || xobj.ColorSpace == "/CalGray" {
+			// 校准颜色空间：分量数由族名确定，白点、gamma 和矩阵用于转换到 sRGB
+			if arr, ok := xobj.ColorSpaceArray.(types.Array); ok {
+				cs, err := parseColorSpaceObject(ctx, arr, 0)
+				if err != nil {
+					debugPrintf("[loadXObject] Failed to parse %s color space: %v\n", xobj.ColorSpace, err)
+				} else {
+					applyColorSpaceToXObject(xobj, cs)
+				}
+			}
+		} else if xobj.ColorSpace == "/Separation" || xobj.ColorSpace == "/DeviceN" {
+			// 解析备用颜色空间和 tint 变换函数
+			if arr, ok := xobj.ColorSpaceArray.(types.Array); ok {
+				cs, err := parseColorSpaceObject(ctx, arr, 0)
+				if err != nil {
+					debugPrintf("[loadXObject] Failed to parse %s color space: %v\n", xobj.ColorSpace, err)
+				} else {
+					applyColorSpaceToXObject(xobj, cs)
+				}
+			}
 		} else if xobj.ColorSpace == "/Indexed" || xobj.ColorSpace == "Indexed" {
 			// 解析 Indexed 数组以获取调色板
 			if arr, ok := xobj.ColorSpaceArray.(types.Array); ok && len(arr) >= 4 {
@@ -2156,6 +3371,27 @@ func loadXObject(ctx *model.Context, xobjName string, xobjObj types.Object, reso
 			}
 		}
 
+		// JPXDecode 图像的字典可以省略 ColorSpace 和 BitsPerComponent，此时取自 JPEG 2000 数据；
+		// 字典中给出的 ColorSpace 优先于 JPEG 2000 数据内部的颜色空间
+		if xobj.JPX = slices.Contains(streamFilterNames(streamDict), "JPXDecode"); xobj.JPX {
+			if info, err := ParseJPXHeader(xobj.Stream); err == nil {
+				xobj.BitsPerComponent = info.BitsPerComponent
+				if !colorSpaceFound || xobj.ColorSpace == "" {
+					switch {
+					case info.ColorSpace == "DeviceGray" || info.Components == 1:
+						xobj.ColorSpace = "DeviceGray"
+					case info.Components == 4:
+						xobj.ColorSpace = "DeviceCMYK"
+					default:
+						xobj.ColorSpace = "DeviceRGB"
+					}
+					colorSpaceFound = true
+				}
+				debugPrintf("[loadXObject] JPXDecode image %s: %dx%d, %d components, %d bits\n",
+					xobjName, info.Width, info.Height, info.Components, info.BitsPerComponent)
+			}
+		}
+
 		// 如果没有找到 ColorSpace，根据图像属性推断
 		if !colorSpaceFound || xobj.ColorSpace == "" {
 			// 根据 BitsPerComponent 推断颜色空间
@@ -2256,6 +3492,37 @@ func loadSMaskXObject(ctx *model.Context, indRef types.IndirectRef) (*XObject, e
 		xobj.ColorSpace = "DeviceGray" // 默认
 	}
 
+	// 读取 Matte：基础图像的颜色已与该颜色预混合
+	if matte, found := streamDict.Find("Matte"); found {
+		if indRef, ok := matte.(types.IndirectRef); ok {
+			if derefMatte, err := ctx.Dereference(indRef); err == nil {
+				matte = derefMatte
+			}
+		}
+		if arr, ok := matte.(types.Array); ok {
+			xobj.Matte = make([]float64, 0, len(arr))
+			for _, v := range arr {
+				if num, ok := v.(types.Float); ok {
+					xobj.Matte = append(xobj.Matte, float64(num))
+				} else if num, ok := v.(types.Integer); ok {
+					xobj.Matte = append(xobj.Matte, float64(num))
+				}
+			}
+		}
+	}
+
+	// 读取 TR：应用到遮罩采样值的传递函数（/Identity 或缺省时为恒等映射）
+	if tr, found := streamDict.Find("TR"); found {
+		if name, ok := tr.(types.Name); !ok || name.Value() != "Identity" {
+			transfer, err := loadPDFFunction(ctx, tr)
+			if err != nil {
+				debugPrintf("[loadSMaskXObject] Ignoring unsupported TR: %v\n", err)
+			} else {
+				xobj.Transfer = transfer
+			}
+		}
+	}
+
 	// 解码流
 	if err := streamDict.Decode(); err != nil {
 		return nil, fmt.Errorf("failed to decode SMask stream: %w", err)
@@ -2335,6 +3602,16 @@ func loadFontWidths(ctx *model.Context, fontDict types.Dict, font *Font) error {
 		}
 	}
 
+	// 没有嵌入字体程序也没有 Widths 的标准 14 字体使用 AFM 宽度
+	if len(widths.Widths) == 0 && len(font.EmbeddedFontData) == 0 && font.Subtype != "/Type3" {
+		if name, ok := standardFontName(font.BaseFont); ok {
+			if std := standardFontWidths(name, font.Encoding); std != nil {
+				widths = std
+				debugPrintf("✓ Using standard font metrics %s for font %s\n", name, font.Name)
+			}
+		}
+	}
+
 	font.Widths = widths
 	return nil
 }
@@ -2420,10 +3697,82 @@ func loadCIDFontWidths(ctx *model.Context, fontDict types.Dict, font *Font) erro
 		}
 	}
 
+	// 读取竖排度量 DW2 / W2（仅竖排字体使用）
+	if dw2Obj, found := descendantFontDict.Find("DW2"); found {
+		if indRef, ok := dw2Obj.(types.IndirectRef); ok {
+			if derefObj, err := ctx.Dereference(indRef); err == nil {
+				dw2Obj = derefObj
+			}
+		}
+		if dw2Array, ok := dw2Obj.(types.Array); ok && len(dw2Array) == 2 {
+			vy, ok1 := getNumber(dw2Array[0])
+			w1, ok2 := getNumber(dw2Array[1])
+			if ok1 && ok2 {
+				font.DefaultVertical = [2]float64{vy, w1}
+			}
+		}
+	}
+	if w2Obj, found := descendantFontDict.Find("W2"); found {
+		if indRef, ok := w2Obj.(types.IndirectRef); ok {
+			if derefObj, err := ctx.Dereference(indRef); err == nil {
+				w2Obj = derefObj
+			}
+		}
+		if w2Array, ok := w2Obj.(types.Array); ok {
+			font.VerticalMetrics = parseCIDVerticalMetricsArray(w2Array)
+			debugPrintf("✓ Loaded vertical metrics for font %s: %d CIDs\n", font.Name, len(font.VerticalMetrics))
+		}
+	}
+
 	font.Widths = widths
 	return nil
 }
 
+// parseCIDVerticalMetricsArray 解析 CID 字体的 W2 数组
+// 格式: [c [w1 vx vy w1 vx vy ...]] 表示从 CID c 开始的连续 CID 的竖排度量
+// 格式: [c1 c2 w1 vx vy] 表示 CID c1 到 c2 使用相同的竖排度量
+func parseCIDVerticalMetricsArray(w2Array types.Array) map[uint16]VerticalMetric {
+	metrics := make(map[uint16]VerticalMetric)
+	i := 0
+	for i < len(w2Array) {
+		startCID, ok := getInteger(w2Array[i])
+		if !ok || i+1 >= len(w2Array) {
+			i++
+			continue
+		}
+
+		if nextArray, ok := w2Array[i+1].(types.Array); ok {
+			for j := 0; j+2 < len(nextArray); j += 3 {
+				w1, ok1 := getNumber(nextArray[j])
+				vx, ok2 := getNumber(nextArray[j+1])
+				vy, ok3 := getNumber(nextArray[j+2])
+				if ok1 && ok2 && ok3 {
+					metrics[uint16(startCID+int64(j/3))] = VerticalMetric{W1: w1, VX: vx, VY: vy}
+				}
+			}
+			i += 2
+			continue
+		}
+
+		if i+4 >= len(w2Array) {
+			break
+		}
+		endCID, ok := getInteger(w2Array[i+1])
+		w1, ok1 := getNumber(w2Array[i+2])
+		vx, ok2 := getNumber(w2Array[i+3])
+		vy, ok3 := getNumber(w2Array[i+4])
+		if !ok || !ok1 || !ok2 || !ok3 {
+			i++
+			continue
+		}
+		for cid := startCID; cid <= endCID && cid <= 0xFFFF; cid++ {
+			metrics[uint16(cid)] = VerticalMetric{W1: w1, VX: vx, VY: vy}
+		}
+		i += 5
+	}
+	return metrics
+}
+
 // parseCIDWidthsArray 解析 CID 字体的 W 数组
 // 格式: [c1 c2 w] 表示 CID c1 到 c2 的宽度都是 w
 // 格式: [c [w1 w2 ... wn]] 表示从 CID c 开始的连续 CID 的宽度
@@ -2561,6 +3910,12 @@ func loadExtGState(ctx *model.Context, gsName string, gsObj types.Object, resour
 			extGState[key] = v.String()
 		case types.Boolean:
 			extGState[key] = bool(v)
+		case types.Dict, types.IndirectRef:
+			if key == "SMask" {
+				if smask := loadSoftMaskDict(ctx, v); smask != nil {
+					extGState[key] = smask
+				}
+			}
 		}
 	}
 
@@ -2568,6 +3923,77 @@ func loadExtGState(ctx *model.Context, gsName string, gsObj types.Object, resour
 	return nil
 }
 
+// loadSoftMaskDict 解析 ExtGState 的 /SMask 软遮罩字典
+// 返回包含 S（Alpha 或 Luminosity）、G（遮罩组 Form XObject）、BC（背景色分量）和 TR（传递函数）的映射，
+// 无法解析遮罩组时返回 nil
+func loadSoftMaskDict(ctx *model.Context, obj types.Object) map[string]interface{} {
+	obj, err := ctx.Dereference(obj)
+	if err != nil {
+		debugPrintf("Warning: failed to resolve soft mask: %v\n", err)
+		return nil
+	}
+	dict, ok := obj.(types.Dict)
+	if !ok {
+		return nil
+	}
+
+	gObj, found := dict.Find("G")
+	if !found {
+		return nil
+	}
+	groupResources := NewResources()
+	if err := loadXObject(ctx, "G", gObj, groupResources); err != nil {
+		debugPrintf("Warning: failed to load soft mask group: %v\n", err)
+		return nil
+	}
+	g := groupResources.GetXObject("G")
+	if g == nil || (g.Subtype != "Form" && g.Subtype != "/Form") {
+		return nil
+	}
+
+	// 遮罩组使用自己的资源字典（ExtGState、着色等）
+	if gStream, err := ctx.Dereference(gObj); err == nil {
+		if sd, ok := gStream.(types.StreamDict); ok {
+			if resourcesObj, found := sd.Find("Resources"); found {
+				g.Resources = NewResources()
+				if err := loadResources(ctx, resourcesObj, g.Resources); err != nil {
+					debugPrintf("Warning: failed to load soft mask group resources: %v\n", err)
+				}
+			}
+		}
+	}
+
+	smask := map[string]interface{}{"S": "Luminosity", "G": g}
+	if s, ok := dict.Find("S"); ok {
+		if name, ok := s.(types.Name); ok {
+			smask["S"] = name.Value()
+		}
+	}
+	if bcObj, found := dict.Find("BC"); found {
+		if bcObj, err := ctx.Dereference(bcObj); err == nil {
+			if arr, ok := bcObj.(types.Array); ok {
+				bc := make([]float64, 0, len(arr))
+				for _, v := range arr {
+					if num, ok := getNumber(v); ok {
+						bc = append(bc, num)
+					}
+				}
+				smask["BC"] = bc
+			}
+		}
+	}
+	if tr, found := dict.Find("TR"); found {
+		if name, ok := tr.(types.Name); !ok || name.Value() != "Identity" {
+			if transfer, err := loadPDFFunction(ctx, tr); err == nil {
+				smask["TR"] = transfer
+			} else {
+				debugPrintf("Warning: ignoring unsupported soft mask TR: %v\n", err)
+			}
+		}
+	}
+	return smask
+}
+
 // ExtractPageText 从 PDF 页面提取文本内容（导出供外部使用）
 func ExtractPageText(ctx *model.Context, pageNum int) (string, error) {
 	// 使用 pdfcpu 的 ExtractPageContent 提取文本
@@ -2781,42 +4207,14 @@ func ExtractTextFromStream(stream string) string {
 
 // ConvertGopdfSurfaceToImage 将 Gopdf surface 转换为 Go image.Image（导出供外部使用）
 func ConvertGopdfSurfaceToImage(imgSurf ImageSurface) image.Image {
-	data := imgSurf.GetData()
-	stride := imgSurf.GetStride()
-	width := imgSurf.GetWidth()
-	height := imgSurf.GetHeight()
-
-	img := image.NewRGBA(image.Rect(0, 0, width, height))
-
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			offset := y*stride + x*4
-			// Gopdf 使用 BGRA 预乘 alpha 格式
-			b := data[offset+0]
-			g := data[offset+1]
-			r := data[offset+2]
-			a := data[offset+3]
-
-			// 如果使用了预乘 alpha，需要反预乘
-			if a > 0 && a < 255 {
-				alpha := float64(a)
-				r = uint8(float64(r) * 255.0 / alpha)
-				g = uint8(float64(g) * 255.0 / alpha)
-				b = uint8(float64(b) * 255.0 / alpha)
-			}
-
-			img.SetRGBA(x, y, color.RGBA{R: r, G: g, B: b, A: a})
-		}
-	}
-
-	return img
+	return imgSurf.AsRGBA()
 }
 
-// ConvertPDFPageToImage 使用 Gopdf 将 PDF 页面转换为图像的辅助函数
+// ConvertPDFPageToImage 使用 Gopdf 将 PDF 页面转换为 width x height 像素图像的辅助函数
+// 页面按实际尺寸等比缩放并居中（FitContain），空白处为白色
 func ConvertPDFPageToImage(pdfPath string, pageNum int, width, height int) (image.Image, error) {
 	reader := NewPDFReader(pdfPath)
-	dpi := float64(width) / 8.5 // 假设 Letter size
-	return reader.RenderPageToImage(pageNum, dpi)
+	return reader.RenderPageToSize(pageNum, width, height, FitContain)
 }
 
 // SaveImageToPNG 保存图像为 PNG 文件
@@ -2831,6 +4229,45 @@ func SaveImageToPNG(img image.Image, outputPath string) error {
 	return png.Encode(outFile, img)
 }
 
+// SaveImageToJPEG 将图像保存为 JPEG 文件，quality 取值 1-100（超出范围时使用 DefaultJPEGQuality）
+// JPEG 不支持 alpha 通道，透明像素会先合成到白色背景上
+func SaveImageToJPEG(img image.Image, outputPath string, quality int) error {
+	outFile, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer outFile.Close()
+
+	return encodeImage(outFile, img, ImageFormatJPEG, quality)
+}
+
+// SaveImageToTIFF 将图像保存为 TIFF 文件（Deflate 压缩，保留 alpha）
+func SaveImageToTIFF(img image.Image, outputPath string) error {
+	outFile, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer outFile.Close()
+
+	if err := tiff.Encode(outFile, img, &tiff.Options{Compression: tiff.Deflate}); err != nil {
+		return fmt.Errorf("failed to encode TIFF: %w", err)
+	}
+	return nil
+}
+
+// flattenImageOnWhite 将带 alpha 的图像合成到白色背景上，返回不透明图像
+func flattenImageOnWhite(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+
+	bounds := img.Bounds()
+	flat := image.NewRGBA(bounds)
+	draw.Draw(flat, bounds, image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, bounds, img, bounds.Min, draw.Over)
+	return flat
+}
+
 // FontInfo 字体信息
 type FontInfo struct {
 	Name              string
@@ -2843,6 +4280,11 @@ type FontInfo struct {
 	ToUnicodeRanges   int
 	CIDSystemInfo     string
 	EmbeddedFontSize  int
+	IsEmbedded        bool    // FontDescriptor 中存在 FontFile、FontFile2 或 FontFile3
+	IsSubset          bool    // BaseFont 带有子集前缀（如 ABCDEF+）
+	Flags             int     // FontDescriptor /Flags，见 FontFlagSerif 等常量
+	ItalicAngle       float64 // FontDescriptor /ItalicAngle（度，逆时针为正）
+	StemV             float64 // FontDescriptor /StemV
 }
 
 // ExtractFontInfo 提取页面中使用的字体信息
@@ -2850,7 +4292,7 @@ func (r *PDFReader) ExtractFontInfo(pageNum int) []FontInfo {
 	var fontInfos []FontInfo
 
 	// 打开 PDF 文件并读取上下文
-	ctx, err := api.ReadContextFile(r.pdfPath)
+	ctx, err := r.readContext()
 	if err != nil {
 		debugPrintf("Failed to read PDF context: %v\n", err)
 		return fontInfos
@@ -2865,7 +4307,7 @@ func (r *PDFReader) ExtractFontInfo(pageNum int) []FontInfo {
 
 	// 提取资源
 	resources := NewResources()
-	if resourcesObj, found := pageDict.Find("Resources"); found {
+	if resourcesObj, found := resolvePageResources(ctx, pageDict); found {
 		if err := loadResources(ctx, resourcesObj, resources); err != nil {
 			debugPrintf("Failed to load resources: %v\n", err)
 			return fontInfos
@@ -2882,6 +4324,11 @@ func (r *PDFReader) ExtractFontInfo(pageNum int) []FontInfo {
 			IsIdentity:       font.IsIdentity,
 			CIDSystemInfo:    font.CIDSystemInfo,
 			EmbeddedFontSize: len(font.EmbeddedFontData),
+			IsEmbedded:       font.IsEmbedded,
+			IsSubset:         font.IsSubset,
+			Flags:            font.Flags,
+			ItalicAngle:      font.ItalicAngle,
+			StemV:            font.StemV,
 		}
 
 		if font.ToUnicodeMap != nil {
@@ -2907,11 +4354,10 @@ func ReadContextFile(pdfPath string) (*model.Context, error) {
 }
 
 // extractCIDsFromText 从文本字符串中提取 CID 数组
-func extractCIDsFromText(text string) []uint16 {
-	// 检查是否是十六进制字符串
-	if len(text) >= 2 && text[0] == '<' && text[len(text)-1] == '>' {
-		hexStr := text[1 : len(text)-1]
-		hexStr = strings.ReplaceAll(hexStr, " ", "")
+// hex 表示 text 是十六进制字符串 <...>，否则每个字节是一个字符码
+func extractCIDsFromText(text string, hex bool) []uint16 {
+	if hex {
+		hexStr := hexStringDigits(text)
 
 		// 转换十六进制到字节
 		var result []byte
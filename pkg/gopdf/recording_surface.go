@@ -1,32 +1,32 @@
 package gopdf
 
 import (
+	"math"
 	"runtime"
+	"sync/atomic"
 )
 
 // RecordingSurface is a surface that records all drawing operations.
+// 记录的操作可以多次重放到其他上下文（不同 DPI 的图像、PDF/SVG 表面、平铺图案单元等）
 type RecordingSurface interface {
 	Surface
 	Replay(target Context) error
+	GetExtents() Rectangle
+	InkExtents() Rectangle
 }
 
 // recordingSurface implements the RecordingSurface interface.
 type recordingSurface struct {
 	baseSurface
 
-	// The recorded operations will be stored here.
-	// Since the operations are complex (e.g., SetSource, Stroke, MoveTo),
-	// we will store them as a list of function calls or a custom struct
-	// that represents the gopdf API call.
-	// For simplicity in this implementation, we will use a placeholder
-	// and assume the Context is modified to handle the recording.
-	// A full implementation would require defining a complex command pattern.
-	// For now, we will focus on the surface structure and the Replay method signature.
-
 	extents Rectangle
 
-	// The list of recorded operations (placeholder)
-	operations []interface{}
+	// 记录的绘制操作（设备空间 = 记录表面坐标）
+	operations []*vectorDrawOp
+
+	// 已记录内容的设备空间包围盒
+	inkX1, inkY1, inkX2, inkY2 float64
+	hasInk                     bool
 }
 
 // NewRecordingSurface creates a new recording surface.
@@ -45,39 +45,204 @@ func NewRecordingSurface(content Content, width, height float64) Surface {
 			fallbackResolutionY: 72.0,
 		},
 		extents:    Rectangle{0, 0, width, height},
-		operations: make([]interface{}, 0),
+		operations: make([]*vectorDrawOp, 0),
 	}
+	surface.deviceTransform.InitIdentity()
+	surface.deviceTransformInverse.InitIdentity()
 
 	runtime.SetFinalizer(surface, (*recordingSurface).Destroy)
 	return surface
 }
 
+func (s *recordingSurface) Reference() Surface {
+	atomic.AddInt32(&s.refCount, 1)
+	return s
+}
+
+func (s *recordingSurface) Destroy() {
+	if atomic.AddInt32(&s.refCount, -1) == 0 {
+		for _, op := range s.operations {
+			if op.source != nil {
+				op.source.Destroy()
+			}
+		}
+		s.operations = nil
+		s.cleanup()
+	}
+}
+
+// recordDrawOp 记录一次绘制操作并更新内容包围盒
+func (s *recordingSurface) recordDrawOp(op *vectorDrawOp) {
+	if s.finished {
+		return
+	}
+	if op.source != nil {
+		op.source = op.source.Reference()
+	}
+	s.operations = append(s.operations, op)
+
+	x1, y1, x2, y2, ok := s.opExtents(op)
+	if !ok {
+		return
+	}
+	if !s.hasInk {
+		s.inkX1, s.inkY1, s.inkX2, s.inkY2 = x1, y1, x2, y2
+		s.hasInk = true
+		return
+	}
+	s.inkX1, s.inkY1 = min(s.inkX1, x1), min(s.inkY1, y1)
+	s.inkX2, s.inkY2 = max(s.inkX2, x2), max(s.inkY2, y2)
+}
+
+// opExtents 计算一次操作在设备空间的包围盒（曲线使用控制点，描边按线宽的一半外扩），
+// 与裁剪路径和表面范围求交
+func (s *recordingSurface) opExtents(op *vectorDrawOp) (x1, y1, x2, y2 float64, ok bool) {
+	if op.kind == vectorOpPaint && len(op.path) == 0 {
+		// 没有裁剪的 Paint 覆盖整个表面
+		x1, y1, x2, y2 = s.extents.X, s.extents.Y, s.extents.X+s.extents.Width, s.extents.Y+s.extents.Height
+	} else {
+		x1, y1, x2, y2, ok = pathDeviceBounds(op.path, &op.matrix)
+		if !ok {
+			return 0, 0, 0, 0, false
+		}
+		if op.kind == vectorOpStroke {
+			// 线宽在用户空间，按矩阵的最大缩放换算到设备空间
+			scale := math.Max(math.Hypot(op.matrix.XX, op.matrix.YX), math.Hypot(op.matrix.XY, op.matrix.YY))
+			pad := op.lineWidth / 2 * scale
+			x1, y1, x2, y2 = x1-pad, y1-pad, x2+pad, y2+pad
+		}
+	}
+
+	if len(op.clip) > 0 {
+		cx1, cy1, cx2, cy2, clipOK := pathDeviceBounds(op.clip, &op.matrix)
+		if clipOK {
+			x1, y1, x2, y2 = max(x1, cx1), max(y1, cy1), min(x2, cx2), min(y2, cy2)
+		}
+	}
+	if s.extents.Width > 0 && s.extents.Height > 0 {
+		x1, y1 = max(x1, s.extents.X), max(y1, s.extents.Y)
+		x2, y2 = min(x2, s.extents.X+s.extents.Width), min(y2, s.extents.Y+s.extents.Height)
+	}
+	if x2 <= x1 || y2 <= y1 {
+		return 0, 0, 0, 0, false
+	}
+	return x1, y1, x2, y2, true
+}
+
+// pathDeviceBounds 路径所有点经矩阵变换后的包围盒
+func pathDeviceBounds(data []pathOp, m *Matrix) (x1, y1, x2, y2 float64, ok bool) {
+	for _, op := range data {
+		for _, p := range op.points {
+			x, y := MatrixTransformPoint(m, p.x, p.y)
+			if !ok {
+				x1, y1, x2, y2 = x, y, x, y
+				ok = true
+				continue
+			}
+			x1, y1 = min(x1, x), min(y1, y)
+			x2, y2 = max(x2, x), max(y2, y)
+		}
+	}
+	return x1, y1, x2, y2, ok
+}
+
 // Replay plays back the recorded operations onto the target context.
+// 记录表面的坐标作为目标上下文当前的用户空间：目标的 CTM（如 DPI 缩放）作用于所有重放的操作
 func (s *recordingSurface) Replay(target Context) error {
-	// In a real implementation, this method would iterate over s.operations
-	// and call the corresponding methods on the target Context.
-	// Example:
-	// for _, op := range s.operations {
-	//     switch v := op.(type) {
-	//     case *MoveToOp:
-	//         target.MoveTo(v.x, v.y)
-	//     // ... other operations
-	//     }
-	// }
+	if target == nil {
+		return newError(StatusNullPointer, "nil replay target")
+	}
+	if target.Status() != StatusSuccess {
+		return newError(target.Status(), "")
+	}
+
+	if err := target.Save(); err != nil {
+		return err
+	}
+	defer target.Restore()
+
+	base := target.GetMatrix()
+	for _, op := range s.operations {
+		if err := replayDrawOp(target, base, op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayDrawOp 在目标上下文中重新执行一次记录的绘制操作
+func replayDrawOp(target Context, base *Matrix, op *vectorDrawOp) error {
+	if err := target.Save(); err != nil {
+		return err
+	}
+	defer target.Restore()
+
+	target.SetMatrix(base)
+	target.Transform(&op.matrix)
+	target.SetOperator(op.operator)
+
+	// 裁剪路径（Paint 的裁剪路径保存在 path 中）
+	clip, clipRule := op.clip, op.clipRule
+	if op.kind == vectorOpPaint {
+		clip, clipRule = op.path, op.fillRule
+	}
+	if len(clip) > 0 {
+		target.NewPath()
+		replayPathData(target, clip)
+		target.SetFillRule(clipRule)
+		target.Clip()
+	}
+
+	if op.source != nil {
+		target.SetSource(op.source)
+	}
+
+	switch op.kind {
+	case vectorOpPaint:
+		return target.PaintWithAlpha(op.alpha)
+	case vectorOpFill:
+		target.SetFillRule(op.fillRule)
+		target.NewPath()
+		replayPathData(target, op.path)
+		return target.Fill()
+	case vectorOpStroke:
+		target.SetLineWidth(op.lineWidth)
+		target.SetLineCap(op.lineCap)
+		target.SetLineJoin(op.lineJoin)
+		target.SetMiterLimit(op.miterLimit)
+		target.SetDash(op.dash, op.dashOffset)
+		target.NewPath()
+		replayPathData(target, op.path)
+		return target.Stroke()
+	}
 	return nil
 }
 
+// replayPathData 将记录的路径追加到目标上下文的当前路径
+func replayPathData(target Context, data []pathOp) {
+	for _, op := range data {
+		switch op.op {
+		case PathMoveTo:
+			target.MoveTo(op.points[0].x, op.points[0].y)
+		case PathLineTo:
+			target.LineTo(op.points[0].x, op.points[0].y)
+		case PathCurveTo:
+			target.CurveTo(op.points[0].x, op.points[0].y, op.points[1].x, op.points[1].y, op.points[2].x, op.points[2].y)
+		case PathClosePath:
+			target.ClosePath()
+		}
+	}
+}
+
 // GetExtents returns the extents of the recording surface.
 func (s *recordingSurface) GetExtents() Rectangle {
 	return s.extents
 }
 
-// AddOperation is a helper function for the Context to record an operation.
-// This is a simplified approach. A proper implementation would involve
-// a command pattern where each drawing operation is an object.
-func (s *recordingSurface) AddOperation(op interface{}) {
-	s.operations = append(s.operations, op)
+// InkExtents 返回已记录内容的包围盒（设备空间）；没有可见内容时返回空矩形
+func (s *recordingSurface) InkExtents() Rectangle {
+	if !s.hasInk {
+		return Rectangle{}
+	}
+	return Rectangle{X: s.inkX1, Y: s.inkY1, Width: s.inkX2 - s.inkX1, Height: s.inkY2 - s.inkY1}
 }
-
-// We also need to update context.go to handle this.
-// For now, this file defines the surface structure.
@@ -0,0 +1,82 @@
+package gopdf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxFormDepth RenderOptions.MaxFormDepth 为 0 时表单 XObject 的最大嵌套深度
+// 防止相互引用（或引用自身）的表单无限递归
+const DefaultMaxFormDepth = 32
+
+// ErrRenderLimitExceeded 渲染超出操作符数量或表单嵌套深度的限制
+// 超出限制时返回 *RenderLimitError，errors.Is(err, ErrRenderLimitExceeded) 为 true
+var ErrRenderLimitExceeded = errors.New("render limit exceeded")
+
+// RenderLimitError 超出渲染限制时返回的错误
+type RenderLimitError struct {
+	Limit string // 超出的限制："operators"（RenderOptions.MaxOperators）或 "form depth"（RenderOptions.MaxFormDepth）
+	Max   int    // 限制值
+}
+
+func (e *RenderLimitError) Error() string {
+	return fmt.Sprintf("%v: %s exceeds %d", ErrRenderLimitExceeded, e.Limit, e.Max)
+}
+
+// Unwrap 使 errors.Is(err, ErrRenderLimitExceeded) 成立
+func (e *RenderLimitError) Unwrap() error {
+	return ErrRenderLimitExceeded
+}
+
+// renderBudget 一次渲染的操作符数量和表单嵌套深度预算
+// 同一页面的嵌套渲染上下文（如软遮罩）共享同一个预算
+type renderBudget struct {
+	maxOperators int // 最多执行的操作符数，0 表示不限制
+	maxFormDepth int // 表单 XObject 的最大嵌套深度
+	operators    int // 已执行的操作符数
+	formDepth    int // 当前的表单嵌套深度
+}
+
+// newRenderBudget 创建渲染预算，maxFormDepth 不大于 0 时使用 DefaultMaxFormDepth
+func newRenderBudget(maxOperators, maxFormDepth int) *renderBudget {
+	if maxOperators < 0 {
+		maxOperators = 0
+	}
+	if maxFormDepth <= 0 {
+		maxFormDepth = DefaultMaxFormDepth
+	}
+	return &renderBudget{maxOperators: maxOperators, maxFormDepth: maxFormDepth}
+}
+
+// countOperator 记录执行一个操作符，超出 maxOperators 时返回错误
+func (b *renderBudget) countOperator() error {
+	if b == nil || b.maxOperators == 0 {
+		return nil
+	}
+	b.operators++
+	if b.operators > b.maxOperators {
+		return &RenderLimitError{Limit: "operators", Max: b.maxOperators}
+	}
+	return nil
+}
+
+// enterForm 进入一层表单 XObject，超出 maxFormDepth 时返回错误；成功时返回离开该层的函数
+func (b *renderBudget) enterForm() (func(), error) {
+	if b == nil {
+		return func() {}, nil
+	}
+	if b.formDepth >= b.maxFormDepth {
+		return nil, &RenderLimitError{Limit: "form depth", Max: b.maxFormDepth}
+	}
+	b.formDepth++
+	return func() { b.formDepth-- }, nil
+}
+
+// nestedAbortError 包装嵌套内容流中止渲染的错误，注明所在的内容流
+// 渲染限制错误原样返回，避免深层嵌套时逐层重复包装
+func nestedAbortError(where string, err error) error {
+	if errors.Is(err, ErrRenderLimitExceeded) {
+		return err
+	}
+	return fmt.Errorf("%s: %w", where, err)
+}
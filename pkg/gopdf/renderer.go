@@ -29,7 +29,63 @@ type RenderOptions struct {
 	DPI        float64 // 分辨率，默认 72
 	OutputPath string  // 输出文件路径
 	Format     Format  // 图片格式，默认 ARGB32
-	Background *RGB    // 背景色，nil 表示透明
+	// Background 背景色；PDFRenderer 中 nil 表示透明，PDFReader 渲染页面时 nil 表示白色
+	Background *RGB
+	// TransparentBackground 不绘制背景，保留 ARGB32 表面的零 alpha，忽略 Background；
+	// 导出的 PNG 保留页面透明度，用于叠加和合成
+	TransparentBackground bool
+	// Content 选择要渲染的内容类别（仅用于 PDFReader 渲染页面），零值表示全部渲染
+	Content ContentFilter
+	// GammaCorrectBlending 在线性光空间中进行 alpha 混合，消除饱和色抗锯齿边缘的暗边；
+	// 默认在 sRGB 空间混合，速度更快且与之前的输出一致（见 Context.SetGammaCorrectBlending）
+	GammaCorrectBlending bool
+	// Antialias 路径填充和描边的抗锯齿模式，AntialiasNone 每像素只在中心采样一次（硬边缘）；
+	// 零值 AntialiasDefault 使用 4x4 超采样
+	Antialias Antialias
+	// ICC 使用嵌入的 ICC 配置文件转换 ICCBased 图像，只作用于本次渲染
+	// （SetICCTransformEnabled 为影响所有渲染的全局开关，两者任一开启即转换）
+	ICC bool
+	// SkipAnnotations 不渲染注释（Link、Highlight 等，不含表单 Widget）；零值与之前一样全部渲染
+	SkipAnnotations bool
+	// SkipForms 不渲染表单字段，包括 Widget 注释的外观流
+	SkipForms bool
+	// StreamContent 边解析边执行内容流（见 ParseContentStreamReader），不构建完整的操作符切片，
+	// 页面的多个内容流在解析到时才逐个解码、读完即释放，降低大型矢量页面的内存占用；
+	// 单个内容流仍整体解码，其解码数据在解析期间常驻内存；此模式下不使用单图像页面的直接写入优化
+	StreamContent bool
+	// CMYK DeviceCMYK 颜色和图像的转换方式；零值 CMYKDefault 使用全局设置（SetCMYKConversion，默认朴素公式），
+	// CMYKNaive 强制使用朴素公式，CMYKSWOP 使用近似 SWOP 印刷配置文件的转换，CMYK 照片不再偏暗偏灰
+	CMYK CMYKConversion
+	// Strict 严格模式（仅用于 PDFReader 渲染页面）：内容流中不匹配的 Q（图形状态栈为空）或没有 BT 的 ET
+	// 使渲染返回包装 ErrUnbalancedContent 的错误；默认宽松模式记录警告后继续渲染
+	Strict bool
+	// MaxOperators 渲染页面时最多执行的操作符数（包括表单 XObject、透明度组和软遮罩中的操作符），
+	// 超出时渲染中止并返回 *RenderLimitError；0 表示不限制
+	MaxOperators int
+	// MaxFormDepth 表单 XObject 的最大嵌套深度，超出时渲染中止并返回 *RenderLimitError；
+	// 0 使用 DefaultMaxFormDepth
+	MaxFormDepth int
+}
+
+// ContentFilter 渲染内容类别过滤器，可按位组合
+type ContentFilter uint8
+
+const (
+	ContentText        ContentFilter = 1 << iota // 文本（Tj/TJ/'/"）
+	ContentImages                                // 图像 XObject 和内联图像
+	ContentVectors                               // 路径描边/填充和 sh 着色
+	ContentAnnotations                           // 注释和表单字段
+
+	// ContentAll 渲染全部内容
+	ContentAll = ContentText | ContentImages | ContentVectors | ContentAnnotations
+)
+
+// Has 判断是否包含指定内容类别，零值视为 ContentAll
+func (f ContentFilter) Has(c ContentFilter) bool {
+	if f == 0 {
+		return true
+	}
+	return f&c != 0
 }
 
 // RGB 颜色
@@ -362,7 +418,7 @@ func (r *PDFRenderer) renderWithPixman(opts *RenderOptions, width, height int, s
 	}
 
 	// 设置背景色
-	if opts.Background != nil {
+	if opts.Background != nil && !opts.TransparentBackground {
 		bgColor := color.RGBA{
 			R: uint8(opts.Background.R * 255),
 			G: uint8(opts.Background.G * 255),
@@ -456,7 +512,7 @@ func (r *PDFRenderer) renderWithGopdf(opts *RenderOptions, width, height int, sc
 	defer ctx.Destroy()
 
 	// 设置背景色
-	if opts.Background != nil {
+	if opts.Background != nil && !opts.TransparentBackground {
 		ctx.SetSourceRGB(opts.Background.R, opts.Background.G, opts.Background.B)
 		ctx.Paint()
 	}
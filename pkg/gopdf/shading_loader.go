@@ -18,8 +18,16 @@ func loadShading(ctx *model.Context, shadingName string, shadingObj types.Object
 		shadingObj = derefObj
 	}
 
-	shadingDict, ok := shadingObj.(types.Dict)
-	if !ok {
+	// 网格阴影（类型 4-7）是流，字典部分与其他类型相同
+	var shadingDict types.Dict
+	var meshStream *types.StreamDict
+	switch v := shadingObj.(type) {
+	case types.Dict:
+		shadingDict = v
+	case types.StreamDict:
+		shadingDict = v.Dict
+		meshStream = &v
+	default:
 		return fmt.Errorf("shading is not a dictionary")
 	}
 
@@ -36,6 +44,11 @@ func loadShading(ctx *model.Context, shadingName string, shadingObj types.Object
 	if colorSpace, found := shadingDict.Find("ColorSpace"); found {
 		if cs, ok := colorSpace.(types.Name); ok {
 			shading.ColorSpace = cs.String()
+		} else if cs, err := parseColorSpaceObject(ctx, colorSpace, 0); err == nil {
+			shading.ColorSpace = cs.GetName()
+			shading.ColorSpaceObj = cs
+		} else {
+			debugPrintf("Warning: Failed to parse shading color space: %v\n", err)
 		}
 	}
 
@@ -68,7 +81,7 @@ func loadShading(ctx *model.Context, shadingName string, shadingObj types.Object
 
 	// 获取 Function
 	if function, found := shadingDict.Find("Function"); found {
-		shadingFunc, err := parseShadingFunction(ctx, function)
+		shadingFunc, err := loadPDFFunction(ctx, function)
 		if err == nil {
 			shading.Function = shadingFunc
 		} else {
@@ -76,88 +89,19 @@ func loadShading(ctx *model.Context, shadingName string, shadingObj types.Object
 		}
 	}
 
-	// 存储到资源
-	resources.SetShading(shadingName, shading)
-	debugPrintf("✓ Loaded shading %s (type %d)\n", shadingName, shading.ShadingType)
-
-	return nil
-}
-
-// parseShadingFunction 解析 Shading Function
-func parseShadingFunction(ctx *model.Context, functionObj types.Object) (*ShadingFunction, error) {
-	// 解引用
-	if indRef, ok := functionObj.(types.IndirectRef); ok {
-		derefObj, err := ctx.Dereference(indRef)
-		if err != nil {
-			return nil, err
-		}
-		functionObj = derefObj
-	}
-
-	funcDict, ok := functionObj.(types.Dict)
-	if !ok {
-		return nil, fmt.Errorf("function is not a dictionary")
-	}
-
-	shadingFunc := NewShadingFunction()
-
-	// 获取 FunctionType
-	if funcType, found := funcDict.Find("FunctionType"); found {
-		if ft, ok := funcType.(types.Integer); ok {
-			shadingFunc.FunctionType = int(ft)
-		}
-	}
-
-	// 获取 Domain
-	if domain, found := funcDict.Find("Domain"); found {
-		if arr, ok := domain.(types.Array); ok {
-			shadingFunc.Domain = make([]float64, len(arr))
-			for i, v := range arr {
-				if num, ok := v.(types.Float); ok {
-					shadingFunc.Domain[i] = float64(num)
-				} else if num, ok := v.(types.Integer); ok {
-					shadingFunc.Domain[i] = float64(num)
-				}
-			}
-		}
-	}
-
-	// 获取 C0 (起始颜色)
-	if c0, found := funcDict.Find("C0"); found {
-		if arr, ok := c0.(types.Array); ok {
-			shadingFunc.C0 = make([]float64, len(arr))
-			for i, v := range arr {
-				if num, ok := v.(types.Float); ok {
-					shadingFunc.C0[i] = float64(num)
-				} else if num, ok := v.(types.Integer); ok {
-					shadingFunc.C0[i] = float64(num)
-				}
-			}
+	// 解码网格阴影的顶点流
+	if shading.IsMesh() {
+		if meshStream == nil {
+			return fmt.Errorf("mesh shading (type %d) is not a stream", shading.ShadingType)
 		}
-	}
-
-	// 获取 C1 (结束颜色)
-	if c1, found := funcDict.Find("C1"); found {
-		if arr, ok := c1.(types.Array); ok {
-			shadingFunc.C1 = make([]float64, len(arr))
-			for i, v := range arr {
-				if num, ok := v.(types.Float); ok {
-					shadingFunc.C1[i] = float64(num)
-				} else if num, ok := v.(types.Integer); ok {
-					shadingFunc.C1[i] = float64(num)
-				}
-			}
+		if err := loadMeshShading(ctx, shading, meshStream); err != nil {
+			debugPrintf("Warning: failed to decode mesh shading: %v\n", err)
 		}
 	}
 
-	// 获取 N (指数)
-	if n, found := funcDict.Find("N"); found {
-		if num, ok := n.(types.Float); ok {
-			shadingFunc.N = float64(num)
-		} else if num, ok := n.(types.Integer); ok {
-			shadingFunc.N = float64(num)
-		}
-	}
+	// 存储到资源
+	resources.SetShading(shadingName, shading)
+	debugPrintf("✓ Loaded shading %s (type %d)\n", shadingName, shading.ShadingType)
 
-	return shadingFunc, nil
+	return nil
 }
@@ -7,13 +7,14 @@ import (
 
 // SoftMask 软遮罩（用于高级透明度效果）
 type SoftMask struct {
-	Type    string      // "Alpha" 或 "Luminosity"
-	G       *XObject    // 遮罩的图形对象（Form XObject）
-	BC      []float64   // 背景颜色
-	TR      interface{} // 传递函数
-	Surface Surface     // 渲染的遮罩 surface
-	SubType string      // 子类型
-	Matte   []float64   // 遮罩的背景色（用于预乘）
+	Type    string       // "Alpha" 或 "Luminosity"
+	G       *XObject     // 遮罩的图形对象（Form XObject）
+	BC      []float64    // 背景颜色
+	TR      interface{}  // 传递函数（*PDFFunction），nil 表示恒等映射
+	Surface Surface      // 渲染的遮罩 surface
+	SubType string       // 子类型
+	Matte   []float64    // 遮罩的背景色（用于预乘）
+	Mask    *image.Alpha // 设备空间的遮罩值（RenderSoftMask 之后有效）
 }
 
 // NewSoftMask 创建新的软遮罩
@@ -26,24 +27,24 @@ func NewSoftMask(maskType string, g *XObject) *SoftMask {
 	}
 }
 
-// RenderSoftMask 渲染软遮罩到 Gopdf surface
+// RenderSoftMask 把遮罩组渲染为设备空间的遮罩值（width x height 像素）
+// 遮罩组使用设置 gs 时的 CTM，其余图形状态参数为初始值：
+//   - Luminosity：组绘制在 BC 背景色之上，遮罩值为结果的亮度
+//   - Alpha：组绘制在透明背景上，遮罩值为结果的 alpha（忽略 BC）
+//
+// 遮罩值经 TR 传递函数映射后存入 Mask，Surface 为 alpha 等于遮罩值的黑色图像
 func (sm *SoftMask) RenderSoftMask(ctx *RenderContext, width, height int) error {
 	if sm.G == nil {
 		return fmt.Errorf("soft mask has no graphics object")
 	}
 
-	// 创建遮罩 surface
-	// 对于 Alpha 类型，使用 A8 格式（只有 alpha 通道）
-	// 对于 Luminosity 类型，使用 ARGB32 格式
-	var format Format
-	if sm.Type == "Alpha" {
-		format = FormatA8
-	} else {
-		format = FormatARGB32
+	maskSurface := NewImageSurface(FormatARGB32, width, height)
+	if maskSurface == nil || maskSurface.Status() != StatusSuccess {
+		return fmt.Errorf("failed to create mask surface")
 	}
-
-	maskSurface := NewImageSurface(format, width, height)
-	if maskSurface == nil {
+	imgSurface, ok := maskSurface.(ImageSurface)
+	if !ok {
+		maskSurface.Destroy()
 		return fmt.Errorf("failed to create mask surface")
 	}
 
@@ -54,21 +55,35 @@ func (sm *SoftMask) RenderSoftMask(ctx *RenderContext, width, height int) error
 	}
 	defer maskCtx.Destroy()
 
-	// 设置背景色
-	if len(sm.BC) >= 3 {
-		maskCtx.SetSourceRGB(sm.BC[0], sm.BC[1], sm.BC[2])
+	// 亮度遮罩的背景色（组颜色空间的分量，按分量个数解释）
+	luminosity := sm.Type == "Luminosity"
+	if luminosity {
+		r, g, b := colorComponentsToRGB(nil, sm.BC)
+		maskCtx.SetSourceRGB(r, g, b)
 		maskCtx.Paint()
 	}
 
+	// 遮罩的坐标系是设置 gs 时的用户空间
+	maskCtx.SetMatrix(ctx.GopdfCtx.GetMatrix())
+	stack := NewGraphicsStateStack(float64(width), float64(height))
+	if state := ctx.GetCurrentState(); state != nil && state.CTM != nil {
+		stack.Current().CTM = state.CTM.Clone()
+	}
+
 	// 创建临时渲染上下文
 	tempCtx := &RenderContext{
 		GopdfCtx:           maskCtx,
-		GraphicsStack:      NewGraphicsStateStack(float64(width), float64(height)),
+		GraphicsStack:      stack,
 		MarkedContentStack: NewMarkedContentStack(),
 		CurrentPath:        NewPath(),
 		TextState:          NewTextState(),
-		Resources:          ctx.Resources, // 共享资源
-		XObjectCache:       make(map[string]Surface),
+		Resources:          ctx.Resources,    // 共享资源
+		XObjectCache:       ctx.XObjectCache, // 共享页面内的图像缓存
+		ICCTransform:       ctx.ICCTransform,
+		CMYKConversion:     ctx.CMYKConversion,
+		Strict:             ctx.Strict,
+		stateBase:          1,
+		budget:             ctx.budget, // 遮罩内容计入页面的渲染预算
 	}
 
 	// 渲染遮罩内容
@@ -77,99 +92,38 @@ func (sm *SoftMask) RenderSoftMask(ctx *RenderContext, width, height int) error
 		return fmt.Errorf("failed to render soft mask: %w", err)
 	}
 
-	// 如果是 Luminosity 类型，需要转换为 alpha
-	if sm.Type == "Luminosity" {
-		sm.convertLuminosityToAlpha(maskSurface)
+	var transfer *[256]uint8
+	if fn, ok := sm.TR.(*PDFFunction); ok {
+		transfer = transferTable(fn)
 	}
 
-	sm.Surface = maskSurface
-	return nil
-}
-
-// convertLuminosityToAlpha 将亮度转换为 alpha 值
-// 使用 Pixman 后端进行高效的像素操作
-func (sm *SoftMask) convertLuminosityToAlpha(surface Surface) {
-	imgSurface, ok := surface.(ImageSurface)
+	// 计算遮罩值，并把 Surface 改写为 alpha 等于遮罩值的黑色图像（预乘后颜色分量为 0）
+	rgba, ok := imgSurface.GetGoImage().(*image.RGBA)
 	if !ok {
-		return
-	}
-
-	width := imgSurface.GetWidth()
-	height := imgSurface.GetHeight()
-
-	// 创建 Pixman 后端进行像素操作
-	// 从 Gopdf surface 获取 RGBA 数据
-	converter := NewGopdfImageConverter()
-	img := converter.GopdfSurfaceToImage(imgSurface)
-	rgba, ok := img.(*image.RGBA)
-	if !ok {
-		// 回退到原始方法
-		sm.convertLuminosityToAlphaFallback(surface)
-		return
-	}
-
-	backend := NewPixmanBackendFromRGBA(rgba)
-	if backend == nil {
-		sm.convertLuminosityToAlphaFallback(surface)
-		return
-	}
-	defer backend.Destroy()
-
-	// 使用 Pixman 处理像素
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			// 获取像素
-			pixel := backend.GetImage().GetPixel(x, y)
-
-			// 计算亮度（使用标准公式）
-			r := float64(pixel.R)
-			g := float64(pixel.G)
-			b := float64(pixel.B)
-			luminosity := 0.299*r + 0.587*g + 0.114*b
-
-			// 设置 alpha 值
-			pixel.A = uint8(luminosity)
-			backend.GetImage().SetPixel(x, y, pixel)
-		}
-	}
-
-	// 将处理后的数据写回 Gopdf surface
-	resultRGBA := backend.ToRGBA()
-	resultSurface, err := converter.ImageToGopdfSurface(resultRGBA, FormatARGB32)
-	if err == nil {
-		// 复制数据回原 surface
-		srcData := resultSurface.GetData()
-		dstData := imgSurface.GetData()
-		copy(dstData, srcData)
-		imgSurface.MarkDirty()
-		resultSurface.Destroy()
-	}
-}
-
-// convertLuminosityToAlphaFallback 回退方法
-func (sm *SoftMask) convertLuminosityToAlphaFallback(surface Surface) {
-	imgSurface, ok := surface.(ImageSurface)
-	if !ok {
-		return
+		maskSurface.Destroy()
+		return fmt.Errorf("mask surface has no pixel buffer")
 	}
-
-	data := imgSurface.GetData()
-	stride := imgSurface.GetStride()
-	width := imgSurface.GetWidth()
-	height := imgSurface.GetHeight()
-
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
-			offset := y*stride + x*4
-			b := float64(data[offset+0])
-			g := float64(data[offset+1])
-			r := float64(data[offset+2])
-			luminosity := 0.299*r + 0.587*g + 0.114*b
-			data[offset+3] = uint8(luminosity)
+			i := y*rgba.Stride + x*4
+			p := rgba.Pix[i : i+4 : i+4]
+			v := p[3]
+			if luminosity {
+				// 背景不透明，像素的预乘值即颜色值
+				v = uint8(0.30*float64(p[0]) + 0.59*float64(p[1]) + 0.11*float64(p[2]) + 0.5)
+			}
+			if transfer != nil {
+				v = transfer[v]
+			}
+			mask.Pix[y*mask.Stride+x] = v
+			p[0], p[1], p[2], p[3] = 0, 0, 0, v
 		}
 	}
 
-	imgSurface.MarkDirty()
+	sm.Mask = mask
+	sm.Surface = maskSurface
+	return nil
 }
 
 // ApplySoftMask 应用软遮罩到 Gopdf context
@@ -178,12 +132,13 @@ func (sm *SoftMask) ApplySoftMask(ctx Context) error {
 		return fmt.Errorf("soft mask not rendered")
 	}
 
-	// 创建 surface pattern 并应用遮罩
+	// 创建 surface pattern 并应用遮罩；遮罩表面位于设备空间，用 CTM 把用户空间映射到它
 	pattern := NewPatternForSurface(sm.Surface)
 	if pattern == nil {
 		return fmt.Errorf("failed to create pattern from mask surface")
 	}
 	defer pattern.Destroy()
+	pattern.SetMatrix(ctx.GetMatrix())
 
 	// 使用 Gopdf 的 mask 功能应用遮罩
 	ctx.Mask(pattern)
@@ -191,6 +146,43 @@ func (sm *SoftMask) ApplySoftMask(ctx Context) error {
 	return nil
 }
 
+// executeOperator 执行操作符；当前图形状态设置了软遮罩时，绘制操作符经遮罩合成
+// 每个操作符计入渲染预算（RenderOptions.MaxOperators）
+func executeOperator(ctx *RenderContext, op PDFOperator) error {
+	if err := ctx.budget.countOperator(); err != nil {
+		return err
+	}
+	state := ctx.GetCurrentState()
+	if state == nil || state.SoftMask == nil || state.SoftMask.Surface == nil || !knockoutPaintingOps[op.Name()] {
+		return op.Execute(ctx)
+	}
+	if contextImage(ctx.GopdfCtx) == nil {
+		return op.Execute(ctx)
+	}
+	return executeMaskedOperator(ctx, op, state)
+}
+
+// executeMaskedOperator 把绘制操作符先绘制到透明的临时表面，
+// 再以当前混合模式按遮罩值逐像素合成到目标表面
+func executeMaskedOperator(ctx *RenderContext, op PDFOperator, state *GraphicsState) error {
+	mask := state.SoftMask
+	ctx.GopdfCtx.Save()
+	defer ctx.GopdfCtx.Restore()
+
+	// 操作符内部（如表单 XObject 的内容）不再重复应用遮罩
+	state.SoftMask = nil
+	ctx.GopdfCtx.PushGroup()
+	err := op.Execute(ctx)
+	ctx.GopdfCtx.PopGroupToSource()
+	state.SoftMask = mask
+
+	ctx.GopdfCtx.SetOperator(GetGopdfBlendMode(state.BlendMode))
+	if maskErr := mask.ApplySoftMask(ctx.GopdfCtx); maskErr != nil && err == nil {
+		err = maskErr
+	}
+	return err
+}
+
 // Destroy 销毁软遮罩资源
 func (sm *SoftMask) Destroy() {
 	if sm.Surface != nil {
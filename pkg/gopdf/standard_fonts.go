@@ -0,0 +1,161 @@
+package gopdf
+
+import (
+	"strings"
+
+	pdffont "github.com/pdfcpu/pdfcpu/pkg/font"
+)
+
+// 标准 14 字体的字形宽度来自 pdfcpu 内置的 AFM 度量（按 WinAnsiEncoding 编码索引，
+// Symbol 和 ZapfDingbats 按各自的内置编码索引）
+
+// standardFontFamilies 字体族名（小写、去掉空格）到标准字体族的映射
+// 常见的替代名（Arial、Times New Roman、Courier New）使用度量兼容的标准字体
+var standardFontFamilies = map[string]string{
+	"helvetica":         "Helvetica",
+	"arial":             "Helvetica",
+	"arialmt":           "Helvetica",
+	"times":             "Times",
+	"timesroman":        "Times",
+	"timesnewroman":     "Times",
+	"timesnewromanps":   "Times",
+	"timesnewromanpsmt": "Times",
+	"courier":           "Courier",
+	"couriernew":        "Courier",
+	"couriernewps":      "Courier",
+	"couriernewpsmt":    "Courier",
+	"symbol":            "Symbol",
+	"symbolmt":          "Symbol",
+	"zapfdingbats":      "ZapfDingbats",
+}
+
+// standardFontName 把 BaseFont 规范化为标准 14 字体名
+// 支持子集前缀（ABCDEF+）、"-Bold"/",Bold" 等样式后缀和常见的替代字体名
+func standardFontName(baseFont string) (string, bool) {
+	name := strings.TrimPrefix(baseFont, "/")
+	if i := strings.IndexByte(name, '+'); i == 6 {
+		name = name[i+1:]
+	}
+
+	family, style := name, ""
+	if i := strings.IndexAny(name, ",-"); i >= 0 {
+		family, style = name[:i], strings.ToLower(name[i+1:])
+	}
+	family, ok := standardFontFamilies[strings.ToLower(strings.ReplaceAll(family, " ", ""))]
+	if !ok {
+		return "", false
+	}
+	if strings.Contains(style, "narrow") || strings.Contains(style, "condensed") {
+		// 窄体的宽度与标准字体不同
+		return "", false
+	}
+	if family == "Symbol" || family == "ZapfDingbats" {
+		return family, true
+	}
+
+	bold := strings.Contains(style, "bold")
+	italic := strings.Contains(style, "italic") || strings.Contains(style, "oblique")
+	switch {
+	case bold && italic && family == "Times":
+		return "Times-BoldItalic", true
+	case bold && italic:
+		return family + "-BoldOblique", true
+	case bold:
+		return family + "-Bold", true
+	case italic && family == "Times":
+		return "Times-Italic", true
+	case italic:
+		return family + "-Oblique", true
+	case family == "Times":
+		return "Times-Roman", true
+	}
+	return family, true
+}
+
+// standardEncodingToWinAnsi StandardEncoding 中与 WinAnsiEncoding 不同的编码
+// （值为 WinAnsiEncoding 中同一字形的编码；WinAnsiEncoding 中没有的字形不在表中）
+var standardEncodingToWinAnsi = map[int]int{
+	0x27: 0x92, // quoteright
+	0x60: 0x91, // quoteleft
+	0xA1: 0xA1, // exclamdown
+	0xA2: 0xA2, // cent
+	0xA3: 0xA3, // sterling
+	0xA5: 0xA5, // yen
+	0xA6: 0x83, // florin
+	0xA7: 0xA7, // section
+	0xA8: 0xA4, // currency
+	0xA9: 0x27, // quotesingle
+	0xAA: 0x93, // quotedblleft
+	0xAB: 0xAB, // guillemotleft
+	0xAC: 0x8B, // guilsinglleft
+	0xAD: 0x9B, // guilsinglright
+	0xB1: 0x96, // endash
+	0xB2: 0x86, // dagger
+	0xB3: 0x87, // daggerdbl
+	0xB4: 0xB7, // periodcentered
+	0xB6: 0xB6, // paragraph
+	0xB7: 0x95, // bullet
+	0xB8: 0x82, // quotesinglbase
+	0xB9: 0x84, // quotedblbase
+	0xBA: 0x94, // quotedblright
+	0xBB: 0xBB, // guillemotright
+	0xBC: 0x85, // ellipsis
+	0xBD: 0x89, // perthousand
+	0xBF: 0xBF, // questiondown
+	0xC1: 0x60, // grave
+	0xC2: 0xB4, // acute
+	0xC3: 0x88, // circumflex
+	0xC4: 0x98, // tilde
+	0xC5: 0xAF, // macron
+	0xC8: 0xA8, // dieresis
+	0xCB: 0xB8, // cedilla
+	0xD0: 0x97, // emdash
+	0xE1: 0xC6, // AE
+	0xE3: 0xAA, // ordfeminine
+	0xE9: 0xD8, // Oslash
+	0xEA: 0x8C, // OE
+	0xEB: 0xBA, // ordmasculine
+	0xF1: 0xE6, // ae
+	0xF9: 0xF8, // oslash
+	0xFA: 0x9C, // oe
+	0xFB: 0xDF, // germandbls
+}
+
+// standardFontWidths 返回标准 14 字体在给定编码下字符码 0-255 的宽度（千分之一 em）
+// 没有对应字形的字符码宽度为 0，由 GetWidth 回退到 MissingWidth
+func standardFontWidths(name, encoding string) *FontWidths {
+	if !pdffont.IsCoreFont(name) {
+		return nil
+	}
+
+	encoding = strings.TrimPrefix(encoding, "/")
+	symbolic := name == "Symbol" || name == "ZapfDingbats"
+
+	widths := &FontWidths{
+		FirstChar: 0,
+		LastChar:  255,
+		Widths:    make([]float64, 256),
+		CIDWidths: make(map[uint16]float64),
+	}
+	for code := 32; code < 256; code++ {
+		winAnsi := code
+		switch {
+		case symbolic || encoding == "WinAnsiEncoding":
+			// 符号字体使用内置编码；WinAnsiEncoding 直接对应
+		case encoding == "MacRomanEncoding":
+			// MacRomanEncoding 只有 ASCII 部分与 WinAnsiEncoding 相同
+			if code > 126 {
+				continue
+			}
+		default:
+			// StandardEncoding（没有 Encoding 时的默认编码）
+			if mapped, ok := standardEncodingToWinAnsi[code]; ok {
+				winAnsi = mapped
+			} else if code > 126 {
+				continue
+			}
+		}
+		widths.Widths[code] = float64(pdffont.CharWidth(name, rune(winAnsi)))
+	}
+	return widths
+}
@@ -2,11 +2,15 @@ package gopdf
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"image"
+	"image/jpeg"
 	"image/png"
+	"io"
 	"os"
 	"runtime" // Added for SetFinalizer
+	"strings"
 	"sync"
 	"sync/atomic"
 	"unsafe"
@@ -486,35 +490,110 @@ func (s *imageSurface) unpremultiplyAlphaRect(x, y, width, height int) {
 
 	stride := s.stride
 	for row := y; row < y+height; row++ {
-		argbOff := row*stride + x*4
-		rgbaOff := row*stride + x*4
-		argbPtr := s.data[argbOff:]
-		rgbaPtr := s.rgbaData[rgbaOff:]
-
-		for col := 0; col < width; col++ {
-			i := col * 4
-			a := argbPtr[i+0]
-			r := argbPtr[i+1]
-			g := argbPtr[i+2]
-			b := argbPtr[i+3]
-
-			// Convert from premultiplied to non-premultiplied alpha
-			if a == 0 {
-				rgbaPtr[i+0] = 0
-				rgbaPtr[i+1] = 0
-				rgbaPtr[i+2] = 0
-				rgbaPtr[i+3] = 0
-			} else if a == 255 {
-				rgbaPtr[i+0] = r
-				rgbaPtr[i+1] = g
-				rgbaPtr[i+2] = b
-				rgbaPtr[i+3] = a
-			} else {
-				// Unpremultiply: color = color * 255 / alpha
-				rgbaPtr[i+0] = uint8((uint32(r) * 255) / uint32(a))
-				rgbaPtr[i+1] = uint8((uint32(g) * 255) / uint32(a))
-				rgbaPtr[i+2] = uint8((uint32(b) * 255) / uint32(a))
-				rgbaPtr[i+3] = a
+		off := row*stride + x*4
+		unpremultiplyBGRA(s.rgbaData[off:off+width*4], s.data[off:off+width*4])
+	}
+}
+
+// unpremultiplyBGRA converts a row of premultiplied BGRA pixels (ARGB32 in
+// little-endian byte order) to non-premultiplied RGBA. Fully transparent
+// pixels become transparent black
+func unpremultiplyBGRA(dst, src []byte) {
+	for i := 0; i+3 < len(src) && i+3 < len(dst); i += 4 {
+		b, g, r, a := src[i], src[i+1], src[i+2], src[i+3]
+		switch a {
+		case 0:
+			r, g, b = 0, 0, 0
+		case 255:
+		default:
+			r = unpremultiplyChannel(r, a)
+			g = unpremultiplyChannel(g, a)
+			b = unpremultiplyChannel(b, a)
+		}
+		dst[i], dst[i+1], dst[i+2], dst[i+3] = r, g, b, a
+	}
+}
+
+// premultiplyRGBA converts a row of non-premultiplied RGBA pixels to
+// premultiplied BGRA, the inverse of unpremultiplyBGRA
+func premultiplyRGBA(dst, src []byte) {
+	for i := 0; i+3 < len(src) && i+3 < len(dst); i += 4 {
+		r, g, b, a := src[i], src[i+1], src[i+2], src[i+3]
+		if a < 255 {
+			r = premultiplyChannel(r, a)
+			g = premultiplyChannel(g, a)
+			b = premultiplyChannel(b, a)
+		}
+		dst[i], dst[i+1], dst[i+2], dst[i+3] = b, g, r, a
+	}
+}
+
+// unpremultiplyChannel 反预乘单个颜色分量（四舍五入）
+// 略有偏差的预乘数据可能出现 c > a，结果需要限制在 [0, 255]
+func unpremultiplyChannel(c, a uint8) uint8 {
+	v := (int(c)*255 + int(a)/2) / int(a)
+	if v > 255 {
+		v = 255
+	}
+	return uint8(v)
+}
+
+// premultiplyChannel 预乘单个颜色分量（四舍五入）
+func premultiplyChannel(c, a uint8) uint8 {
+	return uint8((int(c)*int(a) + 127) / 255)
+}
+
+// AsRGBA returns a copy of the surface pixels as non-premultiplied RGBA, the
+// layout MarkDirty keeps in the Go image. ARGB32 surfaces copy the Go image
+// (which the rasterizer draws into); RGB24 and A8 surfaces convert their data
+func (s *imageSurface) AsRGBA() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, s.width, s.height))
+	rowBytes := s.width * 4
+	for y := 0; y < s.height; y++ {
+		out := img.Pix[y*img.Stride : y*img.Stride+rowBytes]
+		row := s.data[y*s.stride:]
+		switch {
+		case s.rgbaImage != nil:
+			copy(out, s.rgbaData[y*s.stride:y*s.stride+rowBytes])
+		case s.format == FormatRGB24:
+			for x := 0; x < s.width; x++ {
+				out[x*4], out[x*4+1], out[x*4+2], out[x*4+3] = row[x*4+2], row[x*4+1], row[x*4], 255
+			}
+		case s.format == FormatA8:
+			for x := 0; x < s.width; x++ {
+				out[x*4+3] = row[x]
+			}
+		}
+	}
+	return img
+}
+
+// SetFromRGBA replaces the surface pixels with img, interpreted as
+// non-premultiplied RGBA like the result of AsRGBA. Both the BGRA data and
+// the Go image are updated, so no MarkDirty call is needed; colour of fully
+// transparent pixels is dropped. Pixels of img outside the surface are ignored
+func (s *imageSurface) SetFromRGBA(img *image.RGBA) {
+	if img == nil {
+		return
+	}
+	r := img.Rect.Intersect(image.Rect(0, 0, s.width, s.height))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		src := img.Pix[img.PixOffset(r.Min.X, y) : img.PixOffset(r.Min.X, y)+r.Dx()*4]
+		switch s.format {
+		case FormatARGB32:
+			off := y*s.stride + r.Min.X*4
+			premultiplyRGBA(s.data[off:off+len(src)], src)
+			// 从预乘数据反算，使 Go 图像与 MarkDirty 的结果一致
+			unpremultiplyBGRA(s.rgbaData[off:off+len(src)], s.data[off:off+len(src)])
+		case FormatRGB24:
+			dst := s.data[y*s.stride+r.Min.X*4:]
+			for i := 0; i < len(src); i += 4 {
+				dst[i], dst[i+1], dst[i+2], dst[i+3] = src[i+2], src[i+1], src[i], 255
+			}
+		case FormatA8:
+			dst := s.data[y*s.stride+r.Min.X:]
+			for i := 0; i < len(src); i += 4 {
+				dst[i/4] = src[i+3]
 			}
 		}
 	}
@@ -522,25 +601,63 @@ func (s *imageSurface) unpremultiplyAlphaRect(x, y, width, height int) {
 
 // WriteToPNG writes the surface to a PNG file
 func (s *imageSurface) WriteToPNG(filename string) Status {
+	return s.writeToFile(filename, s.WriteToPNGStream)
+}
+
+// WriteToJPEG writes the surface to a JPEG file with the given quality (1-100).
+// JPEG has no alpha channel, so transparent pixels are flattened over white.
+func (s *imageSurface) WriteToJPEG(filename string, quality int) Status {
+	return s.writeToFile(filename, func(w io.Writer) Status {
+		return s.WriteToJPEGStream(w, quality)
+	})
+}
+
+// writeToFile creates filename and hands it to one of the stream encoders
+func (s *imageSurface) writeToFile(filename string, encode func(w io.Writer) Status) Status {
 	if s.status != StatusSuccess {
 		return s.status
 	}
 
-	if s.goImage == nil {
-		return StatusSurfaceTypeMismatch
-	}
-
 	file, err := os.Create(filename)
 	if err != nil {
 		return StatusWriteError
 	}
-	defer file.Close()
 
-	err = png.Encode(file, s.goImage)
-	if err != nil {
+	status := encode(file)
+	if err := file.Close(); err != nil && status == StatusSuccess {
+		status = StatusWriteError
+	}
+	return status
+}
+
+// WriteToPNGStream encodes the surface as PNG into w, e.g. an HTTP response
+// or an upload stream, without going through a temporary file
+func (s *imageSurface) WriteToPNGStream(w io.Writer) Status {
+	if s.status != StatusSuccess {
+		return s.status
+	}
+
+	if err := png.Encode(w, s.AsRGBA()); err != nil {
 		return StatusWriteError
 	}
+	return StatusSuccess
+}
 
+// WriteToJPEGStream encodes the surface as JPEG into w with the given quality
+// (1-100, anything else uses DefaultJPEGQuality). Transparent pixels are
+// flattened over white.
+func (s *imageSurface) WriteToJPEGStream(w io.Writer, quality int) Status {
+	if s.status != StatusSuccess {
+		return s.status
+	}
+
+	if quality <= 0 || quality > 100 {
+		quality = DefaultJPEGQuality
+	}
+
+	if err := jpeg.Encode(w, flattenImageOnWhite(s.AsRGBA()), &jpeg.Options{Quality: quality}); err != nil {
+		return StatusWriteError
+	}
 	return StatusSuccess
 }
 
@@ -589,21 +706,36 @@ type ImageSurface interface {
 	GetStride() int
 	GetFormat() Format
 	GetGoImage() image.Image
+	AsRGBA() *image.RGBA
+	SetFromRGBA(img *image.RGBA)
 	WriteToPNG(filename string) Status
+	WriteToJPEG(filename string, quality int) Status
+	WriteToPNGStream(w io.Writer) Status
+	WriteToJPEGStream(w io.Writer, quality int) Status
 }
 
 // pdfSurface implements PDF output surface
+// 绘制操作被记录为内容流，Finish 时写出 PDF 文件
 type pdfSurface struct {
 	baseSurface
 	filename      string
 	width, height float64
+	content       bytes.Buffer       // 当前页面的内容流
+	pages         [][]byte           // 已完成页面的内容流
+	images        []pdfImageObject   // 所有页面共享的图像资源
+	alphaStates   map[float64]string // 透明度 -> ExtGState 资源名
+	shadings      []string           // 渐变填充的 Shading 字典，资源名为 Sh1、Sh2…
 }
 
 // svgSurface implements SVG output surface
+// 绘制操作被记录为 SVG 元素，Finish 时写出 SVG 文档
 type svgSurface struct {
 	baseSurface
 	filename      string
 	width, height float64
+	defs          strings.Builder // 渐变、裁剪路径定义
+	body          strings.Builder // 绘制元素
+	idCounter     int
 }
 
 // psSurface implements PostScript output surface (pure Go)
@@ -0,0 +1,317 @@
+package gopdf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"math"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// ===== SVG 输出表面 =====
+
+// recordDrawOp 将绘制操作转换为 SVG 元素
+// 每个元素包在带 CTM 的 <g transform> 中，路径、裁剪路径和渐变坐标都使用用户空间
+func (s *svgSurface) recordDrawOp(op *vectorDrawOp) {
+	if s.finished {
+		return
+	}
+
+	data := op.path
+	if op.kind == vectorOpPaint && len(data) == 0 {
+		data = pageRectPath(op.matrix, s.width, s.height)
+	}
+	if len(data) == 0 {
+		return
+	}
+
+	fmt.Fprintf(&s.body, "<g transform=\"%s\"", svgMatrix(&op.matrix))
+	if len(op.clip) > 0 {
+		fmt.Fprintf(&s.body, " clip-path=\"url(#%s)\"", s.addClipPath(op.clip, op.clipRule))
+	}
+	if op.alpha < 1 {
+		fmt.Fprintf(&s.body, " opacity=\"%s\"", formatNum(op.alpha))
+	}
+	s.body.WriteString(">\n")
+
+	// 表面图案（图像）：以路径为裁剪区域绘制图像
+	if sp, ok := op.source.(*surfacePattern); ok && op.kind != vectorOpStroke {
+		clipID := s.addClipPath(data, op.fillRule)
+		s.writeImage(sp, clipID)
+		s.body.WriteString("</g>\n")
+		return
+	}
+
+	var attrs strings.Builder
+	switch op.kind {
+	case vectorOpStroke:
+		attrs.WriteString(` fill="none"`)
+		s.writePaintAttrs(&attrs, "stroke", op.source)
+		fmt.Fprintf(&attrs, ` stroke-width="%s"`, formatNum(op.lineWidth))
+		if op.lineCap != LineCapButt {
+			fmt.Fprintf(&attrs, ` stroke-linecap="%s"`, svgLineCap(op.lineCap))
+		}
+		if op.lineJoin != LineJoinMiter {
+			fmt.Fprintf(&attrs, ` stroke-linejoin="%s"`, svgLineJoin(op.lineJoin))
+		} else {
+			fmt.Fprintf(&attrs, ` stroke-miterlimit="%s"`, formatNum(op.miterLimit))
+		}
+		if len(op.dash) > 0 {
+			dashes := make([]string, len(op.dash))
+			for i, d := range op.dash {
+				dashes[i] = formatNum(d)
+			}
+			fmt.Fprintf(&attrs, ` stroke-dasharray="%s"`, strings.Join(dashes, " "))
+			if op.dashOffset != 0 {
+				fmt.Fprintf(&attrs, ` stroke-dashoffset="%s"`, formatNum(op.dashOffset))
+			}
+		}
+	case vectorOpFill, vectorOpPaint:
+		s.writePaintAttrs(&attrs, "fill", op.source)
+		if op.fillRule == FillRuleEvenOdd {
+			attrs.WriteString(` fill-rule="evenodd"`)
+		}
+	}
+
+	if x, y, w, h, ok := pathAsRect(data); ok {
+		fmt.Fprintf(&s.body, "<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\"%s/>\n",
+			formatNum(x), formatNum(y), formatNum(w), formatNum(h), attrs.String())
+	} else {
+		fmt.Fprintf(&s.body, "<path d=\"%s\"%s/>\n", svgPathData(data), attrs.String())
+	}
+	s.body.WriteString("</g>\n")
+}
+
+// writePaintAttrs 输出填充或描边的颜色属性：纯色直接使用，渐变引用 defs 中的渐变定义
+func (s *svgSurface) writePaintAttrs(attrs *strings.Builder, prop string, source Pattern) {
+	if id := s.addGradient(source); id != "" {
+		fmt.Fprintf(attrs, ` %s="url(#%s)"`, prop, id)
+		return
+	}
+
+	r, g, b, a := vectorSourceColor(source)
+	fmt.Fprintf(attrs, ` %s="%s"`, prop, svgColor(r, g, b))
+	if a < 1 {
+		fmt.Fprintf(attrs, ` %s-opacity="%s"`, prop, formatNum(a))
+	}
+}
+
+// addGradient 为线性/径向渐变图案添加 defs 定义并返回 id；其它图案返回空字符串
+// 渐变坐标位于图案空间，图案矩阵的逆矩阵作为 gradientTransform 把它放回用户空间
+func (s *svgSurface) addGradient(source Pattern) string {
+	var grad *gradientPattern
+	var open string
+	switch p := source.(type) {
+	case *linearGradient:
+		grad = &p.gradientPattern
+		open = fmt.Sprintf(`<linearGradient id="%%s" gradientUnits="userSpaceOnUse" x1="%s" y1="%s" x2="%s" y2="%s"`,
+			formatNum(p.x0), formatNum(p.y0), formatNum(p.x1), formatNum(p.y1))
+	case *radialGradient:
+		grad = &p.gradientPattern
+		// SVG 的焦点圆半径 fr 对应起始圆
+		open = fmt.Sprintf(`<radialGradient id="%%s" gradientUnits="userSpaceOnUse" cx="%s" cy="%s" r="%s" fx="%s" fy="%s" fr="%s"`,
+			formatNum(p.cx1), formatNum(p.cy1), formatNum(p.radius1),
+			formatNum(p.cx0), formatNum(p.cy0), formatNum(p.radius0))
+	default:
+		return ""
+	}
+	if len(grad.stops) == 0 {
+		return ""
+	}
+
+	id := s.nextID("grad")
+	fmt.Fprintf(&s.defs, open, id)
+
+	inv := *grad.GetMatrix()
+	if MatrixInvert(&inv) == StatusSuccess && inv != (Matrix{XX: 1, YY: 1}) {
+		fmt.Fprintf(&s.defs, ` gradientTransform="%s"`, svgMatrix(&inv))
+	}
+	switch grad.GetExtend() {
+	case ExtendRepeat:
+		s.defs.WriteString(` spreadMethod="repeat"`)
+	case ExtendReflect:
+		s.defs.WriteString(` spreadMethod="reflect"`)
+	}
+	s.defs.WriteString(">\n")
+
+	for _, stop := range grad.stops {
+		fmt.Fprintf(&s.defs, `<stop offset="%s" stop-color="%s"`, formatNum(stop.offset), svgColor(stop.red, stop.green, stop.blue))
+		if stop.alpha < 1 {
+			fmt.Fprintf(&s.defs, ` stop-opacity="%s"`, formatNum(stop.alpha))
+		}
+		s.defs.WriteString("/>\n")
+	}
+
+	if _, ok := source.(*linearGradient); ok {
+		s.defs.WriteString("</linearGradient>\n")
+	} else {
+		s.defs.WriteString("</radialGradient>\n")
+	}
+	return id
+}
+
+// addClipPath 添加裁剪路径定义并返回 id（坐标为引用元素的用户空间）
+func (s *svgSurface) addClipPath(data []pathOp, fillRule FillRule) string {
+	id := s.nextID("clip")
+	fmt.Fprintf(&s.defs, "<clipPath id=\"%s\"><path d=\"%s\"", id, svgPathData(data))
+	if fillRule == FillRuleEvenOdd {
+		s.defs.WriteString(` clip-rule="evenodd"`)
+	}
+	s.defs.WriteString("/></clipPath>\n")
+	return id
+}
+
+// writeImage 以 PNG data URI 嵌入表面图案的图像
+// 图案矩阵的逆矩阵把图像所在的 [0,w]x[0,h] 放回用户空间
+func (s *svgSurface) writeImage(sp *surfacePattern, clipID string) {
+	imgSurf, ok := sp.surface.(ImageSurface)
+	if !ok {
+		debugPrintln("[SVGSurface] Surface pattern source is not an image surface, skipped")
+		return
+	}
+	img := ConvertGopdfSurfaceToImage(imgSurf)
+	if img == nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		debugPrintf("[SVGSurface] Failed to encode image: %v\n", err)
+		return
+	}
+
+	inv := *sp.GetMatrix()
+	if MatrixInvert(&inv) != StatusSuccess {
+		inv.InitIdentity()
+	}
+	bounds := img.Bounds()
+	fmt.Fprintf(&s.body, "<g clip-path=\"url(#%s)\"><image transform=\"%s\" width=\"%d\" height=\"%d\" xlink:href=\"data:image/png;base64,%s\"/></g>\n",
+		clipID, svgMatrix(&inv), bounds.Dx(), bounds.Dy(), base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+// nextID 生成 defs 元素的唯一 id
+func (s *svgSurface) nextID(prefix string) string {
+	s.idCounter++
+	return fmt.Sprintf("%s%d", prefix, s.idCounter)
+}
+
+// pathAsRect 判断路径是否为轴对齐矩形（Rectangle 生成的 M L L L Z），是则返回 x, y, 宽, 高
+func pathAsRect(data []pathOp) (x, y, w, h float64, ok bool) {
+	if len(data) != 5 || data[0].op != PathMoveTo || data[4].op != PathClosePath {
+		return 0, 0, 0, 0, false
+	}
+	pts := make([]point, 4)
+	for i := 0; i < 4; i++ {
+		if i > 0 && data[i].op != PathLineTo {
+			return 0, 0, 0, 0, false
+		}
+		pts[i] = data[i].points[0]
+	}
+
+	// 相邻的点交替共享 x 或 y
+	horizontalFirst := pts[0].y == pts[1].y && pts[1].x == pts[2].x && pts[2].y == pts[3].y && pts[3].x == pts[0].x
+	verticalFirst := pts[0].x == pts[1].x && pts[1].y == pts[2].y && pts[2].x == pts[3].x && pts[3].y == pts[0].y
+	if !horizontalFirst && !verticalFirst {
+		return 0, 0, 0, 0, false
+	}
+
+	x, y = math.Min(pts[0].x, pts[2].x), math.Min(pts[0].y, pts[2].y)
+	w, h = math.Abs(pts[2].x-pts[0].x), math.Abs(pts[2].y-pts[0].y)
+	// 退化矩形在 SVG 中不会被描边，保留为路径
+	if w == 0 || h == 0 {
+		return 0, 0, 0, 0, false
+	}
+	return x, y, w, h, true
+}
+
+// svgPathData 将路径转换为 SVG path 的 d 属性
+func svgPathData(data []pathOp) string {
+	parts := make([]string, 0, len(data))
+	for _, op := range data {
+		switch op.op {
+		case PathMoveTo:
+			parts = append(parts, fmt.Sprintf("M%s %s", formatNum(op.points[0].x), formatNum(op.points[0].y)))
+		case PathLineTo:
+			parts = append(parts, fmt.Sprintf("L%s %s", formatNum(op.points[0].x), formatNum(op.points[0].y)))
+		case PathCurveTo:
+			parts = append(parts, fmt.Sprintf("C%s %s %s %s %s %s",
+				formatNum(op.points[0].x), formatNum(op.points[0].y),
+				formatNum(op.points[1].x), formatNum(op.points[1].y),
+				formatNum(op.points[2].x), formatNum(op.points[2].y)))
+		case PathClosePath:
+			parts = append(parts, "Z")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// svgMatrix 格式化 transform 属性的 matrix(a b c d e f)
+func svgMatrix(m *Matrix) string {
+	return fmt.Sprintf("matrix(%s %s %s %s %s %s)",
+		formatNum(m.XX), formatNum(m.YX), formatNum(m.XY), formatNum(m.YY), formatNum(m.X0), formatNum(m.Y0))
+}
+
+// svgColor 将 0-1 的 RGB 分量格式化为 #rrggbb
+func svgColor(r, g, b float64) string {
+	toByte := func(v float64) int {
+		return int(math.Round(math.Max(0, math.Min(1, v)) * 255))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", toByte(r), toByte(g), toByte(b))
+}
+
+func svgLineCap(lineCap LineCap) string {
+	switch lineCap {
+	case LineCapRound:
+		return "round"
+	case LineCapSquare:
+		return "square"
+	}
+	return "butt"
+}
+
+func svgLineJoin(lineJoin LineJoin) string {
+	switch lineJoin {
+	case LineJoinRound:
+		return "round"
+	case LineJoinBevel:
+		return "bevel"
+	}
+	return "miter"
+}
+
+func (s *svgSurface) Destroy() {
+	if atomic.AddInt32(&s.refCount, -1) == 0 {
+		s.Finish()
+		s.cleanup()
+	}
+}
+
+// Finish 将记录的绘制内容写入 SVG 文件；重复调用不会再次写入
+// SVG 只有一页，ShowPage 不会分页，所有内容都输出到同一个文档
+func (s *svgSurface) Finish() error {
+	if s.finished {
+		return nil
+	}
+	s.finished = true
+
+	var doc bytes.Buffer
+	doc.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&doc, "<svg xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\" version=\"1.1\" width=\"%spt\" height=\"%spt\" viewBox=\"0 0 %s %s\">\n",
+		formatNum(s.width), formatNum(s.height), formatNum(s.width), formatNum(s.height))
+	if s.defs.Len() > 0 {
+		doc.WriteString("<defs>\n")
+		doc.WriteString(s.defs.String())
+		doc.WriteString("</defs>\n")
+	}
+	doc.WriteString(s.body.String())
+	doc.WriteString("</svg>\n")
+
+	if err := os.WriteFile(s.filename, doc.Bytes(), 0644); err != nil {
+		s.status = StatusWriteError
+		return fmt.Errorf("failed to write SVG surface %s: %w", s.filename, err)
+	}
+	return nil
+}
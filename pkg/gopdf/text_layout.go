@@ -0,0 +1,281 @@
+package gopdf
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ===== 文本版面分析（多栏、旋转文本）=====
+
+// TextBlock 版面分析得到的文本块（一栏中的一个段落，或一段旋转文本）
+type TextBlock struct {
+	Angle    float64           // 块的阅读方向（基线旋转角度，度）
+	Lines    []string          // 按阅读顺序排列的文本行
+	Elements []TextElementInfo // 块内的文本元素（按阅读顺序）
+}
+
+// Text 返回块的文本，行之间以换行分隔
+func (b *TextBlock) Text() string {
+	return strings.Join(b.Lines, "\n")
+}
+
+// layoutItem 阅读坐标系中的文本元素：u 沿基线方向，v 垂直于基线向上
+type layoutItem struct {
+	elem   TextElementInfo
+	u0, u1 float64
+	v      float64
+}
+
+// layoutLine 阅读坐标系中的一行文本
+type layoutLine struct {
+	items []layoutItem
+	v     float64
+	size  float64
+}
+
+// ExtractTextBlocks 提取页面文本并进行版面分析，返回按阅读顺序排列的文本块
+func (r *PDFReader) ExtractTextBlocks(pageNum int) ([]TextBlock, error) {
+	pageCount, err := r.GetPageCount()
+	if err != nil {
+		return nil, err
+	}
+	if pageNum < 1 || pageNum > pageCount {
+		return nil, fmt.Errorf("page %d out of range (1-%d)", pageNum, pageCount)
+	}
+
+	textElements, _ := r.ExtractPageElements(pageNum)
+	return AnalyzeTextLayout(textElements), nil
+}
+
+// ExtractOrderedText 按版面阅读顺序提取页面文本：先按栏、再按栏内的行，
+// 旋转的文本段（如侧边栏、图注）保持为独立的块；块之间以空行分隔
+// 只需按位置从上到下、从左到右排列而不切分栏时使用 ExtractPageTextOrdered
+func (r *PDFReader) ExtractOrderedText(pageNum int) (string, error) {
+	blocks, err := r.ExtractTextBlocks(pageNum)
+	if err != nil {
+		return "", err
+	}
+
+	texts := make([]string, 0, len(blocks))
+	for i := range blocks {
+		texts = append(texts, blocks[i].Text())
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// AnalyzeTextLayout 对文本元素进行版面分析
+// 1. 按基线方向分组，旋转文本与水平文本分开处理
+// 2. 在每组的阅读坐标系中按基线方向上的空白间隙切分栏
+// 3. 栏内按行聚类，行间距明显增大处切分为不同的块
+// 块的顺序：元素最多的方向（正文）优先，其余方向按角度排序；同一方向内栏从左到右、块从上到下
+func AnalyzeTextLayout(elements []TextElementInfo) []TextBlock {
+	groups := make(map[int][]TextElementInfo)
+	for _, e := range elements {
+		if strings.TrimSpace(e.Text) == "" {
+			continue
+		}
+		key := int(math.Round(e.Angle))
+		// 接近水平的文本视为水平
+		if key >= -2 && key <= 2 {
+			key = 0
+		}
+		groups[key] = append(groups[key], e)
+	}
+
+	angles := make([]int, 0, len(groups))
+	for angle := range groups {
+		angles = append(angles, angle)
+	}
+	sort.Slice(angles, func(i, j int) bool {
+		ni, nj := len(groups[angles[i]]), len(groups[angles[j]])
+		if ni != nj {
+			return ni > nj
+		}
+		return angles[i] < angles[j]
+	})
+
+	var blocks []TextBlock
+	for _, angle := range angles {
+		blocks = append(blocks, layoutDirectionGroup(groups[angle], float64(angle))...)
+	}
+	return blocks
+}
+
+// ExtractPageTextOrdered 按位置顺序提取页面文本，适合全文索引
+// 不切分栏：所有元素按基线位置聚类成行，行从上到下、行内从左到右，
+// 相邻元素间距较大时插入空格，行间距明显大于字号处以空行分隔；
+// 多栏页面中同一高度的各栏文本会合并到一行，需要按栏输出时使用 ExtractOrderedText
+func (r *PDFReader) ExtractPageTextOrdered(pageNum int) (string, error) {
+	pageCount, err := r.GetPageCount()
+	if err != nil {
+		return "", err
+	}
+	if pageNum < 1 || pageNum > pageCount {
+		return "", fmt.Errorf("page %d out of range (1-%d)", pageNum, pageCount)
+	}
+
+	textElements, _ := r.ExtractPageElements(pageNum)
+	elements := make([]TextElementInfo, 0, len(textElements))
+	for _, e := range textElements {
+		if strings.TrimSpace(e.Text) != "" {
+			elements = append(elements, e)
+		}
+	}
+
+	lines := groupLines(newLayoutItems(elements, 0))
+	texts := make([]string, 0, len(lines))
+	for _, blockLines := range splitBlocks(lines) {
+		block := buildTextBlock(blockLines, 0)
+		texts = append(texts, block.Text())
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// layoutDirectionGroup 对同一阅读方向的文本元素切分栏和块
+func layoutDirectionGroup(elements []TextElementInfo, angle float64) []TextBlock {
+	items := newLayoutItems(elements, angle)
+	sizes := make([]float64, 0, len(elements))
+	for _, e := range elements {
+		sizes = append(sizes, textElementSize(e))
+	}
+
+	sort.Float64s(sizes)
+	medianSize := sizes[len(sizes)/2]
+
+	var blocks []TextBlock
+	for _, column := range splitColumns(items, medianSize) {
+		for _, lines := range splitBlocks(groupLines(column)) {
+			blocks = append(blocks, buildTextBlock(lines, angle))
+		}
+	}
+	return blocks
+}
+
+// newLayoutItems 将文本元素转换到按 angle 旋转的阅读坐标系
+func newLayoutItems(elements []TextElementInfo, angle float64) []layoutItem {
+	rad := angle * math.Pi / 180
+	cos, sin := math.Cos(rad), math.Sin(rad)
+
+	items := make([]layoutItem, 0, len(elements))
+	for _, e := range elements {
+		// 元素坐标为屏幕坐标（Y 向下），先转换回 Y 向上再旋转到阅读坐标系
+		px, py := e.X, -e.Y
+		u := px*cos + py*sin
+		v := -px*sin + py*cos
+		items = append(items, layoutItem{elem: e, u0: u, u1: u + textElementWidth(e), v: v})
+	}
+	return items
+}
+
+// splitColumns 按基线方向上的投影空白切分栏：间隙不小于 minGap 的位置即为栏间距
+func splitColumns(items []layoutItem, minGap float64) [][]layoutItem {
+	sort.Slice(items, func(i, j int) bool { return items[i].u0 < items[j].u0 })
+
+	var columns [][]layoutItem
+	var current []layoutItem
+	end := math.Inf(-1)
+	for _, item := range items {
+		if len(current) > 0 && item.u0-end >= minGap {
+			columns = append(columns, current)
+			current = nil
+		}
+		current = append(current, item)
+		end = max(end, item.u1)
+	}
+	if len(current) > 0 {
+		columns = append(columns, current)
+	}
+	return columns
+}
+
+// groupLines 将一栏中的元素按基线位置聚类成行，行从上到下排列，行内从左到右
+func groupLines(items []layoutItem) []layoutLine {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].v != items[j].v {
+			return items[i].v > items[j].v
+		}
+		return items[i].u0 < items[j].u0
+	})
+
+	var lines []layoutLine
+	for _, item := range items {
+		size := textElementSize(item.elem)
+		if n := len(lines); n > 0 && math.Abs(lines[n-1].v-item.v) <= max(lines[n-1].size, size)*0.5 {
+			lines[n-1].items = append(lines[n-1].items, item)
+			lines[n-1].size = max(lines[n-1].size, size)
+			continue
+		}
+		lines = append(lines, layoutLine{items: []layoutItem{item}, v: item.v, size: size})
+	}
+
+	for i := range lines {
+		sort.Slice(lines[i].items, func(a, b int) bool { return lines[i].items[a].u0 < lines[i].items[b].u0 })
+	}
+	return lines
+}
+
+// splitBlocks 在行间距明显大于字号处切分块
+func splitBlocks(lines []layoutLine) [][]layoutLine {
+	var blocks [][]layoutLine
+	for i, line := range lines {
+		if i == 0 || lines[i-1].v-line.v > max(lines[i-1].size, line.size)*2 {
+			blocks = append(blocks, nil)
+		}
+		blocks[len(blocks)-1] = append(blocks[len(blocks)-1], line)
+	}
+	return blocks
+}
+
+// buildTextBlock 拼接行文本：同一行内相邻元素间距较大时插入空格
+func buildTextBlock(lines []layoutLine, angle float64) TextBlock {
+	block := TextBlock{Angle: angle}
+	for _, line := range lines {
+		var sb strings.Builder
+		for i, item := range line.items {
+			if i > 0 {
+				prev := line.items[i-1]
+				gap := item.u0 - prev.u1
+				if gap > textElementSize(item.elem)*0.2 &&
+					!strings.HasSuffix(prev.elem.Text, " ") && !strings.HasPrefix(item.elem.Text, " ") {
+					sb.WriteString(" ")
+				}
+			}
+			sb.WriteString(item.elem.Text)
+			block.Elements = append(block.Elements, item.elem)
+		}
+		block.Lines = append(block.Lines, strings.TrimSpace(sb.String()))
+	}
+	return block
+}
+
+// textElementSize 元素字号（缺失时按 12 处理）
+func textElementSize(e TextElementInfo) float64 {
+	if e.FontSize > 0 {
+		return e.FontSize
+	}
+	return 12
+}
+
+// textElementWidth 元素宽度（缺失时按每字符半个字号估算）
+func textElementWidth(e TextElementInfo) float64 {
+	if e.Width > 0 {
+		return e.Width
+	}
+	return float64(len([]rune(e.Text))) * textElementSize(e) * 0.5
+}
+
+// baselineDirection 文本矩阵基线方向的单位向量
+func baselineDirection(m *Matrix) (float64, float64) {
+	length := math.Hypot(m.XX, m.YX)
+	if length == 0 {
+		return 1, 0
+	}
+	return m.XX / length, m.YX / length
+}
+
+// baselineAngle 文本矩阵基线相对水平方向的旋转角度（度，逆时针为正）
+func baselineAngle(m *Matrix) float64 {
+	return math.Atan2(m.YX, m.XX) * 180 / math.Pi
+}
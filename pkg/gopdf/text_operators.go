@@ -2,7 +2,9 @@ package gopdf
 
 import (
 	"fmt"
+	"math"
 	"strings"
+	"unicode/utf8"
 )
 
 // TextState 文本状态
@@ -72,6 +74,54 @@ type Font struct {
 	Widths           *FontWidths      // 字形宽度信息
 	DefaultWidth     float64          // 默认字形宽度（用于 CID 字体）
 	MissingWidth     float64          // 缺失字形的宽度
+
+	// FontDescriptor 信息
+	IsEmbedded  bool    // 是否嵌入了字体程序（FontFile、FontFile2 或 FontFile3）
+	IsSubset    bool    // BaseFont 是否带有子集前缀（如 ABCDEF+）
+	Flags       int     // 字体标志，见 FontFlagSerif 等常量
+	ItalicAngle float64 // 斜体角度（度，逆时针为正）
+	StemV       float64 // 竖直主干宽度
+
+	// 竖排（WMode 1，如 Identity-V）
+	Vertical        bool                      // 是否竖排书写
+	DefaultVertical [2]float64                // DW2：[竖排原点 vy, 竖排推进 w1]（千分之一 em）
+	VerticalMetrics map[uint16]VerticalMetric // W2：逐 CID 的竖排度量
+}
+
+// FontDescriptor /Flags 中的标志位（PDF 规范表 121，位号从 1 开始）
+const (
+	FontFlagFixedPitch  = 1 << 0  // 等宽
+	FontFlagSerif       = 1 << 1  // 衬线
+	FontFlagSymbolic    = 1 << 2  // 含标准拉丁字符集以外的字形
+	FontFlagScript      = 1 << 3  // 手写体
+	FontFlagNonsymbolic = 1 << 5  // 只使用标准拉丁字符集
+	FontFlagItalic      = 1 << 6  // 斜体
+	FontFlagAllCap      = 1 << 16 // 只有大写字母
+	FontFlagSmallCap    = 1 << 17 // 小型大写字母
+	FontFlagForceBold   = 1 << 18 // 小字号时强制加粗
+)
+
+// VerticalMetric 竖排字形度量（千分之一 em）
+type VerticalMetric struct {
+	W1 float64 // 竖排推进量（通常为负，表示向下）
+	VX float64 // 位置向量 v 的 X 分量：水平原点到竖排原点的偏移
+	VY float64 // 位置向量 v 的 Y 分量
+}
+
+// defaultVerticalMetrics PDF 规范中 DW2 的默认值 [880 -1000]
+var defaultVerticalMetrics = [2]float64{880, -1000}
+
+// GetVerticalMetric 获取字形的竖排度量
+// W2 中没有的 CID 使用 DW2，位置向量的 X 分量取水平宽度的一半
+func (f *Font) GetVerticalMetric(cid uint16) VerticalMetric {
+	if m, ok := f.VerticalMetrics[cid]; ok {
+		return m
+	}
+	dw2 := f.DefaultVertical
+	if dw2 == [2]float64{} {
+		dw2 = defaultVerticalMetrics
+	}
+	return VerticalMetric{W1: dw2[1], VX: f.GetWidth(cid) / 2, VY: dw2[0]}
 }
 
 // FontWidths 字形宽度信息
@@ -202,6 +252,8 @@ func (op *OpBeginText) Execute(ctx *RenderContext) error {
 	// 重置文本矩阵和文本行矩阵为单位矩阵
 	ctx.TextState.TextMatrix = NewIdentityMatrix()
 	ctx.TextState.TextLineMatrix = NewIdentityMatrix()
+	ctx.TextClipPath = nil
+	ctx.inTextObject = true
 	debugPrintf("[BT] Begin text object - Reset text matrices\n")
 	return nil
 }
@@ -212,7 +264,23 @@ type OpEndText struct{}
 func (op *OpEndText) Name() string { return "ET" }
 
 func (op *OpEndText) Execute(ctx *RenderContext) error {
-	// 文本对象结束，不需要特殊处理
+	if !ctx.inTextObject {
+		if ctx.Strict {
+			return fmt.Errorf("%w: ET without matching BT", ErrUnbalancedContent)
+		}
+		debugPrintf("[ET] Warning: ET without matching BT\n")
+	}
+	ctx.inTextObject = false
+
+	// 渲染模式 4-7 累积的字形轮廓在文本对象结束时加入裁剪路径
+	clipPath := ctx.TextClipPath
+	ctx.TextClipPath = nil
+	if clipPath != nil && !clipPath.IsEmpty() {
+		debugPrintf("[ET] End text object - Clip to text outlines\n")
+		filler := NewPathFiller(ctx.GopdfCtx)
+		filler.SetFillRule(FillRuleWinding)
+		return filler.ClipPath(clipPath)
+	}
 	debugPrintf("[ET] End text object\n")
 	return nil
 }
@@ -246,9 +314,10 @@ type OpMoveTextPosition struct {
 func (op *OpMoveTextPosition) Name() string { return "Td" }
 
 func (op *OpMoveTextPosition) Execute(ctx *RenderContext) error {
-	// 根据PDF规范：Tlm = Tlm × [1 0 0 1 tx ty]，然后 Tm = Tlm
+	// 根据PDF规范：Tlm = [1 0 0 1 tx ty] × Tlm，然后 Tm = Tlm
+	// （偏移量在文本空间中，随文本矩阵缩放和旋转）
 	translation := NewTranslationMatrix(op.Tx, op.Ty)
-	ctx.TextState.TextLineMatrix = ctx.TextState.TextLineMatrix.Multiply(translation)
+	ctx.TextState.TextLineMatrix = translation.Multiply(ctx.TextState.TextLineMatrix)
 	ctx.TextState.TextMatrix = ctx.TextState.TextLineMatrix.Clone()
 
 	debugPrintf("[Td] Move text position: tx=%.2f, ty=%.2f -> New Tm: [%.2f %.2f %.2f %.2f %.2f %.2f]\n",
@@ -279,18 +348,10 @@ func (op *OpMoveToNextLine) Name() string { return "T*" }
 
 func (op *OpMoveToNextLine) Execute(ctx *RenderContext) error {
 	// 🔥 关键修复：T* 必须重置 X 坐标到行首
-	// 根据 PDF 规范：Tlm = Tlm × [1 0 0 1 0 -Tl]，然后 Tm = Tlm
-	// 这意味着只移动 Y，X 保持为 TextLineMatrix 的初始 X
-
-	// 保存当前行的起始 X 坐标
-	lineStartX := ctx.TextState.TextLineMatrix.X0
-
-	// 移动 Y 坐标
-	ctx.TextState.TextLineMatrix = ctx.TextState.TextLineMatrix.Translate(0, -ctx.TextState.Leading)
-
-	// 🔥 修复：确保 X 坐标重置到行首
-	// 如果 TextLineMatrix 的 X 被之前的文本操作修改了，需要重置
-	ctx.TextState.TextLineMatrix.X0 = lineStartX
+	// 根据 PDF 规范：Tlm = [1 0 0 1 0 -Tl] × Tlm，然后 Tm = Tlm
+	// 文本行矩阵不受文本显示操作影响，Tm = Tlm 即回到行首
+	translation := NewTranslationMatrix(0, -ctx.TextState.Leading)
+	ctx.TextState.TextLineMatrix = translation.Multiply(ctx.TextState.TextLineMatrix)
 
 	// 重置 TextMatrix 为 TextLineMatrix
 	ctx.TextState.TextMatrix = ctx.TextState.TextLineMatrix.Clone()
@@ -423,17 +484,19 @@ func (op *OpSetTextRise) Execute(ctx *RenderContext) error {
 // OpShowText Tj - 显示文本
 type OpShowText struct {
 	Text string
+	Hex  bool // Text 是十六进制字符串 <...>
 }
 
 func (op *OpShowText) Name() string { return "Tj" }
 
 func (op *OpShowText) Execute(ctx *RenderContext) error {
-	return renderText(ctx, op.Text, nil)
+	return renderText(ctx, op.Text, op.Hex, nil)
 }
 
 // OpShowTextNextLine ' - 移到下一行并显示文本
 type OpShowTextNextLine struct {
 	Text string
+	Hex  bool // Text 是十六进制字符串 <...>
 }
 
 func (op *OpShowTextNextLine) Name() string { return "'" }
@@ -446,7 +509,7 @@ func (op *OpShowTextNextLine) Execute(ctx *RenderContext) error {
 	}
 	// 然后显示文本（会自动更新TextMatrix）
 	debugPrintf("['] Moving to next line and showing text\n")
-	return (&OpShowText{Text: op.Text}).Execute(ctx)
+	return (&OpShowText{Text: op.Text, Hex: op.Hex}).Execute(ctx)
 }
 
 // OpShowTextWithSpacing " - 设置间距并显示文本
@@ -454,6 +517,7 @@ type OpShowTextWithSpacing struct {
 	WordSpacing float64
 	CharSpacing float64
 	Text        string
+	Hex         bool // Text 是十六进制字符串 <...>
 }
 
 func (op *OpShowTextWithSpacing) Name() string { return "\"" }
@@ -465,31 +529,75 @@ func (op *OpShowTextWithSpacing) Execute(ctx *RenderContext) error {
 	ctx.TextState.WordSpacing = op.WordSpacing
 	ctx.TextState.CharSpacing = op.CharSpacing
 	// 然后移动到下一行并显示文本
-	return (&OpShowTextNextLine{Text: op.Text}).Execute(ctx)
+	return (&OpShowTextNextLine{Text: op.Text, Hex: op.Hex}).Execute(ctx)
 }
 
 // OpShowTextArray TJ - 显示文本数组（带位置调整）
 type OpShowTextArray struct {
-	Array []any // string 或 float64
+	Array []any // string（字面量）、HexString 或 float64
 }
 
 func (op *OpShowTextArray) Name() string { return "TJ" }
 
 func (op *OpShowTextArray) Execute(ctx *RenderContext) error {
-	return renderText(ctx, "", op.Array)
+	return renderText(ctx, "", false, op.Array)
+}
+
+// showTextItem 把 Tj、'、" 的文本转换为与 TJ 数组元素相同的形式
+func showTextItem(text string, hex bool) any {
+	if hex {
+		return HexString(text)
+	}
+	return text
+}
+
+// textItem 取 TJ 数组元素中的字符串及其是否为十六进制字符串，数字元素返回 ok=false
+func textItem(v any) (text string, hex bool, ok bool) {
+	switch s := v.(type) {
+	case HexString:
+		return string(s), true, true
+	case string:
+		return s, false, true
+	}
+	return "", false, false
+}
+
+// hexStringDigits 去掉十六进制字符串的尖括号和空白
+func hexStringDigits(text string) string {
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "<"), ">")
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\r', '\n', '\f':
+			return -1
+		}
+		return r
+	}, text)
 }
 
 // GlyphWithPosition 带位置的字形
 type GlyphWithPosition struct {
 	CID        uint16
-	Rune       rune
+	Rune       rune   // Text 的第一个字符
+	Text       string // 字形对应的 Unicode 文本，连字可能包含多个字符
 	X, Y       float64
 	FontFamily string  // 字体族名
 	FontSize   float64 // 字体大小
 }
 
+// textGlyphMatrix 返回把字形轮廓变换到用户空间的矩阵
+// 字形轮廓是 Y 轴向下的字体空间形状，只取文本矩阵的线性部分（翻转 Y 轴），
+// 使字形的方向、缩放和旋转与文本矩阵一致；平移已包含在字形的绝对坐标中，不能重复应用
+func textGlyphMatrix(tm *Matrix) *Matrix {
+	return &Matrix{XX: tm.XX, YX: tm.YX, XY: -tm.XY, YY: -tm.YY}
+}
+
 // renderText 渲染文本到 Gopdf
-func renderText(ctx *RenderContext, text string, array []any) error {
+// hex 表示 text 是十六进制字符串；TJ 数组的元素自带类型（string 或 HexString）
+func renderText(ctx *RenderContext, text string, hex bool, array []any) error {
+	// 文本被过滤或不可见（Tr 3）时不绘制，但仍需推进文本矩阵，
+	// 否则同一 BT…ET 块中后续文本的位置会错乱
+	visible := ctx.shouldRender(ContentText)
+
 	state := ctx.GetCurrentState()
 	textState := ctx.TextState
 
@@ -501,26 +609,20 @@ func renderText(ctx *RenderContext, text string, array []any) error {
 	ctx.GopdfCtx.Save()
 	defer ctx.GopdfCtx.Restore()
 
-	// 🔥 关键修复：不应用文本矩阵到Gopdf上下文
-	// 因为我们会计算绝对坐标并直接使用 MoveTo 定位
-	// 这样避免双重变换（文本矩阵变换 + Gopdf变换）
-
-	// 注意：文本上升仍然需要应用，因为它是相对于文本基线的偏移
-	// 但由于我们使用绝对坐标，上升也应该在计算坐标时处理
-	// 暂时保留这里的实现以保持兼容性
-	if textState.Rise != 0 {
-		// 上升应该在Y方向应用，但由于我们使用绝对坐标
-		// 这个变换可能不需要，取决于具体实现
-		// ctx.GopdfCtx.Translate(0, textState.Rise)
-	}
+	// 🔥 关键修复：字形位置由文本矩阵计算出绝对坐标，
+	// 绘制每个字形时只应用文本矩阵的线性部分，避免平移被重复应用
+
+	// 注意：文本上升（Ts）是相对于基线的文本空间 Y 偏移，
+	// 在下面计算字形绝对坐标时（glyphOrigin）加入，再经过文本矩阵变换
 
 	// 设置字体
-	// 🔥 关键：字体大小直接使用 FontSize，不从文本矩阵提取
-	// 因为文本矩阵的缩放已经在计算绝对坐标时应用了
+	// 🔥 关键：字体大小直接使用 FontSize，文本矩阵的缩放在绘制字形时应用
+	// （常见的 "/F1 1 Tf 12 0 0 12 x y Tm" 写法得到 12pt 字形）
 	fontSize := textState.FontSize
 
 	// 如果字体大小为0，使用默认值
-	if fontSize < 1.0 {
+	// （小于 1 的字号是有效的：文本矩阵的缩放由 textGlyphMatrix 施加到字形上，不能替换为默认值）
+	if fontSize <= 0 {
 		fontSize = 12.0
 	}
 
@@ -543,49 +645,39 @@ func renderText(ctx *RenderContext, text string, array []any) error {
 	// 水平缩放已经在 GlyphAdvance 计算中处理了
 	// 这样避免双重缩放
 
-	// 设置颜色（根据渲染模式）
-	switch textState.RenderMode {
-	case 0: // 填充
-		if state.FillColor != nil {
-			debugPrintf("[TEXT_STATE] Using FillColor: RGB(%.3f, %.3f, %.3f, %.3f)\n",
-				state.FillColor.R, state.FillColor.G, state.FillColor.B, state.FillColor.A)
-			ctx.GopdfCtx.SetSourceRGBA(
-				state.FillColor.R,
-				state.FillColor.G,
-				state.FillColor.B,
-				state.FillColor.A,
-			)
-		} else {
-			// 默认使用黑色
-			debugPrintf("[TEXT_STATE] Using default black color\n")
-			ctx.GopdfCtx.SetSourceRGBA(0, 0, 0, 1)
-		}
-	case 1: // 描边
-		if state.StrokeColor != nil {
-			ctx.GopdfCtx.SetSourceRGBA(
-				state.StrokeColor.R,
-				state.StrokeColor.G,
-				state.StrokeColor.B,
-				state.StrokeColor.A,
-			)
-		}
-	case 2: // 填充+描边
-		if state.FillColor != nil {
-			ctx.GopdfCtx.SetSourceRGBA(
-				state.FillColor.R,
-				state.FillColor.G,
-				state.FillColor.B,
-				state.FillColor.A,
-			)
-		}
-	case 3: // 不可见
-		return nil
+	// 渲染模式：0 填充、1 描边、2 填充+描边、3 不可见；
+	// 4-7 与 0-3 相同，但同时把字形轮廓加入裁剪路径（在 ET 时生效）；
+	// 颜色空间为 None 着色剂时对应的填充或描边不产生标记
+	mode := textState.RenderMode
+	fillGlyphs := (mode == 0 || mode == 2 || mode == 4 || mode == 6) && !state.FillNoMarks
+	strokeGlyphs := (mode == 1 || mode == 2 || mode == 5 || mode == 6) && !state.StrokeNoMarks
+	clipGlyphs := mode >= 4 && mode <= 7
+	if !fillGlyphs && !strokeGlyphs {
+		visible = false
+	}
+
+	fillColor := &Color{R: 0, G: 0, B: 0, A: 1}
+	if state.FillColor != nil {
+		fillColor = state.FillColor
 	}
+	strokeColor := &Color{R: 0, G: 0, B: 0, A: 1}
+	if state.StrokeColor != nil {
+		strokeColor = state.StrokeColor
+	}
+	debugPrintf("[TEXT_STATE] RenderMode=%d FillColor=(%.3f, %.3f, %.3f, %.3f) StrokeColor=(%.3f, %.3f, %.3f, %.3f)\n",
+		mode, fillColor.R, fillColor.G, fillColor.B, fillColor.A,
+		strokeColor.R, strokeColor.G, strokeColor.B, strokeColor.A)
 
 	// 🔥 新策略：使用 Pango 自动布局
 	// 只记录文本的起始位置，让 Pango 处理字符间距和宽度
 	var glyphs []GlyphWithPosition
-	currentX := 0.0 // 文本空间中的相对 X 位置
+	currentX := 0.0 // 文本空间中沿书写方向的相对位置（竖排字体为 Y）
+	vertical := textState.Font != nil && textState.Font.Vertical
+	// TJ 数字调整的缩放：横排受水平缩放影响，竖排不受
+	kerningScale := textState.HorizontalScaling / 100.0
+	if vertical {
+		kerningScale = 1
+	}
 
 	// 渲染文本
 	if array != nil {
@@ -594,25 +686,26 @@ func renderText(ctx *RenderContext, text string, array []any) error {
 
 		for idx, item := range array {
 			switch v := item.(type) {
-			case string:
+			case string, HexString:
 				// 解码文本并获取 CID 数组
-				decodedText, cids := decodeTextStringWithCIDs(v, toUnicodeMap, textState.Font)
-				if decodedText == "" {
+				str, isHex, _ := textItem(v)
+				texts, cids := decodeTextStringWithCIDs(str, isHex, toUnicodeMap, textState.Font)
+				if len(cids) == 0 {
 					debugPrintf("[TJ_ARRAY][%d] Empty string after decode\n", idx)
 					continue
 				}
 
-				debugPrintf("[TJ_ARRAY][%d] Text=%q (len=%d runes, %d CIDs) at x=%.2f\n",
-					idx, decodedText, len([]rune(decodedText)), len(cids), currentX)
+				debugPrintf("[TJ_ARRAY][%d] Text=%q (%d CIDs) at x=%.2f\n",
+					idx, strings.Join(texts, ""), len(cids), currentX)
 
-				runes := []rune(decodedText)
 				for i, cid := range cids {
-					// 计算当前字形的绝对坐标（应用文本矩阵）
-					absX, absY := textState.TextMatrix.Transform(currentX, 0)
+					// 计算当前字形的绝对坐标（文本空间原点加上文本上升后应用文本矩阵）
+					absX, absY := textState.TextMatrix.Transform(textState.glyphOrigin(cid, currentX, fontSize))
 
 					glyph := GlyphWithPosition{
 						CID:        cid,
-						Rune:       runes[i],
+						Rune:       firstRune(texts[i]),
+						Text:       texts[i],
 						X:          absX,
 						Y:          absY,
 						FontFamily: fontFamily,
@@ -622,24 +715,23 @@ func renderText(ctx *RenderContext, text string, array []any) error {
 
 					// 🔥 关键改进：仍然计算字形推进距离用于更新文本矩阵
 					// 但渲染时让 Pango 自动处理布局
-					isSpace := i < len(runes) && runes[i] == ' '
-					adv := textState.GlyphAdvance(cid, isSpace)
+					adv := textState.GlyphAdvance(cid, texts[i] == " ")
 					currentX += adv
 
-					debugPrintf("[TJ_ARRAY][%d][%d] CID=%d Rune=%c absPos=(%.2f, %.2f) adv=%.2f\n",
-						idx, i, cid, runes[i], absX, absY, adv)
+					debugPrintf("[TJ_ARRAY][%d][%d] CID=%d Text=%q absPos=(%.2f, %.2f) adv=%.2f\n",
+						idx, i, cid, texts[i], absX, absY, adv)
 				}
 
 			case float64:
-				// PDF规范：负值表示向右移动，正值表示向左移动
+				// PDF规范：负值表示向右（竖排为向上）移动，正值表示向左（竖排为向下）移动
 				// 调整值以千分之一em为单位
-				kerningAdjustment := -v * fontSize / 1000.0 * textState.HorizontalScaling / 100.0
+				kerningAdjustment := -v * fontSize / 1000.0 * kerningScale
 				debugPrintf("[TJ_ARRAY][%d] Kerning=%.0f adj=%.2f (x: %.2f -> %.2f)\n",
 					idx, v, kerningAdjustment, currentX, currentX+kerningAdjustment)
 				currentX += kerningAdjustment
 
 			case int:
-				kerningAdjustment := -float64(v) * fontSize / 1000.0 * textState.HorizontalScaling / 100.0
+				kerningAdjustment := -float64(v) * fontSize / 1000.0 * kerningScale
 				debugPrintf("[TJ_ARRAY][%d] Kerning=%d adj=%.2f (x: %.2f -> %.2f)\n",
 					idx, v, kerningAdjustment, currentX, currentX+kerningAdjustment)
 				currentX += kerningAdjustment
@@ -647,19 +739,19 @@ func renderText(ctx *RenderContext, text string, array []any) error {
 		}
 	} else {
 		// Tj 操作符：简单文本
-		decodedText, cids := decodeTextStringWithCIDs(text, toUnicodeMap, textState.Font)
-		if decodedText != "" {
-			debugPrintf("[Tj] Text=%q (len=%d runes, %d CIDs) at Tm=[%.2f, %.2f]\n",
-				decodedText, len([]rune(decodedText)), len(cids), textState.TextMatrix.X0, textState.TextMatrix.Y0)
+		texts, cids := decodeTextStringWithCIDs(text, hex, toUnicodeMap, textState.Font)
+		if len(cids) > 0 {
+			debugPrintf("[Tj] Text=%q (%d CIDs) at Tm=[%.2f, %.2f]\n",
+				strings.Join(texts, ""), len(cids), textState.TextMatrix.X0, textState.TextMatrix.Y0)
 
-			runes := []rune(decodedText)
 			for i, cid := range cids {
-				// 计算当前字形的绝对坐标
-				absX, absY := textState.TextMatrix.Transform(currentX, 0)
+				// 计算当前字形的绝对坐标（文本空间原点加上文本上升）
+				absX, absY := textState.TextMatrix.Transform(textState.glyphOrigin(cid, currentX, fontSize))
 
 				glyph := GlyphWithPosition{
 					CID:        cid,
-					Rune:       runes[i],
+					Rune:       firstRune(texts[i]),
+					Text:       texts[i],
 					X:          absX,
 					Y:          absY,
 					FontFamily: fontFamily,
@@ -669,18 +761,17 @@ func renderText(ctx *RenderContext, text string, array []any) error {
 
 				// 🔥 关键改进：仍然计算字形推进距离用于更新文本矩阵
 				// 但渲染时让 Pango 自动处理布局
-				isSpace := i < len(runes) && runes[i] == ' '
-				adv := textState.GlyphAdvance(cid, isSpace)
+				adv := textState.GlyphAdvance(cid, texts[i] == " ")
 				currentX += adv
 
-				debugPrintf("[Tj][%d] CID=%d Rune=%c absPos=(%.2f, %.2f) adv=%.2f\n",
-					i, cid, runes[i], absX, absY, adv)
+				debugPrintf("[Tj][%d] CID=%d Text=%q absPos=(%.2f, %.2f) adv=%.2f\n",
+					i, cid, texts[i], absX, absY, adv)
 			}
 		}
 	}
 
 	// 🔥 使用 PangoPdf 渲染文字：逐个字形渲染以精确控制位置
-	if len(glyphs) > 0 {
+	if (visible || clipGlyphs) && len(glyphs) > 0 {
 		debugPrintf("[TEXT_RENDER] Rendering %d glyphs individually using PangoPdf\n", len(glyphs))
 
 		// 创建 PangoPdf 布局（在循环外创建以提高性能）
@@ -690,20 +781,51 @@ func renderText(ctx *RenderContext, text string, array []any) error {
 		fontDesc.SetSize(fontSize)
 		layout.SetFontDescription(fontDesc)
 
+		glyphMatrix := textGlyphMatrix(textState.TextMatrix)
+
 		for i, glyph := range glyphs {
+			ctx.GopdfCtx.Save()
+
 			// 移动到字形位置
-			ctx.GopdfCtx.MoveTo(glyph.X, glyph.Y)
+			ctx.GopdfCtx.Translate(glyph.X, glyph.Y)
+			ctx.GopdfCtx.Transform(glyphMatrix)
 
-			// 设置单个字符文本
-			text := string(glyph.Rune)
-			layout.SetText(text)
+			// 设置字形文本（连字可能包含多个字符）
+			layout.SetText(glyph.Text)
 
-			// 渲染文本
-			ctx.GopdfCtx.PangoPdfShowText(layout)
+			if visible && fillGlyphs {
+				ctx.GopdfCtx.SetSourceRGBA(fillColor.R, fillColor.G, fillColor.B, fillColor.A)
+				ctx.GopdfCtx.NewPath()
+				ctx.GopdfCtx.MoveTo(0, 0)
+				ctx.GopdfCtx.PangoPdfShowText(layout)
+			}
+			if visible && strokeGlyphs {
+				ctx.GopdfCtx.NewPath()
+				ctx.GopdfCtx.MoveTo(0, 0)
+				ctx.GopdfCtx.PangoPdfLayoutPath(layout)
+				ctx.GopdfCtx.SetSourceRGBA(strokeColor.R, strokeColor.G, strokeColor.B, strokeColor.A)
+				// 线宽在用户空间中解释（PDF 9.3.6），抵消字形矩阵的缩放
+				lineWidth := state.LineWidth
+				if det := math.Abs(glyphMatrix.XX*glyphMatrix.YY - glyphMatrix.XY*glyphMatrix.YX); det > 0 {
+					lineWidth /= math.Sqrt(det)
+				}
+				ctx.GopdfCtx.SetLineWidth(lineWidth)
+				ctx.GopdfCtx.Stroke()
+			}
+			if clipGlyphs {
+				// 字形轮廓变换回文本对象所在的用户空间后累积，ET 时统一裁剪
+				ctx.GopdfCtx.NewPath()
+				ctx.GopdfCtx.MoveTo(0, 0)
+				ctx.GopdfCtx.PangoPdfLayoutPath(layout)
+				glyphToUser := glyphMatrix.Multiply(NewTranslationMatrix(glyph.X, glyph.Y))
+				ctx.appendTextClipPath(ctx.GopdfCtx.CopyPath(), glyphToUser)
+				ctx.GopdfCtx.NewPath()
+			}
+			ctx.GopdfCtx.Restore()
 
 			if i < 5 || i >= len(glyphs)-5 {
-				debugPrintf("[TEXT_RENDER][%d] Rendered '%c' at (%.2f, %.2f)\n",
-					i, glyph.Rune, glyph.X, glyph.Y)
+				debugPrintf("[TEXT_RENDER][%d] Rendered %q at (%.2f, %.2f)\n",
+					i, glyph.Text, glyph.X, glyph.Y)
 			}
 		}
 
@@ -712,9 +834,15 @@ func renderText(ctx *RenderContext, text string, array []any) error {
 
 	// 更新文本矩阵：使用PDF的字形宽度
 	// 这对于在同一个BT...ET块中的多个Tj操作是必要的
+	// 根据PDF规范：Tm = [1 0 0 1 tx 0] × Tm，推进量 tx 位于文本空间，
+	// 需经过文本矩阵的缩放/旋转，而不是直接加到设备坐标上
+	// 竖排字体沿 Y 轴推进
 	if currentX != 0 {
 		translation := NewTranslationMatrix(currentX, 0)
-		textState.TextMatrix = textState.TextMatrix.Multiply(translation)
+		if vertical {
+			translation = NewTranslationMatrix(0, currentX)
+		}
+		textState.TextMatrix = translation.Multiply(textState.TextMatrix)
 		debugPrintf("[TEXT_MATRIX] Updated after text: PDF_width=%.2f, new X0=%.2f\n",
 			currentX, textState.TextMatrix.X0)
 	}
@@ -725,12 +853,12 @@ func renderText(ctx *RenderContext, text string, array []any) error {
 	// 当前实现已尽可能应用了TJ操作符中的数字偏移到文本位置
 }
 
-// decodeTextStringWithCIDs 解码文本并返回 Unicode 字符串和 CID 数组
-func decodeTextStringWithCIDs(text string, toUnicodeMap *CIDToUnicodeMap, font *Font) (string, []uint16) {
-	// 检查是否是十六进制字符串
-	if len(text) >= 2 && text[0] == '<' && text[len(text)-1] == '>' {
-		hexStr := text[1 : len(text)-1]
-		hexStr = strings.ReplaceAll(hexStr, " ", "")
+// decodeTextStringWithCIDs 解码文本并返回每个 CID 对应的 Unicode 文本和 CID 数组
+// 两个切片一一对应：ToUnicode 可以把一个 CID 映射为多个字符（如连字 fi），
+// 因此不能把整段解码结果按字符与 CID 配对；hex 表示 text 是十六进制字符串 <...>
+func decodeTextStringWithCIDs(text string, hex bool, toUnicodeMap *CIDToUnicodeMap, font *Font) ([]string, []uint16) {
+	if hex {
+		hexStr := hexStringDigits(text)
 
 		// 转换十六进制到字节
 		var result []byte
@@ -743,7 +871,7 @@ func decodeTextStringWithCIDs(text string, toUnicodeMap *CIDToUnicodeMap, font *
 		}
 
 		if len(result) < 2 || len(result)%2 != 0 {
-			return "", nil
+			return nil, nil
 		}
 
 		// 提取CID数组
@@ -753,61 +881,71 @@ func decodeTextStringWithCIDs(text string, toUnicodeMap *CIDToUnicodeMap, font *
 			cids = append(cids, cid)
 		}
 
-		// 解码为 Unicode
-		var decoded strings.Builder
+		// 逐个 CID 解码为 Unicode
+		texts := make([]string, len(cids))
 		isIdentity := font != nil && font.IsIdentity
 
 		// 如果有 ToUnicode 映射，优先使用它
 		if toUnicodeMap != nil {
 			allMapped := true
-			for _, cid := range cids {
-				if uni, ok := toUnicodeMap.MapCIDToUnicode(cid); ok {
-					// 验证Unicode字符有效性
-					if isValidUnicodeRune(uni) {
-						decoded.WriteRune(uni)
-					} else {
-						debugPrintf("⚠️ Invalid Unicode from mapping for CID %d: U+%04X\n", cid, uni)
-						decoded.WriteRune('�') // 使用替换字符
-					}
-				} else {
+			for i, cid := range cids {
+				mapped, ok := toUnicodeMap.MapCIDToString(cid)
+				if !ok {
 					allMapped = false
 					break
 				}
+				// 验证Unicode字符有效性
+				if !utf8.ValidString(mapped) {
+					debugPrintf("⚠️ Invalid Unicode from mapping for CID %d: %q\n", cid, mapped)
+					mapped = strings.ToValidUTF8(mapped, "�") // 使用替换字符
+				}
+				texts[i] = mapped
 			}
 
 			// 如果所有CID都成功映射，返回结果
 			if allMapped {
-				return decoded.String(), cids
+				return texts, cids
 			}
-			decoded.Reset()
 		}
 
 		// 如果ToUnicode映射失败或不存在，且是Identity映射，CID直接等于Unicode码点
 		if isIdentity {
-			for _, cid := range cids {
+			for i, cid := range cids {
 				r := rune(cid)
 				// 验证Unicode码点有效性
 				if isValidUnicodeRune(r) {
-					decoded.WriteRune(r)
+					texts[i] = string(r)
 				} else {
 					debugPrintf("⚠️ Invalid Unicode codepoint: U+%04X\n", cid)
-					decoded.WriteRune('�') // 使用替换字符
+					texts[i] = "�" // 使用替换字符
 				}
 			}
-			return decoded.String(), cids
+			return texts, cids
 		}
 
-		// 否则尝试标准解码
-		decodedStr := decodeTextString(text)
-		return decodedStr, cids
+		// 否则逐个 CID 尝试标准解码
+		for i, cid := range cids {
+			texts[i] = decodeTextString(fmt.Sprintf("<%04X>", cid), true)
+		}
+		return texts, cids
 	}
 
-	// 普通字符串 - 转换为 CID 数组（字节码）
-	var cids []uint16
+	// 普通字符串 - 每个字节是一个字符码，没有映射时按 Latin-1 处理
+	cids := make([]uint16, len(text))
+	texts := make([]string, len(text))
 	for i := 0; i < len(text); i++ {
-		cids = append(cids, uint16(text[i]))
+		cids[i] = uint16(text[i])
+		texts[i] = string(rune(text[i]))
+	}
+	return texts, cids
+}
+
+// firstRune 返回文本的第一个字符，空文本返回 0
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
 	}
-	return text, cids
+	return 0
 }
 
 // isValidUnicodeRune 验证Unicode码点是否有效
@@ -824,11 +962,21 @@ func isValidUnicodeRune(r rune) bool {
 }
 
 // GlyphAdvance 计算单个字形的推进距离（核心方法）
+// 横排时返回沿 X 轴的推进量；竖排字体返回沿 Y 轴的位移（向下为负）
 func (ts *TextState) GlyphAdvance(cid uint16, isSpace bool) float64 {
 	if ts.Font == nil {
 		return 0.0
 	}
 
+	// 竖排：ty = w1 × Tfs + Tc + Tw，不应用水平缩放
+	if ts.Font.Vertical {
+		adv := ts.Font.GetVerticalMetric(cid).W1*ts.FontSize/1000.0 + ts.CharSpacing
+		if isSpace {
+			adv += ts.WordSpacing
+		}
+		return adv
+	}
+
 	// 1. 获取字形宽度（千分之一 em）
 	glyphWidth := ts.Font.GetWidth(cid)
 
@@ -879,6 +1027,16 @@ func (ts *TextState) GlyphAdvance(cid uint16, isSpace bool) float64 {
 	return adv
 }
 
+// glyphOrigin 返回书写方向上位置 pen 处字形的水平原点（文本空间，已计入文本上升）
+// 竖排时 pen 沿 Y 轴，字形绘制在竖排原点减去位置向量 v 的位置
+func (ts *TextState) glyphOrigin(cid uint16, pen, fontSize float64) (float64, float64) {
+	if ts.Font == nil || !ts.Font.Vertical {
+		return pen, ts.Rise
+	}
+	v := ts.Font.GetVerticalMetric(cid)
+	return -v.VX * fontSize / 1000.0, pen - v.VY*fontSize/1000.0 + ts.Rise
+}
+
 // isCJKCharacterFromCID 从CID判断是否是CJK字符
 func isCJKCharacterFromCID(cid uint16) bool {
 	r := rune(cid)
@@ -915,7 +1073,8 @@ func isCJKCharacterRune(r rune) bool {
 }
 
 // CalculateTextWidthFromCIDs 使用字形宽度计算文本宽度（从 CID 数组）
-func CalculateTextWidthFromCIDs(cids []uint16, textState *TextState, decodedText string) float64 {
+// texts 是 decodeTextStringWithCIDs 返回的与 cids 一一对应的文本
+func CalculateTextWidthFromCIDs(cids []uint16, textState *TextState, texts []string) float64 {
 	if textState.Font == nil || len(cids) == 0 {
 		// 关键修复：当没有字体信息时，返回0而不是过估
 		// 这样可以避免推动后续文本向右偏移
@@ -925,12 +1084,11 @@ func CalculateTextWidthFromCIDs(cids []uint16, textState *TextState, decodedText
 	}
 
 	totalWidth := 0.0
-	runes := []rune(decodedText)
 
 	// 使用字形宽度计算
 	for i, cid := range cids {
 		// 检查是否是空格
-		isSpace := i < len(runes) && runes[i] == ' '
+		isSpace := i < len(texts) && texts[i] == " "
 
 		// 使用统一的 advance 计算
 		adv := textState.GlyphAdvance(cid, isSpace)
@@ -942,11 +1100,10 @@ func CalculateTextWidthFromCIDs(cids []uint16, textState *TextState, decodedText
 }
 
 // decodeTextStringWithFontAndIdentity 使用字体的 ToUnicode 映射解码文本，支持Identity映射
-func decodeTextStringWithFontAndIdentity(text string, toUnicodeMap *CIDToUnicodeMap, isIdentity bool) string {
-	// 检查是否是十六进制字符串
-	if len(text) >= 2 && text[0] == '<' && text[len(text)-1] == '>' {
-		hexStr := text[1 : len(text)-1]
-		hexStr = strings.ReplaceAll(hexStr, " ", "")
+// hex 表示 text 是十六进制字符串 <...>，否则为字面量字符串
+func decodeTextStringWithFontAndIdentity(text string, hex bool, toUnicodeMap *CIDToUnicodeMap, isIdentity bool) string {
+	if hex {
+		hexStr := hexStringDigits(text)
 
 		// 转换十六进制到字节
 		var result []byte
@@ -975,8 +1132,8 @@ func decodeTextStringWithFontAndIdentity(text string, toUnicodeMap *CIDToUnicode
 			allMapped := true
 
 			for _, cid := range cids {
-				if uni, ok := toUnicodeMap.MapCIDToUnicode(cid); ok {
-					decoded.WriteRune(uni)
+				if text, ok := toUnicodeMap.MapCIDToString(cid); ok {
+					decoded.WriteString(text)
 				} else {
 					allMapped = false
 					break
@@ -999,7 +1156,7 @@ func decodeTextStringWithFontAndIdentity(text string, toUnicodeMap *CIDToUnicode
 		}
 
 		// 否则尝试标准解码
-		return decodeTextString(text)
+		return decodeTextString(text, true)
 	}
 
 	// 普通字符串
@@ -1007,15 +1164,11 @@ func decodeTextStringWithFontAndIdentity(text string, toUnicodeMap *CIDToUnicode
 }
 
 // decodeTextString 解码 PDF 文本字符串
-// 处理普通字符串和十六进制字符串 <...>
-func decodeTextString(text string) string {
-	// 检查是否是十六进制字符串
-	if len(text) >= 2 && text[0] == '<' && text[len(text)-1] == '>' {
+// 处理普通字符串和十六进制字符串 <...>（hex 为 true）
+func decodeTextString(text string, hex bool) string {
+	if hex {
 		// 十六进制字符串：<48656C6C6F> -> "Hello"
-		hexStr := text[1 : len(text)-1]
-
-		// 移除空格
-		hexStr = strings.ReplaceAll(hexStr, " ", "")
+		hexStr := hexStringDigits(text)
 
 		// 转换十六进制到字节
 		var result []byte
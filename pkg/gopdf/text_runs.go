@@ -0,0 +1,270 @@
+package gopdf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// 字形包围盒的上升/下降高度（相对字号的 em 框，字体没有提供度量时使用）
+const (
+	glyphBoxAscent  = 0.8
+	glyphBoxDescent = 0.2
+)
+
+// TextRun 一次文本显示操作（Tj/TJ/'/"）产生的文本及其逐字形包围盒
+// Text 由各字形的 Unicode 文本依次拼接而成，与 Glyphs 一一对应
+type TextRun struct {
+	Text     string
+	FontName string
+	FontSize float64 // 有效字号（Tf 字号 × 文本矩阵缩放）
+	Glyphs   []GlyphBox
+}
+
+// GlyphBox 单个字形的包围盒
+// 坐标与 TextElementInfo 一致：页面左上角为原点，Y 轴向下；X/Y 为包围盒左上角，
+// 旋转文本取字形四边形的轴对齐包围盒
+type GlyphBox struct {
+	Rune   rune
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// ExtractPageTextRuns 提取页面中的文本片段，每个片段带有逐字形的包围盒（用于搜索高亮、复制选择）
+func (r *PDFReader) ExtractPageTextRuns(pageNum int) ([]TextRun, error) {
+	_, _, textRuns, err := r.extractPageContent(pageNum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract text runs from page %d: %w", pageNum, err)
+	}
+	return textRuns, nil
+}
+
+// SearchText 在页面中搜索 query，返回每处匹配的包围矩形
+// 矩形坐标与 GlyphBox 一致：页面左上角为原点，Y 轴向下，单位为 PDF 点（72 DPI）；
+// 按内容流顺序拼接相邻的文本片段，匹配可以跨越多个 Tj/TJ；跨行的匹配每行返回一个矩形。
+// 连续空白视为一个空格，片段之间有明显间距或换行时视为有空格
+func (r *PDFReader) SearchText(pageNum int, query string, caseInsensitive bool) ([]Rectangle, error) {
+	runs, err := r.ExtractPageTextRuns(pageNum)
+	if err != nil {
+		return nil, err
+	}
+
+	needle := []rune(strings.Join(strings.Fields(query), " "))
+	if len(needle) == 0 {
+		return nil, nil
+	}
+	fold := func(r rune) rune {
+		if caseInsensitive {
+			return unicode.ToLower(r)
+		}
+		return r
+	}
+	for i := range needle {
+		needle[i] = fold(needle[i])
+	}
+
+	glyphs := stitchTextRuns(runs)
+
+	var rects []Rectangle
+	for start := 0; start+len(needle) <= len(glyphs); start++ {
+		matched := true
+		for i, r := range needle {
+			if fold(glyphs[start+i].Rune) != r {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		rects = append(rects, matchRectangles(glyphs[start:start+len(needle)])...)
+		start += len(needle) - 1
+	}
+	return rects, nil
+}
+
+// stitchTextRuns 按内容流顺序拼接文本片段的字形，用于跨片段搜索
+// 空白字形折叠为一个空格；相邻片段之间有间距或不在同一行时插入一个没有包围盒的空格
+func stitchTextRuns(runs []TextRun) []GlyphBox {
+	var glyphs []GlyphBox
+	var last GlyphBox // 最后一个非空白字形
+	hasLast := false
+	isSpace := func() bool {
+		return len(glyphs) > 0 && glyphs[len(glyphs)-1].Rune == ' '
+	}
+
+	for _, run := range runs {
+		for i, g := range run.Glyphs {
+			if i == 0 && hasLast && !isSpace() && !adjacentGlyphs(last, g) {
+				glyphs = append(glyphs, GlyphBox{Rune: ' '})
+			}
+			if unicode.IsSpace(g.Rune) {
+				if len(glyphs) > 0 && !isSpace() {
+					g.Rune = ' '
+					glyphs = append(glyphs, g)
+				}
+				continue
+			}
+			glyphs = append(glyphs, g)
+			last, hasLast = g, true
+		}
+	}
+	return glyphs
+}
+
+// adjacentGlyphs 判断 next 是否紧接在 prev 之后（同一行，且间距小于字高的 0.2 倍）
+func adjacentGlyphs(prev, next GlyphBox) bool {
+	size := max(prev.Height, next.Height)
+	if math.Abs(prev.Y-next.Y) > size*0.5 {
+		return false
+	}
+	gap := next.X - (prev.X + prev.Width)
+	return gap > -size*0.5 && gap < size*0.2
+}
+
+// matchRectangles 合并一处匹配中各字形的包围盒，同一行的字形合并为一个矩形
+func matchRectangles(glyphs []GlyphBox) []Rectangle {
+	var rects []Rectangle
+	var x1, y1, x2, y2 float64
+	open := false
+	for _, g := range glyphs {
+		if g.Width == 0 && g.Height == 0 {
+			continue // 拼接时插入的空格
+		}
+		if open && math.Abs(g.Y-y1) <= max(g.Height, y2-y1)*0.5 {
+			x1, y1 = min(x1, g.X), min(y1, g.Y)
+			x2, y2 = max(x2, g.X+g.Width), max(y2, g.Y+g.Height)
+			continue
+		}
+		if open {
+			rects = append(rects, Rectangle{X: x1, Y: y1, Width: x2 - x1, Height: y2 - y1})
+		}
+		x1, y1, x2, y2 = g.X, g.Y, g.X+g.Width, g.Y+g.Height
+		open = true
+	}
+	if open {
+		rects = append(rects, Rectangle{X: x1, Y: y1, Width: x2 - x1, Height: y2 - y1})
+	}
+	return rects
+}
+
+// buildTextRun 根据文本显示操作符中的原始字符串计算逐字形包围盒
+// 字形在文本空间中从原点开始沿基线排列（宽度来自字体，TJ 数字为字距调整，
+// 并计入 ts 的字符间距、单词间距、水平缩放和文本上升），再经 m（文本矩阵 × CTM）变换到页面空间
+func buildTextRun(op PDFOperator, ts *TextState, m *Matrix, pageHeight float64) TextRun {
+	var items []any
+	switch t := op.(type) {
+	case *OpShowText:
+		items = []any{showTextItem(t.Text, t.Hex)}
+	case *OpShowTextNextLine:
+		items = []any{showTextItem(t.Text, t.Hex)}
+	case *OpShowTextWithSpacing:
+		items = []any{showTextItem(t.Text, t.Hex)}
+	case *OpShowTextArray:
+		items = t.Array
+	}
+
+	font, fontSize := ts.Font, ts.FontSize
+	hScale := ts.HorizontalScaling / 100.0
+
+	var run TextRun
+	var text []rune
+	tx := 0.0 // 文本空间中的当前 x 位置
+	for _, item := range items {
+		switch v := item.(type) {
+		case string, HexString:
+			str, hex, _ := textItem(v)
+			for _, cid := range extractCIDsFromText(str, hex) {
+				width := glyphWidth(font, cid) / 1000.0 * fontSize * hScale
+				// 单词间距只作用于单字节编码的空格
+				spacing := ts.CharSpacing
+				if !hex && cid == ' ' {
+					spacing += ts.WordSpacing
+				}
+				advance := width + spacing*hScale
+				runes := []rune(decodeGlyphText(cid, hex, font))
+				if len(runes) == 0 {
+					tx += advance
+					continue
+				}
+				// 一个字形对应多个字符（如连字 fi）时平分字形宽度
+				share := width / float64(len(runes))
+				for i, r := range runes {
+					box := glyphBox(m, tx+share*float64(i), share, fontSize, ts.Rise, pageHeight)
+					box.Rune = r
+					run.Glyphs = append(run.Glyphs, box)
+					text = append(text, r)
+				}
+				tx += advance
+			}
+		case float64:
+			tx -= v / 1000.0 * fontSize * hScale
+		case int:
+			tx -= float64(v) / 1000.0 * fontSize * hScale
+		}
+	}
+
+	run.Text = string(text)
+	return run
+}
+
+// glyphWidth 字形宽度（千分之一 em），零宽度时回退到默认宽度；没有字体时按半角估算
+func glyphWidth(font *Font, cid uint16) float64 {
+	if font == nil {
+		return 500
+	}
+	width := font.GetWidth(cid)
+	if width == 0 {
+		switch {
+		case font.DefaultWidth > 0:
+			width = font.DefaultWidth
+		case font.MissingWidth > 0:
+			width = font.MissingWidth
+		default:
+			width = 1000
+		}
+	}
+	return width
+}
+
+// decodeGlyphText 解码单个字形对应的 Unicode 文本
+// 优先使用 ToUnicode 映射；单字节编码没有映射时按 Latin-1 处理，避免产生无效的 UTF-8
+func decodeGlyphText(cid uint16, hex bool, font *Font) string {
+	if font != nil && font.ToUnicodeMap != nil {
+		if text, ok := font.ToUnicodeMap.MapCIDToString(cid); ok {
+			return text
+		}
+	}
+	if !hex {
+		return string(rune(cid))
+	}
+
+	code := fmt.Sprintf("<%04X>", cid)
+	if font == nil {
+		return decodeTextString(code, true)
+	}
+	return decodeTextStringWithFontAndIdentity(code, true, font.ToUnicodeMap, font.IsIdentity)
+}
+
+// glyphBox 计算文本空间中 [x, x+width] × [rise-descent, rise+ascent] 的字形框在页面空间（Y 轴向下）的包围盒
+func glyphBox(m *Matrix, x, width, fontSize, rise, pageHeight float64) GlyphBox {
+	bottom, top := rise-glyphBoxDescent*fontSize, rise+glyphBoxAscent*fontSize
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range [][2]float64{{x, bottom}, {x + width, bottom}, {x, top}, {x + width, top}} {
+		px, py := m.Transform(p[0], p[1])
+		minX, maxX = min(minX, px), max(maxX, px)
+		minY, maxY = min(minY, py), max(maxY, py)
+	}
+
+	return GlyphBox{
+		X:      minX,
+		Y:      pageHeight - maxY,
+		Width:  maxX - minX,
+		Height: maxY - minY,
+	}
+}
@@ -80,22 +80,22 @@ func (m *Matrix) TransformDistance(dx, dy float64) (float64, float64) {
 	return newDx, newDy
 }
 
-// Invert 计算逆矩阵
-func (m *Matrix) Invert() (*Matrix, error) {
+// Invert 计算逆矩阵；矩阵不可逆（行列式为零）时返回 false
+func (m *Matrix) Invert() (Matrix, bool) {
 	det := m.XX*m.YY - m.YX*m.XY
 	if math.Abs(det) < 1e-10 {
-		return nil, fmt.Errorf("matrix is not invertible (determinant is zero)")
+		return Matrix{}, false
 	}
 
 	invDet := 1.0 / det
-	return &Matrix{
+	return Matrix{
 		XX: m.YY * invDet,
 		YX: -m.YX * invDet,
 		XY: -m.XY * invDet,
 		YY: m.XX * invDet,
 		X0: (m.XY*m.Y0 - m.YY*m.X0) * invDet,
 		Y0: (m.YX*m.X0 - m.XX*m.Y0) * invDet,
-	}, nil
+	}, true
 }
 
 // Translate 添加平移变换
@@ -0,0 +1,153 @@
+package gopdf
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ===== 矢量输出表面（PDF、SVG、记录表面）=====
+
+// vectorOpKind 矢量绘制操作类型
+type vectorOpKind int
+
+const (
+	vectorOpFill vectorOpKind = iota
+	vectorOpStroke
+	vectorOpPaint
+)
+
+// vectorDrawOp 一次绘制操作的快照：路径为用户空间坐标，matrix 为绘制时的 CTM
+// Paint 操作的 path 为裁剪路径，为空时表示整个页面；
+// Fill/Stroke 的 clip 为当前裁剪路径（与光栅化一致，使用同一个 CTM）；
+// alpha 为 PaintWithAlpha 或纯色 Mask 的整体透明度，1 表示不衰减
+type vectorDrawOp struct {
+	kind       vectorOpKind
+	alpha      float64
+	path       []pathOp
+	clip       []pathOp
+	clipRule   FillRule
+	matrix     Matrix
+	source     Pattern
+	operator   Operator
+	fillRule   FillRule
+	lineWidth  float64
+	lineCap    LineCap
+	lineJoin   LineJoin
+	miterLimit float64
+	dash       []float64
+	dashOffset float64
+}
+
+// vectorSurface 以矢量形式输出的表面：context 在光栅化之外把每次绘制操作通知给它
+type vectorSurface interface {
+	recordDrawOp(op *vectorDrawOp)
+}
+
+// recordVectorOp 目标为矢量表面时记录当前绘制操作
+func (c *context) recordVectorOp(kind vectorOpKind) {
+	c.recordVectorOpWithAlpha(kind, 1)
+}
+
+// recordVectorOpWithAlpha 记录整体透明度为 alpha 的绘制操作
+func (c *context) recordVectorOpWithAlpha(kind vectorOpKind, alpha float64) {
+	vs, ok := c.target.(vectorSurface)
+	if !ok {
+		return
+	}
+
+	op := &vectorDrawOp{
+		kind:       kind,
+		alpha:      alpha,
+		matrix:     c.gstate.matrix,
+		source:     c.gstate.source,
+		operator:   c.gstate.operator,
+		fillRule:   c.gstate.fillRule,
+		lineWidth:  c.gstate.lineWidth,
+		lineCap:    c.gstate.lineCap,
+		lineJoin:   c.gstate.lineJoin,
+		miterLimit: c.gstate.miterLimit,
+		dash:       c.gstate.dash,
+		dashOffset: c.gstate.dashOffset,
+	}
+
+	var clipPath *path
+	if c.gstate.clip != nil {
+		clipPath = c.gstate.clip.path
+	}
+	if kind == vectorOpPaint {
+		op.path = copyPathData(clipPath)
+		if clipPath != nil {
+			op.fillRule = c.gstate.clip.fillRule
+		}
+	} else {
+		op.path = copyPathData(c.path)
+		op.clip = copyPathData(clipPath)
+		if clipPath != nil {
+			op.clipRule = c.gstate.clip.fillRule
+		}
+	}
+
+	vs.recordDrawOp(op)
+}
+
+// copyPathData 深拷贝路径数据
+func copyPathData(p *path) []pathOp {
+	if p == nil || len(p.data) == 0 {
+		return nil
+	}
+	data := make([]pathOp, len(p.data))
+	for i, op := range p.data {
+		data[i] = pathOp{op: op.op, points: append([]point(nil), op.points...)}
+	}
+	return data
+}
+
+// pageRectPath 设备空间中的页面矩形经逆 CTM 映射回用户空间的路径，用于没有裁剪路径的 Paint
+func pageRectPath(m Matrix, width, height float64) []pathOp {
+	inv := m
+	if MatrixInvert(&inv) != StatusSuccess {
+		inv.InitIdentity()
+	}
+	data := make([]pathOp, 0, 5)
+	for i, corner := range [][2]float64{{0, 0}, {width, 0}, {width, height}, {0, height}} {
+		x, y := MatrixTransformPoint(&inv, corner[0], corner[1])
+		op := PathLineTo
+		if i == 0 {
+			op = PathMoveTo
+		}
+		data = append(data, pathOp{op: op, points: []point{{x, y}}})
+	}
+	return append(data, pathOp{op: PathClosePath})
+}
+
+// vectorSourceColor 取图案的纯色近似，用于无法直接输出该图案的情况（如 PDF 表面的渐变描边）：
+// 纯色图案直接使用，渐变退化为第一个色标，其它图案使用黑色
+func vectorSourceColor(source Pattern) (r, g, b, a float64) {
+	switch p := source.(type) {
+	case *solidPattern:
+		return p.GetRGBA()
+	case interface {
+		GetColorStopCount() int
+		GetColorStop(int) (float64, float64, float64, float64, float64, Status)
+	}:
+		if p.GetColorStopCount() > 0 {
+			_, r, g, b, a, _ := p.GetColorStop(0)
+			return r, g, b, a
+		}
+	}
+	return 0, 0, 0, 1
+}
+
+// formatNum 格式化输出数值（PDF 内容流、SVG 属性）：最多保留 4 位小数并去掉多余的 0
+func formatNum(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "0"
+	}
+	str := strconv.FormatFloat(v, 'f', 4, 64)
+	str = strings.TrimRight(strings.TrimRight(str, "0"), ".")
+	if str == "" || str == "-0" {
+		return "0"
+	}
+	return str
+}
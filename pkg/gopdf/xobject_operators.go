@@ -3,6 +3,9 @@ package gopdf
 import (
 	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
+	"math"
 )
 
 // ===== XObject 操作符 =====
@@ -57,21 +60,44 @@ type XObject struct {
 	// 注意：PDF 规范中没有直接的 DPI 字段，但可以通过以下方式推断：
 	// 1. 如果 Width/Height 与解码后的像素尺寸不同，说明有缩放
 	// 2. 外层 CTM 矩阵决定了图像在页面上的实际尺寸
-	ActualPixelWidth  int      // 解码后的实际像素宽度
-	ActualPixelHeight int      // 解码后的实际像素高度
-	SMask             *XObject // 🔥 新增：软遮罩（透明度掩码）
-	ColorComponents   int      // 🔥 新增：颜色分量数（来自 ICCBased N 或其他）
-	Palette           []byte   // 🔥 新增：调色板数据（用于 Indexed 颜色空间）
+	ActualPixelWidth  int                // 解码后的实际像素宽度
+	ActualPixelHeight int                // 解码后的实际像素高度
+	SMask             *XObject           // 🔥 新增：软遮罩（透明度掩码）
+	ColorComponents   int                // 🔥 新增：颜色分量数（来自 ICCBased N 或其他）
+	Palette           []byte             // 🔥 新增：调色板数据（用于 Indexed 颜色空间）
+	Matte             []float64          // 🔥 新增：SMask 的 /Matte 预混合颜色（基础图像颜色空间分量）
+	Interpolate       bool               // 图像的 /Interpolate 标志：缩放时使用双线性插值
+	ICCProfile        []byte             // ICCBased 颜色空间嵌入的 ICC 配置文件数据
+	Transfer          *PDFFunction       // SMask 的 /TR 传递函数，nil 表示恒等映射
+	DeviceN           *DeviceNColorSpace // Separation/DeviceN 颜色空间（含 tint 变换）
+	Calibrated        ColorSpace         // CalRGB/CalGray 颜色空间（含白点、gamma 和矩阵）
+	ICCTransform      bool               // 解码时使用嵌入的 ICC 配置文件转换，不受全局开关影响
+	CMYKConversion    CMYKConversion     // CMYK 图像的转换方式，CMYKDefault 表示使用渲染上下文或全局设置
+	ObjectNumber      int                // 间接对象号，0 表示直接对象或内联图像
+	Generation        int                // 间接对象的生成号
+	JPX               bool               // 图像使用 JPXDecode 滤镜，Stream 是 JPEG 2000 数据
+	ImageMask         bool               // 模板掩码（/ImageMask true）：1 位样本标记以当前填充颜色绘制的区域，其余区域透明
+	DecodeInverted    bool               // 模板掩码的 /Decode [1 0]：样本 1（而不是 0）标记绘制区域
 }
 
 // renderFormXObject 渲染表单 XObject
+// 嵌套深度超过渲染预算（RenderOptions.MaxFormDepth）时返回 *RenderLimitError，防止表单相互引用导致无限递归
 func renderFormXObject(ctx *RenderContext, xobj *XObject) error {
+	leave, err := ctx.budget.enterForm()
+	if err != nil {
+		return err
+	}
+	defer leave()
+
 	// 检查是否有透明度组
-	if xobj.Group != nil {
+	if xobj.Group != nil && !canRenderGroupInPlace(ctx, xobj.Group) {
 		return renderTransparencyGroup(ctx, xobj)
 	}
+	return renderFormXObjectInline(ctx, xobj)
+}
 
-	// 普通表单 XObject 渲染
+// renderFormXObjectInline 把表单内容直接绘制到当前表面
+func renderFormXObjectInline(ctx *RenderContext, xobj *XObject) error {
 	// 保存图形状态
 	ctx.GopdfCtx.Save()
 	ctx.GraphicsStack.Push()
@@ -79,6 +105,7 @@ func renderFormXObject(ctx *RenderContext, xobj *XObject) error {
 		ctx.GopdfCtx.Restore()
 		ctx.GraphicsStack.Pop()
 	}()
+	defer ctx.enterContentStream()()
 
 	// 应用 XObject 的变换矩阵
 	if xobj.Matrix != nil {
@@ -100,6 +127,7 @@ func renderFormXObject(ctx *RenderContext, xobj *XObject) error {
 	}
 
 	// 解析并执行内容流
+	var abortErr error
 	if len(xobj.Stream) > 0 {
 		operators, err := ParseContentStream(xobj.Stream)
 		if err != nil {
@@ -107,7 +135,11 @@ func renderFormXObject(ctx *RenderContext, xobj *XObject) error {
 		}
 
 		for _, op := range operators {
-			if err := op.Execute(ctx); err != nil {
+			if err := executeOperator(ctx, op); err != nil {
+				if ctx.abortOnError(err) {
+					abortErr = nestedAbortError("form XObject", err)
+					break
+				}
 				// 继续执行其他操作符，不中断
 				debugPrintf("Warning: operator %s failed: %v\n", op.Name(), err)
 			}
@@ -117,16 +149,52 @@ func renderFormXObject(ctx *RenderContext, xobj *XObject) error {
 	// 恢复资源
 	ctx.Resources = oldResources
 
-	return nil
+	return abortErr
+}
+
+// groupCompositing 返回 Do 时图形状态中用于合成整个组的混合模式和填充透明度
+func groupCompositing(ctx *RenderContext) (Operator, float64) {
+	state := ctx.GetCurrentState()
+	if state == nil {
+		return OperatorOver, 1.0
+	}
+	return GetGopdfBlendMode(state.BlendMode), state.FillAlpha
+}
+
+// canRenderGroupInPlace 判断透明度组能否直接绘制到当前背景上
+// 非隔离、非敲除的组以 Normal 模式、不透明度 1 合成时，与先合成组再绘制的结果相同，
+// 直接绘制可让组内的混合模式与背景正确地相互作用
+func canRenderGroupInPlace(ctx *RenderContext, group *TransparencyGroup) bool {
+	if group.Isolated || group.Knockout {
+		return false
+	}
+	op, alpha := groupCompositing(ctx)
+	return op == OperatorOver && alpha >= 1.0
 }
 
 // renderTransparencyGroup 渲染透明度组
+// 组内容先绘制到独立的离屏表面上，再以 Do 时图形状态的混合模式和透明度作为整体合成到背景上：
+//   - 隔离组：初始背景完全透明，组内对象不会读取页面背景
+//   - 非隔离组：初始背景是当前页面内容的副本，组内的混合模式与背景相互作用；
+//     组结果已包含背景，Normal 模式下直接以组透明度合成，其他混合模式先从结果中去掉背景
+//     （见 removeGroupBackdrop），再以组的混合模式合成
+//   - 敲除组：每个绘制对象只与组的初始背景合成，覆盖（而不是叠加）组内先前的对象
 func renderTransparencyGroup(ctx *RenderContext, xobj *XObject) error {
 	group := xobj.Group
 
+	// 离屏合成需要图像表面，其他表面退化为直接绘制
+	backdrop := contextImage(ctx.GopdfCtx)
+	if backdrop == nil {
+		return renderFormXObjectInline(ctx, xobj)
+	}
+
 	debugPrintf("[TransparencyGroup] Rendering group: Isolated=%v, Knockout=%v\n",
 		group.Isolated, group.Knockout)
 
+	// 组内的 gs 操作符只影响组内对象，先记录组自身的合成参数
+	blendOp, groupAlpha := groupCompositing(ctx)
+	removeBackdrop := !group.Isolated && blendOp != OperatorOver
+
 	// 保存图形状态
 	ctx.GopdfCtx.Save()
 	ctx.GraphicsStack.Push()
@@ -134,15 +202,28 @@ func renderTransparencyGroup(ctx *RenderContext, xobj *XObject) error {
 		ctx.GopdfCtx.Restore()
 		ctx.GraphicsStack.Pop()
 	}()
+	defer ctx.enterContentStream()()
+
+	// 混合模式、透明度和软遮罩已用于合成整个组，组内容从初始值开始（PDF 32000-1 11.6.6）
+	if state := ctx.GetCurrentState(); state != nil {
+		state.SetBlendMode("Normal")
+		state.ApplyBlendMode(ctx.GopdfCtx)
+		state.SetFillAlpha(1.0)
+		state.SetStrokeAlpha(1.0)
+		state.SoftMask = nil
+	}
 
 	// 应用 XObject 的变换矩阵
 	if xobj.Matrix != nil {
 		xobj.Matrix.ApplyToGopdfContext(ctx.GopdfCtx)
 	}
 
-	// 使用 Gopdf push_group 创建隔离的合成表面
-	// 这会创建一个临时的 surface 用于渲染组内容
+	// 使用 Gopdf push_group 创建离屏合成表面
 	ctx.GopdfCtx.PushGroup()
+	groupImage := contextImage(ctx.GopdfCtx)
+	if !group.Isolated {
+		draw.Draw(groupImage, groupImage.Bounds(), backdrop, backdrop.Bounds().Min, draw.Src)
+	}
 
 	// 应用边界框裁剪
 	if len(xobj.BBox) == 4 {
@@ -157,30 +238,43 @@ func renderTransparencyGroup(ctx *RenderContext, xobj *XObject) error {
 		ctx.Resources = xobj.Resources
 	}
 
-	// 如果是 knockout 组，需要特殊处理
-	// knockout 意味着组内对象不相互混合
-	if group.Knockout {
-		debugPrintf("[TransparencyGroup] Knockout mode enabled\n")
-		// 在 knockout 模式下，每个对象都直接绘制到组 surface
-		// 而不与之前的对象混合
-		// 这需要为每个操作符创建单独的 group
-		// 当前简化实现：仍然正常渲染，但记录 knockout 状态
+	// 敲除组和需要去掉背景的非隔离组需要组的初始背景
+	var initial *image.RGBA
+	if group.Knockout || removeBackdrop {
+		debugPrintf("[TransparencyGroup] Knockout=%v, removing backdrop=%v\n", group.Knockout, removeBackdrop)
+		initial = image.NewRGBA(groupImage.Bounds())
+		copy(initial.Pix, groupImage.Pix)
 	}
 
 	// 解析并执行内容流
+	var operators []PDFOperator
 	if len(xobj.Stream) > 0 {
-		operators, err := ParseContentStream(xobj.Stream)
+		var err error
+		operators, err = ParseContentStream(xobj.Stream)
 		if err != nil {
 			ctx.GopdfCtx.PopGroupToSource() // 清理 group
 			ctx.Resources = oldResources
 			return fmt.Errorf("failed to parse transparency group content: %w", err)
 		}
+	}
 
-		for _, op := range operators {
-			if err := op.Execute(ctx); err != nil {
-				debugPrintf("Warning: operator %s failed in transparency group: %v\n", op.Name(), err)
+	var knockoutBase *image.RGBA
+	if group.Knockout {
+		knockoutBase = initial
+	}
+	abortErr := runGroupPass(ctx, operators, groupImage, knockoutBase)
+
+	// 组内的混合模式不影响 alpha，以透明背景再执行一遍组内容即可得到组自身的 alpha
+	if abortErr == nil && removeBackdrop {
+		ctx.GopdfCtx.PushGroup()
+		if shape := contextImage(ctx.GopdfCtx); shape != nil {
+			if group.Knockout {
+				knockoutBase = image.NewRGBA(shape.Bounds())
 			}
+			abortErr = runGroupPass(ctx, operators, shape, knockoutBase)
+			removeGroupBackdrop(groupImage, initial, shape)
 		}
+		ctx.GopdfCtx.PopGroup().Destroy()
 	}
 
 	// 恢复资源
@@ -189,40 +283,205 @@ func renderTransparencyGroup(ctx *RenderContext, xobj *XObject) error {
 	// 使用 Gopdf pop_group_to_source 将组内容作为源
 	ctx.GopdfCtx.PopGroupToSource()
 
-	// 应用当前图形状态的混合模式和透明度
-	state := ctx.GetCurrentState()
-	if state != nil {
-		// 应用混合模式
-		state.ApplyBlendMode(ctx.GopdfCtx)
-
-		// 应用填充透明度
-		if state.FillAlpha < 1.0 {
-			ctx.GopdfCtx.PaintWithAlpha(state.FillAlpha)
-		} else {
-			ctx.GopdfCtx.Paint()
-		}
+	// 应用组自身的混合模式和透明度
+	ctx.GopdfCtx.SetOperator(blendOp)
+	if groupAlpha < 1.0 {
+		ctx.GopdfCtx.PaintWithAlpha(groupAlpha)
 	} else {
 		ctx.GopdfCtx.Paint()
 	}
 
 	debugPrintf("[TransparencyGroup] Group rendered and composited\n")
 
+	return abortErr
+}
+
+// runGroupPass 在透明度组的表面上执行一遍组内容
+// 执行前保存图形状态、执行后恢复（包括内容中未配对的 q），使同一组内容可以从相同的初始状态再执行一遍；
+// knockoutBase 非 nil 时按敲除组合成绘制操作符
+func runGroupPass(ctx *RenderContext, operators []PDFOperator, groupImage, knockoutBase *image.RGBA) error {
+	depth := ctx.GraphicsStack.Depth()
+	ctx.GopdfCtx.Save()
+	ctx.GraphicsStack.Push()
+	defer func() {
+		for ctx.GraphicsStack.Depth() > depth {
+			ctx.GopdfCtx.Restore()
+			ctx.GraphicsStack.Pop()
+		}
+	}()
+	defer ctx.enterContentStream()()
+
+	for _, op := range operators {
+		var err error
+		if knockoutBase != nil && knockoutPaintingOps[op.Name()] {
+			err = executeKnockoutOperator(ctx, op, groupImage, knockoutBase)
+		} else {
+			err = executeOperator(ctx, op)
+		}
+		if err != nil {
+			if ctx.abortOnError(err) {
+				return nestedAbortError("transparency group", err)
+			}
+			debugPrintf("Warning: operator %s failed in transparency group: %v\n", op.Name(), err)
+		}
+	}
 	return nil
 }
 
-// renderImageXObject 渲染图像 XObject
-func renderImageXObject(ctx *RenderContext, xobj *XObject) error {
+// removeGroupBackdrop 从非隔离组的结果中去掉组的初始背景，得到组自身的颜色（PDF 32000-1 11.4.8）：
+//
+//	C = Cn + (Cn - C0) × (α0/αg - α0)
+//
+// Cn 为含背景的组结果，C0、α0 为初始背景，αg 为组自身的 alpha（取自 shape）；
+// 结果以 αg 为 alpha 按预乘分量写回 groupImage
+func removeGroupBackdrop(groupImage, backdrop, shape *image.RGBA) {
+	unpremultiply := func(v, a uint8) float64 {
+		if a == 0 {
+			return 0
+		}
+		return float64(v) / float64(a)
+	}
+	for i := 0; i+3 < len(groupImage.Pix); i += 4 {
+		pix := groupImage.Pix[i : i+4 : i+4]
+		ag := shape.Pix[i+3]
+		if ag == 0 {
+			pix[0], pix[1], pix[2], pix[3] = 0, 0, 0, 0
+			continue
+		}
+		g := float64(ag) / 255
+		a0 := float64(backdrop.Pix[i+3]) / 255
+		k := a0/g - a0
+		for c := 0; c < 3; c++ {
+			cn := unpremultiply(pix[c], pix[3])
+			c0 := unpremultiply(backdrop.Pix[i+c], backdrop.Pix[i+3])
+			v := math.Min(math.Max(cn+(cn-c0)*k, 0), 1)
+			pix[c] = uint8(math.Round(v * g * 255))
+		}
+		pix[3] = ag
+	}
+}
+
+// knockoutPaintingOps 需要单独合成的绘制操作符（敲除组和软遮罩）
+var knockoutPaintingOps = map[string]bool{
+	"S": true, "s": true, "f": true, "f*": true, "B": true, "b": true,
+	"sh": true, "Do": true, "ID": true,
+	"Tj": true, "TJ": true, "'": true, "\"": true,
+}
+
+// executeKnockoutOperator 把单个绘制操作符渲染到透明的临时表面，
+// 然后在其覆盖的像素上用“对象覆盖初始背景”的结果替换组内已有内容
+// 只处理对象实际绘制到的设备区域，直接读写预乘的像素缓冲
+func executeKnockoutOperator(ctx *RenderContext, op PDFOperator, groupImage, initial *image.RGBA) error {
+	ctx.GopdfCtx.PushGroup()
+	err := op.Execute(ctx)
+	objImage := contextImage(ctx.GopdfCtx)
+	var area image.Rectangle
+	if objImage != nil {
+		area = paintedBounds(ctx.GopdfCtx, objImage).Intersect(groupImage.Bounds())
+	}
+	pattern := ctx.GopdfCtx.PopGroup()
+	defer pattern.Destroy()
+	if objImage == nil {
+		return err
+	}
+
+	// 预乘分量的 source-over：out = src + initial * (1 - srcA)
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		i := groupImage.PixOffset(area.Min.X, y)
+		for x := area.Min.X; x < area.Max.X; x, i = x+1, i+4 {
+			src := objImage.Pix[i : i+4 : i+4]
+			if src[3] == 0 {
+				continue
+			}
+			dst := groupImage.Pix[i : i+4 : i+4]
+			base := initial.Pix[i : i+4 : i+4]
+			rest := 255 - uint32(src[3])
+			for c := 0; c < 4; c++ {
+				dst[c] = uint8(uint32(src[c]) + (uint32(base[c])*rest+127)/255)
+			}
+		}
+	}
+	return err
+}
+
+// paintedBounds 返回上下文当前目标上已绘制像素的设备空间包围盒
+// 刚压入的组表面据此只需处理被绘制的区域；无法确定时返回 img 的整个范围
+func paintedBounds(c Context, img *image.RGBA) image.Rectangle {
+	if gc, ok := c.(*context); ok && gc.gc != nil && gc.gc.img == img {
+		return gc.gc.painted.Intersect(img.Bounds())
+	}
+	return img.Bounds()
+}
+
+// contextImage 返回上下文当前目标表面的像素缓冲，非图像表面返回 nil
+func contextImage(c Context) *image.RGBA {
+	imgSurface, ok := c.GetTarget().(ImageSurface)
+	if !ok {
+		return nil
+	}
+	img, _ := imgSurface.GetGoImage().(*image.RGBA)
+	return img
+}
+
+// xobjectCacheKey 返回图像 XObject 在 XObjectCache 中的键
+// 按间接对象号区分，同一图像经不同资源名或不同表单引用时共用一个条目；
+// 模板掩码的颜色来自当前填充颜色，键中还包含填充颜色，避免以其它颜色绘制时复用旧表面；
+// 没有对象号（直接对象、内联图像）时返回空字符串，不缓存
+func xobjectCacheKey(ctx *RenderContext, xobj *XObject) string {
+	if xobj.ObjectNumber <= 0 {
+		return ""
+	}
+	key := fmt.Sprintf("%d %d R", xobj.ObjectNumber, xobj.Generation)
+	if xobj.ImageMask {
+		fill := &Color{}
+		if state := ctx.GetCurrentState(); state != nil && state.FillColor != nil {
+			fill = state.FillColor
+		}
+		key += fmt.Sprintf(" mask %g %g %g", fill.R, fill.G, fill.B)
+	}
+	return key
+}
+
+// imageXObjectSurface 把图像 XObject 解码并转换为预乘 ARGB32 表面
+// 结果在当前页面的 XObjectCache 中缓存，重复 Do 同一图像时直接复用；
+// 缓存中的表面归 RenderContext 所有，由 releaseXObjectCache 释放
+func imageXObjectSurface(ctx *RenderContext, xobj *XObject) (Surface, int, int, error) {
+	key := xobjectCacheKey(ctx, xobj)
+	if cached, ok := ctx.XObjectCache[key]; ok && key != "" {
+		if imgSurface, ok := cached.(ImageSurface); ok {
+			debugPrintf("[renderImageXObject] Reusing decoded image %s\n", key)
+			return cached, imgSurface.GetWidth(), imgSurface.GetHeight(), nil
+		}
+	}
+
+	if xobj.ImageMask {
+		// 模板掩码的颜色来自当前填充颜色，每次绘制时重新生成
+		fill := &Color{A: 1}
+		if state := ctx.GetCurrentState(); state != nil && state.FillColor != nil {
+			fill = state.FillColor
+		}
+		imgData, err := stencilMaskImage(xobj, fill)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to decode image mask: %w", err)
+		}
+		xobj.ImageData = imgData
+	}
+
 	if xobj.ImageData == nil {
 		// 尝试解码图像数据
+		xobj.ICCTransform = xobj.ICCTransform || ctx.ICCTransform
+		if xobj.CMYKConversion == CMYKDefault {
+			xobj.CMYKConversion = ctx.CMYKConversion
+		}
 		imgData, err := decodeImageXObject(xobj)
 		if err != nil {
-			return fmt.Errorf("failed to decode image: %w", err)
+			return nil, 0, 0, fmt.Errorf("failed to decode image: %w", err)
 		}
 		xobj.ImageData = imgData
 	}
 
 	if xobj.ImageData == nil {
-		return fmt.Errorf("no image data available")
+		return nil, 0, 0, fmt.Errorf("no image data available")
 	}
 
 	// 创建 Gopdf image surface
@@ -306,7 +565,6 @@ func renderImageXObject(ctx *RenderContext, xobj *XObject) error {
 
 	// 使用 ARGB32 格式以支持透明度
 	imgSurface := NewImageSurface(FormatARGB32, width, height)
-	defer imgSurface.Destroy()
 
 	// 手动填充数据
 	if gopdfImg, ok := imgSurface.(ImageSurface); ok {
@@ -345,6 +603,67 @@ func renderImageXObject(ctx *RenderContext, xobj *XObject) error {
 		gopdfImg.MarkDirty()
 	}
 
+	if key != "" && ctx.XObjectCache != nil {
+		ctx.XObjectCache[key] = imgSurface
+	}
+	return imgSurface, width, height, nil
+}
+
+// stencilMaskImage 把模板掩码展开为 RGBA 图像：绘制区域为填充颜色，其余区域透明
+// 默认 /Decode [0 1] 时样本 0 标记绘制区域，DecodeInverted 时样本 1 标记绘制区域；每行按字节对齐
+func stencilMaskImage(xobj *XObject, fill *Color) (*image.RGBA, error) {
+	width, height := xobj.Width, xobj.Height
+	rowBytes := (width + 7) / 8
+	if width <= 0 || height <= 0 || len(xobj.Stream) < rowBytes*height {
+		return nil, fmt.Errorf("image mask data too short: %d bytes for %dx%d", len(xobj.Stream), width, height)
+	}
+
+	paint := color.RGBA{
+		R: uint8(clamp01(fill.R)*255 + 0.5),
+		G: uint8(clamp01(fill.G)*255 + 0.5),
+		B: uint8(clamp01(fill.B)*255 + 0.5),
+		A: 255,
+	}
+	var paintBit byte
+	if xobj.DecodeInverted {
+		paintBit = 1
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := xobj.Stream[y*rowBytes:]
+		for x := 0; x < width; x++ {
+			if (row[x/8]>>(7-uint(x%8)))&1 == paintBit {
+				img.SetRGBA(x, y, paint)
+			}
+		}
+	}
+	return img, nil
+}
+
+// releaseXObjectCache 释放页面渲染期间缓存的图像表面
+func (rc *RenderContext) releaseXObjectCache() {
+	for key, surface := range rc.XObjectCache {
+		surface.Destroy()
+		delete(rc.XObjectCache, key)
+	}
+}
+
+// renderImageXObject 渲染图像 XObject
+func renderImageXObject(ctx *RenderContext, xobj *XObject) error {
+	if !ctx.shouldRender(ContentImages) {
+		return nil
+	}
+
+	imgSurface, width, height, err := imageXObjectSurface(ctx, xobj)
+	if err != nil {
+		return err
+	}
+	// 只有按对象号缓存的表面归 XObjectCache 所有；内联图像和直接对象的表面用完即释放
+	if xobjectCacheKey(ctx, xobj) == "" || ctx.XObjectCache == nil {
+		defer imgSurface.Destroy()
+	}
+
 	debugPrintf("[renderImageXObject] Applying transformations\n")
 
 	// 获取当前图形状态
@@ -464,12 +783,14 @@ func renderImageXObject(ctx *RenderContext, xobj *XObject) error {
 	ctx.GopdfCtx.SetSourceSurface(imgSurface, 0, 0)
 	debugPrintf("[renderImageXObject] Set source surface\n")
 
-	// 设置过滤器
+	// 设置过滤器：/Interpolate true 时双线性插值，否则按 PDF 默认行为取最近邻采样
+	filter := FilterNearest
+	if xobj.Interpolate {
+		filter = FilterBilinear
+	}
 	pattern := ctx.GopdfCtx.GetSource()
-	pattern.SetFilter(FilterBest)
-
-	// 🔍 调试：检查 pattern 的矩阵
-	debugPrintf("[renderImageXObject] Pattern filter set to Best\n")
+	pattern.SetFilter(filter)
+	debugPrintf("[renderImageXObject] Pattern filter set to %d (Interpolate=%v)\n", filter, xobj.Interpolate)
 
 	debugPrintf("[renderImageXObject] Painting image\n")
 
@@ -530,25 +851,19 @@ func (op *OpBeginInlineImage) Execute(ctx *RenderContext) error {
 }
 
 // OpInlineImageData ID - 内联图像数据
+// ImageDict 为 BI 与 ID 之间的图像字典（键可以是缩写形式），ImageData 为 ID 与 EI 之间的原始数据
 type OpInlineImageData struct {
-	ImageData        []byte
-	Width            int
-	Height           int
-	ColorSpace       string
-	BitsPerComponent int
+	ImageDict map[string]interface{}
+	ImageData []byte
 }
 
 func (op *OpInlineImageData) Name() string { return "ID" }
 
 func (op *OpInlineImageData) Execute(ctx *RenderContext) error {
-	// 创建临时 XObject 并渲染
-	xobj := &XObject{
-		Subtype:          "Image",
-		Width:            op.Width,
-		Height:           op.Height,
-		ColorSpace:       op.ColorSpace,
-		BitsPerComponent: op.BitsPerComponent,
-		Stream:           op.ImageData,
+	// 创建等价的图像 XObject 并按普通图像渲染
+	xobj, err := NewInlineImageXObject(op.ImageDict, op.ImageData, ctx.Resources)
+	if err != nil {
+		return fmt.Errorf("failed to load inline image: %w", err)
 	}
 
 	return renderImageXObject(ctx, xobj)
@@ -1,6 +1,10 @@
 package test
 
 import (
+	"errors"
+	"math"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/novvoo/go-pdf/pkg/gopdf"
@@ -173,3 +177,570 @@ func TestExtractFromCorruptedPDF(t *testing.T) {
 		t.Logf("Expected error occurred: %v", err)
 	}
 }
+
+// TestExtractOrderedTextColumnsAndRotation 测试两栏页面和旋转图注的阅读顺序
+func TestExtractOrderedTextColumnsAndRotation(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "two_columns.pdf")
+
+	// 内容流按行交错输出左右两栏，简单的"先 Y 后 X"排序会把两栏混在一起；
+	// 页面右侧有一段旋转 90 度的图注，由两次 Tj 组成
+	stream := strings.Join([]string{
+		"BT /F1 12 Tf",
+		"1 0 0 1 50 700 Tm (Left one) Tj",
+		"1 0 0 1 320 700 Tm (Right one) Tj",
+		"1 0 0 1 50 686 Tm (Left two) Tj",
+		"1 0 0 1 320 686 Tm (Right two) Tj",
+		"1 0 0 1 50 672 Tm (Left three) Tj",
+		"1 0 0 1 320 672 Tm (Right three) Tj",
+		"0 1 -1 0 580 400 Tm (Rotated ) Tj (caption) Tj",
+		"ET",
+	}, "\n")
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 612 792] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> >>",
+		pdfStreamObject("", stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	blocks, err := reader.ExtractTextBlocks(1)
+	helper.AssertNoError(err, "ExtractTextBlocks failed")
+
+	want := []string{
+		"Left one\nLeft two\nLeft three",
+		"Right one\nRight two\nRight three",
+		"Rotated caption",
+	}
+	if len(blocks) != len(want) {
+		for i := range blocks {
+			t.Logf("block %d: %q", i, blocks[i].Text())
+		}
+		t.Fatalf("expected %d blocks, got %d", len(want), len(blocks))
+	}
+	for i, w := range want {
+		if got := blocks[i].Text(); got != w {
+			t.Errorf("block %d = %q, want %q", i, got, w)
+		}
+	}
+	if blocks[2].Angle != 90 {
+		t.Errorf("caption angle = %v, want 90", blocks[2].Angle)
+	}
+
+	text, err := reader.ExtractOrderedText(1)
+	helper.AssertNoError(err, "ExtractOrderedText failed")
+	if text != strings.Join(want, "\n\n") {
+		t.Errorf("unexpected ordered text:\n%s", text)
+	}
+
+	if _, err := reader.ExtractOrderedText(2); err == nil {
+		t.Error("expected error for out-of-range page")
+	}
+}
+
+func TestExtractPageTextRuns(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "text_runs.pdf")
+
+	// A/B/C 宽 600/500/700；TJ 中 -200 使 C 右移 2pt；第二段文本旋转 90 度
+	stream := strings.Join([]string{
+		"BT /F1 10 Tf",
+		"1 0 0 1 100 700 Tm [(AB) -200 (C)] TJ",
+		"0 1 -1 0 300 400 Tm (A) Tj",
+		"ET",
+	}, "\n")
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 612 792] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> >>",
+		pdfStreamObject("", stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /FirstChar 65 /LastChar 67 /Widths [600 500 700] >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	runs, err := reader.ExtractPageTextRuns(1)
+	helper.AssertNoError(err, "ExtractPageTextRuns failed")
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 text runs, got %d", len(runs))
+	}
+	if runs[0].Text != "ABC" || runs[0].FontName != "F1" || runs[0].FontSize != 10 {
+		t.Errorf("unexpected first run: %q font %s size %v", runs[0].Text, runs[0].FontName, runs[0].FontSize)
+	}
+
+	// 包围盒左上角坐标（Y 轴向下），高度为 1 em
+	want := []gopdf.GlyphBox{
+		{Rune: 'A', X: 100, Y: 84, Width: 6, Height: 10},
+		{Rune: 'B', X: 106, Y: 84, Width: 5, Height: 10},
+		{Rune: 'C', X: 113, Y: 84, Width: 7, Height: 10},
+	}
+	wantRotated := gopdf.GlyphBox{Rune: 'A', X: 292, Y: 386, Width: 10, Height: 6}
+
+	check := func(name string, got, want gopdf.GlyphBox) {
+		t.Helper()
+		near := func(a, b float64) bool { return a-b < 1e-6 && b-a < 1e-6 }
+		if got.Rune != want.Rune || !near(got.X, want.X) || !near(got.Y, want.Y) ||
+			!near(got.Width, want.Width) || !near(got.Height, want.Height) {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+	if len(runs[0].Glyphs) != len(want) {
+		t.Fatalf("expected %d glyph boxes, got %d", len(want), len(runs[0].Glyphs))
+	}
+	for i := range want {
+		check(string(want[i].Rune), runs[0].Glyphs[i], want[i])
+	}
+	if len(runs[1].Glyphs) != 1 {
+		t.Fatalf("expected 1 glyph box in rotated run, got %d", len(runs[1].Glyphs))
+	}
+	check("rotated A", runs[1].Glyphs[0], wantRotated)
+
+	if _, err := reader.ExtractPageTextRuns(2); err == nil {
+		t.Error("expected error for out-of-range page")
+	}
+}
+
+func TestExtractPageElementsTextState(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "text_state.pdf")
+
+	// 空格宽 250，A/B/C 宽 600/500/700
+	widths := "250" + strings.Repeat(" 500", 32) + " 600 500 700"
+	stream := strings.Join([]string{
+		"BT /F1 10 Tf",
+		"2 Tc 50 Tz 1 0 0 1 100 700 Tm (AB) Tj (C) Tj",
+		"0 Tc 100 Tz 3 Tw 1 0 0 1 100 600 Tm (A A) Tj (B) Tj",
+		"0 Tw 5 Ts 1 0 0 1 100 500 Tm (C) Tj",
+		"ET",
+	}, "\n")
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 612 792] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> >>",
+		pdfStreamObject("", stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /FirstChar 32 /LastChar 67 /Widths ["+widths+"] >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	texts, _ := reader.ExtractPageElements(1)
+	if len(texts) != 5 {
+		t.Fatalf("expected 5 text elements, got %d", len(texts))
+	}
+
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+	want := []struct {
+		text        string
+		x, y, width float64
+	}{
+		{"AB", 100, 92, 7.5},    // (6+2 + 5+2) × 50%
+		{"C", 107.5, 92, 4.5},   // (7+2) × 50%
+		{"A A", 100, 192, 17.5}, // 6 + 2.5+3 + 6
+		{"B", 117.5, 192, 5},
+		{"C", 100, 287, 7}, // 基线上移 5pt
+	}
+	for i, w := range want {
+		got := texts[i]
+		if got.Text != w.text || !near(got.X, w.x) || !near(got.Y, w.y) || !near(got.Width, w.width) {
+			t.Errorf("element %d: got %q at (%.2f, %.2f) width %.2f, want %q at (%.2f, %.2f) width %.2f",
+				i, got.Text, got.X, got.Y, got.Width, w.text, w.x, w.y, w.width)
+		}
+	}
+
+	runs, err := reader.ExtractPageTextRuns(1)
+	helper.AssertNoError(err, "ExtractPageTextRuns failed")
+	if len(runs) != 5 {
+		t.Fatalf("expected 5 text runs, got %d", len(runs))
+	}
+	// 水平缩放压缩字形宽度，字符间距计入推进距离
+	if g := runs[0].Glyphs[1]; !near(g.X, 104) || !near(g.Width, 2.5) {
+		t.Errorf("scaled B: got %+v, want X=104 Width=2.5", g)
+	}
+	// 单词间距只加在空格之后
+	if g := runs[2].Glyphs[2]; !near(g.X, 111.5) {
+		t.Errorf("word-spaced A: got %+v, want X=111.5", g)
+	}
+	// 文本上升抬高包围盒
+	if g := runs[4].Glyphs[0]; !near(g.Y, 279) || !near(g.Height, 10) {
+		t.Errorf("raised C: got %+v, want Y=279 Height=10", g)
+	}
+}
+
+func TestExtractPageElementsLineAdvance(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "line_advance.pdf")
+
+	// 第一段：TL + T* 与 '；第二段：TD 设置行距，" 同时设置间距；
+	// 第三段：文本矩阵 2 倍缩放，T* 的行距也随之缩放
+	stream := strings.Join([]string{
+		"BT /F1 10 Tf 14 TL",
+		"1 0 0 1 50 700 Tm (Line1) Tj T* (Line2) Tj (Line3) '",
+		"1 0 0 1 300 700 Tm (A) Tj 0 -20 TD (B) Tj 1 2 (C) \"",
+		"2 0 0 2 50 400 Tm 14 TL (D) Tj T* (E) Tj",
+		"ET",
+	}, "\n")
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 612 792] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> >>",
+		pdfStreamObject("", stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	texts, _ := gopdf.NewPDFReader(pdfPath).ExtractPageElements(1)
+	want := []struct {
+		text string
+		x, y float64
+	}{
+		{"Line1", 50, 92},
+		{"Line2", 50, 106},
+		{"Line3", 50, 120},
+		{"A", 300, 92},
+		{"B", 300, 112},
+		{"C", 300, 132},
+		{"D", 50, 392},
+		{"E", 50, 420},
+	}
+	if len(texts) != len(want) {
+		t.Fatalf("expected %d text elements, got %d", len(want), len(texts))
+	}
+	for i, w := range want {
+		got := texts[i]
+		if got.Text != w.text || math.Abs(got.X-w.x) > 1e-6 || math.Abs(got.Y-w.y) > 1e-6 {
+			t.Errorf("element %d: got %q at (%.2f, %.2f), want %q at (%.2f, %.2f)",
+				i, got.Text, got.X, got.Y, w.text, w.x, w.y)
+		}
+	}
+}
+
+func TestExtractPageTextOrdered(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "reading_order.pdf")
+
+	// 内容流顺序：脚注先于正文，同一行中右侧的词先于左侧的词
+	stream := strings.Join([]string{
+		"BT /F1 12 Tf",
+		"1 0 0 1 100 100 Tm (Footnote) Tj",
+		"1 0 0 1 150 700 Tm (World) Tj",
+		"1 0 0 1 100 700 Tm (Hello) Tj",
+		"1 0 0 1 100 686 Tm (Second) Tj",
+		"1 0 0 1 140 686.5 Tm (line) Tj",
+		"ET",
+	}, "\n")
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 612 792] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> >>",
+		pdfStreamObject("", stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /FirstChar 32 /LastChar 126 /Widths ["+
+			strings.Repeat("500 ", 95)+"] >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	text, err := reader.ExtractPageTextOrdered(1)
+	helper.AssertNoError(err, "ExtractPageTextOrdered failed")
+
+	want := "Hello World\nSecond line\n\nFootnote"
+	if text != want {
+		t.Errorf("ordered text = %q, want %q", text, want)
+	}
+
+	if _, err := reader.ExtractPageTextOrdered(2); err == nil {
+		t.Error("expected error for out-of-range page")
+	}
+}
+
+func TestExtractPageTextOrderedDoesNotSplitColumns(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "two_columns.pdf")
+
+	// 两栏，每栏两行；内容流中先写完右栏
+	stream := strings.Join([]string{
+		"BT /F1 12 Tf",
+		"1 0 0 1 350 700 Tm (Right1) Tj",
+		"1 0 0 1 350 686 Tm (Right2) Tj",
+		"1 0 0 1 72 700 Tm (Left1) Tj",
+		"1 0 0 1 72 686 Tm (Left2) Tj",
+		"ET",
+	}, "\n")
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 612 792] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> >>",
+		pdfStreamObject("", stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /FirstChar 32 /LastChar 126 /Widths ["+
+			strings.Repeat("500 ", 95)+"] >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	text, err := reader.ExtractPageTextOrdered(1)
+	helper.AssertNoError(err, "ExtractPageTextOrdered failed")
+	if want := "Left1 Right1\nLeft2 Right2"; text != want {
+		t.Errorf("ordered text = %q, want %q", text, want)
+	}
+
+	// ExtractOrderedText 按栏输出
+	columns, err := reader.ExtractOrderedText(1)
+	helper.AssertNoError(err, "ExtractOrderedText failed")
+	if want := "Left1\nLeft2\n\nRight1\nRight2"; columns != want {
+		t.Errorf("column text = %q, want %q", columns, want)
+	}
+}
+
+func TestExtractLiteralStringLookingLikeHex(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "literal_hex.pdf")
+
+	// 字面量字符串 (<41>) 是四个单字节字符，不能当作十六进制字符串解码
+	stream := "BT /F1 10 Tf 1 0 0 1 100 700 Tm (<41>) Tj 1 0 0 1 100 600 Tm [(<42>)] TJ ET"
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 612 792] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> >>",
+		pdfStreamObject("", stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /FirstChar 32 /LastChar 126 /Widths ["+
+			strings.Repeat("500 ", 95)+"] >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	runs, err := reader.ExtractPageTextRuns(1)
+	helper.AssertNoError(err, "ExtractPageTextRuns failed")
+	var runTexts []string
+	for _, run := range runs {
+		runTexts = append(runTexts, run.Text)
+		if len(run.Glyphs) != 4 {
+			t.Errorf("run %q has %d glyphs, want 4", run.Text, len(run.Glyphs))
+		}
+	}
+	if got := strings.Join(runTexts, "|"); got != "<41>|<42>" {
+		t.Errorf("run texts = %q, want %q", got, "<41>|<42>")
+	}
+
+	texts, _ := reader.ExtractPageElements(1)
+	var elementTexts []string
+	for _, te := range texts {
+		elementTexts = append(elementTexts, te.Text)
+	}
+	if got := strings.Join(elementTexts, "|"); got != "<41>|<42>" {
+		t.Errorf("text elements = %q, want %q", got, "<41>|<42>")
+	}
+}
+
+func TestSearchText(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "search.pdf")
+
+	// 第一行由三次 Tj 拼接而成；所有字符宽 500，字号 10 时每个字符宽 5pt
+	stream := strings.Join([]string{
+		"BT /F1 10 Tf",
+		"1 0 0 1 100 700 Tm (Hel) Tj (lo) Tj ( World) Tj",
+		"1 0 0 1 100 680 Tm (hello again) Tj",
+		"ET",
+	}, "\n")
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 612 792] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> >>",
+		pdfStreamObject("", stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /FirstChar 32 /LastChar 126 /Widths ["+
+			strings.Repeat("500 ", 95)+"] >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	near := func(a, b float64) bool { return a-b < 1e-6 && b-a < 1e-6 }
+	sameRect := func(got, want gopdf.Rectangle) bool {
+		return near(got.X, want.X) && near(got.Y, want.Y) && near(got.Width, want.Width) && near(got.Height, want.Height)
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		caseInsensitive bool
+		want            []gopdf.Rectangle
+	}{
+		{"spans Tj boundaries", "hello world", true, []gopdf.Rectangle{{X: 100, Y: 84, Width: 55, Height: 10}}},
+		{"case sensitive", "Hello", false, []gopdf.Rectangle{{X: 100, Y: 84, Width: 25, Height: 10}}},
+		{"case insensitive", "HELLO", true, []gopdf.Rectangle{
+			{X: 100, Y: 84, Width: 25, Height: 10},
+			{X: 100, Y: 104, Width: 25, Height: 10},
+		}},
+		{"spans lines", "world  hello", true, []gopdf.Rectangle{
+			{X: 130, Y: 84, Width: 25, Height: 10},
+			{X: 100, Y: 104, Width: 25, Height: 10},
+		}},
+		{"no match", "missing", true, nil},
+	}
+	for _, tt := range tests {
+		rects, err := reader.SearchText(1, tt.query, tt.caseInsensitive)
+		helper.AssertNoError(err, "SearchText failed")
+		if len(rects) != len(tt.want) {
+			t.Errorf("%s: got %d rects %+v, want %d", tt.name, len(rects), rects, len(tt.want))
+			continue
+		}
+		for i := range rects {
+			if !sameRect(rects[i], tt.want[i]) {
+				t.Errorf("%s: rect %d = %+v, want %+v", tt.name, i, rects[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestIterateOperators(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "iterate.pdf")
+
+	stream := strings.Join([]string{
+		"q 2 0 0 2 10 20 cm 1 0 0 rg",
+		"BT /F1 12 Tf 5 TL 3 Tc (AB) Tj T* (C) Tj ET",
+		"Q 0 0 1 RG",
+	}, "\n")
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 612 792] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> >>",
+		pdfStreamObject("", stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	var names []string
+	snapshots := map[string]gopdf.GraphicsSnapshot{}
+	err = reader.IterateOperators(1, func(op gopdf.PDFOperator, state *gopdf.GraphicsSnapshot) error {
+		names = append(names, op.Name())
+		if show, ok := op.(*gopdf.OpShowText); ok {
+			snapshots[show.Text] = *state
+		}
+		if op.Name() == "RG" {
+			snapshots["RG"] = *state
+		}
+		return nil
+	})
+	helper.AssertNoError(err, "IterateOperators failed")
+
+	wantNames := "q cm rg BT Tf TL Tc Tj T* Tj ET Q RG"
+	if got := strings.Join(names, " "); got != wantNames {
+		t.Errorf("operators = %q, want %q", got, wantNames)
+	}
+
+	// 快照是操作符执行前的状态
+	ab := snapshots["AB"]
+	if ab.CTM.XX != 2 || ab.CTM.X0 != 10 || ab.CTM.Y0 != 20 {
+		t.Errorf("CTM at (AB) = %s, want [2 0 0 2 10 20]", ab.CTM.String())
+	}
+	if ab.FontName != "F1" || ab.FontSize != 12 || ab.CharSpacing != 3 || ab.Leading != 5 || !ab.InText {
+		t.Errorf("text state at (AB) = %+v", ab)
+	}
+	if ab.FillColor != [3]float64{1, 0, 0} {
+		t.Errorf("fill color at (AB) = %v, want red", ab.FillColor)
+	}
+
+	// T* 换到下一行，X 回到行首
+	c := snapshots["C"]
+	if c.TextMatrix.X0 != 0 || c.TextMatrix.Y0 != -5 {
+		t.Errorf("text matrix at (C) = %s, want translation (0, -5)", c.TextMatrix.String())
+	}
+
+	// Q 恢复 q 之前的 CTM 和颜色
+	rg := snapshots["RG"]
+	if rg.CTM.XX != 1 || rg.CTM.X0 != 0 || rg.FillColor != [3]float64{} || rg.InText {
+		t.Errorf("state after Q = %+v, want restored defaults", rg)
+	}
+
+	// 回调返回错误时停止遍历
+	stop := errors.New("stop")
+	count := 0
+	err = reader.IterateOperators(1, func(op gopdf.PDFOperator, state *gopdf.GraphicsSnapshot) error {
+		count++
+		if op.Name() == "BT" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || count != 4 {
+		t.Errorf("early stop: err = %v after %d operators, want stop after 4", err, count)
+	}
+}
+
+// TestExtractFontInfoDescriptor 测试字体信息中的嵌入、子集标志和 FontDescriptor 度量
+func TestExtractFontInfoDescriptor(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "fonts.pdf")
+
+	// F1：子集 TrueType，带 FontFile2；F2：Type0 字体，FontDescriptor 在 DescendantFonts 中且未嵌入
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 200 100] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R /F2 8 0 R >> >>",
+		pdfStreamObject("", "BT /F1 12 Tf 10 50 Td (A) Tj ET\n"),
+		"<< /Type /Font /Subtype /TrueType /BaseFont /ABCDEF+Georgia-Italic /FirstChar 65 /LastChar 65 "+
+			"/Widths [600] /FontDescriptor 6 0 R >>",
+		"<< /Type /FontDescriptor /FontName /ABCDEF+Georgia-Italic /Flags 98 /ItalicAngle -12.5 /StemV 88 "+
+			"/FontBBox [0 0 1000 1000] /Ascent 900 /Descent -200 /CapHeight 700 /FontFile2 7 0 R >>",
+		pdfStreamObject("/Length1 23 ", "not a real font program"),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /SimSun /Encoding /Identity-H /DescendantFonts [9 0 R] >>",
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /SimSun "+
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor 10 0 R >>",
+		"<< /Type /FontDescriptor /FontName /SimSun /Flags 5 /ItalicAngle 0 /StemV 50 "+
+			"/FontBBox [0 0 1000 1000] /Ascent 880 /Descent -120 /CapHeight 700 >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	infos := map[string]gopdf.FontInfo{}
+	for _, info := range gopdf.NewPDFReader(pdfPath).ExtractFontInfo(1) {
+		infos[info.Name] = info
+	}
+
+	f1, ok := infos["F1"]
+	if !ok {
+		t.Fatalf("font F1 not found in %v", infos)
+	}
+	if !f1.IsEmbedded || !f1.IsSubset {
+		t.Errorf("F1 IsEmbedded=%v IsSubset=%v, want both true", f1.IsEmbedded, f1.IsSubset)
+	}
+	if f1.Flags != 98 || f1.Flags&gopdf.FontFlagSerif == 0 || f1.Flags&gopdf.FontFlagItalic == 0 {
+		t.Errorf("F1 Flags = %d, want 98 (serif, nonsymbolic, italic)", f1.Flags)
+	}
+	if f1.ItalicAngle != -12.5 || f1.StemV != 88 {
+		t.Errorf("F1 ItalicAngle=%v StemV=%v, want -12.5 and 88", f1.ItalicAngle, f1.StemV)
+	}
+
+	f2, ok := infos["F2"]
+	if !ok {
+		t.Fatalf("font F2 not found in %v", infos)
+	}
+	if f2.IsEmbedded || f2.IsSubset {
+		t.Errorf("F2 IsEmbedded=%v IsSubset=%v, want both false", f2.IsEmbedded, f2.IsSubset)
+	}
+	if f2.Flags&gopdf.FontFlagSymbolic == 0 || f2.Flags&gopdf.FontFlagFixedPitch == 0 || f2.StemV != 50 {
+		t.Errorf("F2 Flags=%d StemV=%v, want symbolic fixed-pitch with StemV 50", f2.Flags, f2.StemV)
+	}
+}
+
+// TestExtractPageElementsCropBoxOffset 测试 CropBox 不从 (0,0) 开始时，提取的坐标相对于可见区域的左上角
+func TestExtractPageElementsCropBoxOffset(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "cropbox_offset.pdf")
+
+	// 可见区域 (50,100)-(250,250)，即 200x150；文本基线在 (60,200)，图像占据 (100,130)-(150,150)
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 300 300] /CropBox [50 100 250 250] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> /XObject << /Im1 6 0 R >> >>",
+		pdfStreamObject("", "BT /F1 10 Tf 1 0 0 1 60 200 Tm (Hi) Tj ET\nq 50 0 0 20 100 130 cm /Im1 Do Q"),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /FirstChar 72 /LastChar 105 /Widths ["+
+			strings.Repeat("500 ", 34)+"] >>",
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8 ", "\xff\x00\x00"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	textElements, images := reader.ExtractPageElements(1)
+	if len(textElements) != 1 || len(images) != 1 {
+		t.Fatalf("got %d text elements and %d images, want 1 and 1", len(textElements), len(images))
+	}
+	if te := textElements[0]; te.X != 10 || te.Y != 50 {
+		t.Errorf("text at (%.2f, %.2f), want (10, 50)", te.X, te.Y)
+	}
+	if img := images[0]; img.X != 50 || img.Y != 100 || img.Width != 50 || img.Height != 20 {
+		t.Errorf("image at (%.2f, %.2f) %.2fx%.2f, want (50, 100) 50x20", img.X, img.Y, img.Width, img.Height)
+	}
+
+	rects, err := reader.SearchText(1, "Hi", false)
+	helper.AssertNoError(err, "SearchText failed")
+	if len(rects) != 1 || rects[0].X != 10 || rects[0].Y != 42 {
+		t.Errorf("SearchText rects = %+v, want one at (10, 42)", rects)
+	}
+
+	// 命中测试使用与渲染图像一致的像素坐标
+	hit, err := reader.HitTest(1, 75, 110, 72)
+	helper.AssertNoError(err, "HitTest failed")
+	if hit.Image == nil || hit.Image.Name != "Im1" {
+		t.Errorf("HitTest(75, 110) image = %+v, want Im1", hit.Image)
+	}
+}
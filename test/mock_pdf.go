@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // MockPDFGenerator 用于生成测试用的 PDF 文件
@@ -560,6 +561,91 @@ startxref
 	return pdfPath, os.WriteFile(pdfPath, []byte(content), 0644)
 }
 
+// GenerateRotatedPDF 生成带 /Rotate 的单页 PDF
+// 页面左下角绘制一个 50x50 的红色方块，用于校验旋转方向
+func (m *MockPDFGenerator) GenerateRotatedPDF(width, height float64, rotate int) (string, error) {
+	return m.GenerateSinglePagePDF(
+		fmt.Sprintf("rotated_%.0fx%.0f_%d.pdf", width, height, rotate),
+		fmt.Sprintf("/MediaBox [0 0 %.2f %.2f] /Rotate %d", width, height, rotate),
+		"1 0 0 rg\n0 0 50 50 re\nf\n",
+	)
+}
+
+// GenerateSinglePagePDF 生成单页 PDF
+// pageAttrs 为页面字典的附加条目（如 MediaBox、Resources），stream 为页面内容流
+func (m *MockPDFGenerator) GenerateSinglePagePDF(name, pageAttrs, stream string) (string, error) {
+	pdfPath := filepath.Join(m.tempDir, name)
+
+	return pdfPath, writeSinglePagePDF(pdfPath, pageAttrs+" /Contents 4 0 R", pdfStreamObject("", stream))
+}
+
+// GenerateLayeredContentPDF 生成同时包含图像、矢量图形和文本的 300x100 单页 PDF
+// 左侧 (10,10)-(90,90) 为绿色图像，中间 (110,10)-(190,90) 为红色矩形，右侧为黑色文本
+func (m *MockPDFGenerator) GenerateLayeredContentPDF() (string, error) {
+	pdfPath := filepath.Join(m.tempDir, "layered_content.pdf")
+
+	// 2x2 绿色 DeviceRGB 图像
+	imageData := strings.Repeat("\x00\xff\x00", 4)
+	stream := "q\n80 0 0 80 10 10 cm\n/Im1 Do\nQ\n" +
+		"1 0 0 rg\n110 10 80 80 re\nf\n" +
+		"0 0 0 rg\nBT\n/F1 60 Tf\n210 30 Td\n(MW) Tj\nET\n"
+
+	return pdfPath, writeSinglePagePDF(pdfPath, "/MediaBox [0 0 300 100] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 << /Type /Font /Subtype /Type1 /BaseFont /Helvetica >> >> /XObject << /Im1 5 0 R >> >>",
+		pdfStreamObject("", stream),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 2 /Height 2 /ColorSpace /DeviceRGB /BitsPerComponent 8 ", imageData),
+	)
+}
+
+// pdfStreamObject 构造流对象，extraDict 为附加的字典条目
+func pdfStreamObject(extraDict, data string) string {
+	return fmt.Sprintf("<< /Length %d %s>>\nstream\n%s\nendstream", len(data), extraDict, data)
+}
+
+// singlePagePDFObjects 构造单页文档的对象列表：1~3 号为 Catalog、Pages 和 Page，objects 从 4 号对象开始
+// catalogAttrs、pageAttrs 分别为 Catalog 和页面字典的附加条目
+func singlePagePDFObjects(catalogAttrs, pageAttrs string, objects ...string) []string {
+	return append([]string{
+		pdfDict("/Type /Catalog /Pages 2 0 R", catalogAttrs),
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		pdfDict("/Type /Page /Parent 2 0 R", pageAttrs),
+	}, objects...)
+}
+
+// writeSinglePagePDF 写出单页文档，pageAttrs 为页面字典的附加条目，objects 从 4 号对象开始
+func writeSinglePagePDF(pdfPath, pageAttrs string, objects ...string) error {
+	return writePDFObjects(pdfPath, singlePagePDFObjects("", pageAttrs, objects...))
+}
+
+// pdfDict 拼接字典的固定条目和附加条目
+func pdfDict(entries, extra string) string {
+	if extra == "" {
+		return "<< " + entries + " >>"
+	}
+	return "<< " + entries + " " + extra + " >>"
+}
+
+// writePDFObjects 按顺序写出对象（对象号从 1 开始，1 号为 Catalog）并生成正确的 xref 表
+func writePDFObjects(pdfPath string, objects []string) error {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<<\n/Size %d\n/Root 1 0 R\n>>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset)
+
+	return os.WriteFile(pdfPath, buf.Bytes(), 0644)
+}
+
 // MockPDFReader 用于测试的 mock PDF 读取器
 type MockPDFReader struct {
 	pageCount int
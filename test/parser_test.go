@@ -1,7 +1,11 @@
 package test
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/novvoo/go-pdf/pkg/gopdf"
 )
@@ -201,6 +205,201 @@ func TestParseTokensEdgeCases(t *testing.T) {
 	}
 }
 
+// TestParseContentStreamStringEscapes 测试字符串字面量的转义序列
+func TestParseContentStreamStringEscapes(t *testing.T) {
+	ops, err := gopdf.ParseContentStream([]byte(`BT (a\(b\)\\c\101) Tj [(x\)) -120 (y)] TJ ET`))
+	if err != nil {
+		t.Fatalf("ParseContentStream failed: %v", err)
+	}
+	if len(ops) != 4 {
+		t.Fatalf("expected 4 operators, got %d", len(ops))
+	}
+
+	tj, ok := ops[1].(*gopdf.OpShowText)
+	if !ok {
+		t.Fatalf("expected Tj, got %s", ops[1].Name())
+	}
+	if tj.Text != `a(b)\cA` {
+		t.Errorf("Tj text = %q, want %q", tj.Text, `a(b)\cA`)
+	}
+
+	tjArray, ok := ops[2].(*gopdf.OpShowTextArray)
+	if !ok {
+		t.Fatalf("expected TJ, got %s", ops[2].Name())
+	}
+	if len(tjArray.Array) != 3 || tjArray.Array[0] != "x)" || tjArray.Array[2] != "y" {
+		t.Errorf("TJ array = %v, want [x) -120 y]", tjArray.Array)
+	}
+}
+
+// TestParseContentStreamsSplitAcrossStreams 测试跨越内容流边界的文本对象和字符串
+func TestParseContentStreamsSplitAcrossStreams(t *testing.T) {
+	// BT 在第一个流中，字符串在两个流之间被拆开，Tj 和 ET 在最后一个流中；
+	// 第二个字符串含有未转义的成对括号，十六进制字符串同样被拆开
+	streams := [][]byte{
+		[]byte("BT /F1 12 Tf 10 20 Td (Hel"),
+		[]byte("lo) Tj (a (b) c"),
+		[]byte(") Tj <4142"),
+		[]byte("43> Tj ET"),
+	}
+	ops, err := gopdf.ParseContentStreams(streams)
+	if err != nil {
+		t.Fatalf("ParseContentStreams failed: %v", err)
+	}
+
+	var names, texts []string
+	for _, op := range ops {
+		names = append(names, op.Name())
+		if tj, ok := op.(*gopdf.OpShowText); ok {
+			texts = append(texts, tj.Text)
+		}
+	}
+	if got := strings.Join(names, " "); got != "BT Tf Td Tj Tj Tj ET" {
+		t.Errorf("operators = %q, want %q", got, "BT Tf Td Tj Tj Tj ET")
+	}
+	want := []string{"Hello", "a (b) c", "<414243>"}
+	if strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("Tj texts = %q, want %q", texts, want)
+	}
+
+	// 流边界处的分隔符仍然分隔普通 token
+	ops, err = gopdf.ParseContentStreams([][]byte{[]byte("0 0 10 10 re"), []byte("f")})
+	if err != nil || len(ops) != 2 || ops[0].Name() != "re" || ops[1].Name() != "f" {
+		t.Errorf("expected re f across streams, got %v (err=%v)", ops, err)
+	}
+}
+
+// TestParseContentStreamInlineImage 测试内联图像（BI/ID/EI）的解析
+func TestParseContentStreamInlineImage(t *testing.T) {
+	// 数据中包含 "EI" 和空白字节，只有前后都是空白的 EI 才结束数据
+	data := "\x01EI\x20\x0a EIX\xffEI"
+	stream := "q BI /W 7 /H 1 /CS /G /BPC 8 ID " + data + "\nEI Q"
+
+	ops, err := gopdf.ParseContentStream([]byte(stream))
+	if err != nil {
+		t.Fatalf("ParseContentStream failed: %v", err)
+	}
+
+	var names []string
+	for _, op := range ops {
+		names = append(names, op.Name())
+	}
+	if got := strings.Join(names, " "); got != "q BI ID EI Q" {
+		t.Fatalf("operators = %q, want %q", got, "q BI ID EI Q")
+	}
+
+	id := ops[2].(*gopdf.OpInlineImageData)
+	if string(id.ImageData) != data {
+		t.Errorf("ImageData = %q, want %q", id.ImageData, data)
+	}
+	if id.ImageDict["/W"] != 7.0 || id.ImageDict["/CS"] != "/G" {
+		t.Errorf("ImageDict = %v, want /W 7 and /CS /G", id.ImageDict)
+	}
+}
+
+// TestParseContentStreamReader 测试增量解析与一次性解析结果一致，并能由 emit 提前结束
+func TestParseContentStreamReader(t *testing.T) {
+	data := "\x01EI\x20\x0a EIX\xffEI"
+	stream := "q 1 0 0 1 10 20 cm BT (a\\(b\\) c) Tj [(x) -120 (y)] TJ ET " +
+		"BI /W 7 /H 1 /CS /G /BPC 8 ID " + data + "\nEI /GS0 gs << /MCID 0 >> BDC EMC Q"
+
+	want, err := gopdf.ParseContentStream([]byte(stream))
+	if err != nil {
+		t.Fatalf("ParseContentStream failed: %v", err)
+	}
+
+	// 每次只读一个字节，确保分词不依赖一次读到完整内容
+	var got []gopdf.PDFOperator
+	err = gopdf.ParseContentStreamReader(iotest.OneByteReader(strings.NewReader(stream)), func(op gopdf.PDFOperator) error {
+		got = append(got, op)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ParseContentStreamReader failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("streamed operators differ from ParseContentStream:\n got %v\nwant %v", got, want)
+	}
+
+	// emit 返回的错误终止解析并原样返回
+	errStop := errors.New("stop")
+	count := 0
+	err = gopdf.ParseContentStreamReader(strings.NewReader(stream), func(op gopdf.PDFOperator) error {
+		count++
+		if op.Name() == "BT" {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("err = %v, want %v", err, errStop)
+	}
+	if count != 3 {
+		t.Errorf("emit called %d times, want 3 (q cm BT)", count)
+	}
+}
+
+// TestParseContentStreamStringEscapesNextLineOperators 测试 ' 和 " 操作符的字符串字面量同样处理转义序列
+func TestParseContentStreamStringEscapesNextLineOperators(t *testing.T) {
+	ops, err := gopdf.ParseContentStream([]byte("BT (tab\\there\\053) ' 1 2 (line\\\ncont\\n) \" ET"))
+	if err != nil {
+		t.Fatalf("ParseContentStream failed: %v", err)
+	}
+	if len(ops) != 4 {
+		t.Fatalf("expected 4 operators, got %d", len(ops))
+	}
+
+	quote, ok := ops[1].(*gopdf.OpShowTextNextLine)
+	if !ok {
+		t.Fatalf("expected ', got %s", ops[1].Name())
+	}
+	if quote.Text != "tab\there+" {
+		t.Errorf("' text = %q, want %q", quote.Text, "tab\there+")
+	}
+
+	doubleQuote, ok := ops[2].(*gopdf.OpShowTextWithSpacing)
+	if !ok {
+		t.Fatalf("expected \", got %s", ops[2].Name())
+	}
+	// 反斜杠加换行是续行，不产生字符
+	if doubleQuote.Text != "linecont\n" || doubleQuote.WordSpacing != 1 || doubleQuote.CharSpacing != 2 {
+		t.Errorf("\" = (%g, %g, %q), want (1, 2, %q)",
+			doubleQuote.WordSpacing, doubleQuote.CharSpacing, doubleQuote.Text, "linecont\n")
+	}
+}
+
+// TestParseContentStreamHexStrings 测试十六进制字符串与内容相同的字面量字符串可以区分
+func TestParseContentStreamHexStrings(t *testing.T) {
+	ops, err := gopdf.ParseContentStream([]byte("BT (<41>) Tj <41> Tj (/F1) ' [(<42>) -120 <42>] TJ ET"))
+	if err != nil {
+		t.Fatalf("ParseContentStream failed: %v", err)
+	}
+	if len(ops) != 6 {
+		t.Fatalf("expected 6 operators, got %d", len(ops))
+	}
+
+	literal, ok := ops[1].(*gopdf.OpShowText)
+	if !ok || literal.Text != "<41>" || literal.Hex {
+		t.Errorf("(<41>) Tj = %+v, want literal %q", ops[1], "<41>")
+	}
+	hex, ok := ops[2].(*gopdf.OpShowText)
+	if !ok || hex.Text != "<41>" || !hex.Hex {
+		t.Errorf("<41> Tj = %+v, want hex %q", ops[2], "<41>")
+	}
+	// 以 / 开头的字面量字符串不是名称，不能去掉前缀
+	if quote, ok := ops[3].(*gopdf.OpShowTextNextLine); !ok || quote.Text != "/F1" || quote.Hex {
+		t.Errorf("(/F1) ' = %+v, want literal %q", ops[3], "/F1")
+	}
+
+	tj, ok := ops[4].(*gopdf.OpShowTextArray)
+	if !ok || len(tj.Array) != 3 {
+		t.Fatalf("expected TJ with 3 elements, got %+v", ops[4])
+	}
+	if tj.Array[0] != "<42>" || tj.Array[2] != gopdf.HexString("<42>") {
+		t.Errorf("TJ array = %#v, want [\"<42>\" -120 HexString(\"<42>\")]", tj.Array)
+	}
+}
+
 // BenchmarkParseTokens 基准测试token解析性能
 func BenchmarkParseTokens(b *testing.B) {
 	tokens := []string{"q", "1", "0", "0", "1", "100", "200", "cm", "BT", "/F1", "12", "Tf", "(Hello)", "Tj", "ET", "Q"}
@@ -299,15 +299,8 @@ func extractPageOperators(ctx *model.Context, pageNum int) ([]gopdf.PDFOperator,
 		return nil, fmt.Errorf("无法提取内容流: %w", err)
 	}
 
-	// 合并所有内容流
-	var allContent []byte
-	for _, stream := range contentStreams {
-		allContent = append(allContent, stream...)
-		allContent = append(allContent, '\n')
-	}
-
-	// 解析操作符
-	operators, err := gopdf.ParseContentStream(allContent)
+	// 合并所有内容流并解析操作符
+	operators, err := gopdf.ParseContentStreams(contentStreams)
 	if err != nil {
 		return nil, fmt.Errorf("无法解析内容流: %w", err)
 	}
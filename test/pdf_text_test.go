@@ -144,3 +144,100 @@ func TestTextSpacing(t *testing.T) {
 		}
 	}
 }
+
+// TestParseToUnicodeCMapRanges 测试 bfrange 的递增形式、数组形式以及多字符目标串
+func TestParseToUnicodeCMapRanges(t *testing.T) {
+	cmap := []byte(`/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <00660069>
+<0002> <D835DC00>
+endbfchar
+3 beginbfrange
+<0010> <0012> <0041>
+<0020> <0022> [<0058> <00660066>
+<005A>]
+<0030> <0031> <00660061>
+endbfrange
+endcmap
+`)
+
+	cidMap, err := gopdf.ParseToUnicodeCMap(cmap)
+	if err != nil {
+		t.Fatalf("ParseToUnicodeCMap failed: %v", err)
+	}
+
+	tests := []struct {
+		cid  uint16
+		want string
+	}{
+		{0x0001, "fi"},
+		{0x0002, "\U0001D400"},
+		{0x0010, "A"},
+		{0x0012, "C"},
+		{0x0020, "X"},
+		{0x0021, "ff"},
+		{0x0022, "Z"},
+		{0x0030, "fa"},
+		{0x0031, "fb"},
+	}
+	for _, tt := range tests {
+		got, ok := cidMap.MapCIDToString(tt.cid)
+		if !ok || got != tt.want {
+			t.Errorf("CID %04X: got %q (ok=%v), want %q", tt.cid, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := cidMap.MapCIDToString(0x0023); ok {
+		t.Error("CID 0023 is outside every range and should not map")
+	}
+
+	if got := cidMap.MapCIDsToUnicode([]uint16{0x0001, 0x0010, 0x0021}); got != "fiAff" {
+		t.Errorf("MapCIDsToUnicode = %q, want %q", got, "fiAff")
+	}
+}
+
+// TestLoadCIDToUnicodeFromRegistry 测试 poppler-data 映射的解析（行号即 CID）和跨调用缓存
+func TestLoadCIDToUnicodeFromRegistry(t *testing.T) {
+	tests := []struct {
+		registry string
+		cid      uint16
+		want     rune
+	}{
+		{"Adobe-GB1", 4559, '中'},
+		{"Adobe-GB1", 3795, '文'},
+		{"Adobe-Japan1", 3284, '日'},
+		{"Adobe-Japan1", 3722, '本'},
+	}
+	for _, tt := range tests {
+		cidMap, err := gopdf.LoadCIDToUnicodeFromRegistry(tt.registry)
+		if err != nil {
+			t.Fatalf("%s: LoadCIDToUnicodeFromRegistry failed: %v", tt.registry, err)
+		}
+		if got, ok := cidMap.MapCIDToUnicode(tt.cid); !ok || got != tt.want {
+			t.Errorf("%s CID %d: got %q (ok=%v), want %q", tt.registry, tt.cid, got, ok, tt.want)
+		}
+		if _, ok := cidMap.MapCIDToUnicode(0); ok {
+			t.Errorf("%s: CID 0 (.notdef) should not map", tt.registry)
+		}
+	}
+
+	// 同一字符集合只解析一次，之后的调用返回共享的映射
+	first, _ := gopdf.LoadCIDToUnicodeFromRegistry("Adobe-Korea1")
+	second, _ := gopdf.LoadCIDToUnicodeFromRegistry("Adobe-Korea1")
+	if first == nil || first != second {
+		t.Error("expected the cached map to be returned on the second call")
+	}
+	gopdf.ClearCIDRegistryCache()
+	if third, _ := gopdf.LoadCIDToUnicodeFromRegistry("Adobe-Korea1"); third == first {
+		t.Error("expected a freshly parsed map after ClearCIDRegistryCache")
+	}
+
+	if _, err := gopdf.LoadCIDToUnicodeFromRegistry("Adobe-Unknown"); err == nil {
+		t.Error("expected an error for an unknown registry")
+	}
+}
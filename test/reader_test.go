@@ -1,9 +1,26 @@
 package test
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/novvoo/go-pdf/pkg/gopdf"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
 )
 
 // TestPDFReaderCreation 测试PDF读取器的创建
@@ -105,6 +122,270 @@ func TestGetPageInfo(t *testing.T) {
 	helper.AssertTrue(pageInfo.Height > 0, "Page height should be positive")
 }
 
+// TestPageCountAndInfoShareOneRead 测试页数和页面尺寸由同一次读取填充
+func TestPageCountAndInfoShareOneRead(t *testing.T) {
+	helper := NewTestHelper(t)
+	mockGen := NewMockPDFGenerator()
+	defer mockGen.Cleanup()
+
+	pdfPath, err := mockGen.GeneratePDFWithSize(300, 400)
+	helper.AssertNoError(err, "Failed to generate mock PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	count, err := reader.GetPageCount()
+	helper.AssertNoError(err, "Failed to get page count")
+	helper.AssertEqual(count, 1, "Page count mismatch")
+
+	// 文件删除后页面尺寸仍来自获取页数时填充的缓存
+	helper.AssertNoError(os.Remove(pdfPath), "Failed to remove PDF")
+	info, err := reader.GetPageInfo(1)
+	helper.AssertNoError(err, "Page info should come from the cache")
+	helper.AssertEqual(info.Width, 300.0, "Page width mismatch")
+	helper.AssertEqual(info.Height, 400.0, "Page height mismatch")
+
+	// Close 清理缓存后需要重新读取文件
+	helper.AssertNoError(reader.Close(), "Failed to close reader")
+	_, err = reader.GetPageCount()
+	helper.AssertError(err, "Expected error after the cache is cleared")
+}
+
+// TestUserUnitScalesRender 测试 /UserUnit 放大渲染尺寸
+func TestUserUnitScalesRender(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "userunit.pdf")
+
+	// 100x50 用户单位、UserUnit 2：物理尺寸 200x100 点，左半部分填充蓝色
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 50] /UserUnit 2 /Contents 4 0 R",
+		pdfStreamObject("", "0 0 1 rg 0 0 50 50 re f\n"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	info, err := reader.GetPageInfo(1)
+	helper.AssertNoError(err, "Failed to get page info")
+	helper.AssertEqual(info.UserUnit, 2.0, "UserUnit mismatch")
+	helper.AssertEqual(info.Width, 100.0, "Width should stay in user units")
+
+	img, err := reader.RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+		t.Fatalf("render size = %dx%d, want 200x100", b.Dx(), b.Dy())
+	}
+	if r, g, b, _ := img.At(90, 50).RGBA(); b>>8 < 200 || r>>8 > 60 || g>>8 > 60 {
+		t.Errorf("expected blue at (90,50), got (%d,%d,%d)", r>>8, g>>8, b>>8)
+	}
+	if r, _, _, _ := img.At(110, 50).RGBA(); r>>8 < 200 {
+		t.Errorf("expected white at (110,50), got red=%d", r>>8)
+	}
+
+	// 没有 /UserUnit 时默认 1
+	mockGen := NewMockPDFGenerator()
+	defer mockGen.Cleanup()
+	plainPath, err := mockGen.GeneratePDFWithSize(300, 400)
+	helper.AssertNoError(err, "Failed to generate mock PDF")
+	info, err = gopdf.NewPDFReader(plainPath).GetPageInfo(1)
+	helper.AssertNoError(err, "Failed to get page info")
+	helper.AssertEqual(info.UserUnit, 1.0, "Default UserUnit mismatch")
+}
+
+// TestResetDropsCachesFromPageCount 测试 Reset 丢弃获取页数时填充的页面缓存
+func TestResetDropsCachesFromPageCount(t *testing.T) {
+	helper := NewTestHelper(t)
+	mockGen := NewMockPDFGenerator()
+	defer mockGen.Cleanup()
+
+	firstPath, err := mockGen.GeneratePDFWithSize(300, 400)
+	helper.AssertNoError(err, "Failed to generate mock PDF")
+	secondPath, err := mockGen.GeneratePDFWithSize(500, 200)
+	helper.AssertNoError(err, "Failed to generate mock PDF")
+
+	reader := gopdf.NewPDFReader(firstPath)
+	_, err = reader.GetPageCount()
+	helper.AssertNoError(err, "Failed to get page count")
+
+	// 获取页数时已缓存第一个文档的页面尺寸，Reset 后不能再被使用
+	reader.Reset(secondPath)
+	info, err := reader.GetPageInfo(1)
+	helper.AssertNoError(err, "Failed to get page info")
+	helper.AssertEqual(info.Width, 500.0, "Page width should come from the new document")
+	helper.AssertEqual(info.Height, 200.0, "Page height should come from the new document")
+}
+
+// TestGetPageInfoBoxesAndRotation 测试页面信息中的旋转和边界框（含页面树继承）
+func TestGetPageInfoBoxesAndRotation(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "boxes.pdf")
+
+	// MediaBox 和 Rotate 从 /Pages 节点继承，CropBox 在页面上
+	err := writePDFObjects(pdfPath, []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 200 100] /Rotate 90 >>",
+		"<< /Type /Page /Parent 2 0 R /CropBox [10 20 110 90] /Contents 4 0 R /Resources << >> >>",
+		pdfStreamObject("", ""),
+	})
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	info, err := gopdf.NewPDFReader(pdfPath).GetPageInfo(1)
+	helper.AssertNoError(err, "Failed to get page info")
+
+	if info.Rotation != 90 {
+		t.Errorf("Rotation = %d, want 90", info.Rotation)
+	}
+	if want := [4]float64{0, 0, 200, 100}; info.MediaBox != want {
+		t.Errorf("MediaBox = %v, want %v", info.MediaBox, want)
+	}
+	if want := [4]float64{10, 20, 110, 90}; info.CropBox != want {
+		t.Errorf("CropBox = %v, want %v", info.CropBox, want)
+	}
+	// 可见区域 100x70，旋转 90° 后宽高互换
+	if info.Width != 70 || info.Height != 100 {
+		t.Errorf("size = %vx%v, want 70x100", info.Width, info.Height)
+	}
+}
+
+// TestGetPageBoxes 测试五个页面边界框、默认值和显式定义标志
+func TestGetPageBoxes(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "print_boxes.pdf")
+
+	// 第 1 页：MediaBox 和 CropBox 从 /Pages 继承，定义了 BleedBox 和 TrimBox；
+	// 第 2 页：只有自己的 MediaBox，CropBox 也从 /Pages 继承；第 3 页在没有 CropBox 的子节点下
+	err := writePDFObjects(pdfPath, []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R 6 0 R] /Count 3 /MediaBox [0 0 640 820] /CropBox [0 0 630 810] >>",
+		"<< /Type /Page /Parent 2 0 R /BleedBox [11 11 629 809] /TrimBox [20 20 620 800] /Contents 5 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 300 400] /Contents 5 0 R >>",
+		pdfStreamObject("", ""),
+		"<< /Type /Pages /Parent 2 0 R /Kids [7 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 6 0 R /MediaBox [0 500 200 0] /ArtBox [50 50 150 150] /Contents 5 0 R >>",
+	})
+	helper.AssertNoError(err, "Failed to write PDF")
+	reader := gopdf.NewPDFReader(pdfPath)
+
+	boxes, err := reader.GetPageBoxes(1)
+	helper.AssertNoError(err, "Failed to get page boxes")
+	want := gopdf.PageBoxes{
+		MediaBox:    [4]float64{0, 0, 640, 820},
+		CropBox:     [4]float64{0, 0, 630, 810},
+		BleedBox:    [4]float64{11, 11, 629, 809},
+		TrimBox:     [4]float64{20, 20, 620, 800},
+		ArtBox:      [4]float64{0, 0, 630, 810},
+		HasCropBox:  true,
+		HasBleedBox: true,
+		HasTrimBox:  true,
+	}
+	if boxes != want {
+		t.Errorf("page 1 boxes = %+v, want %+v", boxes, want)
+	}
+
+	boxes, err = reader.GetPageBoxes(3)
+	helper.AssertNoError(err, "Failed to get page boxes")
+	want = gopdf.PageBoxes{
+		MediaBox:   [4]float64{0, 0, 200, 500},
+		CropBox:    [4]float64{0, 0, 630, 810},
+		BleedBox:   [4]float64{0, 0, 630, 810},
+		TrimBox:    [4]float64{0, 0, 630, 810},
+		ArtBox:     [4]float64{50, 50, 150, 150},
+		HasCropBox: true,
+		HasArtBox:  true,
+	}
+	if boxes != want {
+		t.Errorf("page 3 boxes = %+v, want %+v", boxes, want)
+	}
+
+	if _, err := reader.GetPageBoxes(4); err == nil {
+		t.Errorf("expected an error for an invalid page number")
+	}
+}
+
+// TestGetPageBoxesDefaults 测试没有 CropBox 等框时全部默认为 MediaBox
+func TestGetPageBoxesDefaults(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "media_only.pdf")
+
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 300 400] /Contents 4 0 R",
+		pdfStreamObject("", ""),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	boxes, err := gopdf.NewPDFReader(pdfPath).GetPageBoxes(1)
+	helper.AssertNoError(err, "Failed to get page boxes")
+	media := [4]float64{0, 0, 300, 400}
+	want := gopdf.PageBoxes{MediaBox: media, CropBox: media, BleedBox: media, TrimBox: media, ArtBox: media}
+	if boxes != want {
+		t.Errorf("boxes = %+v, want %+v", boxes, want)
+	}
+}
+
+// TestRenderPageToSize 测试按目标像素尺寸渲染的三种适配方式
+func TestRenderPageToSize(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "wide.pdf")
+
+	// 100x50 的横向页面：左半蓝色，右半红色
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 50] /Contents 4 0 R",
+		pdfStreamObject("", "0 0 1 rg 0 0 50 50 re f\n1 0 0 rg 50 0 50 50 re f\n"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+	reader := gopdf.NewPDFReader(pdfPath)
+
+	blue := color.RGBA{0, 0, 255, 255}
+	red := color.RGBA{255, 0, 0, 255}
+	white := color.RGBA{255, 255, 255, 255}
+	tests := []struct {
+		name   string
+		fit    gopdf.FitMode
+		pixels map[image.Point]color.RGBA
+	}{
+		// 等比缩放到 200x100，上下各留 50 像素白边
+		{"contain", gopdf.FitContain, map[image.Point]color.RGBA{
+			{10, 100}: blue, {190, 100}: red, {100, 20}: white, {100, 180}: white,
+		}},
+		// 等比缩放到 400x200，左右各裁掉 100 像素：x=60 对应页面 x=40（蓝色），x=140 对应 x=60（红色）
+		{"cover", gopdf.FitCover, map[image.Point]color.RGBA{
+			{60, 10}: blue, {60, 190}: blue, {140, 10}: red, {140, 190}: red,
+		}},
+		// 水平 2 倍、垂直 4 倍拉伸：x=60 对应页面 x=30（蓝色），x=140 对应 x=70（红色）
+		{"stretch", gopdf.FitStretch, map[image.Point]color.RGBA{
+			{10, 10}: blue, {90, 190}: blue, {110, 10}: red, {190, 190}: red,
+		}},
+	}
+	for _, tt := range tests {
+		img, err := reader.RenderPageToSize(1, 200, 200, tt.fit)
+		helper.AssertNoError(err, "Failed to render page to size")
+		if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 200 {
+			t.Fatalf("%s: size = %dx%d, want 200x200", tt.name, b.Dx(), b.Dy())
+		}
+		rgba := img.(*image.RGBA)
+		for pt, want := range tt.pixels {
+			if got := rgba.RGBAAt(pt.X, pt.Y); got != want {
+				t.Errorf("%s: pixel %v = %v, want %v", tt.name, pt, got, want)
+			}
+		}
+	}
+
+	// 目标更宽时 contain 在左右留白：页面缩放到 200x100，位于 x=50..250
+	img, err := reader.RenderPageToSize(1, 300, 100, gopdf.FitContain)
+	helper.AssertNoError(err, "Failed to render page to size")
+	if got := img.(*image.RGBA).RGBAAt(20, 50); got != white {
+		t.Errorf("contain pillarbox pixel = %v, want white", got)
+	}
+	if got := img.(*image.RGBA).RGBAAt(60, 50); got != blue {
+		t.Errorf("contain page pixel = %v, want blue", got)
+	}
+
+	if _, err := reader.RenderPageToSize(1, 0, 100, gopdf.FitContain); err == nil {
+		t.Errorf("expected an error for an empty target size")
+	}
+
+	// ConvertPDFPageToImage 按实际页面尺寸输出请求的像素尺寸
+	img, err = gopdf.ConvertPDFPageToImage(pdfPath, 1, 300, 100)
+	helper.AssertNoError(err, "ConvertPDFPageToImage failed")
+	if b := img.Bounds(); b.Dx() != 300 || b.Dy() != 100 {
+		t.Errorf("ConvertPDFPageToImage size = %dx%d, want 300x100", b.Dx(), b.Dy())
+	}
+}
+
 // TestMultiPagePDF 测试多页 PDF 处理
 func TestMultiPagePDF(t *testing.T) {
 	helper := NewTestHelper(t)
@@ -214,3 +495,2405 @@ func TestEmptyPDFHandling(t *testing.T) {
 	helper.AssertTrue(pageInfo.Width > 0, "Page width should be positive")
 	helper.AssertTrue(pageInfo.Height > 0, "Page height should be positive")
 }
+
+// TestRenderRotatedPage 测试 /Rotate 页面的渲染尺寸和旋转方向
+func TestRenderRotatedPage(t *testing.T) {
+	mockGen := NewMockPDFGenerator()
+	defer mockGen.Cleanup()
+
+	// 页面 200x100，左下角有红色方块；/Rotate 表示显示时顺时针旋转
+	tests := []struct {
+		name       string
+		rotate     int
+		wantWidth  int
+		wantHeight int
+		redX, redY int // 红色方块在输出图像中的采样点
+	}{
+		{"no rotation", 0, 200, 100, 10, 90},
+		{"rotate 90", 90, 100, 200, 10, 10},
+		{"rotate 180", 180, 200, 100, 190, 10},
+		{"rotate 270", 270, 100, 200, 90, 190},
+		{"rotate -90", -90, 100, 200, 90, 190},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := NewTestHelper(t)
+			pdfPath, err := mockGen.GenerateRotatedPDF(200, 100, tt.rotate)
+			helper.AssertNoError(err, "Failed to generate rotated PDF")
+
+			reader := gopdf.NewPDFReader(pdfPath)
+
+			img, err := reader.RenderPageToImage(1, 72)
+			helper.AssertNoError(err, "Failed to render rotated page")
+			bounds := img.Bounds()
+			if bounds.Dx() != tt.wantWidth || bounds.Dy() != tt.wantHeight {
+				t.Errorf("RenderPageToImage size = %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), tt.wantWidth, tt.wantHeight)
+			}
+
+			outputPath := filepath.Join(t.TempDir(), "rotated.png")
+			err = reader.RenderPageToPNG(1, outputPath, 72)
+			helper.AssertNoError(err, "Failed to render rotated page to PNG")
+
+			rendered := helper.LoadAndValidateImage(outputPath)
+			bounds = rendered.Bounds()
+			if bounds.Dx() != tt.wantWidth || bounds.Dy() != tt.wantHeight {
+				t.Fatalf("RenderPageToPNG size = %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), tt.wantWidth, tt.wantHeight)
+			}
+
+			r, g, b, _ := rendered.At(tt.redX, tt.redY).RGBA()
+			if r>>8 < 200 || g>>8 > 50 || b>>8 > 50 {
+				t.Errorf("pixel (%d,%d) = (%d,%d,%d), want red", tt.redX, tt.redY, r>>8, g>>8, b>>8)
+			}
+		})
+	}
+}
+
+// TestRenderPageBoxOrigin 测试 MediaBox 原点偏移和 CropBox 交集
+func TestRenderPageBoxOrigin(t *testing.T) {
+	mockGen := NewMockPDFGenerator()
+	defer mockGen.Cleanup()
+
+	// 红色方块位于可见区域左下角
+	tests := []struct {
+		name       string
+		pageAttrs  string
+		stream     string
+		wantWidth  int
+		wantHeight int
+	}{
+		{
+			"negative MediaBox origin",
+			"/MediaBox [-10 -10 190 90]",
+			"1 0 0 rg\n-10 -10 50 50 re\nf\n",
+			200, 100,
+		},
+		{
+			"CropBox inside MediaBox",
+			"/MediaBox [0 0 400 400] /CropBox [100 100 300 200]",
+			"1 0 0 rg\n100 100 50 50 re\nf\n",
+			200, 100,
+		},
+		{
+			"CropBox exceeding MediaBox",
+			"/MediaBox [50 50 250 150] /CropBox [0 0 500 500]",
+			"1 0 0 rg\n50 50 50 50 re\nf\n",
+			200, 100,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := NewTestHelper(t)
+			pdfPath, err := mockGen.GenerateSinglePagePDF(fmt.Sprintf("box_origin_%d.pdf", i), tt.pageAttrs, tt.stream)
+			helper.AssertNoError(err, "Failed to generate PDF")
+
+			reader := gopdf.NewPDFReader(pdfPath)
+			outputPath := filepath.Join(t.TempDir(), "box.png")
+			err = reader.RenderPageToPNG(1, outputPath, 72)
+			helper.AssertNoError(err, "Failed to render page")
+
+			rendered := helper.LoadAndValidateImage(outputPath)
+			bounds := rendered.Bounds()
+			if bounds.Dx() != tt.wantWidth || bounds.Dy() != tt.wantHeight {
+				t.Fatalf("size = %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), tt.wantWidth, tt.wantHeight)
+			}
+
+			// 左下角应为红色，中心应为白色背景
+			r, g, b, _ := rendered.At(10, tt.wantHeight-10).RGBA()
+			if r>>8 < 200 || g>>8 > 50 || b>>8 > 50 {
+				t.Errorf("bottom-left pixel = (%d,%d,%d), want red", r>>8, g>>8, b>>8)
+			}
+			r, g, b, _ = rendered.At(tt.wantWidth/2, tt.wantHeight/2).RGBA()
+			if r>>8 < 200 || g>>8 < 200 || b>>8 < 200 {
+				t.Errorf("center pixel = (%d,%d,%d), want white", r>>8, g>>8, b>>8)
+			}
+		})
+	}
+}
+
+// TestRenderInheritedPageAttributes 测试从页面树祖先节点继承的 Rotate、MediaBox、CropBox 和 Resources
+func TestRenderInheritedPageAttributes(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "inherited.pdf")
+
+	// 根 /Pages 提供 MediaBox、Rotate 和 Resources，中间 /Pages 提供 CropBox，页面本身都不设置
+	// 红色方块位于可见区域左下角，旋转 90° 后出现在输出图像左上角
+	stream := "/GS0 gs\n1 0 0 rg\n10 10 30 30 re\nf\n"
+	err := writePDFObjects(pdfPath, []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 400 400] /Rotate 90 /Resources << /ExtGState << /GS0 << /ca 1 >> >> >> >>",
+		"<< /Type /Pages /Parent 2 0 R /Kids [4 0 R] /Count 1 /CropBox [10 10 210 110] >>",
+		"<< /Type /Page /Parent 3 0 R /Contents 5 0 R >>",
+		pdfStreamObject("", stream),
+	})
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	info, err := reader.GetPageInfo(1)
+	helper.AssertNoError(err, "Failed to get page info")
+	if info.Rotation != 90 || info.Width != 100 || info.Height != 200 {
+		t.Errorf("GetPageInfo = %vx%v rot %d, want 100x200 rot 90", info.Width, info.Height, info.Rotation)
+	}
+
+	img, err := reader.RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+	bounds := img.Bounds()
+	if bounds.Dx() != 100 || bounds.Dy() != 200 {
+		t.Fatalf("size = %dx%d, want 100x200", bounds.Dx(), bounds.Dy())
+	}
+
+	r, g, b, _ := img.At(10, 10).RGBA()
+	if r>>8 < 200 || g>>8 > 50 || b>>8 > 50 {
+		t.Errorf("top-left pixel = (%d,%d,%d), want red", r>>8, g>>8, b>>8)
+	}
+	r, g, b, _ = img.At(50, 100).RGBA()
+	if r>>8 < 200 || g>>8 < 200 || b>>8 < 200 {
+		t.Errorf("center pixel = (%d,%d,%d), want white", r>>8, g>>8, b>>8)
+	}
+}
+
+// TestRenderMergedPageResources 测试只在 /Pages 节点上的资源以及与页面资源的合并
+func TestRenderMergedPageResources(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "merged_resources.pdf")
+
+	// /Pages 提供 Im0（红色），页面自身的 Resources 只提供 Im1（蓝色），两者都必须能找到
+	stream := "q 40 0 0 40 10 10 cm /Im0 Do Q\nq 40 0 0 40 50 10 cm /Im1 Do Q\n"
+	err := writePDFObjects(pdfPath, []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 100 60] /Resources << /XObject << /Im0 5 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /XObject << /Im1 6 0 R >> >> >>",
+		pdfStreamObject("", stream),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8", "\xff\x00\x00"),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8", "\x00\x00\xff"),
+	})
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	img, err := gopdf.NewPDFReader(pdfPath).RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+
+	r, g, b, _ := img.At(30, 30).RGBA()
+	if r>>8 < 200 || g>>8 > 50 || b>>8 > 50 {
+		t.Errorf("inherited image pixel = (%d,%d,%d), want red", r>>8, g>>8, b>>8)
+	}
+	r, g, b, _ = img.At(70, 30).RGBA()
+	if r>>8 > 50 || g>>8 > 50 || b>>8 < 200 {
+		t.Errorf("page image pixel = (%d,%d,%d), want blue", r>>8, g>>8, b>>8)
+	}
+}
+
+// TestRenderCalibratedImages 测试 CalRGB 和 CalGray 图像经 XYZ 转换到 sRGB
+func TestRenderCalibratedImages(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "calibrated.pdf")
+
+	// CalRGB 使用 sRGB 原色矩阵、线性 gamma：线性中灰 0.5 应编码为 sRGB 约 188
+	// CalGray gamma 1.0 同理；设备颜色空间下两者都会是 128
+	calRGB := "[/CalRGB << /WhitePoint [0.9505 1 1.089] /Gamma [1 1 1] /Matrix [0.4124 0.2126 0.0193 0.3576 0.7152 0.1192 0.1805 0.0722 0.9505] >>]"
+	calGray := "[/CalGray << /WhitePoint [0.9505 1 1.089] /Gamma 1 >>]"
+	stream := "q 30 0 0 10 0 10 cm /Im0 Do Q\nq 10 0 0 10 0 0 cm /Im1 Do Q\n"
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 30 20] /Contents 4 0 R /Resources << /XObject << /Im0 5 0 R /Im1 6 0 R >> >>",
+		pdfStreamObject("", stream),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 3 /Height 1 /ColorSpace "+calRGB+" /BitsPerComponent 8", "\xff\x00\x00\x80\x80\x80\xff\xff\xff"),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace "+calGray+" /BitsPerComponent 8", "\x80"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	img, err := gopdf.NewPDFReader(pdfPath).RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+
+	tests := []struct {
+		name    string
+		x, y    int
+		r, g, b uint8
+	}{
+		{"CalRGB red", 5, 5, 255, 0, 0},
+		{"CalRGB mid gray", 15, 5, 188, 188, 188},
+		{"CalRGB white", 25, 5, 255, 255, 255},
+		{"CalGray mid gray", 5, 15, 188, 188, 188},
+	}
+	for _, tt := range tests {
+		c := color.RGBAModel.Convert(img.At(tt.x, tt.y)).(color.RGBA)
+		if absDiff(c.R, tt.r) > 4 || absDiff(c.G, tt.g) > 4 || absDiff(c.B, tt.b) > 4 {
+			t.Errorf("%s = (%d,%d,%d), want about (%d,%d,%d)", tt.name, c.R, c.G, c.B, tt.r, tt.g, tt.b)
+		}
+	}
+}
+
+// TestRenderPageBackgroundOptions 测试背景色和透明背景渲染选项
+func TestRenderPageBackgroundOptions(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "background.pdf")
+
+	// 左半边红色方块，右半边（含半透明绿色）未被不透明内容覆盖
+	stream := "1 0 0 rg 0 0 20 20 re f\n0 1 0 rg /GS0 gs 20 0 20 20 re f\n"
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 60 20] /Contents 4 0 R /Resources << /ExtGState << /GS0 << /ca 0.5 >> >> >>",
+		pdfStreamObject("", stream),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+	reader := gopdf.NewPDFReader(pdfPath)
+
+	pixel := func(img image.Image, x, y int) color.NRGBA {
+		return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+	}
+
+	// 默认白色背景
+	img, err := reader.RenderPageToImageWithOptions(1, &gopdf.RenderOptions{DPI: 72})
+	helper.AssertNoError(err, "Failed to render page")
+	if c := pixel(img, 50, 10); c != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("default background = %v, want opaque white", c)
+	}
+
+	// 指定背景色
+	img, err = reader.RenderPageToImageWithOptions(1, &gopdf.RenderOptions{DPI: 72, Background: &gopdf.RGB{B: 1}})
+	helper.AssertNoError(err, "Failed to render page")
+	if c := pixel(img, 50, 10); c != (color.NRGBA{0, 0, 255, 255}) {
+		t.Errorf("blue background = %v, want opaque blue", c)
+	}
+
+	// 透明背景导出的 PNG 保留 alpha：空白处全透明，半透明绿色保持约 50% alpha
+	pngPath := filepath.Join(t.TempDir(), "transparent.png")
+	err = reader.RenderPageToPNGWithOptions(1, &gopdf.RenderOptions{DPI: 72, OutputPath: pngPath, TransparentBackground: true})
+	helper.AssertNoError(err, "Failed to render transparent PNG")
+	f, err := os.Open(pngPath)
+	helper.AssertNoError(err, "Failed to open PNG")
+	defer f.Close()
+	decoded, err := png.Decode(f)
+	helper.AssertNoError(err, "Failed to decode PNG")
+
+	if c := pixel(decoded, 50, 10); c.A != 0 {
+		t.Errorf("transparent background alpha = %d, want 0", c.A)
+	}
+	if c := pixel(decoded, 10, 10); c != (color.NRGBA{255, 0, 0, 255}) {
+		t.Errorf("opaque content = %v, want opaque red", c)
+	}
+	if c := pixel(decoded, 30, 10); c.G < 250 || c.R > 5 || absDiff(c.A, 128) > 2 {
+		t.Errorf("semi-transparent content = %v, want green with alpha about 128", c)
+	}
+}
+
+// TestRenderWithCancelledContext 测试已取消的 context 会中止渲染
+func TestRenderWithCancelledContext(t *testing.T) {
+	helper := NewTestHelper(t)
+	mockGen := NewMockPDFGenerator()
+	defer mockGen.Cleanup()
+
+	pdfPath, err := mockGen.GenerateMultiPagePDF(3)
+	helper.AssertNoError(err, "Failed to generate multi-page PDF")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	_, err = reader.RenderPageToImageContext(ctx, 1, 72)
+	helper.AssertTrue(errors.Is(err, context.Canceled), "RenderPageToImageContext should return context.Canceled")
+
+	outputDir := t.TempDir()
+	err = reader.RenderAllPagesToPNGContext(ctx, outputDir, 72)
+	helper.AssertTrue(errors.Is(err, context.Canceled), "RenderAllPagesToPNGContext should return context.Canceled")
+
+	_, statErr := os.Stat(filepath.Join(outputDir, "page_1.png"))
+	helper.AssertTrue(os.IsNotExist(statErr), "No page should be rendered after cancellation")
+}
+
+// TestRenderAllPagesToPNGParallel 测试并行渲染所有页面
+func TestRenderAllPagesToPNGParallel(t *testing.T) {
+	helper := NewTestHelper(t)
+	mockGen := NewMockPDFGenerator()
+	defer mockGen.Cleanup()
+
+	pdfPath, err := mockGen.GenerateMultiPagePDF(5)
+	helper.AssertNoError(err, "Failed to generate multi-page PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	outputDir := t.TempDir()
+	err = reader.RenderAllPagesToPNGParallel(outputDir, 72, 3)
+	helper.AssertNoError(err, "Failed to render pages in parallel")
+
+	for i := 1; i <= 5; i++ {
+		helper.LoadAndValidateImage(filepath.Join(outputDir, fmt.Sprintf("page_%d.png", i)))
+	}
+}
+
+// TestPDFReaderFromBytes 测试从内存数据和 io.ReadSeeker 创建的读取器与文件读取器输出一致
+func TestPDFReaderFromBytes(t *testing.T) {
+	helper := NewTestHelper(t)
+	mockGen := NewMockPDFGenerator()
+	defer mockGen.Cleanup()
+
+	pdfPath, err := mockGen.GenerateMultiPagePDF(3)
+	helper.AssertNoError(err, "Failed to generate multi-page PDF")
+	data, err := os.ReadFile(pdfPath)
+	helper.AssertNoError(err, "Failed to read PDF file")
+
+	fromFile := gopdf.NewPDFReader(pdfPath)
+	want, err := fromFile.RenderPageToImage(2, 72)
+	helper.AssertNoError(err, "Failed to render page from file")
+
+	// 读取器位置不在起点时也应读取完整数据
+	rs := bytes.NewReader(data)
+	_, _ = rs.Seek(100, io.SeekStart)
+	fromReader, err := gopdf.NewPDFReaderFromReader(rs)
+	helper.AssertNoError(err, "Failed to create reader from io.ReadSeeker")
+
+	for name, reader := range map[string]*gopdf.PDFReader{
+		"bytes":  gopdf.NewPDFReaderFromBytes(data),
+		"reader": fromReader,
+	} {
+		count, err := reader.GetPageCount()
+		helper.AssertNoError(err, name+": failed to get page count")
+		if count != 3 {
+			t.Errorf("%s: page count = %d, want 3", name, count)
+		}
+
+		got, err := reader.RenderPageToImage(2, 72)
+		helper.AssertNoError(err, name+": failed to render page")
+		if got.Bounds() != want.Bounds() {
+			t.Fatalf("%s: bounds = %v, want %v", name, got.Bounds(), want.Bounds())
+		}
+		for y := want.Bounds().Min.Y; y < want.Bounds().Max.Y; y += 7 {
+			for x := want.Bounds().Min.X; x < want.Bounds().Max.X; x += 7 {
+				if got.At(x, y) != want.At(x, y) {
+					t.Fatalf("%s: pixel (%d,%d) = %v, want %v", name, x, y, got.At(x, y), want.At(x, y))
+				}
+			}
+		}
+
+		outputDir := t.TempDir()
+		helper.AssertNoError(reader.RenderAllPagesToPNGParallel(outputDir, 72, 2), name+": failed to render pages in parallel")
+		for i := 1; i <= 3; i++ {
+			helper.LoadAndValidateImage(filepath.Join(outputDir, fmt.Sprintf("page_%d.png", i)))
+		}
+	}
+
+	if _, err := gopdf.NewPDFReaderFromBytes([]byte("not a pdf")).GetPageCount(); err == nil {
+		t.Error("expected error for invalid in-memory PDF")
+	}
+}
+
+// TestPDFReaderReset 测试 Reset 后读取器只反映新文档，不残留上一个文档的缓存
+func TestPDFReaderReset(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+
+	pdfPaths := make([]string, 2)
+	for i, size := range []string{"200 100", "300 400"} {
+		pdfPaths[i] = filepath.Join(dir, fmt.Sprintf("doc%d.pdf", i))
+		err := writePDFObjects(pdfPaths[i], []string{
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			fmt.Sprintf("<< /Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 %s] >>", size),
+			"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+			pdfStreamObject("", "0 0 1 rg 0 0 50 50 re f"),
+		})
+		helper.AssertNoError(err, "Failed to write PDF")
+	}
+
+	mockGen := NewMockPDFGenerator()
+	defer mockGen.Cleanup()
+	multiPath, err := mockGen.GenerateMultiPagePDF(3)
+	helper.AssertNoError(err, "Failed to generate multi-page PDF")
+	data, err := os.ReadFile(multiPath)
+	helper.AssertNoError(err, "Failed to read PDF file")
+
+	// 从内存数据创建的读取器在 Reset 后改为读取文件
+	reader := gopdf.NewPDFReaderFromBytes(data)
+	count, err := reader.GetPageCount()
+	helper.AssertNoError(err, "Failed to get page count")
+	if count != 3 {
+		t.Fatalf("page count = %d, want 3", count)
+	}
+
+	for i, want := range [][2]float64{{200, 100}, {300, 400}} {
+		reader.Reset(pdfPaths[i])
+		count, err := reader.GetPageCount()
+		helper.AssertNoError(err, "Failed to get page count after Reset")
+		if count != 1 {
+			t.Errorf("doc%d: page count = %d, want 1", i, count)
+		}
+		info, err := reader.GetPageInfo(1)
+		helper.AssertNoError(err, "Failed to get page info after Reset")
+		if info.Width != want[0] || info.Height != want[1] {
+			t.Errorf("doc%d: page size = %vx%v, want %vx%v", i, info.Width, info.Height, want[0], want[1])
+		}
+		img, err := reader.RenderPageToImage(1, 72)
+		helper.AssertNoError(err, "Failed to render page after Reset")
+		if b := img.Bounds(); b.Dx() != int(want[0]) || b.Dy() != int(want[1]) {
+			t.Errorf("doc%d: image size = %dx%d, want %vx%v", i, b.Dx(), b.Dy(), want[0], want[1])
+		}
+	}
+
+	// Close 之后 Reset 仍可继续使用读取器
+	helper.AssertNoError(reader.Close(), "Failed to close reader")
+	reader.Reset(multiPath)
+	if count, err := reader.GetPageCount(); err != nil || count != 3 {
+		t.Errorf("page count after Close and Reset = %d, %v, want 3", count, err)
+	}
+}
+
+// TestPDFReaderConcurrentUse 测试同一个 PDFReader 可被并发调用（配合 go test -race）
+func TestPDFReaderConcurrentUse(t *testing.T) {
+	helper := NewTestHelper(t)
+	mockGen := NewMockPDFGenerator()
+	defer mockGen.Cleanup()
+
+	pdfPath, err := mockGen.GenerateMultiPagePDF(3)
+	helper.AssertNoError(err, "Failed to generate multi-page PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 12)
+	for i := 0; i < 6; i++ {
+		pageNum := i%3 + 1
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := reader.GetPageInfo(pageNum); err != nil {
+				errs <- err
+			}
+			if _, err := reader.GetPageCount(); err != nil {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := reader.RenderPageToImage(pageNum, 36); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent call failed: %v", err)
+	}
+}
+
+// TestRenderPageToWriter 测试将页面直接编码为 PNG/JPEG 写入 io.Writer
+func TestRenderPageToWriter(t *testing.T) {
+	mockGen := NewMockPDFGenerator()
+	defer mockGen.Cleanup()
+
+	pdfPath, err := mockGen.GenerateRotatedPDF(200, 100, 0)
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+	reader := gopdf.NewPDFReader(pdfPath)
+
+	tests := []struct {
+		name   string
+		format gopdf.ImageFormat
+		decode func(r io.Reader) (image.Image, error)
+	}{
+		{"PNG", gopdf.ImageFormatPNG, png.Decode},
+		{"JPEG", gopdf.ImageFormatJPEG, jpeg.Decode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := reader.RenderPageToWriterWithQuality(1, 72, &buf, tt.format, 95); err != nil {
+				t.Fatalf("RenderPageToWriter failed: %v", err)
+			}
+
+			img, err := tt.decode(&buf)
+			if err != nil {
+				t.Fatalf("failed to decode output: %v", err)
+			}
+			if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+				t.Fatalf("size = %dx%d, want 200x100", b.Dx(), b.Dy())
+			}
+
+			// 左下角为红色方块，中心为白色背景
+			r, g, b, _ := img.At(10, 90).RGBA()
+			if r>>8 < 200 || g>>8 > 60 || b>>8 > 60 {
+				t.Errorf("bottom-left pixel = (%d,%d,%d), want red", r>>8, g>>8, b>>8)
+			}
+			r, g, b, _ = img.At(100, 50).RGBA()
+			if r>>8 < 200 || g>>8 < 200 || b>>8 < 200 {
+				t.Errorf("center pixel = (%d,%d,%d), want white", r>>8, g>>8, b>>8)
+			}
+		})
+	}
+}
+
+// TestSaveImageToJPEGAndTIFF 测试 JPEG/TIFF 导出，JPEG 透明区域合成到白色背景
+func TestSaveImageToJPEGAndTIFF(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	// 左半部分不透明红色，右半部分完全透明
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	dir := t.TempDir()
+
+	jpegPath := filepath.Join(dir, "out.jpg")
+	if err := gopdf.SaveImageToJPEG(img, jpegPath, 95); err != nil {
+		t.Fatalf("SaveImageToJPEG failed: %v", err)
+	}
+	f, err := os.Open(jpegPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := jpeg.Decode(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("failed to decode JPEG: %v", err)
+	}
+	if r, g, b, _ := decoded.At(3, 5).RGBA(); r>>8 < 200 || g>>8 > 60 || b>>8 > 60 {
+		t.Errorf("opaque pixel = (%d,%d,%d), want red", r>>8, g>>8, b>>8)
+	}
+	if r, g, b, _ := decoded.At(16, 5).RGBA(); r>>8 < 240 || g>>8 < 240 || b>>8 < 240 {
+		t.Errorf("transparent pixel = (%d,%d,%d), want white", r>>8, g>>8, b>>8)
+	}
+
+	tiffPath := filepath.Join(dir, "out.tiff")
+	if err := gopdf.SaveImageToTIFF(img, tiffPath); err != nil {
+		t.Fatalf("SaveImageToTIFF failed: %v", err)
+	}
+	if info, err := os.Stat(tiffPath); err != nil || info.Size() == 0 {
+		t.Errorf("TIFF file not written: %v", err)
+	}
+}
+
+// TestRenderContentFilter 测试按内容类别过滤渲染
+func TestRenderContentFilter(t *testing.T) {
+	helper := NewTestHelper(t)
+	mockGen := NewMockPDFGenerator()
+	defer mockGen.Cleanup()
+
+	pdfPath, err := mockGen.GenerateLayeredContentPDF()
+	helper.AssertNoError(err, "Failed to generate layered PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	dir := t.TempDir()
+
+	// 图像、矢量、文本区域（像素坐标，72 DPI，Y 轴向下）
+	imageRegion := [4]int{20, 20, 80, 80}
+	vectorRegion := [4]int{120, 20, 180, 80}
+	textRegion := [4]int{210, 10, 300, 90}
+
+	countPixels := func(path string, region [4]int, match func(r, g, b uint32) bool) int {
+		img := helper.LoadAndValidateImage(path)
+		n := 0
+		for y := region[1]; y < region[3]; y++ {
+			for x := region[0]; x < region[2]; x++ {
+				r, g, b, _ := img.At(x, y).RGBA()
+				if match(r>>8, g>>8, b>>8) {
+					n++
+				}
+			}
+		}
+		return n
+	}
+	isGreen := func(r, g, b uint32) bool { return g > 200 && r < 60 && b < 60 }
+	isRed := func(r, g, b uint32) bool { return r > 200 && g < 60 && b < 60 }
+	isDark := func(r, g, b uint32) bool { return r < 128 && g < 128 && b < 128 }
+
+	allPath := filepath.Join(dir, "all.png")
+	err = reader.RenderPageToPNGWithOptions(1, &gopdf.RenderOptions{DPI: 72, OutputPath: allPath})
+	helper.AssertNoError(err, "Failed to render all content")
+	if countPixels(allPath, imageRegion, isGreen) == 0 {
+		t.Fatal("image missing when rendering all content")
+	}
+	if countPixels(allPath, vectorRegion, isRed) == 0 {
+		t.Fatal("vector missing when rendering all content")
+	}
+	if countPixels(allPath, textRegion, isDark) == 0 {
+		t.Fatal("text missing when rendering all content")
+	}
+
+	imagesPath := filepath.Join(dir, "images.png")
+	err = reader.RenderPageToPNGWithOptions(1, &gopdf.RenderOptions{
+		DPI:        72,
+		OutputPath: imagesPath,
+		Content:    gopdf.ContentImages,
+	})
+	helper.AssertNoError(err, "Failed to render images only")
+
+	if n := countPixels(imagesPath, imageRegion, isGreen); n == 0 {
+		t.Error("image should be present when only images are enabled")
+	}
+	if n := countPixels(imagesPath, vectorRegion, isRed); n != 0 {
+		t.Errorf("found %d vector pixels, want none", n)
+	}
+	if n := countPixels(imagesPath, textRegion, isDark); n != 0 {
+		t.Errorf("found %d text pixels, want none", n)
+	}
+}
+
+// TestRenderICCBasedFillColorSpace 测试 cs/scn 使用资源中的 ICCBased 颜色空间填充
+func TestRenderICCBasedFillColorSpace(t *testing.T) {
+	tests := []struct {
+		name       string
+		n          int
+		components string
+		want       [3]uint32
+	}{
+		{"ICCBased N=4 cyan", 4, "1 0 0 0", [3]uint32{0, 255, 255}},
+		{"ICCBased N=4 black", 4, "0 0 0 1", [3]uint32{0, 0, 0}},
+		{"ICCBased N=3", 3, "0.2 0.4 0.6", [3]uint32{51, 102, 153}},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := NewTestHelper(t)
+			dir := t.TempDir()
+			pdfPath := filepath.Join(dir, fmt.Sprintf("iccbased_fill_%d.pdf", i))
+
+			stream := fmt.Sprintf("/CS0 cs\n%s scn\n0 0 100 100 re\nf\n", tt.components)
+			err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+				"/Resources << /ColorSpace << /CS0 [/ICCBased 5 0 R] >> >>",
+				pdfStreamObject("", stream),
+				pdfStreamObject(fmt.Sprintf("/N %d ", tt.n), "dummy"),
+			)
+			helper.AssertNoError(err, "Failed to write PDF")
+
+			outputPath := filepath.Join(dir, "fill.png")
+			err = gopdf.NewPDFReader(pdfPath).RenderPageToPNG(1, outputPath, 72)
+			helper.AssertNoError(err, "Failed to render page")
+
+			r, g, b, _ := helper.LoadAndValidateImage(outputPath).At(50, 50).RGBA()
+			got := [3]uint32{r >> 8, g >> 8, b >> 8}
+			for c := 0; c < 3; c++ {
+				diff := int(got[c]) - int(tt.want[c])
+				if diff < -2 || diff > 2 {
+					t.Fatalf("fill color = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderCheckboxAppearanceState(t *testing.T) {
+	tests := []struct {
+		name string
+		as   string
+		want [3]uint32
+	}{
+		{"checked", "Yes", [3]uint32{0, 255, 0}},
+		{"off", "Off", [3]uint32{0, 0, 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := NewTestHelper(t)
+			dir := t.TempDir()
+			pdfPath := filepath.Join(dir, "checkbox_"+tt.name+".pdf")
+
+			// /AP /N 按状态名索引：Yes 为绿色外观，Off 为蓝色外观
+			err := writePDFObjects(pdfPath, singlePagePDFObjects("/AcroForm << /Fields [5 0 R] >>",
+				"/MediaBox [0 0 200 200] /Contents 4 0 R /Annots [5 0 R]",
+				pdfStreamObject("", "1 1 1 rg\n0 0 200 200 re\nf\n"),
+				"<< /Type /Annot /Subtype /Widget /FT /Btn /T (cb) /V /Yes /AS /"+tt.as+
+					" /Rect [20 20 120 120] /AP << /N << /Yes 6 0 R /Off 7 0 R >> >> >>",
+				pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 50 50] ",
+					"0 1 0 rg\n0 0 50 50 re\nf\n"),
+				pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 50 50] ",
+					"0 0 1 rg\n0 0 50 50 re\nf\n"),
+			))
+			helper.AssertNoError(err, "Failed to write PDF")
+
+			outputPath := filepath.Join(dir, "checkbox.png")
+			err = gopdf.NewPDFReader(pdfPath).RenderPageToPNG(1, outputPath, 72)
+			helper.AssertNoError(err, "Failed to render page")
+
+			// 注释矩形中心 (70, 70) 在图像坐标中为 (70, 130)
+			r, g, b, _ := helper.LoadAndValidateImage(outputPath).At(70, 130).RGBA()
+			got := [3]uint32{r >> 8, g >> 8, b >> 8}
+			for c := 0; c < 3; c++ {
+				diff := int(got[c]) - int(tt.want[c])
+				if diff < -2 || diff > 2 {
+					t.Fatalf("appearance color = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderAnnotationAppearanceStreams(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "annotations.pdf")
+
+	// Square：外观流为绿色；Link：外观流只填充左半边蓝色；Highlight：没有外观流，回退为合成的半透明黄色
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R /Annots [5 0 R 6 0 R 7 0 R]",
+		pdfStreamObject("", "1 1 1 rg\n0 0 100 100 re\nf\n"),
+		"<< /Type /Annot /Subtype /Square /Rect [10 10 40 40] /AP << /N 8 0 R >> >>",
+		"<< /Type /Annot /Subtype /Link /Rect [60 60 90 90] /AP << /N 9 0 R >> >>",
+		"<< /Type /Annot /Subtype /Highlight /Rect [10 60 40 90] /C [1 1 0] >>",
+		pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 10 10] ", "0 1 0 rg\n0 0 10 10 re\nf\n"),
+		pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 30 30] ", "0 0 1 rg\n0 0 15 30 re\nf\n"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	outputPath := filepath.Join(dir, "annotations.png")
+	err = gopdf.NewPDFReader(pdfPath).RenderPageToPNG(1, outputPath, 72)
+	helper.AssertNoError(err, "Failed to render page")
+	img := helper.LoadAndValidateImage(outputPath)
+
+	// 图像坐标 = (x, 100 - y)
+	tests := []struct {
+		name string
+		x, y int
+		want [3]int
+	}{
+		{"square appearance", 25, 75, [3]int{0, 255, 0}},
+		{"link appearance", 65, 25, [3]int{0, 0, 255}},
+		{"link appearance right half untouched", 85, 25, [3]int{255, 255, 255}},
+		{"highlight fallback", 25, 25, [3]int{255, 255, 178}},
+	}
+	for _, tt := range tests {
+		r, g, b, _ := img.At(tt.x, tt.y).RGBA()
+		got := [3]int{int(r >> 8), int(g >> 8), int(b >> 8)}
+		for c := 0; c < 3; c++ {
+			if diff := got[c] - tt.want[c]; diff < -2 || diff > 2 {
+				t.Errorf("%s: pixel (%d,%d) = %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// TestRenderOptionsAnnotationsAndAntialias 测试 SkipAnnotations、SkipForms 和 Antialias 渲染选项
+func TestRenderOptionsAnnotationsAndAntialias(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "options.pdf")
+
+	// 左上角 Square 注释（绿色），右上角 Widget 注释（蓝色），下半部分是一条斜边的红色三角形
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R /Annots [5 0 R 6 0 R]",
+		pdfStreamObject("", "1 0 0 rg\n0 0 m 100 0 l 100 50 l h f\n"),
+		"<< /Type /Annot /Subtype /Square /Rect [10 60 40 90] /AP << /N 7 0 R >> >>",
+		"<< /Type /Annot /Subtype /Widget /FT /Btn /Rect [60 60 90 90] /AP << /N 8 0 R >> >>",
+		pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 30 30] ", "0 1 0 rg\n0 0 30 30 re\nf\n"),
+		pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 30 30] ", "0 0 1 rg\n0 0 30 30 re\nf\n"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+	reader := gopdf.NewPDFReader(pdfPath)
+
+	render := func(opts gopdf.RenderOptions) *image.RGBA {
+		opts.DPI = 72
+		img, err := reader.RenderPageToImageWithOptions(1, &opts)
+		helper.AssertNoError(err, "Failed to render page")
+		return img.(*image.RGBA)
+	}
+	isWhite := func(img *image.RGBA, x, y int) bool {
+		return img.RGBAAt(x, y) == color.RGBA{255, 255, 255, 255}
+	}
+
+	tests := []struct {
+		name           string
+		opts           gopdf.RenderOptions
+		square, widget bool
+	}{
+		{"default", gopdf.RenderOptions{}, true, true},
+		{"skip annotations", gopdf.RenderOptions{SkipAnnotations: true}, false, true},
+		{"skip forms", gopdf.RenderOptions{SkipForms: true}, true, false},
+		{"skip both", gopdf.RenderOptions{SkipAnnotations: true, SkipForms: true}, false, false},
+	}
+	for _, tt := range tests {
+		img := render(tt.opts)
+		if got := !isWhite(img, 25, 25); got != tt.square {
+			t.Errorf("%s: square annotation drawn = %v, want %v", tt.name, got, tt.square)
+		}
+		if got := !isWhite(img, 75, 25); got != tt.widget {
+			t.Errorf("%s: widget annotation drawn = %v, want %v", tt.name, got, tt.widget)
+		}
+	}
+
+	// 斜边上的像素：默认抗锯齿有中间色，AntialiasNone 只有纯红或纯白
+	countEdge := func(img *image.RGBA) int {
+		n := 0
+		for y := 50; y < 100; y++ {
+			for x := 0; x < 100; x++ {
+				if c := img.RGBAAt(x, y); c.G != 0 && c.G != 255 {
+					n++
+				}
+			}
+		}
+		return n
+	}
+	if n := countEdge(render(gopdf.RenderOptions{})); n == 0 {
+		t.Errorf("default antialiasing produced no intermediate edge pixels")
+	}
+	if n := countEdge(render(gopdf.RenderOptions{Antialias: gopdf.AntialiasNone})); n != 0 {
+		t.Errorf("AntialiasNone produced %d intermediate edge pixels, want 0", n)
+	}
+}
+
+// TestRenderStreamContent 测试边解析边执行内容流与一次性解析的渲染结果一致
+func TestRenderStreamContent(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "stream_content.pdf")
+
+	// 两个内容流：矩形路径在流之间被拆开，第二个流含内联图像和曲线
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents [4 0 R 5 0 R]",
+		pdfStreamObject("", "q 1 0 0 rg 10 10 40"),
+		pdfStreamObject("", " 30 re f Q\nq 60 0 0 20 30 60 cm BI /W 2 /H 1 /CS /RGB /BPC 8 ID \x00\x00\xff\x00\xff\x00\nEI Q\n"+
+			"0 0 1 RG 3 w 60 10 m 90 10 90 50 60 50 c S\n"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+	reader := gopdf.NewPDFReader(pdfPath)
+
+	want, err := reader.RenderPageToImageWithOptions(1, &gopdf.RenderOptions{DPI: 72})
+	helper.AssertNoError(err, "Failed to render page")
+	got, err := reader.RenderPageToImageWithOptions(1, &gopdf.RenderOptions{DPI: 72, StreamContent: true})
+	helper.AssertNoError(err, "Failed to render page with StreamContent")
+
+	if !bytes.Equal(got.(*image.RGBA).Pix, want.(*image.RGBA).Pix) {
+		t.Errorf("StreamContent rendering differs from the default rendering")
+	}
+	// 确认内容确实被绘制：红色矩形和内联图像的蓝色像素
+	if c := got.(*image.RGBA).RGBAAt(20, 80); c.R < 200 || c.G > 50 {
+		t.Errorf("rectangle pixel = %v, want red", c)
+	}
+	if c := got.(*image.RGBA).RGBAAt(40, 30); c.B < 200 || c.R > 50 {
+		t.Errorf("inline image pixel = %v, want blue", c)
+	}
+}
+
+// TestRenderAllPagesWithoutAnnotations 测试批量导出不含注释的干净页面
+func TestRenderAllPagesWithoutAnnotations(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "annotated_pages.pdf")
+
+	err := writePDFObjects(pdfPath, []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /MediaBox [0 0 50 50] >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 5 0 R /Annots [6 0 R] >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 5 0 R /Annots [6 0 R] >>",
+		pdfStreamObject("", "0 0 0 rg\n0 0 10 10 re\nf\n"),
+		"<< /Type /Annot /Subtype /Square /Rect [20 20 40 40] /AP << /N 7 0 R >> >>",
+		pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 20 20] ", "0 1 0 rg\n0 0 20 20 re\nf\n"),
+	})
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	outputDir := filepath.Join(dir, "clean")
+	err = reader.RenderAllPagesToPNGWithOptions(outputDir, &gopdf.RenderOptions{DPI: 72, SkipAnnotations: true, SkipForms: true})
+	helper.AssertNoError(err, "Failed to render pages")
+
+	for i := 1; i <= 2; i++ {
+		img := helper.LoadAndValidateImage(filepath.Join(outputDir, fmt.Sprintf("page_%d.png", i)))
+		// 图像坐标 = (x, 50 - y)
+		if r, g, b, _ := img.At(30, 20).RGBA(); r>>8 != 255 || g>>8 != 255 || b>>8 != 255 {
+			t.Errorf("page %d: annotation area = (%d,%d,%d), want white", i, r>>8, g>>8, b>>8)
+		}
+		if r, g, b, _ := img.At(5, 45).RGBA(); r>>8 != 0 || g>>8 != 0 || b>>8 != 0 {
+			t.Errorf("page %d: page content = (%d,%d,%d), want black", i, r>>8, g>>8, b>>8)
+		}
+	}
+
+	// 默认选项仍然渲染注释
+	err = reader.RenderAllPagesToPNGWithOptions(filepath.Join(dir, "default"), &gopdf.RenderOptions{DPI: 72})
+	helper.AssertNoError(err, "Failed to render pages")
+	img := helper.LoadAndValidateImage(filepath.Join(dir, "default", "page_1.png"))
+	if _, g, _, _ := img.At(30, 20).RGBA(); g>>8 != 255 {
+		t.Errorf("default options should render the annotation, got green = %d", g>>8)
+	}
+}
+
+func TestRenderTextFieldValues(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "textfields.pdf")
+
+	// 单行字段：DA 字号为 0（自动），红色；多行字段：10pt 蓝色，长文本需要按字段宽度折行
+	err := writePDFObjects(pdfPath, singlePagePDFObjects("/AcroForm 5 0 R",
+		"/MediaBox [0 0 200 100] /Contents 4 0 R",
+		pdfStreamObject("", "1 1 1 rg\n0 0 200 100 re\nf\n"),
+		"<< /Fields [6 0 R 7 0 R] /DA (/Helv 0 Tf 1 0 0 rg) /DR << /Font << /Helv 8 0 R >> >> >>",
+		"<< /FT /Tx /T (name) /V (Hello \\(World\\)) /Rect [10 60 190 90] >>",
+		"<< /FT /Tx /T (notes) /V (one two three four five six seven) /Rect [10 5 70 50] /Ff 4096 /DA (/Helv 10 Tf 0 0 1 rg) >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /FirstChar 32 /LastChar 126 /Widths ["+
+			strings.Repeat("500 ", 95)+"] >>",
+	))
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	ctx, err := api.ReadContextFile(pdfPath)
+	helper.AssertNoError(err, "Failed to read PDF")
+	fields, err := gopdf.ExtractFormFields(ctx)
+	helper.AssertNoError(err, "ExtractFormFields failed")
+	if len(fields) != 2 {
+		t.Fatalf("expected 2 form fields, got %d", len(fields))
+	}
+	if fields[0].Value != "Hello (World)" {
+		t.Errorf("expected decoded value %q, got %q", "Hello (World)", fields[0].Value)
+	}
+	if fields[0].DefaultAppearance != "/Helv 0 Tf 1 0 0 rg" {
+		t.Errorf("expected DA inherited from AcroForm, got %q", fields[0].DefaultAppearance)
+	}
+	if !fields[1].IsMultiline() {
+		t.Errorf("expected notes field to be multiline")
+	}
+
+	outputPath := filepath.Join(dir, "textfields.png")
+	err = gopdf.NewPDFReader(pdfPath).RenderPageToPNG(1, outputPath, 72)
+	helper.AssertNoError(err, "Failed to render page")
+	img := helper.LoadAndValidateImage(outputPath)
+
+	// 统计区域内偏红/偏蓝的像素，图像坐标 = (x, 100 - y)
+	count := func(x1, y1, x2, y2 int, match func(r, g, b uint32) bool) (n int, rows map[int]bool) {
+		rows = make(map[int]bool)
+		for y := y1; y < y2; y++ {
+			for x := x1; x < x2; x++ {
+				r, g, b, _ := img.At(x, y).RGBA()
+				if match(r>>8, g>>8, b>>8) {
+					n++
+					rows[y] = true
+				}
+			}
+		}
+		return n, rows
+	}
+	isRed := func(r, g, b uint32) bool { return r > 150 && g < 100 && b < 100 }
+	isBlue := func(r, g, b uint32) bool { return b > 150 && r < 100 && g < 100 }
+
+	if n, _ := count(10, 10, 190, 40, isRed); n == 0 {
+		t.Errorf("expected red value text inside the single-line field")
+	}
+
+	// 多行字段：文本应折成多行（蓝色像素分布在多个行带），且被裁剪在字段矩形内
+	_, rows := count(10, 50, 70, 95, isBlue)
+	if len(rows) == 0 {
+		t.Fatalf("expected blue value text inside the multiline field")
+	}
+	minRow, maxRow := 100, 0
+	for y := range rows {
+		minRow, maxRow = min(minRow, y), max(maxRow, y)
+	}
+	if maxRow-minRow < 15 {
+		t.Errorf("expected wrapped text spanning several lines, got rows %d..%d", minRow, maxRow)
+	}
+	if n, _ := count(70, 40, 200, 100, isBlue); n != 0 {
+		t.Errorf("expected multiline text clipped to the field rect, found %d blue pixels outside", n)
+	}
+}
+
+func TestRenderTextFieldCJKValue(t *testing.T) {
+	// 字段值为 UTF-16BE 的“中文”：应按字体回退绘制汉字，而不是替换为 ?
+	render := func(value string) image.Image {
+		helper := NewTestHelper(t)
+		pdfPath := filepath.Join(t.TempDir(), "cjk_field.pdf")
+		err := writePDFObjects(pdfPath, singlePagePDFObjects("/AcroForm << /Fields [5 0 R] /DA (/Helv 20 Tf 1 0 0 rg) >>",
+			"/MediaBox [0 0 100 50] /Contents 4 0 R",
+			pdfStreamObject("", "1 1 1 rg\n0 0 100 50 re\nf\n"),
+			"<< /FT /Tx /T (cjk) /V "+value+" /Rect [5 10 95 40] >>",
+		))
+		helper.AssertNoError(err, "Failed to write PDF")
+		img, err := gopdf.NewPDFReader(pdfPath).RenderPageToImage(1, 72)
+		helper.AssertNoError(err, "Failed to render page")
+		return img
+	}
+
+	cjk := render("<FEFF4E2D6587>")
+	question := render("(??)")
+
+	redPixels, differing := 0, 0
+	for y := 10; y < 40; y++ {
+		for x := 5; x < 95; x++ {
+			r, g, b, _ := cjk.At(x, y).RGBA()
+			if r>>8 > 150 && g>>8 < 100 && b>>8 < 100 {
+				redPixels++
+			}
+			if cjk.At(x, y) != question.At(x, y) {
+				differing++
+			}
+		}
+	}
+	if redPixels == 0 {
+		t.Fatalf("expected red CJK value text inside the field")
+	}
+	if differing == 0 {
+		t.Errorf("CJK field value rendered identically to \"??\"")
+	}
+}
+
+func TestStrokeWidthScalesWithDPI(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "stroke.pdf")
+
+	// 4pt 宽的水平线：渲染宽度应为 4*dpi/72 像素
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R",
+		pdfStreamObject("", "4 w 10 50 m 90 50 l S\n"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	for _, dpi := range []float64{72, 300} {
+		img, err := reader.RenderPageToImage(1, dpi)
+		helper.AssertNoError(err, "Failed to render page")
+
+		// 统计页面中央一列的线条覆盖率（按灰度累加，抗锯齿边缘计为部分像素）
+		x := img.Bounds().Dx() / 2
+		thickness := 0.0
+		for y := 0; y < img.Bounds().Dy(); y++ {
+			r, _, _, _ := img.At(x, y).RGBA()
+			thickness += 1 - float64(r)/0xffff
+		}
+		if want := 4 * dpi / 72; math.Abs(thickness-want) > 1 {
+			t.Errorf("stroke at %.0f DPI is %.2f px thick, want %.2f", dpi, thickness, want)
+		}
+	}
+}
+
+func TestRenderTextClipMasksImage(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "textclip.pdf")
+
+	// 渲染模式 7 的 "H" 只设置裁剪，随后铺满整页的红色图像应只出现在字形内部
+	red := string([]byte{255, 0, 0, 255, 0, 0, 255, 0, 0, 255, 0, 0})
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> /XObject << /Im1 6 0 R >> >>",
+		pdfStreamObject("", "q BT /F1 80 Tf 7 Tr 5 15 Td (H) Tj ET\nq 100 0 0 100 0 0 cm /Im1 Do Q Q\n"+
+			"0 0 1 rg 80 80 10 10 re f\n"),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 2 /Height 2 /ColorSpace /DeviceRGB /BitsPerComponent 8 ", red),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	outputPath := filepath.Join(dir, "textclip.png")
+	helper.AssertNoError(gopdf.NewPDFReader(pdfPath).RenderPageToPNG(1, outputPath, 72), "Failed to render page")
+	img := helper.LoadAndValidateImage(outputPath)
+
+	isRed := func(x, y int) bool {
+		r, g, b, _ := img.At(x, y).RGBA()
+		return r>>8 > 200 && g>>8 < 60 && b>>8 < 60
+	}
+	redPixels := 0
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			if isRed(x, y) {
+				redPixels++
+			}
+		}
+	}
+	// 左竖笔画内部为图像颜色，字形之外保持空白
+	if !isRed(15, 50) {
+		t.Errorf("expected image inside the glyph stem, got %v", img.At(15, 50))
+	}
+	if isRed(3, 50) || isRed(50, 5) {
+		t.Errorf("expected image to be clipped outside the glyph")
+	}
+	if redPixels == 0 || redPixels > 3000 {
+		t.Errorf("red pixels = %d, want only the glyph area", redPixels)
+	}
+	// Q 恢复裁剪后的绘制不受影响
+	if r, g, b, _ := img.At(85, 15).RGBA(); b>>8 < 200 || r>>8 > 60 || g>>8 > 60 {
+		t.Errorf("expected blue square after Q, got (%d,%d,%d)", r>>8, g>>8, b>>8)
+	}
+}
+
+// TestRenderStrokedTextLineWidth 描边文本的线宽按用户空间解释，不随文本矩阵放大
+func TestRenderStrokedTextLineWidth(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "stroked.pdf")
+
+	// 字号 1、文本矩阵放大 80 倍的描边 "I"：2 点的线宽只勾出竖笔画的轮廓
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> >>",
+		pdfStreamObject("", "BT /F1 1 Tf 80 0 0 80 40 15 Tm 1 Tr 2 w 0 0 1 RG (I) Tj ET"),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	img, err := gopdf.NewPDFReader(pdfPath).RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+
+	isBlue := func(x, y int) bool {
+		r, g, b, _ := img.At(x, y).RGBA()
+		return b>>8 > 128 && r>>8 < 128 && g>>8 < 128
+	}
+	left, right := 100, -1
+	for x := 0; x < 100; x++ {
+		if isBlue(x, 50) {
+			left, right = min(left, x), max(right, x)
+		}
+	}
+	if right < 0 {
+		t.Fatal("expected stroked glyph outline")
+	}
+	if right-left > 20 {
+		t.Errorf("stroked stem spans x=%d..%d, want the line width in user space", left, right)
+	}
+	if mid := (left + right) / 2; isBlue(mid, 50) {
+		t.Errorf("stroked stem interior (%d,50) is painted, want a hollow outline", mid)
+	}
+}
+
+func TestRenderImageInterpolate(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+
+	// 1x2 图像（左黑右白）放大到整页：/Interpolate true 时两个像素之间渐变，否则保持硬边
+	pixels := string([]byte{0, 0, 0, 255, 255, 255})
+	render := func(interpolate string) image.Image {
+		pdfPath := filepath.Join(dir, "interp"+interpolate+".pdf")
+		err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+			"/Resources << /XObject << /Im1 5 0 R >> >>",
+			pdfStreamObject("", "q 100 0 0 100 0 0 cm /Im1 Do Q\n"),
+			pdfStreamObject("/Type /XObject /Subtype /Image /Width 2 /Height 1 /ColorSpace /DeviceRGB "+
+				"/BitsPerComponent 8 /Interpolate "+interpolate+" ", pixels),
+		)
+		helper.AssertNoError(err, "Failed to write PDF")
+
+		outputPath := filepath.Join(dir, "interp"+interpolate+".png")
+		helper.AssertNoError(gopdf.NewPDFReader(pdfPath).RenderPageToPNG(1, outputPath, 72), "Failed to render page")
+		return helper.LoadAndValidateImage(outputPath)
+	}
+	gray := func(img image.Image, x int) uint32 {
+		r, _, _, _ := img.At(x, 50).RGBA()
+		return r >> 8
+	}
+
+	nearest := render("false")
+	if g := gray(nearest, 40); g != 0 {
+		t.Errorf("nearest sampling at x=40 = %d, want 0", g)
+	}
+	if g := gray(nearest, 60); g != 255 {
+		t.Errorf("nearest sampling at x=60 = %d, want 255", g)
+	}
+
+	smooth := render("true")
+	// 像素中心在 x=25 和 x=75 处，中间线性过渡
+	if g := gray(smooth, 10); g != 0 {
+		t.Errorf("bilinear sampling left of the first texel centre = %d, want 0", g)
+	}
+	if g := gray(smooth, 50); g < 120 || g > 135 {
+		t.Errorf("bilinear sampling at x=50 = %d, want about 128", g)
+	}
+	if g40, g60 := gray(smooth, 40), gray(smooth, 60); !(g40 > 0 && g40 < g60 && g60 < 255) {
+		t.Errorf("bilinear sampling not increasing: x=40 %d, x=60 %d", g40, g60)
+	}
+}
+
+func TestRenderSingleImagePage(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+
+	// 2x2 图像：上排红、绿，下排蓝、白；放在 (20,20)-(80,80)
+	pixels := string([]byte{255, 0, 0, 0, 255, 0, 0, 0, 255, 255, 255, 255})
+	render := func(name, content string) image.Image {
+		pdfPath := filepath.Join(dir, name+".pdf")
+		err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+			"/Resources << /XObject << /Im1 5 0 R >> >>",
+			pdfStreamObject("", content),
+			pdfStreamObject("/Type /XObject /Subtype /Image /Width 2 /Height 2 /ColorSpace /DeviceRGB "+
+				"/BitsPerComponent 8 ", pixels),
+		)
+		helper.AssertNoError(err, "Failed to write PDF")
+
+		outputPath := filepath.Join(dir, name+".png")
+		helper.AssertNoError(gopdf.NewPDFReader(pdfPath).RenderPageToPNG(1, outputPath, 72), "Failed to render page")
+		return helper.LoadAndValidateImage(outputPath)
+	}
+	checkColor := func(img image.Image, x, y int, want [3]uint32, label string) {
+		t.Helper()
+		r, g, b, _ := img.At(x, y).RGBA()
+		if got := [3]uint32{r >> 8, g >> 8, b >> 8}; got != want {
+			t.Errorf("%s: pixel (%d,%d) = %v, want %v", label, x, y, got, want)
+		}
+	}
+	checkImage := func(img image.Image, label string) {
+		t.Helper()
+		checkColor(img, 35, 35, [3]uint32{255, 0, 0}, label)
+		checkColor(img, 65, 35, [3]uint32{0, 255, 0}, label)
+		checkColor(img, 35, 65, [3]uint32{0, 0, 255}, label)
+		checkColor(img, 65, 65, [3]uint32{255, 255, 255}, label)
+	}
+
+	// 只有一个图像：直接写入图像，图像外保持白色背景
+	single := render("single", "BMC q 60 0 0 60 20 20 cm /Im1 Do Q EMC\n")
+	checkImage(single, "single image")
+	checkColor(single, 10, 10, [3]uint32{255, 255, 255}, "outside image")
+	checkColor(single, 90, 90, [3]uint32{255, 255, 255}, "outside image")
+
+	// 图像之后还有矢量内容：走完整渲染流程，两部分都要绘制
+	mixed := render("mixed", "q 60 0 0 60 20 20 cm /Im1 Do Q 0 0 0 rg 0 0 10 10 re f\n")
+	checkImage(mixed, "image with vector content")
+	checkColor(mixed, 5, 95, [3]uint32{0, 0, 0}, "vector content")
+}
+
+// TestRenderImageSMask 测试带 SMask 的图像按 alpha 与页面内容合成，而不是画成不透明矩形
+func TestRenderImageSMask(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+
+	// 3x1 蓝色图像，SMask 依次为不透明、全透明、半透明，横向铺满 (0,0)-(90,100)
+	render := func(name, content string) image.Image {
+		pdfPath := filepath.Join(dir, name+".pdf")
+		err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+			"/Resources << /XObject << /Im1 5 0 R >> >>",
+			pdfStreamObject("", content),
+			pdfStreamObject("/Type /XObject /Subtype /Image /Width 3 /Height 1 /ColorSpace /DeviceRGB "+
+				"/BitsPerComponent 8 /SMask 6 0 R ", string([]byte{0, 0, 255, 0, 0, 255, 0, 0, 255})),
+			pdfStreamObject("/Type /XObject /Subtype /Image /Width 3 /Height 1 /ColorSpace /DeviceGray "+
+				"/BitsPerComponent 8 ", string([]byte{255, 0, 128})),
+		)
+		helper.AssertNoError(err, "Failed to write PDF")
+
+		img, err := gopdf.NewPDFReader(pdfPath).RenderPageToImage(1, 72)
+		helper.AssertNoError(err, "Failed to render page")
+		return img
+	}
+	checkColor := func(img image.Image, x int, want [3]uint32, label string) {
+		t.Helper()
+		r, g, b, _ := img.At(x, 50).RGBA()
+		got := [3]uint32{r >> 8, g >> 8, b >> 8}
+		for i := range got {
+			if d := int(got[i]) - int(want[i]); d < -2 || d > 2 {
+				t.Errorf("%s: pixel x=%d = %v, want %v", label, x, got, want)
+				return
+			}
+		}
+	}
+
+	// 图像画在红色背景之上：透明处露出背景，半透明处与背景混合
+	over := render("over", "1 0 0 rg 0 0 100 100 re f q 90 0 0 100 0 0 cm /Im1 Do Q\n")
+	checkColor(over, 15, [3]uint32{0, 0, 255}, "over page content")
+	checkColor(over, 45, [3]uint32{255, 0, 0}, "over page content")
+	checkColor(over, 75, [3]uint32{127, 0, 128}, "over page content")
+
+	// 单图像页面走直接写入的快速路径，同样要按 alpha 与白色背景合成
+	single := render("single", "q 90 0 0 100 0 0 cm /Im1 Do Q\n")
+	checkColor(single, 15, [3]uint32{0, 0, 255}, "single image page")
+	checkColor(single, 45, [3]uint32{255, 255, 255}, "single image page")
+	checkColor(single, 75, [3]uint32{127, 127, 255}, "single image page")
+}
+
+// TestExtractImageWithMask 测试分别提取图像的基础颜色和软遮罩
+func TestExtractImageWithMask(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "smask_extract.pdf")
+
+	// 2x1 图像（红、绿），SMask 为 1x1 的半透明灰度；Im2 没有 SMask
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+		"/Resources << /XObject << /Im1 5 0 R /Im2 7 0 R >> >>",
+		pdfStreamObject("", "q 100 0 0 100 0 0 cm /Im1 Do Q\n"),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 2 /Height 1 /ColorSpace /DeviceRGB "+
+			"/BitsPerComponent 8 /SMask 6 0 R ", string([]byte{255, 0, 0, 0, 255, 0})),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray "+
+			"/BitsPerComponent 8 ", string([]byte{200})),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray "+
+			"/BitsPerComponent 8 ", string([]byte{90})),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+	reader := gopdf.NewPDFReader(pdfPath)
+
+	base, mask, err := reader.ExtractImageWithMask(1, "Im1")
+	helper.AssertNoError(err, "ExtractImageWithMask failed")
+	if got := base.RGBAAt(0, 0); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("base pixel 0 = %v, want opaque red", got)
+	}
+	if got := base.RGBAAt(1, 0); got != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("base pixel 1 = %v, want opaque green", got)
+	}
+	if mask == nil {
+		t.Fatalf("expected a soft mask")
+	}
+	if b := mask.Bounds(); b.Dx() != 1 || b.Dy() != 1 {
+		t.Errorf("mask size = %dx%d, want the SMask's own 1x1", b.Dx(), b.Dy())
+	}
+	if got := mask.GrayAt(0, 0).Y; absDiff(got, 200) > 1 {
+		t.Errorf("mask value = %d, want 200", got)
+	}
+
+	// ExtractImageData 仍返回合成了 alpha 的结果
+	composited, err := reader.ExtractImageData(1, "Im1")
+	helper.AssertNoError(err, "ExtractImageData failed")
+	if a := composited.RGBAAt(0, 0).A; absDiff(a, 200) > 1 {
+		t.Errorf("composited alpha = %d, want 200", a)
+	}
+
+	// 没有 SMask 的图像：mask 为 nil
+	base, mask, err = reader.ExtractImageWithMask(1, "Im2")
+	helper.AssertNoError(err, "ExtractImageWithMask failed")
+	if mask != nil {
+		t.Errorf("expected no mask for an image without SMask")
+	}
+	if got := base.RGBAAt(0, 0); got != (color.RGBA{90, 90, 90, 255}) {
+		t.Errorf("gray image pixel = %v, want (90,90,90)", got)
+	}
+
+	if _, _, err := reader.ExtractImageWithMask(1, "Missing"); err == nil {
+		t.Errorf("expected an error for a missing image")
+	}
+}
+
+func TestRenderPageThumbnail(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "stripes.pdf")
+
+	// 200x100 页面，左半部分为 1pt 宽的黑白竖条纹，右半部分为白色
+	var content strings.Builder
+	content.WriteString("0 0 0 rg\n")
+	for x := 0; x < 100; x += 2 {
+		fmt.Fprintf(&content, "%d 0 1 100 re\n", x)
+	}
+	content.WriteString("f\n")
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 200 100] /Contents 4 0 R",
+		pdfStreamObject("", content.String()),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	thumb, err := gopdf.NewPDFReader(pdfPath).RenderPageThumbnail(1, 50)
+	helper.AssertNoError(err, "Failed to render thumbnail")
+	if b := thumb.Bounds(); b.Dx() != 50 || b.Dy() != 25 {
+		t.Fatalf("thumbnail size = %dx%d, want 50x25", b.Dx(), b.Dy())
+	}
+
+	// 每个缩略图像素覆盖两条条纹：面积平均后为均匀的中灰色，而不是黑白交替的锯齿
+	for x := 2; x < 23; x++ {
+		r, _, _, _ := thumb.At(x, 12).RGBA()
+		if gray := int(r >> 8); gray < 100 || gray > 155 {
+			t.Errorf("striped pixel (%d,12) = %d, want mid gray", x, gray)
+		}
+	}
+	if r, g, b, _ := thumb.At(40, 12).RGBA(); r>>8 != 255 || g>>8 != 255 || b>>8 != 255 {
+		t.Errorf("blank pixel = (%d,%d,%d), want white", r>>8, g>>8, b>>8)
+	}
+
+	if _, err := gopdf.NewPDFReader(pdfPath).RenderPageThumbnail(1, 0); err == nil {
+		t.Error("expected an error for a zero thumbnail size")
+	}
+}
+
+// BenchmarkRenderTiledImage 同一个 256x256 图像在页面上平铺 400 次，
+// 页面内的图像缓存使每次 Do 不再重复解码和转换
+func BenchmarkRenderTiledImage(b *testing.B) {
+	dir := b.TempDir()
+	pdfPath := filepath.Join(dir, "tiled.pdf")
+
+	logo := make([]byte, 0, 256*256*3)
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			logo = append(logo, byte(x), byte(y), 128)
+		}
+	}
+	var content strings.Builder
+	for row := 0; row < 20; row++ {
+		for col := 0; col < 20; col++ {
+			fmt.Fprintf(&content, "q 20 0 0 20 %d %d cm /Logo Do Q\n", col*20, row*20)
+		}
+	}
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 400 400] /Contents 4 0 R "+
+		"/Resources << /XObject << /Logo 5 0 R >> >>",
+		pdfStreamObject("", content.String()),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 256 /Height 256 /ColorSpace /DeviceRGB "+
+			"/BitsPerComponent 8 ", string(logo)),
+	)
+	if err != nil {
+		b.Fatalf("Failed to write PDF: %v", err)
+	}
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	outputPath := filepath.Join(dir, "tiled.png")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := reader.RenderPageToPNG(1, outputPath, 72); err != nil {
+			b.Fatalf("Failed to render page: %v", err)
+		}
+	}
+}
+
+func TestExtractAllImages(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "images.pdf")
+
+	// Im1：2x2 红色 RGB 图像；Im2：空数据流（解码失败，应被跳过）；Fm1：表单 XObject（不是图像）
+	red := string([]byte{255, 0, 0, 255, 0, 0, 255, 0, 0, 255, 0, 0})
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+		"/Resources << /XObject << /Im1 5 0 R /Im2 6 0 R /Fm1 7 0 R >> >>",
+		pdfStreamObject("", "q 50 0 0 50 0 0 cm /Im1 Do Q\n"),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 2 /Height 2 /ColorSpace /DeviceRGB /BitsPerComponent 8 ", red),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 2 /Height 2 /ColorSpace /DeviceRGB /BitsPerComponent 8 ", ""),
+		pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 10 10] ", "0 0 10 10 re f\n"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	images, err := reader.ExtractAllImages(1)
+	helper.AssertNoError(err, "ExtractAllImages failed")
+
+	if len(images) != 1 {
+		t.Fatalf("expected 1 decoded image, got %d", len(images))
+	}
+	img, ok := images["Im1"]
+	if !ok {
+		t.Fatalf("expected image Im1, got %v", images)
+	}
+	if img.Bounds().Dx() != 2 || img.Bounds().Dy() != 2 {
+		t.Errorf("unexpected image size: %v", img.Bounds())
+	}
+	if r, g, b, _ := img.At(1, 1).RGBA(); r>>8 != 255 || g>>8 != 0 || b>>8 != 0 {
+		t.Errorf("expected red pixel, got (%d, %d, %d)", r>>8, g>>8, b>>8)
+	}
+
+	outDir := filepath.Join(dir, "out")
+	helper.AssertNoError(reader.ExtractImagesToDir(1, outDir), "ExtractImagesToDir failed")
+
+	entries, err := os.ReadDir(outDir)
+	helper.AssertNoError(err, "Failed to read output directory")
+	if len(entries) != 1 || entries[0].Name() != "Im1.png" {
+		t.Fatalf("expected only Im1.png in output directory, got %v", entries)
+	}
+	helper.LoadAndValidateImage(filepath.Join(outDir, "Im1.png"))
+}
+
+// TestRenderJBIG2Image 测试 JBIG2Decode 图像（MMR 编码的通用区域，带 JBIG2Globals）按 1 为黑解码并渲染
+func TestRenderJBIG2Image(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "jbig2.pdf")
+
+	// 8x2 页面：页面信息段、MMR 通用区域段（每行 x=2..5 为黑）、页面结束段
+	jbig2 := string([]byte{
+		0, 0, 0, 0, 0x30, 0, 1, 0, 0, 0, 19,
+		0, 0, 0, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 1, 0x27, 0, 1, 0, 0, 0, 23,
+		0, 0, 0, 8, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+		0x2E, 0xFC, 0x00, 0x40, 0x04,
+		0, 0, 0, 2, 0x31, 0, 1, 0, 0, 0, 0,
+	})
+	// 全局段中只有一个扩展段，解码时跳过
+	globals := string([]byte{0, 0, 0, 3, 0x3E, 0, 0, 0, 0, 0, 4, 0, 0, 0, 0})
+
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 40] /Contents 4 0 R "+
+		"/Resources << /XObject << /Im 5 0 R >> >>",
+		pdfStreamObject("", "q 80 0 0 20 10 10 cm /Im Do Q"),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 8 /Height 2 /ColorSpace /DeviceGray "+
+			"/BitsPerComponent 1 /Filter /JBIG2Decode /DecodeParms << /JBIG2Globals 6 0 R >> ", jbig2),
+		pdfStreamObject("", globals),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	img, err := gopdf.NewPDFReader(pdfPath).RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+
+	// 每个图像像素覆盖 10x10 设备像素，图像列 2..5 为黑
+	for col := 0; col < 8; col++ {
+		want := uint8(255)
+		if col >= 2 && col < 6 {
+			want = 0
+		}
+		for _, y := range []int{15, 25} {
+			r, g, b, _ := img.At(10+col*10+5, y).RGBA()
+			if absDiff(uint8(r>>8), want) > 8 || absDiff(uint8(g>>8), want) > 8 || absDiff(uint8(b>>8), want) > 8 {
+				t.Errorf("pixel for image column %d at y=%d = (%d,%d,%d), want %d", col, y, r>>8, g>>8, b>>8, want)
+			}
+		}
+	}
+}
+
+// testJPXCodestream 生成只有 SOC、SIZ 和 EOC 的 JPEG 2000 码流头部（8 位无符号分量）
+func testJPXCodestream(width, height, components int) []byte {
+	cs := []byte{0xFF, 0x4F, 0xFF, 0x51}
+	cs = binary.BigEndian.AppendUint16(cs, uint16(38+3*components))
+	cs = binary.BigEndian.AppendUint16(cs, 0)
+	for _, v := range []int{width + 2, height + 3, 2, 3, width, height, 0, 0} {
+		cs = binary.BigEndian.AppendUint32(cs, uint32(v))
+	}
+	cs = binary.BigEndian.AppendUint16(cs, uint16(components))
+	for i := 0; i < components; i++ {
+		cs = append(cs, 7, 1, 1)
+	}
+	return append(cs, 0xFF, 0xD9)
+}
+
+// testJP2Box 生成一个 JP2 框
+func testJP2Box(boxType string, body []byte) []byte {
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(box, boxType...), body...)
+}
+
+// testJP2File 生成带 ihdr、枚举颜色空间 colr 框和码流的 JP2 文件
+func testJP2File(width, height, components int, enumCS uint32) []byte {
+	file := []byte{0, 0, 0, 0x0C, 'j', 'P', ' ', ' ', 0x0D, 0x0A, 0x87, 0x0A}
+	file = append(file, testJP2Box("ftyp", []byte("jp2 \x00\x00\x00\x00jp2 "))...)
+	ihdr := binary.BigEndian.AppendUint32(nil, uint32(height))
+	ihdr = binary.BigEndian.AppendUint32(ihdr, uint32(width))
+	ihdr = binary.BigEndian.AppendUint16(ihdr, uint16(components))
+	ihdr = append(ihdr, 7, 7, 0, 0)
+	colr := binary.BigEndian.AppendUint32([]byte{1, 0, 0}, enumCS)
+	file = append(file, testJP2Box("jp2h", append(testJP2Box("ihdr", ihdr), testJP2Box("colr", colr)...))...)
+	return append(file, testJP2Box("jp2c", testJPXCodestream(width, height, components))...)
+}
+
+// TestParseJPXHeader 测试 JP2 文件和原始码流的头部解析
+func TestParseJPXHeader(t *testing.T) {
+	info, err := gopdf.ParseJPXHeader(testJP2File(640, 480, 3, 16))
+	if err != nil {
+		t.Fatalf("ParseJPXHeader (JP2) failed: %v", err)
+	}
+	want := gopdf.JPXInfo{Width: 640, Height: 480, Components: 3, BitsPerComponent: 8, ColorSpace: "DeviceRGB"}
+	if info != want {
+		t.Errorf("JP2 info = %+v, want %+v", info, want)
+	}
+
+	info, err = gopdf.ParseJPXHeader(testJP2File(20, 10, 1, 17))
+	if err != nil || info.ColorSpace != "DeviceGray" || info.Components != 1 {
+		t.Errorf("grayscale JP2 info = %+v, %v", info, err)
+	}
+
+	// 原始码流：尺寸为参考网格减去图像偏移，没有颜色空间信息
+	info, err = gopdf.ParseJPXHeader(testJPXCodestream(33, 17, 4))
+	if err != nil {
+		t.Fatalf("ParseJPXHeader (codestream) failed: %v", err)
+	}
+	want = gopdf.JPXInfo{Width: 33, Height: 17, Components: 4, BitsPerComponent: 8}
+	if info != want {
+		t.Errorf("codestream info = %+v, want %+v", info, want)
+	}
+
+	for name, data := range map[string][]byte{
+		"JPEG":      {0xFF, 0xD8, 0xFF, 0xE0},
+		"truncated": testJP2File(8, 8, 3, 16)[:40],
+		"empty":     nil,
+	} {
+		if _, err := gopdf.ParseJPXHeader(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestRenderJPXImage 测试 JPXDecode 图像返回可识别的错误，页面渲染时跳过该图像而不是绘制噪声
+func TestRenderJPXImage(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "jpx.pdf")
+
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+		"/Resources << /XObject << /Im 5 0 R >> >>",
+		pdfStreamObject("", "1 0 0 rg 0 0 20 20 re f q 80 0 0 80 10 10 cm /Im Do Q"),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 64 /Height 48 /Filter /JPXDecode ",
+			string(testJP2File(64, 48, 3, 16))),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	_, err = reader.ExtractImageData(1, "Im")
+	if !errors.Is(err, gopdf.ErrJPXUnsupported) {
+		t.Fatalf("ExtractImageData error = %v, want ErrJPXUnsupported", err)
+	}
+	var jpxErr *gopdf.JPXUnsupportedError
+	if !errors.As(err, &jpxErr) || jpxErr.Info.Width != 64 || jpxErr.Info.Height != 48 || jpxErr.Info.Components != 3 {
+		t.Errorf("JPX error info = %+v, want 64x48 with 3 components", jpxErr)
+	}
+
+	img, err := reader.RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+	// 图像区域保持白色，之前的绘制不受影响
+	for _, p := range [][2]int{{50, 50}, {30, 40}, {85, 20}} {
+		if r, g, b, _ := img.At(p[0], p[1]).RGBA(); r>>8 != 255 || g>>8 != 255 || b>>8 != 255 {
+			t.Errorf("pixel %v = (%d,%d,%d), want white", p, r>>8, g>>8, b>>8)
+		}
+	}
+	if r, g, b, _ := img.At(5, 95).RGBA(); r>>8 < 240 || g>>8 > 15 || b>>8 > 15 {
+		t.Errorf("red square pixel = (%d,%d,%d), want red", r>>8, g>>8, b>>8)
+	}
+}
+
+// TestExtractPageLabels 测试 /PageLabels 数字树：罗马数字前言、带前缀和起始值的正文、字母附录
+func TestExtractPageLabels(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+
+	// 对象 1-2 为 Catalog 和 Pages，3-9 为 7 个页面，10 为共享的空内容流
+	build := func(name, catalogExtra string, extra ...string) string {
+		objects := []string{
+			"<< /Type /Catalog /Pages 2 0 R " + catalogExtra + " >>",
+			"<< /Type /Pages /Kids [3 0 R 4 0 R 5 0 R 6 0 R 7 0 R 8 0 R 9 0 R] /Count 7 /MediaBox [0 0 100 100] >>",
+		}
+		for i := 0; i < 7; i++ {
+			objects = append(objects, "<< /Type /Page /Parent 2 0 R /Contents 10 0 R >>")
+		}
+		objects = append(objects, pdfStreamObject("", ""))
+		objects = append(objects, extra...)
+
+		pdfPath := filepath.Join(dir, name+".pdf")
+		helper.AssertNoError(writePDFObjects(pdfPath, objects), "Failed to write PDF")
+		return pdfPath
+	}
+
+	// 数字树根节点通过 /Kids 引用两个叶子节点
+	labeled := build("labeled", "/PageLabels 11 0 R",
+		"<< /Kids [12 0 R 13 0 R] >>",
+		"<< /Limits [0 2] /Nums [0 << /S /r >> 2 << /S /D /P (Chapter-) /St 5 >>] >>",
+		"<< /Limits [4 6] /Nums [4 << /S /A >> 6 << /P (Cover) >>] >>",
+	)
+	labels, err := gopdf.NewPDFReader(labeled).ExtractPageLabels()
+	helper.AssertNoError(err, "Failed to extract page labels")
+	want := []string{"i", "ii", "Chapter-5", "Chapter-6", "A", "B", "Cover"}
+	if strings.Join(labels, ",") != strings.Join(want, ",") {
+		t.Errorf("labels = %q, want %q", labels, want)
+	}
+
+	// 过大的 /St 被限制，超出上限的罗马数字和字母编号按十进制输出
+	huge := build("huge", "/PageLabels 11 0 R",
+		"<< /Nums [0 << /S /R /St 10000000000 >> 2 << /S /a /St 100000 >> 4 << /S /D /St 10000000000 >>] >>",
+	)
+	labels, err = gopdf.NewPDFReader(huge).ExtractPageLabels()
+	helper.AssertNoError(err, "Failed to extract page labels")
+	want = []string{"2147483647", "2147483648", strings.Repeat("d", 3847), "100001", "2147483647", "2147483648", "2147483649"}
+	if strings.Join(labels, ",") != strings.Join(want, ",") {
+		t.Errorf("labels with huge /St = %q, want %q", labels, want)
+	}
+
+	// 没有 /PageLabels 时使用物理页码
+	plain := build("plain", "")
+	labels, err = gopdf.NewPDFReader(plain).ExtractPageLabels()
+	helper.AssertNoError(err, "Failed to extract page labels")
+	if strings.Join(labels, ",") != "1,2,3,4,5,6,7" {
+		t.Errorf("labels without PageLabels = %q, want physical page numbers", labels)
+	}
+}
+
+func TestGetPermissions(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "plain.pdf")
+	err := writeSinglePagePDF(plainPath, "/MediaBox [0 0 100 100]")
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	perms, err := gopdf.NewPDFReader(plainPath).GetPermissions()
+	helper.AssertNoError(err, "GetPermissions failed on plain PDF")
+	if perms.Encrypted || !perms.CanCopy || !perms.CanPrint || !perms.CanModify {
+		t.Errorf("unencrypted PDF should allow everything, got %+v", perms)
+	}
+
+	// 空用户密码 + 所有者密码，仅允许打印（禁止复制）
+	encryptedPath := filepath.Join(dir, "encrypted.pdf")
+	conf := model.NewAESConfiguration("", "owner", 256)
+	conf.Permissions = model.PermissionsPrint
+	helper.AssertNoError(api.EncryptFile(plainPath, encryptedPath, conf), "Failed to encrypt PDF")
+
+	perms, err = gopdf.NewPDFReader(encryptedPath).GetPermissions()
+	helper.AssertNoError(err, "GetPermissions failed on encrypted PDF")
+	if !perms.Encrypted {
+		t.Fatal("expected encrypted document")
+	}
+	if perms.CanCopy || perms.CanModify || perms.CanAnnotate {
+		t.Errorf("expected copy/modify/annotate to be disallowed, got %+v", perms)
+	}
+	if !perms.CanPrint || !perms.CanPrintHighQuality {
+		t.Errorf("expected printing to be allowed, got %+v", perms)
+	}
+	if perms.Algorithm != "AES" || perms.KeyLength != 256 {
+		t.Errorf("encryption = %s-%d, want AES-256", perms.Algorithm, perms.KeyLength)
+	}
+}
+
+func TestOpenPasswordProtectedPDF(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "plain.pdf")
+	err := writeSinglePagePDF(plainPath, "/MediaBox [0 0 100 100] /Contents 4 0 R",
+		pdfStreamObject("", "1 0 0 rg\n0 0 100 100 re\nf\n"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	encryptedPath := filepath.Join(dir, "encrypted.pdf")
+	conf := model.NewAESConfiguration("user", "owner", 256)
+	helper.AssertNoError(api.EncryptFile(plainPath, encryptedPath, conf), "Failed to encrypt PDF")
+
+	if _, err := gopdf.NewPDFReader(encryptedPath).GetPageCount(); !errors.Is(err, gopdf.ErrPasswordRequired) {
+		t.Errorf("without password: err = %v, want ErrPasswordRequired", err)
+	}
+	if _, err := gopdf.NewPDFReaderWithPassword(encryptedPath, "wrong").GetPageCount(); !errors.Is(err, gopdf.ErrWrongPassword) {
+		t.Errorf("wrong password: err = %v, want ErrWrongPassword", err)
+	}
+	outPath := filepath.Join(dir, "wrong.png")
+	if err := gopdf.NewPDFReaderWithPassword(encryptedPath, "wrong").RenderPageToPNG(1, outPath, 72); !errors.Is(err, gopdf.ErrWrongPassword) {
+		t.Errorf("render with wrong password: err = %v, want ErrWrongPassword", err)
+	}
+
+	for _, password := range []string{"user", "owner"} {
+		reader := gopdf.NewPDFReaderWithPassword(encryptedPath, password)
+		count, err := reader.GetPageCount()
+		helper.AssertNoError(err, "GetPageCount failed with password "+password)
+		if count != 1 {
+			t.Errorf("page count = %d, want 1", count)
+		}
+
+		outPath := filepath.Join(dir, password+".png")
+		helper.AssertNoError(reader.RenderPageToPNG(1, outPath, 72), "RenderPageToPNG failed with password "+password)
+		img := helper.LoadAndValidateImage(outPath)
+		r, g, b, _ := img.At(50, 50).RGBA()
+		if r>>8 < 200 || g>>8 > 50 || b>>8 > 50 {
+			t.Errorf("password %s: center pixel = (%d,%d,%d), want red", password, r>>8, g>>8, b>>8)
+		}
+	}
+}
+
+func TestRenderIsolatedTransparencyGroup(t *testing.T) {
+	// 红色背景上绘制透明度组，组内为 Multiply 混合的蓝色方块：
+	// 非隔离组与背景混合得到黑色，隔离组以透明背景开始，保持蓝色
+	tests := []struct {
+		isolated string
+		want     [3]uint32
+	}{
+		{"false", [3]uint32{0, 0, 0}},
+		{"true", [3]uint32{0, 0, 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run("isolated="+tt.isolated, func(t *testing.T) {
+			helper := NewTestHelper(t)
+			pdfPath := filepath.Join(t.TempDir(), "group.pdf")
+
+			err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+				"/Resources << /XObject << /Fm1 5 0 R >> /ExtGState << /GS0 6 0 R >> >>",
+				pdfStreamObject("", "1 0 0 rg\n0 0 100 100 re\nf\n/Fm1 Do\n"),
+				pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 100 100] "+
+					"/Group << /S /Transparency /I "+tt.isolated+" >> ",
+					"/GS0 gs\n0 0 1 rg\n20 20 60 60 re\nf\n"),
+				"<< /Type /ExtGState /BM /Multiply >>",
+			)
+			helper.AssertNoError(err, "Failed to write PDF")
+
+			img, err := gopdf.NewPDFReader(pdfPath).RenderPageToImage(1, 72)
+			helper.AssertNoError(err, "Failed to render page")
+
+			r, g, b, _ := img.At(50, 50).RGBA()
+			got := [3]uint32{r >> 8, g >> 8, b >> 8}
+			if got != tt.want {
+				t.Errorf("group center = %v, want %v", got, tt.want)
+			}
+
+			// 组外仍为红色背景
+			if r, g, b, _ := img.At(5, 5).RGBA(); r>>8 != 255 || g>>8 != 0 || b>>8 != 0 {
+				t.Errorf("backdrop = (%d, %d, %d), want red", r>>8, g>>8, b>>8)
+			}
+		})
+	}
+}
+
+func TestRenderNonIsolatedGroupBlendMode(t *testing.T) {
+	// 红色背景上以 /BM /Multiply 绘制非隔离组，组内为 Normal 模式的 50% 灰色方块：
+	// 先从组结果中去掉背景，再以 Multiply 合成，方块处得到暗红色，组内未绘制处保持红色
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "group_blend.pdf")
+
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+		"/Resources << /XObject << /Fm1 5 0 R >> /ExtGState << /GS0 6 0 R >> >>",
+		pdfStreamObject("", "1 0 0 rg\n0 0 100 100 re\nf\n/GS0 gs\n/Fm1 Do\n"),
+		pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 100 100] "+
+			"/Group << /S /Transparency >> ", "0.5 g\n20 20 60 60 re\nf\n"),
+		"<< /Type /ExtGState /BM /Multiply >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	img, err := gopdf.NewPDFReader(pdfPath).RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+
+	near := func(got uint32, want int) bool {
+		d := int(got>>8) - want
+		return d >= -3 && d <= 3
+	}
+	checks := []struct {
+		x, y    int
+		r, g, b int
+	}{
+		{50, 50, 127, 0, 0}, // 灰色 Multiply 红色
+		{5, 5, 255, 0, 0},   // 组内未绘制：背景不与自身相乘
+	}
+	for _, c := range checks {
+		r, g, b, _ := img.At(c.x, c.y).RGBA()
+		if !near(r, c.r) || !near(g, c.g) || !near(b, c.b) {
+			t.Errorf("pixel (%d,%d) = (%d,%d,%d), want about (%d,%d,%d)",
+				c.x, c.y, r>>8, g>>8, b>>8, c.r, c.g, c.b)
+		}
+	}
+}
+
+func TestRenderKnockoutTransparencyGroup(t *testing.T) {
+	// 白色背景上的敲除组：半透明红色方块后绘制半透明蓝色方块，
+	// 重叠处蓝色只与组的初始（透明）背景合成，不叠加在红色之上
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "knockout.pdf")
+
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+		"/Resources << /XObject << /Fm1 5 0 R >> /ExtGState << /GS0 6 0 R >> >>",
+		pdfStreamObject("", "1 1 1 rg\n0 0 100 100 re\nf\n/Fm1 Do\n"),
+		pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 100 100] "+
+			"/Group << /S /Transparency /I true /K true >> ",
+			"1 0 0 rg\n/GS0 gs\n10 10 60 60 re\nf\n0 0 1 rg\n/GS0 gs\n40 40 50 50 re\nf\n"),
+		"<< /Type /ExtGState /ca 0.5 >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	img, err := gopdf.NewPDFReader(pdfPath).RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+
+	near := func(got uint32, want int) bool {
+		d := int(got>>8) - want
+		return d >= -3 && d <= 3
+	}
+	checks := []struct {
+		x, y    int
+		r, g, b int
+	}{
+		{20, 80, 255, 127, 127}, // 仅红色
+		{55, 45, 127, 127, 255}, // 重叠处：蓝色敲除红色
+		{85, 15, 127, 127, 255}, // 仅蓝色
+		{5, 95, 255, 255, 255},  // 组外
+	}
+	for _, c := range checks {
+		r, g, b, _ := img.At(c.x, c.y).RGBA()
+		if !near(r, c.r) || !near(g, c.g) || !near(b, c.b) {
+			t.Errorf("pixel (%d,%d) = (%d,%d,%d), want about (%d,%d,%d)",
+				c.x, c.y, r>>8, g>>8, b>>8, c.r, c.g, c.b)
+		}
+	}
+}
+
+func TestRenderExtGStateSoftMask(t *testing.T) {
+	// ExtGState 的 /SMask 作用于之后的所有绘制，直到 Q 或 /SMask /None：
+	//   - 上半部分：亮度遮罩，遮罩组在 gs 时的 CTM（右移 50）下把左半填成白色，只有右半露出红色
+	//   - 下方 25 点：alpha 遮罩，遮罩组以 ca 0.5 填充，蓝色半透明
+	//   - 中间 25 点：/SMask /None 之后绿色不受遮罩影响
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "extgstate_smask.pdf")
+
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+		"/Resources << /ExtGState << /GS1 5 0 R /GS2 6 0 R /GS3 << /SMask /None >> >> >>",
+		pdfStreamObject("", "q 1 0 0 1 50 0 cm /GS1 gs 1 0 0 rg -50 50 100 50 re f Q\n"+
+			"/GS2 gs 0 0 1 rg 0 0 100 25 re f\n/GS3 gs 0 1 0 rg 0 25 100 25 re f\n"),
+		"<< /Type /ExtGState /SMask << /Type /Mask /S /Luminosity /G 7 0 R >> >>",
+		"<< /Type /ExtGState /SMask << /Type /Mask /S /Alpha /G 8 0 R /BC [1] >> >>",
+		pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 100 100] "+
+			"/Group << /S /Transparency /CS /DeviceGray >> ", "1 g 0 50 50 50 re f\n"),
+		pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 100 100] "+
+			"/Resources << /ExtGState << /H << /ca 0.5 >> >> >> ", "/H gs 0 0 100 100 re f\n"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	img, err := gopdf.NewPDFReader(pdfPath).RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+
+	near := func(got uint32, want int) bool {
+		d := int(got>>8) - want
+		return d >= -3 && d <= 3
+	}
+	checks := []struct {
+		x, y    int
+		r, g, b int
+	}{
+		{25, 25, 255, 255, 255}, // 亮度遮罩为 0（BC 黑色）：不绘制
+		{75, 25, 255, 0, 0},     // 亮度遮罩为 1：红色
+		{50, 90, 127, 127, 255}, // alpha 遮罩 0.5：半透明蓝色
+		{50, 60, 0, 255, 0},     // 遮罩已移除
+	}
+	for _, c := range checks {
+		r, g, b, _ := img.At(c.x, c.y).RGBA()
+		if !near(r, c.r) || !near(g, c.g) || !near(b, c.b) {
+			t.Errorf("pixel (%d,%d) = (%d,%d,%d), want about (%d,%d,%d)",
+				c.x, c.y, r>>8, g>>8, b>>8, c.r, c.g, c.b)
+		}
+	}
+}
+
+func TestRenderSoftMaskOversizedTransfer(t *testing.T) {
+	// 软遮罩 /TR 为 /Size 极大的采样函数：不能按 /Size 分配采样表，
+	// 忽略该传递函数后按恒等映射渲染，亮度遮罩为白色时红色完全露出
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "smask_oversized_tr.pdf")
+
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+		"/Resources << /ExtGState << /GS1 5 0 R >> >>",
+		pdfStreamObject("", "/GS1 gs 1 0 0 rg 0 0 100 100 re f\n"),
+		"<< /Type /ExtGState /SMask << /Type /Mask /S /Luminosity /G 6 0 R /TR 7 0 R >> >>",
+		pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 100 100] "+
+			"/Group << /S /Transparency /CS /DeviceGray >> ", "1 g 0 0 100 100 re f\n"),
+		pdfStreamObject("/FunctionType 0 /Domain [0 1] /Range [0 1] /Size [1073741824] /BitsPerSample 8 ", "\x00\xff"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	img, err := gopdf.NewPDFReader(pdfPath).RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+
+	r, g, b, _ := img.At(50, 50).RGBA()
+	if r>>8 < 250 || g>>8 > 5 || b>>8 > 5 {
+		t.Errorf("pixel (50,50) = (%d,%d,%d), want red with the transfer function ignored", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestRenderInlineImage(t *testing.T) {
+	// 2x1 RGB 内联图像（红、绿）缩放到页面左下角 60x60
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "inline.pdf")
+
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R /Resources << >>",
+		pdfStreamObject("", "q 60 0 0 60 0 0 cm\nBI /W 2 /H 1 /CS /RGB /BPC 8 ID \xff\x00\x00\x00\xff\x00\nEI\nQ\n"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	img, err := reader.RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+
+	checks := []struct {
+		x, y    int
+		r, g, b uint32
+	}{
+		{15, 70, 255, 0, 0},
+		{45, 70, 0, 255, 0},
+		{80, 20, 255, 255, 255},
+	}
+	for _, c := range checks {
+		r, g, b, _ := img.At(c.x, c.y).RGBA()
+		if r>>8 != c.r || g>>8 != c.g || b>>8 != c.b {
+			t.Errorf("pixel (%d,%d) = (%d,%d,%d), want (%d,%d,%d)", c.x, c.y, r>>8, g>>8, b>>8, c.r, c.g, c.b)
+		}
+	}
+
+	_, images := reader.ExtractPageElements(1)
+	if len(images) != 1 {
+		t.Fatalf("expected 1 image element, got %d", len(images))
+	}
+	if im := images[0]; im.Name != "inline1" || im.X != 0 || im.Y != 40 || im.Width != 60 || im.Height != 60 {
+		t.Errorf("inline image element = %+v, want inline1 at (0,40) 60x60", im)
+	}
+}
+
+func TestHitTest(t *testing.T) {
+	// 第 1 页：文本、URI 链接、GoTo 链接、命名目标链接和图像；第 3 页旋转 90° 且 MediaBox 原点不为 0
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "hittest.pdf")
+
+	err := writePDFObjects(pdfPath, []string{
+		"<< /Type /Catalog /Pages 2 0 R /Dests << /chap2 [6 0 R /Fit] >> >>",
+		"<< /Type /Pages /Kids [3 0 R 6 0 R 7 0 R] /Count 3 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Contents 4 0 R " +
+			"/Resources << /Font << /F1 8 0 R >> /XObject << /Im 5 0 R >> >> /Annots [9 0 R 10 0 R 11 0 R] >>",
+		pdfStreamObject("", "BT /F1 20 Tf 20 150 Td (Hi) Tj ET q 50 0 0 50 100 20 cm /Im Do Q"),
+		pdfStreamObject("/Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 ", "0"),
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 100 100] >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [50 50 250 150] /Rotate 90 /Contents 12 0 R " +
+			"/Resources << /XObject << /Im 5 0 R >> >> >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Annot /Subtype /Link /Rect [10 100 60 120] /A << /S /URI /URI (https://example.com/) >> >>",
+		"<< /Type /Annot /Subtype /Link /Rect [150 120 100 100] /A << /S /GoTo /D [6 0 R /Fit] >> >>",
+		"<< /Type /Annot /Subtype /Link /Rect [150 100 190 120] /Dest /chap2 >>",
+		pdfStreamObject("", "q 20 0 0 20 60 60 cm /Im Do Q"),
+	})
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+
+	// 144 DPI 时第 1 页的像素 (px, py) 对应用户空间 ((px+0.5)/2, 200-(py+0.5)/2)
+	hit, err := reader.HitTest(1, 50, 90, 144)
+	helper.AssertNoError(err, "HitTest on text failed")
+	if math.Abs(hit.X-25.25) > 1e-6 || math.Abs(hit.Y-154.75) > 1e-6 {
+		t.Errorf("user point = (%g, %g), want (25.25, 154.75)", hit.X, hit.Y)
+	}
+	if hit.Text == nil || hit.Text.Text != "Hi" || hit.GlyphIndex != 0 || hit.Link != nil || hit.Image != nil {
+		t.Errorf("text hit = %+v, want glyph 0 of \"Hi\" only", hit)
+	}
+	hit, err = reader.HitTest(1, 72, 90, 144)
+	helper.AssertNoError(err, "HitTest on second glyph failed")
+	if hit.Text == nil || hit.GlyphIndex != 1 || hit.Text.Glyphs[1].Rune != 'i' {
+		t.Errorf("second glyph hit = %+v, want glyph 1 ('i')", hit)
+	}
+
+	links := []struct {
+		name     string
+		px, py   int
+		uri      string
+		destPage int
+		destName string
+	}{
+		{"uri", 60, 180, "https://example.com/", 0, ""},
+		{"goto", 240, 180, "", 2, ""},
+		{"named", 340, 180, "", 2, "chap2"},
+	}
+	for _, l := range links {
+		hit, err := reader.HitTest(1, l.px, l.py, 144)
+		helper.AssertNoError(err, "HitTest on link failed")
+		if hit.Link == nil {
+			t.Errorf("%s: no link hit at (%d,%d)", l.name, l.px, l.py)
+			continue
+		}
+		if hit.Link.URI != l.uri || hit.Link.DestPage != l.destPage || hit.Link.DestName != l.destName {
+			t.Errorf("%s: link target = (%q, %d, %q), want (%q, %d, %q)", l.name,
+				hit.Link.URI, hit.Link.DestPage, hit.Link.DestName, l.uri, l.destPage, l.destName)
+		}
+	}
+
+	hit, err = reader.HitTest(1, 250, 310, 144)
+	helper.AssertNoError(err, "HitTest on image failed")
+	if hit.Image == nil || hit.Image.Name != "Im" || hit.Text != nil || hit.Link != nil {
+		t.Errorf("image hit = %+v, want image Im only", hit)
+	}
+
+	hit, err = reader.HitTest(1, 360, 40, 144)
+	helper.AssertNoError(err, "HitTest on empty area failed")
+	if hit.Text != nil || hit.Link != nil || hit.Image != nil {
+		t.Errorf("empty area hit = %+v, want nothing", hit)
+	}
+
+	// 旋转页面：显示尺寸 100x200，用户点 (70.25, 70.25) 在 144 DPI 下位于像素 (40, 40)
+	hit, err = reader.HitTest(3, 40, 40, 144)
+	helper.AssertNoError(err, "HitTest on rotated page failed")
+	if math.Abs(hit.X-70.25) > 1e-6 || math.Abs(hit.Y-70.25) > 1e-6 {
+		t.Errorf("rotated page user point = (%g, %g), want (70.25, 70.25)", hit.X, hit.Y)
+	}
+	if hit.Image == nil {
+		t.Errorf("rotated page: image not hit")
+	}
+
+	if _, err := reader.HitTest(4, 0, 0, 144); err == nil {
+		t.Errorf("expected error for invalid page number")
+	}
+}
+
+func TestRenderStrictMode(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+
+	cases := []struct {
+		name     string
+		content  string
+		wantFail bool
+	}{
+		{"balanced", "q 0 0 1 rg 0 0 50 50 re f Q BT /F1 12 Tf 10 80 Td (A) Tj ET", false},
+		{"unmatched Q", "q 0 0 1 rg 0 0 50 50 re f Q Q", true},
+		{"ET without BT", "0 0 1 rg 0 0 50 50 re f ET", true},
+		{"unmatched Q in form", "0 0 1 rg 0 0 50 50 re f q /Fm Do Q", true},
+		{"unmatched Q in soft mask", "0 0 1 rg 0 0 50 50 re f q /GS1 gs 60 60 10 10 re f Q", true},
+	}
+	for _, c := range cases {
+		pdfPath := filepath.Join(dir, strings.ReplaceAll(c.name, " ", "_")+".pdf")
+		err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+			"/Resources << /Font << /F1 6 0 R >> /XObject << /Fm 5 0 R >> "+
+			"/ExtGState << /GS1 << /SMask << /Type /Mask /S /Alpha /G 5 0 R >> >> >> >>",
+			pdfStreamObject("", c.content),
+			// 表单同时用作软遮罩组：多出的 Q 只能在严格模式下中止渲染
+			pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 100 100] ", "q 1 0 0 rg 60 60 10 10 re f Q Q"),
+			"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		)
+		helper.AssertNoError(err, "Failed to write PDF")
+		reader := gopdf.NewPDFReader(pdfPath)
+
+		// 宽松模式（默认）继续渲染
+		img, err := reader.RenderPageToImageWithOptions(1, &gopdf.RenderOptions{DPI: 72})
+		if err != nil {
+			t.Errorf("%s: lenient render failed: %v", c.name, err)
+		} else if r, g, b, _ := img.At(25, 75).RGBA(); r>>8 != 0 || g>>8 != 0 || b>>8 != 255 {
+			t.Errorf("%s: lenient pixel (25,75) = (%d,%d,%d), want blue", c.name, r>>8, g>>8, b>>8)
+		}
+
+		for _, stream := range []bool{false, true} {
+			_, err = reader.RenderPageToImageWithOptions(1, &gopdf.RenderOptions{DPI: 72, Strict: true, StreamContent: stream})
+			if c.wantFail {
+				if !errors.Is(err, gopdf.ErrUnbalancedContent) {
+					t.Errorf("%s (stream=%v): strict render error = %v, want ErrUnbalancedContent", c.name, stream, err)
+				}
+			} else if err != nil {
+				t.Errorf("%s (stream=%v): strict render failed: %v", c.name, stream, err)
+			}
+		}
+	}
+}
+
+func TestSeparationAllAndNone(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "separation.pdf")
+
+	tint := "<< /FunctionType 2 /Domain [0 1] /C0 [0 0 0 0] /C1 [0 0 1 0] /N 1 >>"
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> /ColorSpace << "+
+		"/CsRed [/Separation /Red /DeviceRGB << /FunctionType 2 /Domain [0 1] /C0 [1 1 1] /C1 [1 0 0] /N 1 >>] "+
+		"/CsAll [/Separation /All /DeviceCMYK "+tint+"] "+
+		"/CsNone [/Separation /None /DeviceCMYK "+tint+"] >> >>",
+		pdfStreamObject("", "/CsRed cs 1 sc 0 0 30 30 re f "+
+			"/CsAll cs 1 sc 35 0 30 30 re f "+
+			"0 0 1 rg 0 40 30 30 re f /CsNone cs 1 sc 0 40 30 30 re f "+
+			"/CsNone CS 10 w 35 40 30 30 re S "+
+			"/CsNone cs 0 1 0 rg 70 40 30 30 re f "+
+			"BT /CsNone cs /F1 20 Tf 5 78 Td (WWW) Tj ET"),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	img, err := reader.RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+
+	checks := []struct {
+		name    string
+		x, y    int
+		r, g, b uint32
+	}{
+		{"Separation tint", 15, 85, 255, 0, 0},
+		{"All", 50, 85, 0, 0, 0},
+		{"None fill", 15, 45, 0, 0, 255},
+		{"None stroke", 35, 45, 255, 255, 255},
+		{"rg after None", 85, 45, 0, 255, 0},
+	}
+	for _, c := range checks {
+		r, g, b, _ := img.At(c.x, c.y).RGBA()
+		if r>>8 != c.r || g>>8 != c.g || b>>8 != c.b {
+			t.Errorf("%s: pixel (%d,%d) = (%d,%d,%d), want (%d,%d,%d)", c.name, c.x, c.y, r>>8, g>>8, b>>8, c.r, c.g, c.b)
+		}
+	}
+	for y := 0; y < 25; y++ {
+		for x := 0; x < 100; x++ {
+			if r, g, b, _ := img.At(x, y).RGBA(); r>>8 != 255 || g>>8 != 255 || b>>8 != 255 {
+				t.Fatalf("text with None fill left a mark at (%d,%d)", x, y)
+			}
+		}
+	}
+
+	// 提取时的图形状态跟踪 cs/sc 和 None 着色剂
+	var fills []gopdf.GraphicsSnapshot
+	err = reader.IterateOperators(1, func(op gopdf.PDFOperator, state *gopdf.GraphicsSnapshot) error {
+		if op.Name() == "f" {
+			fills = append(fills, *state)
+		}
+		return nil
+	})
+	helper.AssertNoError(err, "Failed to iterate operators")
+	if len(fills) != 5 {
+		t.Fatalf("expected 5 fills, got %d", len(fills))
+	}
+	if f := fills[0]; f.FillColorSpace != "CsRed" || f.FillColor != [3]float64{1, 0, 0} || f.FillNoMarks {
+		t.Errorf("Separation fill state = %q %v noMarks=%v, want CsRed red", f.FillColorSpace, f.FillColor, f.FillNoMarks)
+	}
+	if f := fills[1]; f.FillColor != [3]float64{0, 0, 0} || f.FillNoMarks {
+		t.Errorf("All fill state = %v noMarks=%v, want black", f.FillColor, f.FillNoMarks)
+	}
+	if f := fills[2]; f.FillColorSpace != "DeviceRGB" || f.FillColor != [3]float64{0, 0, 1} {
+		t.Errorf("rg fill state = %q %v, want DeviceRGB blue", f.FillColorSpace, f.FillColor)
+	}
+	if f := fills[3]; f.FillColorSpace != "CsNone" || !f.FillNoMarks {
+		t.Errorf("None fill state = %q noMarks=%v, want CsNone without marks", f.FillColorSpace, f.FillNoMarks)
+	}
+	if f := fills[4]; f.FillColorSpace != "DeviceRGB" || f.FillNoMarks || !f.StrokeNoMarks {
+		t.Errorf("fill after rg = %q noMarks=%v strokeNoMarks=%v, want DeviceRGB with marks", f.FillColorSpace, f.FillNoMarks, f.StrokeNoMarks)
+	}
+}
+
+func TestGrayAndCMYKColorOperators(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "colors.pdf")
+
+	// k 和 g 之后的 sc 在 DeviceCMYK、DeviceGray 中解释；cs 切换到 DeviceRGB 后 sc 需要 3 个分量
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> >>",
+		pdfStreamObject("", "0 0 0 1 k 0 0 30 30 re f "+
+			"0.5 g 35 0 30 30 re f "+
+			"1 0 0 0 k 0 1 0 0 sc 70 0 30 30 re f "+
+			"0 0 1 rg 0.25 g 0.75 sc 0 40 30 30 re f "+
+			"/DeviceRGB cs 0 0 1 sc 35 40 30 30 re f "+
+			"BT /F1 10 Tf 0 0 1 0 k 5 80 Td (C) Tj 0.5 g (G) Tj /DeviceRGB cs 0 1 0 sc (R) Tj ET"),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	reader := gopdf.NewPDFReader(pdfPath)
+	img, err := reader.RenderPageToImage(1, 72)
+	helper.AssertNoError(err, "Failed to render page")
+
+	checks := []struct {
+		name    string
+		x, y    int
+		r, g, b uint8
+	}{
+		{"0 0 0 1 k", 15, 85, 0, 0, 0},
+		{"0.5 g", 50, 85, 128, 128, 128},
+		{"sc after k", 85, 85, 255, 0, 255},
+		{"sc after g", 15, 45, 191, 191, 191},
+		{"sc after cs", 50, 45, 0, 0, 255},
+	}
+	for _, c := range checks {
+		r, g, b, _ := img.At(c.x, c.y).RGBA()
+		if absDiff(uint8(r>>8), c.r) > 1 || absDiff(uint8(g>>8), c.g) > 1 || absDiff(uint8(b>>8), c.b) > 1 {
+			t.Errorf("%s: pixel (%d,%d) = (%d,%d,%d), want (%d,%d,%d)", c.name, c.x, c.y, r>>8, g>>8, b>>8, c.r, c.g, c.b)
+		}
+	}
+
+	texts, _ := reader.ExtractPageElements(1)
+	want := [][3]float64{{1, 1, 0}, {0.5, 0.5, 0.5}, {0, 1, 0}}
+	if len(texts) != len(want) {
+		t.Fatalf("expected %d text elements, got %d", len(want), len(texts))
+	}
+	for i, w := range want {
+		if texts[i].Color != w {
+			t.Errorf("text %q color = %v, want %v", texts[i].Text, texts[i].Color, w)
+		}
+	}
+}
+
+func TestRenderLimits(t *testing.T) {
+	helper := NewTestHelper(t)
+	dir := t.TempDir()
+
+	writePage := func(name, content string, forms ...string) string {
+		pdfPath := filepath.Join(dir, name)
+		objects := []string{pdfStreamObject("", content)}
+		for _, form := range forms {
+			objects = append(objects, pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 100 100] ", form))
+		}
+		err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R "+
+			"/Resources << /XObject << /Fm1 5 0 R /Fm2 6 0 R >> >>", objects...)
+		helper.AssertNoError(err, "Failed to write PDF")
+		return pdfPath
+	}
+
+	limitError := func(err error) *gopdf.RenderLimitError {
+		t.Helper()
+		var limitErr *gopdf.RenderLimitError
+		if !errors.Is(err, gopdf.ErrRenderLimitExceeded) || !errors.As(err, &limitErr) {
+			t.Fatalf("render error = %v, want *RenderLimitError", err)
+		}
+		return limitErr
+	}
+
+	// 引用自身的表单在默认深度限制处中止，而不是无限递归
+	selfRef := gopdf.NewPDFReader(writePage("self.pdf", "q /Fm1 Do Q",
+		"0 0 1 rg 0 0 10 10 re f /Fm1 Do", "0 0 1 rg 0 0 10 10 re f"))
+	_, err := selfRef.RenderPageToImage(1, 72)
+	if e := limitError(err); e.Limit != "form depth" || e.Max != gopdf.DefaultMaxFormDepth {
+		t.Errorf("self-referencing form: limit = %+v, want form depth %d", e, gopdf.DefaultMaxFormDepth)
+	}
+	_, err = selfRef.RenderPageToImageWithOptions(1, &gopdf.RenderOptions{DPI: 72, MaxFormDepth: 5})
+	if e := limitError(err); e.Max != 5 {
+		t.Errorf("self-referencing form: limit = %+v, want form depth 5", e)
+	}
+
+	// 两层嵌套的表单：深度限制为 2 时正常渲染，为 1 时中止
+	nested := gopdf.NewPDFReader(writePage("nested.pdf", "q /Fm1 Do Q",
+		"/Fm2 Do", "0 0 1 rg 0 0 50 50 re f"))
+	img, err := nested.RenderPageToImageWithOptions(1, &gopdf.RenderOptions{DPI: 72, MaxFormDepth: 2})
+	helper.AssertNoError(err, "Failed to render nested forms")
+	if r, g, b, _ := img.At(25, 75).RGBA(); r>>8 != 0 || g>>8 != 0 || b>>8 != 255 {
+		t.Errorf("nested form pixel = (%d,%d,%d), want blue", r>>8, g>>8, b>>8)
+	}
+	_, err = nested.RenderPageToImageWithOptions(1, &gopdf.RenderOptions{DPI: 72, MaxFormDepth: 1})
+	limitError(err)
+
+	// 操作符数量限制包括表单中的操作符：页面 4 个 + Fm1 的 1 个 + Fm2 的 3 个
+	counted := gopdf.NewPDFReader(writePage("ops.pdf", "0 0 1 rg /Fm1 Do 0 0 10 10 re f",
+		"/Fm2 Do", "0 0 1 rg 0 0 50 50 re f"))
+	for _, stream := range []bool{false, true} {
+		_, err = counted.RenderPageToImageWithOptions(1, &gopdf.RenderOptions{DPI: 72, MaxOperators: 8, StreamContent: stream})
+		helper.AssertNoError(err, "Render within operator limit failed")
+		_, err = counted.RenderPageToImageWithOptions(1, &gopdf.RenderOptions{DPI: 72, MaxOperators: 7, StreamContent: stream})
+		if e := limitError(err); e.Limit != "operators" || e.Max != 7 {
+			t.Errorf("stream=%v: limit = %+v, want operators 7", stream, e)
+		}
+	}
+}
+
+// TestRenderLimitsAppearanceStreams 注释外观流中的操作符计入页面的渲染预算
+func TestRenderLimitsAppearanceStreams(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "appearance-ops.pdf")
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 100 100] /Contents 4 0 R /Annots [5 0 R]",
+		pdfStreamObject("", "0 0 1 rg 0 0 10 10 re f"),
+		"<< /Type /Annot /Subtype /Square /Rect [20 20 80 80] /AP << /N 6 0 R >> >>",
+		pdfStreamObject("/Type /XObject /Subtype /Form /BBox [0 0 60 60] ", "1 0 0 rg 0 0 60 60 re f"),
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+	reader := gopdf.NewPDFReader(pdfPath)
+
+	// 页面 3 个操作符 + 外观流 3 个
+	img, err := reader.RenderPageToImageWithOptions(1, &gopdf.RenderOptions{DPI: 72, MaxOperators: 6})
+	helper.AssertNoError(err, "Render within operator limit failed")
+	if r, g, b, _ := img.At(50, 50).RGBA(); r>>8 != 255 || g>>8 != 0 || b>>8 != 0 {
+		t.Errorf("appearance pixel = (%d,%d,%d), want red", r>>8, g>>8, b>>8)
+	}
+	_, err = reader.RenderPageToImageWithOptions(1, &gopdf.RenderOptions{DPI: 72, MaxOperators: 5})
+	var limitErr *gopdf.RenderLimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "operators" || limitErr.Max != 5 {
+		t.Errorf("render error = %v, want operators limit 5", err)
+	}
+}
+
+// TestExtractType0TextFromRegistry 测试没有 ToUnicode 的 Type0 字体（pdfcpu 给出的 Subtype 不带 "/"）
+// 按 BaseFont 推断字符集合，通过 poppler-data 的 CID 映射解码文本
+func TestExtractType0TextFromRegistry(t *testing.T) {
+	helper := NewTestHelper(t)
+	pdfPath := filepath.Join(t.TempDir(), "type0.pdf")
+
+	// Adobe-GB1 中 CID 4559、3795 为 "中文"
+	err := writeSinglePagePDF(pdfPath, "/MediaBox [0 0 300 100] /Contents 4 0 R "+
+		"/Resources << /Font << /F1 5 0 R >> >>",
+		pdfStreamObject("", "BT /F1 12 Tf 10 50 Td <11CF0ED3> Tj ET"),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /SimSun /Encoding /Identity-H /DescendantFonts [6 0 R] >>",
+		"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /SimSun "+
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor 7 0 R /DW 1000 >>",
+		"<< /Type /FontDescriptor /FontName /SimSun /Flags 4 /FontBBox [0 -141 1000 859] "+
+			"/ItalicAngle 0 /Ascent 859 /Descent -141 /CapHeight 700 /StemV 80 >>",
+	)
+	helper.AssertNoError(err, "Failed to write PDF")
+
+	text, err := gopdf.NewPDFReader(pdfPath).ExtractOrderedText(1)
+	helper.AssertNoError(err, "Failed to extract text")
+	if got := strings.TrimSpace(text); got != "中文" {
+		t.Errorf("extracted text = %q, want %q", got, "中文")
+	}
+}
+
+// BenchmarkBatchCJKText 依次提取 50 个使用未嵌入 CJK 字体（无 ToUnicode）的文档的文本，
+// 这类字体的文本通过 poppler-data 的 CID 映射解码；比较每个文件新建读取器与复用同一个读取器（Reset）的吞吐量，
+// ColdRegistry 在每个文件前清空包级别的 CID 映射缓存，相当于没有跨读取器缓存时的开销
+func BenchmarkBatchCJKText(b *testing.B) {
+	dir := b.TempDir()
+	fonts := []string{"SimSun", "MS-Mincho", "Batang", "MingLiU"}
+	pdfPaths := make([]string, 50)
+	for i := range pdfPaths {
+		pdfPaths[i] = filepath.Join(dir, fmt.Sprintf("cjk%02d.pdf", i))
+		err := writeSinglePagePDF(pdfPaths[i], "/MediaBox [0 0 300 100] /Contents 4 0 R "+
+			"/Resources << /Font << /F1 5 0 R >> >>",
+			pdfStreamObject("", "BT /F1 12 Tf 10 50 Td <0401040204030404> Tj ET"),
+			fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H "+
+				"/DescendantFonts [6 0 R] >>", fonts[i%len(fonts)]),
+			fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /%s "+
+				"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor 7 0 R /DW 1000 >>",
+				fonts[i%len(fonts)]),
+			fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 4 /FontBBox [0 -141 1000 859] "+
+				"/ItalicAngle 0 /Ascent 859 /Descent -141 /CapHeight 700 /StemV 80 >>", fonts[i%len(fonts)]),
+		)
+		if err != nil {
+			b.Fatalf("Failed to write PDF: %v", err)
+		}
+	}
+
+	extract := func(b *testing.B, reader *gopdf.PDFReader) {
+		if _, err := reader.ExtractPageTextRuns(1); err != nil {
+			b.Fatalf("Failed to extract text: %v", err)
+		}
+	}
+
+	b.Run("NewReader", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, path := range pdfPaths {
+				extract(b, gopdf.NewPDFReader(path))
+			}
+		}
+	})
+	b.Run("Reset", func(b *testing.B) {
+		reader := gopdf.NewPDFReader("")
+		for i := 0; i < b.N; i++ {
+			for _, path := range pdfPaths {
+				reader.Reset(path)
+				extract(b, reader)
+			}
+		}
+	})
+	b.Run("ColdRegistry", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, path := range pdfPaths {
+				gopdf.ClearCIDRegistryCache()
+				extract(b, gopdf.NewPDFReader(path))
+			}
+		}
+	})
+}
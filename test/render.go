@@ -260,10 +260,19 @@ func ExtractFontInfoForReport(pdfPath string, pageNum int) string {
 
 		if font.EmbeddedFontSize > 0 {
 			report.WriteString(fmt.Sprintf("  Embedded Font: YES (%d bytes)\n", font.EmbeddedFontSize))
+		} else if font.IsEmbedded {
+			report.WriteString("  Embedded Font: YES\n")
 		} else {
 			report.WriteString("  Embedded Font: NO\n")
 		}
 
+		if font.IsSubset {
+			report.WriteString("  Subset: YES\n")
+		}
+		if font.Flags != 0 {
+			report.WriteString(fmt.Sprintf("  Flags: %d (ItalicAngle %.1f, StemV %.0f)\n", font.Flags, font.ItalicAngle, font.StemV))
+		}
+
 		report.WriteString("\n")
 	}
 
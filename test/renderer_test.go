@@ -1,9 +1,15 @@
 package test
 
 import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/novvoo/go-pdf/pkg/gopdf"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
 )
 
 // TestPDFRenderer 测试PDF渲染器的基本功能
@@ -26,6 +32,199 @@ func TestPDFRenderer(t *testing.T) {
 	helper.AssertFileExists(outputPath)
 }
 
+// TestRenderToPDFRoundTrip 测试 PDF 表面输出的矢量内容可以被重新打开和渲染
+func TestRenderToPDFRoundTrip(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "rect.pdf")
+
+	renderer := gopdf.NewPDFRenderer(200, 100)
+	err := renderer.RenderToPDF(outputPath, func(ctx gopdf.Context) {
+		ctx.SetSourceRGB(1, 0, 0)
+		ctx.Rectangle(20, 10, 60, 40)
+		ctx.Fill()
+
+		ctx.SetSourceRGB(0, 0, 1)
+		ctx.SetLineWidth(4)
+		ctx.MoveTo(120, 80)
+		ctx.LineTo(180, 80)
+		ctx.Stroke()
+	})
+	if err != nil {
+		t.Fatalf("RenderToPDF failed: %v", err)
+	}
+
+	pdfCtx, err := api.ReadContextFile(outputPath)
+	if err != nil {
+		t.Fatalf("pdfcpu failed to read output: %v", err)
+	}
+	if pdfCtx.PageCount != 1 {
+		t.Fatalf("page count = %d, want 1", pdfCtx.PageCount)
+	}
+
+	img, err := gopdf.NewPDFReader(outputPath).RenderPageToImage(1, 72)
+	if err != nil {
+		t.Fatalf("RenderPageToImage failed: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 200 || bounds.Dy() != 100 {
+		t.Fatalf("rendered size = %dx%d, want 200x100", bounds.Dx(), bounds.Dy())
+	}
+
+	// 设备坐标系 Y 向下：矩形位于左上方，线段位于右下方
+	tests := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{"rectangle", 50, 30, color.RGBA{255, 0, 0, 255}},
+		{"stroke", 150, 80, color.RGBA{0, 0, 255, 255}},
+	}
+	for _, tt := range tests {
+		got := color.RGBAModel.Convert(img.At(tt.x, tt.y)).(color.RGBA)
+		if got != tt.want {
+			t.Errorf("%s pixel at (%d,%d) = %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+	if got := color.RGBAModel.Convert(img.At(50, 80)).(color.RGBA); got.R == 255 && got.G == 0 {
+		t.Errorf("pixel below the rectangle is red, content is not flipped correctly")
+	}
+}
+
+// TestRenderToPDFGradient 测试 PDF 表面把渐变填充输出为 Shading，而不是退化为单色
+func TestRenderToPDFGradient(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "gradient.pdf")
+
+	renderer := gopdf.NewPDFRenderer(200, 100)
+	err := renderer.RenderToPDF(outputPath, func(ctx gopdf.Context) {
+		gradient := gopdf.NewPatternLinear(0, 0, 200, 0)
+		gradient.(gopdf.LinearGradientPattern).AddColorStopRGB(0, 1, 0, 0)
+		gradient.(gopdf.LinearGradientPattern).AddColorStopRGB(0.5, 0, 1, 0)
+		gradient.(gopdf.LinearGradientPattern).AddColorStopRGB(1, 0, 0, 1)
+		ctx.SetSource(gradient)
+		ctx.Rectangle(0, 0, 200, 100)
+		ctx.Fill()
+		gradient.Destroy()
+	})
+	if err != nil {
+		t.Fatalf("RenderToPDF failed: %v", err)
+	}
+
+	img, err := gopdf.NewPDFReader(outputPath).RenderPageToImage(1, 72)
+	if err != nil {
+		t.Fatalf("RenderPageToImage failed: %v", err)
+	}
+	tests := []struct {
+		x       int
+		r, g, b uint8
+	}{
+		{2, 255, 0, 0},
+		{100, 0, 255, 0},
+		{197, 0, 0, 255},
+		{50, 128, 128, 0},
+	}
+	for _, tt := range tests {
+		got := color.RGBAModel.Convert(img.At(tt.x, 50)).(color.RGBA)
+		if absDiff(got.R, tt.r) > 8 || absDiff(got.G, tt.g) > 8 || absDiff(got.B, tt.b) > 8 {
+			t.Errorf("pixel at x=%d = %v, want about (%d,%d,%d)", tt.x, got, tt.r, tt.g, tt.b)
+		}
+	}
+}
+
+// TestSVGSurfaceOutput 测试 SVG 表面把路径、填充、描边、裁剪和渐变输出为 SVG 元素
+func TestSVGSurfaceOutput(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "drawing.svg")
+
+	surface := gopdf.NewSVGSurface(outputPath, 200, 100)
+	ctx := gopdf.NewContext(surface)
+
+	ctx.SetSourceRGB(1, 0, 0)
+	ctx.Rectangle(20, 10, 60, 40)
+	ctx.Fill()
+
+	ctx.SetSourceRGBA(0, 0, 1, 0.5)
+	ctx.SetLineWidth(3)
+	ctx.SetLineCap(gopdf.LineCapRound)
+	ctx.MoveTo(120, 80)
+	ctx.CurveTo(140, 20, 160, 20, 180, 80)
+	ctx.Stroke()
+
+	ctx.Save()
+	ctx.Rectangle(100, 0, 100, 50)
+	ctx.Clip()
+	gradient := gopdf.NewPatternLinear(100, 0, 200, 0)
+	gradient.(gopdf.LinearGradientPattern).AddColorStopRGB(0, 0, 1, 0)
+	gradient.(gopdf.LinearGradientPattern).AddColorStopRGB(1, 1, 1, 0)
+	ctx.SetSource(gradient)
+	ctx.Rectangle(0, 0, 200, 100)
+	ctx.Fill()
+	ctx.Restore()
+
+	ctx.Destroy()
+	if err := surface.Finish(); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	surface.Destroy()
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to read SVG: %v", err)
+	}
+
+	// 解析 SVG，按元素名收集属性
+	elements := make(map[string][]map[string]string)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			attrs := make(map[string]string)
+			for _, attr := range start.Attr {
+				attrs[attr.Name.Local] = attr.Value
+			}
+			elements[start.Name.Local] = append(elements[start.Name.Local], attrs)
+		}
+	}
+
+	if svg := elements["svg"]; len(svg) != 1 || svg[0]["viewBox"] != "0 0 200 100" {
+		t.Fatalf("unexpected <svg> root: %v", svg)
+	}
+
+	rects := elements["rect"]
+	if len(rects) == 0 || rects[0]["fill"] != "#ff0000" || rects[0]["x"] != "20" || rects[0]["width"] != "60" {
+		t.Errorf("filled rectangle not emitted as <rect>: %v", rects)
+	}
+
+	var stroke map[string]string
+	for _, p := range elements["path"] {
+		if p["stroke"] != "" {
+			stroke = p
+		}
+	}
+	if stroke == nil {
+		t.Fatalf("stroked curve not emitted as <path>: %v", elements["path"])
+	}
+	if stroke["fill"] != "none" || stroke["stroke"] != "#0000ff" || stroke["stroke-opacity"] != "0.5" ||
+		stroke["stroke-width"] != "3" || stroke["stroke-linecap"] != "round" || stroke["d"] != "M120 80 C140 20 160 20 180 80" {
+		t.Errorf("unexpected stroke attributes: %v", stroke)
+	}
+
+	if len(elements["clipPath"]) != 1 {
+		t.Fatalf("clip region not emitted as <clipPath>: %v", elements["clipPath"])
+	}
+	clipped := false
+	for _, g := range elements["g"] {
+		if g["clip-path"] == "url(#"+elements["clipPath"][0]["id"]+")" {
+			clipped = true
+		}
+	}
+	if !clipped {
+		t.Error("gradient fill does not reference the clip path")
+	}
+	if grads := elements["linearGradient"]; len(grads) != 1 || len(elements["stop"]) != 2 {
+		t.Errorf("gradient not emitted as <linearGradient> with 2 stops: %v %v", grads, elements["stop"])
+	}
+}
+
 // TestPDFRendererDimensions 测试不同尺寸的渲染器
 func TestPDFRendererDimensions(t *testing.T) {
 	tests := []struct {
@@ -5,6 +5,7 @@ import (
 	"image"
 	"image/png"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/novvoo/go-pdf/pkg/gopdf"
@@ -313,3 +314,44 @@ func loadAndValidateImage(filename string) (image.Image, error) {
 
 	return img, nil
 }
+
+// TestTextRiseRendering 测试 Ts 文本上升在实际绘制路径中生效
+func TestTextRiseRendering(t *testing.T) {
+	helper := NewTestHelper(t)
+	mockGen := NewMockPDFGenerator()
+	defer mockGen.Cleanup()
+
+	const rise = 5
+	fontRes := "/MediaBox [0 0 200 100] /Resources << /Font << /F1 << /Type /Font /Subtype /Type1 /BaseFont /Helvetica >> >> >>"
+
+	renderTop := func(name string, ts int) int {
+		stream := fmt.Sprintf("BT\n/F1 40 Tf\n%d Ts\n20 30 Td\n(HH) Tj\nET\n", ts)
+		pdfPath, err := mockGen.GenerateSinglePagePDF(name, fontRes, stream)
+		helper.AssertNoError(err, "Failed to generate PDF")
+
+		outputPath := filepath.Join(t.TempDir(), name+".png")
+		err = gopdf.NewPDFReader(pdfPath).RenderPageToPNG(1, outputPath, 72)
+		helper.AssertNoError(err, "Failed to render page")
+
+		img := helper.LoadAndValidateImage(outputPath)
+		bounds := img.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r, g, b, _ := img.At(x, y).RGBA()
+				if r>>8 < 128 && g>>8 < 128 && b>>8 < 128 {
+					return y
+				}
+			}
+		}
+		t.Fatalf("%s: no text pixels rendered", name)
+		return -1
+	}
+
+	baseTop := renderTop("rise_0.pdf", 0)
+	raisedTop := renderTop("rise_5.pdf", rise)
+
+	// PDF Y 轴向上，上升 5pt 在 72 DPI 下对应图像中向上 5 像素
+	if shift := baseTop - raisedTop; shift < rise-1 || shift > rise+1 {
+		t.Errorf("glyph top moved by %d px, want %d", shift, rise)
+	}
+}